@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -22,6 +23,9 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Route all logging (including the standard log package) through slog
+	slog.SetDefault(newLogger(cfg.LogFormat))
+
 	// Connect to database
 	dbpool, err := pgxpool.New(context.Background(), cfg.Database.URL)
 	if err != nil {
@@ -37,6 +41,12 @@ func main() {
 
 	// Create router with database pool
 	router := api.NewServer(cfg, dbpool)
+	defer router.Close()
+
+	// Run scheduled jobs until shutdown
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	router.StartBackground(bgCtx)
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -61,6 +71,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopBackground()
 
 	// Give outstanding requests 30 seconds to complete
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -70,5 +81,21 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Let background imports finish, interrupting any still running at the deadline
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), time.Duration(cfg.Import.DrainTimeoutSeconds)*time.Second)
+	defer cancelDrain()
+	if n := router.DrainImports(drainCtx); n > 0 {
+		log.Printf("Interrupted %d background imports at shutdown; they can be retried", n)
+	}
+
 	log.Println("Server stopped")
 }
+
+// newLogger builds the process logger: readable text for local development,
+// JSON for log aggregation in production
+func newLogger(format string) *slog.Logger {
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, nil))
+}
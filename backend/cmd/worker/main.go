@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/aggregates"
+)
+
+// Worker refreshes KPI aggregates after imports. With no flags it recomputes
+// the full sales history; -from/-to or -import limit the refresh.
+func main() {
+	from := flag.String("from", "", "first day to refresh (YYYY-MM-DD)")
+	to := flag.String("to", "", "last day to refresh (YYYY-MM-DD)")
+	locationFlag := flag.String("location", "", "location ID for -from/-to (defaults to the first location)")
+	importFlag := flag.String("import", "", "refresh only the days touched by this import job ID")
+	flag.Parse()
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL is required")
+	}
+
+	useRollup, _ := strconv.ParseBool(os.Getenv("KPI_USE_ROLLUP"))
+	opts := aggregates.RefreshOptions{
+		LaborBasis:    os.Getenv("LABOR_ALLOCATION_BASIS"),
+		RefreshRollup: useRollup,
+	}
+	if opts.LaborBasis == "" {
+		opts.LaborBasis = aggregates.AllocateByRevenue
+	}
+	if err := aggregates.ValidAllocationBasis(opts.LaborBasis); err != nil {
+		log.Fatalf("LABOR_ALLOCATION_BASIS: %v", err)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	switch {
+	case *importFlag != "":
+		jobID, err := uuid.Parse(*importFlag)
+		if err != nil {
+			log.Fatalf("Invalid -import job ID: %v", err)
+		}
+		err = aggregates.RefreshAggregatesForImport(ctx, pool, jobID, opts)
+		if err != nil {
+			log.Fatalf("Failed to refresh aggregates for import %s: %v", jobID, err)
+		}
+	case *from != "" || *to != "":
+		if *from == "" || *to == "" {
+			log.Fatal("-from and -to must be used together")
+		}
+		start, err := time.Parse("2006-01-02", *from)
+		if err != nil {
+			log.Fatalf("Invalid -from date: %v", err)
+		}
+		end, err := time.Parse("2006-01-02", *to)
+		if err != nil {
+			log.Fatalf("Invalid -to date: %v", err)
+		}
+
+		var locationID uuid.UUID
+		if *locationFlag != "" {
+			locationID, err = uuid.Parse(*locationFlag)
+			if err != nil {
+				log.Fatalf("Invalid -location ID: %v", err)
+			}
+		} else if err := pool.QueryRow(ctx, "SELECT id FROM locations LIMIT 1").Scan(&locationID); err != nil {
+			log.Fatalf("No location found: %v", err)
+		}
+
+		if err := aggregates.RefreshAggregatesForRange(ctx, pool, locationID, start, end, opts); err != nil {
+			log.Fatalf("Failed to refresh aggregates: %v", err)
+		}
+	default:
+		if err := aggregates.RefreshAggregates(ctx, pool, opts); err != nil {
+			log.Fatalf("Failed to refresh aggregates: %v", err)
+		}
+	}
+
+	log.Println("Aggregates refreshed successfully")
+}
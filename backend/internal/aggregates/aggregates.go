@@ -0,0 +1,490 @@
+package aggregates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+// RefreshOptions controls how aggregates are recomputed
+type RefreshOptions struct {
+	LaborBasis    string // revenue, covers or even
+	RefreshRollup bool   // refresh the kpi_daily_rollup view once the days are recomputed
+}
+
+// RefreshAggregates recalculates KPI aggregates from sales and payroll data
+// across the full sales history
+func RefreshAggregates(ctx context.Context, pool *pgxpool.Pool, opts RefreshOptions) error {
+	// Get location ID
+	var locationID uuid.UUID
+	err := pool.QueryRow(ctx, "SELECT id FROM locations LIMIT 1").Scan(&locationID)
+	if err != nil {
+		log.Println("No location found, skipping aggregate refresh")
+		return nil
+	}
+
+	// Get date range from sales data, in the location's local days
+	var minDate, maxDate time.Time
+	err = pool.QueryRow(ctx, `
+		SELECT COALESCE(MIN((s.occurred_at AT TIME ZONE l.timezone)::date), CURRENT_DATE),
+			COALESCE(MAX((s.occurred_at AT TIME ZONE l.timezone)::date), CURRENT_DATE)
+		FROM sales s
+		JOIN locations l ON l.id = s.location_id
+		WHERE s.location_id = $1
+	`, locationID).Scan(&minDate, &maxDate)
+	if err != nil {
+		return err
+	}
+
+	return RefreshAggregatesForRange(ctx, pool, locationID, minDate, maxDate, opts)
+}
+
+// RefreshAggregatesForRange recalculates KPI aggregates for one location over
+// the inclusive date range. Days that fail are logged and skipped.
+func RefreshAggregatesForRange(ctx context.Context, pool *pgxpool.Pool, locationID uuid.UUID, start, end time.Time, opts RefreshOptions) error {
+	if end.Before(start) {
+		return fmt.Errorf("refresh range end %s is before start %s", end.Format("2006-01-02"), start.Format("2006-01-02"))
+	}
+
+	// Process each day
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if err := refreshDayAggregates(ctx, pool, locationID, d, opts); err != nil {
+			log.Printf("Failed to refresh aggregates for %s: %v", d.Format("2006-01-02"), err)
+		}
+	}
+
+	if opts.RefreshRollup {
+		return kpi.NewStore(pool).RefreshRollup(ctx)
+	}
+	return nil
+}
+
+// ErrNoImportDates is returned when an import job touched no dated rows, so
+// there is nothing to refresh
+var ErrNoImportDates = errors.New("import has no dated rows to refresh")
+
+// RefreshAggregatesForImport recalculates only the days covered by an import
+// job's rows
+func RefreshAggregatesForImport(ctx context.Context, pool *pgxpool.Pool, jobID uuid.UUID, opts RefreshOptions) error {
+	locationID, start, end, err := importRange(ctx, pool, jobID)
+	if err != nil {
+		return err
+	}
+	return RefreshAggregatesForRange(ctx, pool, locationID, start, end, opts)
+}
+
+// importRange returns the location and days an import job touched. Sale
+// times in the file and aggregate days are both local to the location, so the
+// job's data range is exactly the days to refresh.
+func importRange(ctx context.Context, pool *pgxpool.Pool, jobID uuid.UUID) (uuid.UUID, time.Time, time.Time, error) {
+	var locationID uuid.UUID
+	var start, end *time.Time
+	err := pool.QueryRow(ctx, `
+		SELECT location_id, data_start, data_end FROM import_jobs WHERE id = $1
+	`, jobID).Scan(&locationID, &start, &end)
+	if err != nil {
+		return uuid.Nil, time.Time{}, time.Time{}, err
+	}
+	if start == nil || end == nil {
+		return uuid.Nil, time.Time{}, time.Time{}, ErrNoImportDates
+	}
+	return locationID, *start, *end, nil
+}
+
+// localDay matches sales whose occurred_at falls on the local day $1 in the
+// timezone $3, as a range so the occurred_at index can be used
+const localDay = `s.occurred_at >= $1::date::timestamp AT TIME ZONE $3::text
+	AND s.occurred_at < ($1::date + 1)::timestamp AT TIME ZONE $3::text`
+
+// refreshDayAggregates recomputes one location-day in a single transaction.
+// A transaction-scoped advisory lock on (location, date) serializes concurrent
+// refreshes of the same day, so the revenue upsert and the labor/net profit
+// update can never interleave with another refresh and leave torn rows.
+func refreshDayAggregates(ctx context.Context, pool *pgxpool.Pool, locationID uuid.UUID, date time.Time, opts RefreshOptions) error {
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		lockKey := locationID.String() + ":" + date.Format("2006-01-02")
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, lockKey); err != nil {
+			return err
+		}
+		return refreshDayAggregatesTx(ctx, tx, locationID, date, opts)
+	})
+}
+
+func refreshDayAggregatesTx(ctx context.Context, tx pgx.Tx, locationID uuid.UUID, date time.Time, opts RefreshOptions) error {
+	// Days run midnight to midnight in the restaurant's timezone, not the database's
+	var timezone string
+	if err := tx.QueryRow(ctx, `SELECT timezone FROM locations WHERE id = $1`, locationID).Scan(&timezone); err != nil {
+		return err
+	}
+
+	// The day-level row only exists to carry costs on days without sales;
+	// allocateDayCosts recreates it if it is still needed
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM kpi_aggregates
+		WHERE date = $1 AND location_id = $2 AND channel_id IS NULL AND daypart_id IS NULL
+	`, date, locationID); err != nil {
+		return err
+	}
+
+	// Calculate revenue and sales metrics by channel and daypart
+	query := `
+		INSERT INTO kpi_aggregates (date, location_id, channel_id, daypart_id, revenue, cogs, gross_margin, labor_cost, labor_pct, opex, net_profit, covers, avg_check, discounts, comps, freshness_timestamp)
+		SELECT
+			$1::date as date,
+			s.location_id,
+			s.channel_id,
+			s.daypart_id,
+			COALESCE(SUM(s.total), 0) as revenue,
+			COALESCE(SUM(lc.cogs), 0) as cogs,
+			COALESCE(SUM(s.total), 0) - COALESCE(SUM(lc.cogs), 0) as gross_margin,
+			0 as labor_cost,
+			0 as labor_pct,
+			0 as opex,
+			0 as net_profit,
+			COUNT(DISTINCT s.id) as covers,
+			CASE WHEN COUNT(DISTINCT s.id) > 0 THEN SUM(s.total) / COUNT(DISTINCT s.id) ELSE 0 END as avg_check,
+			COALESCE(SUM(s.discounts), 0) as discounts,
+			COALESCE(SUM(s.comps), 0) as comps,
+			NOW() as freshness_timestamp
+		FROM sales s
+		-- Line costs are summed per sale first so a sale's total is not
+		-- counted once per line
+		LEFT JOIN (
+			SELECT sl.sale_id, SUM(sl.quantity * COALESCE(mi.recipe_cost, 0)) as cogs
+			FROM sale_lines sl
+			LEFT JOIN menu_items mi ON sl.menu_item_id = mi.id
+			GROUP BY sl.sale_id
+		) lc ON lc.sale_id = s.id
+		WHERE s.location_id = $2 AND ` + localDay + `
+		GROUP BY s.location_id, s.channel_id, s.daypart_id
+		ON CONFLICT (date, location_id, channel_id, daypart_id)
+		DO UPDATE SET
+			revenue = EXCLUDED.revenue,
+			cogs = EXCLUDED.cogs,
+			gross_margin = EXCLUDED.gross_margin,
+			covers = EXCLUDED.covers,
+			avg_check = EXCLUDED.avg_check,
+			discounts = EXCLUDED.discounts,
+			comps = EXCLUDED.comps,
+			freshness_timestamp = NOW(),
+			updated_at = NOW()
+	`
+
+	_, err := tx.Exec(ctx, query, date, locationID, timezone)
+	if err != nil {
+		return err
+	}
+
+	// Net the day's refunds out of revenue before costs are allocated
+	if err := applyDayRefunds(ctx, tx, locationID, date); err != nil {
+		return err
+	}
+
+	// Delivery platform commissions come out of the margin of the row they were charged on
+	if err := applyDayCommissions(ctx, tx, locationID, date, timezone); err != nil {
+		return err
+	}
+
+	// Allocate the day's labor and operating expenses across channel/daypart rows
+	if err := allocateDayCosts(ctx, tx, locationID, date, opts.LaborBasis); err != nil {
+		return err
+	}
+
+	// Mark closed days so the KPI service can exclude them from averages
+	closedQuery := `
+		UPDATE kpi_aggregates k
+		SET is_closed = EXISTS(SELECT 1 FROM closed_days c WHERE c.location_id = k.location_id AND c.date = k.date)
+		WHERE k.date = $1 AND k.location_id = $2
+	`
+
+	if _, err = tx.Exec(ctx, closedQuery, date, locationID); err != nil {
+		return err
+	}
+
+	return refreshHourlyAggregates(ctx, tx, locationID, date, timezone)
+}
+
+// refreshHourlyAggregates rebuilds the day's hourly buckets for locations that
+// opted in. Sales are selected with the same day boundary as the daily rollup
+// so the hours always sum to the day, and bucketed by the hour on the
+// location's local clock.
+func refreshHourlyAggregates(ctx context.Context, tx pgx.Tx, locationID uuid.UUID, date time.Time, timezone string) error {
+	var enabled bool
+	if err := tx.QueryRow(ctx, `SELECT hourly_aggregates FROM locations WHERE id = $1`, locationID).Scan(&enabled); err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM kpi_hourly_aggregates WHERE date = $1 AND location_id = $2`, date, locationID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO kpi_hourly_aggregates (location_id, date, hour, revenue, covers, discounts, comps, freshness_timestamp)
+		SELECT
+			s.location_id,
+			$1::date,
+			EXTRACT(HOUR FROM s.occurred_at AT TIME ZONE $3::text)::int as hour,
+			COALESCE(SUM(s.total), 0),
+			COUNT(*),
+			COALESCE(SUM(s.discounts), 0),
+			COALESCE(SUM(s.comps), 0),
+			NOW()
+		FROM sales s
+		WHERE s.location_id = $2 AND `+localDay+`
+		GROUP BY s.location_id, hour
+	`, date, locationID, timezone)
+	return err
+}
+
+// applyDayRefunds nets refunds dated on the day against the day's aggregate
+// rows. A refund matched to a sale lands on that sale's channel/daypart row;
+// unmatched refunds, and matched ones whose row has no sales that day, are
+// spread across the day's rows by revenue.
+func applyDayRefunds(ctx context.Context, tx pgx.Tx, locationID uuid.UUID, date time.Time) error {
+	type rowKey struct{ channelID, daypartID uuid.UUID }
+
+	rows, err := tx.Query(ctx, `
+		SELECT s.channel_id, s.daypart_id, COALESCE(SUM(r.amount), 0)
+		FROM refunds r
+		JOIN sales s ON s.id = r.sale_id
+		WHERE r.refund_date = $1 AND r.location_id = $2
+		GROUP BY s.channel_id, s.daypart_id
+	`, date, locationID)
+	if err != nil {
+		return err
+	}
+	matched := make(map[rowKey]float64)
+	for rows.Next() {
+		var k rowKey
+		var amount float64
+		if err := rows.Scan(&k.channelID, &k.daypartID, &amount); err != nil {
+			rows.Close()
+			return err
+		}
+		matched[k] = amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var unmatched float64
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM refunds
+		WHERE refund_date = $1 AND location_id = $2 AND sale_id IS NULL
+	`, date, locationID).Scan(&unmatched)
+	if err != nil {
+		return err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT id, channel_id, daypart_id, revenue, covers
+		FROM kpi_aggregates
+		WHERE date = $1 AND location_id = $2
+		ORDER BY id
+	`, date, locationID)
+	if err != nil {
+		return err
+	}
+	type aggRow struct {
+		id      uuid.UUID
+		key     rowKey
+		revenue float64
+		covers  int
+	}
+	var aggs []aggRow
+	var weights []float64
+	for rows.Next() {
+		var a aggRow
+		if err := rows.Scan(&a.id, &a.key.channelID, &a.key.daypartID, &a.revenue, &a.covers); err != nil {
+			rows.Close()
+			return err
+		}
+		aggs = append(aggs, a)
+		weights = append(weights, a.revenue)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	present := make(map[rowKey]bool, len(aggs))
+	for _, a := range aggs {
+		present[a.key] = true
+	}
+	for k, amount := range matched {
+		if !present[k] {
+			unmatched += amount
+		}
+	}
+
+	spread := allocate(unmatched, weights)
+
+	batch := &pgx.Batch{}
+	for i, a := range aggs {
+		refunds := matched[a.key] + spread[i]
+		net := a.revenue - refunds
+		avgCheck := 0.0
+		if a.covers > 0 {
+			avgCheck = net / float64(a.covers)
+		}
+		batch.Queue(`
+			UPDATE kpi_aggregates
+			SET refunds = $1, revenue = $2, gross_margin = gross_margin - $1, avg_check = $3, updated_at = NOW()
+			WHERE id = $4
+		`, refunds, net, avgCheck, a.id)
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// applyDayCommissions totals the platform fees on the day's sales for each
+// channel/daypart row and nets them out of the row's gross margin. Revenue
+// stays gross; net-of-commission revenue is revenue minus commissions.
+func applyDayCommissions(ctx context.Context, tx pgx.Tx, locationID uuid.UUID, date time.Time, timezone string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE kpi_aggregates k
+		SET commissions = c.fees, gross_margin = k.gross_margin - c.fees, updated_at = NOW()
+		FROM (
+			SELECT s.channel_id, s.daypart_id, SUM(s.platform_fee) as fees
+			FROM sales s
+			WHERE s.location_id = $2 AND `+localDay+`
+			GROUP BY s.channel_id, s.daypart_id
+		) c
+		WHERE k.date = $1 AND k.location_id = $2
+		AND k.channel_id IS NOT DISTINCT FROM c.channel_id
+		AND k.daypart_id IS NOT DISTINCT FROM c.daypart_id
+	`, date, locationID, timezone)
+	return err
+}
+
+// allocateDayCosts spreads the day's share of payroll and the day's operating
+// expenses across the day's aggregate rows using the given basis, so channel
+// and daypart rows carry a realistic labor cost and opex and the rows sum
+// exactly to the day's totals. Labor and expenses on a day without sales
+// (a closed day, say) go on a day-level row with no channel or daypart, which
+// counts toward day totals but not toward any channel or daypart breakdown.
+//
+// By revenue, a row with revenue but no covers still takes its full share;
+// by covers, it takes none and the rows with covers absorb it.
+func allocateDayCosts(ctx context.Context, tx pgx.Tx, locationID uuid.UUID, date time.Time, basis string) error {
+	// Payroll periods are spread evenly over the days they cover
+	var dayLabor float64
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(labor_cost / (end_date - start_date + 1)), 0)
+		FROM payroll_periods
+		WHERE start_date <= $1 AND end_date >= $1 AND location_id = $2
+	`, date, locationID).Scan(&dayLabor)
+	if err != nil {
+		return err
+	}
+
+	var dayOpex float64
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM operating_expenses
+		WHERE expense_date = $1 AND location_id = $2
+	`, date, locationID).Scan(&dayOpex)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, revenue, covers, gross_margin
+		FROM kpi_aggregates
+		WHERE date = $1 AND location_id = $2
+		ORDER BY id
+	`, date, locationID)
+	if err != nil {
+		return err
+	}
+
+	type aggRow struct {
+		id          uuid.UUID
+		revenue     float64
+		covers      int
+		grossMargin float64
+	}
+	var aggs []aggRow
+	var dayRevenue float64
+	for rows.Next() {
+		var a aggRow
+		if err := rows.Scan(&a.id, &a.revenue, &a.covers, &a.grossMargin); err != nil {
+			rows.Close()
+			return err
+		}
+		aggs = append(aggs, a)
+		if a.revenue > 0 {
+			dayRevenue += a.revenue
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(aggs) == 0 {
+		if dayLabor == 0 && dayOpex == 0 {
+			return nil
+		}
+		// NULL channel and daypart never match the unique key, so this is a
+		// plain insert; the refresh deleted any earlier day-level row
+		labor, opex := allocate(dayLabor, []float64{1})[0], allocate(dayOpex, []float64{1})[0]
+		_, err := tx.Exec(ctx, `
+			INSERT INTO kpi_aggregates (date, location_id, channel_id, daypart_id, labor_cost, opex, net_profit, freshness_timestamp)
+			VALUES ($1, $2, NULL, NULL, $3, $4, $5, NOW())
+		`, date, locationID, labor, opex, -labor-opex)
+		return err
+	}
+
+	// A day with no revenue (e.g. all comps) falls back to covers; allocate
+	// splits evenly if there are no covers either
+	if basis == AllocateByRevenue && dayRevenue <= 0 {
+		basis = AllocateByCovers
+	}
+
+	weights := make([]float64, len(aggs))
+	for i, a := range aggs {
+		switch basis {
+		case AllocateByCovers:
+			weights[i] = float64(a.covers)
+		case AllocateEvenly:
+			weights[i] = 1
+		default:
+			weights[i] = a.revenue
+		}
+	}
+
+	laborShares := allocate(dayLabor, weights)
+	opexShares := allocate(dayOpex, weights)
+
+	batch := &pgx.Batch{}
+	for i, a := range aggs {
+		labor, opex := laborShares[i], opexShares[i]
+		laborPct := 0.0
+		if a.revenue > 0 {
+			// labor_pct is DECIMAL(5,2); clamp rather than fail the whole batch
+			laborPct = math.Min(labor/a.revenue*100, 999.99)
+		}
+		batch.Queue(`
+			UPDATE kpi_aggregates
+			SET labor_cost = $1, labor_pct = $2, opex = $3, net_profit = $4, updated_at = NOW()
+			WHERE id = $5
+		`, labor, laborPct, opex, a.grossMargin-labor-opex, a.id)
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
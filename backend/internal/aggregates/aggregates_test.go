@@ -0,0 +1,827 @@
+package aggregates
+
+import (
+	"context"
+	"math"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+// testPool connects to the migrated database named by TEST_DATABASE_URL,
+// skipping the test when it is unset
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// queryIDs returns the ids a query selects
+func queryIDs(t *testing.T, pool *pgxpool.Pool, query string) []uuid.UUID {
+	t.Helper()
+	rows, err := pool.Query(context.Background(), query)
+	if err != nil {
+		t.Fatalf("query ids: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// testLocation creates a UTC location and removes it, with everything the
+// tests seed for it, when the test ends
+func testLocation(t *testing.T, pool *pgxpool.Pool, name string) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	var locationID uuid.UUID
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO locations (name, timezone) VALUES ($1, 'UTC') RETURNING id
+	`, name).Scan(&locationID); err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM kpi_snapshots WHERE location_id = $1`,
+			`DELETE FROM kpi_hourly_aggregates WHERE location_id = $1`,
+			`DELETE FROM kpi_aggregates WHERE location_id = $1`,
+			`DELETE FROM refunds WHERE location_id = $1`,
+			`DELETE FROM sales WHERE location_id = $1`,
+			`DELETE FROM payroll_periods WHERE location_id = $1`,
+			`DELETE FROM operating_expenses WHERE location_id = $1`,
+			`DELETE FROM closed_days WHERE location_id = $1`,
+			`DELETE FROM locations WHERE id = $1`,
+		} {
+			if _, err := pool.Exec(ctx, q, locationID); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+	})
+	return locationID
+}
+
+type aggSnapshot struct {
+	channelID   uuid.UUID
+	daypartID   uuid.UUID
+	revenue     float64
+	grossMargin float64
+	laborCost   float64
+	laborPct    float64
+	opex        float64
+	netProfit   float64
+	covers      int
+}
+
+func snapshotDay(t *testing.T, pool *pgxpool.Pool, locationID uuid.UUID, date time.Time) []aggSnapshot {
+	t.Helper()
+	rows, err := pool.Query(context.Background(), `
+		SELECT channel_id, daypart_id, revenue, gross_margin, labor_cost, labor_pct, opex, net_profit, covers
+		FROM kpi_aggregates
+		WHERE date = $1 AND location_id = $2
+		ORDER BY channel_id, daypart_id
+	`, date, locationID)
+	if err != nil {
+		t.Fatalf("query aggregates: %v", err)
+	}
+	defer rows.Close()
+
+	var out []aggSnapshot
+	for rows.Next() {
+		var s aggSnapshot
+		if err := rows.Scan(&s.channelID, &s.daypartID, &s.revenue, &s.grossMargin, &s.laborCost, &s.laborPct, &s.opex, &s.netProfit, &s.covers); err != nil {
+			t.Fatalf("scan aggregate: %v", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read aggregates: %v", err)
+	}
+	return out
+}
+
+// TestRefreshDayAggregatesConcurrent runs overlapping refreshes of the same
+// location-day and checks they leave exactly what a single refresh does
+func TestRefreshDayAggregatesConcurrent(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	date := time.Date(2001, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	locationID := testLocation(t, pool, "Aggregate refresh test")
+
+	channelIDs := queryIDs(t, pool, `SELECT id FROM service_channels ORDER BY id LIMIT 2`)
+	daypartIDs := queryIDs(t, pool, `SELECT id FROM dayparts ORDER BY id LIMIT 2`)
+	if len(channelIDs) == 0 || len(daypartIDs) == 0 {
+		t.Fatal("service channels and dayparts must be seeded")
+	}
+
+	// Spread sales over several channel/daypart rows so labor is allocated
+	for i, total := range []float64{120, 80.5, 45.25, 60} {
+		channelID := channelIDs[i%len(channelIDs)]
+		daypartID := daypartIDs[(i/len(channelIDs))%len(daypartIDs)]
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total)
+			VALUES ($1, $2, $3, $4, $5, $5)
+		`, date.Add(time.Duration(11+i)*time.Hour), locationID, channelID, daypartID, total); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+	}
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO payroll_periods (start_date, end_date, labor_cost, hours, location_id)
+		VALUES ($1, $1, 90, 6, $2)
+	`, date, locationID); err != nil {
+		t.Fatalf("insert payroll: %v", err)
+	}
+
+	opts := RefreshOptions{LaborBasis: "revenue"}
+	if err := refreshDayAggregates(ctx, pool, locationID, date, opts); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	want := snapshotDay(t, pool, locationID, date)
+	if len(want) == 0 {
+		t.Fatal("refresh wrote no aggregate rows")
+	}
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- refreshDayAggregates(ctx, pool, locationID, date, opts)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent refresh: %v", err)
+		}
+	}
+
+	got := snapshotDay(t, pool, locationID, date)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("after concurrent refreshes aggregates = %+v, want %+v", got, want)
+	}
+}
+
+// TestAllocateDayCostsReconciles checks the day's rows carry exactly the
+// location's share of payroll, including on a day without sales, and that
+// another location's payroll is not charged
+func TestAllocateDayCostsReconciles(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	saleDay := time.Date(2001, 4, 2, 0, 0, 0, 0, time.UTC)
+	emptyDay := saleDay.AddDate(0, 0, 1)
+
+	locationID := testLocation(t, pool, "Labor reconciliation test")
+	otherID := testLocation(t, pool, "Labor reconciliation other")
+
+	channelIDs := queryIDs(t, pool, `SELECT id FROM service_channels ORDER BY id LIMIT 2`)
+	daypartIDs := queryIDs(t, pool, `SELECT id FROM dayparts ORDER BY id LIMIT 1`)
+	if len(channelIDs) == 0 || len(daypartIDs) == 0 {
+		t.Fatal("service channels and dayparts must be seeded")
+	}
+	for i, total := range []float64{70, 33.33, 12.5} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total)
+			VALUES ($1, $2, $3, $4, $5, $5)
+		`, saleDay.Add(time.Duration(12+i)*time.Hour), locationID, channelIDs[i%len(channelIDs)], daypartIDs[0], total); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+	}
+
+	// The other location's payroll covers the same days and must not be
+	// charged here
+	for _, p := range []struct {
+		locationID uuid.UUID
+		laborCost  float64
+	}{{locationID, 200.02}, {otherID, 5000}} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO payroll_periods (start_date, end_date, labor_cost, hours, location_id)
+			VALUES ($1, $2, $3, 16, $4)
+		`, saleDay, emptyDay, p.laborCost, p.locationID); err != nil {
+			t.Fatalf("insert payroll: %v", err)
+		}
+	}
+
+	opts := RefreshOptions{LaborBasis: "revenue"}
+	for _, date := range []time.Time{saleDay, emptyDay} {
+		if err := refreshDayAggregates(ctx, pool, locationID, date, opts); err != nil {
+			t.Fatalf("refresh %s: %v", date.Format("2006-01-02"), err)
+		}
+	}
+
+	sumLabor := func(rows []aggSnapshot) float64 {
+		var cents int64
+		for _, r := range rows {
+			cents += int64(math.Round(r.laborCost * 100))
+		}
+		return float64(cents) / 100
+	}
+
+	saleRows := snapshotDay(t, pool, locationID, saleDay)
+	if len(saleRows) < 2 {
+		t.Fatalf("sale day has %d rows, want one per channel", len(saleRows))
+	}
+	if got := sumLabor(saleRows); got != 100.01 {
+		t.Errorf("sale day labor = %.2f, want 100.01", got)
+	}
+
+	emptyRows := snapshotDay(t, pool, locationID, emptyDay)
+	if len(emptyRows) != 1 || emptyRows[0].channelID != uuid.Nil || emptyRows[0].daypartID != uuid.Nil {
+		t.Fatalf("day without sales = %+v, want a single day-level row", emptyRows)
+	}
+	if got := emptyRows[0]; got.laborCost != 100.01 || got.netProfit != -100.01 {
+		t.Errorf("day-level row labor %.2f, net profit %.2f; want 100.01 and -100.01", got.laborCost, got.netProfit)
+	}
+
+	// Once the day has sales the day-level row gives way to real rows
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total)
+		VALUES ($1, $2, $3, $4, 40, 40)
+	`, emptyDay.Add(13*time.Hour), locationID, channelIDs[0], daypartIDs[0]); err != nil {
+		t.Fatalf("insert sale: %v", err)
+	}
+	if err := refreshDayAggregates(ctx, pool, locationID, emptyDay, opts); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	emptyRows = snapshotDay(t, pool, locationID, emptyDay)
+	if len(emptyRows) != 1 || emptyRows[0].channelID == uuid.Nil {
+		t.Fatalf("after a sale rows = %+v, want only the channel row", emptyRows)
+	}
+	if got := sumLabor(emptyRows); got != 100.01 {
+		t.Errorf("labor after a sale = %.2f, want 100.01", got)
+	}
+}
+
+// An expense dated on a day the restaurant was closed still reaches the day's
+// opex and net profit through the day-level row
+func TestRefreshClosedDayExpense(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	date := time.Date(2001, 5, 7, 0, 0, 0, 0, time.UTC)
+	locationID := testLocation(t, pool, "Closed day expense test")
+
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO closed_days (location_id, date, reason) VALUES ($1, $2, 'Renovation')
+	`, locationID, date); err != nil {
+		t.Fatalf("insert closed day: %v", err)
+	}
+	for _, amount := range []float64{1200, 310.45} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO operating_expenses (location_id, expense_date, category, amount) VALUES ($1, $2, 'Rent', $3)
+		`, locationID, date, amount); err != nil {
+			t.Fatalf("insert expense: %v", err)
+		}
+	}
+
+	if err := refreshDayAggregates(ctx, pool, locationID, date, RefreshOptions{LaborBasis: "revenue"}); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	var opex, netProfit float64
+	var isClosed bool
+	var n int
+	if err := pool.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(opex), 0), COALESCE(SUM(net_profit), 0), BOOL_AND(is_closed)
+		FROM kpi_aggregates
+		WHERE date = $1 AND location_id = $2 AND channel_id IS NULL AND daypart_id IS NULL
+	`, date, locationID).Scan(&n, &opex, &netProfit, &isClosed); err != nil {
+		t.Fatalf("query day-level row: %v", err)
+	}
+	if n != 1 || opex != 1510.45 || netProfit != -1510.45 || !isClosed {
+		t.Errorf("got %d day-level rows, opex %.2f, net profit %.2f, closed %v; want 1, 1510.45, -1510.45, true", n, opex, netProfit, isClosed)
+	}
+
+	// Refreshing again replaces the row rather than adding another
+	if err := refreshDayAggregates(ctx, pool, locationID, date, RefreshOptions{LaborBasis: "revenue"}); err != nil {
+		t.Fatalf("second refresh: %v", err)
+	}
+	if rows := snapshotDay(t, pool, locationID, date); len(rows) != 1 || rows[0].opex != 1510.45 {
+		t.Errorf("after a second refresh rows = %+v, want one with opex 1510.45", rows)
+	}
+}
+
+// Channel and daypart breakdowns carry the allocated labor and opex, so their
+// net profit is complete and sums to the day's
+func TestBreakdownsCarryAllocatedCosts(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	date := time.Date(2001, 7, 9, 0, 0, 0, 0, time.UTC)
+	locationID := testLocation(t, pool, "Allocated breakdown test")
+
+	channelIDs := queryIDs(t, pool, `SELECT id FROM service_channels ORDER BY id LIMIT 2`)
+	daypartIDs := queryIDs(t, pool, `SELECT id FROM dayparts ORDER BY id LIMIT 1`)
+	if len(channelIDs) < 2 || len(daypartIDs) == 0 {
+		t.Fatal("two service channels and a daypart must be seeded")
+	}
+	for i, total := range []float64{300, 100} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total)
+			VALUES ($1, $2, $3, $4, $5, $5)
+		`, date.Add(12*time.Hour), locationID, channelIDs[i], daypartIDs[0], total); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+	}
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO payroll_periods (start_date, end_date, labor_cost, hours, location_id) VALUES ($1, $1, 140, 8, $2)
+	`, date, locationID); err != nil {
+		t.Fatalf("insert payroll: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO operating_expenses (location_id, expense_date, category, amount) VALUES ($1, $2, 'Utilities', 60)
+	`, locationID, date); err != nil {
+		t.Fatalf("insert expense: %v", err)
+	}
+
+	if err := refreshDayAggregates(ctx, pool, locationID, date, RefreshOptions{LaborBasis: "revenue"}); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	store := kpi.NewStore(pool)
+	byChannel, err := store.GetByChannel(ctx, locationID, date, date)
+	if err != nil {
+		t.Fatalf("GetByChannel: %v", err)
+	}
+	if len(byChannel) != 2 {
+		t.Fatalf("GetByChannel returned %d channels, want 2", len(byChannel))
+	}
+	var labor, opex, net float64
+	for _, c := range byChannel {
+		// By revenue the 300 channel takes three quarters of each cost
+		wantLabor, wantOpex := 35.0, 15.0
+		if c.Revenue == 300 {
+			wantLabor, wantOpex = 105, 45
+		}
+		if c.LaborCost != wantLabor || c.Opex != wantOpex || c.NetProfit != c.GrossMargin-wantLabor-wantOpex {
+			t.Errorf("%s: labor %.2f, opex %.2f, net profit %.2f; want %.2f, %.2f, %.2f",
+				c.Label, c.LaborCost, c.Opex, c.NetProfit, wantLabor, wantOpex, c.GrossMargin-wantLabor-wantOpex)
+		}
+		labor += c.LaborCost
+		opex += c.Opex
+		net += c.NetProfit
+	}
+	if labor != 140 || opex != 60 || net != 200 {
+		t.Errorf("channels sum to labor %.2f, opex %.2f, net profit %.2f; want 140, 60, 200", labor, opex, net)
+	}
+
+	byDaypart, err := store.GetByDaypart(ctx, locationID, date, date)
+	if err != nil {
+		t.Fatalf("GetByDaypart: %v", err)
+	}
+	if len(byDaypart) != 1 || byDaypart[0].LaborCost != 140 || byDaypart[0].Opex != 60 || byDaypart[0].NetProfit != 200 {
+		t.Errorf("GetByDaypart = %+v, want one daypart with labor 140, opex 60, net profit 200", byDaypart)
+	}
+}
+
+// TestHourlyBucketsSumToDay checks an opted-in location's hourly buckets add
+// up to its daily aggregate, with sales near local midnight landing on the
+// local day and hour
+func TestHourlyBucketsSumToDay(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	date := time.Date(2001, 5, 7, 0, 0, 0, 0, time.UTC)
+
+	locationID := testLocation(t, pool, "Hourly rollup test")
+	if _, err := pool.Exec(ctx, `
+		UPDATE locations SET timezone = 'Australia/Brisbane', hourly_aggregates = true WHERE id = $1
+	`, locationID); err != nil {
+		t.Fatalf("opt in: %v", err)
+	}
+
+	channelIDs := queryIDs(t, pool, `SELECT id FROM service_channels ORDER BY id LIMIT 2`)
+	daypartIDs := queryIDs(t, pool, `SELECT id FROM dayparts ORDER BY id LIMIT 1`)
+	if len(channelIDs) == 0 || len(daypartIDs) == 0 {
+		t.Fatal("service channels and dayparts must be seeded")
+	}
+
+	brisbane, err := time.LoadLocation("Australia/Brisbane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := func(day, hour, minute int) time.Time {
+		return time.Date(2001, 5, day, hour, minute, 0, 0, brisbane)
+	}
+	for i, sale := range []struct {
+		at    time.Time
+		total float64
+	}{
+		{at: local(7, 0, 5), total: 12.5}, // just after local midnight, 14:05 UTC the day before
+		{at: local(7, 12, 15), total: 40}, // lunch
+		{at: local(7, 12, 45), total: 35.25},
+		{at: local(7, 19, 30), total: 88.1},
+		{at: local(7, 23, 55), total: 20},  // just before local midnight
+		{at: local(8, 0, 10), total: 999},  // the next local day
+		{at: local(6, 23, 50), total: 999}, // the previous local day
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total)
+			VALUES ($1, $2, $3, $4, $5, $5)
+		`, sale.at, locationID, channelIDs[i%len(channelIDs)], daypartIDs[0], sale.total); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+	}
+
+	if err := refreshDayAggregates(ctx, pool, locationID, date, RefreshOptions{LaborBasis: "revenue"}); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	var dayRevenue float64
+	var dayCovers int
+	if err := pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(revenue), 0), COALESCE(SUM(covers), 0)::int FROM kpi_aggregates WHERE date = $1 AND location_id = $2
+	`, date, locationID).Scan(&dayRevenue, &dayCovers); err != nil {
+		t.Fatalf("query daily total: %v", err)
+	}
+	if dayRevenue != 195.85 || dayCovers != 5 {
+		t.Fatalf("daily revenue = %v over %d covers, want 195.85 over 5", dayRevenue, dayCovers)
+	}
+
+	hourly, err := kpi.NewService(kpi.NewStore(pool)).GetHourly(ctx, locationID, date)
+	if err != nil {
+		t.Fatalf("GetHourly() error = %v", err)
+	}
+	if hourly.Revenue != dayRevenue || hourly.Covers != dayCovers {
+		t.Errorf("hourly total = %v over %d covers, want the daily %v over %d", hourly.Revenue, hourly.Covers, dayRevenue, dayCovers)
+	}
+
+	var sum float64
+	for _, h := range hourly.Hours {
+		sum += h.Revenue
+	}
+	if math.Abs(sum-dayRevenue) > 0.005 {
+		t.Errorf("hours sum to %v, want %v", sum, dayRevenue)
+	}
+
+	want := map[int]float64{0: 12.5, 12: 75.25, 19: 88.1, 23: 20}
+	for _, h := range hourly.Hours {
+		if h.Revenue != want[h.Hour] {
+			t.Errorf("hour %d revenue = %v, want %v", h.Hour, h.Revenue, want[h.Hour])
+		}
+	}
+}
+
+// TestSnapshotDriftAfterReimport freezes a month, imports more sales into it,
+// and checks the comparison reports the change against the snapshot
+func TestSnapshotDriftAfterReimport(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	start := time.Date(2001, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2001, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	var userID uuid.UUID
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash) VALUES ($1, 'x') RETURNING id
+	`, "snapshot-"+uuid.NewString()[:8]+"@example.com").Scan(&userID); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+	})
+	locationID := testLocation(t, pool, "Snapshot drift test")
+
+	channelIDs := queryIDs(t, pool, `SELECT id FROM service_channels ORDER BY id LIMIT 1`)
+	daypartIDs := queryIDs(t, pool, `SELECT id FROM dayparts ORDER BY id LIMIT 1`)
+	if len(channelIDs) == 0 || len(daypartIDs) == 0 {
+		t.Fatal("service channels and dayparts must be seeded")
+	}
+	importSales := func(day time.Time, totals ...float64) {
+		t.Helper()
+		for i, total := range totals {
+			if _, err := pool.Exec(ctx, `
+				INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total)
+				VALUES ($1, $2, $3, $4, $5, $5)
+			`, day.Add(time.Duration(12+i)*time.Hour), locationID, channelIDs[0], daypartIDs[0], total); err != nil {
+				t.Fatalf("insert sale: %v", err)
+			}
+		}
+		if err := refreshDayAggregates(ctx, pool, locationID, day, RefreshOptions{LaborBasis: "revenue"}); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+	}
+
+	importSales(start.AddDate(0, 0, 4), 100, 50)
+	importSales(start.AddDate(0, 0, 19), 250)
+
+	svc := kpi.NewService(kpi.NewStore(pool))
+	snap, err := svc.CreateSnapshot(ctx, locationID, userID, start, end, "June close")
+	if err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+	if snap.Totals.Revenue != 400 || snap.Totals.Covers != 3 {
+		t.Fatalf("snapshot totals = %v over %d covers, want 400 over 3", snap.Totals.Revenue, snap.Totals.Covers)
+	}
+
+	stored, err := svc.GetSnapshot(ctx, snap.ID)
+	if err != nil {
+		t.Fatalf("GetSnapshot() error = %v", err)
+	}
+	unchanged, err := svc.CompareSnapshot(ctx, stored)
+	if err != nil {
+		t.Fatalf("CompareSnapshot() error = %v", err)
+	}
+	if unchanged.HasDrift {
+		t.Errorf("comparison before re-import reports drift: %+v", unchanged.Drift)
+	}
+
+	// A late re-import adds a sale to a day inside the closed month
+	importSales(start.AddDate(0, 0, 19), 250, 40)
+
+	comparison, err := svc.CompareSnapshot(ctx, stored)
+	if err != nil {
+		t.Fatalf("CompareSnapshot() error = %v", err)
+	}
+	if !comparison.HasDrift {
+		t.Fatal("comparison after re-import reports no drift")
+	}
+	drift := map[string]kpi.MetricDrift{}
+	for _, d := range comparison.Drift {
+		drift[d.Metric] = d
+	}
+	if d := drift["revenue"]; d.Snapshot != 400 || d.Current != 690 || d.Delta != 290 || d.DeltaPct == nil || *d.DeltaPct != 72.5 {
+		t.Errorf("revenue drift = %+v, want 400 -> 690 (+290, 72.5%%)", d)
+	}
+	if d := drift["covers"]; d.Snapshot != 3 || d.Current != 5 || d.Delta != 2 {
+		t.Errorf("covers drift = %+v, want 3 -> 5", d)
+	}
+	if d := drift["cogs"]; d.Delta != 0 || d.DeltaPct != nil {
+		t.Errorf("cogs drift = %+v, want unchanged at zero", d)
+	}
+}
+
+// TestRefundsNetRevenue checks a refund matched to a sale comes off that
+// sale's row, and an unmatched one is spread across the day's rows by revenue
+func TestRefundsNetRevenue(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	date := time.Date(2001, 7, 9, 0, 0, 0, 0, time.UTC)
+
+	locationID := testLocation(t, pool, "Refund netting test")
+
+	channelIDs := queryIDs(t, pool, `SELECT id FROM service_channels ORDER BY id LIMIT 2`)
+	daypartIDs := queryIDs(t, pool, `SELECT id FROM dayparts ORDER BY id LIMIT 1`)
+	if len(channelIDs) < 2 || len(daypartIDs) == 0 {
+		t.Fatal("two service channels and a daypart must be seeded")
+	}
+
+	saleIDs := make([]uuid.UUID, 2)
+	for i, total := range []float64{300, 100} {
+		if err := pool.QueryRow(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total, external_id)
+			VALUES ($1, $2, $3, $4, $5, $5, $6)
+			RETURNING id
+		`, date.Add(12*time.Hour), locationID, channelIDs[i], daypartIDs[0], total, "ORD-"+uuid.NewString()[:8]).Scan(&saleIDs[i]); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+	}
+
+	for _, r := range []struct {
+		saleID *uuid.UUID
+		amount float64
+	}{
+		{saleID: &saleIDs[1], amount: 30}, // matched to the second channel's sale
+		{amount: 20},                      // unmatched, split 3:1 by revenue
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO refunds (location_id, refund_date, sale_id, amount, import_source, source_id)
+			VALUES ($1, $2, $3, $4, 'test', $5)
+		`, locationID, date, r.saleID, r.amount, uuid.NewString()); err != nil {
+			t.Fatalf("insert refund: %v", err)
+		}
+	}
+
+	if err := refreshDayAggregates(ctx, pool, locationID, date, RefreshOptions{LaborBasis: "revenue"}); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	got := map[uuid.UUID][2]float64{}
+	rows, err := pool.Query(ctx, `
+		SELECT channel_id, revenue, refunds FROM kpi_aggregates WHERE date = $1 AND location_id = $2
+	`, date, locationID)
+	if err != nil {
+		t.Fatalf("query aggregates: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var channelID uuid.UUID
+		var revenue, refunds float64
+		if err := rows.Scan(&channelID, &revenue, &refunds); err != nil {
+			t.Fatalf("scan aggregate: %v", err)
+		}
+		got[channelID] = [2]float64{revenue, refunds}
+	}
+
+	want := map[uuid.UUID][2]float64{
+		channelIDs[0]: {285, 15}, // 300 less three quarters of the unmatched 20
+		channelIDs[1]: {65, 35},  // 100 less the matched 30 and a quarter of the unmatched 20
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("revenue, refunds by channel = %v, want %v", got, want)
+	}
+}
+
+// TestCommissionReducesChannelNetRevenue checks delivery platform fees come
+// off the channel they were charged on, leaving gross revenue alone
+func TestCommissionReducesChannelNetRevenue(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	date := time.Date(2001, 8, 14, 0, 0, 0, 0, time.UTC)
+	locationID := testLocation(t, pool, "Platform fee test")
+
+	channelIDs := queryIDs(t, pool, `SELECT id FROM service_channels ORDER BY id LIMIT 2`)
+	daypartIDs := queryIDs(t, pool, `SELECT id FROM dayparts ORDER BY id LIMIT 1`)
+	if len(channelIDs) < 2 || len(daypartIDs) == 0 {
+		t.Fatal("two service channels and a daypart must be seeded")
+	}
+	dineIn, delivery := channelIDs[0], channelIDs[1]
+
+	for _, s := range []struct {
+		channelID uuid.UUID
+		total     float64
+		fee       float64
+	}{
+		{channelID: dineIn, total: 200},
+		{channelID: delivery, total: 120, fee: 36},
+		{channelID: delivery, total: 80, fee: 24.5},
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total, platform_fee)
+			VALUES ($1, $2, $3, $4, $5, $5, $6)
+		`, date.Add(12*time.Hour), locationID, s.channelID, daypartIDs[0], s.total, s.fee); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+	}
+
+	if err := refreshDayAggregates(ctx, pool, locationID, date, RefreshOptions{LaborBasis: "revenue"}); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	byChannel, err := kpi.NewStore(pool).GetByChannel(ctx, locationID, date, date)
+	if err != nil {
+		t.Fatalf("GetByChannel: %v", err)
+	}
+	var codes []string
+	if err := pool.QueryRow(ctx, `
+		SELECT ARRAY[(SELECT code FROM service_channels WHERE id = $1), (SELECT code FROM service_channels WHERE id = $2)]
+	`, dineIn, delivery).Scan(&codes); err != nil {
+		t.Fatalf("channel codes: %v", err)
+	}
+
+	want := map[string][3]float64{ // revenue, commissions, net revenue
+		codes[0]: {200, 0, 200},
+		codes[1]: {200, 60.5, 139.5},
+	}
+	got := map[string][3]float64{}
+	margins := map[string]float64{}
+	for _, c := range byChannel {
+		got[c.Label] = [3]float64{c.Revenue, c.Commissions, c.NetRevenue}
+		margins[c.Label] = c.GrossMargin
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("revenue, commissions, net revenue by channel = %v, want %v", got, want)
+	}
+	// Both channels have no COGS, so the fee is the whole margin gap
+	if gap := margins[codes[0]] - margins[codes[1]]; gap != 60.5 {
+		t.Errorf("gross margin gap = %v, want the delivery channel 60.50 lower", gap)
+	}
+}
+
+// TestLocalDayBoundaries checks sales late in the evening land on the
+// location's local day rather than the UTC day they fall on
+func TestLocalDayBoundaries(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	day := time.Date(2001, 9, 4, 0, 0, 0, 0, time.UTC)
+	nextDay := day.AddDate(0, 0, 1)
+
+	locationID := testLocation(t, pool, "Local day test")
+	if _, err := pool.Exec(ctx, `UPDATE locations SET timezone = 'America/Los_Angeles' WHERE id = $1`, locationID); err != nil {
+		t.Fatalf("set timezone: %v", err)
+	}
+
+	channelIDs := queryIDs(t, pool, `SELECT id FROM service_channels ORDER BY id LIMIT 1`)
+	daypartIDs := queryIDs(t, pool, `SELECT id FROM dayparts ORDER BY id LIMIT 1`)
+	if len(channelIDs) == 0 || len(daypartIDs) == 0 {
+		t.Fatal("service channels and dayparts must be seeded")
+	}
+
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sale := range []struct {
+		at    time.Time
+		total float64
+	}{
+		{at: time.Date(2001, 9, 4, 12, 0, 0, 0, losAngeles), total: 50},
+		{at: time.Date(2001, 9, 4, 23, 30, 0, 0, losAngeles), total: 25}, // 06:30 UTC on the 5th
+		{at: time.Date(2001, 9, 5, 0, 15, 0, 0, losAngeles), total: 10},
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total)
+			VALUES ($1, $2, $3, $4, $5, $5)
+		`, sale.at, locationID, channelIDs[0], daypartIDs[0], sale.total); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		date time.Time
+		want float64
+	}{{day, 75}, {nextDay, 10}} {
+		if err := refreshDayAggregates(ctx, pool, locationID, tt.date, RefreshOptions{LaborBasis: "revenue"}); err != nil {
+			t.Fatalf("refresh %s: %v", tt.date.Format("2006-01-02"), err)
+		}
+		var revenue float64
+		for _, row := range snapshotDay(t, pool, locationID, tt.date) {
+			revenue += row.revenue
+		}
+		if revenue != tt.want {
+			t.Errorf("%s revenue = %v, want %v", tt.date.Format("2006-01-02"), revenue, tt.want)
+		}
+	}
+}
+
+// TestSaleLinesCostCountedOncePerSale checks a sale with several lines adds
+// each line's recipe cost to COGS while its total is counted only once
+func TestSaleLinesCostCountedOncePerSale(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	date := time.Date(2001, 8, 14, 0, 0, 0, 0, time.UTC)
+
+	menuItems := make([]uuid.UUID, 2)
+	for i, cost := range []float64{10, 5} {
+		if err := pool.QueryRow(ctx, `
+			INSERT INTO menu_items (name, recipe_cost) VALUES ($1, $2) RETURNING id
+		`, "COGS test item "+uuid.NewString()[:8], cost).Scan(&menuItems[i]); err != nil {
+			t.Fatalf("insert menu item: %v", err)
+		}
+	}
+	// Registered before the location so it runs after the sale lines are gone
+	t.Cleanup(func() {
+		if _, err := pool.Exec(ctx, `DELETE FROM menu_items WHERE id = ANY($1)`, menuItems); err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+	})
+	locationID := testLocation(t, pool, "Sale line COGS test")
+
+	channelIDs := queryIDs(t, pool, `SELECT id FROM service_channels ORDER BY id LIMIT 1`)
+	daypartIDs := queryIDs(t, pool, `SELECT id FROM dayparts ORDER BY id LIMIT 1`)
+	if len(channelIDs) == 0 || len(daypartIDs) == 0 {
+		t.Fatal("a service channel and a daypart must be seeded")
+	}
+
+	var saleID uuid.UUID
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total)
+		VALUES ($1, $2, $3, $4, 100, 100)
+		RETURNING id
+	`, date.Add(12*time.Hour), locationID, channelIDs[0], daypartIDs[0]).Scan(&saleID); err != nil {
+		t.Fatalf("insert sale: %v", err)
+	}
+	for i, qty := range []float64{2, 1} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO sale_lines (sale_id, location_id, menu_item_id, quantity) VALUES ($1, $2, $3, $4)
+		`, saleID, locationID, menuItems[i], qty); err != nil {
+			t.Fatalf("insert sale line: %v", err)
+		}
+	}
+
+	if err := refreshDayAggregates(ctx, pool, locationID, date, RefreshOptions{LaborBasis: "revenue"}); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	got := snapshotDay(t, pool, locationID, date)
+	if len(got) != 1 {
+		t.Fatalf("got %d aggregate rows, want 1", len(got))
+	}
+	// Revenue 100 once, less 2 x 10 + 1 x 5 of recipe cost
+	if got[0].revenue != 100 || got[0].grossMargin != 75 {
+		t.Errorf("revenue, gross margin = %v, %v, want 100, 75", got[0].revenue, got[0].grossMargin)
+	}
+}
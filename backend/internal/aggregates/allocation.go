@@ -0,0 +1,79 @@
+package aggregates
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Allocation bases for distributing location-level costs across aggregate rows
+const (
+	AllocateByRevenue = "revenue"
+	AllocateByCovers  = "covers"
+	AllocateEvenly    = "even"
+)
+
+// ValidAllocationBasis reports whether basis is a supported allocation basis
+func ValidAllocationBasis(basis string) error {
+	switch basis {
+	case AllocateByRevenue, AllocateByCovers, AllocateEvenly:
+		return nil
+	}
+	return fmt.Errorf("invalid allocation basis %q, use revenue, covers or even", basis)
+}
+
+// allocate splits total across weights proportionally, working in cents so the
+// parts always sum exactly to the rounded total. Leftover cents go to the rows
+// with the largest fractional remainders. If all weights are zero the total is
+// split evenly.
+func allocate(total float64, weights []float64) []float64 {
+	n := len(weights)
+	if n == 0 {
+		return nil
+	}
+
+	var sum float64
+	for _, w := range weights {
+		if w > 0 {
+			sum += w
+		}
+	}
+
+	shares := make([]float64, n)
+	for i, w := range weights {
+		switch {
+		case sum <= 0:
+			shares[i] = 1 / float64(n)
+		case w > 0:
+			shares[i] = w / sum
+		}
+	}
+
+	totalCents := int64(math.Round(total * 100))
+	parts := make([]int64, n)
+	remainders := make([]float64, n)
+	var allocated int64
+	for i, share := range shares {
+		exact := float64(totalCents) * share
+		parts[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(parts[i])
+		allocated += parts[i]
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for k := int64(0); k < totalCents-allocated; k++ {
+		parts[order[int(k)%n]]++
+	}
+
+	result := make([]float64, n)
+	for i, p := range parts {
+		result[i] = float64(p) / 100
+	}
+	return result
+}
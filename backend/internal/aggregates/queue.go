@@ -0,0 +1,114 @@
+package aggregates
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+// dayKey identifies one location-day of aggregates
+type dayKey struct {
+	locationID uuid.UUID
+	date       time.Time
+}
+
+// Refresher queues aggregate refreshes for the days completed imports touched
+// and works through them in the background. A day already waiting in the
+// queue is not queued again, so overlapping imports refresh each day once; a
+// day that is enqueued while it is being refreshed runs again afterwards so
+// it picks up the newer data.
+type Refresher struct {
+	db   *pgxpool.Pool
+	opts RefreshOptions
+
+	mu      sync.Mutex
+	pending map[dayKey]bool
+	queue   []dayKey
+	wake    chan struct{}
+}
+
+// NewRefresher creates a new aggregate refresher. Call Run to start processing the queue.
+func NewRefresher(db *pgxpool.Pool, opts RefreshOptions) *Refresher {
+	return &Refresher{
+		db:      db,
+		opts:    opts,
+		pending: make(map[dayKey]bool),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// EnqueueImport queues a refresh of the days a completed import touched
+func (r *Refresher) EnqueueImport(ctx context.Context, jobID uuid.UUID) error {
+	locationID, start, end, err := importRange(ctx, r.db, jobID)
+	if err != nil {
+		return err
+	}
+	r.Enqueue(locationID, start, end)
+	return nil
+}
+
+// Enqueue queues a refresh of each day in the inclusive range that is not already waiting
+func (r *Refresher) Enqueue(locationID uuid.UUID, start, end time.Time) {
+	r.mu.Lock()
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		key := dayKey{locationID: locationID, date: time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)}
+		if r.pending[key] {
+			continue
+		}
+		r.pending[key] = true
+		r.queue = append(r.queue, key)
+	}
+	r.mu.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run refreshes queued days until ctx is cancelled. With RefreshRollup set,
+// the rollup view is refreshed each time the queue empties.
+func (r *Refresher) Run(ctx context.Context) {
+	refreshed := false
+	for {
+		key, ok := r.next()
+		if !ok {
+			if refreshed && r.opts.RefreshRollup {
+				if err := kpi.NewStore(r.db).RefreshRollup(ctx); err != nil {
+					log.Printf("Failed to refresh KPI rollup: %v", err)
+				}
+			}
+			refreshed = false
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.wake:
+			}
+			continue
+		}
+
+		if err := refreshDayAggregates(ctx, r.db, key.locationID, key.date, r.opts); err != nil {
+			log.Printf("Failed to refresh aggregates for %s: %v", key.date.Format("2006-01-02"), err)
+		}
+		refreshed = true
+	}
+}
+
+// next pops the oldest queued day, releasing it so it can be queued again
+func (r *Refresher) next() (dayKey, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.queue) == 0 {
+		return dayKey{}, false
+	}
+	key := r.queue[0]
+	r.queue = r.queue[1:]
+	delete(r.pending, key)
+	return key, true
+}
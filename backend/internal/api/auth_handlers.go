@@ -0,0 +1,237 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/audit"
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+)
+
+// RefreshRequest carries a refresh token for the refresh and logout endpoints
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all,omitempty"` // logout only: revoke every session for the user
+}
+
+// issueRefreshToken creates and stores a new refresh token for a user
+func (s *Server) issueRefreshToken(r *http.Request, userID uuid.UUID) (string, error) {
+	refreshToken, err := s.jwtService.GenerateRefreshToken(userID)
+	if err != nil {
+		return "", err
+	}
+	claims, err := s.jwtService.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return "", err
+	}
+	if err := s.refreshStore.Save(r.Context(), claims); err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// handleRefresh exchanges a refresh token for a new access token. The refresh
+// token is rotated: the presented token is revoked and a new one returned.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "refresh_token required")
+		return
+	}
+
+	claims, err := s.jwtService.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "invalid or expired refresh token")
+		return
+	}
+
+	active, err := s.refreshStore.Revoke(r.Context(), claims.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "failed to refresh token")
+		return
+	}
+	if !active {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, auth.ErrRevokedToken.Error())
+		return
+	}
+
+	// Reload the user so role or location changes and deactivation take effect on refresh
+	var email, role string
+	var locationID *uuid.UUID
+	err = s.db.QueryRow(r.Context(), `
+		SELECT email, role, location_id FROM users WHERE id = $1 AND deactivated_at IS NULL
+	`, claims.UserID).Scan(&email, &role, &locationID)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "invalid or expired refresh token")
+		return
+	}
+	if locationID == nil {
+		respondError(w, http.StatusForbidden, codeForbidden, "user is not assigned to a location")
+		return
+	}
+
+	token, err := s.jwtService.GenerateToken(claims.UserID, email, auth.Role(role), *locationID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "failed to generate token")
+		return
+	}
+	refreshToken, err := s.issueRefreshToken(r, claims.UserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "failed to generate token")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"token":         token,
+		"refresh_token": refreshToken,
+	})
+}
+
+// handleLogout revokes the presented refresh token, or all of the user's
+// refresh tokens when all is set. Access tokens expire on their own.
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "refresh_token required")
+		return
+	}
+
+	// An invalid or expired token has nothing left to revoke
+	claims, err := s.jwtService.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if req.All {
+		err = s.refreshStore.RevokeAllForUser(r.Context(), claims.UserID)
+	} else {
+		_, err = s.refreshStore.Revoke(r.Context(), claims.ID)
+	}
+	if err != nil {
+		log.Printf("Failed to revoke refresh token for user %s: %v", claims.UserID, err)
+		respondError(w, http.StatusInternalServerError, codeInternal, "failed to log out")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ActivityResponse is a page of audit log entries
+type ActivityResponse struct {
+	Data       []audit.Entry `json:"data"`
+	Total      int           `json:"total"`
+	Page       int           `json:"page"`
+	PageSize   int           `json:"page_size"`
+	TotalPages int           `json:"total_pages"`
+}
+
+// handleActivity lists the authenticated user's own recent logins, imports and exports
+func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserClaims(r.Context())
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "unauthorized")
+		return
+	}
+
+	page := 1
+	pageSize := 50
+	if v := r.URL.Query().Get("page"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if v := r.URL.Query().Get("page_size"); v != "" {
+		if ps, err := strconv.Atoi(v); err == nil && ps > 0 && ps <= 100 {
+			pageSize = ps
+		}
+	}
+
+	entries, total, err := s.auditLog.ListForUser(r.Context(), claims.UserID, page, pageSize)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "failed to load activity")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ActivityResponse{
+		Data:       entries,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	})
+}
+
+// handleAudit lists the audit log for owner admins, newest first. It can be
+// filtered by action (comma-separated), user_id, and from/to dates
+// (YYYY-MM-DD, inclusive, Brisbane time).
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := audit.Filter{Page: 1, PageSize: 50}
+	if v := q.Get("page"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil && p > 0 {
+			filter.Page = p
+		}
+	}
+	if v := q.Get("page_size"); v != "" {
+		if ps, err := strconv.Atoi(v); err == nil && ps > 0 && ps <= 100 {
+			filter.PageSize = ps
+		}
+	}
+
+	for _, a := range strings.Split(q.Get("action"), ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			filter.Actions = append(filter.Actions, a)
+		}
+	}
+	if v := q.Get("user_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "invalid user_id")
+			return
+		}
+		filter.UserID = &id
+	}
+
+	loc, _ := time.LoadLocation("Australia/Brisbane")
+	if v := q.Get("from"); v != "" {
+		from, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "invalid from, use YYYY-MM-DD")
+			return
+		}
+		filter.From = from
+	}
+	if v := q.Get("to"); v != "" {
+		to, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "invalid to, use YYYY-MM-DD")
+			return
+		}
+		filter.To = to.AddDate(0, 0, 1)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "from must not be after to")
+		return
+	}
+
+	entries, total, err := s.auditLog.List(r.Context(), filter)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "failed to load audit log")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ActivityResponse{
+		Data:       entries,
+		Total:      total,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		TotalPages: (total + filter.PageSize - 1) / filter.PageSize,
+	})
+}
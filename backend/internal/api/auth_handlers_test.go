@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+)
+
+func TestHandleAudit(t *testing.T) {
+	s := testServer(t)
+	token := func(role auth.Role) string {
+		v, err := s.jwtService.GenerateToken(uuid.New(), "staff@example.com", role, uuid.New())
+		if err != nil {
+			t.Fatalf("GenerateToken: %v", err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name       string
+		role       auth.Role // empty sends no token
+		query      string
+		wantStatus int
+		wantMsg    string
+	}{
+		{name: "anonymous", wantStatus: http.StatusUnauthorized},
+		{name: "manager", role: auth.RoleManager, wantStatus: http.StatusForbidden},
+		{name: "accountant", role: auth.RoleAccountant, wantStatus: http.StatusForbidden},
+		{name: "bad user", role: auth.RoleOwnerAdmin, query: "user_id=bob", wantStatus: http.StatusBadRequest, wantMsg: "invalid user_id"},
+		{name: "bad from", role: auth.RoleOwnerAdmin, query: "from=01/04/2024", wantStatus: http.StatusBadRequest, wantMsg: "invalid from"},
+		{name: "bad to", role: auth.RoleOwnerAdmin, query: "to=yesterday", wantStatus: http.StatusBadRequest, wantMsg: "invalid to"},
+		{name: "reversed", role: auth.RoleOwnerAdmin, query: "from=2024-04-02&to=2024-04-01", wantStatus: http.StatusBadRequest, wantMsg: "from must not be after to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/audit?"+tt.query, nil)
+			if tt.role != "" {
+				r.Header.Set("Authorization", "Bearer "+token(tt.role))
+			}
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantMsg) {
+				t.Errorf("got %d %s, want %d containing %q", w.Code, w.Body, tt.wantStatus, tt.wantMsg)
+			}
+		})
+	}
+}
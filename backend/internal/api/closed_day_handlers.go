@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+// ClosedDayHandler handles closed-day calendar requests
+type ClosedDayHandler struct {
+	store *kpi.Store
+}
+
+// NewClosedDayHandler creates a new closed-day handler
+func NewClosedDayHandler(store *kpi.Store) *ClosedDayHandler {
+	return &ClosedDayHandler{store: store}
+}
+
+// CreateClosedDayRequest represents a closed-day creation request
+type CreateClosedDayRequest struct {
+	Date   string `json:"date"`
+	Reason string `json:"reason"`
+}
+
+// HandleList handles GET /closed-days requests
+func (h *ClosedDayHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Default to the current year
+	loc, _ := time.LoadLocation("Australia/Brisbane")
+	now := time.Now().In(loc)
+	startDate := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc)
+	endDate := time.Date(now.Year(), 12, 31, 0, 0, 0, 0, loc)
+
+	if v := r.URL.Query().Get("start_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid start_date format, use YYYY-MM-DD")
+			return
+		}
+		startDate = t
+	}
+	if v := r.URL.Query().Get("end_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid end_date format, use YYYY-MM-DD")
+			return
+		}
+		endDate = t
+	}
+
+	days, err := h.store.ListClosedDays(ctx, claims.LocationID, startDate, endDate)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to list closed days")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(days)
+}
+
+// HandleCreate handles POST /closed-days requests
+func (h *ClosedDayHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateClosedDayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid date format, use YYYY-MM-DD")
+		return
+	}
+
+	userID := claims.UserID
+	day := &kpi.ClosedDay{
+		LocationID: claims.LocationID,
+		Date:       date,
+		Reason:     req.Reason,
+		CreatedBy:  &userID,
+	}
+
+	if err := h.store.CreateClosedDay(ctx, day); err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to create closed day")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(day)
+}
+
+// HandleDelete handles DELETE /closed-days/{id} requests
+func (h *ClosedDayHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid closed day ID")
+		return
+	}
+
+	found, err := h.store.DeleteClosedDay(ctx, id, claims.LocationID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to delete closed day")
+		return
+	}
+	if !found {
+		respondError(w, http.StatusNotFound, codeNotFound, "Closed day not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
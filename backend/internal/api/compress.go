@@ -0,0 +1,214 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressibleTypes are the media types worth gzipping. PDFs are already
+// compressed, so they are sent as they are.
+var compressibleTypes = map[string]bool{
+	"application/json": true,
+	"text/csv":         true,
+	"text/plain":       true,
+	"text/html":        true,
+}
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(io.Discard) },
+}
+
+// compress gzips responses of at least minSize bytes for clients that accept
+// it. Output is held back until minSize bytes are written, the handler
+// flushes or it returns, so small responses keep their Content-Length and go
+// out uncompressed. Handlers opt out with Cache-Control: no-transform, which
+// stored export downloads use so their byte ranges stay valid.
+func compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if minSize <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || r.Header.Get("Range") != "" || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Not deferred, so a panicking handler's partial output is dropped
+			// and the recoverer's 500 goes out instead
+			cw := &compressWriter{ResponseWriter: w, minSize: minSize, status: http.StatusOK}
+			next.ServeHTTP(cw, r)
+			cw.finish()
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip. An
+// explicit gzip entry takes precedence over *.
+func acceptsGzip(header string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = v
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip":
+			gzipQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
+
+// compressWriter buffers the start of a response until it knows whether to
+// gzip it
+type compressWriter struct {
+	http.ResponseWriter
+	minSize     int
+	status      int
+	wroteHeader bool
+	decided     bool
+	gz          *gzip.Writer
+	buf         []byte
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	// Informational and bodiless responses have nothing to compress, and a
+	// declared length below the threshold settles it up front
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified {
+		w.decide(false)
+		return
+	}
+	if n, err := strconv.Atoi(w.Header().Get("Content-Length")); err == nil && n < w.minSize {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(w.eligible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what has been buffered so a streaming handler is not held
+// back, compressing it if the response could be
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if err := w.start(w.eligible()); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// eligible reports whether the response may be gzipped
+func (w *compressWriter) eligible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" || w.status == http.StatusPartialContent {
+		return false
+	}
+	if strings.Contains(strings.ToLower(h.Get("Cache-Control")), "no-transform") {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && compressibleTypes[mediaType]
+}
+
+// decide writes the headers, switching to gzip when gzipped is set
+func (w *compressWriter) decide(gzipped bool) {
+	w.decided = true
+	if gzipped {
+		h := w.Header()
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		// The encoded bytes differ from what a strong ETag was computed over
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// start decides and writes out anything buffered so far
+func (w *compressWriter) start(gzipped bool) error {
+	w.decide(gzipped)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// finish sends a response that stayed below the threshold as it is, with
+// its length, or closes the gzip stream
+func (w *compressWriter) finish() {
+	if !w.decided {
+		if !w.wroteHeader {
+			w.status = http.StatusOK
+		}
+		if w.Header().Get("Content-Length") == "" && w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+			w.Header().Set("Content-Length", strconv.Itoa(len(w.buf)))
+		}
+		w.start(false)
+		return
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(io.Discard)
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: "gzip", want: true},
+		{header: "GZIP", want: true},
+		{header: "deflate, gzip;q=0.8", want: true},
+		{header: "br, deflate", want: false},
+		{header: "*", want: true},
+		{header: "gzip;q=0", want: false},
+		{header: "gzip; q=0.0", want: false},
+		{header: "*;q=0", want: false},
+		{header: "gzip;q=0, *", want: false},
+		{header: "*;q=0, gzip", want: true},
+		{header: "identity, *;q=0.5", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := acceptsGzip(tt.header); got != tt.want {
+				t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	const minSize = 64
+	large := strings.Repeat(`{"revenue":1200.5}`, 20)
+	small := `{"revenue":1200.5}`
+
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		rangeHeader    string
+		headers        map[string]string
+		status         int
+		body           string
+		wantGzip       bool
+		wantLength     bool // Content-Length set to the body size
+	}{
+		{name: "large json", acceptEncoding: "gzip", body: large, wantGzip: true},
+		{name: "small json keeps its length", acceptEncoding: "gzip", body: small, wantLength: true},
+		{name: "client without gzip", acceptEncoding: "br", body: large},
+		{name: "csv", acceptEncoding: "gzip", headers: map[string]string{"Content-Type": "text/csv"}, body: large, wantGzip: true},
+		{name: "pdf", acceptEncoding: "gzip", headers: map[string]string{"Content-Type": "application/pdf"}, body: large},
+		{name: "no-transform", acceptEncoding: "gzip", headers: map[string]string{"Cache-Control": "private, no-transform"}, body: large},
+		{name: "already encoded", acceptEncoding: "gzip", headers: map[string]string{"Content-Encoding": "br"}, body: large},
+		{name: "range request", acceptEncoding: "gzip", rangeHeader: "bytes=0-9", body: large},
+		{name: "partial content", acceptEncoding: "gzip", status: http.StatusPartialContent, body: large},
+		{name: "declared length below threshold", acceptEncoding: "gzip", headers: map[string]string{"Content-Length": strconv.Itoa(len(small))}, body: small, wantLength: true},
+		{name: "error response", acceptEncoding: "gzip", status: http.StatusBadRequest, body: large, wantGzip: true},
+		{name: "head", method: http.MethodHead, acceptEncoding: "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := compress(minSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				for k, v := range tt.headers {
+					w.Header().Set(k, v)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				// Write in pieces so the threshold is crossed mid-response
+				for i := 0; i < len(tt.body); i += 50 {
+					io.WriteString(w, tt.body[i:min(i+50, len(tt.body))])
+				}
+			}))
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "/kpi/daily", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			if tt.rangeHeader != "" {
+				r.Header.Set("Range", tt.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			wantStatus := tt.status
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			if w.Code != wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, wantStatus)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+
+			gzipped := w.Header().Get("Content-Encoding") == "gzip"
+			if gzipped != tt.wantGzip {
+				t.Fatalf("gzipped = %v, want %v", gzipped, tt.wantGzip)
+			}
+			body := w.Body.String()
+			if gzipped {
+				if w.Header().Get("Content-Length") != "" {
+					t.Errorf("Content-Length = %q on a gzipped response", w.Header().Get("Content-Length"))
+				}
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("gzip.NewReader: %v", err)
+				}
+				decoded, err := io.ReadAll(zr)
+				if err != nil {
+					t.Fatalf("decompress: %v", err)
+				}
+				body = string(decoded)
+			}
+			if body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+			if tt.wantLength {
+				if got := w.Header().Get("Content-Length"); got != strconv.Itoa(len(tt.body)) {
+					t.Errorf("Content-Length = %q, want %d", got, len(tt.body))
+				}
+			}
+		})
+	}
+}
+
+func TestCompressWeakensETag(t *testing.T) {
+	body := strings.Repeat("date,revenue\n", 20)
+	tests := []struct {
+		etag string
+		want string
+	}{
+		{etag: `"abc"`, want: `W/"abc"`},
+		{etag: `W/"abc"`, want: `W/"abc"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.etag, func(t *testing.T) {
+			handler := compress(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+				w.Header().Set("ETag", tt.etag)
+				io.WriteString(w, body)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/kpi/series.csv", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Header().Get("Content-Encoding") != "gzip" {
+				t.Fatal("response was not gzipped")
+			}
+			if got := w.Header().Get("ETag"); got != tt.want {
+				t.Errorf("ETag = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// A handler that flushes early starts the response even below the threshold
+func TestCompressFlush(t *testing.T) {
+	handler := compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "progress 1\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "progress 2\n")
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if !w.Flushed {
+		t.Error("Flush did not reach the underlying writer")
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("flushed response was not gzipped")
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	decoded, _ := io.ReadAll(zr)
+	if string(decoded) != "progress 1\nprogress 2\n" {
+		t.Errorf("body = %q", decoded)
+	}
+}
+
+func TestCompressDisabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := compress(0)(next)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("Vary") != "" {
+		t.Errorf("compress(0) still set Vary = %q", w.Header().Get("Vary"))
+	}
+}
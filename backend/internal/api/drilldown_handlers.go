@@ -47,7 +47,7 @@ type DrilldownResponse struct {
 // HandleSales handles GET /kpi/drilldown/sales requests
 func (h *DrilldownHandler) HandleSales(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	
+
 	// Get location ID from claims if authenticated, otherwise use default
 	var locationID string
 	claims := auth.GetUserClaims(ctx)
@@ -57,7 +57,7 @@ func (h *DrilldownHandler) HandleSales(w http.ResponseWriter, r *http.Request) {
 		// Use default location for public access - query from database
 		row := h.db.QueryRow(ctx, "SELECT id FROM locations LIMIT 1")
 		if err := row.Scan(&locationID); err != nil {
-			http.Error(w, "No location configured", http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, codeInternal, "No location configured")
 			return
 		}
 	}
@@ -127,7 +127,7 @@ func (h *DrilldownHandler) HandleSales(w http.ResponseWriter, r *http.Request) {
 	var total int
 	countQuery := `SELECT COUNT(*) ` + baseQuery
 	if err := h.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
-		http.Error(w, "Failed to count sales", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to count sales")
 		return
 	}
 
@@ -143,7 +143,7 @@ func (h *DrilldownHandler) HandleSales(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := h.db.Query(ctx, dataQuery, args...)
 	if err != nil {
-		http.Error(w, "Failed to fetch sales", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch sales")
 		return
 	}
 	defer rows.Close()
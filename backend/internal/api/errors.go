@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Error codes sent in the error envelope, one per kind of failure a client
+// may handle differently
+const (
+	codeBadRequest    = "bad_request"
+	codeUnauthorized  = "unauthorized"
+	codeForbidden     = "forbidden"
+	codeNotFound      = "not_found"
+	codeNotAllowed    = "method_not_allowed"
+	codeConflict      = "conflict"
+	codeGone          = "gone"
+	codeUnprocessable = "unprocessable"
+	codeInternal      = "internal_error"
+	codeUpstream      = "upstream_error"
+	codeUnavailable   = "unavailable"
+	codeTimeout       = "timeout"
+)
+
+// errorBody is the envelope every error response is sent in
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// respondError writes a JSON error envelope. The request ID comes from the
+// X-Request-ID response header set by exposeRequestID, so support can match
+// a reported error to the server logs.
+func respondError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorBody{Error: errorDetail{
+		Code:      code,
+		Message:   message,
+		RequestID: w.Header().Get("X-Request-ID"),
+	}})
+}
+
+// errorCode returns the envelope code for a status chosen at run time
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return codeBadRequest
+	case http.StatusUnauthorized:
+		return codeUnauthorized
+	case http.StatusForbidden:
+		return codeForbidden
+	case http.StatusNotFound:
+		return codeNotFound
+	case http.StatusMethodNotAllowed:
+		return codeNotAllowed
+	case http.StatusConflict:
+		return codeConflict
+	case http.StatusGone:
+		return codeGone
+	case http.StatusUnprocessableEntity:
+		return codeUnprocessable
+	case http.StatusBadGateway:
+		return codeUpstream
+	case http.StatusServiceUnavailable:
+		return codeUnavailable
+	case http.StatusGatewayTimeout:
+		return codeTimeout
+	}
+	if status >= http.StatusInternalServerError {
+		return codeInternal
+	}
+	return codeBadRequest
+}
+
+// exposeRequestID echoes the request ID chi assigned into the X-Request-ID
+// response header so clients can quote it
+func exposeRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			w.Header().Set("X-Request-ID", id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+)
+
+func TestRespondError(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Request-ID", "host/abc-000042")
+	respondError(w, http.StatusConflict, codeConflict, "file has already been imported")
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	want := `{"error":{"code":"conflict","message":"file has already been imported","request_id":"host/abc-000042"}}`
+	if got := strings.TrimSpace(w.Body.String()); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := map[int]string{
+		http.StatusBadRequest:          codeBadRequest,
+		http.StatusNotFound:            codeNotFound,
+		http.StatusConflict:            codeConflict,
+		http.StatusUnprocessableEntity: codeUnprocessable,
+		http.StatusTooManyRequests:     codeBadRequest,
+		http.StatusInternalServerError: codeInternal,
+		http.StatusNotImplemented:      codeInternal,
+		http.StatusBadGateway:          codeUpstream,
+		http.StatusServiceUnavailable:  codeUnavailable,
+		http.StatusGatewayTimeout:      codeTimeout,
+	}
+	for status, want := range tests {
+		if got := errorCode(status); got != want {
+			t.Errorf("errorCode(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+// TestErrorEnvelopes sends failing requests through the router and checks
+// each comes back as a JSON envelope quoting the request's ID
+func TestErrorEnvelopes(t *testing.T) {
+	s := testServer(t)
+	token := func(role auth.Role) string {
+		v, err := s.jwtService.GenerateToken(uuid.New(), "staff@example.com", role, uuid.New())
+		if err != nil {
+			t.Fatalf("GenerateToken: %v", err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		role       auth.Role // empty sends no token
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "unknown route", method: http.MethodGet, path: "/api/v1/nothing-here", wantStatus: http.StatusNotFound, wantCode: codeNotFound},
+		{name: "wrong method", method: http.MethodPatch, path: "/api/v1/auth/login", wantStatus: http.StatusMethodNotAllowed, wantCode: codeNotAllowed},
+		{name: "auth: bad body", method: http.MethodPost, path: "/api/v1/auth/login", body: "{", wantStatus: http.StatusBadRequest, wantCode: codeBadRequest},
+		{name: "auth: missing password", method: http.MethodPost, path: "/api/v1/auth/login", body: `{"email":"a@example.com"}`, wantStatus: http.StatusBadRequest, wantCode: codeBadRequest},
+		{name: "middleware: no token", method: http.MethodGet, path: "/api/v1/imports/", wantStatus: http.StatusUnauthorized, wantCode: codeUnauthorized},
+		{name: "middleware: role", method: http.MethodGet, path: "/api/v1/imports/", role: auth.RoleViewer, wantStatus: http.StatusForbidden, wantCode: codeForbidden},
+		{name: "import: bad id", method: http.MethodGet, path: "/api/v1/imports/not-an-id", role: auth.RoleAccountant, wantStatus: http.StatusBadRequest, wantCode: codeBadRequest},
+		{name: "export: bad id", method: http.MethodGet, path: "/api/v1/exports/not-an-id", wantStatus: http.StatusBadRequest, wantCode: codeBadRequest},
+		{name: "kpi: unknown metric", method: http.MethodGet, path: "/api/v1/kpi/series.csv?range=7d&metrics=revenue,vibes", wantStatus: http.StatusBadRequest, wantCode: codeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.role != "" {
+				r.Header.Set("Authorization", "Bearer "+token(tt.role))
+			}
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body errorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q is not an error envelope: %v", w.Body, err)
+			}
+			if body.Error.Code != tt.wantCode || body.Error.Message == "" {
+				t.Errorf("error = %+v, want code %q with a message", body.Error, tt.wantCode)
+			}
+			if id := w.Header().Get("X-Request-ID"); id == "" || body.Error.RequestID != id {
+				t.Errorf("request_id = %q, want the X-Request-ID header %q", body.Error.RequestID, id)
+			}
+		})
+	}
+}
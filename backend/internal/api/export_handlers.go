@@ -1,59 +1,156 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
+	"github.com/lakehouse/restaurant-finance/internal/audit"
 	"github.com/lakehouse/restaurant-finance/internal/auth"
 	"github.com/lakehouse/restaurant-finance/internal/exports"
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+	"github.com/lakehouse/restaurant-finance/internal/sheets"
 )
 
+// maxShareLinkTTL caps how long a signed download link may stay valid
+const maxShareLinkTTL = 7 * 24 * time.Hour
+
 // ExportHandler handles export-related HTTP requests
 type ExportHandler struct {
-	service *exports.ExportService
-	store   *exports.ExportStore
+	service    *exports.ExportService
+	store      *exports.ExportStore
+	signer     *exports.URLSigner
+	linkTTL    time.Duration
+	auditLog   *audit.Logger
+	reports    *exports.SavedReportStore
+	locations  locationSettings
+	systemUser uuid.UUID // requester recorded for anonymous exports; uuid.Nil records none
+	cacheTTL   time.Duration
+	sheets     *sheets.Exporter // nil when Google Sheets export is disabled
+}
+
+// locationSettings resolves the default location and per-location settings
+// exports depend on
+type locationSettings interface {
+	locationDefaulter
+	FiscalYearStart(ctx context.Context, locationID uuid.UUID) (kpi.FiscalYearStart, error)
 }
 
-// NewExportHandler creates a new export handler
-func NewExportHandler(service *exports.ExportService, store *exports.ExportStore) *ExportHandler {
+// NewExportHandler creates a new export handler. Anonymous exports are scoped
+// like the public dashboard and recorded as requested by systemUser.
+// Downloads may be cached by the browser for cacheTTL; zero disables caching.
+// sheetsExporter may be nil to disable the google_sheets target.
+func NewExportHandler(service *exports.ExportService, store *exports.ExportStore, reports *exports.SavedReportStore, locations locationSettings, systemUser uuid.UUID, signer *exports.URLSigner, linkTTL, cacheTTL time.Duration, sheetsExporter *sheets.Exporter, auditLog *audit.Logger) *ExportHandler {
 	return &ExportHandler{
-		service: service,
-		store:   store,
+		service:    service,
+		store:      store,
+		signer:     signer,
+		linkTTL:    linkTTL,
+		auditLog:   auditLog,
+		reports:    reports,
+		locations:  locations,
+		systemUser: systemUser,
+		cacheTTL:   cacheTTL,
+		sheets:     sheetsExporter,
 	}
 }
 
 // CreateExportRequest represents the export creation request
 type CreateExportRequest struct {
-	ExportType string `json:"export_type"` // pnl, channel_summary
-	StartDate  string `json:"start_date"`
-	EndDate    string `json:"end_date"`
+	ExportType  string `json:"export_type"` // pnl, channel_summary, daypart_summary, tax_summary, inventory_valuation
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+	GroupBy     string `json:"group_by,omitempty"`     // tax_summary filing period: month, quarter
+	SummaryOnly bool   `json:"summary_only,omitempty"` // pnl: period totals only, no daily detail
+	// SnapshotDate picks one day's inventory counts for inventory_valuation;
+	// empty uses each item's latest count up to end_date
+	SnapshotDate string `json:"snapshot_date,omitempty"`
+	Format       string `json:"format,omitempty"` // pnl: csv (default) or pdf
+	// Target is file (default) to download the export, or google_sheets to
+	// write its rows into SheetTab of SpreadsheetID
+	Target        string `json:"target,omitempty"`
+	SpreadsheetID string `json:"spreadsheet_id,omitempty"`
+	SheetTab      string `json:"sheet_tab,omitempty"` // defaults to the export type
+}
+
+// SheetsExportResponse reports an export written to Google Sheets
+type SheetsExportResponse struct {
+	ExportID      uuid.UUID `json:"export_id"`
+	SpreadsheetID string    `json:"spreadsheet_id"`
+	SheetTab      string    `json:"sheet_tab"`
+	Rows          int       `json:"rows"`
 }
 
 // HandlePnL handles POST /exports/pnl requests
 func (h *ExportHandler) HandlePnL(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	
-	// Get location ID from claims if authenticated, otherwise use default
-	var locationID uuid.UUID
-	var userID uuid.UUID
-	claims := auth.GetUserClaims(ctx)
-	if claims != nil {
-		locationID = claims.LocationID
+
+	locationID, status, err := resolveLocation(r, h.locations)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+	userID := h.systemUser
+	if claims := auth.GetUserClaims(ctx); claims != nil {
 		userID = claims.UserID
-	} else {
-		// Use default location and admin user for public access
-		locationID = uuid.MustParse("593bb8d0-36a8-4ce3-bf51-715532cee9ca")
-		userID = uuid.MustParse("22222222-2222-2222-2222-222222222222") // admin@lakehouse.com
 	}
 
 	var req CreateExportRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+
+	// The format can also be given as a query parameter, e.g. for links
+	format := req.Format
+	if format == "" {
+		format = r.URL.Query().Get("format")
+	}
+	switch format {
+	case "", "csv":
+		format = "csv"
+	case "pdf":
+		if req.ExportType != "" && req.ExportType != "pnl" {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "format=pdf is only available for the pnl export")
+			return
+		}
+	default:
+		respondError(w, http.StatusBadRequest, codeBadRequest, "format must be csv or pdf")
+		return
+	}
+
+	switch req.Target {
+	case "", "file":
+	case "google_sheets":
+		if h.sheets == nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "Google Sheets export is not enabled")
+			return
+		}
+		if auth.GetUserClaims(ctx) == nil {
+			respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+			return
+		}
+		if format != "csv" {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "target=google_sheets requires format=csv")
+			return
+		}
+		if req.SpreadsheetID == "" {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "spreadsheet_id is required for target=google_sheets")
+			return
+		}
+	default:
+		respondError(w, http.StatusBadRequest, codeBadRequest, "target must be file or google_sheets")
 		return
 	}
 
@@ -73,36 +170,129 @@ func (h *ExportHandler) HandlePnL(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if req.ExportType == "tax_summary" {
+		if _, err := kpi.TaxPeriodTrunc(req.GroupBy); err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+			return
+		}
+	}
+
 	params := exports.ExportPnLParams{
-		StartDate:  startDate,
-		EndDate:    endDate,
-		LocationID: locationID,
-		UserID:     userID,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		LocationID:  locationID,
+		UserID:      userID,
+		GroupBy:     req.GroupBy,
+		SummaryOnly: req.SummaryOnly,
+	}
+	if req.SnapshotDate != "" {
+		t, err := time.Parse("2006-01-02", req.SnapshotDate)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "snapshot_date must be YYYY-MM-DD")
+			return
+		}
+		params.SnapshotDate = t
+	}
+
+	job, data, err := h.generate(ctx, req.ExportType, format, params)
+	var limitErr *exports.RowLimitError
+	if errors.As(err, &limitErr) {
+		respondError(w, http.StatusUnprocessableEntity, codeUnprocessable, limitErr.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Export generation error: %v", err)
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to generate export: "+err.Error())
+		return
 	}
 
-	var job *exports.ExportJob
-	var data []byte
-	var err error
+	if req.Target == "google_sheets" {
+		h.sendToSheets(w, r, job, data, locationID, req.SpreadsheetID, req.SheetTab)
+		return
+	}
 
-	switch req.ExportType {
-	case "channel_summary":
-		job, data, err = h.service.GenerateChannelSummary(ctx, params)
-	default:
-		job, data, err = h.service.GeneratePnLExport(ctx, params)
+	h.sendExport(w, r, job, data, format, nil)
+}
+
+// sendToSheets writes a generated CSV export into a Google Sheet tab, using
+// the location's credentials, instead of returning the file
+func (h *ExportHandler) sendToSheets(w http.ResponseWriter, r *http.Request, job *exports.ExportJob, data []byte, locationID uuid.UUID, spreadsheetID, tab string) {
+	if tab == "" {
+		tab = job.ExportType
 	}
 
+	rows, err := h.sheets.WriteCSV(r.Context(), locationID, spreadsheetID, tab, data)
+	if errors.Is(err, sheets.ErrNotConfigured) {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
 	if err != nil {
-		log.Printf("Export generation error: %v", err)
-		http.Error(w, "Failed to generate export: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("Export %s to Google Sheets failed: %v", job.ID, err)
+		respondError(w, http.StatusBadGateway, codeUpstream, "Failed to write to Google Sheets: "+err.Error())
 		return
 	}
 
-	// Return CSV directly
-	w.Header().Set("Content-Type", "text/csv")
+	h.recordExport(r, job, "csv", map[string]interface{}{
+		"target":         "google_sheets",
+		"spreadsheet_id": spreadsheetID,
+		"sheet_tab":      tab,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SheetsExportResponse{
+		ExportID:      job.ID,
+		SpreadsheetID: spreadsheetID,
+		SheetTab:      tab,
+		Rows:          rows,
+	})
+}
+
+// generate builds an export of the given type and format
+func (h *ExportHandler) generate(ctx context.Context, exportType, format string, params exports.ExportPnLParams) (*exports.ExportJob, []byte, error) {
+	switch exportType {
+	case "channel_summary":
+		return h.service.GenerateChannelSummary(ctx, params)
+	case "daypart_summary":
+		return h.service.GenerateDaypartSummary(ctx, params)
+	case "tax_summary":
+		return h.service.GenerateTaxSummary(ctx, params)
+	case "inventory_valuation":
+		return h.service.GenerateInventoryValuation(ctx, params)
+	default:
+		if format == "pdf" {
+			return h.service.GeneratePnLPDF(ctx, params)
+		}
+		return h.service.GeneratePnLExport(ctx, params)
+	}
+}
+
+// sendExport records a generated export in the audit log, with any extra
+// metadata, and writes the file as the response
+func (h *ExportHandler) sendExport(w http.ResponseWriter, r *http.Request, job *exports.ExportJob, data []byte, format string, extra map[string]interface{}) {
+	h.recordExport(r, job, format, extra)
+
+	// Return the file directly
+	w.Header().Set("Content-Type", exportContentType(job.FileName))
 	w.Header().Set("Content-Disposition", "attachment; filename="+job.FileName)
 	w.Write(data)
 }
 
+// recordExport records a generated export in the audit log
+func (h *ExportHandler) recordExport(r *http.Request, job *exports.ExportJob, format string, extra map[string]interface{}) {
+	metadata := map[string]interface{}{
+		"export_type":  job.ExportType,
+		"period_start": job.PeriodStart.Format("2006-01-02"),
+		"period_end":   job.PeriodEnd.Format("2006-01-02"),
+		"format":       format,
+	}
+	for k, v := range extra {
+		metadata[k] = v
+	}
+	if err := h.auditLog.Record(r.Context(), audit.ActionExportGenerate, "export_job", &job.ID, metadata); err != nil {
+		log.Printf("Failed to record export %s: %v", job.ID, err)
+	}
+}
+
 // HandleGet handles GET /exports/{id} requests
 func (h *ExportHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -110,13 +300,13 @@ func (h *ExportHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "Invalid export ID", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid export ID")
 		return
 	}
 
 	job, err := h.store.GetJobByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Export not found", http.StatusNotFound)
+		respondError(w, http.StatusNotFound, codeNotFound, "Export not found")
 		return
 	}
 
@@ -124,21 +314,189 @@ func (h *ExportHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(job)
 }
 
+// ExportListResponse is a page of export jobs
+type ExportListResponse struct {
+	Data       []exports.ExportJob `json:"data"`
+	Total      int                 `json:"total"`
+	Page       int                 `json:"page"`
+	PageSize   int                 `json:"page_size"`
+	TotalPages int                 `json:"total_pages"`
+}
+
 // HandleList handles GET /exports requests
 func (h *ExportHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	claims := auth.GetUserClaims(ctx)
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
 		return
 	}
 
-	jobs, err := h.store.ListJobs(ctx, claims.LocationID, 50)
+	filter := exports.ListJobsFilter{
+		LocationID: claims.LocationID,
+		Page:       1,
+		PageSize:   50,
+	}
+	if v := r.URL.Query().Get("page"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil && p > 0 {
+			filter.Page = p
+		}
+	}
+	if v := r.URL.Query().Get("page_size"); v != "" {
+		if ps, err := strconv.Atoi(v); err == nil && ps > 0 && ps <= 100 {
+			filter.PageSize = ps
+		}
+	}
+	if v := r.URL.Query().Get("requested_by"); v != "" {
+		userID, err := uuid.Parse(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid requested_by")
+			return
+		}
+		filter.RequestedBy = &userID
+	}
+
+	jobs, total, err := h.store.ListJobs(ctx, filter)
 	if err != nil {
-		http.Error(w, "Failed to list exports", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to list exports")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(jobs)
+	json.NewEncoder(w).Encode(ExportListResponse{
+		Data:       jobs,
+		Total:      total,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		TotalPages: (total + filter.PageSize - 1) / filter.PageSize,
+	})
+}
+
+// ShareExportRequest represents a signed download link request
+type ShareExportRequest struct {
+	ExpiresInMinutes int `json:"expires_in_minutes,omitempty"`
+}
+
+// ShareExportResponse contains a signed download link for an export
+type ShareExportResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// HandleShare handles POST /exports/{id}/share requests
+func (h *ExportHandler) HandleShare(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid export ID")
+		return
+	}
+
+	var req ShareExportRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	ttl := h.linkTTL
+	if req.ExpiresInMinutes > 0 {
+		ttl = time.Duration(req.ExpiresInMinutes) * time.Minute
+	}
+	if ttl > maxShareLinkTTL {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Link lifetime cannot exceed 7 days")
+		return
+	}
+
+	job, err := h.store.GetJobByID(ctx, id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, codeNotFound, "Export not found")
+		return
+	}
+	if job.FilePath == "" {
+		respondError(w, http.StatusConflict, codeConflict, "Export file is not available for download")
+		return
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	link := url.URL{
+		Path:     "/api/v1/exports/" + id.String() + "/download",
+		RawQuery: h.signer.SignedQuery(id, expiresAt).Encode(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ShareExportResponse{
+		URL:       link.String(),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// HandleDownload handles GET /exports/{id}/download requests. Access requires
+// either an authenticated session or a valid signed token and expiry.
+func (h *ExportHandler) HandleDownload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid export ID")
+		return
+	}
+
+	if token := r.URL.Query().Get("token"); token != "" {
+		if err := h.signer.Verify(id, token, r.URL.Query().Get("expires")); err != nil {
+			respondError(w, http.StatusForbidden, codeForbidden, err.Error())
+			return
+		}
+	} else if auth.GetUserClaims(ctx) == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	job, file, err := h.service.OpenExport(ctx, id)
+	if err != nil {
+		if errors.Is(err, exports.ErrExportNotStored) {
+			respondError(w, http.StatusGone, codeGone, "Export file is not available")
+			return
+		}
+		respondError(w, http.StatusNotFound, codeNotFound, "Export not found")
+		return
+	}
+	defer file.Close()
+
+	h.serveExport(w, r, job, file)
+}
+
+// serveExport writes a stored export file. A generated export never changes,
+// so its ID is a strong validator. ServeContent answers If-None-Match and
+// If-Modified-Since with 304, and no-transform keeps it uncompressed so Range
+// requests and Content-Length match the stored file.
+func (h *ExportHandler) serveExport(w http.ResponseWriter, r *http.Request, job *exports.ExportJob, file io.ReadSeeker) {
+	w.Header().Set("Content-Type", exportContentType(job.FileName))
+	w.Header().Set("Content-Disposition", "attachment; filename="+job.FileName)
+	w.Header().Set("ETag", `"`+job.ID.String()+`"`)
+	if h.cacheTTL > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d, immutable, no-transform", int(h.cacheTTL.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", "no-store, no-transform")
+	}
+	modified := job.RequestedAt
+	if job.CompletedAt != nil {
+		modified = *job.CompletedAt
+	}
+	http.ServeContent(w, r, job.FileName, modified, file)
+}
+
+// exportContentType returns the MIME type for a generated export file
+func exportContentType(fileName string) string {
+	if strings.HasSuffix(fileName, ".pdf") {
+		return "application/pdf"
+	}
+	return "text/csv"
 }
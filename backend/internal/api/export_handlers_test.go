@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/exports"
+)
+
+func TestServeExportConditional(t *testing.T) {
+	completed := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	job := &exports.ExportJob{ID: uuid.New(), FileName: "pnl.csv", RequestedAt: completed.Add(-time.Minute), CompletedAt: &completed}
+	etag := `"` + job.ID.String() + `"`
+	content := strings.Repeat("date,revenue\n2024-02-01,1200.50\n", 100)
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		cacheTTL   time.Duration
+		wantStatus int
+		wantBody   bool
+		wantCache  string
+	}{
+		{
+			name:       "unconditional",
+			cacheTTL:   time.Hour,
+			wantStatus: http.StatusOK,
+			wantBody:   true,
+			wantCache:  "private, max-age=3600, immutable, no-transform",
+		},
+		{
+			name:       "matching If-None-Match",
+			header:     http.Header{"If-None-Match": {etag}},
+			cacheTTL:   time.Hour,
+			wantStatus: http.StatusNotModified,
+			wantCache:  "private, max-age=3600, immutable, no-transform",
+		},
+		{
+			name:       "weak and listed If-None-Match",
+			header:     http.Header{"If-None-Match": {`"other", W/` + etag}},
+			cacheTTL:   time.Hour,
+			wantStatus: http.StatusNotModified,
+			wantCache:  "private, max-age=3600, immutable, no-transform",
+		},
+		{
+			name:       "stale If-None-Match",
+			header:     http.Header{"If-None-Match": {`"` + uuid.NewString() + `"`}},
+			cacheTTL:   time.Hour,
+			wantStatus: http.StatusOK,
+			wantBody:   true,
+			wantCache:  "private, max-age=3600, immutable, no-transform",
+		},
+		{
+			name:       "If-Modified-Since after completion",
+			header:     http.Header{"If-Modified-Since": {completed.Add(time.Hour).Format(http.TimeFormat)}},
+			wantStatus: http.StatusNotModified,
+			wantCache:  "no-store, no-transform",
+		},
+		{
+			name:       "caching disabled",
+			wantStatus: http.StatusOK,
+			wantBody:   true,
+			wantCache:  "no-store, no-transform",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &ExportHandler{cacheTTL: tt.cacheTTL}
+			r := httptest.NewRequest(http.MethodGet, "/exports/"+job.ID.String()+"/download", nil)
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+
+			h.serveExport(w, r, job, bytes.NewReader([]byte(content)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag = %q, want %q", got, etag)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCache)
+			}
+			if gotBody := w.Body.String(); tt.wantBody && gotBody != content || !tt.wantBody && gotBody != "" {
+				t.Errorf("body = %d bytes, want content %v", len(gotBody), tt.wantBody)
+			}
+		})
+	}
+}
+
+// Stored exports opt out of compression so byte ranges match the file
+func TestServeExportNotCompressed(t *testing.T) {
+	completed := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	job := &exports.ExportJob{ID: uuid.New(), FileName: "pnl.csv", CompletedAt: &completed}
+	content := strings.Repeat("date,revenue\n2024-02-01,1200.50\n", 100)
+	h := &ExportHandler{cacheTTL: time.Hour}
+	handler := compress(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.serveExport(w, r, job, bytes.NewReader([]byte(content)))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/exports/"+job.ID.String()+"/download", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" || w.Body.String() != content {
+		t.Errorf("got status %d, Content-Encoding %q, %d bytes; want the file as stored", w.Code, w.Header().Get("Content-Encoding"), w.Body.Len())
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/exports/"+job.ID.String()+"/download", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("Range", "bytes=0-11")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusPartialContent || w.Body.String() != content[:12] {
+		t.Errorf("range got status %d, body %q; want 206 and %q", w.Code, w.Body.String(), content[:12])
+	}
+}
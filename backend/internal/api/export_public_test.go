@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/audit"
+	"github.com/lakehouse/restaurant-finance/internal/exports"
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+// fakeLocations is a deployment whose only location is id; uuid.Nil means it
+// has several, so none is the default
+type fakeLocations struct {
+	id uuid.UUID
+}
+
+func (f fakeLocations) DefaultLocationID(ctx context.Context) (uuid.UUID, error) {
+	if f.id == uuid.Nil {
+		return uuid.Nil, pgx.ErrNoRows
+	}
+	return f.id, nil
+}
+
+func (f fakeLocations) FiscalYearStart(ctx context.Context, locationID uuid.UUID) (kpi.FiscalYearStart, error) {
+	return kpi.DefaultFiscalYearStart, nil
+}
+
+func TestPublicExportNeedsLocation(t *testing.T) {
+	h := &ExportHandler{locations: fakeLocations{}}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/exports/pnl", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	h.HandlePnL(w, r)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "location_id is required") {
+		t.Errorf("status = %d, body = %s, want 400 asking for a location_id", w.Code, w.Body)
+	}
+}
+
+// TestPublicExportSeedUUIDs runs anonymous exports against a location and
+// system user created fresh, so nothing can depend on the UUIDs of any
+// particular seed data
+func TestPublicExportSeedUUIDs(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	var locationID, systemUser uuid.UUID
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO locations (name, timezone) VALUES ('Public export test', 'UTC') RETURNING id
+	`).Scan(&locationID); err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash) VALUES ($1, 'x') RETURNING id
+	`, "system-"+uuid.NewString()[:8]+"@example.com").Scan(&systemUser); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM audit_log WHERE entity_id IN (SELECT id FROM export_jobs WHERE location_id = $1)`,
+			`DELETE FROM export_jobs WHERE location_id = $1`,
+			`DELETE FROM kpi_aggregates WHERE location_id = $1`,
+			`DELETE FROM locations WHERE id = $1`,
+		} {
+			if _, err := pool.Exec(ctx, q, locationID); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+		if _, err := pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, systemUser); err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+	})
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO kpi_aggregates (date, location_id, revenue, net_profit, covers) VALUES ('2024-03-01', $1, 1234.50, 434.25, 40)
+	`, locationID); err != nil {
+		t.Fatalf("seed aggregates: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		locations  fakeLocations
+		query      string
+		systemUser uuid.UUID
+	}{
+		{name: "single venue default", locations: fakeLocations{id: locationID}, systemUser: systemUser},
+		{name: "explicit location", query: "?location_id=" + locationID.String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &ExportHandler{
+				service:    exports.NewExportService(pool, pool, nil, exports.ExportConfig{}),
+				locations:  tt.locations,
+				systemUser: tt.systemUser,
+				auditLog:   audit.NewLogger(pool),
+			}
+			body := `{"start_date":"2024-03-01","end_date":"2024-03-01","summary_only":true}`
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/exports/pnl"+tt.query, strings.NewReader(body))
+			w := httptest.NewRecorder()
+			h.HandlePnL(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), "1234.50") {
+				t.Errorf("export = %s, want the location's revenue", w.Body)
+			}
+
+			var requestedBy *uuid.UUID
+			if err := pool.QueryRow(ctx, `
+				SELECT requested_by FROM export_jobs WHERE location_id = $1 ORDER BY requested_at DESC LIMIT 1
+			`, locationID).Scan(&requestedBy); err != nil {
+				t.Fatalf("query export job: %v", err)
+			}
+			switch {
+			case tt.systemUser == uuid.Nil && requestedBy != nil:
+				t.Errorf("requested_by = %v, want none without a system user", *requestedBy)
+			case tt.systemUser != uuid.Nil && (requestedBy == nil || *requestedBy != tt.systemUser):
+				t.Errorf("requested_by = %v, want the system user %v", requestedBy, tt.systemUser)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// kpiFields are the metric names a client may request with ?fields=
+var kpiFields = map[string]bool{
+	"revenue":           true,
+	"cogs":              true,
+	"gross_margin":      true,
+	"labor_cost":        true,
+	"labor_pct":         true,
+	"opex":              true,
+	"net_profit":        true,
+	"covers":            true,
+	"avg_check":         true,
+	"discounts":         true,
+	"comps":             true,
+	"open_days":         true,
+	"closed_days":       true,
+	"avg_daily_revenue": true,
+	"commissions":       true,
+	"net_revenue":       true,
+}
+
+// kpiIdentityFields identify a row and are kept whatever fields are requested
+var kpiIdentityFields = map[string]bool{
+	"label":               true,
+	"display_name":        true,
+	"date":                true,
+	"closed":              true,
+	"freshness_timestamp": true,
+}
+
+// parseFields reads the comma-separated fields query parameter. A nil set
+// means every field was requested.
+func parseFields(r *http.Request) (map[string]bool, error) {
+	v := r.URL.Query().Get("fields")
+	if v == "" {
+		return nil, nil
+	}
+
+	fields := make(map[string]bool)
+	var unknown []string
+	for _, f := range strings.Split(v, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !kpiFields[f] {
+			unknown = append(unknown, f)
+			continue
+		}
+		fields[f] = true
+	}
+	if len(unknown) > 0 {
+		known := make([]string, 0, len(kpiFields))
+		for f := range kpiFields {
+			known = append(known, f)
+		}
+		sort.Strings(known)
+		return nil, fmt.Errorf("unknown fields: %s (known fields: %s)", strings.Join(unknown, ", "), strings.Join(known, ", "))
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// sparseKPIResponse trims the metrics in a KPI response's totals and
+// breakdown rows to the requested fields, keeping top-level metadata and the
+// keys that identify each row
+func sparseKPIResponse(response interface{}, fields map[string]bool) (interface{}, error) {
+	if fields == nil {
+		return response, nil
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	var top map[string]interface{}
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, err
+	}
+
+	for key, value := range top {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			top[key] = pickKPIFields(v, fields)
+		case []interface{}:
+			for i, item := range v {
+				if obj, ok := item.(map[string]interface{}); ok {
+					v[i] = pickKPIFields(obj, fields)
+				}
+			}
+		}
+	}
+	return top, nil
+}
+
+func pickKPIFields(obj map[string]interface{}, fields map[string]bool) map[string]interface{} {
+	for key := range obj {
+		if !fields[key] && !kpiIdentityFields[key] {
+			delete(obj, key)
+		}
+	}
+	return obj
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    map[string]bool
+		wantErr string
+	}{
+		{query: ""},
+		{query: "fields=revenue,covers", want: map[string]bool{"revenue": true, "covers": true}},
+		{query: "fields=+revenue+,,covers,", want: map[string]bool{"revenue": true, "covers": true}},
+		{query: "fields=,", want: nil},
+		{query: "fields=revenue,profit,margin", wantErr: "unknown fields: profit, margin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseFields(httptest.NewRequest(http.MethodGet, "/kpi/daily?"+tt.query, nil))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseFields() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFields() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// sortedKeys returns the keys of a decoded JSON object
+func sortedKeys(obj interface{}) []string {
+	var keys []string
+	for k := range obj.(map[string]interface{}) {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestSparseKPIResponse(t *testing.T) {
+	response := &kpi.DailyKPIResponse{
+		FreshnessTimestamp: time.Date(2024, 3, 2, 6, 0, 0, 0, time.UTC),
+		Range:              "7d",
+		Totals:             &kpi.KPITotals{Revenue: 1750.75, COGS: 500, NetProfit: 900, Covers: 70, AvgCheck: 25.01},
+		ByChannel:          []kpi.KPISummary{{Label: "dine_in", DisplayName: "Dine In", Revenue: 1200, Covers: 50, NetProfit: 700}},
+		ByDaypart:          []kpi.KPISummary{{Label: "lunch", DisplayName: "Lunch", Revenue: 550.75, Covers: 20}},
+		Daily:              []kpi.DailyPoint{{Date: "2024-03-01", Revenue: 1750.75, Covers: 70, LaborCost: 300}},
+	}
+
+	body, err := sparseKPIResponse(response, map[string]bool{"revenue": true, "covers": true})
+	if err != nil {
+		t.Fatalf("sparseKPIResponse() error = %v", err)
+	}
+	// Round-trip through JSON as the handler's encoder would
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["range"] != "7d" || got["freshnessTimestamp"] == nil {
+		t.Errorf("top-level metadata = %v, %v, want it kept", got["range"], got["freshnessTimestamp"])
+	}
+
+	tests := []struct {
+		name string
+		obj  interface{}
+		want []string
+	}{
+		{name: "totals", obj: got["totals"], want: []string{"covers", "freshness_timestamp", "revenue"}},
+		{name: "byChannel", obj: got["byChannel"].([]interface{})[0], want: []string{"covers", "display_name", "label", "revenue"}},
+		{name: "byDaypart", obj: got["byDaypart"].([]interface{})[0], want: []string{"covers", "display_name", "label", "revenue"}},
+		{name: "daily", obj: got["daily"].([]interface{})[0], want: []string{"closed", "covers", "date", "revenue"}},
+	}
+	for _, tt := range tests {
+		if keys := sortedKeys(tt.obj); !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("%s keys = %v, want %v", tt.name, keys, tt.want)
+		}
+	}
+	if totals := got["totals"].(map[string]interface{}); totals["revenue"] != 1750.75 || totals["covers"] != float64(70) {
+		t.Errorf("totals = %v, want the revenue and covers values unchanged", totals)
+	}
+
+	// Without fields the response is returned as is
+	if all, err := sparseKPIResponse(response, nil); err != nil || all != interface{}(response) {
+		t.Errorf("sparseKPIResponse(nil) = %v, %v, want the response unchanged", all, err)
+	}
+}
+
+func TestHandleDailyRejectsUnknownFields(t *testing.T) {
+	s := testServer(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/kpi/daily?location_id="+uuid.NewString()+"&fields=revenue,profit", nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), "unknown fields: profit") {
+		t.Errorf("body = %s, want the unknown field named", w.Body)
+	}
+}
@@ -2,15 +2,26 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
+	"github.com/lakehouse/restaurant-finance/internal/aggregates"
+	"github.com/lakehouse/restaurant-finance/internal/audit"
 	"github.com/lakehouse/restaurant-finance/internal/auth"
 	"github.com/lakehouse/restaurant-finance/internal/config"
+	"github.com/lakehouse/restaurant-finance/internal/exports"
 	"github.com/lakehouse/restaurant-finance/internal/imports"
 )
 
@@ -18,24 +29,36 @@ import (
 type ImportHandler struct {
 	pipeline     *imports.Pipeline
 	importStore  *imports.ImportStore
+	anomalies    anomalyStore
 	mappingStore *imports.MappingStore
+	auditLog     *audit.Logger
+	refresher    *aggregates.Refresher // nil leaves aggregate refreshes to the worker
 	uploadCfg    config.FileUploadConfig
 }
 
 // NewImportHandler creates a new import handler
-func NewImportHandler(pipeline *imports.Pipeline, importStore *imports.ImportStore, mappingStore *imports.MappingStore) *ImportHandler {
+func NewImportHandler(pipeline *imports.Pipeline, importStore *imports.ImportStore, mappingStore *imports.MappingStore, auditLog *audit.Logger, refresher *aggregates.Refresher) *ImportHandler {
 	return &ImportHandler{
 		pipeline:     pipeline,
 		importStore:  importStore,
+		anomalies:    importStore,
 		mappingStore: mappingStore,
+		auditLog:     auditLog,
+		refresher:    refresher,
 		uploadCfg:    config.DefaultFileUploadConfig(),
 	}
 }
 
+// maxIdempotencyKeyLen is the longest Idempotency-Key header accepted, matching the column
+const maxIdempotencyKeyLen = 255
+
 // CreateImportRequest represents the import creation request
 type CreateImportRequest struct {
 	SourceType string  `json:"source_type"`
 	MappingID  *string `json:"mapping_id,omitempty"`
+	Atomic     bool    `json:"atomic,omitempty"`
+	// ConflictStrategy is update (default), skip or reject
+	ConflictStrategy string `json:"conflict_strategy,omitempty"`
 }
 
 // HandleCreate handles POST /imports requests
@@ -43,33 +66,33 @@ func (h *ImportHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	claims := auth.GetUserClaims(ctx)
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Parse multipart form (10 MB max)
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Failed to parse form")
 		return
 	}
 
 	// Get file
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "File is required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, codeBadRequest, "File is required")
 		return
 	}
 	defer file.Close()
 
 	// Validate file upload (size, extension, path traversal)
 	if err := config.ValidateFileUpload(header, h.uploadCfg); err != nil {
-		http.Error(w, "Invalid file: "+err.Error(), http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid file: "+err.Error())
 		return
 	}
 
 	// Validate file content (MIME type check)
 	if err := config.ValidateFileContent(file, h.uploadCfg); err != nil {
-		http.Error(w, "Invalid file content: "+err.Error(), http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid file content: "+err.Error())
 		return
 	}
 
@@ -91,33 +114,341 @@ func (h *ImportHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Read file content into buffer for hashing and reuse
-	buf := &bytes.Buffer{}
-	if _, err := io.Copy(buf, file); err != nil {
-		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+	// All-or-nothing imports roll back entirely if any row fails
+	atomic, _ := strconv.ParseBool(r.FormValue("atomic"))
+
+	// A retried request with the same key gets the job the first one created
+	idempotencyKey := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	if len(idempotencyKey) > maxIdempotencyKeyLen {
+		respondError(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLen))
+		return
+	}
+
+	// How POS rows that were already imported are handled
+	conflictStrategy := r.FormValue("conflict_strategy")
+	if conflictStrategy == "" {
+		conflictStrategy = imports.ConflictUpdate
+	}
+	if !imports.ValidConflictStrategy(conflictStrategy) {
+		respondError(w, http.StatusBadRequest, codeBadRequest, imports.ErrInvalidConflictStrategy.Error())
+		return
+	}
+
+	// Keep the file for hashing and reuse; large files are spooled to disk and streamed
+	upload, err := newUploadSource(file, h.pipeline.ShouldStream(header.Size))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to read file")
+		return
+	}
+	hashReader, err := upload.Reader()
+	if err != nil {
+		upload.Close()
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to read file")
 		return
 	}
 
 	// Start import
 	params := imports.ImportParams{
-		SourceType: sourceType,
-		FileName:   sanitizedFilename,
-		File:       bytes.NewReader(buf.Bytes()),
-		LocationID: claims.LocationID,
-		MappingID:  mappingID,
-		UserID:     claims.UserID,
+		SourceType:       sourceType,
+		FileName:         sanitizedFilename,
+		File:             hashReader,
+		LocationID:       claims.LocationID,
+		MappingID:        mappingID,
+		UserID:           claims.UserID,
+		Atomic:           atomic,
+		ConflictStrategy: conflictStrategy,
+		IdempotencyKey:   idempotencyKey,
 	}
 
 	job, err := h.pipeline.StartImport(ctx, params)
+	if errors.Is(err, imports.ErrIdempotentReplay) {
+		upload.Close()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		json.NewEncoder(w).Encode(job)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusConflict)
+		upload.Close()
+		if errors.Is(err, imports.ErrUnknownSourceType) || errors.Is(err, imports.ErrMappingNotFound) || errors.Is(err, imports.ErrMappingSourceMismatch) {
+			respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+			return
+		}
+		respondError(w, http.StatusConflict, codeConflict, err.Error())
 		return
 	}
 
-	// Process import in background (for now, synchronously)
-	go func() {
-		h.pipeline.ProcessImport(ctx, job.ID, bytes.NewReader(buf.Bytes()))
-	}()
+	if err := h.auditLog.Record(ctx, audit.ActionImportCreate, "import_job", &job.ID, map[string]interface{}{
+		"source_type": job.SourceType,
+		"filename":    job.FileName,
+	}); err != nil {
+		log.Printf("Failed to record import %s: %v", job.ID, err)
+	}
+
+	// Process import in the background. The request context is cancelled when
+	// this handler returns, so the import gets its own; POST /imports/{id}/cancel stops it.
+	h.pipeline.Go(func(bgCtx context.Context) {
+		defer upload.Close()
+		reader, err := upload.Reader()
+		if err != nil {
+			log.Printf("Failed to reopen upload for import %s: %v", job.ID, err)
+			return
+		}
+		if err := h.pipeline.ProcessImport(bgCtx, job.ID, reader, upload.size); err == nil {
+			h.refreshAggregates(bgCtx, job.ID)
+		}
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// refreshAggregates queues a KPI aggregate refresh for the days a completed
+// import touched, so dashboards don't wait for the next worker run
+func (h *ImportHandler) refreshAggregates(ctx context.Context, jobID uuid.UUID) {
+	if h.refresher == nil {
+		return
+	}
+	err := h.refresher.EnqueueImport(ctx, jobID)
+	if err != nil && !errors.Is(err, aggregates.ErrNoImportDates) {
+		log.Printf("Failed to queue aggregate refresh for import %s: %v", jobID, err)
+	}
+}
+
+// uploadSource holds an uploaded file so it can be read once for hashing and
+// again for processing. Small files stay in memory; large ones are spooled to a
+// temporary file, since multipart temp files are removed when the request ends.
+type uploadSource struct {
+	data []byte
+	file *os.File
+	size int64
+}
+
+func newUploadSource(r io.Reader, spool bool) (*uploadSource, error) {
+	if !spool {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return &uploadSource{data: data, size: int64(len(data))}, nil
+	}
+
+	f, err := os.CreateTemp("", "import-*.csv")
+	if err != nil {
+		return nil, err
+	}
+	size, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &uploadSource{file: f, size: size}, nil
+}
+
+// Reader returns a reader positioned at the start of the file
+func (u *uploadSource) Reader() (io.Reader, error) {
+	if u.file == nil {
+		return bytes.NewReader(u.data), nil
+	}
+	if _, err := u.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return u.file, nil
+}
+
+// Close releases the spooled file, if any
+func (u *uploadSource) Close() {
+	if u.file != nil {
+		u.file.Close()
+		os.Remove(u.file.Name())
+	}
+}
+
+// previewSampleSize is how much of an upload is inspected for dialect detection
+const previewSampleSize = 64 * 1024
+
+// Number of mapped rows returned by a preview
+const (
+	defaultPreviewRows = 20
+	maxPreviewRows     = 100
+)
+
+// PreviewResponse describes how an upload would be read before it is imported
+type PreviewResponse struct {
+	FileName string          `json:"file_name"`
+	Dialect  imports.Dialect `json:"dialect"`
+	Headers  []string        `json:"headers"`
+	*imports.PreviewResult
+}
+
+// HandlePreview handles POST /imports/preview requests. The file is parsed
+// with the chosen source_type and mapping_id as a dry run: the first limit
+// mapped rows and the validation counts are returned, but no job is created
+// and nothing is written.
+func (h *ImportHandler) HandlePreview(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserClaims(r.Context())
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Failed to parse form")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "File is required")
+		return
+	}
+	defer file.Close()
+
+	if err := config.ValidateFileUpload(header, h.uploadCfg); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid file: "+err.Error())
+		return
+	}
+
+	sample, err := io.ReadAll(io.LimitReader(file, previewSampleSize))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to read file")
+		return
+	}
+
+	dialect := imports.DetectDialect(sample)
+
+	// Read the first row with the detected dialect so the UI can show columns
+	headers, _ := dialect.ReadHeader(sample)
+
+	sourceType := r.FormValue("source_type")
+	if sourceType == "" {
+		sourceType = "pos"
+	}
+
+	var mappingID *uuid.UUID
+	if mappingIDStr := r.FormValue("mapping_id"); mappingIDStr != "" {
+		id, err := uuid.Parse(mappingIDStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid mapping_id")
+			return
+		}
+		mappingID = &id
+	}
+
+	limit := defaultPreviewRows
+	if limitStr := r.FormValue("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 0 {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid limit")
+			return
+		}
+		limit = min(n, maxPreviewRows)
+	}
+
+	// Parse the whole upload so the counts cover every row, not just the sample
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to read file")
+		return
+	}
+	result, err := h.pipeline.Preview(r.Context(), sourceType, claims.LocationID, mappingID, file, limit)
+	if errors.Is(err, imports.ErrUnknownSourceType) || errors.Is(err, imports.ErrMappingNotFound) || errors.Is(err, imports.ErrMappingSourceMismatch) {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Failed to parse file: "+err.Error())
+		return
+	}
+
+	response := PreviewResponse{
+		FileName:      config.SanitizeFilename(header.Filename),
+		Dialect:       dialect,
+		Headers:       headers,
+		PreviewResult: result,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleRetry handles POST /imports/{id}/retry requests. The failed or interrupted job is
+// reset and reprocessed from its stored upload.
+func (h *ImportHandler) HandleRetry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid import ID")
+		return
+	}
+
+	existing, err := h.importStore.GetJobByID(ctx, id)
+	if err != nil || existing.LocationID != claims.LocationID {
+		respondError(w, http.StatusNotFound, codeNotFound, "Import not found")
+		return
+	}
+
+	job, file, size, err := h.pipeline.PrepareRetry(ctx, id)
+	switch {
+	case errors.Is(err, imports.ErrNotRetryable):
+		respondError(w, http.StatusConflict, codeConflict, err.Error())
+		return
+	case errors.Is(err, imports.ErrUploadNotStored):
+		respondError(w, http.StatusGone, codeGone, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to retry import")
+		return
+	}
+
+	h.pipeline.Go(func(bgCtx context.Context) {
+		defer file.Close()
+		if err := h.pipeline.ProcessImport(bgCtx, job.ID, file, size); err == nil {
+			h.refreshAggregates(bgCtx, job.ID)
+		}
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// HandleCancel handles POST /imports/{id}/cancel requests. The import stops
+// at its next row and is marked cancelled with none of its rows kept.
+func (h *ImportHandler) HandleCancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid import ID")
+		return
+	}
+
+	job, err := h.importStore.GetJobByID(ctx, id)
+	if err != nil || job.LocationID != claims.LocationID {
+		respondError(w, http.StatusNotFound, codeNotFound, "Import not found")
+		return
+	}
+	if job.Status != "pending" && job.Status != "processing" {
+		respondError(w, http.StatusConflict, codeConflict, "Only pending or processing imports can be cancelled")
+		return
+	}
+	if !h.pipeline.Cancel(id) {
+		respondError(w, http.StatusConflict, codeConflict, "Import is not running on this server")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
@@ -131,16 +462,21 @@ func (h *ImportHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "Invalid import ID", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid import ID")
 		return
 	}
 
 	job, err := h.importStore.GetJobByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Import not found", http.StatusNotFound)
+		respondError(w, http.StatusNotFound, codeNotFound, "Import not found")
 		return
 	}
 
+	// Rows are committed together, so report live progress for an import running here
+	if rows, ok := h.pipeline.Progress(id); ok {
+		job.ProcessedRows = rows
+	}
+
 	// Get anomalies
 	anomalies, _ := h.importStore.GetAnomaliesForJob(ctx, id)
 
@@ -153,18 +489,158 @@ func (h *ImportHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// AnomalyListResponse is a page of an import's anomalies
+type AnomalyListResponse struct {
+	Data       []imports.ImportAnomaly `json:"data"`
+	Total      int                     `json:"total"`
+	Page       int                     `json:"page"`
+	PageSize   int                     `json:"page_size"`
+	TotalPages int                     `json:"total_pages"`
+}
+
+// anomalyStore is the part of the import store the anomaly listing reads
+type anomalyStore interface {
+	GetJobByID(ctx context.Context, id uuid.UUID) (*imports.ImportJob, error)
+	ListAnomalies(ctx context.Context, filter imports.AnomalyFilter) ([]imports.ImportAnomaly, int, error)
+	EachAnomaly(ctx context.Context, jobID uuid.UUID, severity string, fn func(imports.ImportAnomaly) error) error
+}
+
+// HandleAnomalies handles GET /imports/{id}/anomalies requests. Results can be
+// narrowed with severity=error|warning; format=csv downloads every matching
+// anomaly instead of a page so the source file can be fixed and re-uploaded.
+func (h *ImportHandler) HandleAnomalies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid import ID")
+		return
+	}
+
+	severity := r.URL.Query().Get("severity")
+	if severity != "" && severity != "error" && severity != "warning" {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "severity must be error or warning")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "format must be json or csv")
+		return
+	}
+
+	if _, err := h.anomalies.GetJobByID(ctx, id); err != nil {
+		respondError(w, http.StatusNotFound, codeNotFound, "Import not found")
+		return
+	}
+
+	if format == "csv" {
+		h.streamAnomaliesCSV(w, r, id, severity)
+		return
+	}
+
+	filter := imports.AnomalyFilter{
+		JobID:    id,
+		Severity: severity,
+		Page:     1,
+		PageSize: 100,
+	}
+	if v := r.URL.Query().Get("page"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil && p > 0 {
+			filter.Page = p
+		}
+	}
+	if v := r.URL.Query().Get("page_size"); v != "" {
+		if ps, err := strconv.Atoi(v); err == nil && ps > 0 && ps <= 500 {
+			filter.PageSize = ps
+		}
+	}
+
+	anomalies, total, err := h.anomalies.ListAnomalies(ctx, filter)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to load anomalies")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AnomalyListResponse{
+		Data:       anomalies,
+		Total:      total,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		TotalPages: (total + filter.PageSize - 1) / filter.PageSize,
+	})
+}
+
+// streamAnomaliesCSV writes an import's anomalies as a CSV download row by
+// row. Once the header is sent a database error can only truncate the file,
+// so it is logged.
+func (h *ImportHandler) streamAnomaliesCSV(w http.ResponseWriter, r *http.Request, id uuid.UUID, severity string) {
+	fileName := "import-" + id.String() + "-anomalies"
+	if severity != "" {
+		fileName += "-" + severity
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename="+fileName+".csv")
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"line_number", "severity", "message", "raw_data"})
+	err := h.anomalies.EachAnomaly(r.Context(), id, severity, func(a imports.ImportAnomaly) error {
+		line := ""
+		if a.LineNumber > 0 {
+			line = strconv.Itoa(a.LineNumber)
+		}
+		return writer.Write([]string{line, a.Severity, a.Message, a.RawData})
+	})
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		log.Printf("import %s: anomaly CSV export failed: %v", id, err)
+	}
+}
+
+// HandleReport handles GET /imports/{id}/report requests
+func (h *ImportHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid import ID")
+		return
+	}
+
+	job, err := h.importStore.GetJobByID(ctx, id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, codeNotFound, "Import not found")
+		return
+	}
+
+	anomalies, err := h.importStore.GetAnomaliesForJob(ctx, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to load anomalies")
+		return
+	}
+
+	fileName, data := exports.GenerateImportReport(job, anomalies)
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+	w.Write(data)
+}
+
 // HandleList handles GET /imports requests
 func (h *ImportHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	claims := auth.GetUserClaims(ctx)
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
 		return
 	}
 
 	jobs, err := h.importStore.ListJobs(ctx, claims.LocationID, 50)
 	if err != nil {
-		http.Error(w, "Failed to list imports", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to list imports")
 		return
 	}
 
@@ -177,20 +653,21 @@ func (h *ImportHandler) HandleMappingsGet(w http.ResponseWriter, r *http.Request
 	ctx := r.Context()
 	claims := auth.GetUserClaims(ctx)
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
 		return
 	}
 
 	profiles, err := h.mappingStore.GetAll(ctx, claims.LocationID)
 	if err != nil {
-		http.Error(w, "Failed to list mappings", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to list mappings")
 		return
 	}
 
-	// Include default mappings
+	// Include default mappings and the validation rules profiles can enable
 	response := map[string]interface{}{
 		"profiles": profiles,
 		"defaults": imports.DefaultMappings(),
+		"rules":    imports.Rules(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -199,10 +676,15 @@ func (h *ImportHandler) HandleMappingsGet(w http.ResponseWriter, r *http.Request
 
 // CreateMappingRequest represents a mapping profile creation request
 type CreateMappingRequest struct {
-	Name       string                 `json:"name"`
-	SourceType string                 `json:"source_type"`
-	ColumnMaps map[string]string      `json:"column_maps"`
-	Defaults   map[string]interface{} `json:"defaults"`
+	Name         string                 `json:"name"`
+	SourceType   string                 `json:"source_type"`
+	ColumnMaps   map[string]string      `json:"column_maps"`
+	Defaults     map[string]interface{} `json:"defaults"`
+	Encoding     string                 `json:"encoding,omitempty"`
+	Delimiter    string                 `json:"delimiter,omitempty"`     // comma, semicolon, tab, pipe
+	NumberFormat string                 `json:"number_format,omitempty"` // en (1,234.56) or de (1.234,56)
+	DateFormat   string                 `json:"date_format,omitempty"`   // dmy (default) or mdy
+	Rules        []string               `json:"rules,omitempty"`         // validation rules to enable, e.g. total_reconciles
 }
 
 // HandleMappingCreate handles POST /mappings requests
@@ -210,36 +692,239 @@ func (h *ImportHandler) HandleMappingCreate(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 	claims := auth.GetUserClaims(ctx)
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req CreateMappingRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Name == "" || req.SourceType == "" {
-		http.Error(w, "Name and source_type are required", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Name and source_type are required")
+		return
+	}
+	if !imports.IsValidSourceType(req.SourceType) {
+		respondError(w, http.StatusBadRequest, codeBadRequest, imports.ErrUnknownSourceType.Error())
+		return
+	}
+
+	encoding, err := imports.NormalizeEncoding(req.Encoding)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	delimiter, err := imports.NormalizeDelimiter(req.Delimiter)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	numberFormat, err := imports.NormalizeNumberFormat(req.NumberFormat)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	dateFormat, err := imports.NormalizeDateFormat(req.DateFormat)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	if err := imports.ValidateRules(req.SourceType, req.Rules); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
 		return
 	}
 
 	profile := &imports.MappingProfile{
-		Name:        req.Name,
-		SourceType:  req.SourceType,
-		ColumnMaps:  req.ColumnMaps,
-		Defaults:    req.Defaults,
-		LocationID:  claims.LocationID,
-		CreatedByID: claims.UserID,
+		Name:         req.Name,
+		SourceType:   req.SourceType,
+		ColumnMaps:   req.ColumnMaps,
+		Defaults:     req.Defaults,
+		Encoding:     encoding,
+		Delimiter:    delimiter,
+		NumberFormat: numberFormat,
+		DateFormat:   dateFormat,
+		Rules:        req.Rules,
+		LocationID:   claims.LocationID,
+		CreatedByID:  claims.UserID,
 	}
 
 	if err := h.mappingStore.Create(ctx, profile); err != nil {
-		http.Error(w, "Failed to create mapping", http.StatusInternalServerError)
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to create mapping")
 		return
 	}
 
+	if err := h.auditLog.Record(ctx, audit.ActionMappingCreate, "mapping_profile", &profile.ID, map[string]interface{}{
+		"name":        profile.Name,
+		"source_type": profile.SourceType,
+	}); err != nil {
+		log.Printf("Failed to record mapping %s: %v", profile.ID, err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(profile)
 }
+
+// UpdateMappingRequest represents a mapping profile update request. The
+// source type cannot be changed.
+type UpdateMappingRequest struct {
+	Name         string                 `json:"name"`
+	ColumnMaps   map[string]string      `json:"column_maps"`
+	Defaults     map[string]interface{} `json:"defaults"`
+	Encoding     string                 `json:"encoding,omitempty"`
+	Delimiter    string                 `json:"delimiter,omitempty"`     // comma, semicolon, tab, pipe
+	NumberFormat string                 `json:"number_format,omitempty"` // en (1,234.56) or de (1.234,56)
+	DateFormat   string                 `json:"date_format,omitempty"`   // dmy (default) or mdy
+	Rules        []string               `json:"rules,omitempty"`         // validation rules to enable; replaces the current set
+}
+
+// HandleMappingUpdate handles PUT /mappings/{id} requests
+func (h *ImportHandler) HandleMappingUpdate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid mapping ID")
+		return
+	}
+
+	var req UpdateMappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Name == "" {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Name is required")
+		return
+	}
+
+	encoding, err := imports.NormalizeEncoding(req.Encoding)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	delimiter, err := imports.NormalizeDelimiter(req.Delimiter)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	numberFormat, err := imports.NormalizeNumberFormat(req.NumberFormat)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	dateFormat, err := imports.NormalizeDateFormat(req.DateFormat)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	// Rules are checked against the profile's source type, which can't change
+	existing, err := h.mappingStore.GetByID(ctx, id, claims.LocationID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, codeNotFound, "Mapping not found")
+		return
+	}
+	if err := imports.ValidateRules(existing.SourceType, req.Rules); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	profile := &imports.MappingProfile{
+		ID:           id,
+		Name:         req.Name,
+		ColumnMaps:   req.ColumnMaps,
+		Defaults:     req.Defaults,
+		Encoding:     encoding,
+		Delimiter:    delimiter,
+		NumberFormat: numberFormat,
+		DateFormat:   dateFormat,
+		Rules:        req.Rules,
+		LocationID:   claims.LocationID,
+	}
+
+	err = h.mappingStore.Update(ctx, profile)
+	if errors.Is(err, imports.ErrMappingNotFound) {
+		respondError(w, http.StatusNotFound, codeNotFound, "Mapping not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to update mapping")
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"name":        profile.Name,
+		"source_type": existing.SourceType,
+	}
+	if existing.Name != profile.Name {
+		metadata["previous_name"] = existing.Name
+	}
+	if err := h.auditLog.Record(ctx, audit.ActionMappingUpdate, "mapping_profile", &profile.ID, metadata); err != nil {
+		log.Printf("Failed to record mapping %s: %v", profile.ID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// HandleMappingDelete handles DELETE /mappings/{id} requests. Mappings that
+// imports still reference cannot be deleted.
+func (h *ImportHandler) HandleMappingDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid mapping ID")
+		return
+	}
+
+	// Loaded first so the audit entry can name what was deleted
+	existing, err := h.mappingStore.GetByID(ctx, id, claims.LocationID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, codeNotFound, "Mapping not found")
+		return
+	}
+
+	err = h.mappingStore.Delete(ctx, id, claims.LocationID)
+	switch {
+	case errors.Is(err, imports.ErrMappingNotFound):
+		respondError(w, http.StatusNotFound, codeNotFound, "Mapping not found")
+		return
+	case errors.Is(err, imports.ErrMappingInUse):
+		respondError(w, http.StatusConflict, codeConflict, "Cannot delete mapping: "+err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to delete mapping")
+		return
+	}
+
+	if err := h.auditLog.Record(ctx, audit.ActionMappingDelete, "mapping_profile", &id, map[string]interface{}{
+		"name":        existing.Name,
+		"source_type": existing.SourceType,
+	}); err != nil {
+		log.Printf("Failed to record mapping %s: %v", id, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+	"github.com/lakehouse/restaurant-finance/internal/imports"
+)
+
+// fakeAnomalies holds one import's anomalies in line order
+type fakeAnomalies struct {
+	jobID     uuid.UUID
+	anomalies []imports.ImportAnomaly
+	filter    imports.AnomalyFilter // the last filter listed
+}
+
+func (f *fakeAnomalies) GetJobByID(ctx context.Context, id uuid.UUID) (*imports.ImportJob, error) {
+	if id != f.jobID {
+		return nil, errors.New("no rows in result set")
+	}
+	return &imports.ImportJob{ID: id}, nil
+}
+
+func (f *fakeAnomalies) matching(severity string) []imports.ImportAnomaly {
+	var matched []imports.ImportAnomaly
+	for _, a := range f.anomalies {
+		if severity == "" || a.Severity == severity {
+			matched = append(matched, a)
+		}
+	}
+	return matched
+}
+
+func (f *fakeAnomalies) ListAnomalies(ctx context.Context, filter imports.AnomalyFilter) ([]imports.ImportAnomaly, int, error) {
+	f.filter = filter
+	matched := f.matching(filter.Severity)
+	start := (filter.Page - 1) * filter.PageSize
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + filter.PageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[start:end], len(matched), nil
+}
+
+func (f *fakeAnomalies) EachAnomaly(ctx context.Context, jobID uuid.UUID, severity string, fn func(imports.ImportAnomaly) error) error {
+	for _, a := range f.matching(severity) {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// anomalyRouter serves HandleAnomalies with its route parameter
+func anomalyRouter(store *fakeAnomalies) http.Handler {
+	h := &ImportHandler{anomalies: store}
+	r := chi.NewRouter()
+	r.Get("/imports/{id}/anomalies", h.HandleAnomalies)
+	return r
+}
+
+func testAnomalies(jobID uuid.UUID) *fakeAnomalies {
+	return &fakeAnomalies{jobID: jobID, anomalies: []imports.ImportAnomaly{
+		{ImportJobID: jobID, LineNumber: 3, Severity: "error", Message: "invalid date", RawData: `{"date":"31/02/2024"}`},
+		{ImportJobID: jobID, LineNumber: 7, Severity: "warning", Message: "unknown channel, created", RawData: `{"channel":"Kiosk"}`},
+		{ImportJobID: jobID, LineNumber: 9, Severity: "error", Message: "invalid total", RawData: `{"total":"abc"}`},
+		{ImportJobID: jobID, Severity: "warning", Message: "file has no covers column"},
+	}}
+}
+
+func TestHandleAnomaliesPaginates(t *testing.T) {
+	jobID := uuid.New()
+	store := testAnomalies(jobID)
+
+	r := httptest.NewRequest(http.MethodGet, "/imports/"+jobID.String()+"/anomalies?severity=error&page=2&page_size=1", nil)
+	w := httptest.NewRecorder()
+	anomalyRouter(store).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var got AnomalyListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Total != 2 || got.Page != 2 || got.PageSize != 1 || got.TotalPages != 2 {
+		t.Errorf("page = %d of %d (size %d, total %d), want 2 of 2 (size 1, total 2)", got.Page, got.TotalPages, got.PageSize, got.Total)
+	}
+	if len(got.Data) != 1 || got.Data[0].LineNumber != 9 {
+		t.Errorf("data = %+v, want the error on line 9", got.Data)
+	}
+	if store.filter.JobID != jobID || store.filter.Severity != "error" {
+		t.Errorf("filter = %+v, want the job's errors", store.filter)
+	}
+
+	// Out of range page sizes fall back to the default
+	r = httptest.NewRequest(http.MethodGet, "/imports/"+jobID.String()+"/anomalies?page_size=5000", nil)
+	anomalyRouter(store).ServeHTTP(httptest.NewRecorder(), r)
+	if store.filter.Page != 1 || store.filter.PageSize != 100 {
+		t.Errorf("filter = %+v, want page 1 of 100", store.filter)
+	}
+}
+
+func TestHandleAnomaliesCSV(t *testing.T) {
+	jobID := uuid.New()
+
+	r := httptest.NewRequest(http.MethodGet, "/imports/"+jobID.String()+"/anomalies?format=csv&severity=warning", nil)
+	w := httptest.NewRecorder()
+	anomalyRouter(testAnomalies(jobID)).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/csv" {
+		t.Errorf("Content-Type = %q, want text/csv", got)
+	}
+	wantDisposition := "attachment; filename=import-" + jobID.String() + "-anomalies-warning.csv"
+	if got := w.Header().Get("Content-Disposition"); got != wantDisposition {
+		t.Errorf("Content-Disposition = %q, want %q", got, wantDisposition)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	want := [][]string{
+		{"line_number", "severity", "message", "raw_data"},
+		{"7", "warning", "unknown channel, created", `{"channel":"Kiosk"}`},
+		// File-level anomalies have no line
+		{"", "warning", "file has no covers column", ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV = %q, want %q", records, want)
+	}
+}
+
+func TestHandleAnomaliesRejects(t *testing.T) {
+	jobID := uuid.New()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "bad id", path: "/imports/not-a-uuid/anomalies", wantStatus: http.StatusBadRequest, wantCode: codeBadRequest},
+		{name: "bad severity", path: "/imports/" + jobID.String() + "/anomalies?severity=info", wantStatus: http.StatusBadRequest, wantCode: codeBadRequest},
+		{name: "bad format", path: "/imports/" + jobID.String() + "/anomalies?format=xlsx", wantStatus: http.StatusBadRequest, wantCode: codeBadRequest},
+		{name: "unknown import", path: "/imports/" + uuid.NewString() + "/anomalies", wantStatus: http.StatusNotFound, wantCode: codeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			anomalyRouter(testAnomalies(jobID)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			var body errorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode %s: %v", w.Body, err)
+			}
+			if w.Code != tt.wantStatus || body.Error.Code != tt.wantCode {
+				t.Errorf("got %d %s, want %d %s", w.Code, body.Error.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestHandleCreateRejectsLongIdempotencyKey(t *testing.T) {
+	s := testServer(t)
+	token, err := s.jwtService.GenerateToken(uuid.New(), "accounts@example.com", auth.RoleAccountant, uuid.New())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "sales.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("Date,Total\n2024-03-04,12.50\n"))
+	form.WriteField("source_type", "pos")
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/imports", &body)
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r.Header.Set("Idempotency-Key", strings.Repeat("k", maxIdempotencyKeyLen+1))
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+	var resp errorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || !strings.Contains(resp.Error.Message, "Idempotency-Key") {
+		t.Errorf("body = %s, want an Idempotency-Key error", w.Body)
+	}
+}
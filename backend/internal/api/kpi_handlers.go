@@ -1,39 +1,593 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
 	"github.com/lakehouse/restaurant-finance/internal/kpi"
 )
 
 // KPIHandler handles KPI-related HTTP requests
 type KPIHandler struct {
 	service *kpi.Service
+	limits  kpi.RangeLimits
+	stale   *staleCache // last good daily responses, served while the database is down
 }
 
-// NewKPIHandler creates a new KPI handler
-func NewKPIHandler(service *kpi.Service) *KPIHandler {
-	return &KPIHandler{service: service}
+// NewKPIHandler creates a new KPI handler that rejects ranges outside limits.
+// stale may be nil to return errors rather than cached responses.
+func NewKPIHandler(service *kpi.Service, limits kpi.RangeLimits, stale *staleCache) *KPIHandler {
+	return &KPIHandler{service: service, limits: limits, stale: stale}
 }
 
-// HandleDaily handles GET /kpi/daily requests
+// HandleDaily handles GET /kpi/daily requests. An optional fields parameter
+// limits the metrics returned in totals and breakdowns.
 func (h *KPIHandler) HandleDaily(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// Parse query parameters
+	// Every lookup below needs the database, so while it is unreachable the
+	// last good response for the same caller and query is served instead
+	cacheKey := staleKey(r)
+
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil && dbUnavailable(err) {
+		writeUnavailable(w, h.stale, cacheKey)
+		return
+	}
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	// Optional sparse fieldset, e.g. fields=revenue,covers
+	fields, err := parseFields(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	startDate, endDate, rangeStr, status, err := h.parseRange(r, locationID)
+	if err != nil && dbUnavailable(err) {
+		writeUnavailable(w, h.stale, cacheKey)
+		return
+	}
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	// Get KPI data
+	response, err := h.service.GetDailyKPIs(ctx, locationID, startDate, endDate, rangeStr)
+	if err != nil && dbUnavailable(err) {
+		writeUnavailable(w, h.stale, cacheKey)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch KPI data")
+		return
+	}
+
+	body, err := sparseKPIResponse(response, fields)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch KPI data")
+		return
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch KPI data")
+		return
+	}
+	h.stale.put(cacheKey, data, time.Now())
+
+	// Dashboards poll this endpoint, so let them revalidate instead of
+	// downloading an unchanged payload again
+	etag := kpiETag(locationID, startDate, endDate, rangeStr, r.URL.Query().Get("fields"), response.FreshnessTimestamp, data)
+	writeRevalidatable(w, r, etag, data)
+}
+
+// writeRevalidatable writes a per-user JSON body that clients must revalidate
+// before reuse, answering a matching If-None-Match with 304
+func writeRevalidatable(w http.ResponseWriter, r *http.Request, etag string, data []byte) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Add("Vary", "Authorization")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+// kpiETag builds a strong validator for a daily KPI response. The location,
+// range and fields keep tags for different queries apart, and the freshness
+// timestamp and body change whenever aggregates or targets do.
+func kpiETag(locationID uuid.UUID, start, end time.Time, rangeStr, fields string, freshness time.Time, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s|%d|", locationID, start.Format("2006-01-02"), end.Format("2006-01-02"), rangeStr, fields, freshness.UnixNano())
+	h.Write(body)
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing
+// weakly as RFC 9110 requires for GET
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleSeriesCSV handles GET /kpi/series.csv requests, writing the gap-filled
+// daily series for a location with one row per day. The metrics parameter
+// picks the columns after date, e.g. metrics=revenue,covers,net_profit.
+func (h *KPIHandler) HandleSeriesCSV(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	metrics, err := parseSeriesMetrics(r.URL.Query().Get("metrics"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	startDate, endDate, _, status, err := h.parseRange(r, locationID)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	series, err := h.service.DailySeries(ctx, locationID, startDate, endDate)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch KPI data")
+		return
+	}
+
+	fileName := fmt.Sprintf("kpi-series-%s-%s.csv", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+
+	if err := writeSeriesCSV(w, metrics, series); err != nil {
+		log.Printf("kpi series CSV export failed: %v", err)
+	}
+}
+
+// writeSeriesCSV writes a date column and one column per metric, with a row
+// for each point of the series
+func writeSeriesCSV(w io.Writer, metrics []string, series []kpi.DailyPoint) error {
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{"date"}, metrics...))
+	record := make([]string, len(metrics)+1)
+	for _, p := range series {
+		record[0] = p.Date
+		for i, m := range metrics {
+			record[i+1] = p.MetricValue(m)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// parseSeriesMetrics reads the comma-separated metrics parameter of the
+// series export, defaulting to every series metric
+func parseSeriesMetrics(v string) ([]string, error) {
+	if v == "" {
+		return kpi.SeriesMetrics, nil
+	}
+
+	var metrics []string
+	seen := make(map[string]bool)
+	for _, m := range strings.Split(v, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" || seen[m] {
+			continue
+		}
+		if !kpi.IsSeriesMetric(m) {
+			return nil, fmt.Errorf("unknown metric %q (known metrics: %s)", m, strings.Join(kpi.SeriesMetrics, ", "))
+		}
+		seen[m] = true
+		metrics = append(metrics, m)
+	}
+	if len(metrics) == 0 {
+		return kpi.SeriesMetrics, nil
+	}
+	return metrics, nil
+}
+
+// HandleByDiscountReason handles GET /kpi/by-discount-reason requests
+func (h *KPIHandler) HandleByDiscountReason(w http.ResponseWriter, r *http.Request) {
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	startDate, endDate, rangeStr, status, err := h.parseRange(r, locationID)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	response, err := h.service.GetByDiscountReason(r.Context(), locationID, startDate, endDate, rangeStr)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch discount reasons")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleByOrderType handles GET /kpi/by-order-type requests
+func (h *KPIHandler) HandleByOrderType(w http.ResponseWriter, r *http.Request) {
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	startDate, endDate, rangeStr, status, err := h.parseRange(r, locationID)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	response, err := h.service.GetByOrderType(r.Context(), locationID, startDate, endDate, rangeStr)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch order types")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleByServer handles GET /kpi/by-server requests
+func (h *KPIHandler) HandleByServer(w http.ResponseWriter, r *http.Request) {
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	startDate, endDate, rangeStr, status, err := h.parseRange(r, locationID)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	response, err := h.service.GetByServer(r.Context(), locationID, startDate, endDate, rangeStr)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch server breakdown")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleCOGSVariance handles GET /kpi/cogs/variance and /kpi/cogs-variance requests
+func (h *KPIHandler) HandleCOGSVariance(w http.ResponseWriter, r *http.Request) {
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	startDate, endDate, rangeStr, status, err := h.parseRange(r, locationID)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	threshold := kpi.DefaultCOGSVarianceThreshold
+	if thresholdStr := r.URL.Query().Get("threshold"); thresholdStr != "" {
+		threshold, err = strconv.ParseFloat(thresholdStr, 64)
+		if err != nil || threshold < 0 {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid threshold, must be a non-negative percentage")
+			return
+		}
+	}
+
+	response, err := h.service.GetCOGSVariance(r.Context(), locationID, startDate, endDate, rangeStr, threshold)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to compute COGS variance")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleTaxSummary handles GET /kpi/tax-summary requests
+func (h *KPIHandler) HandleTaxSummary(w http.ResponseWriter, r *http.Request) {
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	startDate, endDate, rangeStr, status, err := h.parseRange(r, locationID)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	if _, err := kpi.TaxPeriodTrunc(groupBy); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	response, err := h.service.GetTaxSummary(r.Context(), locationID, startDate, endDate, rangeStr, groupBy)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch tax summary")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleSupplierSpend handles GET /kpi/supplier-spend requests
+func (h *KPIHandler) HandleSupplierSpend(w http.ResponseWriter, r *http.Request) {
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	startDate, endDate, rangeStr, status, err := h.parseRange(r, locationID)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	response, err := h.service.GetSupplierSpend(r.Context(), locationID, startDate, endDate, rangeStr)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch supplier spend")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleGiftCards handles GET /kpi/gift-cards requests
+func (h *KPIHandler) HandleGiftCards(w http.ResponseWriter, r *http.Request) {
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	startDate, endDate, rangeStr, status, err := h.parseRange(r, locationID)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	response, err := h.service.GetGiftCardSummary(r.Context(), locationID, startDate, endDate, rangeStr)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch gift card summary")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleLaborProductivity handles GET /kpi/labor-productivity requests
+func (h *KPIHandler) HandleLaborProductivity(w http.ResponseWriter, r *http.Request) {
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	startDate, endDate, rangeStr, status, err := h.parseRange(r, locationID)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	response, err := h.service.GetLaborProductivity(r.Context(), locationID, startDate, endDate, rangeStr)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch labor productivity")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleDepositReconciliation handles GET /reconciliation/deposits requests.
+// tolerance is the shortfall in currency units allowed before a day is flagged.
+func (h *KPIHandler) HandleDepositReconciliation(w http.ResponseWriter, r *http.Request) {
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	startDate, endDate, rangeStr, status, err := h.parseRange(r, locationID)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	tolerance := kpi.DefaultDepositTolerance
+	if v := r.URL.Query().Get("tolerance"); v != "" {
+		tolerance, err = strconv.ParseFloat(v, 64)
+		if err != nil || tolerance < 0 {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "tolerance must be a non-negative number")
+			return
+		}
+	}
+
+	response, err := h.service.ReconcileDeposits(r.Context(), locationID, startDate, endDate, rangeStr, tolerance)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to reconcile deposits")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleHourly handles GET /kpi/hourly?date=YYYY-MM-DD requests
+func (h *KPIHandler) HandleHourly(w http.ResponseWriter, r *http.Request) {
 	dateStr := r.URL.Query().Get("date")
-	rangeStr := r.URL.Query().Get("range")
+	if dateStr == "" {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "date is required")
+		return
+	}
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "invalid date, use YYYY-MM-DD")
+		return
+	}
+
+	locationID, status, err := resolveLocation(r, h.service)
+	if err != nil {
+		respondError(w, status, errorCode(status), err.Error())
+		return
+	}
+
+	response, err := h.service.GetHourly(r.Context(), locationID, date)
+	if errors.Is(err, kpi.ErrHourlyDisabled) {
+		respondError(w, http.StatusNotFound, codeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch hourly KPIs")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// locationDefaulter finds the location anonymous requests fall back to
+type locationDefaulter interface {
+	DefaultLocationID(ctx context.Context) (uuid.UUID, error)
+}
+
+// resolveLocation picks the location a request is scoped to. Authenticated
+// callers default to their own location and may only request another one if
+// they are owner admins; anonymous dashboard access uses the location_id query
+// parameter or, for single-venue deployments, the only location.
+func resolveLocation(r *http.Request, locations locationDefaulter) (uuid.UUID, int, error) {
+	var requested uuid.UUID
+	if v := r.URL.Query().Get("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return uuid.Nil, http.StatusBadRequest, errors.New("invalid location_id")
+		}
+		requested = id
+	}
+
+	if claims := auth.GetUserClaims(r.Context()); claims != nil {
+		if requested == uuid.Nil {
+			return claims.LocationID, http.StatusOK, nil
+		}
+		if requested != claims.LocationID && claims.Role != auth.RoleOwnerAdmin {
+			return uuid.Nil, http.StatusForbidden, errors.New("not permitted to view this location")
+		}
+		return requested, http.StatusOK, nil
+	}
+
+	if requested != uuid.Nil {
+		return requested, http.StatusOK, nil
+	}
+	id, err := locations.DefaultLocationID(r.Context())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, http.StatusBadRequest, errors.New("location_id is required")
+	}
+	if err != nil {
+		return uuid.Nil, http.StatusInternalServerError, &internalError{msg: "failed to resolve location", cause: err}
+	}
+	return id, http.StatusOK, nil
+}
+
+// parseRange reads the KPI range for a location, starting ytd at its fiscal
+// year, and checks it against the handler's limits
+func (h *KPIHandler) parseRange(r *http.Request, locationID uuid.UUID) (startDate, endDate time.Time, rangeStr string, status int, err error) {
+	fy, err := h.service.FiscalYearStart(r.Context(), locationID)
+	if err != nil {
+		return time.Time{}, time.Time{}, "", http.StatusInternalServerError, &internalError{msg: "failed to load fiscal year", cause: err}
+	}
+	startDate, endDate, rangeStr, err = parseKPIRange(r, fy)
+	if err != nil {
+		return time.Time{}, time.Time{}, "", http.StatusBadRequest, err
+	}
+	if err := h.limits.Check(startDate, endDate, time.Now()); err != nil {
+		return time.Time{}, time.Time{}, "", http.StatusBadRequest, err
+	}
+	return startDate, endDate, rangeStr, http.StatusOK, nil
+}
+
+// parseKPIRange reads the date and range query parameters shared by KPI
+// endpoints. Explicit start and end dates select a custom range.
+func parseKPIRange(r *http.Request, fy kpi.FiscalYearStart) (startDate, endDate time.Time, rangeStr string, err error) {
+	q := r.URL.Query()
+	dateStr := q.Get("date")
+	rangeStr = q.Get("range")
+
+	startStr, endStr := q.Get("start"), q.Get("end")
+	if rangeStr == "custom" || startStr != "" || endStr != "" {
+		if startStr == "" || endStr == "" {
+			return time.Time{}, time.Time{}, "", errors.New("A custom range requires both start and end, use YYYY-MM-DD")
+		}
+		start, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", errors.New("Invalid start format, use YYYY-MM-DD")
+		}
+		end, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", errors.New("Invalid end format, use YYYY-MM-DD")
+		}
+		startDate, endDate, err = kpi.CustomDateRange(start, end)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", err
+		}
+		return startDate, endDate, "custom", nil
+	}
 
 	// Default to today if no date specified
 	var referenceDate time.Time
 	if dateStr != "" {
-		var err error
 		referenceDate, err = time.Parse("2006-01-02", dateStr)
 		if err != nil {
-			http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
-			return
+			return time.Time{}, time.Time{}, "", errors.New("Invalid date format, use YYYY-MM-DD")
 		}
 	} else {
 		// Use Brisbane timezone for "today"
@@ -45,17 +599,10 @@ func (h *KPIHandler) HandleDaily(w http.ResponseWriter, r *http.Request) {
 	if rangeStr == "" {
 		rangeStr = "30d"
 	}
-
-	// Parse date range
-	startDate, endDate := kpi.ParseDateRange(rangeStr, referenceDate)
-
-	// Get KPI data
-	response, err := h.service.GetDailyKPIs(ctx, startDate, endDate, rangeStr)
-	if err != nil {
-		http.Error(w, "Failed to fetch KPI data", http.StatusInternalServerError)
-		return
+	if !kpi.ValidRange(rangeStr) {
+		return time.Time{}, time.Time{}, "", errors.New("Invalid range, use 7d, 30d, mtd, qtd, ytd, trailing12m or custom")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	startDate, endDate = kpi.ParseDateRange(rangeStr, referenceDate, fy)
+	return startDate, endDate, rangeStr, nil
 }
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"0123456789abcdef"`
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        bool
+	}{
+		{name: "exact", ifNoneMatch: etag, want: true},
+		{name: "weak", ifNoneMatch: "W/" + etag, want: true},
+		{name: "in a list", ifNoneMatch: `"aaaa", ` + etag + `, "bbbb"`, want: true},
+		{name: "any", ifNoneMatch: "*", want: true},
+		{name: "different", ifNoneMatch: `"fedcba9876543210"`},
+		{name: "unquoted", ifNoneMatch: "0123456789abcdef"},
+		{name: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMatches(tt.ifNoneMatch, etag); got != tt.want {
+				t.Errorf("etagMatches(%q) = %v, want %v", tt.ifNoneMatch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKPIETag(t *testing.T) {
+	location := uuid.New()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)
+	fresh := time.Date(2024, 3, 8, 2, 0, 0, 0, time.UTC)
+	body := []byte(`{"revenue":1200.5}`)
+	base := kpiETag(location, start, end, "7d", "", fresh, body)
+
+	if !strings.HasPrefix(base, `"`) || !strings.HasSuffix(base, `"`) || len(base) != 34 {
+		t.Fatalf("kpiETag() = %s, want a quoted 32 character hex tag", base)
+	}
+	if again := kpiETag(location, start, end, "7d", "", fresh, body); again != base {
+		t.Errorf("kpiETag() is not stable: %s then %s", base, again)
+	}
+
+	tests := []struct {
+		name string
+		etag string
+	}{
+		{name: "location", etag: kpiETag(uuid.New(), start, end, "7d", "", fresh, body)},
+		{name: "start", etag: kpiETag(location, start.AddDate(0, 0, -1), end, "7d", "", fresh, body)},
+		{name: "end", etag: kpiETag(location, start, end.AddDate(0, 0, 1), "7d", "", fresh, body)},
+		{name: "range", etag: kpiETag(location, start, end, "custom", "", fresh, body)},
+		{name: "fields", etag: kpiETag(location, start, end, "7d", "revenue", fresh, body)},
+		{name: "freshness", etag: kpiETag(location, start, end, "7d", "", fresh.Add(time.Second), body)},
+		{name: "body", etag: kpiETag(location, start, end, "7d", "", fresh, []byte(`{"revenue":1200.6}`))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.etag == base {
+				t.Errorf("changing %s kept ETag %s", tt.name, base)
+			}
+		})
+	}
+}
+
+func TestWriteRevalidatable(t *testing.T) {
+	const etag = `"0123456789abcdef"`
+	data := []byte(`{"revenue":1200.5}`)
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+		wantBody    string
+	}{
+		{name: "unconditional", wantStatus: http.StatusOK, wantBody: string(data) + "\n"},
+		{name: "matching", ifNoneMatch: etag, wantStatus: http.StatusNotModified},
+		{name: "weak match", ifNoneMatch: "W/" + etag, wantStatus: http.StatusNotModified},
+		{name: "changed", ifNoneMatch: `"fedcba9876543210"`, wantStatus: http.StatusOK, wantBody: string(data) + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/kpi/daily?range=7d", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			writeRevalidatable(w, r, etag, data)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag = %q, want %q", got, etag)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "private, no-cache" {
+				t.Errorf("Cache-Control = %q, want private, no-cache", got)
+			}
+		})
+	}
+}
+
+func TestParseKPIRange(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantRange string
+		wantStart string
+		wantEnd   string
+		wantErr   string
+	}{
+		{query: "range=custom&start=2024-02-01&end=2024-02-29", wantRange: "custom", wantStart: "2024-02-01", wantEnd: "2024-02-29"},
+		// Explicit dates select a custom range without naming it
+		{query: "start=2024-02-01&end=2024-02-01", wantRange: "custom", wantStart: "2024-02-01", wantEnd: "2024-02-01"},
+		{query: "range=7d&date=2024-05-15", wantRange: "7d", wantStart: "2024-05-08", wantEnd: "2024-05-15"},
+		{query: "range=mtd&date=2024-05-15", wantRange: "mtd", wantStart: "2024-05-01", wantEnd: "2024-05-15"},
+		{query: "range=qtd&date=2024-05-15", wantRange: "qtd", wantStart: "2024-04-01", wantEnd: "2024-05-15"},
+		{query: "date=2024-05-15", wantRange: "30d", wantStart: "2024-04-15", wantEnd: "2024-05-15"},
+		{query: "range=custom", wantErr: "requires both start and end"},
+		{query: "range=custom&start=2024-02-01", wantErr: "requires both start and end"},
+		{query: "end=2024-02-29", wantErr: "requires both start and end"},
+		{query: "start=2024-02-30&end=2024-03-01", wantErr: "Invalid start"},
+		{query: "start=2024-03-01&end=2024-02-01", wantErr: "end must not be before start"},
+		{query: "range=90d&date=2024-05-15", wantErr: "Invalid range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/kpi/daily?"+tt.query, nil)
+			start, end, rangeStr, err := parseKPIRange(r, kpi.DefaultFiscalYearStart)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseKPIRange() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseKPIRange() error = %v", err)
+			}
+			if rangeStr != tt.wantRange || start.Format("2006-01-02") != tt.wantStart || end.Format("2006-01-02") != tt.wantEnd {
+				t.Errorf("parseKPIRange() = %s %s..%s, want %s %s..%s", rangeStr, start.Format("2006-01-02"), end.Format("2006-01-02"), tt.wantRange, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestCustomRangeOverTwoYearsRejected(t *testing.T) {
+	// The limits the server applies by default
+	limits := kpi.RangeLimits{MaxDays: 731, MaxFutureDays: 1}
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{query: "start=2022-01-01&end=2023-12-31"},
+		// 2024 is a leap year, so these two years are 731 days
+		{query: "start=2024-01-01&end=2025-12-31"},
+		{query: "start=2024-01-01&end=2026-01-01", wantErr: true},
+		{query: "start=2020-01-01&end=2026-01-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			start, end, _, err := parseKPIRange(httptest.NewRequest(http.MethodGet, "/kpi/daily?"+tt.query, nil), kpi.DefaultFiscalYearStart)
+			if err != nil {
+				t.Fatalf("parseKPIRange() error = %v", err)
+			}
+			if err := limits.Check(start, end, now); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSeriesMetrics(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr string
+	}{
+		{value: "", want: kpi.SeriesMetrics},
+		{value: "revenue,covers,net_profit", want: []string{"revenue", "covers", "net_profit"}},
+		{value: " covers , revenue,covers,", want: []string{"covers", "revenue"}},
+		{value: ",", want: kpi.SeriesMetrics},
+		{value: "revenue,profit", wantErr: `unknown metric "profit"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSeriesMetrics(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseSeriesMetrics() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSeriesMetrics() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteSeriesCSV(t *testing.T) {
+	series := []kpi.DailyPoint{
+		{Date: "2024-03-01", Revenue: 1250.5, NetProfit: 310.25, Covers: 48, AvgCheck: 26.05},
+		{Date: "2024-03-02"}, // gap-filled
+		{Date: "2024-03-03", Revenue: 980, NetProfit: -42.1, Covers: 37},
+	}
+
+	var buf bytes.Buffer
+	if err := writeSeriesCSV(&buf, []string{"revenue", "covers", "net_profit"}, series); err != nil {
+		t.Fatalf("writeSeriesCSV() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+
+	want := [][]string{
+		{"date", "revenue", "covers", "net_profit"},
+		{"2024-03-01", "1250.50", "48", "310.25"},
+		{"2024-03-02", "0.00", "0", "0.00"},
+		{"2024-03-03", "980.00", "37", "-42.10"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV = %q, want %q", records, want)
+	}
+}
+
+func TestHandleSeriesCSVRejectsUnknownMetrics(t *testing.T) {
+	s := testServer(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/kpi/series.csv?location_id="+uuid.NewString()+"&metrics=revenue,margin", nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `unknown metric \"margin\"`) {
+		t.Errorf("got %d %s, want 400 naming the metric", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,330 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/aggregates"
+	"github.com/lakehouse/restaurant-finance/internal/audit"
+	"github.com/lakehouse/restaurant-finance/internal/menu"
+)
+
+// maxBulkMenuItems caps one bulk upsert so a bad upload can't hold a
+// transaction open for long
+const maxBulkMenuItems = 5000
+
+// MenuHandler handles menu item and recipe cost requests
+type MenuHandler struct {
+	store     *menu.Store
+	refresher *aggregates.Refresher
+	auditLog  *audit.Logger
+}
+
+// NewMenuHandler creates a new menu handler. refresher may be nil, in which
+// case recipe cost changes reach the aggregates on the worker's next run.
+func NewMenuHandler(store *menu.Store, refresher *aggregates.Refresher, auditLog *audit.Logger) *MenuHandler {
+	return &MenuHandler{store: store, refresher: refresher, auditLog: auditLog}
+}
+
+// MenuItemRequest represents a menu item creation request
+type MenuItemRequest struct {
+	SKU        *string `json:"sku,omitempty"`
+	Name       string  `json:"name"`
+	Category   string  `json:"category"`
+	RecipeCost float64 `json:"recipe_cost"`
+	Price      float64 `json:"price"`
+	Active     *bool   `json:"active,omitempty"` // defaults to true
+}
+
+// item converts the request into a menu item
+func (req MenuItemRequest) item() menu.Item {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return menu.Item{
+		SKU:        req.SKU,
+		Name:       req.Name,
+		Category:   req.Category,
+		RecipeCost: req.RecipeCost,
+		Price:      req.Price,
+		Active:     active,
+	}
+}
+
+// UpdateMenuItemRequest changes the fields that are set and leaves the rest
+type UpdateMenuItemRequest struct {
+	SKU        *string  `json:"sku,omitempty"` // empty clears the SKU
+	Name       *string  `json:"name,omitempty"`
+	Category   *string  `json:"category,omitempty"`
+	RecipeCost *float64 `json:"recipe_cost,omitempty"`
+	Price      *float64 `json:"price,omitempty"`
+	Active     *bool    `json:"active,omitempty"`
+}
+
+// BulkMenuItemsRequest represents a bulk upsert of menu items keyed by SKU
+type BulkMenuItemsRequest struct {
+	Items []MenuItemRequest `json:"items"`
+}
+
+// BulkMenuItemsResponse reports the outcome of a bulk upsert
+type BulkMenuItemsResponse struct {
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Items   []menu.Item `json:"items"`
+}
+
+// HandleList handles GET /menu-items requests. include_inactive=true adds
+// inactive items.
+func (h *MenuHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	includeInactive := r.URL.Query().Get("include_inactive") == "true"
+
+	list, err := h.store.List(r.Context(), includeInactive)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to list menu items")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": list})
+}
+
+// HandleGet handles GET /menu-items/{id} requests
+func (h *MenuHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid menu item ID")
+		return
+	}
+
+	item, err := h.store.Get(r.Context(), id)
+	if errors.Is(err, menu.ErrNotFound) {
+		respondError(w, http.StatusNotFound, codeNotFound, "Menu item not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to get menu item")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// HandleCreate handles POST /menu-items requests. refresh=true queues an
+// aggregate refresh for the days sale lines linked to the new item were sold.
+func (h *MenuHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	var req MenuItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+
+	item := req.item()
+	if err := item.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	err := h.store.Create(r.Context(), &item)
+	if errors.Is(err, menu.ErrSKUTaken) {
+		respondError(w, http.StatusConflict, codeConflict, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to create menu item")
+		return
+	}
+
+	h.record(r, audit.ActionMenuItemCreate, item.ID, map[string]interface{}{
+		"name":        item.Name,
+		"sku":         item.SKU,
+		"recipe_cost": item.RecipeCost,
+	})
+	if r.URL.Query().Get("refresh") == "true" {
+		h.refreshSold(r.Context(), item.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(item)
+}
+
+// HandleUpdate handles PUT /menu-items/{id} requests. refresh=true queues an
+// aggregate refresh for the days the item was sold when its recipe cost or
+// SKU changed.
+func (h *MenuHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid menu item ID")
+		return
+	}
+
+	var req UpdateMenuItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+
+	item, err := h.store.Get(ctx, id)
+	if errors.Is(err, menu.ErrNotFound) {
+		respondError(w, http.StatusNotFound, codeNotFound, "Menu item not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to get menu item")
+		return
+	}
+	previousCost, previousSKU := item.RecipeCost, item.SKU
+
+	if req.SKU != nil {
+		item.SKU = req.SKU
+	}
+	if req.Name != nil {
+		item.Name = *req.Name
+	}
+	if req.Category != nil {
+		item.Category = *req.Category
+	}
+	if req.RecipeCost != nil {
+		item.RecipeCost = *req.RecipeCost
+	}
+	if req.Price != nil {
+		item.Price = *req.Price
+	}
+	if req.Active != nil {
+		item.Active = *req.Active
+	}
+	if err := item.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	err = h.store.Update(ctx, item)
+	switch {
+	case errors.Is(err, menu.ErrNotFound):
+		respondError(w, http.StatusNotFound, codeNotFound, "Menu item not found")
+		return
+	case errors.Is(err, menu.ErrSKUTaken):
+		respondError(w, http.StatusConflict, codeConflict, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to update menu item")
+		return
+	}
+
+	costChanged := item.RecipeCost != previousCost || !sameSKU(item.SKU, previousSKU)
+	metadata := map[string]interface{}{"name": item.Name, "sku": item.SKU}
+	if item.RecipeCost != previousCost {
+		metadata["previous_recipe_cost"] = previousCost
+		metadata["recipe_cost"] = item.RecipeCost
+	}
+	h.record(r, audit.ActionMenuItemUpdate, item.ID, metadata)
+	if costChanged && r.URL.Query().Get("refresh") == "true" {
+		h.refreshSold(ctx, item.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// HandleBulkUpsert handles POST /menu-items/bulk requests, creating or
+// updating every item by SKU in one transaction. refresh=true queues an
+// aggregate refresh for the days any of the items were sold.
+func (h *MenuHandler) HandleBulkUpsert(w http.ResponseWriter, r *http.Request) {
+	var req BulkMenuItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Items) == 0 {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "items is required")
+		return
+	}
+	if len(req.Items) > maxBulkMenuItems {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "too many items; send at most 5000 per request")
+		return
+	}
+
+	items := make([]menu.Item, len(req.Items))
+	for n, itemReq := range req.Items {
+		items[n] = itemReq.item()
+		if err := items[n].Validate(); err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "items["+strconv.Itoa(n)+"]: "+err.Error())
+			return
+		}
+		if items[n].SKU == nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "items["+strconv.Itoa(n)+"]: sku is required for a bulk upsert")
+			return
+		}
+	}
+
+	stored, created, err := h.store.UpsertBySKU(r.Context(), items)
+	if err != nil {
+		log.Printf("Menu item bulk upsert failed: %v", err)
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to save menu items")
+		return
+	}
+
+	response := BulkMenuItemsResponse{Items: stored}
+	ids := make([]uuid.UUID, len(stored))
+	for n := range stored {
+		ids[n] = stored[n].ID
+		if created[n] {
+			response.Created++
+		} else {
+			response.Updated++
+		}
+	}
+
+	if err := h.auditLog.Record(r.Context(), audit.ActionMenuItemImport, "menu_item", nil, map[string]interface{}{
+		"created": response.Created,
+		"updated": response.Updated,
+	}); err != nil {
+		log.Printf("Failed to record %s: %v", audit.ActionMenuItemImport, err)
+	}
+	if r.URL.Query().Get("refresh") == "true" {
+		h.refreshSold(r.Context(), ids...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// refreshSold queues an aggregate refresh for the days the items were sold
+func (h *MenuHandler) refreshSold(ctx context.Context, ids ...uuid.UUID) {
+	if h.refresher == nil {
+		return
+	}
+	ranges, err := h.store.SoldRanges(ctx, ids)
+	if err != nil {
+		log.Printf("Failed to find days to refresh for menu items: %v", err)
+		return
+	}
+	for _, rg := range ranges {
+		h.refresher.Enqueue(rg.LocationID, rg.Start, rg.End)
+	}
+}
+
+// record writes a menu item change to the audit log
+func (h *MenuHandler) record(r *http.Request, action string, id uuid.UUID, metadata map[string]interface{}) {
+	if err := h.auditLog.Record(r.Context(), action, "menu_item", &id, metadata); err != nil {
+		log.Printf("Failed to record %s for menu item %s: %v", action, id, err)
+	}
+}
+
+func sameSKU(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
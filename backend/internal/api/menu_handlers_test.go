@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+)
+
+// TestMenuItemRequestsRejected covers the menu item requests turned away
+// before the store is reached
+func TestMenuItemRequestsRejected(t *testing.T) {
+	s := testServer(t)
+	token := func(role auth.Role) string {
+		v, err := s.jwtService.GenerateToken(uuid.New(), "staff@example.com", role, uuid.New())
+		if err != nil {
+			t.Fatalf("GenerateToken: %v", err)
+		}
+		return v
+	}
+	tooMany := `{"items":[` + strings.TrimSuffix(strings.Repeat(`{"sku":"X","name":"X"},`, maxBulkMenuItems+1), ",") + `]}`
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		role        auth.Role
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "viewer", method: http.MethodGet, path: "/api/v1/menu-items/", role: auth.RoleViewer, wantStatus: http.StatusForbidden},
+		{name: "manager", method: http.MethodPost, path: "/api/v1/menu-items/", body: `{"name":"Burger"}`, role: auth.RoleManager, wantStatus: http.StatusForbidden},
+		{name: "create: bad body", method: http.MethodPost, path: "/api/v1/menu-items/", body: "{", role: auth.RoleAccountant, wantStatus: http.StatusBadRequest, wantMessage: "Invalid request body"},
+		{name: "create: no name", method: http.MethodPost, path: "/api/v1/menu-items/", body: `{"name":"  ","recipe_cost":4}`, role: auth.RoleAccountant, wantStatus: http.StatusBadRequest, wantMessage: "name is required"},
+		{name: "create: negative cost", method: http.MethodPost, path: "/api/v1/menu-items/", body: `{"name":"Burger","recipe_cost":-1}`, role: auth.RoleOwnerAdmin, wantStatus: http.StatusBadRequest, wantMessage: "recipe_cost and price must not be negative"},
+		{name: "get: bad id", method: http.MethodGet, path: "/api/v1/menu-items/not-an-id", role: auth.RoleAccountant, wantStatus: http.StatusBadRequest, wantMessage: "Invalid menu item ID"},
+		{name: "update: bad id", method: http.MethodPut, path: "/api/v1/menu-items/not-an-id", body: `{}`, role: auth.RoleAccountant, wantStatus: http.StatusBadRequest, wantMessage: "Invalid menu item ID"},
+		{name: "bulk: no items", method: http.MethodPost, path: "/api/v1/menu-items/bulk", body: `{"items":[]}`, role: auth.RoleAccountant, wantStatus: http.StatusBadRequest, wantMessage: "items is required"},
+		{name: "bulk: no sku", method: http.MethodPost, path: "/api/v1/menu-items/bulk", body: `{"items":[{"sku":"BURG-01","name":"Burger"},{"sku":" ","name":"Fries"}]}`, role: auth.RoleAccountant, wantStatus: http.StatusBadRequest, wantMessage: "items[1]: sku is required for a bulk upsert"},
+		{name: "bulk: invalid item", method: http.MethodPost, path: "/api/v1/menu-items/bulk", body: `{"items":[{"sku":"BURG-01","name":"Burger","price":-3}]}`, role: auth.RoleAccountant, wantStatus: http.StatusBadRequest, wantMessage: "items[0]: recipe_cost and price must not be negative"},
+		{name: "bulk: too many", method: http.MethodPost, path: "/api/v1/menu-items/bulk", body: tooMany, role: auth.RoleAccountant, wantStatus: http.StatusBadRequest, wantMessage: "too many items; send at most 5000 per request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer "+token(tt.role))
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantMessage == "" {
+				return
+			}
+			var body errorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q is not an error envelope: %v", w.Body, err)
+			}
+			if body.Error.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", body.Error.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestMenuItemRequestDefaultsActive(t *testing.T) {
+	inactive := false
+	if item := (MenuItemRequest{Name: "Burger"}).item(); !item.Active {
+		t.Error("item() without active = inactive, want active")
+	}
+	if item := (MenuItemRequest{Name: "Burger", Active: &inactive}).item(); item.Active {
+		t.Error("item() with active false = active, want inactive")
+	}
+}
+
+func TestSameSKU(t *testing.T) {
+	a, a2, b := "BURG-01", "BURG-01", "FRY-02"
+	tests := []struct {
+		x, y *string
+		want bool
+	}{
+		{nil, nil, true},
+		{&a, &a2, true},
+		{&a, &b, false},
+		{&a, nil, false},
+		{nil, &b, false},
+	}
+	for _, tt := range tests {
+		if got := sameSKU(tt.x, tt.y); got != tt.want {
+			t.Errorf("sameSKU(%v, %v) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
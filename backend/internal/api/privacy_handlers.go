@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/lakehouse/restaurant-finance/internal/audit"
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+	"github.com/lakehouse/restaurant-finance/internal/privacy"
+)
+
+// PrivacyHandler handles data-protection requests
+type PrivacyHandler struct {
+	staff    *privacy.StaffStore
+	auditLog *audit.Logger
+}
+
+// NewPrivacyHandler creates a new privacy handler
+func NewPrivacyHandler(staff *privacy.StaffStore, auditLog *audit.Logger) *PrivacyHandler {
+	return &PrivacyHandler{staff: staff, auditLog: auditLog}
+}
+
+// StaffRequest identifies a staff member by the name their records carry
+type StaffRequest struct {
+	Name string `json:"name"`
+}
+
+// AnonymizeStaffResponse returns the records as they were before anonymizing
+type AnonymizeStaffResponse struct {
+	Tombstone string             `json:"tombstone"`
+	Export    *privacy.StaffData `json:"export"`
+}
+
+// HandleStaffExport handles GET /privacy/staff?name= requests
+func (h *PrivacyHandler) HandleStaffExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "name is required")
+		return
+	}
+
+	data, err := h.staff.Export(ctx, claims.LocationID, name)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to export staff records")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// HandleStaffAnonymize handles POST /privacy/staff/anonymize requests. The
+// staff member's records are returned as they were, then their name is
+// replaced with a tombstone everywhere; amounts are left unchanged.
+func (h *PrivacyHandler) HandleStaffAnonymize(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req StaffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "name is required")
+		return
+	}
+
+	data, tombstone, err := h.staff.Anonymize(ctx, claims.LocationID, req.Name)
+	if errors.Is(err, privacy.ErrNoStaffRecords) {
+		respondError(w, http.StatusNotFound, codeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to anonymize staff records")
+		return
+	}
+
+	// The audit entry must not reintroduce the name being erased
+	if err := h.auditLog.Record(ctx, audit.ActionStaffAnonymize, "staff", nil, map[string]interface{}{
+		"tombstone": tombstone,
+		"sales":     len(data.Sales),
+	}); err != nil {
+		log.Printf("Failed to record staff anonymization %s: %v", tombstone, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AnonymizeStaffResponse{Tombstone: tombstone, Export: data})
+}
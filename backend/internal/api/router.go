@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -12,11 +13,21 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/lakehouse/restaurant-finance/internal/aggregates"
+	"github.com/lakehouse/restaurant-finance/internal/audit"
 	"github.com/lakehouse/restaurant-finance/internal/auth"
 	"github.com/lakehouse/restaurant-finance/internal/config"
+	"github.com/lakehouse/restaurant-finance/internal/digest"
 	"github.com/lakehouse/restaurant-finance/internal/exports"
+	"github.com/lakehouse/restaurant-finance/internal/fieldcrypt"
 	"github.com/lakehouse/restaurant-finance/internal/imports"
 	"github.com/lakehouse/restaurant-finance/internal/kpi"
+	"github.com/lakehouse/restaurant-finance/internal/menu"
+	"github.com/lakehouse/restaurant-finance/internal/notify"
+	"github.com/lakehouse/restaurant-finance/internal/privacy"
+	"github.com/lakehouse/restaurant-finance/internal/sheets"
+	"github.com/lakehouse/restaurant-finance/internal/storage"
+	"github.com/lakehouse/restaurant-finance/internal/users"
 )
 
 // Server holds all dependencies for the HTTP server
@@ -25,36 +36,163 @@ type Server struct {
 	config           *config.Config
 	db               *pgxpool.Pool
 	jwtService       *auth.JWTService
+	refreshStore     *auth.RefreshTokenStore
+	auditLog         *audit.Logger
 	kpiHandler       *KPIHandler
 	importHandler    *ImportHandler
 	drilldownHandler *DrilldownHandler
 	exportHandler    *ExportHandler
+	closedDayHandler *ClosedDayHandler
+	snapshotHandler  *SnapshotHandler
+	settingsHandler  *SettingsHandler
+	privacyHandler   *PrivacyHandler
+	userHandler      *UserHandler
+	menuHandler      *MenuHandler
+	digest           *digest.Scheduler     // nil when the anomaly digest is disabled
+	refresher        *aggregates.Refresher // nil when imports don't refresh aggregates
+	reaper           *imports.Reaper       // nil when stuck imports are left alone
+	readDB           *pgxpool.Pool         // replica for read-heavy queries; db when none is configured
 }
 
 // NewServer creates a new HTTP server
 func NewServer(cfg *config.Config, db *pgxpool.Pool) *Server {
-	// Initialize KPI services
-	kpiStore := kpi.NewStore(db)
-	kpiService := kpi.NewService(kpiStore)
+	readDB := openReadPool(cfg.Database.ReadURL, db)
+
+	// Initialize KPI services. Dashboard reads use the replica; closed days and
+	// snapshots are written and read back straight away, so they use the primary.
+	newKPIStore := kpi.NewStore
+	if cfg.Aggregates.UseRollup {
+		newKPIStore = kpi.NewRollupStore
+	}
+	kpiService := kpi.NewService(newKPIStore(readDB))
+	kpiStore := newKPIStore(db)
+	primaryKPIService := kpi.NewService(kpiStore)
+
+	fileStorage, err := storage.NewFileStorage(cfg.StoragePath)
+	if err != nil {
+		log.Printf("File storage unavailable, uploads and exports will not be stored: %v", err)
+	}
+
+	var fieldCipher *fieldcrypt.Cipher
+	if cfg.Encryption.Key != "" {
+		fieldCipher, err = fieldcrypt.NewCipher(cfg.Encryption.Key)
+		if err != nil {
+			log.Fatalf("Invalid FIELD_ENCRYPTION_KEY: %v", err)
+		}
+	}
 
 	// Initialize import services
-	importPipeline := imports.NewPipeline(db)
+	importPipeline := imports.NewPipeline(db, fileStorage, imports.PipelineConfig{
+		AnomalyCap:               cfg.Import.AnomalyCap,
+		MaxRetries:               cfg.Import.MaxRetries,
+		RetryBackoff:             time.Duration(cfg.Import.RetryBackoffMS) * time.Millisecond,
+		StreamThreshold:          cfg.Import.StreamThreshold,
+		DuplicateHeaders:         cfg.Import.DuplicateHeaders,
+		MangledNumbers:           cfg.Import.MangledNumbers,
+		EnforceMappingSourceType: cfg.Import.EnforceMappingSourceType,
+		InventoryMode:            cfg.Import.InventoryMode,
+		JobTimeout:               time.Duration(cfg.Import.TimeoutMinutes) * time.Minute,
+		FieldCipher:              fieldCipher,
+		EncryptedFields:          cfg.Encryption.Fields,
+		CacheLookups:             cfg.Import.CacheLookups,
+		IdempotencyTTL:           time.Duration(cfg.Import.IdempotencyTTLHours) * time.Hour,
+	})
 	importStore := imports.NewImportStore(db)
 	mappingStore := imports.NewMappingStore(db)
 
+	var refresher *aggregates.Refresher
+	if cfg.Aggregates.RefreshOnImport {
+		refresher = aggregates.NewRefresher(db, aggregates.RefreshOptions{
+			LaborBasis:    cfg.Aggregates.LaborBasis,
+			RefreshRollup: cfg.Aggregates.UseRollup,
+		})
+	}
+
 	// Initialize export services
-	exportService := exports.NewExportService(db)
+	exportService := exports.NewExportService(db, readDB, fileStorage, exports.ExportConfig{
+		CurrencyFormat: cfg.Export.CurrencyFormat,
+		MaxRows:        cfg.Export.MaxRows,
+		OverflowMode:   cfg.Export.OverflowMode,
+	})
 	exportStore := exports.NewExportStore(db)
+	signingKey := cfg.Export.SigningKey
+	if signingKey == "" {
+		signingKey = cfg.JWT.Secret
+	}
+	exportSigner := exports.NewURLSigner(signingKey)
+	linkTTL := time.Duration(cfg.Export.LinkTTLMinutes) * time.Minute
+	var systemUser uuid.UUID
+	if cfg.Export.SystemUserID != "" {
+		if id, err := uuid.Parse(cfg.Export.SystemUserID); err == nil {
+			systemUser = id
+		} else {
+			log.Printf("Invalid EXPORT_SYSTEM_USER_ID %q, anonymous exports record no requester: %v", cfg.Export.SystemUserID, err)
+		}
+	}
+
+	notifier := notify.NewNotifier(notify.Config{
+		SMTPHost:     cfg.Notify.SMTPHost,
+		SMTPPort:     cfg.Notify.SMTPPort,
+		SMTPUsername: cfg.Notify.SMTPUsername,
+		SMTPPassword: cfg.Notify.SMTPPassword,
+		SMTPFrom:     cfg.Notify.SMTPFrom,
+		WebhookURL:   cfg.Notify.WebhookURL,
+		Timeout:      time.Duration(cfg.Notify.TimeoutSeconds) * time.Second,
+	})
+
+	auditLog := audit.NewLogger(db)
+	refreshStore := auth.NewRefreshTokenStore(db)
+
+	var sheetsExporter *sheets.Exporter
+	if cfg.Export.GoogleSheets {
+		sheetsExporter = sheets.NewExporter(sheets.NewCredentialStore(db, fieldCipher), 30*time.Second)
+	}
+
+	var digestScheduler *digest.Scheduler
+	if cfg.Digest.Enabled {
+		sendAt, err := time.Parse("15:04", cfg.Digest.Time)
+		if err != nil {
+			log.Printf("Invalid DIGEST_TIME %q, sending at midnight: %v", cfg.Digest.Time, err)
+		}
+		digestLoc, err := time.LoadLocation(cfg.Digest.Timezone)
+		if err != nil {
+			log.Printf("Invalid DIGEST_TIMEZONE %q, using UTC: %v", cfg.Digest.Timezone, err)
+			digestLoc = time.UTC
+		}
+		digestScheduler = digest.NewScheduler(digest.NewStore(db), notifier, digest.Config{
+			Hour:        sendAt.Hour(),
+			Minute:      sendAt.Minute(),
+			Location:    digestLoc,
+			Recipients:  cfg.Digest.Recipients,
+			Webhook:     cfg.Digest.Webhook,
+			TopMessages: cfg.Digest.TopMessages,
+		})
+	}
 
 	s := &Server{
 		router:           chi.NewRouter(),
 		config:           cfg,
 		db:               db,
-		jwtService:       auth.NewJWTService(cfg.JWT.Secret, cfg.JWT.ExpireHours),
-		kpiHandler:       NewKPIHandler(kpiService),
-		importHandler:    NewImportHandler(importPipeline, importStore, mappingStore),
-		drilldownHandler: NewDrilldownHandler(db),
-		exportHandler:    NewExportHandler(exportService, exportStore),
+		jwtService:       auth.NewJWTService(cfg.JWT.Secret, cfg.JWT.ExpireHours, cfg.JWT.RefreshExpireHours),
+		refreshStore:     refreshStore,
+		auditLog:         auditLog,
+		kpiHandler:       NewKPIHandler(kpiService, kpi.RangeLimits{MaxDays: cfg.KPI.MaxRangeDays, MaxFutureDays: cfg.KPI.MaxFutureDays}, newStaleCache(time.Duration(cfg.KPI.StaleCacheSeconds)*time.Second, cfg.KPI.StaleCacheEntries)),
+		importHandler:    NewImportHandler(importPipeline, importStore, mappingStore, auditLog, refresher),
+		drilldownHandler: NewDrilldownHandler(readDB),
+		exportHandler:    NewExportHandler(exportService, exportStore, exports.NewSavedReportStore(db), kpiService, systemUser, exportSigner, linkTTL, time.Duration(cfg.Export.CacheSeconds)*time.Second, sheetsExporter, auditLog),
+		closedDayHandler: NewClosedDayHandler(kpiStore),
+		snapshotHandler:  NewSnapshotHandler(primaryKPIService),
+		settingsHandler:  NewSettingsHandler(notifier, sheetsExporter),
+		privacyHandler:   NewPrivacyHandler(privacy.NewStaffStore(db), auditLog),
+		userHandler:      NewUserHandler(users.NewStore(db), refreshStore, auditLog),
+		menuHandler:      NewMenuHandler(menu.NewStore(db), refresher, auditLog),
+		digest:           digestScheduler,
+		refresher:        refresher,
+		readDB:           readDB,
+	}
+	if cfg.Import.StaleAfterMinutes > 0 {
+		staleAfter := time.Duration(cfg.Import.StaleAfterMinutes) * time.Minute
+		s.reaper = imports.NewReaper(importPipeline, staleAfter, s.importHandler.refreshAggregates)
 	}
 	s.setupMiddleware()
 	s.setupRoutes()
@@ -62,40 +200,42 @@ func NewServer(cfg *config.Config, db *pgxpool.Pool) *Server {
 }
 
 func (s *Server) setupMiddleware() {
+	s.router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		respondError(w, http.StatusNotFound, codeNotFound, "Not found")
+	})
+	s.router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		respondError(w, http.StatusMethodNotAllowed, codeNotAllowed, "Method not allowed")
+	})
+
 	// Request ID
 	s.router.Use(middleware.RequestID)
+	s.router.Use(exposeRequestID)
 
 	// Real IP
 	s.router.Use(middleware.RealIP)
 
 	// Logging
-	s.router.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
-			defer func() {
-				log.Printf(
-					"[%s] %s %s %d %s",
-					middleware.GetReqID(r.Context()),
-					r.Method,
-					r.URL.Path,
-					ww.Status(),
-					time.Since(start),
-				)
-			}()
-			next.ServeHTTP(ww, r)
-		})
-	})
+	s.router.Use(requestLogger)
 
 	// Recovery
-	s.router.Use(middleware.Recoverer)
+	s.router.Use(recoverer)
+
+	// Compression
+	s.router.Use(compress(s.config.Server.CompressMinBytes))
 
 	// CORS
+	origins := s.config.Server.CORSAllowedOrigins
+	for _, origin := range origins {
+		if origin == "*" {
+			log.Println("Warning: CORS_ALLOWED_ORIGINS is *, so any site can make credentialed requests to the API")
+			break
+		}
+	}
 	s.router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000", "http://127.0.0.1:3000"},
+		AllowedOrigins:   origins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposedHeaders:   []string{"Link", "X-Request-ID"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID", "Idempotency-Key"},
+		ExposedHeaders:   []string{"Link", "X-Request-ID", "Idempotent-Replayed"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -112,28 +252,54 @@ func (s *Server) setupRoutes() {
 	s.router.Route("/api/v1", func(r chi.Router) {
 		// Public routes
 		r.Post("/auth/login", s.handleLogin)
+		r.Post("/auth/refresh", s.handleRefresh)
+		r.Post("/auth/logout", s.handleLogout)
 
 		// Public KPI routes (read-only, for dashboard)
-		r.Get("/kpi/daily", s.kpiHandler.HandleDaily)
-		r.Get("/kpi/drilldown/sales", s.drilldownHandler.HandleSales)
+		r.Group(func(r chi.Router) {
+			r.Use(queryTimeout(s.statementTimeout()))
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/daily", s.kpiHandler.HandleDaily)
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/series.csv", s.kpiHandler.HandleSeriesCSV)
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/by-discount-reason", s.kpiHandler.HandleByDiscountReason)
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/by-order-type", s.kpiHandler.HandleByOrderType)
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/cogs-variance", s.kpiHandler.HandleCOGSVariance)
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/cogs/variance", s.kpiHandler.HandleCOGSVariance)
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/tax-summary", s.kpiHandler.HandleTaxSummary)
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/supplier-spend", s.kpiHandler.HandleSupplierSpend)
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/gift-cards", s.kpiHandler.HandleGiftCards)
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/labor-productivity", s.kpiHandler.HandleLaborProductivity)
+			r.With(auth.OptionalMiddleware(s.jwtService)).Get("/kpi/hourly", s.kpiHandler.HandleHourly)
+			r.Get("/kpi/drilldown/sales", s.drilldownHandler.HandleSales)
+		})
 
 		// Public export routes (handler checks auth internally)
 		r.Route("/exports", func(r chi.Router) {
+			r.Use(auth.OptionalMiddleware(s.jwtService))
+			r.Use(queryTimeout(s.statementTimeout()))
 			r.Get("/", s.exportHandler.HandleList)
 			r.Post("/pnl", s.exportHandler.HandlePnL)
 			r.Get("/{id}", s.exportHandler.HandleGet)
+			r.Post("/{id}/share", s.exportHandler.HandleShare)
+			r.Get("/{id}/download", s.exportHandler.HandleDownload)
 		})
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(auth.Middleware(s.jwtService))
 
+			r.Get("/auth/activity", s.handleActivity)
+
 			// Import routes (accountant or admin only)
 			r.Route("/imports", func(r chi.Router) {
 				r.Use(auth.RequireRole(auth.RoleOwnerAdmin, auth.RoleAccountant))
 				r.Get("/", s.importHandler.HandleList)
 				r.Post("/", s.importHandler.HandleCreate)
+				r.Post("/preview", s.importHandler.HandlePreview)
 				r.Get("/{id}", s.importHandler.HandleGet)
+				r.Get("/{id}/report", s.importHandler.HandleReport)
+				r.Get("/{id}/anomalies", s.importHandler.HandleAnomalies)
+				r.Post("/{id}/retry", s.importHandler.HandleRetry)
+				r.Post("/{id}/cancel", s.importHandler.HandleCancel)
 			})
 
 			// Mapping profiles
@@ -142,12 +308,95 @@ func (s *Server) setupRoutes() {
 				r.Group(func(r chi.Router) {
 					r.Use(auth.RequireRole(auth.RoleOwnerAdmin, auth.RoleAccountant))
 					r.Post("/", s.importHandler.HandleMappingCreate)
+					r.Put("/{id}", s.importHandler.HandleMappingUpdate)
+					r.Delete("/{id}", s.importHandler.HandleMappingDelete)
+				})
+			})
+
+			// Sales by server name identify staff, so owners and accountants only
+			r.With(auth.RequireRole(auth.RoleOwnerAdmin, auth.RoleAccountant), queryTimeout(s.statementTimeout())).
+				Get("/kpi/by-server", s.kpiHandler.HandleByServer)
+
+			// Cash sales against bank deposits
+			r.With(auth.RequireRole(auth.RoleOwnerAdmin, auth.RoleAccountant), queryTimeout(s.statementTimeout())).
+				Get("/reconciliation/deposits", s.kpiHandler.HandleDepositReconciliation)
+
+			// Saved export configurations
+			r.Route("/reports", func(r chi.Router) {
+				r.Get("/", s.exportHandler.HandleSavedReportList)
+				r.Post("/", s.exportHandler.HandleSavedReportCreate)
+				r.Get("/{id}", s.exportHandler.HandleSavedReportGet)
+				r.Put("/{id}", s.exportHandler.HandleSavedReportUpdate)
+				r.Delete("/{id}", s.exportHandler.HandleSavedReportDelete)
+				r.With(queryTimeout(s.statementTimeout())).Post("/{id}/run", s.exportHandler.HandleSavedReportRun)
+			})
+
+			// Closed-day calendar
+			r.Route("/closed-days", func(r chi.Router) {
+				r.Get("/", s.closedDayHandler.HandleList)
+				r.Group(func(r chi.Router) {
+					r.Use(auth.RequireRole(auth.RoleOwnerAdmin, auth.RoleManager))
+					r.Post("/", s.closedDayHandler.HandleCreate)
+					r.Delete("/{id}", s.closedDayHandler.HandleDelete)
+				})
+			})
+
+			// KPI snapshots (month-end close)
+			r.Route("/kpi/snapshot", func(r chi.Router) {
+				r.Get("/", s.snapshotHandler.HandleList)
+				r.Get("/{id}/compare", s.snapshotHandler.HandleCompare)
+				r.Group(func(r chi.Router) {
+					r.Use(auth.RequireRole(auth.RoleOwnerAdmin, auth.RoleAccountant))
+					r.Post("/", s.snapshotHandler.HandleCreate)
 				})
 			})
+
+			// Settings
+			r.Route("/settings", func(r chi.Router) {
+				r.Use(auth.RequireRole(auth.RoleOwnerAdmin))
+				r.Post("/test-notification", s.settingsHandler.HandleTestNotification)
+				r.Put("/google-sheets", s.settingsHandler.HandleGoogleSheetsSave)
+				r.Delete("/google-sheets", s.settingsHandler.HandleGoogleSheetsDelete)
+			})
+
+			// Data-protection requests
+			r.Route("/privacy", func(r chi.Router) {
+				r.Use(auth.RequireRole(auth.RoleOwnerAdmin))
+				r.Get("/staff", s.privacyHandler.HandleStaffExport)
+				r.Post("/staff/anonymize", s.privacyHandler.HandleStaffAnonymize)
+			})
+
+			// Audit log
+			r.With(auth.RequireRole(auth.RoleOwnerAdmin)).Get("/audit", s.handleAudit)
+
+			// User management
+			r.Route("/users", func(r chi.Router) {
+				r.Use(auth.RequireRole(auth.RoleOwnerAdmin))
+				r.Get("/", s.userHandler.HandleList)
+				r.Post("/", s.userHandler.HandleCreate)
+				r.Get("/{id}", s.userHandler.HandleGet)
+				r.Put("/{id}", s.userHandler.HandleUpdate)
+				r.Delete("/{id}", s.userHandler.HandleDelete)
+			})
+
+			// Menu items and recipe costs
+			r.Route("/menu-items", func(r chi.Router) {
+				r.Use(auth.RequireRole(auth.RoleOwnerAdmin, auth.RoleAccountant))
+				r.Get("/", s.menuHandler.HandleList)
+				r.Post("/", s.menuHandler.HandleCreate)
+				r.Post("/bulk", s.menuHandler.HandleBulkUpsert)
+				r.Get("/{id}", s.menuHandler.HandleGet)
+				r.Put("/{id}", s.menuHandler.HandleUpdate)
+			})
 		})
 	})
 }
 
+// statementTimeout is the deadline for read-heavy KPI and export queries; zero disables it
+func (s *Server) statementTimeout() time.Duration {
+	return time.Duration(s.config.Database.StatementTimeoutSeconds) * time.Second
+}
+
 // Router returns the chi router
 func (s *Server) Router() *chi.Mux {
 	return s.router
@@ -158,11 +407,61 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
 
+// openReadPool connects to the read replica at url, falling back to primary
+// when no replica is configured or it cannot be reached
+func openReadPool(url string, primary *pgxpool.Pool) *pgxpool.Pool {
+	if url == "" {
+		return primary
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		log.Printf("Invalid DATABASE_READ_URL, reading from the primary: %v", err)
+		return primary
+	}
+	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		log.Printf("Read replica unavailable, reading from the primary: %v", err)
+		return primary
+	}
+	log.Println("Connected to read replica")
+	return pool
+}
+
+// Close releases the read replica pool, if one was opened. The primary pool
+// belongs to the caller.
+func (s *Server) Close() {
+	if s.readDB != s.db {
+		s.readDB.Close()
+	}
+}
+
+// StartBackground launches scheduled jobs such as the anomaly digest, the
+// post-import aggregate refresh queue and the stuck import reaper. They stop
+// when ctx is cancelled.
+func (s *Server) StartBackground(ctx context.Context) {
+	if s.digest != nil {
+		go s.digest.Run(ctx)
+	}
+	if s.refresher != nil {
+		go s.refresher.Run(ctx)
+	}
+	if s.reaper != nil {
+		go s.reaper.Run(ctx)
+	}
+}
+
+// DrainImports waits for background imports to finish, interrupting any
+// still running when ctx ends. Returns how many were interrupted.
+func (s *Server) DrainImports(ctx context.Context) int {
+	return s.importHandler.pipeline.Drain(ctx)
+}
+
 // Health check handler
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	respondJSON(w, http.StatusOK, map[string]string{
-		"status":    "ok",
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":            "ok",
+		"timestamp":         time.Now().UTC().Format(time.RFC3339),
+		"imports_in_flight": s.importHandler.pipeline.InFlight(),
 	})
 }
 
@@ -174,70 +473,86 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		respondError(w, http.StatusBadRequest, codeBadRequest, "invalid request body")
 		return
 	}
 
 	if req.Email == "" || req.Password == "" {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "email and password required"})
+		respondError(w, http.StatusBadRequest, codeBadRequest, "email and password required")
 		return
 	}
 
-	// Query user from database
+	// Query user and their assigned location
 	var userID uuid.UUID
 	var passwordHash string
 	var role string
-	var locationID uuid.UUID
+	var locationID *uuid.UUID
 
 	err := s.db.QueryRow(r.Context(), `
-		SELECT u.id, u.password_hash, u.role, l.id as location_id
-		FROM users u
-		CROSS JOIN locations l
-		WHERE u.email = $1
-		LIMIT 1
+		SELECT id, password_hash, role, location_id
+		FROM users
+		WHERE email = $1 AND deactivated_at IS NULL
 	`, req.Email).Scan(&userID, &passwordHash, &role, &locationID)
 
 	if err != nil {
-		respondJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "invalid credentials")
 		return
 	}
 
-	// Verify password (simple comparison for dev - use bcrypt in production)
-	if passwordHash != req.Password && !checkPasswordHash(req.Password, passwordHash) {
-		respondJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
+	// Verify password; legacy plaintext values are only accepted when explicitly allowed
+	needsRehash, err := auth.VerifyPassword(req.Password, passwordHash, s.config.Auth.AllowPlaintextLogin)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "invalid credentials")
+		return
+	}
+
+	// Upgrade plaintext passwords to bcrypt now that we know the password
+	if needsRehash {
+		if hash, err := auth.HashPassword(req.Password); err == nil {
+			if _, err := s.db.Exec(r.Context(), `UPDATE users SET password_hash = $1 WHERE id = $2`, hash, userID); err != nil {
+				log.Printf("Failed to upgrade password hash for user %s: %v", userID, err)
+			}
+		}
+	}
+
+	if locationID == nil {
+		respondError(w, http.StatusForbidden, codeForbidden, "user is not assigned to a location")
 		return
 	}
 
 	// Generate JWT token
-	token, err := s.jwtService.GenerateToken(userID, req.Email, auth.Role(role), locationID)
+	token, err := s.jwtService.GenerateToken(userID, req.Email, auth.Role(role), *locationID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "failed to generate token")
+		return
+	}
+
+	refreshToken, err := s.issueRefreshToken(r, userID)
 	if err != nil {
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to generate token"})
+		respondError(w, http.StatusInternalServerError, codeInternal, "failed to generate token")
 		return
 	}
 
 	// Update last login
 	s.db.Exec(r.Context(), `UPDATE users SET last_login = NOW() WHERE id = $1`, userID)
+	if err := s.auditLog.RecordFor(r.Context(), userID, locationID, audit.ActionLogin, "user", &userID, map[string]interface{}{
+		"ip": r.RemoteAddr,
+	}); err != nil {
+		log.Printf("Failed to record login for user %s: %v", userID, err)
+	}
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"token": token,
+		"token":         token,
+		"refresh_token": refreshToken,
 		"user": map[string]interface{}{
-			"id":    userID,
-			"email": req.Email,
-			"role":  role,
+			"id":          userID,
+			"email":       req.Email,
+			"role":        role,
+			"location_id": locationID,
 		},
 	})
 }
 
-// Simple password hash check (for bcrypt hashed passwords)
-func checkPasswordHash(password, hash string) bool {
-	// For development, also allow plaintext comparison
-	if password == hash {
-		return true
-	}
-	// In production, use bcrypt.CompareHashAndPassword
-	return false
-}
-
 // Helper functions
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
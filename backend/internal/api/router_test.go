@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+	"github.com/lakehouse/restaurant-finance/internal/config"
+)
+
+// testServer builds the API with default configuration and no database, for
+// checking routing and authorization ahead of any handler
+func testServer(t *testing.T) *Server {
+	t.Helper()
+	t.Setenv("STORAGE_PATH", t.TempDir())
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load() error = %v", err)
+	}
+	return NewServer(cfg, nil)
+}
+
+func TestByServerRequiresRole(t *testing.T) {
+	s := testServer(t)
+
+	tests := []struct {
+		name       string
+		role       auth.Role // empty sends no token
+		wantStatus int
+	}{
+		{name: "anonymous", wantStatus: http.StatusUnauthorized},
+		{name: "viewer", role: auth.RoleViewer, wantStatus: http.StatusForbidden},
+		{name: "manager", role: auth.RoleManager, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/kpi/by-server?range=7d", nil)
+			if tt.role != "" {
+				token, err := s.jwtService.GenerateToken(uuid.New(), "staff@example.com", tt.role, uuid.New())
+				if err != nil {
+					t.Fatalf("GenerateToken: %v", err)
+				}
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://finance.example.com")
+	s := testServer(t)
+
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{origin: "https://finance.example.com", want: "https://finance.example.com"},
+		{origin: "http://localhost:3000"}, // the dev default no longer applies
+		{origin: "https://evil.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "/api/v1/kpi/daily", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+	"github.com/lakehouse/restaurant-finance/internal/exports"
+)
+
+// SavedReportRequest represents a saved report create or update request
+type SavedReportRequest struct {
+	Name        string `json:"name"`
+	ExportType  string `json:"export_type"`
+	RangeSpec   string `json:"range_spec"`           // 7d, 30d, mtd, qtd, ytd, trailing12m or custom
+	StartDate   string `json:"start_date,omitempty"` // custom range only
+	EndDate     string `json:"end_date,omitempty"`
+	GroupBy     string `json:"group_by,omitempty"`
+	SummaryOnly bool   `json:"summary_only,omitempty"`
+	Format      string `json:"format,omitempty"`
+}
+
+// decodeSavedReport decodes and validates a saved report request body
+func decodeSavedReport(r *http.Request) (*exports.SavedReport, error) {
+	var req SavedReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.New("Invalid request body")
+	}
+
+	report := &exports.SavedReport{
+		Name:        req.Name,
+		ExportType:  req.ExportType,
+		RangeSpec:   req.RangeSpec,
+		GroupBy:     req.GroupBy,
+		SummaryOnly: req.SummaryOnly,
+		Format:      req.Format,
+	}
+	if req.StartDate != "" {
+		t, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return nil, errors.New("Invalid start_date format, use YYYY-MM-DD")
+		}
+		report.StartDate = &t
+	}
+	if req.EndDate != "" {
+		t, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return nil, errors.New("Invalid end_date format, use YYYY-MM-DD")
+		}
+		report.EndDate = &t
+	}
+	if err := report.Validate(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// HandleSavedReportList handles GET /reports requests
+func (h *ExportHandler) HandleSavedReportList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	reports, err := h.reports.List(ctx, claims.LocationID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to list saved reports")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
+
+// HandleSavedReportGet handles GET /reports/{id} requests
+func (h *ExportHandler) HandleSavedReportGet(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid report ID")
+		return
+	}
+
+	report, err := h.reports.Get(ctx, id, claims.LocationID)
+	if errors.Is(err, exports.ErrSavedReportNotFound) {
+		respondError(w, http.StatusNotFound, codeNotFound, "Saved report not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to get saved report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleSavedReportCreate handles POST /reports requests
+func (h *ExportHandler) HandleSavedReportCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	report, err := decodeSavedReport(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	userID := claims.UserID
+	report.LocationID = claims.LocationID
+	report.CreatedBy = &userID
+
+	err = h.reports.Create(ctx, report)
+	if errors.Is(err, exports.ErrSavedReportNameTaken) {
+		respondError(w, http.StatusConflict, codeConflict, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to create saved report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleSavedReportUpdate handles PUT /reports/{id} requests
+func (h *ExportHandler) HandleSavedReportUpdate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid report ID")
+		return
+	}
+
+	existing, err := h.reports.Get(ctx, id, claims.LocationID)
+	if errors.Is(err, exports.ErrSavedReportNotFound) {
+		respondError(w, http.StatusNotFound, codeNotFound, "Saved report not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to get saved report")
+		return
+	}
+
+	report, err := decodeSavedReport(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	report.ID = existing.ID
+	report.LocationID = existing.LocationID
+	report.CreatedBy = existing.CreatedBy
+	report.CreatedAt = existing.CreatedAt
+
+	err = h.reports.Update(ctx, report)
+	switch {
+	case errors.Is(err, exports.ErrSavedReportNotFound):
+		respondError(w, http.StatusNotFound, codeNotFound, "Saved report not found")
+		return
+	case errors.Is(err, exports.ErrSavedReportNameTaken):
+		respondError(w, http.StatusConflict, codeConflict, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to update saved report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleSavedReportDelete handles DELETE /reports/{id} requests
+func (h *ExportHandler) HandleSavedReportDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid report ID")
+		return
+	}
+
+	found, err := h.reports.Delete(ctx, id, claims.LocationID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to delete saved report")
+		return
+	}
+	if !found {
+		respondError(w, http.StatusNotFound, codeNotFound, "Saved report not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSavedReportRun handles POST /reports/{id}/run requests. The export is
+// generated from the saved configuration, with relative ranges resolved
+// against today, and returned like POST /exports/pnl.
+func (h *ExportHandler) HandleSavedReportRun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid report ID")
+		return
+	}
+
+	report, err := h.reports.Get(ctx, id, claims.LocationID)
+	if errors.Is(err, exports.ErrSavedReportNotFound) {
+		respondError(w, http.StatusNotFound, codeNotFound, "Saved report not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to get saved report")
+		return
+	}
+
+	fy, err := h.locations.FiscalYearStart(ctx, report.LocationID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to load fiscal year")
+		return
+	}
+
+	loc, _ := time.LoadLocation("Australia/Brisbane")
+	params := report.Params(time.Now().In(loc), claims.UserID, fy)
+
+	job, data, err := h.generate(ctx, report.ExportType, report.Format, params)
+	var limitErr *exports.RowLimitError
+	if errors.As(err, &limitErr) {
+		respondError(w, http.StatusUnprocessableEntity, codeUnprocessable, limitErr.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Saved report %s generation error: %v", report.ID, err)
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to generate export: "+err.Error())
+		return
+	}
+
+	h.sendExport(w, r, job, data, report.Format, map[string]interface{}{
+		"saved_report_id": report.ID,
+	})
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+	"github.com/lakehouse/restaurant-finance/internal/notify"
+	"github.com/lakehouse/restaurant-finance/internal/sheets"
+)
+
+// SettingsHandler handles settings-related HTTP requests
+type SettingsHandler struct {
+	notifier *notify.Notifier
+	sheets   *sheets.Exporter // nil when Google Sheets export is disabled
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(notifier *notify.Notifier, sheetsExporter *sheets.Exporter) *SettingsHandler {
+	return &SettingsHandler{notifier: notifier, sheets: sheetsExporter}
+}
+
+// TestNotificationRequest represents a request to send a test notification
+type TestNotificationRequest struct {
+	Channel string `json:"channel,omitempty"` // email, webhook; empty tests every configured channel
+	To      string `json:"to,omitempty"`      // email recipient; defaults to the caller's address
+}
+
+// NotificationResult reports the outcome of one delivery attempt
+type NotificationResult struct {
+	Channel   string `json:"channel"`
+	Delivered bool   `json:"delivered"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HandleTestNotification handles POST /settings/test-notification requests
+func (h *SettingsHandler) HandleTestNotification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req TestNotificationRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	cfg := h.notifier.Config()
+	var channels []string
+	switch req.Channel {
+	case "email", "webhook":
+		channels = []string{req.Channel}
+	case "":
+		if cfg.EmailConfigured() {
+			channels = append(channels, "email")
+		}
+		if cfg.WebhookConfigured() {
+			channels = append(channels, "webhook")
+		}
+		if len(channels) == 0 {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "No notification channels are configured")
+			return
+		}
+	default:
+		respondError(w, http.StatusBadRequest, codeBadRequest, "channel must be email or webhook")
+		return
+	}
+
+	sentAt := time.Now().UTC()
+	status := http.StatusOK
+	results := make([]NotificationResult, 0, len(channels))
+	for _, channel := range channels {
+		var err error
+		switch channel {
+		case "email":
+			to := req.To
+			if to == "" {
+				to = claims.Email
+			}
+			err = h.notifier.SendEmail(ctx, []string{to},
+				"Restaurant Finance test notification",
+				fmt.Sprintf("This is a test email requested by %s at %s.\nEmail delivery is configured correctly.", claims.Email, sentAt.Format(time.RFC3339)))
+		case "webhook":
+			err = h.notifier.PostWebhook(ctx, map[string]interface{}{
+				"event":        "test",
+				"message":      "Webhook delivery is configured correctly",
+				"requested_by": claims.Email,
+				"sent_at":      sentAt,
+			})
+		}
+
+		result := NotificationResult{Channel: channel, Delivered: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			if errors.Is(err, notify.ErrNotConfigured) {
+				status = http.StatusBadRequest
+			} else if status == http.StatusOK {
+				status = http.StatusBadGateway
+			}
+		}
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": results,
+	})
+}
+
+// HandleGoogleSheetsSave handles PUT /settings/google-sheets requests, storing
+// the OAuth client and refresh token exports to Google Sheets use for the
+// caller's location
+func (h *SettingsHandler) HandleGoogleSheetsSave(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+	if h.sheets == nil {
+		respondError(w, http.StatusNotFound, codeNotFound, "Google Sheets export is not enabled")
+		return
+	}
+
+	var creds sheets.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+	if creds.ClientID == "" || creds.ClientSecret == "" || creds.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "client_id, client_secret and refresh_token are required")
+		return
+	}
+
+	if err := h.sheets.Credentials().Save(ctx, claims.LocationID, creds); err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to save Google Sheets credentials")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGoogleSheetsDelete handles DELETE /settings/google-sheets requests
+func (h *SettingsHandler) HandleGoogleSheetsDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+	if h.sheets == nil {
+		respondError(w, http.StatusNotFound, codeNotFound, "Google Sheets export is not enabled")
+		return
+	}
+
+	found, err := h.sheets.Credentials().Delete(ctx, claims.LocationID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to delete Google Sheets credentials")
+		return
+	}
+	if !found {
+		respondError(w, http.StatusNotFound, codeNotFound, sheets.ErrNotConfigured.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+	"github.com/lakehouse/restaurant-finance/internal/notify"
+)
+
+func TestHandleTestNotification(t *testing.T) {
+	var delivered []map[string]interface{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		delivered = append(delivered, payload)
+	}))
+	defer webhook.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such hook", http.StatusNotFound)
+	}))
+	defer broken.Close()
+
+	jwtService := auth.NewJWTService("test-secret", 1, 1)
+	token, err := jwtService.GenerateToken(uuid.New(), "owner@example.com", auth.RoleOwnerAdmin, uuid.New())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		cfg           notify.Config
+		body          string
+		wantStatus    int
+		wantDelivered bool
+		wantError     string
+	}{
+		{name: "webhook", cfg: notify.Config{WebhookURL: webhook.URL}, wantStatus: http.StatusOK, wantDelivered: true},
+		{name: "webhook fails", cfg: notify.Config{WebhookURL: broken.URL}, wantStatus: http.StatusBadGateway, wantError: "no such hook"},
+		{name: "email not configured", cfg: notify.Config{WebhookURL: webhook.URL}, body: `{"channel":"email"}`, wantStatus: http.StatusBadRequest, wantError: notify.ErrNotConfigured.Error()},
+		{name: "nothing configured", wantStatus: http.StatusBadRequest},
+		{name: "unknown channel", cfg: notify.Config{WebhookURL: webhook.URL}, body: `{"channel":"sms"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivered = nil
+			h := NewSettingsHandler(notify.NewNotifier(tt.cfg), nil)
+			handler := auth.Middleware(jwtService)(http.HandlerFunc(h.HandleTestNotification))
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/settings/test-notification", strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantDelivered {
+				if len(delivered) != 1 || delivered[0]["event"] != "test" || delivered[0]["requested_by"] != "owner@example.com" {
+					t.Errorf("webhook received %v, want one test event from the caller", delivered)
+				}
+			}
+			if tt.wantError != "" {
+				var resp struct {
+					Results []NotificationResult `json:"results"`
+				}
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if len(resp.Results) != 1 || resp.Results[0].Delivered || !strings.Contains(resp.Results[0].Error, tt.wantError) {
+					t.Errorf("results = %+v, want an undelivered result reporting %q", resp.Results, tt.wantError)
+				}
+			}
+		})
+	}
+}
+
+func TestTestNotificationRequiresOwner(t *testing.T) {
+	s := testServer(t)
+	token, err := s.jwtService.GenerateToken(uuid.New(), "manager@example.com", auth.RoleManager, uuid.New())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/settings/test-notification", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+// SnapshotHandler handles KPI snapshot requests
+type SnapshotHandler struct {
+	service *kpi.Service
+}
+
+// NewSnapshotHandler creates a new snapshot handler
+func NewSnapshotHandler(service *kpi.Service) *SnapshotHandler {
+	return &SnapshotHandler{service: service}
+}
+
+// CreateSnapshotRequest represents a snapshot creation request
+type CreateSnapshotRequest struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Label     string `json:"label,omitempty"`
+}
+
+// HandleCreate handles POST /kpi/snapshot requests
+func (h *SnapshotHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateSnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid start_date format, use YYYY-MM-DD")
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid end_date format, use YYYY-MM-DD")
+		return
+	}
+	if endDate.Before(startDate) {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "end_date must not be before start_date")
+		return
+	}
+
+	snap, err := h.service.CreateSnapshot(ctx, claims.LocationID, claims.UserID, startDate, endDate, req.Label)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to create snapshot")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(snap)
+}
+
+// HandleList handles GET /kpi/snapshot requests
+func (h *SnapshotHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	snapshots, err := h.service.ListSnapshots(ctx, claims.LocationID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to list snapshots")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshots)
+}
+
+// HandleCompare handles GET /kpi/snapshot/{id}/compare requests
+func (h *SnapshotHandler) HandleCompare(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid snapshot ID")
+		return
+	}
+
+	snap, err := h.service.GetSnapshot(ctx, id)
+	if err != nil || (snap.LocationID != claims.LocationID && claims.Role != auth.RoleOwnerAdmin) {
+		respondError(w, http.StatusNotFound, codeNotFound, "Snapshot not found")
+		return
+	}
+
+	comparison, err := h.service.CompareSnapshot(ctx, snap)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to compare snapshot")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+)
+
+// staleRetryAfter is the Retry-After hint sent while the database is unreachable
+const staleRetryAfter = 30 * time.Second
+
+// staleCache keeps the last successful response body per key for a short
+// time so a dashboard can still be served while the database is unreachable.
+// A nil cache stores nothing.
+type staleCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]staleEntry
+}
+
+type staleEntry struct {
+	body     []byte
+	storedAt time.Time
+}
+
+// newStaleCache creates a cache holding at most maxEntries bodies for ttl;
+// a zero ttl or size disables it
+func newStaleCache(ttl time.Duration, maxEntries int) *staleCache {
+	if ttl <= 0 || maxEntries <= 0 {
+		return nil
+	}
+	return &staleCache{ttl: ttl, maxEntries: maxEntries, entries: make(map[string]staleEntry)}
+}
+
+// put stores a body, evicting expired entries and then the oldest when full
+func (c *staleCache) put(key string, body []byte, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, e := range c.entries {
+			if now.Sub(e.storedAt) > c.ttl {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || e.storedAt.Before(oldest) {
+				oldestKey, oldest = k, e.storedAt
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			delete(c.entries, oldestKey)
+		}
+	}
+	c.entries[key] = staleEntry{body: body, storedAt: now}
+}
+
+// get returns a body stored less than ttl ago
+func (c *staleCache) get(key string, now time.Time) (staleEntry, bool) {
+	if c == nil {
+		return staleEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || now.Sub(e.storedAt) > c.ttl {
+		return staleEntry{}, false
+	}
+	return e, true
+}
+
+// staleKey identifies a cached response by caller and query. The query is
+// re-encoded so parameter order does not matter. Relative ranges such as
+// today resolve the same way for the few minutes an entry is kept.
+func staleKey(r *http.Request) string {
+	key := r.URL.Query().Encode()
+	if claims := auth.GetUserClaims(r.Context()); claims != nil {
+		key = claims.LocationID.String() + "|" + key
+	}
+	return key
+}
+
+// internalError is a server error whose message is safe to show the client
+// while the underlying cause stays available to errors.As
+type internalError struct {
+	msg   string
+	cause error
+}
+
+func (e *internalError) Error() string { return e.msg }
+func (e *internalError) Unwrap() error { return e.cause }
+
+// dbUnavailable reports whether err means the database could not be reached,
+// as opposed to a query that failed
+func dbUnavailable(err error) bool {
+	var connErr *pgconn.ConnectError
+	if errors.As(err, &connErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 connection exceptions, and the server shutting down or starting up
+		return strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+	// Connection failures where the statement never reached the server
+	return pgconn.SafeToRetry(err)
+}
+
+// writeUnavailable answers a request the database could not serve. A cached
+// body is returned with stale set and the time it was cached; without one the
+// response is a 503. Either way Retry-After tells the client when to refresh.
+func writeUnavailable(w http.ResponseWriter, cache *staleCache, key string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(staleRetryAfter.Seconds())))
+
+	entry, ok := cache.get(key, time.Now())
+	if !ok {
+		respondError(w, http.StatusServiceUnavailable, codeUnavailable, "Database unavailable, try again shortly")
+		return
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(entry.body, &body); err != nil {
+		respondError(w, http.StatusServiceUnavailable, codeUnavailable, "Database unavailable, try again shortly")
+		return
+	}
+	body["stale"] = json.RawMessage("true")
+	cachedAt, _ := json.Marshal(entry.storedAt.UTC())
+	body["staleSince"] = cachedAt
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Warning", `110 - "Response is Stale"`)
+	json.NewEncoder(w).Encode(body)
+}
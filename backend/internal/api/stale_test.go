@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+)
+
+// withClaims returns r as OptionalMiddleware would pass it on for a user of locationID
+func withClaims(t *testing.T, r *http.Request, locationID uuid.UUID) *http.Request {
+	t.Helper()
+	jwtService := auth.NewJWTService("test-secret-at-least-thirty-two-bytes", 1, 1)
+	token, err := jwtService.GenerateToken(uuid.New(), "owner@example.com", auth.RoleOwnerAdmin, locationID)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	var out *http.Request
+	auth.OptionalMiddleware(jwtService)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out = r
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	return out
+}
+
+func TestStaleKey(t *testing.T) {
+	locationA, locationB := uuid.New(), uuid.New()
+	request := func(query string, locationID uuid.UUID) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/kpi/daily?"+query, nil)
+		if locationID == uuid.Nil {
+			return r
+		}
+		return withClaims(t, r, locationID)
+	}
+
+	tests := []struct {
+		name string
+		a, b *http.Request
+		same bool
+	}{
+		{
+			name: "parameter order does not matter",
+			a:    request("range=7d&fields=revenue", locationA),
+			b:    request("fields=revenue&range=7d", locationA),
+			same: true,
+		},
+		{
+			name: "different ranges",
+			a:    request("range=7d", locationA),
+			b:    request("range=30d", locationA),
+		},
+		{
+			name: "different locations",
+			a:    request("range=7d", locationA),
+			b:    request("range=7d", locationB),
+		},
+		{
+			name: "anonymous and signed in",
+			a:    request("range=7d", uuid.Nil),
+			b:    request("range=7d", locationA),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := staleKey(tt.a), staleKey(tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("staleKey() = %q and %q, want same %v", a, b, tt.same)
+			}
+		})
+	}
+}
+
+func TestStaleCache(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("disabled", func(t *testing.T) {
+		for _, c := range []*staleCache{newStaleCache(0, 10), newStaleCache(time.Minute, 0)} {
+			if c != nil {
+				t.Fatalf("newStaleCache() = %v, want nil", c)
+			}
+			c.put("k", []byte("{}"), now)
+			if _, ok := c.get("k", now); ok {
+				t.Error("nil cache returned an entry")
+			}
+		}
+	})
+
+	t.Run("expires after ttl", func(t *testing.T) {
+		c := newStaleCache(5*time.Minute, 10)
+		c.put("k", []byte(`{"a":1}`), now)
+		if e, ok := c.get("k", now.Add(5*time.Minute)); !ok || string(e.body) != `{"a":1}` {
+			t.Errorf("get() at ttl = %q, %v; want the body", e.body, ok)
+		}
+		if _, ok := c.get("k", now.Add(5*time.Minute+time.Second)); ok {
+			t.Error("get() after ttl returned an entry")
+		}
+	})
+
+	t.Run("evicts oldest when full", func(t *testing.T) {
+		c := newStaleCache(time.Hour, 2)
+		c.put("a", []byte("1"), now)
+		c.put("b", []byte("2"), now.Add(time.Second))
+		c.put("a", []byte("3"), now.Add(2*time.Second)) // replacing does not evict
+		c.put("c", []byte("4"), now.Add(3*time.Second))
+		at := now.Add(4 * time.Second)
+		if _, ok := c.get("b", at); ok {
+			t.Error("oldest entry b was kept")
+		}
+		for key, want := range map[string]string{"a": "3", "c": "4"} {
+			if e, ok := c.get(key, at); !ok || string(e.body) != want {
+				t.Errorf("get(%q) = %q, %v; want %q", key, e.body, ok, want)
+			}
+		}
+	})
+
+	t.Run("expired entries go first", func(t *testing.T) {
+		c := newStaleCache(time.Minute, 2)
+		c.put("old", []byte("1"), now)
+		c.put("recent", []byte("2"), now.Add(90*time.Second))
+		c.put("new", []byte("3"), now.Add(2*time.Minute))
+		at := now.Add(2 * time.Minute)
+		if _, ok := c.get("recent", at); !ok {
+			t.Error("unexpired entry was evicted instead of the expired one")
+		}
+	})
+}
+
+func TestDBUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "connect error", err: &pgconn.ConnectError{}, want: true},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, want: true},
+		{name: "admin shutdown", err: &pgconn.PgError{Code: "57P01"}, want: true},
+		{name: "cannot connect now", err: fmt.Errorf("daily kpis: %w", &pgconn.PgError{Code: "57P03"}), want: true},
+		{name: "query error", err: &pgconn.PgError{Code: "42703"}, want: false},
+		{name: "statement timeout", err: &pgconn.PgError{Code: "57014"}, want: false},
+		{name: "deadline", err: context.DeadlineExceeded, want: false},
+		{name: "plain error", err: errors.New("bad range"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dbUnavailable(tt.err); got != tt.want {
+				t.Errorf("dbUnavailable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteUnavailable(t *testing.T) {
+	storedAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	cache := newStaleCache(5*time.Minute, 10)
+	cache.put("cached", []byte(`{"revenue":1200.5}`), storedAt)
+
+	t.Run("no cached response", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeUnavailable(w, cache, "missing")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503", w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != "30" {
+			t.Errorf("Retry-After = %q, want 30", got)
+		}
+	})
+
+	t.Run("cached response", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeUnavailable(w, cache, "cached")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != "30" {
+			t.Errorf("Retry-After = %q, want 30", got)
+		}
+		if got := w.Header().Get("Warning"); got != `110 - "Response is Stale"` {
+			t.Errorf("Warning = %q", got)
+		}
+
+		var body struct {
+			Revenue    float64   `json:"revenue"`
+			Stale      bool      `json:"stale"`
+			StaleSince time.Time `json:"staleSince"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if body.Revenue != 1200.5 || !body.Stale || !body.StaleSince.Equal(storedAt) {
+			t.Errorf("body = %+v, want the cached body marked stale since %s", body, storedAt)
+		}
+	})
+
+	t.Run("cache disabled", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeUnavailable(w, nil, "cached")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503", w.Code)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// queryTimeout bounds read-heavy KPI and export requests. The request context
+// gets a deadline, which pgx turns into a cancelled statement, and a 5xx
+// written after the deadline has passed is replaced by a 504 that says why.
+func queryTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(&timeoutWriter{ResponseWriter: w, ctx: ctx, timeout: timeout}, r.WithContext(ctx))
+		})
+	}
+}
+
+// timeoutWriter rewrites server errors caused by the query deadline
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	timeout  time.Duration
+	timedOut bool
+	wrote    bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	if code >= http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		respondError(w.ResponseWriter, http.StatusGatewayTimeout, codeTimeout, fmt.Sprintf("Query timed out after %s; try a shorter date range", w.timeout))
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.timedOut {
+		// Drop the handler's own error body; the timeout message was sent instead
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A handler blocked on a slow query sees the deadline as a cancelled context
+// and reports a 500; the client gets a 504 naming the timeout instead
+func TestQueryTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	handler := queryTimeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+			respondError(w, http.StatusInternalServerError, codeInternal, "Failed to fetch KPIs")
+		case <-time.After(5 * time.Second):
+			respondJSON(w, http.StatusOK, map[string]string{"status": "done"})
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/kpi/daily?range=365d", nil))
+
+	select {
+	case <-cancelled:
+	default:
+		t.Fatal("handler context was not cancelled")
+	}
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", w.Code)
+	}
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q is not one JSON error: %v", w.Body.String(), err)
+	}
+	if body.Error.Code != codeTimeout || !strings.Contains(body.Error.Message, "20ms") {
+		t.Errorf("error = %+v, want %s naming the 20ms timeout", body.Error, codeTimeout)
+	}
+}
+
+func TestQueryTimeoutPassesThrough(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		status  int
+	}{
+		{name: "fast success", timeout: time.Second, status: http.StatusOK},
+		{name: "error before the deadline", timeout: time.Second, status: http.StatusInternalServerError},
+		{name: "client error", timeout: time.Second, status: http.StatusBadRequest},
+		{name: "disabled", status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := queryTimeout(tt.timeout)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, ok := r.Context().Deadline(); ok != (tt.timeout > 0) {
+					t.Errorf("request deadline set = %v, want %v", ok, tt.timeout > 0)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/kpi/daily", nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/audit"
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+	"github.com/lakehouse/restaurant-finance/internal/users"
+)
+
+// minPasswordLength is the shortest password an owner admin may set
+const minPasswordLength = 8
+
+// UserHandler handles user management requests for owner admins
+type UserHandler struct {
+	store         *users.Store
+	refreshTokens *auth.RefreshTokenStore
+	auditLog      *audit.Logger
+}
+
+// NewUserHandler creates a new user handler. Refresh tokens are revoked when
+// a user is deactivated so they cannot keep renewing access.
+func NewUserHandler(store *users.Store, refreshTokens *auth.RefreshTokenStore, auditLog *audit.Logger) *UserHandler {
+	return &UserHandler{store: store, refreshTokens: refreshTokens, auditLog: auditLog}
+}
+
+// CreateUserRequest represents a user creation request
+type CreateUserRequest struct {
+	Email      string    `json:"email"`
+	Password   string    `json:"password"`
+	Role       auth.Role `json:"role"`
+	LocationID uuid.UUID `json:"location_id"`
+}
+
+// UpdateUserRequest changes the fields that are set and leaves the rest
+type UpdateUserRequest struct {
+	Role       *auth.Role `json:"role,omitempty"`
+	LocationID *uuid.UUID `json:"location_id,omitempty"`
+	Password   string     `json:"password,omitempty"`
+	Active     *bool      `json:"active,omitempty"` // true reactivates a deactivated user
+}
+
+// HandleList handles GET /users requests. location_id limits the list to one
+// location and include_inactive=true adds deactivated users.
+func (h *UserHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	var locationID *uuid.UUID
+	if v := r.URL.Query().Get("location_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid location_id")
+			return
+		}
+		locationID = &id
+	}
+	includeInactive := r.URL.Query().Get("include_inactive") == "true"
+
+	list, err := h.store.List(r.Context(), locationID, includeInactive)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to list users")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": list})
+}
+
+// HandleGet handles GET /users/{id} requests
+func (h *UserHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, err := h.store.Get(r.Context(), id)
+	if errors.Is(err, users.ErrNotFound) {
+		respondError(w, http.StatusNotFound, codeNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to get user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// HandleCreate handles POST /users requests
+func (h *UserHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	var req CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if !strings.Contains(req.Email, "@") {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "A valid email is required")
+		return
+	}
+	if !req.Role.IsValid() {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "role must be owner_admin, manager, accountant or viewer")
+		return
+	}
+	if req.LocationID == uuid.Nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "location_id is required")
+		return
+	}
+	hash, err := hashNewPassword(req.Password)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	locationID := req.LocationID
+	user := &users.User{Email: req.Email, Role: req.Role, LocationID: &locationID}
+	err = h.store.Create(r.Context(), user, hash)
+	switch {
+	case errors.Is(err, users.ErrEmailTaken):
+		respondError(w, http.StatusConflict, codeConflict, err.Error())
+		return
+	case errors.Is(err, users.ErrUnknownLocation):
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to create user")
+		return
+	}
+
+	h.record(r, audit.ActionUserCreate, user, map[string]interface{}{
+		"email":       user.Email,
+		"role":        user.Role,
+		"location_id": user.LocationID,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// HandleUpdate handles PUT /users/{id} requests
+func (h *UserHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := auth.GetUserClaims(ctx)
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := h.store.Get(ctx, id)
+	if errors.Is(err, users.ErrNotFound) {
+		respondError(w, http.StatusNotFound, codeNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to get user")
+		return
+	}
+	previousRole, previousLocation := user.Role, user.LocationID
+
+	if req.Role != nil {
+		if !req.Role.IsValid() {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "role must be owner_admin, manager, accountant or viewer")
+			return
+		}
+		// An owner admin demoting themselves could leave nobody able to manage users
+		if id == claims.UserID && *req.Role != auth.RoleOwnerAdmin {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "You cannot change your own role")
+			return
+		}
+		user.Role = *req.Role
+	}
+	if req.LocationID != nil {
+		if *req.LocationID == uuid.Nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, "location_id cannot be empty")
+			return
+		}
+		user.LocationID = req.LocationID
+	}
+	var hash string
+	if req.Password != "" {
+		if hash, err = hashNewPassword(req.Password); err != nil {
+			respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+			return
+		}
+	}
+	if req.Active != nil && !*req.Active && id == claims.UserID {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "You cannot deactivate yourself")
+		return
+	}
+
+	err = h.store.Update(ctx, user, hash)
+	switch {
+	case errors.Is(err, users.ErrNotFound):
+		respondError(w, http.StatusNotFound, codeNotFound, "User not found")
+		return
+	case errors.Is(err, users.ErrUnknownLocation):
+		respondError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to update user")
+		return
+	}
+
+	if req.Active != nil && *req.Active != user.Active {
+		if user, err = h.setActive(r, id, *req.Active); err != nil {
+			respondError(w, http.StatusInternalServerError, codeInternal, "Failed to update user")
+			return
+		}
+	}
+
+	// Passwords are never logged, only that one was set
+	metadata := map[string]interface{}{"email": user.Email, "active": user.Active}
+	if user.Role != previousRole {
+		metadata["role"] = user.Role
+		metadata["previous_role"] = previousRole
+	}
+	if req.LocationID != nil && (previousLocation == nil || *previousLocation != *user.LocationID) {
+		metadata["location_id"] = user.LocationID
+		metadata["previous_location_id"] = previousLocation
+	}
+	if hash != "" {
+		metadata["password_changed"] = true
+	}
+	h.record(r, audit.ActionUserUpdate, user, metadata)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// HandleDelete handles DELETE /users/{id} requests. The user is deactivated
+// rather than removed so records they created keep their reference.
+func (h *UserHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserClaims(r.Context())
+	if claims == nil {
+		respondError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "Invalid user ID")
+		return
+	}
+	if id == claims.UserID {
+		respondError(w, http.StatusBadRequest, codeBadRequest, "You cannot deactivate yourself")
+		return
+	}
+
+	user, err := h.setActive(r, id, false)
+	if errors.Is(err, users.ErrNotFound) {
+		respondError(w, http.StatusNotFound, codeNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, codeInternal, "Failed to deactivate user")
+		return
+	}
+
+	h.record(r, audit.ActionUserDeactivate, user, map[string]interface{}{"email": user.Email})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setActive changes whether a user can log in, revoking their refresh tokens
+// when they are deactivated. Access tokens already issued run to expiry.
+func (h *UserHandler) setActive(r *http.Request, id uuid.UUID, active bool) (*users.User, error) {
+	user, err := h.store.SetActive(r.Context(), id, active)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		if err := h.refreshTokens.RevokeAllForUser(r.Context(), id); err != nil {
+			log.Printf("Failed to revoke refresh tokens for deactivated user %s: %v", id, err)
+		}
+	}
+	return user, nil
+}
+
+// record writes an audit entry for a change to a user, logging rather than
+// failing the request if it cannot be stored
+func (h *UserHandler) record(r *http.Request, action string, user *users.User, metadata map[string]interface{}) {
+	if err := h.auditLog.Record(r.Context(), action, "user", &user.ID, metadata); err != nil {
+		log.Printf("Failed to record %s for user %s: %v", action, user.ID, err)
+	}
+}
+
+// hashNewPassword checks a password set by an owner admin and hashes it
+func hashNewPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", errors.New("password must be at least 8 characters")
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return "", errors.New("password could not be hashed")
+	}
+	return hash, nil
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+)
+
+func TestUsersRequireOwnerAdmin(t *testing.T) {
+	s := testServer(t)
+
+	for _, role := range []auth.Role{"", auth.RoleViewer, auth.RoleManager, auth.RoleAccountant} {
+		for _, req := range []struct{ method, path string }{
+			{http.MethodGet, "/api/v1/users"},
+			{http.MethodPost, "/api/v1/users"},
+			{http.MethodPut, "/api/v1/users/" + uuid.NewString()},
+			{http.MethodDelete, "/api/v1/users/" + uuid.NewString()},
+		} {
+			r := httptest.NewRequest(req.method, req.path, strings.NewReader("{}"))
+			want := http.StatusUnauthorized
+			if role != "" {
+				token, err := s.jwtService.GenerateToken(uuid.New(), "staff@example.com", role, uuid.New())
+				if err != nil {
+					t.Fatalf("GenerateToken: %v", err)
+				}
+				r.Header.Set("Authorization", "Bearer "+token)
+				want = http.StatusForbidden
+			}
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+			if w.Code != want {
+				t.Errorf("%s %s as %q = %d, want %d", req.method, req.path, role, w.Code, want)
+			}
+		}
+	}
+}
+
+func TestUserHandlersValidate(t *testing.T) {
+	s := testServer(t)
+	ownerID := uuid.New()
+	token, err := s.jwtService.GenerateToken(ownerID, "owner@example.com", auth.RoleOwnerAdmin, uuid.New())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	location := uuid.NewString()
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		wantMsg string
+	}{
+		{name: "no email", method: http.MethodPost, path: "/api/v1/users", body: `{"email":"manager","password":"long enough","role":"manager","location_id":"` + location + `"}`, wantMsg: "valid email"},
+		{name: "unknown role", method: http.MethodPost, path: "/api/v1/users", body: `{"email":"m@example.com","password":"long enough","role":"chef","location_id":"` + location + `"}`, wantMsg: "role must be"},
+		{name: "no location", method: http.MethodPost, path: "/api/v1/users", body: `{"email":"m@example.com","password":"long enough","role":"manager"}`, wantMsg: "location_id is required"},
+		{name: "short password", method: http.MethodPost, path: "/api/v1/users", body: `{"email":"m@example.com","password":"short","role":"manager","location_id":"` + location + `"}`, wantMsg: "at least 8 characters"},
+		{name: "deactivate self", method: http.MethodDelete, path: "/api/v1/users/" + ownerID.String(), wantMsg: "cannot deactivate yourself"},
+		{name: "bad id", method: http.MethodDelete, path: "/api/v1/users/not-a-uuid", wantMsg: "Invalid user ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.wantMsg) {
+				t.Errorf("got %d %s, want 400 containing %q", w.Code, w.Body, tt.wantMsg)
+			}
+		})
+	}
+}
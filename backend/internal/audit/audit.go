@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+)
+
+// Recorded actions
+const (
+	ActionLogin          = "login"
+	ActionImportCreate   = "import.create"
+	ActionExportGenerate = "export.generate"
+	ActionStaffAnonymize = "staff.anonymize"
+	ActionMappingCreate  = "mapping.create"
+	ActionMappingUpdate  = "mapping.update"
+	ActionMappingDelete  = "mapping.delete"
+	ActionUserCreate     = "user.create"
+	ActionUserUpdate     = "user.update"
+	ActionUserDeactivate = "user.deactivate"
+	ActionMenuItemCreate = "menu_item.create"
+	ActionMenuItemUpdate = "menu_item.update"
+	ActionMenuItemImport = "menu_item.import"
+)
+
+// Filter narrows an audit log listing; zero fields match everything
+type Filter struct {
+	Actions  []string   // exact action names, e.g. import.create
+	UserID   *uuid.UUID // acting user
+	From     time.Time  // inclusive
+	To       time.Time  // exclusive
+	Page     int
+	PageSize int
+}
+
+// Entry is a single audit log record
+type Entry struct {
+	ID         uuid.UUID              `json:"id"`
+	UserID     *uuid.UUID             `json:"user_id,omitempty"`
+	LocationID *uuid.UUID             `json:"location_id,omitempty"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   *uuid.UUID             `json:"entity_id,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// Logger writes and reads the audit log
+type Logger struct {
+	db *pgxpool.Pool
+}
+
+// NewLogger creates a new audit logger
+func NewLogger(db *pgxpool.Pool) *Logger {
+	return &Logger{db: db}
+}
+
+// Record writes an entry attributed to the authenticated user in ctx, if any
+func (l *Logger) Record(ctx context.Context, action, entityType string, entityID *uuid.UUID, metadata map[string]interface{}) error {
+	var userID, locationID *uuid.UUID
+	if claims := auth.GetUserClaims(ctx); claims != nil {
+		userID = &claims.UserID
+		locationID = &claims.LocationID
+	}
+	return l.insert(ctx, userID, locationID, action, entityType, entityID, metadata)
+}
+
+// RecordFor writes an entry attributed to a specific user, for actions such as
+// login that happen before the request carries claims
+func (l *Logger) RecordFor(ctx context.Context, userID uuid.UUID, locationID *uuid.UUID, action, entityType string, entityID *uuid.UUID, metadata map[string]interface{}) error {
+	return l.insert(ctx, &userID, locationID, action, entityType, entityID, metadata)
+}
+
+func (l *Logger) insert(ctx context.Context, userID, locationID *uuid.UUID, action, entityType string, entityID *uuid.UUID, metadata map[string]interface{}) error {
+	meta, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO audit_log (id, user_id, location_id, action, entity_type, entity_id, metadata, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+	`
+	_, err = l.db.Exec(ctx, query, uuid.New(), userID, locationID, action, entityType, entityID, meta)
+	return err
+}
+
+// ListForUser returns a page of a user's own entries, newest first, along with
+// the total number of entries for that user
+func (l *Logger) ListForUser(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]Entry, int, error) {
+	return l.List(ctx, Filter{UserID: &userID, Page: page, PageSize: pageSize})
+}
+
+// List returns a page of entries matching the filter, newest first, along
+// with the total number of matching entries
+func (l *Logger) List(ctx context.Context, f Filter) ([]Entry, int, error) {
+	var from, to *time.Time
+	if !f.From.IsZero() {
+		from = &f.From
+	}
+	if !f.To.IsZero() {
+		to = &f.To
+	}
+	where := `
+		WHERE ($1::text[] IS NULL OR action = ANY($1))
+		AND ($2::uuid IS NULL OR user_id = $2)
+		AND ($3::timestamptz IS NULL OR created_at >= $3)
+		AND ($4::timestamptz IS NULL OR created_at < $4)
+	`
+	args := []interface{}{f.Actions, f.UserID, from, to}
+
+	var total int
+	if err := l.db.QueryRow(ctx, `SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, user_id, location_id, action, entity_type, entity_id, metadata, created_at
+		FROM audit_log` + where + `
+		ORDER BY created_at DESC
+		LIMIT $5 OFFSET $6
+	`
+	rows, err := l.db.Query(ctx, query, append(args, f.PageSize, (f.Page-1)*f.PageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		var meta []byte
+		if err := rows.Scan(&e.ID, &e.UserID, &e.LocationID, &e.Action, &e.EntityType, &e.EntityID, &meta, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if len(meta) > 0 {
+			json.Unmarshal(meta, &e.Metadata)
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
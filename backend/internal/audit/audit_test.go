@@ -0,0 +1,189 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool connects to TEST_DATABASE_URL, skipping the test when it is unset
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// testUser creates a user and removes it and its audit entries when the test
+// ends
+func testUser(t *testing.T, pool *pgxpool.Pool) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	var userID uuid.UUID
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash) VALUES ($1, 'x') RETURNING id
+	`, "audit-"+uuid.NewString()[:8]+"@example.com").Scan(&userID); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM audit_log WHERE user_id = $1`,
+			`DELETE FROM users WHERE id = $1`,
+		} {
+			if _, err := pool.Exec(ctx, q, userID); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+	})
+	return userID
+}
+
+func TestListForUserOnlyReturnsOwnEntries(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	l := NewLogger(pool)
+	alice, bob := testUser(t, pool), testUser(t, pool)
+
+	for _, e := range []struct {
+		user   uuid.UUID
+		action string
+	}{
+		{user: alice, action: ActionLogin},
+		{user: bob, action: ActionLogin},
+		{user: alice, action: ActionImportCreate},
+	} {
+		if err := l.RecordFor(ctx, e.user, nil, e.action, "user", nil, nil); err != nil {
+			t.Fatalf("RecordFor() error = %v", err)
+		}
+	}
+
+	entries, total, err := l.ListForUser(ctx, alice, 1, 50)
+	if err != nil {
+		t.Fatalf("ListForUser() error = %v", err)
+	}
+	if total != 2 || len(entries) != 2 {
+		t.Fatalf("got %d entries (total %d), want 2", len(entries), total)
+	}
+	for _, e := range entries {
+		if e.UserID == nil || *e.UserID != alice {
+			t.Errorf("entry %s belongs to %v, want %v", e.Action, e.UserID, alice)
+		}
+	}
+	if entries[0].Action != ActionImportCreate || entries[1].Action != ActionLogin {
+		t.Errorf("actions = %s, %s, want newest first", entries[0].Action, entries[1].Action)
+	}
+
+	page, total, err := l.ListForUser(ctx, alice, 2, 1)
+	if err != nil {
+		t.Fatalf("ListForUser() error = %v", err)
+	}
+	if total != 2 || len(page) != 1 || page[0].Action != ActionLogin {
+		t.Errorf("page 2 = %+v (total %d), want the login alone", page, total)
+	}
+
+	entries, total, err = l.ListForUser(ctx, bob, 1, 50)
+	if err != nil {
+		t.Fatalf("ListForUser() error = %v", err)
+	}
+	if total != 1 || len(entries) != 1 || *entries[0].UserID != bob {
+		t.Errorf("bob sees %+v (total %d), want only bob's login", entries, total)
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	l := NewLogger(pool)
+	alice, bob := testUser(t, pool), testUser(t, pool)
+
+	for _, e := range []struct {
+		user   uuid.UUID
+		action string
+		at     string
+	}{
+		{user: alice, action: ActionMappingCreate, at: "2001-04-01T09:00:00Z"},
+		{user: alice, action: ActionMappingDelete, at: "2001-04-03T09:00:00Z"},
+		{user: bob, action: ActionMappingUpdate, at: "2001-04-02T09:00:00Z"},
+		{user: bob, action: ActionLogin, at: "2001-04-02T10:00:00Z"},
+	} {
+		if err := l.RecordFor(ctx, e.user, nil, e.action, "mapping_profile", nil, map[string]interface{}{"name": "Square POS"}); err != nil {
+			t.Fatalf("RecordFor() error = %v", err)
+		}
+		// Backdate the entry so the date filters have something to select
+		if _, err := pool.Exec(ctx, `
+			UPDATE audit_log SET created_at = $1::timestamptz
+			WHERE id = (SELECT id FROM audit_log WHERE user_id = $2 AND action = $3 ORDER BY created_at DESC LIMIT 1)
+		`, e.at, e.user, e.action); err != nil {
+			t.Fatalf("backdate entry: %v", err)
+		}
+	}
+
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name        string
+		filter      Filter
+		wantActions []string
+	}{
+		{
+			name:        "mapping changes",
+			filter:      Filter{Actions: []string{ActionMappingCreate, ActionMappingUpdate, ActionMappingDelete}, From: at("2001-04-01T00:00:00Z"), To: at("2001-04-04T00:00:00Z")},
+			wantActions: []string{ActionMappingDelete, ActionMappingUpdate, ActionMappingCreate},
+		},
+		{
+			name:        "one user",
+			filter:      Filter{UserID: &bob, From: at("2001-04-01T00:00:00Z"), To: at("2001-04-04T00:00:00Z")},
+			wantActions: []string{ActionLogin, ActionMappingUpdate},
+		},
+		{
+			name:        "to is exclusive",
+			filter:      Filter{From: at("2001-04-01T00:00:00Z"), To: at("2001-04-02T09:00:00Z")},
+			wantActions: []string{ActionMappingCreate},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.Page, tt.filter.PageSize = 1, 50
+			entries, total, err := l.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var actions []string
+			for _, e := range entries {
+				if *e.UserID == alice || *e.UserID == bob {
+					actions = append(actions, e.Action)
+				}
+			}
+			if !reflect.DeepEqual(actions, tt.wantActions) || total < len(tt.wantActions) {
+				t.Errorf("actions = %q (total %d), want %q", actions, total, tt.wantActions)
+			}
+		})
+	}
+
+	entries, _, err := l.List(ctx, Filter{UserID: &alice, Actions: []string{ActionMappingCreate}, Page: 1, PageSize: 1})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Metadata["name"] != "Square POS" || entries[0].EntityType != "mapping_profile" {
+		t.Errorf("entry = %+v, want the mapping and its metadata", entries)
+	}
+}
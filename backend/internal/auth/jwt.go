@@ -11,28 +11,45 @@ import (
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
+// Token types carried in the typ claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// DefaultRefreshExpireHours is the refresh token lifetime when none is configured
+const DefaultRefreshExpireHours = 30 * 24
+
 // Claims represents JWT claims for a user
 type Claims struct {
 	UserID     uuid.UUID `json:"user_id"`
 	Email      string    `json:"email"`
 	Role       Role      `json:"role"`
 	LocationID uuid.UUID `json:"location_id"`
+	TokenType  string    `json:"typ,omitempty"` // access or refresh; empty is treated as access
 	jwt.RegisteredClaims
 }
 
 // JWTService handles JWT token operations
 type JWTService struct {
-	secret      []byte
-	expireHours int
+	secret             []byte
+	expireHours        int
+	refreshExpireHours int
 }
 
-// NewJWTService creates a new JWT service
-func NewJWTService(secret string, expireHours int) *JWTService {
+// NewJWTService creates a new JWT service. A refreshExpireHours of zero uses
+// DefaultRefreshExpireHours.
+func NewJWTService(secret string, expireHours, refreshExpireHours int) *JWTService {
+	if refreshExpireHours <= 0 {
+		refreshExpireHours = DefaultRefreshExpireHours
+	}
 	return &JWTService{
-		secret:      []byte(secret),
-		expireHours: expireHours,
+		secret:             []byte(secret),
+		expireHours:        expireHours,
+		refreshExpireHours: refreshExpireHours,
 	}
 }
 
@@ -43,6 +60,7 @@ func (s *JWTService) GenerateToken(userID uuid.UUID, email string, role Role, lo
 		Email:      email,
 		Role:       role,
 		LocationID: locationID,
+		TokenType:  TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(s.expireHours) * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -54,8 +72,62 @@ func (s *JWTService) GenerateToken(userID uuid.UUID, email string, role Role, lo
 	return token.SignedString(s.secret)
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// GenerateRefreshToken creates a long-lived refresh token for a user. Each
+// token carries a unique ID (jti) so it can be stored and revoked.
+func (s *JWTService) GenerateRefreshToken(userID uuid.UUID) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID:    userID,
+		TokenType: TokenTypeRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(s.refreshExpireHours) * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.secret)
+}
+
+// ValidateRefreshToken validates a refresh token and returns its user ID.
+// Access tokens are rejected.
+func (s *JWTService) ValidateRefreshToken(tokenString string) (uuid.UUID, error) {
+	claims, err := s.ParseRefreshToken(tokenString)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.UserID, nil
+}
+
+// ParseRefreshToken validates a refresh token and returns its claims,
+// including the token ID and expiry needed for revocation
+func (s *JWTService) ParseRefreshToken(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != TokenTypeRefresh || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// ValidateToken validates an access token and returns the claims. Refresh
+// tokens are rejected.
 func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != "" && claims.TokenType != TokenTypeAccess {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (s *JWTService) parse(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, ErrInvalidToken
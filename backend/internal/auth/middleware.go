@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"strings"
 )
@@ -18,19 +19,19 @@ func Middleware(jwtService *JWTService) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "unauthorized", "Authorization header required")
 				return
 			}
 
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid authorization header format")
 				return
 			}
 
 			claims, err := jwtService.ValidateToken(parts[1])
 			if err != nil {
-				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid or expired token")
 				return
 			}
 
@@ -65,7 +66,7 @@ func RequireRole(roles ...Role) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			claims := GetClaims(r.Context())
 			if claims == nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				writeError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
 				return
 			}
 
@@ -76,11 +77,26 @@ func RequireRole(roles ...Role) func(http.Handler) http.Handler {
 				}
 			}
 
-			http.Error(w, "Forbidden", http.StatusForbidden)
+			writeError(w, http.StatusForbidden, "forbidden", "Forbidden")
 		})
 	}
 }
 
+// writeError writes the same JSON error envelope as the api package, which
+// this package cannot import
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{
+			"code":       code,
+			"message":    message,
+			"request_id": w.Header().Get("X-Request-ID"),
+		},
+	})
+}
+
 // GetClaims retrieves claims from the context
 func GetClaims(ctx context.Context) *Claims {
 	claims, _ := ctx.Value(claimsContextKey).(*Claims)
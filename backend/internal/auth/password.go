@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidPassword is returned when a password does not match the stored hash
+var ErrInvalidPassword = errors.New("invalid password")
+
+// HashPassword returns a bcrypt hash of password suitable for users.password_hash
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// IsBcryptHash reports whether stored looks like a bcrypt hash
+func IsBcryptHash(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") || strings.HasPrefix(stored, "$2b$") || strings.HasPrefix(stored, "$2y$")
+}
+
+// VerifyPassword checks password against a stored hash. Bcrypt hashes are
+// always accepted; legacy plaintext values only match when allowPlaintext is
+// set, using a constant-time comparison. needsRehash is true when the stored
+// value matched but is not bcrypt, so callers can upgrade it.
+func VerifyPassword(password, stored string, allowPlaintext bool) (needsRehash bool, err error) {
+	if IsBcryptHash(stored) {
+		if err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)); err != nil {
+			return false, ErrInvalidPassword
+		}
+		return false, nil
+	}
+
+	if !allowPlaintext || stored == "" {
+		return false, ErrInvalidPassword
+	}
+	if subtle.ConstantTimeCompare([]byte(password), []byte(stored)) != 1 {
+		return false, ErrInvalidPassword
+	}
+	return true, nil
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RefreshTokenStore persists issued refresh token IDs so they can be revoked
+type RefreshTokenStore struct {
+	db *pgxpool.Pool
+}
+
+// NewRefreshTokenStore creates a new refresh token store
+func NewRefreshTokenStore(db *pgxpool.Pool) *RefreshTokenStore {
+	return &RefreshTokenStore{db: db}
+}
+
+// Save records a newly issued refresh token
+func (s *RefreshTokenStore) Save(ctx context.Context, claims *Claims) error {
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`
+	_, err = s.db.Exec(ctx, query, id, claims.UserID, claims.ExpiresAt.Time)
+	return err
+}
+
+// Revoke marks a refresh token as revoked. It reports whether the token was
+// active before the call, so a token can only be consumed once.
+func (s *RefreshTokenStore) Revoke(ctx context.Context, jti string) (bool, error) {
+	id, err := uuid.Parse(jti)
+	if err != nil {
+		return false, ErrInvalidToken
+	}
+	tag, err := s.db.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// RevokeAllForUser revokes every active refresh token for a user
+func (s *RefreshTokenStore) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
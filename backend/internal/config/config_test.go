@@ -0,0 +1,98 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "compression disabled", env: map[string]string{"COMPRESS_MIN_BYTES": "0"}},
+		{name: "short jwt secret", env: map[string]string{"JWT_SECRET": "too-short"}, wantErr: "JWT_SECRET"},
+		{name: "non-postgres database", env: map[string]string{"DATABASE_URL": "mysql://localhost/finance"}, wantErr: "DATABASE_URL"},
+		{name: "port out of range", env: map[string]string{"SERVER_PORT": "70000"}, wantErr: "SERVER_PORT"},
+		{name: "negative compress threshold", env: map[string]string{"COMPRESS_MIN_BYTES": "-1"}, wantErr: "COMPRESS_MIN_BYTES"},
+		{name: "negative anomaly cap", env: map[string]string{"IMPORT_ANOMALY_CAP": "-1"}, wantErr: "IMPORT_ANOMALY_CAP"},
+		{name: "unknown currency format", env: map[string]string{"EXPORT_CURRENCY_FORMAT": "dollars"}, wantErr: "EXPORT_CURRENCY_FORMAT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STORAGE_PATH", t.TempDir())
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() = %v, %v; want an error naming %s", cfg, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	t.Setenv("STORAGE_PATH", t.TempDir())
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	wantOrigins := []string{"http://localhost:3000", "http://127.0.0.1:3000"}
+	if !reflect.DeepEqual(cfg.Server.CORSAllowedOrigins, wantOrigins) {
+		t.Errorf("CORSAllowedOrigins = %q, want %q", cfg.Server.CORSAllowedOrigins, wantOrigins)
+	}
+	if cfg.Export.CurrencyFormat != "none" {
+		t.Errorf("Export.CurrencyFormat = %q, want none", cfg.Export.CurrencyFormat)
+	}
+}
+
+func TestLoadCORSOrigins(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr string
+	}{
+		{value: "https://finance.example.com", want: []string{"https://finance.example.com"}},
+		{value: " https://finance.example.com , http://10.0.0.5:3000,", want: []string{"https://finance.example.com", "http://10.0.0.5:3000"}},
+		{value: "*", want: []string{"*"}},
+		{value: "finance.example.com", wantErr: `"finance.example.com" is not an origin`},
+		{value: "https://finance.example.com/", wantErr: `"https://finance.example.com/" is not an origin`},
+		{value: "ftp://finance.example.com", wantErr: `"ftp://finance.example.com" is not an origin`},
+		{value: "https://*.example.com", wantErr: "partial wildcard"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("STORAGE_PATH", t.TempDir())
+			t.Setenv("CORS_ALLOWED_ORIGINS", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !reflect.DeepEqual(cfg.Server.CORSAllowedOrigins, tt.want) {
+				t.Errorf("CORSAllowedOrigins = %q, want %q", cfg.Server.CORSAllowedOrigins, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,17 @@
 package config
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 // FileUploadConfig holds upload safety settings
@@ -35,12 +40,22 @@ func ValidateConfig(cfg *Config) error {
 	} else if !strings.HasPrefix(cfg.Database.URL, "postgres://") && !strings.HasPrefix(cfg.Database.URL, "postgresql://") {
 		errs = append(errs, errors.New("DATABASE_URL must be a valid PostgreSQL connection string"))
 	}
+	if cfg.Database.StatementTimeoutSeconds < 0 {
+		errs = append(errs, errors.New("DB_STATEMENT_TIMEOUT_SECONDS must not be negative"))
+	}
 
 	// Server validation
 	if cfg.Server.Port < 1 || cfg.Server.Port > 65535 {
 		errs = append(errs, fmt.Errorf("SERVER_PORT must be between 1 and 65535, got %d", cfg.Server.Port))
 	}
 
+	if err := validateCORSOrigins(cfg.Server.CORSAllowedOrigins); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Server.CompressMinBytes < 0 {
+		errs = append(errs, errors.New("COMPRESS_MIN_BYTES must not be negative"))
+	}
+
 	// JWT validation
 	if cfg.JWT.Secret == "" {
 		errs = append(errs, errors.New("JWT_SECRET is required"))
@@ -50,12 +65,159 @@ func ValidateConfig(cfg *Config) error {
 	if cfg.JWT.ExpireHours < 1 {
 		errs = append(errs, errors.New("JWT_EXPIRE_HOURS must be at least 1"))
 	}
+	if cfg.JWT.RefreshExpireHours < cfg.JWT.ExpireHours {
+		errs = append(errs, errors.New("JWT_REFRESH_EXPIRE_HOURS must not be shorter than JWT_EXPIRE_HOURS"))
+	}
+
+	// Import validation
+	if cfg.Import.AnomalyCap < 0 {
+		errs = append(errs, errors.New("IMPORT_ANOMALY_CAP must not be negative"))
+	}
+	if cfg.Import.MaxRetries < 0 {
+		errs = append(errs, errors.New("IMPORT_MAX_RETRIES must not be negative"))
+	}
+	if cfg.Import.RetryBackoffMS < 0 {
+		errs = append(errs, errors.New("IMPORT_RETRY_BACKOFF_MS must not be negative"))
+	}
+	if cfg.Import.StreamThreshold < 0 {
+		errs = append(errs, errors.New("IMPORT_STREAM_THRESHOLD_BYTES must not be negative"))
+	}
+	if cfg.Import.StaleAfterMinutes < 0 {
+		errs = append(errs, errors.New("IMPORT_STALE_AFTER_MINUTES must not be negative"))
+	}
+	if cfg.Import.TimeoutMinutes < 0 {
+		errs = append(errs, errors.New("IMPORT_TIMEOUT_MINUTES must not be negative"))
+	}
+	if cfg.Import.DrainTimeoutSeconds < 0 {
+		errs = append(errs, errors.New("IMPORT_DRAIN_TIMEOUT_SECONDS must not be negative"))
+	}
+	if cfg.Import.IdempotencyTTLHours < 0 {
+		errs = append(errs, errors.New("IMPORT_IDEMPOTENCY_TTL_HOURS must not be negative"))
+	}
+	switch cfg.Import.DuplicateHeaders {
+	case "error", "rename":
+	default:
+		errs = append(errs, fmt.Errorf("IMPORT_DUPLICATE_HEADERS must be one of error, rename, got %q", cfg.Import.DuplicateHeaders))
+	}
+	switch cfg.Import.InventoryMode {
+	case "replace", "append":
+	default:
+		errs = append(errs, fmt.Errorf("IMPORT_INVENTORY_MODE must be one of replace, append, got %q", cfg.Import.InventoryMode))
+	}
+	switch cfg.Import.MangledNumbers {
+	case "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("IMPORT_MANGLED_NUMBERS must be one of warn, error, got %q", cfg.Import.MangledNumbers))
+	}
+
+	// Encryption validation
+	if cfg.Encryption.Key != "" {
+		if key, err := base64.StdEncoding.DecodeString(cfg.Encryption.Key); err != nil || len(key) != 32 {
+			errs = append(errs, errors.New("FIELD_ENCRYPTION_KEY must be a base64-encoded 32-byte key"))
+		}
+	}
+	for _, field := range cfg.Encryption.Fields {
+		switch field {
+		case "tax_withheld", "superannuation":
+		default:
+			errs = append(errs, fmt.Errorf("FIELD_ENCRYPTION_FIELDS must contain only tax_withheld, superannuation, got %q", field))
+		}
+	}
+
+	// Export validation
+	switch cfg.Export.CurrencyFormat {
+	case "symbol", "code", "none":
+	default:
+		errs = append(errs, fmt.Errorf("EXPORT_CURRENCY_FORMAT must be one of symbol, code, none, got %q", cfg.Export.CurrencyFormat))
+	}
+	if cfg.Export.LinkTTLMinutes < 1 {
+		errs = append(errs, errors.New("EXPORT_LINK_TTL_MINUTES must be at least 1"))
+	}
+	if cfg.Export.SystemUserID != "" {
+		if _, err := uuid.Parse(cfg.Export.SystemUserID); err != nil {
+			errs = append(errs, fmt.Errorf("EXPORT_SYSTEM_USER_ID must be a UUID, got %q", cfg.Export.SystemUserID))
+		}
+	}
+	if cfg.Export.CacheSeconds < 0 {
+		errs = append(errs, errors.New("EXPORT_CACHE_SECONDS must not be negative"))
+	}
+	if cfg.Export.MaxRows < 0 {
+		errs = append(errs, errors.New("EXPORT_MAX_ROWS must not be negative"))
+	}
+	switch cfg.Export.OverflowMode {
+	case "error", "summarize":
+	default:
+		errs = append(errs, fmt.Errorf("EXPORT_OVERFLOW_MODE must be one of error, summarize, got %q", cfg.Export.OverflowMode))
+	}
+
+	// Notification validation
+	if cfg.Notify.SMTPHost != "" {
+		if cfg.Notify.SMTPPort < 1 || cfg.Notify.SMTPPort > 65535 {
+			errs = append(errs, fmt.Errorf("SMTP_PORT must be between 1 and 65535, got %d", cfg.Notify.SMTPPort))
+		}
+		if cfg.Notify.SMTPFrom == "" {
+			errs = append(errs, errors.New("SMTP_FROM is required when SMTP_HOST is set"))
+		}
+	}
+	if cfg.Notify.WebhookURL != "" && !strings.HasPrefix(cfg.Notify.WebhookURL, "https://") && !strings.HasPrefix(cfg.Notify.WebhookURL, "http://") {
+		errs = append(errs, errors.New("NOTIFY_WEBHOOK_URL must be an http or https URL"))
+	}
+	if cfg.Notify.TimeoutSeconds < 1 {
+		errs = append(errs, errors.New("NOTIFY_TIMEOUT_SECONDS must be at least 1"))
+	}
+
+	// Digest validation
+	if cfg.Digest.Enabled {
+		if _, err := time.Parse("15:04", cfg.Digest.Time); err != nil {
+			errs = append(errs, fmt.Errorf("DIGEST_TIME must be HH:MM, got %q", cfg.Digest.Time))
+		}
+		if _, err := time.LoadLocation(cfg.Digest.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("DIGEST_TIMEZONE is not a valid time zone: %q", cfg.Digest.Timezone))
+		}
+		if len(cfg.Digest.Recipients) == 0 && !cfg.Digest.Webhook {
+			errs = append(errs, errors.New("DIGEST_RECIPIENTS or DIGEST_WEBHOOK is required when DIGEST_ENABLED is set"))
+		}
+		if len(cfg.Digest.Recipients) > 0 && cfg.Notify.SMTPHost == "" {
+			errs = append(errs, errors.New("SMTP_HOST is required when DIGEST_RECIPIENTS is set"))
+		}
+		if cfg.Digest.TopMessages < 1 {
+			errs = append(errs, errors.New("DIGEST_TOP_MESSAGES must be at least 1"))
+		}
+	}
+
+	// Aggregates validation
+	switch cfg.Aggregates.LaborBasis {
+	case "revenue", "covers", "even":
+	default:
+		errs = append(errs, fmt.Errorf("LABOR_ALLOCATION_BASIS must be one of revenue, covers, even, got %q", cfg.Aggregates.LaborBasis))
+	}
+
+	// KPI range validation
+	if cfg.KPI.MaxRangeDays < 0 {
+		errs = append(errs, errors.New("KPI_MAX_RANGE_DAYS must not be negative"))
+	}
+	if cfg.KPI.MaxFutureDays < 0 {
+		errs = append(errs, errors.New("KPI_MAX_FUTURE_DAYS must not be negative"))
+	}
+	if cfg.KPI.StaleCacheSeconds < 0 {
+		errs = append(errs, errors.New("KPI_STALE_CACHE_SECONDS must not be negative"))
+	}
+	if cfg.KPI.StaleCacheEntries < 0 {
+		errs = append(errs, errors.New("KPI_STALE_CACHE_ENTRIES must not be negative"))
+	}
 
 	// Storage path validation
 	if cfg.StoragePath == "" {
 		errs = append(errs, errors.New("STORAGE_PATH is required"))
 	}
 
+	// Logging validation
+	switch cfg.LogFormat {
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be one of text, json, got %q", cfg.LogFormat))
+	}
+
 	if len(errs) > 0 {
 		return errors.Join(errs...)
 	}
@@ -141,3 +303,22 @@ func containsString(slice []string, item string) bool {
 	}
 	return false
 }
+
+// validateCORSOrigins checks each CORS_ALLOWED_ORIGINS entry is "*" or a bare
+// http(s) origin such as https://app.example.com, with no path or trailing slash
+func validateCORSOrigins(origins []string) error {
+	for _, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return fmt.Errorf("CORS_ALLOWED_ORIGINS: %q is not an origin, use scheme://host[:port] or *", origin)
+		}
+		if strings.Contains(u.Host, "*") {
+			return fmt.Errorf("CORS_ALLOWED_ORIGINS: %q uses a partial wildcard, list each origin or use * on its own", origin)
+		}
+	}
+	return nil
+}
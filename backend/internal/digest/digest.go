@@ -0,0 +1,259 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/notify"
+)
+
+// MessageCount is an anomaly message and how often it occurred
+type MessageCount struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// Summary is one location's anomalies for the digest day
+type Summary struct {
+	LocationID   uuid.UUID      `json:"location_id"`
+	LocationName string         `json:"location_name"`
+	Imports      int            `json:"imports"`
+	Total        int            `json:"total"`
+	BySeverity   map[string]int `json:"by_severity"`
+	TopMessages  []MessageCount `json:"top_messages"`
+}
+
+// Config holds the digest schedule and recipients
+type Config struct {
+	Hour        int
+	Minute      int
+	Location    *time.Location
+	Recipients  []string
+	Webhook     bool
+	TopMessages int
+}
+
+// Store compiles anomaly digests from import data
+type Store struct {
+	db *pgxpool.Pool
+}
+
+// NewStore creates a new digest store
+func NewStore(db *pgxpool.Pool) *Store {
+	return &Store{db: db}
+}
+
+// anomalyGroup counts the anomalies one import recorded with the same
+// severity and message
+type anomalyGroup struct {
+	locationID   uuid.UUID
+	locationName string
+	jobID        uuid.UUID
+	severity     string
+	message      string
+	count        int
+}
+
+// Compile summarizes anomalies recorded in [start, end) per location, with
+// counts by severity and the top most frequent messages
+func (s *Store) Compile(ctx context.Context, start, end time.Time, top int) ([]Summary, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT j.location_id, COALESCE(l.name, ''), j.id, a.severity, a.message, COUNT(*)
+		FROM import_anomalies a
+		JOIN import_jobs j ON j.id = a.import_job_id
+		LEFT JOIN locations l ON l.id = j.location_id
+		WHERE a.created_at >= $1 AND a.created_at < $2
+		GROUP BY j.location_id, l.name, j.id, a.severity, a.message
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []anomalyGroup
+	for rows.Next() {
+		var g anomalyGroup
+		if err := rows.Scan(&g.locationID, &g.locationName, &g.jobID, &g.severity, &g.message, &g.count); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return summarize(groups, top), nil
+}
+
+// summarize folds anomaly groups from any number of imports into one summary
+// per location, sorted by location name. Imports are counted once per
+// location, and messages are ranked by their count across all of its imports,
+// ties broken alphabetically.
+func summarize(groups []anomalyGroup, top int) []Summary {
+	byLocation := make(map[uuid.UUID]*Summary)
+	imports := make(map[uuid.UUID]map[uuid.UUID]bool)
+	messages := make(map[uuid.UUID]map[string]int)
+	for _, g := range groups {
+		sum, ok := byLocation[g.locationID]
+		if !ok {
+			sum = &Summary{LocationID: g.locationID, LocationName: g.locationName, BySeverity: make(map[string]int)}
+			byLocation[g.locationID] = sum
+			imports[g.locationID] = make(map[uuid.UUID]bool)
+			messages[g.locationID] = make(map[string]int)
+		}
+		sum.BySeverity[g.severity] += g.count
+		sum.Total += g.count
+		imports[g.locationID][g.jobID] = true
+		messages[g.locationID][g.message] += g.count
+	}
+
+	summaries := make([]Summary, 0, len(byLocation))
+	for locationID, sum := range byLocation {
+		sum.Imports = len(imports[locationID])
+
+		ranked := make([]MessageCount, 0, len(messages[locationID]))
+		for message, count := range messages[locationID] {
+			ranked = append(ranked, MessageCount{Message: message, Count: count})
+		}
+		sort.Slice(ranked, func(i, j int) bool {
+			if ranked[i].Count != ranked[j].Count {
+				return ranked[i].Count > ranked[j].Count
+			}
+			return ranked[i].Message < ranked[j].Message
+		})
+		if top < 0 {
+			top = 0
+		}
+		if len(ranked) > top {
+			ranked = ranked[:top]
+		}
+		if len(ranked) > 0 {
+			sum.TopMessages = ranked
+		}
+		summaries = append(summaries, *sum)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].LocationName < summaries[j].LocationName
+	})
+	return summaries
+}
+
+// Scheduler sends the anomaly digest once a day
+type Scheduler struct {
+	store    *Store
+	notifier *notify.Notifier
+	cfg      Config
+}
+
+// NewScheduler creates a new digest scheduler
+func NewScheduler(store *Store, notifier *notify.Notifier, cfg Config) *Scheduler {
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	return &Scheduler{store: store, notifier: notifier, cfg: cfg}
+}
+
+// Run sends the previous day's digest at the configured time each day until
+// ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		next := s.nextRun(time.Now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		day := next.AddDate(0, 0, -1)
+		if err := s.Send(ctx, day); err != nil {
+			log.Printf("Failed to send anomaly digest for %s: %v", day.Format("2006-01-02"), err)
+		}
+	}
+}
+
+// nextRun returns the first scheduled send time after now
+func (s *Scheduler) nextRun(now time.Time) time.Time {
+	now = now.In(s.cfg.Location)
+	next := time.Date(now.Year(), now.Month(), now.Day(), s.cfg.Hour, s.cfg.Minute, 0, 0, s.cfg.Location)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Send compiles the anomalies for the calendar day containing day and
+// delivers them to the configured recipients and webhook. Days without
+// anomalies send nothing.
+func (s *Scheduler) Send(ctx context.Context, day time.Time) error {
+	day = day.In(s.cfg.Location)
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, s.cfg.Location)
+	end := start.AddDate(0, 0, 1)
+	date := start.Format("2006-01-02")
+
+	summaries, err := s.store.Compile(ctx, start, end, s.cfg.TopMessages)
+	if err != nil {
+		return fmt.Errorf("compile digest: %w", err)
+	}
+	if len(summaries) == 0 {
+		log.Printf("No import anomalies on %s, skipping digest", date)
+		return nil
+	}
+
+	var errs []error
+	if len(s.cfg.Recipients) > 0 {
+		subject := "Import anomaly digest for " + date
+		if err := s.notifier.SendEmail(ctx, s.cfg.Recipients, subject, formatText(date, summaries)); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	if s.cfg.Webhook && s.notifier.Config().WebhookConfigured() {
+		err := s.notifier.PostWebhook(ctx, map[string]interface{}{
+			"event":     "anomaly_digest",
+			"date":      date,
+			"locations": summaries,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// formatText renders the digest as a plain-text email body
+func formatText(date string, summaries []Summary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Import anomalies for %s\n", date)
+	for _, sum := range summaries {
+		name := sum.LocationName
+		if name == "" {
+			name = sum.LocationID.String()
+		}
+		fmt.Fprintf(&b, "\n%s: %d anomalies across %d imports\n", name, sum.Total, sum.Imports)
+
+		severities := make([]string, 0, len(sum.BySeverity))
+		for severity := range sum.BySeverity {
+			severities = append(severities, severity)
+		}
+		sort.Strings(severities)
+		for _, severity := range severities {
+			fmt.Fprintf(&b, "  %s: %d\n", severity, sum.BySeverity[severity])
+		}
+
+		if len(sum.TopMessages) > 0 {
+			b.WriteString("  Most frequent:\n")
+			for _, mc := range sum.TopMessages {
+				fmt.Fprintf(&b, "    %dx %s\n", mc.Count, mc.Message)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package digest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSummarizeAcrossImports(t *testing.T) {
+	cafe, bistro := uuid.New(), uuid.New()
+	morning, evening, bistroImport := uuid.New(), uuid.New(), uuid.New()
+
+	groups := []anomalyGroup{
+		// Two imports into the cafe repeat the same bad-date message
+		{locationID: cafe, locationName: "Cafe", jobID: morning, severity: "error", message: "invalid date", count: 3},
+		{locationID: cafe, locationName: "Cafe", jobID: morning, severity: "warning", message: "negative total", count: 1},
+		{locationID: cafe, locationName: "Cafe", jobID: evening, severity: "error", message: "invalid date", count: 2},
+		{locationID: cafe, locationName: "Cafe", jobID: evening, severity: "warning", message: "unknown channel", count: 1},
+		{locationID: cafe, locationName: "Cafe", jobID: evening, severity: "warning", message: "missing covers", count: 1},
+		{locationID: bistro, locationName: "Bistro", jobID: bistroImport, severity: "warning", message: "unknown channel", count: 4},
+	}
+
+	got := summarize(groups, 2)
+	want := []Summary{
+		{
+			LocationID:   bistro,
+			LocationName: "Bistro",
+			Imports:      1,
+			Total:        4,
+			BySeverity:   map[string]int{"warning": 4},
+			TopMessages:  []MessageCount{{Message: "unknown channel", Count: 4}},
+		},
+		{
+			LocationID:   cafe,
+			LocationName: "Cafe",
+			Imports:      2,
+			Total:        8,
+			BySeverity:   map[string]int{"error": 5, "warning": 3},
+			TopMessages:  []MessageCount{{Message: "invalid date", Count: 5}, {Message: "missing covers", Count: 1}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summarize() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	if got := summarize(nil, 5); len(got) != 0 {
+		t.Errorf("summarize(nil) = %+v, want no summaries", got)
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	cafe := uuid.New()
+	got := formatText("2024-03-01", []Summary{{
+		LocationID:   cafe,
+		LocationName: "Cafe",
+		Imports:      2,
+		Total:        8,
+		BySeverity:   map[string]int{"warning": 3, "error": 5},
+		TopMessages:  []MessageCount{{Message: "invalid date", Count: 5}},
+	}})
+
+	want := "Import anomalies for 2024-03-01\n" +
+		"\nCafe: 8 anomalies across 2 imports\n" +
+		"  error: 5\n" +
+		"  warning: 3\n" +
+		"  Most frequent:\n" +
+		"    5x invalid date\n"
+	if got != want {
+		t.Errorf("formatText() =\n%s\nwant\n%s", got, want)
+	}
+
+	if unnamed := formatText("2024-03-01", []Summary{{LocationID: cafe, BySeverity: map[string]int{}}}); !strings.Contains(unnamed, cafe.String()) {
+		t.Errorf("formatText() = %q, want the location ID for an unnamed location", unnamed)
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	brisbane, err := time.LoadLocation("Australia/Brisbane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewScheduler(nil, nil, Config{Hour: 7, Minute: 30, Location: brisbane})
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{name: "before send time", now: time.Date(2024, 3, 1, 6, 0, 0, 0, brisbane), want: time.Date(2024, 3, 1, 7, 30, 0, 0, brisbane)},
+		{name: "at send time", now: time.Date(2024, 3, 1, 7, 30, 0, 0, brisbane), want: time.Date(2024, 3, 2, 7, 30, 0, 0, brisbane)},
+		{name: "after send time", now: time.Date(2024, 3, 1, 22, 0, 0, 0, brisbane), want: time.Date(2024, 3, 2, 7, 30, 0, 0, brisbane)},
+		// 22:00 UTC is already 08:00 the next day in Brisbane
+		{name: "utc clock", now: time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC), want: time.Date(2024, 3, 3, 7, 30, 0, 0, brisbane)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.nextRun(tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextRun(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
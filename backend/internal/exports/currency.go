@@ -0,0 +1,84 @@
+package exports
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Currency formatting modes for export amounts. CSVs default to none so
+// spreadsheets read the amounts as numbers; the column header carries the code.
+const (
+	CurrencyFormatSymbol = "symbol" // €1234.56
+	CurrencyFormatCode   = "code"   // 1234.56 EUR
+	CurrencyFormatNone   = "none"   // 1234.56
+)
+
+// defaultCurrency is used when a location has no currency configured
+const defaultCurrency = "AUD"
+
+var currencySymbols = map[string]string{
+	"AUD": "$",
+	"CAD": "$",
+	"NZD": "$",
+	"SGD": "$",
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CHF": "CHF ",
+	"INR": "₹",
+}
+
+// moneyFormatter formats amounts for a single currency
+type moneyFormatter struct {
+	code   string
+	format string
+}
+
+func newMoneyFormatter(code, format string) moneyFormatter {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		code = defaultCurrency
+	}
+	return moneyFormatter{code: code, format: format}
+}
+
+// Amount formats v with two decimals and the configured currency marker
+func (f moneyFormatter) Amount(v float64) string {
+	num := fmt.Sprintf("%.2f", math.Abs(v))
+	sign := ""
+	if v < 0 && num != "0.00" {
+		sign = "-"
+	}
+
+	switch f.format {
+	case CurrencyFormatSymbol:
+		if symbol, ok := currencySymbols[f.code]; ok {
+			return sign + symbol + num
+		}
+		return sign + num + " " + f.code
+	case CurrencyFormatCode:
+		return sign + num + " " + f.code
+	default:
+		return sign + num
+	}
+}
+
+// Column labels a monetary column header with the currency code
+func (f moneyFormatter) Column(name string) string {
+	return fmt.Sprintf("%s (%s)", name, f.code)
+}
+
+// locationCurrency looks up a location's currency, falling back to the default
+func (s *ExportService) locationCurrency(ctx context.Context, locationID uuid.UUID) string {
+	var code string
+	err := s.db.QueryRow(ctx, `SELECT currency FROM locations WHERE id = $1`, locationID).Scan(&code)
+	if err != nil || code == "" {
+		return defaultCurrency
+	}
+	return code
+}
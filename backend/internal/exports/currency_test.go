@@ -0,0 +1,58 @@
+package exports
+
+import "testing"
+
+func TestMoneyFormatterAmount(t *testing.T) {
+	tests := []struct {
+		code   string
+		format string
+		v      float64
+		want   string
+	}{
+		{code: "AUD", format: CurrencyFormatNone, v: 1234.567, want: "1234.57"},
+		{code: "AUD", format: "", v: 1234.5, want: "1234.50"},
+		{code: "EUR", format: CurrencyFormatNone, v: -12.5, want: "-12.50"},
+		{code: "AUD", format: CurrencyFormatSymbol, v: 1234.5, want: "$1234.50"},
+		{code: "eur", format: CurrencyFormatSymbol, v: -12.5, want: "-€12.50"},
+		{code: "GBP", format: CurrencyFormatSymbol, v: 0.001, want: "£0.00"},
+		{code: "SEK", format: CurrencyFormatSymbol, v: 99, want: "99.00 SEK"},
+		{code: "EUR", format: CurrencyFormatCode, v: 1234.5, want: "1234.50 EUR"},
+		{code: "", format: CurrencyFormatCode, v: 1, want: "1.00 AUD"},
+		{code: "USD", format: CurrencyFormatCode, v: -0.001, want: "0.00 USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code+" "+tt.format+" "+tt.want, func(t *testing.T) {
+			if got := newMoneyFormatter(tt.code, tt.format).Amount(tt.v); got != tt.want {
+				t.Errorf("Amount(%v) = %q, want %q", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	tests := []struct {
+		in        string
+		want      string
+		canRender bool
+	}{
+		{in: "Revenue (AUD)", want: `Revenue \(AUD\)`, canRender: true},
+		{in: `a\b`, want: `a\\b`, canRender: true},
+		{in: "€12.50", want: `\20012.50`, canRender: true},
+		{in: "£12.50", want: `\24312.50`, canRender: true},
+		{in: "Café", want: `Caf\351`, canRender: true},
+		{in: "₹12.50", want: "?12.50"},
+		{in: "¥12", want: `\24512`, canRender: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := pdfEscape(tt.in); got != tt.want {
+				t.Errorf("pdfEscape(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if got := pdfCanRender(tt.in); got != tt.canRender {
+				t.Errorf("pdfCanRender(%q) = %v, want %v", tt.in, got, tt.canRender)
+			}
+		})
+	}
+}
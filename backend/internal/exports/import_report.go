@@ -0,0 +1,70 @@
+package exports
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/lakehouse/restaurant-finance/internal/imports"
+)
+
+// GenerateImportReport renders a PDF summarizing an import job: its status and
+// row counts, anomaly counts per severity, and the full anomaly table
+func GenerateImportReport(job *imports.ImportJob, anomalies []imports.ImportAnomaly) (string, []byte) {
+	doc := newPDFDocument("Import report " + job.ID.String())
+
+	doc.Heading("Import Report")
+	doc.Line("Import ID:      " + job.ID.String())
+	doc.Line("File:           " + job.FileName)
+	doc.Line("Source type:    " + job.SourceType)
+	doc.Line("Status:         " + job.Status)
+	doc.Line("Started:        " + job.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	if job.CompletedAt != nil {
+		doc.Line("Completed:      " + job.CompletedAt.Format("2006-01-02 15:04:05 MST"))
+	}
+	if job.ErrorMessage != "" {
+		doc.Line("Error:          " + job.ErrorMessage)
+	}
+	doc.Blank()
+
+	doc.Line(fmt.Sprintf("Total rows:     %d", job.TotalRows))
+	doc.Line(fmt.Sprintf("Processed rows: %d", job.ProcessedRows))
+	doc.Line(fmt.Sprintf("Error rows:     %d", job.ErrorRows))
+	doc.Blank()
+
+	// Per-severity counts
+	bySeverity := make(map[string]int)
+	for _, a := range anomalies {
+		bySeverity[a.Severity]++
+	}
+	severities := make([]string, 0, len(bySeverity))
+	for sev := range bySeverity {
+		severities = append(severities, sev)
+	}
+	sort.Strings(severities)
+
+	doc.Heading(fmt.Sprintf("Anomalies (%d)", len(anomalies)))
+	if len(anomalies) == 0 {
+		doc.Line("No anomalies were recorded for this import.")
+	} else {
+		counts := make([][]string, 0, len(severities))
+		for _, sev := range severities {
+			counts = append(counts, []string{sev, strconv.Itoa(bySeverity[sev])})
+		}
+		doc.Table([]string{"Severity", "Count"}, counts)
+		doc.Blank()
+
+		rows := make([][]string, 0, len(anomalies))
+		for _, a := range anomalies {
+			line := strconv.Itoa(a.LineNumber)
+			if a.LineNumber == 0 {
+				line = "-"
+			}
+			rows = append(rows, []string{line, a.Severity, a.Message})
+		}
+		doc.Table([]string{"Line", "Severity", "Message"}, rows)
+	}
+
+	fileName := fmt.Sprintf("import_report_%s.pdf", job.ID.String())
+	return fileName, doc.Bytes()
+}
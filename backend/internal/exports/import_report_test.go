@@ -0,0 +1,105 @@
+package exports
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/imports"
+)
+
+func TestGenerateImportReport(t *testing.T) {
+	job := &imports.ImportJob{
+		ID:            uuid.New(),
+		SourceType:    "pos",
+		Status:        "completed",
+		FileName:      "sales_march.csv",
+		TotalRows:     120,
+		ProcessedRows: 117,
+		ErrorRows:     3,
+		CreatedAt:     time.Date(2024, 4, 1, 9, 30, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name      string
+		anomalies []imports.ImportAnomaly
+		want      []string
+		wantPages int
+	}{
+		{
+			name: "anomalies",
+			anomalies: []imports.ImportAnomaly{
+				{LineNumber: 14, Severity: "error", Message: "invalid total: abc"},
+				{LineNumber: 15, Severity: "warning", Message: "unknown channel: Uber"},
+				{LineNumber: 0, Severity: "error", Message: "2 more errors with a similar message"},
+			},
+			want:      []string{"Anomalies (3)", "invalid total: abc", "unknown channel: Uber"},
+			wantPages: 1,
+		},
+		{
+			name:      "none",
+			want:      []string{"Anomalies (0)", "No anomalies were recorded"},
+			wantPages: 1,
+		},
+		{
+			name:      "many",
+			anomalies: manyAnomalies(200),
+			want:      []string{"Anomalies (200)", "negative covers on line 200"},
+			wantPages: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName, data := GenerateImportReport(job, tt.anomalies)
+
+			if want := "import_report_" + job.ID.String() + ".pdf"; fileName != want {
+				t.Errorf("fileName = %q, want %q", fileName, want)
+			}
+			if !bytes.HasPrefix(data, []byte("%PDF-")) {
+				t.Fatal("report is not a PDF")
+			}
+			for _, want := range append(tt.want, "Import ID:      "+job.ID.String(), "Processed rows: 117", "Error rows:     3") {
+				if !bytes.Contains(data, []byte(pdfEscape(want))) {
+					t.Errorf("report does not contain %q", want)
+				}
+			}
+			if got := bytes.Count(data, []byte("/Type /Page ")); got < tt.wantPages {
+				t.Errorf("report has %d pages, want at least %d", got, tt.wantPages)
+			}
+		})
+	}
+}
+
+// Severity counts are listed once per severity, in order
+func TestGenerateImportReportSeverityCounts(t *testing.T) {
+	job := &imports.ImportJob{ID: uuid.New(), CreatedAt: time.Now()}
+	anomalies := append(manyAnomalies(3), imports.ImportAnomaly{Severity: "error", Message: "bad date"})
+
+	_, data := GenerateImportReport(job, anomalies)
+	text := string(data)
+	errorRow := strings.Index(text, pdfEscape("error     1"))
+	warningRow := strings.Index(text, pdfEscape("warning   3"))
+	if errorRow < 0 || warningRow < 0 {
+		t.Fatalf("severity counts missing: error at %d, warning at %d", errorRow, warningRow)
+	}
+	if errorRow > warningRow {
+		t.Error("severities are not sorted")
+	}
+}
+
+func manyAnomalies(n int) []imports.ImportAnomaly {
+	anomalies := make([]imports.ImportAnomaly, n)
+	for i := range anomalies {
+		anomalies[i] = imports.ImportAnomaly{
+			LineNumber: i + 1,
+			Severity:   "warning",
+			Message:    fmt.Sprintf("negative covers on line %d", i+1),
+		}
+	}
+	return anomalies
+}
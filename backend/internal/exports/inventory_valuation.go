@@ -0,0 +1,166 @@
+package exports
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GenerateInventoryValuation creates an inventory valuation CSV export with
+// each item's change since its previous count, for reconciling actual against
+// theoretical food cost. With a SnapshotDate only that day's counts are
+// listed; otherwise each item's latest count on or before EndDate is used.
+func (s *ExportService) GenerateInventoryValuation(ctx context.Context, params ExportPnLParams) (*ExportJob, []byte, error) {
+	asOf := params.EndDate
+	exact := !params.SnapshotDate.IsZero()
+	if exact {
+		asOf = params.SnapshotDate
+	}
+
+	job := &ExportJob{
+		ID:          uuid.New(),
+		ExportType:  "inventory_valuation",
+		PeriodStart: asOf,
+		PeriodEnd:   asOf,
+		Status:      "processing",
+		FileName:    fmt.Sprintf("inventory_valuation_%s.csv", asOf.Format("20060102")),
+		LocationID:  &params.LocationID,
+		RequestedBy: requester(params.UserID),
+		RequestedAt: time.Now(),
+	}
+
+	if err := s.store.CreateJob(ctx, job); err != nil {
+		return nil, nil, err
+	}
+
+	query := `
+		WITH counted AS (
+			SELECT DISTINCT ON (item_name)
+				item_name, category, snapshot_date, quantity, unit, unit_cost, total_value
+			FROM inventory_snapshots
+			WHERE location_id = $1
+			AND snapshot_date <= $2
+			AND (NOT $3 OR snapshot_date = $2)
+			ORDER BY item_name, snapshot_date DESC
+		)
+		SELECT
+			c.item_name,
+			COALESCE(NULLIF(c.category, ''), 'Uncategorized') as category,
+			c.snapshot_date,
+			c.quantity,
+			COALESCE(c.unit, '') as unit,
+			c.unit_cost,
+			c.total_value,
+			p.snapshot_date,
+			p.quantity,
+			p.total_value
+		FROM counted c
+		LEFT JOIN LATERAL (
+			SELECT snapshot_date, quantity, total_value
+			FROM inventory_snapshots
+			WHERE location_id = $1
+			AND item_name = c.item_name
+			AND snapshot_date < c.snapshot_date
+			ORDER BY snapshot_date DESC
+			LIMIT 1
+		) p ON true
+		ORDER BY 2, c.item_name
+	`
+
+	rows, err := s.db.Query(ctx, query, params.LocationID, asOf, exact)
+	if err != nil {
+		s.store.UpdateJobStatus(ctx, job.ID, "failed", err.Error())
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var counts []valuationRow
+	for rows.Next() {
+		var c valuationRow
+		err := rows.Scan(&c.item, &c.category, &c.date, &c.quantity, &c.unit, &c.unitCost, &c.value, &c.priorDate, &c.priorQuantity, &c.priorValue)
+		if err != nil {
+			continue
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		s.store.UpdateJobStatus(ctx, job.ID, "failed", err.Error())
+		return nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	money := newMoneyFormatter(s.locationCurrency(ctx, params.LocationID), s.cfg.CurrencyFormat)
+	if err := writeInventoryValuation(&buf, money, counts); err != nil {
+		s.store.UpdateJobStatus(ctx, job.ID, "failed", err.Error())
+		return nil, nil, err
+	}
+
+	if err := s.completeJob(ctx, job, buf.Bytes()); err != nil {
+		return nil, nil, err
+	}
+
+	return job, buf.Bytes(), nil
+}
+
+// valuationRow is one item's count with its previous count, if any
+type valuationRow struct {
+	item, category, unit      string
+	date                      time.Time
+	quantity, unitCost        float64
+	value                     float64
+	priorDate                 *time.Time
+	priorQuantity, priorValue *float64
+}
+
+// writeInventoryValuation writes the valuation CSV: one row per counted item
+// and a total of value and value change
+func writeInventoryValuation(w io.Writer, money moneyFormatter, counts []valuationRow) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"Item", "Category", "Snapshot Date", "Quantity", "Unit", money.Column("Unit Cost"), money.Column("Total Value"), "Prior Snapshot Date", "Quantity Change", money.Column("Value Change")}
+	writer.Write(header)
+
+	var totalValue, totalChange float64
+	for _, c := range counts {
+		// Items counted for the first time have no prior snapshot to compare
+		priorDay, quantityChange, valueChange := "", "", ""
+		if c.priorDate != nil {
+			priorDay = c.priorDate.Format("2006-01-02")
+			quantityChange = formatQuantity(c.quantity - *c.priorQuantity)
+			valueChange = money.Amount(c.value - *c.priorValue)
+			totalChange += c.value - *c.priorValue
+		}
+		totalValue += c.value
+
+		writer.Write([]string{
+			c.item,
+			c.category,
+			c.date.Format("2006-01-02"),
+			formatQuantity(c.quantity),
+			c.unit,
+			money.Amount(c.unitCost),
+			money.Amount(c.value),
+			priorDay,
+			quantityChange,
+			valueChange,
+		})
+	}
+
+	writer.Write([]string{"Total", "", "", "", "", "", money.Amount(totalValue), "", "", money.Amount(totalChange)})
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatQuantity writes a count to the three decimals quantities are stored
+// with, without trailing zeros
+func formatQuantity(q float64) string {
+	return strconv.FormatFloat(math.Round(q*1000)/1000, 'f', -1, 64)
+}
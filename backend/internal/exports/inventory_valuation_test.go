@@ -0,0 +1,47 @@
+package exports
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteInventoryValuation(t *testing.T) {
+	day := func(d int) *time.Time {
+		v := time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC)
+		return &v
+	}
+	f := func(v float64) *float64 { return &v }
+
+	counts := []valuationRow{
+		{item: "Flour", category: "Dry goods", unit: "kg", date: *day(31), quantity: 12.5, unitCost: 1.8, value: 22.5, priorDate: day(24), priorQuantity: f(20), priorValue: f(36)},
+		{item: "Salmon", category: "Seafood", unit: "kg", date: *day(31), quantity: 4.25, unitCost: 32, value: 136, priorDate: day(24), priorQuantity: f(3.125), priorValue: f(100)},
+		{item: "Saffron", category: "Uncategorized", unit: "g", date: *day(31), quantity: 10, unitCost: 12.5, value: 125},
+	}
+
+	var buf bytes.Buffer
+	if err := writeInventoryValuation(&buf, newMoneyFormatter("AUD", CurrencyFormatNone), counts); err != nil {
+		t.Fatalf("writeInventoryValuation() error = %v", err)
+	}
+
+	want := [][]string{
+		{"Item", "Category", "Snapshot Date", "Quantity", "Unit", "Unit Cost (AUD)", "Total Value (AUD)", "Prior Snapshot Date", "Quantity Change", "Value Change (AUD)"},
+		{"Flour", "Dry goods", "2024-03-31", "12.5", "kg", "1.80", "22.50", "2024-03-24", "-7.5", "-13.50"},
+		{"Salmon", "Seafood", "2024-03-31", "4.25", "kg", "32.00", "136.00", "2024-03-24", "1.125", "36.00"},
+		// A first count has nothing to compare against
+		{"Saffron", "Uncategorized", "2024-03-31", "10", "g", "12.50", "125.00", "", "", ""},
+		{"Total", "", "", "", "", "", "283.50", "", "", "22.50"},
+	}
+	if got := exportRecords(t, buf.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("valuation CSV =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFormatQuantity(t *testing.T) {
+	for q, want := range map[float64]string{12: "12", 1.5: "1.5", 0.1 + 0.2: "0.3", 2.0004: "2", -7.125: "-7.125"} {
+		if got := formatQuantity(q); got != want {
+			t.Errorf("formatQuantity(%v) = %q, want %q", q, got, want)
+		}
+	}
+}
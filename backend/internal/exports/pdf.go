@@ -0,0 +1,240 @@
+package exports
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Page geometry for generated PDFs (A4 portrait, in points)
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 40.0
+	pdfFontSize   = 9.0
+	pdfLineHeight = 12.0
+	pdfCharWidth  = pdfFontSize * 0.6 // Courier is fixed width
+)
+
+// pdfMaxChars is how many monospaced characters fit on one line:
+// (pdfPageWidth - 2*pdfMargin) / pdfCharWidth, rounded down
+const pdfMaxChars = 95
+
+// pdfDocument is a minimal text-only PDF writer. It lays out headings, lines
+// and fixed-width tables using the built-in Courier fonts, which keeps exports
+// free of external rendering dependencies.
+type pdfDocument struct {
+	title string
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64
+}
+
+func newPDFDocument(title string) *pdfDocument {
+	d := &pdfDocument{title: title}
+	d.newPage()
+	return d
+}
+
+func (d *pdfDocument) newPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// ensureSpace starts a new page when fewer than n lines remain
+func (d *pdfDocument) ensureSpace(n int) bool {
+	if d.y-float64(n)*pdfLineHeight < pdfMargin {
+		d.newPage()
+		return true
+	}
+	return false
+}
+
+func (d *pdfDocument) text(font string, size float64, s string) {
+	d.ensureSpace(1)
+	d.y -= pdfLineHeight
+	fmt.Fprintf(d.page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, pdfMargin, d.y, pdfEscape(truncateRunes(s, pdfMaxChars)))
+}
+
+// Heading writes a bold title line followed by a blank line
+func (d *pdfDocument) Heading(s string) {
+	d.ensureSpace(3)
+	d.text("F2", pdfFontSize+4, s)
+	d.Blank()
+}
+
+// Line writes a single line of body text
+func (d *pdfDocument) Line(s string) {
+	d.text("F1", pdfFontSize, s)
+}
+
+// Blank advances by one line
+func (d *pdfDocument) Blank() {
+	if !d.ensureSpace(1) {
+		d.y -= pdfLineHeight
+	}
+}
+
+// Table writes rows as fixed-width columns, repeating the header on each page.
+// The last column absorbs whatever width is left and is truncated to fit.
+func (d *pdfDocument) Table(header []string, rows [][]string) {
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len([]rune(h))
+	}
+	for _, row := range rows {
+		for i := 0; i < len(row) && i < len(widths); i++ {
+			if n := len([]rune(row[i])); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	used := 0
+	for i := 0; i < len(widths)-1; i++ {
+		used += widths[i] + 2
+	}
+	if last := len(widths) - 1; last >= 0 && used+widths[last] > pdfMaxChars {
+		widths[last] = pdfMaxChars - used
+		if widths[last] < 8 {
+			widths[last] = 8
+		}
+	}
+
+	format := func(cells []string) string {
+		var b strings.Builder
+		for i, w := range widths {
+			cell := ""
+			if i < len(cells) {
+				cell = truncateRunes(cells[i], w)
+			}
+			b.WriteString(cell)
+			if i < len(widths)-1 {
+				b.WriteString(strings.Repeat(" ", w-len([]rune(cell))+2))
+			}
+		}
+		return b.String()
+	}
+
+	writeHeader := func() {
+		d.text("F2", pdfFontSize, format(header))
+		d.Line(strings.Repeat("-", len([]rune(format(header)))))
+	}
+
+	d.ensureSpace(3)
+	writeHeader()
+	for _, row := range rows {
+		if d.ensureSpace(1) {
+			writeHeader()
+		}
+		d.Line(format(row))
+	}
+}
+
+// Bytes serializes the document
+func (d *pdfDocument) Bytes() []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	startObj := func() int {
+		offsets = append(offsets, buf.Len())
+		n := len(offsets)
+		fmt.Fprintf(&buf, "%d 0 obj\n", n)
+		return n
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// Fixed objects: 1 catalog, 2 page tree, 3-4 fonts, 5 info
+	startObj()
+	buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	firstPage := 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+	startObj()
+	fmt.Fprintf(&buf, "<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(d.pages))
+
+	startObj()
+	buf.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>\nendobj\n")
+	startObj()
+	buf.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	startObj()
+	fmt.Fprintf(&buf, "<< /Title (%s) /Producer (Restaurant Finance) /CreationDate (D:%s) >>\nendobj\n",
+		pdfEscape(d.title), time.Now().UTC().Format("20060102150405Z"))
+
+	for i, page := range d.pages {
+		pageObj := startObj()
+		fmt.Fprintf(&buf, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			pdfPageWidth, pdfPageHeight, pageObj+1)
+
+		// Page footer
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(d.pages))
+		content := page.String() + fmt.Sprintf("BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", pdfFontSize-1, pdfMargin, pdfMargin/2, footer)
+
+		startObj()
+		fmt.Fprintf(&buf, "<< /Length %d >>\nstream\n%sendstream\nendobj\n", len(content), content)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+// pdfEscape escapes a string for use in a PDF literal. Characters outside
+// Latin-1, other than the euro sign, are replaced since the built-in fonts
+// cannot render them.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r >= 160 && r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case r == '€':
+			b.WriteString("\\200") // WinAnsiEncoding's euro sign
+		case r == '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// pdfCanRender reports whether pdfEscape can write s without replacing any
+// character
+func pdfCanRender(s string) bool {
+	for _, r := range s {
+		if (r < 32 || r >= 127) && (r < 160 || r >= 256) && r != '€' {
+			return false
+		}
+	}
+	return true
+}
+
+// truncateRunes shortens s to at most n characters, marking the cut with "..."
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(r[:n])
+	}
+	return string(r[:n-3]) + "..."
+}
@@ -0,0 +1,128 @@
+package exports
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+// GeneratePnLPDF creates a printable P&L statement: a header with the location
+// and period, the period totals, and revenue-to-profit tables by channel and
+// by daypart. Amounts carry no currency symbol since the built-in PDF fonts
+// cannot render most of them; the currency is named in the header instead.
+func (s *ExportService) GeneratePnLPDF(ctx context.Context, params ExportPnLParams) (*ExportJob, []byte, error) {
+	job := &ExportJob{
+		ID:          uuid.New(),
+		ExportType:  "pnl",
+		PeriodStart: params.StartDate,
+		PeriodEnd:   params.EndDate,
+		Status:      "processing",
+		FileName:    fmt.Sprintf("pnl_%s_%s.pdf", params.StartDate.Format("20060102"), params.EndDate.Format("20060102")),
+		LocationID:  &params.LocationID,
+		RequestedBy: requester(params.UserID),
+		RequestedAt: time.Now(),
+	}
+
+	if err := s.store.CreateJob(ctx, job); err != nil {
+		return nil, nil, err
+	}
+
+	kpiStore := kpi.NewStore(s.db)
+	totals, err := kpiStore.GetTotals(ctx, params.LocationID, params.StartDate, params.EndDate)
+	if err != nil {
+		s.store.UpdateJobStatus(ctx, job.ID, "failed", err.Error())
+		return nil, nil, err
+	}
+	byChannel, err := kpiStore.GetByChannel(ctx, params.LocationID, params.StartDate, params.EndDate)
+	if err != nil {
+		s.store.UpdateJobStatus(ctx, job.ID, "failed", err.Error())
+		return nil, nil, err
+	}
+	byDaypart, err := kpiStore.GetByDaypart(ctx, params.LocationID, params.StartDate, params.EndDate)
+	if err != nil {
+		s.store.UpdateJobStatus(ctx, job.ID, "failed", err.Error())
+		return nil, nil, err
+	}
+
+	currency := s.locationCurrency(ctx, params.LocationID)
+	// PDFs are read, not parsed, so amounts carry the currency symbol unless
+	// the built-in fonts can't draw it
+	money := newMoneyFormatter(currency, CurrencyFormatSymbol)
+	if symbol, ok := currencySymbols[money.code]; ok && !pdfCanRender(symbol) {
+		money.format = CurrencyFormatCode
+	}
+
+	var locationName string
+	if err := s.db.QueryRow(ctx, `SELECT name FROM locations WHERE id = $1`, params.LocationID).Scan(&locationName); err != nil {
+		locationName = params.LocationID.String()
+	}
+
+	doc := newPDFDocument("Profit and Loss " + locationName)
+
+	doc.Heading("Profit & Loss Statement")
+	doc.Line("Location:  " + locationName)
+	doc.Line(fmt.Sprintf("Period:    %s to %s", params.StartDate.Format("2 Jan 2006"), params.EndDate.Format("2 Jan 2006")))
+	doc.Line("Currency:  " + money.code)
+	doc.Line("Generated: " + job.RequestedAt.Format("2006-01-02 15:04 MST"))
+	doc.Blank()
+
+	doc.Heading("Totals")
+	doc.Table([]string{"", "Amount"}, [][]string{
+		{"Revenue", money.Amount(totals.Revenue)},
+		{"Cost of goods sold", money.Amount(totals.COGS)},
+		{"Gross margin", money.Amount(totals.GrossMargin)},
+		{"Labor cost", money.Amount(totals.LaborCost)},
+		{"Labor %", fmt.Sprintf("%.1f%%", totals.LaborPct)},
+		{"Operating expenses", money.Amount(totals.Opex)},
+		{"Net profit", money.Amount(totals.NetProfit)},
+		{"Covers", strconv.Itoa(totals.Covers)},
+		{"Average check", money.Amount(totals.AvgCheck)},
+		{"Discounts", money.Amount(totals.Discounts)},
+		{"Comps", money.Amount(totals.Comps)},
+	})
+	doc.Blank()
+
+	doc.Heading("By Channel")
+	doc.Table(pnlBreakdownHeader("Channel"), pnlBreakdownRows(byChannel, money))
+	doc.Blank()
+
+	doc.Heading("By Daypart")
+	doc.Table(pnlBreakdownHeader("Daypart"), pnlBreakdownRows(byDaypart, money))
+
+	data := doc.Bytes()
+	if err := s.completeJob(ctx, job, data); err != nil {
+		return nil, nil, err
+	}
+
+	return job, data, nil
+}
+
+func pnlBreakdownHeader(dimension string) []string {
+	return []string{dimension, "Revenue", "COGS", "Gross Margin", "Labor", "OpEx", "Net Profit", "Covers"}
+}
+
+func pnlBreakdownRows(summaries []kpi.KPISummary, money moneyFormatter) [][]string {
+	rows := make([][]string, 0, len(summaries))
+	for _, sum := range summaries {
+		name := sum.DisplayName
+		if name == "" {
+			name = sum.Label
+		}
+		rows = append(rows, []string{
+			name,
+			money.Amount(sum.Revenue),
+			money.Amount(sum.COGS),
+			money.Amount(sum.GrossMargin),
+			money.Amount(sum.LaborCost),
+			money.Amount(sum.Opex),
+			money.Amount(sum.NetProfit),
+			strconv.Itoa(sum.Covers),
+		})
+	}
+	return rows
+}
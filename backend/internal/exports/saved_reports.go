@@ -0,0 +1,212 @@
+package exports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+// ErrSavedReportNotFound is returned when a saved report does not exist for the location
+var ErrSavedReportNotFound = errors.New("saved report not found")
+
+// ErrSavedReportNameTaken is returned when the location already has a saved report with the name
+var ErrSavedReportNameTaken = errors.New("a saved report with this name already exists")
+
+// RangeCustom is the saved report range spec for a fixed start and end date
+const RangeCustom = "custom"
+
+// SavedReport is a named export configuration that can be re-run on demand.
+// RangeSpec is a relative range (7d, 30d, mtd, qtd, ytd, trailing12m) resolved when the
+// report runs, or custom for the fixed StartDate and EndDate.
+type SavedReport struct {
+	ID          uuid.UUID  `json:"id"`
+	LocationID  uuid.UUID  `json:"location_id"`
+	Name        string     `json:"name"`
+	ExportType  string     `json:"export_type"`
+	RangeSpec   string     `json:"range_spec"`
+	StartDate   *time.Time `json:"start_date,omitempty"`
+	EndDate     *time.Time `json:"end_date,omitempty"`
+	GroupBy     string     `json:"group_by,omitempty"`
+	SummaryOnly bool       `json:"summary_only"`
+	Format      string     `json:"format"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Validate checks the report's export type, format, range and filters,
+// filling in the csv format and 30d range when they are empty
+func (r *SavedReport) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	switch r.ExportType {
+	case "pnl", "channel_summary", "daypart_summary", "inventory_valuation":
+	case "tax_summary":
+		if _, err := kpi.TaxPeriodTrunc(r.GroupBy); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid export_type %q", r.ExportType)
+	}
+
+	switch r.Format {
+	case "":
+		r.Format = "csv"
+	case "csv":
+	case "pdf":
+		if r.ExportType != "pnl" {
+			return errors.New("format=pdf is only available for the pnl export")
+		}
+	default:
+		return errors.New("format must be csv or pdf")
+	}
+
+	switch r.RangeSpec {
+	case "":
+		r.RangeSpec = "30d"
+	case RangeCustom:
+		if r.StartDate == nil || r.EndDate == nil {
+			return errors.New("custom range requires start_date and end_date")
+		}
+		if r.EndDate.Before(*r.StartDate) {
+			return errors.New("end_date must not be before start_date")
+		}
+	default:
+		if !kpi.ValidRange(r.RangeSpec) {
+			return fmt.Errorf("invalid range_spec %q, use 7d, 30d, mtd, qtd, ytd, trailing12m or custom", r.RangeSpec)
+		}
+	}
+	if r.RangeSpec != RangeCustom {
+		r.StartDate, r.EndDate = nil, nil
+	}
+	return nil
+}
+
+// Params resolves the report into export parameters, evaluating relative
+// ranges against now and the location's fiscal year
+func (r *SavedReport) Params(now time.Time, userID uuid.UUID, fy kpi.FiscalYearStart) ExportPnLParams {
+	params := ExportPnLParams{
+		LocationID:  r.LocationID,
+		UserID:      userID,
+		GroupBy:     r.GroupBy,
+		SummaryOnly: r.SummaryOnly,
+	}
+	if r.RangeSpec == RangeCustom && r.StartDate != nil && r.EndDate != nil {
+		params.StartDate, params.EndDate = *r.StartDate, *r.EndDate
+	} else {
+		params.StartDate, params.EndDate = kpi.ParseDateRange(r.RangeSpec, now, fy)
+	}
+	return params
+}
+
+// SavedReportStore handles saved report persistence
+type SavedReportStore struct {
+	db *pgxpool.Pool
+}
+
+// NewSavedReportStore creates a new saved report store
+func NewSavedReportStore(db *pgxpool.Pool) *SavedReportStore {
+	return &SavedReportStore{db: db}
+}
+
+const savedReportColumns = `id, location_id, name, export_type, range_spec, start_date, end_date,
+	group_by, summary_only, format, created_by, created_at, updated_at`
+
+func scanSavedReport(row pgx.Row) (*SavedReport, error) {
+	var r SavedReport
+	err := row.Scan(&r.ID, &r.LocationID, &r.Name, &r.ExportType, &r.RangeSpec, &r.StartDate, &r.EndDate,
+		&r.GroupBy, &r.SummaryOnly, &r.Format, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// List returns a location's saved reports ordered by name
+func (s *SavedReportStore) List(ctx context.Context, locationID uuid.UUID) ([]SavedReport, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+savedReportColumns+` FROM saved_reports WHERE location_id = $1 ORDER BY name`, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []SavedReport{}
+	for rows.Next() {
+		r, err := scanSavedReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *r)
+	}
+	return reports, rows.Err()
+}
+
+// Get retrieves a saved report belonging to the location
+func (s *SavedReportStore) Get(ctx context.Context, id, locationID uuid.UUID) (*SavedReport, error) {
+	r, err := scanSavedReport(s.db.QueryRow(ctx, `SELECT `+savedReportColumns+` FROM saved_reports WHERE id = $1 AND location_id = $2`, id, locationID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSavedReportNotFound
+	}
+	return r, err
+}
+
+// Create inserts a new saved report
+func (s *SavedReportStore) Create(ctx context.Context, r *SavedReport) error {
+	r.ID = uuid.New()
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = r.CreatedAt
+
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO saved_reports (id, location_id, name, export_type, range_spec, start_date, end_date,
+			group_by, summary_only, format, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`, r.ID, r.LocationID, r.Name, r.ExportType, r.RangeSpec, r.StartDate, r.EndDate,
+		r.GroupBy, r.SummaryOnly, r.Format, r.CreatedBy, r.CreatedAt, r.UpdatedAt)
+	return nameTaken(err)
+}
+
+// Update replaces a saved report's configuration
+func (s *SavedReportStore) Update(ctx context.Context, r *SavedReport) error {
+	r.UpdatedAt = time.Now()
+	tag, err := s.db.Exec(ctx, `
+		UPDATE saved_reports
+		SET name = $3, export_type = $4, range_spec = $5, start_date = $6, end_date = $7,
+			group_by = $8, summary_only = $9, format = $10, updated_at = $11
+		WHERE id = $1 AND location_id = $2
+	`, r.ID, r.LocationID, r.Name, r.ExportType, r.RangeSpec, r.StartDate, r.EndDate,
+		r.GroupBy, r.SummaryOnly, r.Format, r.UpdatedAt)
+	if err != nil {
+		return nameTaken(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSavedReportNotFound
+	}
+	return nil
+}
+
+// Delete removes a saved report, returning false if none matched
+func (s *SavedReportStore) Delete(ctx context.Context, id, locationID uuid.UUID) (bool, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM saved_reports WHERE id = $1 AND location_id = $2`, id, locationID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// nameTaken maps a unique violation on (location_id, name) to ErrSavedReportNameTaken
+func nameTaken(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrSavedReportNameTaken
+	}
+	return err
+}
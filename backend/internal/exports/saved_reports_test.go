@@ -0,0 +1,160 @@
+package exports
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+)
+
+func TestSavedReportValidate(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		report     SavedReport
+		wantErr    string
+		wantFormat string
+		wantRange  string
+	}{
+		{name: "defaults", report: SavedReport{Name: "Monthly P&L", ExportType: "pnl"}, wantFormat: "csv", wantRange: "30d"},
+		{name: "pdf pnl", report: SavedReport{Name: "P&L", ExportType: "pnl", Format: "pdf", RangeSpec: "mtd"}, wantFormat: "pdf", wantRange: "mtd"},
+		{name: "custom", report: SavedReport{Name: "March", ExportType: "pnl", RangeSpec: RangeCustom, StartDate: &start, EndDate: &end}, wantFormat: "csv", wantRange: RangeCustom},
+		{name: "tax by quarter", report: SavedReport{Name: "BAS", ExportType: "tax_summary", GroupBy: "quarter"}, wantFormat: "csv", wantRange: "30d"},
+		{name: "no name", report: SavedReport{ExportType: "pnl"}, wantErr: "name is required"},
+		{name: "unknown type", report: SavedReport{Name: "x", ExportType: "balance_sheet"}, wantErr: "invalid export_type"},
+		{name: "pdf channel summary", report: SavedReport{Name: "x", ExportType: "channel_summary", Format: "pdf"}, wantErr: "only available for the pnl export"},
+		{name: "unknown range", report: SavedReport{Name: "x", ExportType: "pnl", RangeSpec: "fortnight"}, wantErr: "invalid range_spec"},
+		{name: "custom without dates", report: SavedReport{Name: "x", ExportType: "pnl", RangeSpec: RangeCustom}, wantErr: "requires start_date and end_date"},
+		{name: "custom reversed", report: SavedReport{Name: "x", ExportType: "pnl", RangeSpec: RangeCustom, StartDate: &end, EndDate: &start}, wantErr: "must not be before"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.report
+			err := r.Validate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if r.Format != tt.wantFormat || r.RangeSpec != tt.wantRange {
+				t.Errorf("format, range = %q, %q, want %q, %q", r.Format, r.RangeSpec, tt.wantFormat, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestSavedReportValidateDropsDatesForRelativeRanges(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	r := SavedReport{Name: "x", ExportType: "pnl", RangeSpec: "7d", StartDate: &start, EndDate: &start}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if r.StartDate != nil || r.EndDate != nil {
+		t.Errorf("dates = %v, %v, want none for a relative range", r.StartDate, r.EndDate)
+	}
+}
+
+func TestSavedReportParams(t *testing.T) {
+	locationID, userID := uuid.New(), uuid.New()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 5, 20, 10, 0, 0, 0, time.UTC)
+
+	custom := SavedReport{LocationID: locationID, RangeSpec: RangeCustom, StartDate: &start, EndDate: &end, GroupBy: "month", SummaryOnly: true}
+	got := custom.Params(now, userID, kpi.DefaultFiscalYearStart)
+	want := ExportPnLParams{LocationID: locationID, UserID: userID, StartDate: start, EndDate: end, GroupBy: "month", SummaryOnly: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("custom Params() = %+v, want %+v", got, want)
+	}
+
+	// Relative ranges resolve against the run time, not the save time
+	fy := kpi.FiscalYearStart{Month: time.July, Day: 1}
+	relative := SavedReport{LocationID: locationID, RangeSpec: "ytd"}
+	got = relative.Params(now, userID, fy)
+	wantStart, wantEnd := kpi.ParseDateRange("ytd", now, fy)
+	if !got.StartDate.Equal(wantStart) || !got.EndDate.Equal(wantEnd) {
+		t.Errorf("ytd Params() = %v to %v, want %v to %v", got.StartDate, got.EndDate, wantStart, wantEnd)
+	}
+	if got.StartDate.Month() != time.July || got.StartDate.Year() != 2023 {
+		t.Errorf("ytd starts %v, want the July 2023 fiscal year start", got.StartDate)
+	}
+}
+
+// TestSavedReportRun stores a report, reads it back and checks running it
+// produces the same export as requesting its configuration directly
+func TestSavedReportRun(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locationID := testLocation(t, pool, "Saved report test", "AUD")
+
+	for _, a := range []struct {
+		date    string
+		revenue float64
+	}{
+		{date: "2024-02-29", revenue: 999}, // outside the saved range
+		{date: "2024-03-01", revenue: 1000},
+		{date: "2024-03-02", revenue: 500.5},
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO kpi_aggregates (date, location_id, revenue, net_profit, covers) VALUES ($1, $2, $3, $3, 10)
+		`, a.date, locationID, a.revenue); err != nil {
+			t.Fatalf("seed aggregates: %v", err)
+		}
+	}
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	store := NewSavedReportStore(pool)
+	report := &SavedReport{LocationID: locationID, Name: "March summary", ExportType: "pnl", RangeSpec: RangeCustom, StartDate: &start, EndDate: &end, SummaryOnly: true}
+	if err := report.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if err := store.Create(ctx, report); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.Create(ctx, &SavedReport{LocationID: locationID, Name: "March summary", ExportType: "pnl", RangeSpec: "30d", Format: "csv"}); !errors.Is(err, ErrSavedReportNameTaken) {
+		t.Errorf("Create() duplicate name error = %v, want ErrSavedReportNameTaken", err)
+	}
+
+	saved, err := store.Get(ctx, report.ID, locationID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := store.Get(ctx, report.ID, uuid.New()); !errors.Is(err, ErrSavedReportNotFound) {
+		t.Errorf("Get() from another location error = %v, want ErrSavedReportNotFound", err)
+	}
+
+	svc := NewExportService(pool, pool, nil, ExportConfig{})
+	job, data, err := svc.GeneratePnLExport(ctx, saved.Params(time.Now(), uuid.Nil, kpi.DefaultFiscalYearStart))
+	if err != nil {
+		t.Fatalf("GeneratePnLExport() error = %v", err)
+	}
+	_, direct, err := svc.GeneratePnLExport(ctx, ExportPnLParams{LocationID: locationID, StartDate: start, EndDate: end, SummaryOnly: true})
+	if err != nil {
+		t.Fatalf("GeneratePnLExport() error = %v", err)
+	}
+
+	if job.FileName != "pnl_summary_20240301_20240302.csv" {
+		t.Errorf("FileName = %q, want the saved range's summary file", job.FileName)
+	}
+	records := exportRecords(t, data)
+	if !reflect.DeepEqual(records, exportRecords(t, direct)) {
+		t.Errorf("saved report export =\n%q\nwant the direct export\n%q", data, direct)
+	}
+	if len(records) != 2 || records[1][2] != "1500.50" {
+		t.Errorf("saved report export = %q, want one summary row with revenue 1500.50", records)
+	}
+}
@@ -4,51 +4,164 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/kpi"
+	"github.com/lakehouse/restaurant-finance/internal/storage"
+)
+
+// ErrExportNotStored is returned when an export has no stored file to download
+var ErrExportNotStored = errors.New("export file is not available")
+
+// Overflow behaviors when an export would exceed ExportConfig.MaxRows
+const (
+	OverflowError     = "error"     // reject the export
+	OverflowSummarize = "summarize" // fall back to one row per day
 )
 
+// RowLimitError is returned when an export would exceed the configured row cap
+type RowLimitError struct {
+	Rows int
+	Max  int
+}
+
+func (e *RowLimitError) Error() string {
+	return fmt.Sprintf("export would contain %d rows, which exceeds the limit of %d; narrow the date range or request summary_only", e.Rows, e.Max)
+}
+
 // ExportJob represents an export job
 type ExportJob struct {
 	ID          uuid.UUID  `json:"id"`
-	ExportType  string     `json:"export_type"` // pnl, channel_summary, daypart_summary
+	ExportType  string     `json:"export_type"` // pnl, channel_summary, daypart_summary, tax_summary, inventory_valuation
+	LocationID  *uuid.UUID `json:"location_id,omitempty"`
 	PeriodStart time.Time  `json:"period_start"`
 	PeriodEnd   time.Time  `json:"period_end"`
 	Status      string     `json:"status"` // pending, processing, completed, failed
 	FileName    string     `json:"file_name"`
 	FilePath    string     `json:"file_path,omitempty"`
-	RequestedBy uuid.UUID  `json:"requested_by"`
+	RequestedBy *uuid.UUID `json:"requested_by,omitempty"` // nil for anonymous exports
 	RequestedAt time.Time  `json:"requested_at"`
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
 }
 
+// requester returns the user to record on a job, or nil for uuid.Nil
+func requester(userID uuid.UUID) *uuid.UUID {
+	if userID == uuid.Nil {
+		return nil
+	}
+	return &userID
+}
+
+// ExportConfig holds tunable export behavior
+type ExportConfig struct {
+	CurrencyFormat string // CSV amounts: none, symbol or code; PDFs always use symbols
+	MaxRows        int    // Max detail rows in a P&L export; 0 disables the cap
+	OverflowMode   string // error, summarize
+}
+
 // ExportService handles export operations
 type ExportService struct {
-	db    *pgxpool.Pool
+	db    *pgxpool.Pool // report queries; may be a read replica
 	store *ExportStore
+	files *storage.FileStorage
+	cfg   ExportConfig
 }
 
-// NewExportService creates a new export service
-func NewExportService(db *pgxpool.Pool) *ExportService {
+// NewExportService creates a new export service. Report data is read from
+// readDB, which may be a replica, and export jobs are written to db. When
+// files is nil, generated exports are returned to the caller but not persisted
+// for later download.
+func NewExportService(db, readDB *pgxpool.Pool, files *storage.FileStorage, cfg ExportConfig) *ExportService {
 	return &ExportService{
-		db:    db,
+		db:    readDB,
 		store: NewExportStore(db),
+		files: files,
+		cfg:   cfg,
 	}
 }
 
+// completeJob persists the generated file (when storage is configured) and marks the job completed
+func (s *ExportService) completeJob(ctx context.Context, job *ExportJob, data []byte) error {
+	if s.files != nil {
+		path, err := s.files.SaveExport(job.ID.String()+"_"+job.FileName, data)
+		if err != nil {
+			s.store.UpdateJobStatus(ctx, job.ID, "failed", err.Error())
+			return err
+		}
+		job.FilePath = path
+	}
+
+	now := time.Now()
+	job.Status = "completed"
+	job.CompletedAt = &now
+	return s.store.UpdateJob(ctx, job)
+}
+
+// OpenExport opens the stored file for a completed export
+func (s *ExportService) OpenExport(ctx context.Context, id uuid.UUID) (*ExportJob, *os.File, error) {
+	job, err := s.store.GetJobByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.files == nil || job.FilePath == "" {
+		return job, nil, ErrExportNotStored
+	}
+
+	name := filepath.Base(job.FilePath)
+	job.FileName = strings.TrimPrefix(name, job.ID.String()+"_")
+
+	f, err := s.files.OpenExport(name)
+	if err != nil {
+		return job, nil, ErrExportNotStored
+	}
+	return job, f, nil
+}
+
 // ExportPnLParams contains parameters for P&L export
 type ExportPnLParams struct {
-	StartDate  time.Time
-	EndDate    time.Time
-	LocationID uuid.UUID
-	UserID     uuid.UUID
+	StartDate   time.Time
+	EndDate     time.Time
+	LocationID  uuid.UUID
+	UserID      uuid.UUID // uuid.Nil records no requester
+	GroupBy     string    // filing period for tax_summary: month, quarter
+	SummaryOnly bool      // P&L only: emit a single period totals row instead of the daily breakdown
+	// SnapshotDate limits inventory_valuation to one day's counts; zero uses
+	// each item's latest count on or before EndDate
+	SnapshotDate time.Time
 }
 
-// GeneratePnLExport creates a P&L CSV export
+// GeneratePnLExport creates a P&L CSV export. When the channel x daypart
+// breakdown would exceed the configured row cap, the export either fails with a
+// *RowLimitError or collapses to one row per day, depending on OverflowMode.
 func (s *ExportService) GeneratePnLExport(ctx context.Context, params ExportPnLParams) (*ExportJob, []byte, error) {
+	prefix := "pnl"
+	if params.SummaryOnly {
+		prefix = "pnl_summary"
+	}
+
+	daily := false
+	if !params.SummaryOnly && s.cfg.MaxRows > 0 {
+		n, err := s.countPnLRows(ctx, params)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n > s.cfg.MaxRows {
+			if s.cfg.OverflowMode != OverflowSummarize {
+				return nil, nil, &RowLimitError{Rows: n, Max: s.cfg.MaxRows}
+			}
+			daily = true
+			prefix = "pnl_daily"
+		}
+	}
+
 	// Create export job
 	job := &ExportJob{
 		ID:          uuid.New(),
@@ -56,8 +169,9 @@ func (s *ExportService) GeneratePnLExport(ctx context.Context, params ExportPnLP
 		PeriodStart: params.StartDate,
 		PeriodEnd:   params.EndDate,
 		Status:      "processing",
-		FileName:    fmt.Sprintf("pnl_%s_%s.csv", params.StartDate.Format("20060102"), params.EndDate.Format("20060102")),
-		RequestedBy: params.UserID,
+		FileName:    fmt.Sprintf("%s_%s_%s.csv", prefix, params.StartDate.Format("20060102"), params.EndDate.Format("20060102")),
+		LocationID:  &params.LocationID,
+		RequestedBy: requester(params.UserID),
 		RequestedAt: time.Now(),
 	}
 
@@ -65,12 +179,29 @@ func (s *ExportService) GeneratePnLExport(ctx context.Context, params ExportPnLP
 		return nil, nil, err
 	}
 
+	if params.SummaryOnly {
+		return s.generatePnLSummary(ctx, job, params)
+	}
+
 	// Query KPI aggregates
+	dimensions := `
+			COALESCE(sc.display_name, 'Total') as channel,
+			COALESCE(d.display_name, 'All Day') as daypart,`
+	grouping := `
+		GROUP BY DATE(k.date), sc.display_name, d.display_name, d.start_time
+		ORDER BY DATE(k.date), sc.display_name, d.start_time`
+	if daily {
+		dimensions = `
+			'Total' as channel,
+			'All Day' as daypart,`
+		grouping = `
+		GROUP BY DATE(k.date)
+		ORDER BY DATE(k.date)`
+	}
+
 	query := `
 		SELECT
-			DATE(k.date) as date,
-			COALESCE(sc.display_name, 'Total') as channel,
-			COALESCE(d.display_name, 'All Day') as daypart,
+			DATE(k.date) as date,` + dimensions + `
 			SUM(k.revenue) as revenue,
 			SUM(k.cogs) as cogs,
 			SUM(k.gross_margin) as gross_margin,
@@ -87,10 +218,7 @@ func (s *ExportService) GeneratePnLExport(ctx context.Context, params ExportPnLP
 		LEFT JOIN dayparts d ON k.daypart_id = d.id
 		WHERE k.location_id = $1
 		AND k.date >= $2
-		AND k.date <= $3
-		GROUP BY DATE(k.date), sc.display_name, d.display_name, d.start_time
-		ORDER BY DATE(k.date), sc.display_name, d.start_time
-	`
+		AND k.date <= $3` + grouping
 
 	rows, err := s.db.Query(ctx, query, params.LocationID, params.StartDate, params.EndDate)
 	if err != nil {
@@ -99,12 +227,14 @@ func (s *ExportService) GeneratePnLExport(ctx context.Context, params ExportPnLP
 	}
 	defer rows.Close()
 
+	money := newMoneyFormatter(s.locationCurrency(ctx, params.LocationID), s.cfg.CurrencyFormat)
+
 	// Generate CSV
 	var buf bytes.Buffer
 	writer := csv.NewWriter(&buf)
 
 	// Write header
-	header := []string{"Date", "Channel", "Daypart", "Revenue", "COGS", "Gross Margin", "Labor Cost", "Labor %", "OpEx", "Net Profit", "Covers", "Avg Check", "Discounts", "Comps"}
+	header := []string{"Date", "Channel", "Daypart", money.Column("Revenue"), money.Column("COGS"), money.Column("Gross Margin"), money.Column("Labor Cost"), "Labor %", money.Column("OpEx"), money.Column("Net Profit"), "Covers", money.Column("Avg Check"), money.Column("Discounts"), money.Column("Comps")}
 	writer.Write(header)
 
 	// Write data rows
@@ -123,28 +253,80 @@ func (s *ExportService) GeneratePnLExport(ctx context.Context, params ExportPnLP
 			date.Format("2006-01-02"),
 			channel,
 			daypart,
-			fmt.Sprintf("%.2f", revenue),
-			fmt.Sprintf("%.2f", cogs),
-			fmt.Sprintf("%.2f", grossMargin),
-			fmt.Sprintf("%.2f", laborCost),
+			money.Amount(revenue),
+			money.Amount(cogs),
+			money.Amount(grossMargin),
+			money.Amount(laborCost),
 			fmt.Sprintf("%.1f%%", laborPct),
-			fmt.Sprintf("%.2f", opex),
-			fmt.Sprintf("%.2f", netProfit),
+			money.Amount(opex),
+			money.Amount(netProfit),
 			fmt.Sprintf("%d", covers),
-			fmt.Sprintf("%.2f", avgCheck),
-			fmt.Sprintf("%.2f", discounts),
-			fmt.Sprintf("%.2f", comps),
+			money.Amount(avgCheck),
+			money.Amount(discounts),
+			money.Amount(comps),
 		}
 		writer.Write(row)
 	}
 
 	writer.Flush()
 
-	// Update job as completed
-	now := time.Now()
-	job.Status = "completed"
-	job.CompletedAt = &now
-	s.store.UpdateJob(ctx, job)
+	// Store the file and mark the job completed
+	if err := s.completeJob(ctx, job, buf.Bytes()); err != nil {
+		return nil, nil, err
+	}
+
+	return job, buf.Bytes(), nil
+}
+
+// countPnLRows returns how many detail rows a P&L export would contain
+func (s *ExportService) countPnLRows(ctx context.Context, params ExportPnLParams) (int, error) {
+	var n int
+	err := s.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM (
+			SELECT 1
+			FROM kpi_aggregates
+			WHERE location_id = $1 AND date >= $2 AND date <= $3
+			GROUP BY DATE(date), channel_id, daypart_id
+		) t
+	`, params.LocationID, params.StartDate, params.EndDate).Scan(&n)
+	return n, err
+}
+
+// generatePnLSummary writes a single row of period totals for a P&L export
+func (s *ExportService) generatePnLSummary(ctx context.Context, job *ExportJob, params ExportPnLParams) (*ExportJob, []byte, error) {
+	totals, err := kpi.NewStore(s.db).GetTotals(ctx, params.LocationID, params.StartDate, params.EndDate)
+	if err != nil {
+		s.store.UpdateJobStatus(ctx, job.ID, "failed", err.Error())
+		return nil, nil, err
+	}
+
+	money := newMoneyFormatter(s.locationCurrency(ctx, params.LocationID), s.cfg.CurrencyFormat)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	writer.Write([]string{"Period Start", "Period End", money.Column("Revenue"), money.Column("COGS"), money.Column("Gross Margin"), money.Column("Labor Cost"), "Labor %", money.Column("OpEx"), money.Column("Net Profit"), "Covers", money.Column("Avg Check"), money.Column("Discounts"), money.Column("Comps")})
+	writer.Write([]string{
+		params.StartDate.Format("2006-01-02"),
+		params.EndDate.Format("2006-01-02"),
+		money.Amount(totals.Revenue),
+		money.Amount(totals.COGS),
+		money.Amount(totals.GrossMargin),
+		money.Amount(totals.LaborCost),
+		fmt.Sprintf("%.1f%%", totals.LaborPct),
+		money.Amount(totals.Opex),
+		money.Amount(totals.NetProfit),
+		fmt.Sprintf("%d", totals.Covers),
+		money.Amount(totals.AvgCheck),
+		money.Amount(totals.Discounts),
+		money.Amount(totals.Comps),
+	})
+
+	writer.Flush()
+
+	if err := s.completeJob(ctx, job, buf.Bytes()); err != nil {
+		return nil, nil, err
+	}
 
 	return job, buf.Bytes(), nil
 }
@@ -158,7 +340,8 @@ func (s *ExportService) GenerateChannelSummary(ctx context.Context, params Expor
 		PeriodEnd:   params.EndDate,
 		Status:      "processing",
 		FileName:    fmt.Sprintf("channel_summary_%s_%s.csv", params.StartDate.Format("20060102"), params.EndDate.Format("20060102")),
-		RequestedBy: params.UserID,
+		LocationID:  &params.LocationID,
+		RequestedBy: requester(params.UserID),
 		RequestedAt: time.Now(),
 	}
 
@@ -170,6 +353,8 @@ func (s *ExportService) GenerateChannelSummary(ctx context.Context, params Expor
 		SELECT
 			COALESCE(sc.display_name, 'Unknown') as channel,
 			SUM(k.revenue) as revenue,
+			SUM(k.commissions) as commissions,
+			SUM(k.revenue - k.commissions) as net_revenue,
 			SUM(k.cogs) as cogs,
 			SUM(k.gross_margin) as gross_margin,
 			SUM(k.covers) as covers,
@@ -191,39 +376,200 @@ func (s *ExportService) GenerateChannelSummary(ctx context.Context, params Expor
 	}
 	defer rows.Close()
 
+	money := newMoneyFormatter(s.locationCurrency(ctx, params.LocationID), s.cfg.CurrencyFormat)
+
 	var buf bytes.Buffer
 	writer := csv.NewWriter(&buf)
 
-	header := []string{"Channel", "Revenue", "COGS", "Gross Margin", "Covers", "Avg Check"}
+	header := []string{"Channel", money.Column("Revenue"), money.Column("Commissions"), money.Column("Net Revenue"), money.Column("COGS"), money.Column("Gross Margin"), "Covers", money.Column("Avg Check")}
 	writer.Write(header)
 
 	for rows.Next() {
 		var channel string
-		var revenue, cogs, grossMargin, avgCheck float64
+		var revenue, commissions, netRevenue, cogs, grossMargin, avgCheck float64
 		var covers int
 
-		err := rows.Scan(&channel, &revenue, &cogs, &grossMargin, &covers, &avgCheck)
+		err := rows.Scan(&channel, &revenue, &commissions, &netRevenue, &cogs, &grossMargin, &covers, &avgCheck)
 		if err != nil {
 			continue
 		}
 
 		row := []string{
 			channel,
-			fmt.Sprintf("%.2f", revenue),
-			fmt.Sprintf("%.2f", cogs),
-			fmt.Sprintf("%.2f", grossMargin),
+			money.Amount(revenue),
+			money.Amount(commissions),
+			money.Amount(netRevenue),
+			money.Amount(cogs),
+			money.Amount(grossMargin),
 			fmt.Sprintf("%d", covers),
-			fmt.Sprintf("%.2f", avgCheck),
+			money.Amount(avgCheck),
 		}
 		writer.Write(row)
 	}
 
 	writer.Flush()
 
-	now := time.Now()
-	job.Status = "completed"
-	job.CompletedAt = &now
-	s.store.UpdateJob(ctx, job)
+	if err := s.completeJob(ctx, job, buf.Bytes()); err != nil {
+		return nil, nil, err
+	}
+
+	return job, buf.Bytes(), nil
+}
+
+// GenerateDaypartSummary creates a daypart summary CSV export. Aggregates without
+// a daypart are reported as "All Day", matching the P&L export.
+func (s *ExportService) GenerateDaypartSummary(ctx context.Context, params ExportPnLParams) (*ExportJob, []byte, error) {
+	job := &ExportJob{
+		ID:          uuid.New(),
+		ExportType:  "daypart_summary",
+		PeriodStart: params.StartDate,
+		PeriodEnd:   params.EndDate,
+		Status:      "processing",
+		FileName:    fmt.Sprintf("daypart_summary_%s_%s.csv", params.StartDate.Format("20060102"), params.EndDate.Format("20060102")),
+		LocationID:  &params.LocationID,
+		RequestedBy: requester(params.UserID),
+		RequestedAt: time.Now(),
+	}
+
+	if err := s.store.CreateJob(ctx, job); err != nil {
+		return nil, nil, err
+	}
+
+	query := `
+		SELECT
+			COALESCE(d.display_name, 'All Day') as daypart,
+			SUM(k.revenue) as revenue,
+			SUM(k.cogs) as cogs,
+			SUM(k.gross_margin) as gross_margin,
+			SUM(k.covers) as covers,
+			CASE WHEN SUM(k.covers) > 0 THEN SUM(k.revenue) / SUM(k.covers) ELSE 0 END as avg_check
+		FROM kpi_aggregates k
+		LEFT JOIN dayparts d ON k.daypart_id = d.id
+		WHERE k.location_id = $1
+		AND k.date >= $2
+		AND k.date <= $3
+		AND k.daypart_id IS NOT NULL
+		GROUP BY d.display_name, d.start_time
+		ORDER BY d.start_time
+	`
+
+	rows, err := s.db.Query(ctx, query, params.LocationID, params.StartDate, params.EndDate)
+	if err != nil {
+		s.store.UpdateJobStatus(ctx, job.ID, "failed", err.Error())
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	money := newMoneyFormatter(s.locationCurrency(ctx, params.LocationID), s.cfg.CurrencyFormat)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := []string{"Daypart", money.Column("Revenue"), money.Column("COGS"), money.Column("Gross Margin"), "Covers", money.Column("Avg Check")}
+	writer.Write(header)
+
+	for rows.Next() {
+		var daypart string
+		var revenue, cogs, grossMargin, avgCheck float64
+		var covers int
+
+		err := rows.Scan(&daypart, &revenue, &cogs, &grossMargin, &covers, &avgCheck)
+		if err != nil {
+			continue
+		}
+
+		row := []string{
+			daypart,
+			money.Amount(revenue),
+			money.Amount(cogs),
+			money.Amount(grossMargin),
+			fmt.Sprintf("%d", covers),
+			money.Amount(avgCheck),
+		}
+		writer.Write(row)
+	}
+
+	writer.Flush()
+
+	if err := s.completeJob(ctx, job, buf.Bytes()); err != nil {
+		return nil, nil, err
+	}
+
+	return job, buf.Bytes(), nil
+}
+
+// GenerateTaxSummary creates a GST/VAT summary CSV for a location grouped by
+// filing period and channel
+func (s *ExportService) GenerateTaxSummary(ctx context.Context, params ExportPnLParams) (*ExportJob, []byte, error) {
+	trunc, err := kpi.TaxPeriodTrunc(params.GroupBy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	job := &ExportJob{
+		ID:          uuid.New(),
+		ExportType:  "tax_summary",
+		PeriodStart: params.StartDate,
+		PeriodEnd:   params.EndDate,
+		Status:      "processing",
+		FileName:    fmt.Sprintf("tax_summary_%s_%s.csv", params.StartDate.Format("20060102"), params.EndDate.Format("20060102")),
+		LocationID:  &params.LocationID,
+		RequestedBy: requester(params.UserID),
+		RequestedAt: time.Now(),
+	}
+
+	if err := s.store.CreateJob(ctx, job); err != nil {
+		return nil, nil, err
+	}
+
+	periods, err := kpi.NewStore(s.db).GetTaxSummary(ctx, params.LocationID, params.StartDate, params.EndDate, trunc)
+	if err != nil {
+		s.store.UpdateJobStatus(ctx, job.ID, "failed", err.Error())
+		return nil, nil, err
+	}
+
+	money := newMoneyFormatter(s.locationCurrency(ctx, params.LocationID), s.cfg.CurrencyFormat)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := []string{"Period", "Channel", money.Column("Taxable Sales"), money.Column("Tax-Exempt Sales"), money.Column("Tax Collected"), "Transactions", "Exempt Transactions"}
+	writer.Write(header)
+
+	var taxable, exempt, tax float64
+	var transactions, exemptTransactions int
+	for _, p := range periods {
+		writer.Write([]string{
+			p.Period,
+			p.Channel,
+			money.Amount(p.TaxableSales),
+			money.Amount(p.TaxExemptSales),
+			money.Amount(p.TaxCollected),
+			fmt.Sprintf("%d", p.Transactions),
+			fmt.Sprintf("%d", p.ExemptTransactions),
+		})
+		taxable += p.TaxableSales
+		exempt += p.TaxExemptSales
+		tax += p.TaxCollected
+		transactions += p.Transactions
+		exemptTransactions += p.ExemptTransactions
+	}
+
+	writer.Write([]string{
+		"Total",
+		"",
+		money.Amount(taxable),
+		money.Amount(exempt),
+		money.Amount(tax),
+		fmt.Sprintf("%d", transactions),
+		fmt.Sprintf("%d", exemptTransactions),
+	})
+
+	writer.Flush()
+
+	if err := s.completeJob(ctx, job, buf.Bytes()); err != nil {
+		return nil, nil, err
+	}
 
 	return job, buf.Bytes(), nil
 }
@@ -241,12 +587,13 @@ func NewExportStore(db *pgxpool.Pool) *ExportStore {
 // CreateJob creates a new export job
 func (s *ExportStore) CreateJob(ctx context.Context, job *ExportJob) error {
 	query := `
-		INSERT INTO export_jobs (id, export_type, period_start, period_end, status, file_path, requested_by, requested_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO export_jobs (id, export_type, location_id, period_start, period_end, status, file_path, requested_by, requested_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 	_, err := s.db.Exec(ctx, query,
 		job.ID,
 		job.ExportType,
+		job.LocationID,
 		job.PeriodStart,
 		job.PeriodEnd,
 		job.Status,
@@ -256,10 +603,11 @@ func (s *ExportStore) CreateJob(ctx context.Context, job *ExportJob) error {
 	)
 	return err
 }
+
 // GetJobByID retrieves an export job by ID
 func (s *ExportStore) GetJobByID(ctx context.Context, id uuid.UUID) (*ExportJob, error) {
 	query := `
-		SELECT id, export_type, period_start, period_end, status, file_path, requested_by, requested_at, completed_at
+		SELECT id, export_type, location_id, period_start, period_end, status, file_path, requested_by, requested_at, completed_at
 		FROM export_jobs
 		WHERE id = $1
 	`
@@ -268,6 +616,7 @@ func (s *ExportStore) GetJobByID(ctx context.Context, id uuid.UUID) (*ExportJob,
 	err := s.db.QueryRow(ctx, query, id).Scan(
 		&job.ID,
 		&job.ExportType,
+		&job.LocationID,
 		&job.PeriodStart,
 		&job.PeriodEnd,
 		&job.Status,
@@ -300,27 +649,48 @@ func (s *ExportStore) UpdateJobStatus(ctx context.Context, id uuid.UUID, status,
 	return err
 }
 
-// ListJobs retrieves export jobs (no location filter for now)
-func (s *ExportStore) ListJobs(ctx context.Context, locationID uuid.UUID, limit int) ([]ExportJob, error) {
-	query := `
-		SELECT id, export_type, period_start, period_end, status, file_path, requested_by, requested_at, completed_at
+// ListJobsFilter selects a page of export jobs for a location
+type ListJobsFilter struct {
+	LocationID  uuid.UUID
+	RequestedBy *uuid.UUID // optional: only jobs requested by this user
+	Page        int        // 1-based
+	PageSize    int
+}
+
+// ListJobs retrieves a page of export jobs for a location, newest first, along
+// with the total number of matching jobs
+func (s *ExportStore) ListJobs(ctx context.Context, filter ListJobsFilter) ([]ExportJob, int, error) {
+	where := `
 		FROM export_jobs
+		WHERE location_id = $1
+		AND ($2::uuid IS NULL OR requested_by = $2)
+	`
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) `+where, filter.LocationID, filter.RequestedBy).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, export_type, location_id, period_start, period_end, status, file_path, requested_by, requested_at, completed_at
+	` + where + `
 		ORDER BY requested_at DESC
-		LIMIT $1
+		LIMIT $3 OFFSET $4
 	`
 
-	rows, err := s.db.Query(ctx, query, limit)
+	rows, err := s.db.Query(ctx, query, filter.LocationID, filter.RequestedBy, filter.PageSize, (filter.Page-1)*filter.PageSize)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var jobs []ExportJob
+	jobs := []ExportJob{}
 	for rows.Next() {
 		var job ExportJob
 		err := rows.Scan(
 			&job.ID,
 			&job.ExportType,
+			&job.LocationID,
 			&job.PeriodStart,
 			&job.PeriodEnd,
 			&job.Status,
@@ -330,9 +700,9 @@ func (s *ExportStore) ListJobs(ctx context.Context, locationID uuid.UUID, limit
 			&job.CompletedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		jobs = append(jobs, job)
 	}
-	return jobs, rows.Err()
+	return jobs, total, rows.Err()
 }
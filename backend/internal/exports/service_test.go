@@ -0,0 +1,291 @@
+package exports
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool connects to TEST_DATABASE_URL, skipping the test when it is unset
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// testLocation creates a location using currency and removes it and its
+// aggregates, channels, export jobs and saved reports when the test ends
+func testLocation(t *testing.T, pool *pgxpool.Pool, name, currency string) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	var locationID uuid.UUID
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO locations (name, timezone, currency) VALUES ($1, 'Europe/Paris', $2) RETURNING id
+	`, name, currency).Scan(&locationID); err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM saved_reports WHERE location_id = $1`,
+			`DELETE FROM export_jobs WHERE location_id = $1`,
+			`DELETE FROM kpi_aggregates WHERE location_id = $1`,
+			`DELETE FROM service_channels WHERE location_id = $1`,
+			`DELETE FROM locations WHERE id = $1`,
+		} {
+			if _, err := pool.Exec(ctx, q, locationID); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+	})
+	return locationID
+}
+
+func TestPnLExportEUR(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locationID := testLocation(t, pool, "Bistro Paris", "EUR")
+
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO kpi_aggregates (date, location_id, revenue, cogs, gross_margin, labor_cost, opex, net_profit, covers, avg_check)
+		VALUES ('2024-03-01', $1, 1234.50, 400.00, 834.50, 300.00, 100.25, 434.25, 40, 30.86)
+	`, locationID); err != nil {
+		t.Fatalf("seed aggregates: %v", err)
+	}
+
+	params := ExportPnLParams{
+		LocationID: locationID,
+		StartDate:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:    time.Date(2024, 3, 1, 23, 59, 59, 0, time.UTC),
+	}
+
+	tests := []struct {
+		format     string
+		summary    bool
+		wantRev    string
+		wantProfit string
+	}{
+		{format: CurrencyFormatNone, wantRev: "1234.50", wantProfit: "434.25"},
+		{format: CurrencyFormatSymbol, wantRev: "€1234.50", wantProfit: "€434.25"},
+		{format: CurrencyFormatCode, wantRev: "1234.50 EUR", wantProfit: "434.25 EUR"},
+		{format: CurrencyFormatSymbol, summary: true, wantRev: "€1234.50", wantProfit: "€434.25"},
+	}
+
+	for _, tt := range tests {
+		name := tt.format
+		if tt.summary {
+			name += " summary"
+		}
+		t.Run(name, func(t *testing.T) {
+			svc := NewExportService(pool, pool, nil, ExportConfig{CurrencyFormat: tt.format})
+			p := params
+			p.SummaryOnly = tt.summary
+			_, data, err := svc.GeneratePnLExport(ctx, p)
+			if err != nil {
+				t.Fatalf("GeneratePnLExport() error = %v", err)
+			}
+
+			records := exportRecords(t, data)
+			if len(records) != 2 {
+				t.Fatalf("got %d records, want header and one row:\n%s", len(records), data)
+			}
+
+			header, row := records[0], records[1]
+			got := map[string]string{}
+			for i, col := range header {
+				got[col] = row[i]
+			}
+			if got["Revenue (EUR)"] != tt.wantRev {
+				t.Errorf("Revenue (EUR) = %q, want %q", got["Revenue (EUR)"], tt.wantRev)
+			}
+			if got["Net Profit (EUR)"] != tt.wantProfit {
+				t.Errorf("Net Profit (EUR) = %q, want %q", got["Net Profit (EUR)"], tt.wantProfit)
+			}
+			if got["Labor %"] != "24.3%" {
+				t.Errorf("Labor %% = %q, want 24.3%%", got["Labor %"])
+			}
+		})
+	}
+
+	t.Run("pdf", func(t *testing.T) {
+		svc := NewExportService(pool, pool, nil, ExportConfig{})
+		_, data, err := svc.GeneratePnLPDF(ctx, params)
+		if err != nil {
+			t.Fatalf("GeneratePnLPDF() error = %v", err)
+		}
+		for _, want := range []string{"Currency:  EUR", pdfEscape("€1234.50"), pdfEscape("€434.25")} {
+			if !bytes.Contains(data, []byte(want)) {
+				t.Errorf("PDF does not contain %q", want)
+			}
+		}
+		if bytes.Contains(data, []byte("$1234.50")) {
+			t.Error("PDF formats EUR amounts with a dollar sign")
+		}
+	})
+}
+
+func TestLocationCurrencyDefault(t *testing.T) {
+	pool := testPool(t)
+	svc := NewExportService(pool, pool, nil, ExportConfig{})
+
+	got := []string{
+		svc.locationCurrency(context.Background(), testLocation(t, pool, "Bistro Paris", "EUR")),
+		svc.locationCurrency(context.Background(), uuid.New()),
+	}
+	if want := []string{"EUR", defaultCurrency}; !reflect.DeepEqual(got, want) {
+		t.Errorf("locationCurrency() = %q, want %q", got, want)
+	}
+}
+
+// exportRecords parses a CSV export
+func exportRecords(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	return records
+}
+
+func TestPnLExportSummaryOnly(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locationID := testLocation(t, pool, "Summary Test", "AUD")
+
+	for _, a := range []struct {
+		date                     string
+		revenue, labor, discount float64
+		covers                   int
+	}{
+		{date: "2024-03-01", revenue: 1000, labor: 300, covers: 40, discount: 20},
+		{date: "2024-03-02", revenue: 500.5, labor: 150, covers: 20},
+		{date: "2024-03-03", revenue: 250.25, labor: 75.1, covers: 10, discount: 5.5},
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO kpi_aggregates (date, location_id, revenue, labor_cost, net_profit, covers, discounts)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, a.date, locationID, a.revenue, a.labor, a.revenue-a.labor, a.covers, a.discount); err != nil {
+			t.Fatalf("seed aggregates: %v", err)
+		}
+	}
+
+	svc := NewExportService(pool, pool, nil, ExportConfig{})
+	job, data, err := svc.GeneratePnLExport(ctx, ExportPnLParams{
+		LocationID:  locationID,
+		StartDate:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:     time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC),
+		SummaryOnly: true,
+	})
+	if err != nil {
+		t.Fatalf("GeneratePnLExport() error = %v", err)
+	}
+	if job.FileName != "pnl_summary_20240301_20240303.csv" {
+		t.Errorf("FileName = %q", job.FileName)
+	}
+
+	records := exportRecords(t, data)
+	want := [][]string{
+		{"Period Start", "Period End", "Revenue (AUD)", "COGS (AUD)", "Gross Margin (AUD)", "Labor Cost (AUD)", "Labor %", "OpEx (AUD)", "Net Profit (AUD)", "Covers", "Avg Check (AUD)", "Discounts (AUD)", "Comps (AUD)"},
+		{"2024-03-01", "2024-03-03", "1750.75", "0.00", "0.00", "525.10", "30.0%", "0.00", "1225.65", "70", "25.01", "25.50", "0.00"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("summary export =\n%q\nwant\n%q", records, want)
+	}
+}
+
+func TestPnLExportRowLimit(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locationID := testLocation(t, pool, "Row Limit Test", "AUD")
+
+	var channels []uuid.UUID
+	for _, name := range []string{"Dine In", "Takeaway", "Delivery"} {
+		var id uuid.UUID
+		if err := pool.QueryRow(ctx, `
+			INSERT INTO service_channels (code, display_name, location_id) VALUES ($1, $2, $3) RETURNING id
+		`, name+"-"+uuid.NewString()[:8], name, locationID).Scan(&id); err != nil {
+			t.Fatalf("create channel: %v", err)
+		}
+		channels = append(channels, id)
+	}
+
+	// Four detail rows: three channels on the 1st, one on the 2nd
+	for _, a := range []struct {
+		date    string
+		channel uuid.UUID
+		revenue float64
+	}{
+		{date: "2024-03-01", channel: channels[0], revenue: 100},
+		{date: "2024-03-01", channel: channels[1], revenue: 50},
+		{date: "2024-03-01", channel: channels[2], revenue: 25},
+		{date: "2024-03-02", channel: channels[0], revenue: 80},
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO kpi_aggregates (date, location_id, channel_id, revenue) VALUES ($1, $2, $3, $4)
+		`, a.date, locationID, a.channel, a.revenue); err != nil {
+			t.Fatalf("seed aggregates: %v", err)
+		}
+	}
+
+	params := ExportPnLParams{
+		LocationID: locationID,
+		StartDate:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:    time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name        string
+		cfg         ExportConfig
+		wantErr     *RowLimitError
+		wantFile    string
+		wantRevenue []string // revenue column of each data row
+	}{
+		{name: "under cap", cfg: ExportConfig{MaxRows: 4}, wantFile: "pnl_20240301_20240302.csv", wantRevenue: []string{"25.00", "100.00", "50.00", "80.00"}},
+		{name: "no cap", cfg: ExportConfig{}, wantFile: "pnl_20240301_20240302.csv", wantRevenue: []string{"25.00", "100.00", "50.00", "80.00"}},
+		{name: "error", cfg: ExportConfig{MaxRows: 3, OverflowMode: OverflowError}, wantErr: &RowLimitError{Rows: 4, Max: 3}},
+		{name: "default mode errors", cfg: ExportConfig{MaxRows: 3}, wantErr: &RowLimitError{Rows: 4, Max: 3}},
+		{name: "summarize", cfg: ExportConfig{MaxRows: 3, OverflowMode: OverflowSummarize}, wantFile: "pnl_daily_20240301_20240302.csv", wantRevenue: []string{"175.00", "80.00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, data, err := NewExportService(pool, pool, nil, tt.cfg).GeneratePnLExport(ctx, params)
+			if tt.wantErr != nil {
+				var limitErr *RowLimitError
+				if !errors.As(err, &limitErr) || *limitErr != *tt.wantErr {
+					t.Fatalf("GeneratePnLExport() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GeneratePnLExport() error = %v", err)
+			}
+			if job.FileName != tt.wantFile {
+				t.Errorf("FileName = %q, want %q", job.FileName, tt.wantFile)
+			}
+
+			var revenue []string
+			for _, row := range exportRecords(t, data)[1:] {
+				revenue = append(revenue, row[3])
+			}
+			if !reflect.DeepEqual(revenue, tt.wantRevenue) {
+				t.Errorf("revenue = %q, want %q", revenue, tt.wantRevenue)
+			}
+		})
+	}
+}
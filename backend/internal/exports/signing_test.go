@@ -0,0 +1,107 @@
+package exports
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestURLSignerVerify(t *testing.T) {
+	signer := NewURLSigner("test-signing-key")
+	exportID := uuid.New()
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+
+	unix := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+	tampered := []byte(signer.Sign(exportID, future))
+	if tampered[0] == '0' {
+		tampered[0] = '1'
+	} else {
+		tampered[0] = '0'
+	}
+
+	tests := []struct {
+		name     string
+		exportID uuid.UUID
+		token    string
+		expires  string
+		want     error
+	}{
+		{
+			name:     "valid",
+			exportID: exportID,
+			token:    signer.Sign(exportID, future),
+			expires:  unix(future),
+		},
+		{
+			name:     "tampered token",
+			exportID: exportID,
+			token:    string(tampered),
+			expires:  unix(future),
+			want:     ErrInvalidSignature,
+		},
+		{
+			name:     "other export",
+			exportID: uuid.New(),
+			token:    signer.Sign(exportID, future),
+			expires:  unix(future),
+			want:     ErrInvalidSignature,
+		},
+		{
+			name:     "extended expiry",
+			exportID: exportID,
+			token:    signer.Sign(exportID, future),
+			expires:  unix(future.Add(24 * time.Hour)),
+			want:     ErrInvalidSignature,
+		},
+		{
+			name:     "signed with another key",
+			exportID: exportID,
+			token:    NewURLSigner("other-key").Sign(exportID, future),
+			expires:  unix(future),
+			want:     ErrInvalidSignature,
+		},
+		{
+			name:     "expired",
+			exportID: exportID,
+			token:    signer.Sign(exportID, past),
+			expires:  unix(past),
+			want:     ErrLinkExpired,
+		},
+		{
+			name:     "non-numeric expires",
+			exportID: exportID,
+			token:    signer.Sign(exportID, future),
+			expires:  "tomorrow",
+			want:     ErrInvalidSignature,
+		},
+		{
+			name:     "missing token",
+			exportID: exportID,
+			expires:  unix(future),
+			want:     ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signer.Verify(tt.exportID, tt.token, tt.expires)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLSignerSignedQuery(t *testing.T) {
+	signer := NewURLSigner("test-signing-key")
+	exportID := uuid.New()
+	q := signer.SignedQuery(exportID, time.Now().Add(time.Hour))
+
+	if err := signer.Verify(exportID, q.Get("token"), q.Get("expires")); err != nil {
+		t.Errorf("Verify(SignedQuery()) error = %v, want nil", err)
+	}
+}
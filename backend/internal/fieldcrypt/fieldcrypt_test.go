@@ -0,0 +1,118 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func testCipher(t *testing.T, b byte) *Cipher {
+	t.Helper()
+	c, err := NewCipher(testKey(b))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	return c
+}
+
+func TestRoundTrip(t *testing.T) {
+	c := testCipher(t, 1)
+
+	for _, plaintext := range []string{"", "Jane Citizen", "Zoë O'Brien-Łukasz", "1234.56"} {
+		sealed, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q) error = %v", plaintext, err)
+		}
+		if !IsEncrypted(sealed) {
+			t.Errorf("Encrypt(%q) = %q, missing prefix", plaintext, sealed)
+		}
+		if plaintext != "" && strings.Contains(sealed, plaintext) {
+			t.Errorf("Encrypt(%q) = %q, contains the plaintext", plaintext, sealed)
+		}
+		got, err := c.Decrypt(sealed)
+		if err != nil {
+			t.Fatalf("Decrypt() error = %v", err)
+		}
+		if got != plaintext {
+			t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+		}
+	}
+}
+
+func TestEncryptUsesFreshNonce(t *testing.T) {
+	c := testCipher(t, 1)
+	a, _ := c.Encrypt("Jane Citizen")
+	b, _ := c.Encrypt("Jane Citizen")
+	if a == b {
+		t.Error("encrypting the same value twice gave the same ciphertext")
+	}
+}
+
+func TestAmountRoundTrip(t *testing.T) {
+	c := testCipher(t, 1)
+
+	for _, v := range []float64{0, 1234.56, -12.5, 0.1} {
+		sealed, err := c.EncryptAmount(v)
+		if err != nil {
+			t.Fatalf("EncryptAmount(%v) error = %v", v, err)
+		}
+		got, err := c.DecryptAmount(sealed)
+		if err != nil {
+			t.Fatalf("DecryptAmount() error = %v", err)
+		}
+		if got != v {
+			t.Errorf("DecryptAmount() = %v, want %v", got, v)
+		}
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	c := testCipher(t, 1)
+	sealed, err := c.Encrypt("Jane Citizen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, prefix))
+	raw[len(raw)-1] ^= 0xFF
+	tampered := prefix + base64.StdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name    string
+		cipher  *Cipher
+		value   string
+		wantErr string
+	}{
+		{name: "wrong key", cipher: testCipher(t, 2), value: sealed, wantErr: "failed to decrypt"},
+		{name: "tampered", cipher: c, value: tampered, wantErr: "failed to decrypt"},
+		{name: "plain value", cipher: c, value: "Jane Citizen", wantErr: ErrNotEncrypted.Error()},
+		{name: "not base64", cipher: c, value: prefix + "!!!", wantErr: "malformed"},
+		{name: "too short", cipher: c, value: prefix + "AAAA", wantErr: "too short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Decrypt() = %q, %v, want error containing %q", got, err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := c.Decrypt("plain"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("Decrypt(plain) error = %v, want ErrNotEncrypted", err)
+	}
+}
+
+func TestNewCipherRejectsBadKeys(t *testing.T) {
+	for _, key := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("too short"))} {
+		if _, err := NewCipher(key); err == nil {
+			t.Errorf("NewCipher(%q) succeeded, want error", key)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package imports
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// anomalyWriter persists anomalies; ImportStore satisfies it
+type anomalyWriter interface {
+	CreateAnomaly(ctx context.Context, anomaly *ImportAnomaly) error
+}
+
+// anomalyRecorder stores anomalies for a single import job, keeping at most
+// cap individual records per kind of problem and summarizing the rest. Rows
+// are grouped by the message template (see anomalyKey) rather than the
+// message, which usually quotes the offending value.
+type anomalyRecorder struct {
+	store      anomalyWriter
+	jobID      uuid.UUID
+	cap        int
+	counts     map[string]int
+	suppressed map[string]int
+	severity   map[string]string
+	example    map[string]string
+	order      []string
+}
+
+func newAnomalyRecorder(store anomalyWriter, jobID uuid.UUID, cap int) *anomalyRecorder {
+	return &anomalyRecorder{
+		store:      store,
+		jobID:      jobID,
+		cap:        cap,
+		counts:     make(map[string]int),
+		suppressed: make(map[string]int),
+		severity:   make(map[string]string),
+		example:    make(map[string]string),
+	}
+}
+
+// Record stores an anomaly unless its kind has already reached the cap.
+// rawData is a snapshot of the offending row, empty for file-level issues.
+func (r *anomalyRecorder) Record(ctx context.Context, lineNumber int, severity, message, rawData string) {
+	key := severity + "|" + anomalyKey(message)
+	r.counts[key]++
+	if r.cap > 0 && r.counts[key] > r.cap {
+		if r.suppressed[key] == 0 {
+			r.order = append(r.order, key)
+			r.severity[key] = severity
+			r.example[key] = message
+		}
+		r.suppressed[key]++
+		return
+	}
+
+	r.store.CreateAnomaly(ctx, &ImportAnomaly{
+		ID:          uuid.New(),
+		ImportJobID: r.jobID,
+		LineNumber:  lineNumber,
+		Severity:    severity,
+		Message:     message,
+		RawData:     rawData,
+		CreatedAt:   time.Now(),
+	})
+}
+
+// anomalyKey reduces a message to its template so rows failing the same way
+// with different values share a cap: only the text before the first ": " is
+// kept, quoted values are dropped and words containing digits become "#".
+// "invalid total: invalid amount \"12,5x\"" and "sale 3fa2-17 was already
+// imported" group as "invalid total" and "sale # was already imported".
+func anomalyKey(message string) string {
+	if i := strings.Index(message, ": "); i > 0 {
+		message = message[:i]
+	}
+	words := strings.Fields(message)
+	for i, w := range words {
+		switch {
+		case len(w) >= 2 && (w[0] == '"' || w[0] == '\'') && w[len(w)-1] == w[0]:
+			words[i] = "#"
+		case strings.ContainsAny(w, "0123456789"):
+			words[i] = "#"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// maxRawDataLen caps the row snapshot stored with each anomaly so a very wide
+// or corrupt row can't bloat the anomalies table
+const maxRawDataLen = 2048
+
+// rowSnapshot renders a row's raw columns as a JSON object, with keys in
+// sorted order, truncated to maxRawDataLen bytes
+func rowSnapshot(raw map[string]string) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return ""
+	}
+	if len(data) <= maxRawDataLen {
+		return string(data)
+	}
+
+	// Cut on a rune boundary and mark the snapshot as incomplete
+	cut := maxRawDataLen - len("...")
+	for cut > 0 && !utf8.RuneStart(data[cut]) {
+		cut--
+	}
+	return string(data[:cut]) + "..."
+}
+
+// Flush writes one summary anomaly per kind of problem whose individual
+// records were capped, quoting the first suppressed message as an example
+func (r *anomalyRecorder) Flush(ctx context.Context) {
+	for _, key := range r.order {
+		n := r.suppressed[key]
+		noun := "rows"
+		if n == 1 {
+			noun = "row"
+		}
+		r.store.CreateAnomaly(ctx, &ImportAnomaly{
+			ID:          uuid.New(),
+			ImportJobID: r.jobID,
+			LineNumber:  0,
+			Severity:    r.severity[key],
+			Message:     fmt.Sprintf("%s more %s with a similar %s, e.g. %s", formatCount(n), noun, r.severity[key], r.example[key]),
+			CreatedAt:   time.Now(),
+		})
+	}
+	r.order = nil
+	r.suppressed = make(map[string]int)
+}
+
+// formatCount renders an integer with thousands separators (1234 -> "1,234")
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	if len(s) <= 3 {
+		return s
+	}
+	var out []byte
+	pre := len(s) % 3
+	if pre > 0 {
+		out = append(out, s[:pre]...)
+	}
+	for i := pre; i < len(s); i += 3 {
+		if len(out) > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, s[i:i+3]...)
+	}
+	return string(out)
+}
@@ -0,0 +1,151 @@
+package imports
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// fakeAnomalies collects the anomalies a recorder writes
+type fakeAnomalies struct {
+	created []ImportAnomaly
+}
+
+func (f *fakeAnomalies) CreateAnomaly(ctx context.Context, anomaly *ImportAnomaly) error {
+	f.created = append(f.created, *anomaly)
+	return nil
+}
+
+func TestAnomalyKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{a: `invalid total: invalid amount "12,5x" for number format en`, b: `invalid total: invalid amount "abc" for number format en`, same: true},
+		{a: "sale 3fa2c1d0-17 was already imported; kept the existing sale", b: "sale 3fa2c1d0-18 was already imported; kept the existing sale", same: true},
+		{a: "refund external_id ORD-1001 matches no sale", b: "refund external_id ORD-20417 matches no sale", same: true},
+		{a: "invalid numeric value for total: abc", b: "invalid numeric value for total: 1.2.3", same: true},
+		{a: "invalid numeric value for total: abc", b: "invalid numeric value for tax: abc"},
+		{a: "invalid total: invalid amount", b: "invalid date: unable to parse date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" / "+tt.b, func(t *testing.T) {
+			ka, kb := anomalyKey(tt.a), anomalyKey(tt.b)
+			if (ka == kb) != tt.same {
+				t.Errorf("anomalyKey gave %q and %q, want same = %v", ka, kb, tt.same)
+			}
+		})
+	}
+}
+
+func TestAnomalyRecorderCap(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeAnomalies{}
+	r := newAnomalyRecorder(store, uuid.New(), 3)
+
+	// Every message quotes a different value, as row errors do
+	for i := 0; i < 1250; i++ {
+		r.Record(ctx, i+2, "error", fmt.Sprintf(`invalid total: invalid amount "%dx" for number format en`, i), `{"total":"x"}`)
+	}
+	for i := 0; i < 4; i++ {
+		r.Record(ctx, i+2, "warning", fmt.Sprintf("refund external_id ORD-%d matches no sale", i), "")
+	}
+	r.Record(ctx, 9, "error", "supplier and item_name are required", "")
+	r.Flush(ctx)
+
+	var individual []ImportAnomaly
+	var summaries []ImportAnomaly
+	for _, a := range store.created {
+		if a.LineNumber == 0 {
+			summaries = append(summaries, a)
+		} else {
+			individual = append(individual, a)
+		}
+	}
+	if len(individual) != 3+3+1 {
+		t.Errorf("stored %d individual anomalies, want 7", len(individual))
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("stored %d summaries, want 2: %+v", len(summaries), summaries)
+	}
+
+	errSummary, warnSummary := summaries[0], summaries[1]
+	if errSummary.Severity != "error" || !strings.HasPrefix(errSummary.Message, "1,247 more rows with a similar error, e.g. invalid total:") {
+		t.Errorf("error summary = %s %q", errSummary.Severity, errSummary.Message)
+	}
+	if warnSummary.Severity != "warning" || warnSummary.Message != "1 more row with a similar warning, e.g. refund external_id ORD-3 matches no sale" {
+		t.Errorf("warning summary = %s %q", warnSummary.Severity, warnSummary.Message)
+	}
+
+	// Flushing again writes nothing new
+	n := len(store.created)
+	r.Flush(ctx)
+	if len(store.created) != n {
+		t.Errorf("second Flush wrote %d more anomalies", len(store.created)-n)
+	}
+}
+
+func TestAnomalyRecorderUncapped(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeAnomalies{}
+	r := newAnomalyRecorder(store, uuid.New(), 0)
+	for i := 0; i < 250; i++ {
+		r.Record(ctx, i+2, "error", "invalid date: unable to parse date", "")
+	}
+	r.Flush(ctx)
+	if len(store.created) != 250 {
+		t.Errorf("stored %d anomalies with no cap, want 250", len(store.created))
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	tests := map[int]string{0: "0", 7: "7", 999: "999", 1000: "1,000", 1247: "1,247", 1234567: "1,234,567"}
+	for n, want := range tests {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestRowSnapshot(t *testing.T) {
+	if got := rowSnapshot(map[string]string{"Total": "12,5x", "Date": "31/02/2024", "Channel": `Dine "In"`}); got != `{"Channel":"Dine \"In\"","Date":"31/02/2024","Total":"12,5x"}` {
+		t.Errorf("rowSnapshot() = %s, want the columns as JSON in key order", got)
+	}
+	if got := rowSnapshot(nil); got != "" {
+		t.Errorf("rowSnapshot(nil) = %q, want empty", got)
+	}
+
+	// A wide row is cut short without splitting a multi-byte character
+	long := rowSnapshot(map[string]string{"Notes": strings.Repeat("é", maxRawDataLen)})
+	if len(long) > maxRawDataLen || !strings.HasSuffix(long, "...") {
+		t.Errorf("rowSnapshot() of a wide row is %d bytes ending %q, want at most %d ending ...", len(long), long[len(long)-5:], maxRawDataLen)
+	}
+	if !utf8.ValidString(long) {
+		t.Error("rowSnapshot() split a character")
+	}
+}
+
+func TestAnomalyRecorderKeepsRawData(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeAnomalies{}
+	r := newAnomalyRecorder(store, uuid.New(), 0)
+
+	raw := rowSnapshot(map[string]string{"Date": "not a date", "Total": "10"})
+	r.Record(ctx, 4, "error", "invalid date format: not a date", raw)
+	r.Record(ctx, 1, "warning", `duplicate header "Total" at column 3 renamed to "Total_2"`, "")
+
+	if len(store.created) != 2 {
+		t.Fatalf("stored %d anomalies, want 2", len(store.created))
+	}
+	if got := store.created[0].RawData; got != `{"Date":"not a date","Total":"10"}` {
+		t.Errorf("row anomaly raw data = %s, want the row snapshot", got)
+	}
+	if got := store.created[1].RawData; got != "" {
+		t.Errorf("file anomaly raw data = %q, want none", got)
+	}
+}
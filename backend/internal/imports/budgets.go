@@ -0,0 +1,81 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// budgetTargets are the budget import's target columns, each optional
+var budgetTargets = []string{"revenue_target", "cogs_target", "labor_target", "opex_target"}
+
+// parseMonth parses a budget month such as 2024-03, 03/2024 or Mar 2024,
+// returning the first day of the month. Full dates are accepted and
+// truncated to their month.
+func parseMonth(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, format := range []string{"2006-01", "01/2006", "1/2006", "Jan 2006", "January 2006", "Jan-2006", "Jan-06"} {
+		if t, err := time.Parse(format, s); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := parseDate(s); err == nil {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, errors.New("unable to parse month")
+}
+
+// processBudgetRow sets a month's budget targets, replacing any earlier
+// budget for that month. Targets left blank are stored as no target.
+func (p *Pipeline) processBudgetRow(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow) error {
+	monthStr, _ := row.Mapped["month"].(string)
+	month, err := parseMonth(monthStr)
+	if err != nil {
+		return fmt.Errorf("invalid month: %w", err)
+	}
+
+	targets := make([]*float64, len(budgetTargets))
+	for i, field := range budgetTargets {
+		v := optionalString(row.Mapped, field)
+		if v == nil {
+			continue
+		}
+		amount, err := parseAmount(*v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", field, err)
+		}
+		targets[i] = &amount
+	}
+
+	query := `
+		INSERT INTO budgets (id, location_id, month, revenue_target, cogs_target, labor_target, opex_target, import_source, source_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		ON CONFLICT (location_id, month) DO UPDATE SET
+			revenue_target = EXCLUDED.revenue_target,
+			cogs_target = EXCLUDED.cogs_target,
+			labor_target = EXCLUDED.labor_target,
+			opex_target = EXCLUDED.opex_target,
+			import_source = EXCLUDED.import_source,
+			source_id = EXCLUDED.source_id,
+			updated_at = NOW()
+	`
+
+	sourceID := fmt.Sprintf("%s-%d", job.FileHash[:8], row.LineNumber)
+
+	_, err = db.Exec(ctx, query,
+		uuid.New(),
+		job.LocationID,
+		month,
+		targets[0],
+		targets[1],
+		targets[2],
+		targets[3],
+		"csv-import",
+		sourceID,
+	)
+	return err
+}
@@ -0,0 +1,91 @@
+package imports
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseMonth(t *testing.T) {
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, s := range []string{"2024-03", "03/2024", "3/2024", "Mar 2024", "March 2024", "Mar-2024", "Mar-24", " 2024-03 ", "2024-03-17"} {
+		got, err := parseMonth(s)
+		if err != nil {
+			t.Errorf("parseMonth(%q) error = %v", s, err)
+			continue
+		}
+		if !got.Equal(march) {
+			t.Errorf("parseMonth(%q) = %v, want %v", s, got, march)
+		}
+	}
+	for _, s := range []string{"", "2024", "13/2024", "Smarch 2024"} {
+		if _, err := parseMonth(s); err == nil {
+			t.Errorf("parseMonth(%q) succeeded, want error", s)
+		}
+	}
+}
+
+// TestBudgetImport parses a budget CSV with the default column names and
+// applies its valid rows, checking each month's targets are written to the
+// budgets table
+func TestBudgetImport(t *testing.T) {
+	const csv = "Month,Revenue Target,COGS Target,Labor Target,OpEx Target\n" +
+		"2024-03,\"50,000.00\",15000,12000,\n" +
+		"Apr 2024,52000,,,8000\n" +
+		"2024-13,50000,15000,12000,8000\n" +
+		"2024-05,lots,15000,12000,8000\n" +
+		"2024-06,,,,\n"
+
+	mapping := &MappingProfile{ColumnMaps: DefaultMappings()["budget"]}
+	result, err := NewParser("budget", mapping).Parse(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if result.ValidRows != 2 || result.ErrorRows != 3 {
+		t.Fatalf("got %d valid and %d error rows, want 2 and 3", result.ValidRows, result.ErrorRows)
+	}
+	wantErrors := []string{"invalid month format: 2024-13", "invalid numeric value for revenue_target: lots", "missing budget targets"}
+	for i, row := range result.Rows[2:] {
+		if len(row.Errors) != 1 || !strings.Contains(row.Errors[0], wantErrors[i]) {
+			t.Errorf("line %d errors = %q, want %q", row.LineNumber, row.Errors, wantErrors[i])
+		}
+	}
+
+	p := &Pipeline{}
+	db := &fakeExecutor{rowsAffected: 1}
+	job := &ImportJob{LocationID: uuid.New(), FileHash: "abcdef0123456789"}
+	for _, row := range result.Rows[:2] {
+		if err := p.processBudgetRow(context.Background(), db, job, row); err != nil {
+			t.Fatalf("processBudgetRow(line %d) error = %v", row.LineNumber, err)
+		}
+	}
+
+	tests := []struct {
+		month   time.Time
+		targets []interface{} // revenue, cogs, labor, opex; nil for no target
+	}{
+		{month: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), targets: []interface{}{50000.0, 15000.0, 12000.0, nil}},
+		{month: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), targets: []interface{}{52000.0, nil, nil, 8000.0}},
+	}
+	for i, tt := range tests {
+		args := db.args[i]
+		if !strings.Contains(db.statements[i], "ON CONFLICT (location_id, month) DO UPDATE") {
+			t.Errorf("budget upsert does not replace the month's earlier budget:\n%s", db.statements[i])
+		}
+		if args[1] != job.LocationID || !args[2].(time.Time).Equal(tt.month) {
+			t.Errorf("row %d location, month = %v, %v, want %v, %v", i, args[1], args[2], job.LocationID, tt.month)
+		}
+		for j, want := range tt.targets {
+			got := args[3+j].(*float64)
+			switch {
+			case want == nil && got != nil:
+				t.Errorf("%s %s = %v, want no target", tt.month.Format("2006-01"), budgetTargets[j], *got)
+			case want != nil && (got == nil || *got != want.(float64)):
+				t.Errorf("%s %s = %v, want %v", tt.month.Format("2006-01"), budgetTargets[j], got, want)
+			}
+		}
+	}
+}
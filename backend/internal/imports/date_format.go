@@ -0,0 +1,154 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date orders a mapping profile may declare for slash-separated dates
+const (
+	DateFormatDMY = "dmy" // 03/04/2024 is 3 April (default)
+	DateFormatMDY = "mdy" // 03/04/2024 is 4 March
+)
+
+// dateFields are the mapped fields parsed as dates
+var dateFields = map[string]bool{
+	"date":          true,
+	"period_start":  true,
+	"period_end":    true,
+	"snapshot_date": true,
+}
+
+// epochLocation is the timezone epoch timestamps are converted to before
+// their date is taken, matching the business day used by the KPI handlers
+var epochLocation = func() *time.Location {
+	loc, err := time.LoadLocation("Australia/Brisbane")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}()
+
+var (
+	isoWeekDate = regexp.MustCompile(`^(\d{4})-?W(\d{2})(?:-?([1-7]))?$`)
+	epochMillis = regexp.MustCompile(`^\d{13}$`)
+	slashDate   = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})/\d{4}$`)
+)
+
+// NormalizeDateFormat returns the canonical date order, or an error if
+// unsupported. An empty name is returned unchanged and means dmy.
+func NormalizeDateFormat(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	switch name = strings.ToLower(strings.TrimSpace(name)); name {
+	case DateFormatDMY, DateFormatMDY:
+		return name, nil
+	}
+	return "", fmt.Errorf("unsupported date_format %q; must be one of: dmy, mdy", name)
+}
+
+// parseDateOrder parses a date, reading slash-separated dates in the given
+// order. ISO week dates (2024-W14-3) and epoch milliseconds are also accepted.
+func parseDateOrder(s, order string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	slashFormats := []string{"2/1/2006", "1/2/2006"}
+	if order == DateFormatMDY {
+		slashFormats = []string{"1/2/2006", "2/1/2006"}
+	}
+	formats := append([]string{"2006-01-02"}, slashFormats...)
+	formats = append(formats, "2006-01-02T15:04:05", "2006-01-02 15:04:05")
+	for _, format := range formats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t, nil
+		}
+	}
+
+	if m := isoWeekDate.FindStringSubmatch(s); m != nil {
+		return parseISOWeek(m[1], m[2], m[3])
+	}
+	if epochMillis.MatchString(s) {
+		ms, err := strconv.ParseInt(s, 10, 64)
+		if err == nil {
+			// Keep the local wall time, as with timestamps written in the file
+			local := time.UnixMilli(ms).In(epochLocation)
+			return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, errors.New("unable to parse date")
+}
+
+// parseISOWeek returns the day of an ISO 8601 week date; a missing weekday
+// means Monday
+func parseISOWeek(yearStr, weekStr, dayStr string) (time.Time, error) {
+	year, _ := strconv.Atoi(yearStr)
+	week, _ := strconv.Atoi(weekStr)
+	day := 1
+	if dayStr != "" {
+		day, _ = strconv.Atoi(dayStr)
+	}
+
+	// Week 1 is the week containing 4 January
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	week1 := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	t := week1.AddDate(0, 0, (week-1)*7+day-1)
+	if y, w := t.ISOWeek(); week < 1 || y != year || w != week {
+		return time.Time{}, fmt.Errorf("year %d has no ISO week %d", year, week)
+	}
+	return t, nil
+}
+
+// ambiguousDate reports whether a slash-separated date reads as a different
+// valid day in day/month and month/day order
+func ambiguousDate(s string) bool {
+	m := slashDate.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return false
+	}
+	a, _ := strconv.Atoi(m[1])
+	b, _ := strconv.Atoi(m[2])
+	return a != b && a >= 1 && a <= 12 && b >= 1 && b <= 12
+}
+
+// normalizeDates rewrites a row's date fields into ISO form when the mapping
+// declares a date order, so every later stage reads them the same way. With
+// no declared order, dates that read two ways are returned as warnings.
+func normalizeDates(mapped map[string]interface{}, order string) []string {
+	fields := make([]string, 0, len(mapped))
+	for field := range mapped {
+		if dateFields[field] {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	var warnings []string
+	for _, field := range fields {
+		val, ok := mapped[field].(string)
+		if !ok || val == "" {
+			continue
+		}
+		if order == "" {
+			// The message leaves out the value so the anomaly cap groups them
+			if ambiguousDate(val) {
+				warnings = append(warnings, fmt.Sprintf("%s could be day/month or month/day; read as day/month, set the mapping's date_format to choose", field))
+			}
+			continue
+		}
+		// Invalid dates are left as written for validation to report
+		if t, err := parseDateOrder(val, order); err == nil {
+			if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+				mapped[field] = t.Format("2006-01-02")
+			} else {
+				mapped[field] = t.Format("2006-01-02T15:04:05")
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,150 @@
+package imports
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDateOrder(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		in      string
+		order   string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "2024-04-03", want: day(2024, time.April, 3)},
+		{in: "03/04/2024", order: DateFormatDMY, want: day(2024, time.April, 3)},
+		{in: "03/04/2024", order: DateFormatMDY, want: day(2024, time.March, 4)},
+		{in: "3/4/2024", order: DateFormatMDY, want: day(2024, time.March, 4)},
+		// A date only valid one way is read that way whatever the order
+		{in: "25/12/2024", order: DateFormatMDY, want: day(2024, time.December, 25)},
+		{in: "12/25/2024", order: DateFormatDMY, want: day(2024, time.December, 25)},
+		{in: "2024-04-03 18:30:00", want: time.Date(2024, time.April, 3, 18, 30, 0, 0, time.UTC)},
+
+		// ISO week dates, Monday when the weekday is left out
+		{in: "2024-W14-3", want: day(2024, time.April, 3)},
+		{in: "2024W143", want: day(2024, time.April, 3)},
+		{in: "2024-W01", want: day(2024, time.January, 1)},
+		{in: "2021-W01-1", want: day(2021, time.January, 4)},
+		{in: "2020-W53-5", want: day(2021, time.January, 1)},
+		{in: "2021-W53", wantErr: true},
+		{in: "2024-W00", wantErr: true},
+
+		// Epoch milliseconds keep the Brisbane wall time
+		{in: "1712118600000", want: time.Date(2024, time.April, 3, 14, 30, 0, 0, time.UTC)},
+		{in: "1712160000000", want: time.Date(2024, time.April, 4, 2, 0, 0, 0, time.UTC)},
+
+		{in: "31/31/2024", wantErr: true},
+		{in: "171211860000", wantErr: true},
+		{in: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in+" "+tt.order, func(t *testing.T) {
+			got, err := parseDateOrder(tt.in, tt.order)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseDateOrder() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("parseDateOrder() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmbiguousDate(t *testing.T) {
+	for in, want := range map[string]bool{
+		"03/04/2024": true,
+		"3/4/2024":   true,
+		"04/04/2024": false,
+		"13/04/2024": false,
+		"04/13/2024": false,
+		"2024-04-03": false,
+	} {
+		if got := ambiguousDate(in); got != want {
+			t.Errorf("ambiguousDate(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeDates(t *testing.T) {
+	tests := []struct {
+		name         string
+		order        string
+		mapped       map[string]interface{}
+		want         map[string]interface{}
+		wantWarnings []string
+	}{
+		{
+			name:         "no order warns on ambiguous dates",
+			mapped:       map[string]interface{}{"date": "03/04/2024", "period_end": "13/04/2024", "total": "03/04/2024"},
+			want:         map[string]interface{}{"date": "03/04/2024", "period_end": "13/04/2024", "total": "03/04/2024"},
+			wantWarnings: []string{"date could be day/month or month/day; read as day/month, set the mapping's date_format to choose"},
+		},
+		{
+			name:   "mdy rewrites to ISO",
+			order:  DateFormatMDY,
+			mapped: map[string]interface{}{"date": "03/04/2024", "period_start": "2024-W14", "snapshot_date": "1712118600000"},
+			want:   map[string]interface{}{"date": "2024-03-04", "period_start": "2024-04-01", "snapshot_date": "2024-04-03T14:30:00"},
+		},
+		{
+			name:   "invalid dates are left for validation",
+			order:  DateFormatDMY,
+			mapped: map[string]interface{}{"date": "soon"},
+			want:   map[string]interface{}{"date": "soon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := normalizeDates(tt.mapped, tt.order)
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+			if !reflect.DeepEqual(tt.mapped, tt.want) {
+				t.Errorf("mapped = %v, want %v", tt.mapped, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWithDateFormat(t *testing.T) {
+	csv := "Date,Total\n03/04/2024,10.00\n"
+	columns := map[string]string{"Date": "date", "Total": "total"}
+
+	result, err := NewParser("pos", &MappingProfile{ColumnMaps: columns, DateFormat: DateFormatMDY}).Parse(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	row := result.Rows[0]
+	if row.Mapped["date"] != "2024-03-04" || len(row.Warnings) != 0 {
+		t.Errorf("mdy date = %v with warnings %q, want 2024-03-04 and none", row.Mapped["date"], row.Warnings)
+	}
+
+	result, err = NewParser("pos", &MappingProfile{ColumnMaps: columns}).Parse(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	row = result.Rows[0]
+	if len(row.Errors) != 0 || len(row.Warnings) != 1 || !strings.Contains(row.Warnings[0], "could be day/month or month/day") {
+		t.Errorf("undeclared order errors = %q, warnings = %q, want one ambiguity warning", row.Errors, row.Warnings)
+	}
+}
+
+func TestNormalizeDateFormat(t *testing.T) {
+	for in, want := range map[string]string{"": "", "dmy": "dmy", " MDY ": "mdy"} {
+		if got, err := NormalizeDateFormat(in); err != nil || got != want {
+			t.Errorf("NormalizeDateFormat(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := NormalizeDateFormat("ymd"); err == nil {
+		t.Error("NormalizeDateFormat(ymd) succeeded, want an error")
+	}
+}
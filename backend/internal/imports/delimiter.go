@@ -0,0 +1,39 @@
+package imports
+
+import (
+	"fmt"
+	"strings"
+)
+
+// delimiterAliases maps accepted delimiter spellings to the canonical character
+var delimiterAliases = map[string]string{
+	",":         ",",
+	"comma":     ",",
+	";":         ";",
+	"semicolon": ";",
+	"\t":        "\t",
+	"\\t":       "\t",
+	"tab":       "\t",
+	"|":         "|",
+	"pipe":      "|",
+}
+
+// NormalizeDelimiter returns the canonical delimiter character, or an error if unsupported.
+// An empty name is returned unchanged and means comma.
+func NormalizeDelimiter(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	if d, ok := delimiterAliases[strings.ToLower(name)]; ok {
+		return d, nil
+	}
+	return "", fmt.Errorf("unsupported delimiter %q; must be one of: comma, semicolon, tab, pipe", name)
+}
+
+// delimiterRune returns the field separator for a canonical delimiter, defaulting to comma
+func delimiterRune(delimiter string) rune {
+	if delimiter == "" {
+		return ','
+	}
+	return []rune(delimiter)[0]
+}
@@ -0,0 +1,51 @@
+package imports
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// processDepositRow records a bank deposit. Deposits with a bank reference
+// are keyed on it, so re-importing an overlapping statement does not count a
+// deposit twice; others are keyed on the file and line like expenses.
+func (p *Pipeline) processDepositRow(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow) error {
+	dateStr, _ := row.Mapped["date"].(string)
+	date, err := parseDate(dateStr)
+	if err != nil {
+		return fmt.Errorf("invalid date: %w", err)
+	}
+
+	amountStr, _ := row.Mapped["amount"].(string)
+	amount, err := parseAmount(amountStr)
+	if err != nil {
+		return fmt.Errorf("invalid amount: %w", err)
+	}
+
+	reference := optionalString(row.Mapped, "reference")
+	sourceID := fmt.Sprintf("%s-%d", job.FileHash[:8], row.LineNumber)
+	if reference != nil {
+		sourceID = "ref-" + *reference
+	}
+
+	query := `
+		INSERT INTO deposits (id, location_id, deposit_date, amount, reference, import_source, source_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (location_id, import_source, source_id) DO UPDATE SET
+			deposit_date = EXCLUDED.deposit_date,
+			amount = EXCLUDED.amount,
+			updated_at = NOW()
+	`
+
+	_, err = db.Exec(ctx, query,
+		uuid.New(),
+		job.LocationID,
+		date,
+		amount,
+		reference,
+		"csv-import",
+		sourceID,
+	)
+	return err
+}
@@ -0,0 +1,241 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Dialect describes the detected layout of a delimited file
+type Dialect struct {
+	Delimiter  string  `json:"delimiter"`
+	QuoteChar  string  `json:"quote_char"`
+	Encoding   string  `json:"encoding"` // utf-8, utf-16le, utf-16be, windows-1252
+	HasBOM     bool    `json:"has_bom"`
+	HasHeader  bool    `json:"has_header"`
+	Confidence float64 `json:"confidence"` // 0..1, how consistent the sniffed structure was
+	// Warnings describe layouts imports read differently than the file intends
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// singleQuoteWarning is reported for files that quote fields with ' rather
+// than ", which the CSV reader does not treat as a quote
+const singleQuoteWarning = "fields are quoted with single quotes, which imports do not recognize: the quotes are kept as part of each value and a delimiter inside them splits the field"
+
+// dialectSampleLines caps how many lines are inspected when sniffing
+const dialectSampleLines = 20
+
+// candidateDelimiters are tried in order of preference when counts tie
+var candidateDelimiters = []rune{',', ';', '\t', '|'}
+
+// DetectDialect inspects the start of a file and guesses its delimiter,
+// quote character, encoding and whether the first row is a header
+func DetectDialect(sample []byte) Dialect {
+	d := Dialect{Delimiter: ",", QuoteChar: `"`, Encoding: "utf-8"}
+
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		d.HasBOM = true
+		sample = sample[3:]
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		d.HasBOM = true
+		d.Encoding = "utf-16le"
+		sample = decodeUTF16Sample(sample[2:], false)
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		d.HasBOM = true
+		d.Encoding = "utf-16be"
+		sample = decodeUTF16Sample(sample[2:], true)
+	}
+
+	if d.Encoding == "utf-8" && !utf8.Valid(trimPartialRune(sample)) {
+		d.Encoding = "windows-1252"
+	}
+
+	lines := sampleLines(sample, dialectSampleLines)
+	if len(lines) == 0 {
+		return d
+	}
+
+	// Pick the delimiter whose per-line count is most consistent and non-zero
+	bestScore := -1.0
+	for _, delim := range candidateDelimiters {
+		counts := make([]int, len(lines))
+		for i, line := range lines {
+			counts[i] = countOutsideQuotes(line, delim)
+		}
+		mode, matches := modeCount(counts)
+		if mode == 0 {
+			continue
+		}
+		score := float64(matches) / float64(len(lines))
+		if score > bestScore {
+			bestScore = score
+			d.Delimiter = string(delim)
+		}
+	}
+	if bestScore < 0 {
+		// Single column file - nothing to split on
+		bestScore = 0.5
+	}
+
+	delim := []rune(d.Delimiter)[0]
+	if !strings.Contains(strings.Join(lines, "\n"), `"`) && quotedFieldsWith(lines, '\'', delim) {
+		d.QuoteChar = "'"
+		d.Warnings = append(d.Warnings, singleQuoteWarning)
+	}
+
+	d.HasHeader = looksLikeHeader(lines, delim)
+	d.Confidence = roundConfidence(bestScore)
+	return d
+}
+
+// ReadHeader decodes sample from the dialect's encoding through the same
+// decoder imports use and returns its first row, so UTF-16 and Windows-1252
+// column names read the same in a preview as they do when imported
+func (d Dialect) ReadHeader(sample []byte) ([]string, error) {
+	reader, err := decodeReader(bytes.NewReader(sample), d.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = delimiterRune(d.Delimiter)
+	csvReader.LazyQuotes = true
+	csvReader.FieldsPerRecord = -1
+	headers, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], "\uFEFF")
+	}
+	for i := range headers {
+		headers[i] = strings.TrimSpace(headers[i])
+	}
+	return headers, nil
+}
+
+func sampleLines(sample []byte, max int) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(sample))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() && len(lines) < max {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	// Drop a trailing line that was cut off by the sample boundary
+	if len(lines) > 2 && len(sample) > 0 && sample[len(sample)-1] != '\n' {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func countOutsideQuotes(line string, delim rune) int {
+	count := 0
+	inQuotes := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == delim && !inQuotes:
+			count++
+		}
+	}
+	return count
+}
+
+func modeCount(counts []int) (mode, matches int) {
+	freq := make(map[int]int)
+	for _, c := range counts {
+		freq[c]++
+	}
+	for c, n := range freq {
+		if n > matches || (n == matches && c > mode) {
+			mode, matches = c, n
+		}
+	}
+	return mode, matches
+}
+
+func quotedFieldsWith(lines []string, quote, delim rune) bool {
+	for _, line := range lines {
+		for _, field := range strings.Split(line, string(delim)) {
+			field = strings.TrimSpace(field)
+			if len(field) >= 2 && rune(field[0]) == quote && rune(field[len(field)-1]) == quote {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// looksLikeHeader treats the first row as a header when none of its cells are
+// numeric while at least one cell in the following rows is
+func looksLikeHeader(lines []string, delim rune) bool {
+	if len(lines) == 0 {
+		return false
+	}
+	for _, cell := range strings.Split(lines[0], string(delim)) {
+		if isNumericCell(cell) {
+			return false
+		}
+	}
+	if len(lines) == 1 {
+		return true
+	}
+	for _, line := range lines[1:] {
+		for _, cell := range strings.Split(line, string(delim)) {
+			if isNumericCell(cell) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isNumericCell(cell string) bool {
+	cell = strings.Trim(strings.TrimSpace(cell), `"'`)
+	if cell == "" {
+		return false
+	}
+	if _, err := parseAmount(cell); err == nil {
+		return true
+	}
+	_, err := parseDate(cell)
+	return err == nil
+}
+
+func decodeUTF16Sample(b []byte, bigEndian bool) []byte {
+	var out strings.Builder
+	for i := 0; i+1 < len(b); i += 2 {
+		var r rune
+		if bigEndian {
+			r = rune(b[i])<<8 | rune(b[i+1])
+		} else {
+			r = rune(b[i+1])<<8 | rune(b[i])
+		}
+		out.WriteRune(r)
+	}
+	return []byte(out.String())
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence cut off at the end of a sample
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+func roundConfidence(f float64) float64 {
+	return float64(int(f*100+0.5)) / 100
+}
@@ -0,0 +1,180 @@
+package imports
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16Bytes(s string, bigEndian bool) []byte {
+	var out []byte
+	if bigEndian {
+		out = []byte{0xFE, 0xFF}
+	} else {
+		out = []byte{0xFF, 0xFE}
+	}
+	for _, u := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return out
+}
+
+func TestDetectDialect(t *testing.T) {
+	tests := []struct {
+		name      string
+		sample    []byte
+		delimiter string
+		quote     string
+		warning   bool
+		encoding  string
+		hasBOM    bool
+		hasHeader bool
+	}{
+		{
+			name:      "comma",
+			sample:    []byte("Date,Total,Channel\n2024-01-01,100.00,Dine In\n2024-01-02,80.50,Takeaway\n"),
+			delimiter: ",", quote: `"`, encoding: "utf-8", hasHeader: true,
+		},
+		{
+			name:      "semicolon with decimal commas",
+			sample:    []byte("Datum;Umsatz;Kanal\n01.01.2024;100,00;Restaurant\n02.01.2024;80,50;Abholung\n"),
+			delimiter: ";", quote: `"`, encoding: "utf-8", hasHeader: true,
+		},
+		{
+			name:      "tab",
+			sample:    []byte("Date\tTotal\n2024-01-01\t100.00\n2024-01-02\t80.50\n"),
+			delimiter: "\t", quote: `"`, encoding: "utf-8", hasHeader: true,
+		},
+		{
+			name:      "pipe",
+			sample:    []byte("Date|Total\n2024-01-01|100.00\n2024-01-02|80.50\n"),
+			delimiter: "|", quote: `"`, encoding: "utf-8", hasHeader: true,
+		},
+		{
+			name:      "quoted delimiters are ignored",
+			sample:    []byte("Date;Item\n2024-01-01;\"Fish, chips\"\n2024-01-02;\"Salt, pepper\"\n"),
+			delimiter: ";", quote: `"`, encoding: "utf-8", hasHeader: true,
+		},
+		{
+			name:      "single quotes",
+			sample:    []byte("Date,Item,Total\n2024-01-01,'Fish and chips',12.50\n2024-01-02,'Soup',8.00\n"),
+			delimiter: ",", quote: "'", encoding: "utf-8", hasHeader: true, warning: true,
+		},
+		{
+			name:      "windows-1252",
+			sample:    []byte("Date;Caf\xe9;Total\n2024-01-01;Cr\xe8me;100,00\n"),
+			delimiter: ";", quote: `"`, encoding: "windows-1252", hasHeader: true,
+		},
+		{
+			name:      "utf-8 bom",
+			sample:    append([]byte{0xEF, 0xBB, 0xBF}, "Date,Total\n2024-01-01,100.00\n"...),
+			delimiter: ",", quote: `"`, encoding: "utf-8", hasBOM: true, hasHeader: true,
+		},
+		{
+			name:      "utf-16le bom",
+			sample:    utf16Bytes("Date\tTotal\r\n2024-01-01\t100.00\r\n", false),
+			delimiter: "\t", quote: `"`, encoding: "utf-16le", hasBOM: true, hasHeader: true,
+		},
+		{
+			name:      "utf-16be bom",
+			sample:    utf16Bytes("Date;Total\n2024-01-01;100.00\n", true),
+			delimiter: ";", quote: `"`, encoding: "utf-16be", hasBOM: true, hasHeader: true,
+		},
+		{
+			name:      "no header",
+			sample:    []byte("2024-01-01,100.00\n2024-01-02,80.50\n"),
+			delimiter: ",", quote: `"`, encoding: "utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DetectDialect(tt.sample)
+			if d.Delimiter != tt.delimiter {
+				t.Errorf("Delimiter = %q, want %q", d.Delimiter, tt.delimiter)
+			}
+			if d.QuoteChar != tt.quote {
+				t.Errorf("QuoteChar = %q, want %q", d.QuoteChar, tt.quote)
+			}
+			if d.Encoding != tt.encoding {
+				t.Errorf("Encoding = %q, want %q", d.Encoding, tt.encoding)
+			}
+			if d.HasBOM != tt.hasBOM {
+				t.Errorf("HasBOM = %v, want %v", d.HasBOM, tt.hasBOM)
+			}
+			if d.HasHeader != tt.hasHeader {
+				t.Errorf("HasHeader = %v, want %v", d.HasHeader, tt.hasHeader)
+			}
+			if gotWarning := len(d.Warnings) > 0; gotWarning != tt.warning {
+				t.Errorf("Warnings = %q, want a warning %v", d.Warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestDialectReadHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample []byte
+		want   []string
+	}{
+		{
+			name:   "utf-8 bom",
+			sample: append([]byte{0xEF, 0xBB, 0xBF}, "Date, Café \n2024-01-01,1\n"...),
+			want:   []string{"Date", "Café"},
+		},
+		{
+			name:   "windows-1252 semicolon",
+			sample: []byte("Date;Caf\xe9\n2024-01-01;1\n"),
+			want:   []string{"Date", "Café"},
+		},
+		{
+			name:   "utf-16le tab",
+			sample: utf16Bytes("Date\tCafé\r\n2024-01-01\t1\r\n", false),
+			want:   []string{"Date", "Café"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers, err := DetectDialect(tt.sample).ReadHeader(tt.sample)
+			if err != nil {
+				t.Fatalf("ReadHeader: %v", err)
+			}
+			if !reflect.DeepEqual(headers, tt.want) {
+				t.Errorf("ReadHeader = %q, want %q", headers, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeEncoding(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "UTF-8", want: "utf-8"},
+		{in: "utf8", want: "utf-8"},
+		{in: "cp1252", want: "windows-1252"},
+		{in: "UTF_16LE", want: "utf-16le"},
+		{in: "ebcdic", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := NormalizeEncoding(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeEncoding(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeEncoding(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package imports
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// supportedEncodings maps canonical encoding names to their decoders.
+// UTF-8 and UTF-16 decoders strip a leading byte order mark, but don't need
+// one: Excel's "Unicode text" files are UTF-16LE with a BOM, while other
+// exporters write none.
+var supportedEncodings = map[string]encoding.Encoding{
+	"utf-8":        unicode.UTF8BOM,
+	"utf-16":       unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf-16le":     unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf-16be":     unicode.UTF16(unicode.BigEndian, unicode.UseBOM),
+	"windows-1252": charmap.Windows1252,
+	"iso-8859-1":   charmap.ISO8859_1,
+}
+
+var encodingAliases = map[string]string{
+	"utf8":    "utf-8",
+	"utf16":   "utf-16",
+	"utf16le": "utf-16le",
+	"utf16be": "utf-16be",
+	"cp1252":  "windows-1252",
+	"latin1":  "iso-8859-1",
+	"latin-1": "iso-8859-1",
+}
+
+// NormalizeEncoding returns the canonical name for an encoding, or an error if unsupported.
+// An empty name is returned unchanged and means "UTF-8, auto-detected".
+func NormalizeEncoding(name string) (string, error) {
+	name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "-")
+	if name == "" {
+		return "", nil
+	}
+	if alias, ok := encodingAliases[name]; ok {
+		name = alias
+	}
+	if _, ok := supportedEncodings[name]; !ok {
+		return "", fmt.Errorf("unsupported encoding %q; must be one of: %s", name, strings.Join(SupportedEncodings(), ", "))
+	}
+	return name, nil
+}
+
+// SupportedEncodings lists the canonical encoding names accepted by mappings
+func SupportedEncodings() []string {
+	names := make([]string, 0, len(supportedEncodings))
+	for name := range supportedEncodings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// decodeReader wraps r so its content is transcoded from the named encoding to UTF-8
+func decodeReader(r io.Reader, name string) (io.Reader, error) {
+	name, err := NormalizeEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return r, nil
+	}
+	return transform.NewReader(r, supportedEncodings[name].NewDecoder()), nil
+}
@@ -0,0 +1,69 @@
+package imports
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// POS transaction types that are gift card activity rather than ordinary sales
+const (
+	TransactionGiftCardSale       = "gift_card_sale"
+	TransactionGiftCardRedemption = "gift_card_redemption"
+)
+
+// giftCardAliases maps common POS spellings to gift card transaction types
+var giftCardAliases = map[string]string{
+	"gift card sale":       TransactionGiftCardSale,
+	"gift card purchase":   TransactionGiftCardSale,
+	"gift card issue":      TransactionGiftCardSale,
+	"gift card load":       TransactionGiftCardSale,
+	"gift card reload":     TransactionGiftCardSale,
+	"gift card redemption": TransactionGiftCardRedemption,
+	"gift card redeem":     TransactionGiftCardRedemption,
+	"gift card payment":    TransactionGiftCardRedemption,
+}
+
+// NormalizeTransactionType returns gift_card_sale or gift_card_redemption for
+// gift card activity, and "" for anything else (ordinary sales)
+func NormalizeTransactionType(raw string) string {
+	v := strings.ToLower(strings.TrimSpace(raw))
+	v = strings.NewReplacer("_", " ", "-", " ", "giftcard", "gift card").Replace(v)
+	return giftCardAliases[strings.Join(strings.Fields(v), " ")]
+}
+
+// recordGiftCard writes a gift card issuance or redemption to the ledger
+func (p *Pipeline) recordGiftCard(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow, txType string, date time.Time, amount float64) error {
+	entryType := "issue"
+	if txType == TransactionGiftCardRedemption {
+		entryType = "redeem"
+	}
+
+	query := `
+		INSERT INTO gift_card_ledger (id, location_id, occurred_at, entry_type, amount, card_number, import_source, source_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		ON CONFLICT (location_id, import_source, source_id) DO UPDATE SET
+			occurred_at = EXCLUDED.occurred_at,
+			entry_type = EXCLUDED.entry_type,
+			amount = EXCLUDED.amount,
+			card_number = EXCLUDED.card_number,
+			updated_at = NOW()
+	`
+
+	sourceID := fmt.Sprintf("%s-%d", job.FileHash[:8], row.LineNumber)
+	_, err := db.Exec(ctx, query,
+		uuid.New(),
+		job.LocationID,
+		date,
+		entryType,
+		math.Abs(amount),
+		optionalString(row.Mapped, "gift_card_number"),
+		"csv-import",
+		sourceID,
+	)
+	return err
+}
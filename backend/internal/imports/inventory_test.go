@@ -0,0 +1,93 @@
+package imports
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeInventory applies inventory upserts to in-memory tables keyed like
+// their unique constraints
+type fakeInventory struct {
+	snapshots map[string]float64 // date/item -> quantity
+	counts    map[string]float64 // source_id -> quantity
+}
+
+func (f *fakeInventory) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	switch {
+	case strings.Contains(sql, "INSERT INTO inventory_snapshots"):
+		f.snapshots[fmt.Sprintf("%v/%s", args[2], args[3])] = args[5].(float64)
+	case strings.Contains(sql, "INSERT INTO inventory_counts"):
+		f.counts[args[11].(string)] = args[5].(float64)
+	default:
+		panic("unexpected Exec: " + sql)
+	}
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (f *fakeInventory) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	panic("unexpected QueryRow: " + sql)
+}
+
+func TestInventorySameDayRecount(t *testing.T) {
+	// A morning count and an evening recount of the same item on one day
+	rows := []ParsedRow{
+		{LineNumber: 2, Mapped: map[string]interface{}{"snapshot_date": "2024-03-04", "item_name": "Beef mince", "quantity": "12", "unit_cost": "9.50", "unit": "kg"}},
+		{LineNumber: 3, Mapped: map[string]interface{}{"snapshot_date": "2024-03-04", "item_name": "Milk", "quantity": "20", "unit_cost": "1.80"}},
+		{LineNumber: 4, Mapped: map[string]interface{}{"snapshot_date": "2024-03-04", "item_name": "Beef mince", "quantity": "7", "unit_cost": "9.50", "unit": "kg"}},
+	}
+
+	tests := []struct {
+		mode       string
+		wantCounts map[string]float64
+	}{
+		{mode: InventoryModeReplace, wantCounts: map[string]float64{}},
+		{mode: "", wantCounts: map[string]float64{}},
+		{mode: InventoryModeAppend, wantCounts: map[string]float64{"abcdef01-2": 12, "abcdef01-3": 20, "abcdef01-4": 7}},
+	}
+
+	for _, tt := range tests {
+		name := tt.mode
+		if name == "" {
+			name = "default"
+		}
+		t.Run(name, func(t *testing.T) {
+			p := &Pipeline{cfg: PipelineConfig{InventoryMode: tt.mode}}
+			db := &fakeInventory{snapshots: map[string]float64{}, counts: map[string]float64{}}
+			job := &ImportJob{ID: uuid.New(), LocationID: uuid.New(), FileHash: "abcdef0123456789"}
+
+			// Importing the same file twice must not duplicate counts
+			for pass := 0; pass < 2; pass++ {
+				for _, row := range rows {
+					if err := p.processInventoryRow(context.Background(), db, job, row); err != nil {
+						t.Fatalf("processInventoryRow(line %d) error = %v", row.LineNumber, err)
+					}
+				}
+			}
+
+			// The snapshot that valuation reads always holds the latest count
+			if len(db.snapshots) != 2 {
+				t.Errorf("got %d snapshots, want one per item", len(db.snapshots))
+			}
+			for key, qty := range db.snapshots {
+				if strings.HasSuffix(key, "/Beef mince") && qty != 7 {
+					t.Errorf("Beef mince snapshot = %v, want the recount of 7", qty)
+				}
+			}
+
+			if len(db.counts) != len(tt.wantCounts) {
+				t.Fatalf("counts = %v, want %v", db.counts, tt.wantCounts)
+			}
+			for sourceID, want := range tt.wantCounts {
+				if got, ok := db.counts[sourceID]; !ok || got != want {
+					t.Errorf("count %s = %v, want %v", sourceID, got, want)
+				}
+			}
+		})
+	}
+}
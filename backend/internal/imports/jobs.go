@@ -0,0 +1,70 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrImportInterrupted is returned when an import is stopped because the server is shutting down
+var ErrImportInterrupted = errors.New("import interrupted by server shutdown")
+
+// jobManager tracks imports running in the background so shutdown can wait
+// for them, and stops the ones still running when it gives up
+type jobManager struct {
+	wg       sync.WaitGroup
+	inFlight atomic.Int64
+	ctx      context.Context
+	stop     context.CancelCauseFunc
+}
+
+func newJobManager() *jobManager {
+	ctx, stop := context.WithCancelCause(context.Background())
+	return &jobManager{ctx: ctx, stop: stop}
+}
+
+// start runs fn in a tracked goroutine, bounded by timeout when it is positive
+func (m *jobManager) start(timeout time.Duration, fn func(ctx context.Context)) {
+	m.wg.Add(1)
+	m.inFlight.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer m.inFlight.Add(-1)
+
+		ctx, cancel := m.ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(m.ctx, timeout)
+		}
+		defer cancel()
+		fn(ctx)
+	}()
+}
+
+// InFlight returns how many imports are running in the background
+func (p *Pipeline) InFlight() int {
+	return int(p.jobs.inFlight.Load())
+}
+
+// Drain waits for background imports to finish. If ctx ends first the
+// imports still running are stopped and marked interrupted so they can be
+// retried; Drain returns once they have recorded that, with how many there were.
+func (p *Pipeline) Drain(ctx context.Context) int {
+	done := make(chan struct{})
+	go func() {
+		p.jobs.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0
+	case <-ctx.Done():
+	}
+
+	interrupted := p.InFlight()
+	p.jobs.stop(ErrImportInterrupted)
+	<-done
+	return interrupted
+}
@@ -0,0 +1,125 @@
+package imports
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lookupCache remembers the channel and daypart IDs resolved during one
+// import so repeated values don't cost a query per row. Channels created by
+// a row are held as pending until the row's savepoint commits, since a
+// rolled-back row also rolls back the channel it inserted.
+type lookupCache struct {
+	mu       sync.Mutex
+	channels map[string]uuid.UUID // lower-cased display name -> id
+	pending  map[string]uuid.UUID
+	dayparts []daypartSpan
+	loaded   bool
+}
+
+// daypartSpan is a daypart's [start, end) window as HH:MM:SS strings, which
+// compare correctly as text
+type daypartSpan struct {
+	id    uuid.UUID
+	start string
+	end   string
+}
+
+func newLookupCache() *lookupCache {
+	return &lookupCache{
+		channels: make(map[string]uuid.UUID),
+		pending:  make(map[string]uuid.UUID),
+	}
+}
+
+// channel returns a cached channel ID, including one created by the row
+// being applied
+func (c *lookupCache) channel(name string) (uuid.UUID, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := strings.ToLower(name)
+	if id, ok := c.pending[key]; ok {
+		return id, true
+	}
+	id, ok := c.channels[key]
+	return id, ok
+}
+
+// addChannel caches a channel ID; created marks a channel inserted by the
+// current row, which is only kept once the row commits
+func (c *lookupCache) addChannel(name string, id uuid.UUID, created bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if created {
+		c.pending[strings.ToLower(name)] = id
+		return
+	}
+	c.channels[strings.ToLower(name)] = id
+}
+
+// commit keeps the channels created by a row that was applied
+func (c *lookupCache) commit() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, id := range c.pending {
+		c.channels[k] = id
+	}
+	clear(c.pending)
+}
+
+// discard forgets the channels created by a row that was rolled back
+func (c *lookupCache) discard() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.pending)
+}
+
+// daypart finds the daypart containing t, loading every daypart on first use.
+// Imports never change dayparts, so they are read outside the import transaction.
+func (c *lookupCache) daypart(ctx context.Context, db *pgxpool.Pool, t time.Time) (uuid.UUID, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.loaded {
+		spans, err := loadDayparts(ctx, db)
+		if err != nil {
+			return uuid.Nil, false, err
+		}
+		c.dayparts = spans
+		c.loaded = true
+	}
+
+	clock := t.Format("15:04:05")
+	for _, d := range c.dayparts {
+		if d.start <= clock && d.end > clock {
+			return d.id, true, nil
+		}
+	}
+	return uuid.Nil, false, nil
+}
+
+func loadDayparts(ctx context.Context, db *pgxpool.Pool) ([]daypartSpan, error) {
+	rows, err := db.Query(ctx, `
+		SELECT id, to_char(start_time, 'HH24:MI:SS'), to_char(end_time, 'HH24:MI:SS')
+		FROM dayparts
+		ORDER BY start_time
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spans []daypartSpan
+	for rows.Next() {
+		var d daypartSpan
+		if err := rows.Scan(&d.id, &d.start, &d.end); err != nil {
+			return nil, err
+		}
+		spans = append(spans, d)
+	}
+	return spans, rows.Err()
+}
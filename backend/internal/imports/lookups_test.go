@@ -0,0 +1,249 @@
+package imports
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeChannels stores service channels in memory and counts the queries run
+// against it. Channels in rivals are committed by a concurrent import just
+// before the next insert of the same name, as if it won the race.
+type fakeChannels struct {
+	ids     map[string]uuid.UUID // lower-cased display name -> id
+	rivals  map[string]uuid.UUID
+	queries int
+}
+
+func newFakeChannels() *fakeChannels {
+	return &fakeChannels{ids: make(map[string]uuid.UUID), rivals: make(map[string]uuid.UUID)}
+}
+
+func (f *fakeChannels) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	panic("unexpected Exec: " + sql)
+}
+
+func (f *fakeChannels) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	f.queries++
+	if strings.Contains(sql, "INSERT INTO service_channels") {
+		key := strings.ToLower(args[2].(string))
+		if id, ok := f.rivals[key]; ok {
+			f.ids[key] = id
+			delete(f.rivals, key)
+		}
+		if _, exists := f.ids[key]; exists {
+			return fakeRow{err: pgx.ErrNoRows}
+		}
+		f.ids[key] = args[0].(uuid.UUID)
+		return fakeRow{id: f.ids[key]}
+	}
+	id, ok := f.ids[strings.ToLower(args[0].(string))]
+	if !ok {
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+	return fakeRow{id: id}
+}
+
+type fakeRow struct {
+	id  uuid.UUID
+	err error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*uuid.UUID) = r.id
+	return nil
+}
+
+func TestChannelCacheNoDuplicates(t *testing.T) {
+	ctx := context.Background()
+	p := &Pipeline{}
+	db := newFakeChannels()
+	lookups := newLookupCache()
+	locationID := uuid.New()
+
+	// Each row resolves its channel, then commits like processRow does
+	var ids []uuid.UUID
+	for _, name := range []string{"Dine In", "dine in", "DINE IN", "Dine In"} {
+		id, err := p.getOrCreateChannel(ctx, db, lookups, name, locationID)
+		if err != nil {
+			t.Fatalf("getOrCreateChannel(%q) error = %v", name, err)
+		}
+		lookups.commit()
+		ids = append(ids, id)
+	}
+
+	if len(db.ids) != 1 {
+		t.Errorf("created %d channels, want 1", len(db.ids))
+	}
+	for _, id := range ids[1:] {
+		if id != ids[0] {
+			t.Errorf("rows resolved to different channels: %v", ids)
+			break
+		}
+	}
+	// One select and one insert for the first row; the rest are cached
+	if db.queries != 2 {
+		t.Errorf("ran %d queries, want 2", db.queries)
+	}
+}
+
+func TestChannelCacheDiscardsRolledBackRow(t *testing.T) {
+	ctx := context.Background()
+	p := &Pipeline{}
+	db := newFakeChannels()
+	lookups := newLookupCache()
+	locationID := uuid.New()
+
+	rolledBack, err := p.getOrCreateChannel(ctx, db, lookups, "Delivery", locationID)
+	if err != nil {
+		t.Fatalf("getOrCreateChannel() error = %v", err)
+	}
+	// The row's savepoint rolls back, taking the inserted channel with it
+	delete(db.ids, "delivery")
+	lookups.discard()
+
+	id, err := p.getOrCreateChannel(ctx, db, lookups, "Delivery", locationID)
+	if err != nil {
+		t.Fatalf("getOrCreateChannel() error = %v", err)
+	}
+	lookups.commit()
+
+	if id == rolledBack {
+		t.Error("reused the ID of a channel whose row was rolled back")
+	}
+	if stored := db.ids["delivery"]; stored != id {
+		t.Errorf("resolved %v, but the stored channel is %v", id, stored)
+	}
+}
+
+func TestChannelCreateLosesRace(t *testing.T) {
+	ctx := context.Background()
+	p := &Pipeline{}
+	db := newFakeChannels()
+	lookups := newLookupCache()
+	locationID := uuid.New()
+
+	// Another import creates the channel between this row's select and insert
+	rival := uuid.New()
+	db.rivals["takeaway"] = rival
+
+	id, err := p.getOrCreateChannel(ctx, db, lookups, "Takeaway", locationID)
+	if err != nil {
+		t.Fatalf("getOrCreateChannel() error = %v", err)
+	}
+	if id != rival {
+		t.Errorf("resolved %v, want the channel the other import created %v", id, rival)
+	}
+	if len(db.ids) != 1 {
+		t.Errorf("stored %d channels, want 1", len(db.ids))
+	}
+
+	// The channel was not created by this row, so rolling the row back must
+	// not forget it
+	lookups.discard()
+	queries := db.queries
+	if id, err = p.getOrCreateChannel(ctx, db, lookups, "takeaway", locationID); err != nil {
+		t.Fatalf("getOrCreateChannel() error = %v", err)
+	}
+	if id != rival {
+		t.Errorf("after discard resolved %v, want %v", id, rival)
+	}
+	if db.queries != queries {
+		t.Errorf("ran %d more queries, want the channel served from the cache", db.queries-queries)
+	}
+}
+
+func TestSupplierCreateLosesRace(t *testing.T) {
+	rival := uuid.New()
+	db := &fakeSuppliers{byName: map[string]uuid.UUID{}, rivals: map[string]uuid.UUID{"green grocer": rival}}
+
+	id, err := (&Pipeline{}).getOrCreateSupplier(context.Background(), db, "Green Grocer", uuid.New())
+	if err != nil {
+		t.Fatalf("getOrCreateSupplier() error = %v", err)
+	}
+	if id != rival {
+		t.Errorf("resolved %v, want the supplier the other import created %v", id, rival)
+	}
+	if len(db.byName) != 1 {
+		t.Errorf("stored %d suppliers, want 1", len(db.byName))
+	}
+}
+
+func TestDaypartSpans(t *testing.T) {
+	breakfast, lunch := uuid.New(), uuid.New()
+	lookups := newLookupCache()
+	lookups.loaded = true
+	lookups.dayparts = []daypartSpan{
+		{id: breakfast, start: "06:00:00", end: "11:00:00"},
+		{id: lunch, start: "11:00:00", end: "15:00:00"},
+	}
+
+	tests := []struct {
+		clock  string
+		want   uuid.UUID
+		wantOK bool
+	}{
+		{clock: "06:00", want: breakfast, wantOK: true},
+		{clock: "10:59", want: breakfast, wantOK: true},
+		{clock: "11:00", want: lunch, wantOK: true},
+		{clock: "15:00"},
+		{clock: "03:30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.clock, func(t *testing.T) {
+			clock, err := time.Parse("15:04", tt.clock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, ok, err := lookups.daypart(context.Background(), nil, clock)
+			if err != nil {
+				t.Fatalf("daypart() error = %v", err)
+			}
+			if id != tt.want || ok != tt.wantOK {
+				t.Errorf("daypart(%s) = %v, %v, want %v, %v", tt.clock, id, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// BenchmarkChannelLookup resolves the same few channels for every row, as a
+// POS export does, and reports the queries each row costs
+func BenchmarkChannelLookup(b *testing.B) {
+	names := []string{"Dine In", "Takeaway", "Delivery", "Catering"}
+	for _, cached := range []bool{false, true} {
+		name := "uncached"
+		if cached {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+			p := &Pipeline{}
+			db := newFakeChannels()
+			var lookups *lookupCache
+			if cached {
+				lookups = newLookupCache()
+			}
+			locationID := uuid.New()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := p.getOrCreateChannel(ctx, db, lookups, names[i%len(names)], locationID); err != nil {
+					b.Fatal(err)
+				}
+				if lookups != nil {
+					lookups.commit()
+				}
+			}
+			b.ReportMetric(float64(db.queries)/float64(b.N), "queries/row")
+		})
+	}
+}
@@ -2,23 +2,31 @@ package imports
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // MappingProfile represents a saved column-to-field mapping configuration
 type MappingProfile struct {
-	ID          uuid.UUID              `json:"id"`
-	Name        string                 `json:"name"`
-	SourceType  string                 `json:"source_type"` // pos, payroll, inventory
-	ColumnMaps  map[string]string      `json:"column_maps"` // source column -> target field
-	Defaults    map[string]interface{} `json:"defaults"`    // default values for missing columns
-	LocationID  uuid.UUID              `json:"location_id"`
-	CreatedByID uuid.UUID              `json:"created_by_id"`
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
+	ID           uuid.UUID              `json:"id"`
+	Name         string                 `json:"name"`
+	SourceType   string                 `json:"source_type"`             // pos, payroll, inventory
+	ColumnMaps   map[string]string      `json:"column_maps"`             // source column -> target field
+	Defaults     map[string]interface{} `json:"defaults"`                // default values for missing columns
+	Encoding     string                 `json:"encoding,omitempty"`      // explicit file encoding, empty for UTF-8
+	Delimiter    string                 `json:"delimiter,omitempty"`     // explicit field delimiter, empty for comma
+	NumberFormat string                 `json:"number_format,omitempty"` // en or de amount separators, empty for lenient
+	DateFormat   string                 `json:"date_format,omitempty"`   // dmy or mdy order for slash dates, empty for dmy
+	Rules        []string               `json:"rules"`                   // named validation rules, see Rules()
+	LocationID   uuid.UUID              `json:"location_id"`
+	CreatedByID  uuid.UUID              `json:"created_by_id"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
 }
 
 // MappingStore handles mapping profile persistence
@@ -34,10 +42,13 @@ func NewMappingStore(db *pgxpool.Pool) *MappingStore {
 // Create creates a new mapping profile
 func (s *MappingStore) Create(ctx context.Context, profile *MappingProfile) error {
 	query := `
-		INSERT INTO mapping_profiles (id, name, source_type, column_maps, defaults, location_id, created_by_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO mapping_profiles (id, name, source_type, column_maps, defaults, encoding, delimiter, number_format, date_format, rules, location_id, created_by_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 	profile.ID = uuid.New()
+	if profile.Rules == nil {
+		profile.Rules = []string{}
+	}
 	profile.CreatedAt = time.Now()
 	profile.UpdatedAt = time.Now()
 
@@ -47,6 +58,11 @@ func (s *MappingStore) Create(ctx context.Context, profile *MappingProfile) erro
 		profile.SourceType,
 		profile.ColumnMaps,
 		profile.Defaults,
+		profile.Encoding,
+		profile.Delimiter,
+		profile.NumberFormat,
+		profile.DateFormat,
+		profile.Rules,
 		profile.LocationID,
 		profile.CreatedByID,
 		profile.CreatedAt,
@@ -55,21 +71,27 @@ func (s *MappingStore) Create(ctx context.Context, profile *MappingProfile) erro
 	return err
 }
 
-// GetByID retrieves a mapping profile by ID
-func (s *MappingStore) GetByID(ctx context.Context, id uuid.UUID) (*MappingProfile, error) {
+// GetByID retrieves a location's mapping profile by ID. A profile belonging
+// to another location is not found.
+func (s *MappingStore) GetByID(ctx context.Context, id, locationID uuid.UUID) (*MappingProfile, error) {
 	query := `
-		SELECT id, name, source_type, column_maps, defaults, location_id, created_by_id, created_at, updated_at
+		SELECT id, name, source_type, column_maps, defaults, encoding, delimiter, number_format, date_format, rules, location_id, created_by_id, created_at, updated_at
 		FROM mapping_profiles
-		WHERE id = $1
+		WHERE id = $1 AND location_id = $2
 	`
 
 	var profile MappingProfile
-	err := s.db.QueryRow(ctx, query, id).Scan(
+	err := s.db.QueryRow(ctx, query, id, locationID).Scan(
 		&profile.ID,
 		&profile.Name,
 		&profile.SourceType,
 		&profile.ColumnMaps,
 		&profile.Defaults,
+		&profile.Encoding,
+		&profile.Delimiter,
+		&profile.NumberFormat,
+		&profile.DateFormat,
+		&profile.Rules,
 		&profile.LocationID,
 		&profile.CreatedByID,
 		&profile.CreatedAt,
@@ -84,7 +106,7 @@ func (s *MappingStore) GetByID(ctx context.Context, id uuid.UUID) (*MappingProfi
 // GetBySourceType retrieves all mapping profiles for a source type
 func (s *MappingStore) GetBySourceType(ctx context.Context, sourceType string, locationID uuid.UUID) ([]MappingProfile, error) {
 	query := `
-		SELECT id, name, source_type, column_maps, defaults, location_id, created_by_id, created_at, updated_at
+		SELECT id, name, source_type, column_maps, defaults, encoding, delimiter, number_format, date_format, rules, location_id, created_by_id, created_at, updated_at
 		FROM mapping_profiles
 		WHERE source_type = $1 AND location_id = $2
 		ORDER BY name
@@ -105,6 +127,11 @@ func (s *MappingStore) GetBySourceType(ctx context.Context, sourceType string, l
 			&profile.SourceType,
 			&profile.ColumnMaps,
 			&profile.Defaults,
+			&profile.Encoding,
+			&profile.Delimiter,
+			&profile.NumberFormat,
+			&profile.DateFormat,
+			&profile.Rules,
 			&profile.LocationID,
 			&profile.CreatedByID,
 			&profile.CreatedAt,
@@ -121,7 +148,7 @@ func (s *MappingStore) GetBySourceType(ctx context.Context, sourceType string, l
 // GetAll retrieves all mapping profiles for a location
 func (s *MappingStore) GetAll(ctx context.Context, locationID uuid.UUID) ([]MappingProfile, error) {
 	query := `
-		SELECT id, name, source_type, column_maps, defaults, location_id, created_by_id, created_at, updated_at
+		SELECT id, name, source_type, column_maps, defaults, encoding, delimiter, number_format, date_format, rules, location_id, created_by_id, created_at, updated_at
 		FROM mapping_profiles
 		WHERE location_id = $1
 		ORDER BY source_type, name
@@ -142,6 +169,11 @@ func (s *MappingStore) GetAll(ctx context.Context, locationID uuid.UUID) ([]Mapp
 			&profile.SourceType,
 			&profile.ColumnMaps,
 			&profile.Defaults,
+			&profile.Encoding,
+			&profile.Delimiter,
+			&profile.NumberFormat,
+			&profile.DateFormat,
+			&profile.Rules,
 			&profile.LocationID,
 			&profile.CreatedByID,
 			&profile.CreatedAt,
@@ -155,9 +187,63 @@ func (s *MappingStore) GetAll(ctx context.Context, locationID uuid.UUID) ([]Mapp
 	return profiles, rows.Err()
 }
 
-// Delete deletes a mapping profile
-func (s *MappingStore) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM mapping_profiles WHERE id = $1`
-	_, err := s.db.Exec(ctx, query, id)
+// Update saves a mapping profile's name, column maps, defaults, encoding,
+// delimiter, number and date formats and rules. The source type is fixed once created. Returns
+// ErrMappingNotFound if the profile does not exist for the profile's location.
+func (s *MappingStore) Update(ctx context.Context, profile *MappingProfile) error {
+	query := `
+		UPDATE mapping_profiles
+		SET name = $3, column_maps = $4, defaults = $5, encoding = $6, delimiter = $7, number_format = $8, date_format = $9, rules = $10, updated_at = $11
+		WHERE id = $1 AND location_id = $2
+		RETURNING source_type, created_by_id, created_at
+	`
+	if profile.Rules == nil {
+		profile.Rules = []string{}
+	}
+	profile.UpdatedAt = time.Now()
+
+	err := s.db.QueryRow(ctx, query,
+		profile.ID,
+		profile.LocationID,
+		profile.Name,
+		profile.ColumnMaps,
+		profile.Defaults,
+		profile.Encoding,
+		profile.Delimiter,
+		profile.NumberFormat,
+		profile.DateFormat,
+		profile.Rules,
+		profile.UpdatedAt,
+	).Scan(&profile.SourceType, &profile.CreatedByID, &profile.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrMappingNotFound
+	}
 	return err
 }
+
+// Delete deletes a location's mapping profile. Returns ErrMappingNotFound if
+// it does not exist for the location, or ErrMappingInUse if imports still
+// reference it.
+func (s *MappingStore) Delete(ctx context.Context, id, locationID uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var exists bool
+		err := tx.QueryRow(ctx, `SELECT TRUE FROM mapping_profiles WHERE id = $1 AND location_id = $2 FOR UPDATE`, id, locationID).Scan(&exists)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMappingNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var uses int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM import_jobs WHERE mapping_id = $1`, id).Scan(&uses); err != nil {
+			return err
+		}
+		if uses > 0 {
+			return fmt.Errorf("%w by %d import(s)", ErrMappingInUse, uses)
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM mapping_profiles WHERE id = $1`, id)
+		return err
+	})
+}
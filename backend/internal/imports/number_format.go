@@ -0,0 +1,176 @@
+package imports
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Number formats a mapping profile may declare for its amount columns
+const (
+	NumberFormatEN = "en" // 1,234.56
+	NumberFormatDE = "de" // 1.234,56
+)
+
+// numberFormat describes how one locale writes amounts
+type numberFormat struct {
+	decimal   byte
+	thousands byte
+	symbols   []string // currency symbols and codes stripped from either end, longest first
+}
+
+var numberFormats = map[string]numberFormat{
+	NumberFormatEN: {decimal: '.', thousands: ',', symbols: []string{"AUD", "USD", "GBP", "AU$", "US$", "NZ$", "A$", "$", "£"}},
+	NumberFormatDE: {decimal: ',', thousands: '.', symbols: []string{"EUR", "CHF", "€"}},
+}
+
+// amountFields are the mapped fields parsed as amounts or quantities
+var amountFields = map[string]bool{
+	"total":          true,
+	"subtotal":       true,
+	"tax":            true,
+	"discounts":      true,
+	"comps":          true,
+	"platform_fee":   true,
+	"commission":     true,
+	"amount":         true,
+	"quantity":       true,
+	"unit_cost":      true,
+	"unit_price":     true,
+	"line_total":     true,
+	"total_value":    true,
+	"hours_worked":   true,
+	"hourly_rate":    true,
+	"total_wages":    true,
+	"superannuation": true,
+	"tax_withheld":   true,
+	"revenue_target": true,
+	"cogs_target":    true,
+	"labor_target":   true,
+	"opex_target":    true,
+}
+
+// NormalizeNumberFormat returns the canonical number format name, or an error
+// if unsupported. An empty name is returned unchanged and keeps the lenient
+// default of stripping $ and commas.
+func NormalizeNumberFormat(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, ok := numberFormats[name]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("unsupported number_format %q; must be one of: en, de", name)
+}
+
+// parseLocaleAmount parses an amount written in the given number format.
+// Separators must sit where the format puts them, so 1,234.56 under de or
+// 1,23 under en is rejected rather than read at the wrong magnitude.
+func parseLocaleAmount(s, format string) (float64, error) {
+	f, ok := numberFormats[format]
+	if !ok {
+		return 0, fmt.Errorf("unsupported number_format %q", format)
+	}
+
+	v := strings.TrimSpace(s)
+	negative := false
+	trimSign := func() {
+		if strings.HasPrefix(v, "-") {
+			negative = !negative
+			v = strings.TrimSpace(v[1:])
+		}
+	}
+	trimSign()
+	for _, sym := range f.symbols {
+		if len(v) >= len(sym) && strings.EqualFold(v[:len(sym)], sym) {
+			v = strings.TrimSpace(v[len(sym):])
+			break
+		}
+		if len(v) >= len(sym) && strings.EqualFold(v[len(v)-len(sym):], sym) {
+			v = strings.TrimSpace(v[:len(v)-len(sym)])
+			break
+		}
+	}
+	trimSign()
+	if v == "" {
+		return 0, fmt.Errorf("invalid amount %q for number format %s", s, format)
+	}
+
+	intPart, frac := v, ""
+	if i := strings.IndexByte(v, f.decimal); i >= 0 {
+		intPart, frac = v[:i], v[i+1:]
+	}
+	if strings.IndexByte(frac, f.decimal) >= 0 || strings.IndexByte(frac, f.thousands) >= 0 {
+		return 0, fmt.Errorf("ambiguous amount %q for number format %s: separators are out of order", s, format)
+	}
+	if strings.IndexByte(intPart, f.thousands) >= 0 {
+		groups := strings.Split(intPart, string(f.thousands))
+		if len(groups[0]) == 0 || len(groups[0]) > 3 {
+			return 0, fmt.Errorf("ambiguous amount %q for number format %s: %q must separate groups of three digits", s, format, string(f.thousands))
+		}
+		for _, g := range groups[1:] {
+			if len(g) != 3 {
+				return 0, fmt.Errorf("ambiguous amount %q for number format %s: %q must separate groups of three digits", s, format, string(f.thousands))
+			}
+		}
+		intPart = strings.Join(groups, "")
+	}
+	if !allDigits(intPart) || !allDigits(frac) || intPart+frac == "" {
+		return 0, fmt.Errorf("invalid amount %q for number format %s", s, format)
+	}
+
+	canonical := intPart
+	if canonical == "" {
+		canonical = "0"
+	}
+	if frac != "" {
+		canonical += "." + frac
+	}
+	amount, err := strconv.ParseFloat(canonical, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q for number format %s", s, format)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeAmounts rewrites a row's amount fields from the given number format
+// into plain decimals so every later stage parses them the same way. Values
+// that cannot be read are left as written and returned as row errors.
+func normalizeAmounts(mapped map[string]interface{}, format string) []string {
+	fields := make([]string, 0, len(mapped))
+	for field := range mapped {
+		if amountFields[field] {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	var errs []string
+	for _, field := range fields {
+		val, ok := mapped[field].(string)
+		if !ok || val == "" {
+			continue
+		}
+		amount, err := parseLocaleAmount(val, format)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", field, err))
+			continue
+		}
+		mapped[field] = strconv.FormatFloat(amount, 'f', -1, 64)
+	}
+	return errs
+}
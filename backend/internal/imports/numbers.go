@@ -0,0 +1,49 @@
+package imports
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// How the parser treats values that look mangled by a spreadsheet
+const (
+	MangledNumbersWarn  = "warn"  // import the row and record a warning anomaly (default)
+	MangledNumbersError = "error" // reject the row
+)
+
+// idFields are identifiers kept exactly as written. They are never parsed as
+// numbers, so leading zeros survive from the file to the database.
+var idFields = map[string]bool{
+	"external_id":      true,
+	"gift_card_number": true,
+}
+
+// scientificNotation matches values such as 1.23E+04, which spreadsheets
+// write for long numbers once the column is too narrow to show every digit
+var scientificNotation = regexp.MustCompile(`^[+-]?\d+(\.\d+)?[eE][+-]?\d+$`)
+
+// mangledValues lists mapped values that look like a spreadsheet rewrote
+// them: identifiers in scientific notation have lost digits for good, and
+// amounts in scientific notation may have been rounded
+func mangledValues(mapped map[string]interface{}) []string {
+	fields := make([]string, 0, len(mapped))
+	for field := range mapped {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var issues []string
+	for _, field := range fields {
+		val, ok := mapped[field].(string)
+		if !ok || !scientificNotation.MatchString(val) {
+			continue
+		}
+		if idFields[field] {
+			issues = append(issues, fmt.Sprintf("%s %s is in scientific notation; the original ID was likely lost when the file was saved from a spreadsheet", field, val))
+		} else {
+			issues = append(issues, fmt.Sprintf("%s %s is in scientific notation; check it was not rounded when the file was saved from a spreadsheet", field, val))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,190 @@
+package imports
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{in: "100", want: 100},
+		{in: " 12.50 ", want: 12.5},
+		{in: "$1,234.56", want: 1234.56},
+		{in: "-45.10", want: -45.1},
+		{in: "1.23E4", want: 12300},
+		{in: "1.23e+04", want: 12300},
+		{in: "", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "Inf", wantErr: true},
+		{in: "-Infinity", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseAmount(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAmount(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseAmount(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLocaleAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		format  string
+		want    float64
+		wantErr bool
+	}{
+		{in: "1,234.56", format: NumberFormatEN, want: 1234.56},
+		{in: "$1,234,567", format: NumberFormatEN, want: 1234567},
+		{in: "-12.5 AUD", format: NumberFormatEN, want: -12.5},
+		{in: "1.234,56", format: NumberFormatDE, want: 1234.56},
+		{in: "€ 12,50", format: NumberFormatDE, want: 12.5},
+		{in: "12,50 EUR", format: NumberFormatDE, want: 12.5},
+		{in: ",5", format: NumberFormatDE, want: 0.5},
+		{in: "1,23", format: NumberFormatEN, wantErr: true},
+		{in: "1,234.56", format: NumberFormatDE, wantErr: true},
+		{in: "1.234,56", format: NumberFormatEN, wantErr: true},
+		{in: "1.23E4", format: NumberFormatEN, wantErr: true},
+		{in: "", format: NumberFormatEN, wantErr: true},
+		{in: "12", format: "fr", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.in, func(t *testing.T) {
+			got, err := parseLocaleAmount(tt.in, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLocaleAmount(%q, %s) error = %v, wantErr %v", tt.in, tt.format, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseLocaleAmount(%q, %s) = %v, want %v", tt.in, tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMangledValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		mapped map[string]interface{}
+		want   []string
+	}{
+		{
+			name:   "plain values",
+			mapped: map[string]interface{}{"total": "12300", "external_id": "000123"},
+		},
+		{
+			name:   "amount in scientific notation",
+			mapped: map[string]interface{}{"total": "1.23E4"},
+			want:   []string{"total 1.23E4 is in scientific notation; check it was not rounded when the file was saved from a spreadsheet"},
+		},
+		{
+			name:   "id in scientific notation",
+			mapped: map[string]interface{}{"external_id": "4.5e+15", "total": "10"},
+			want:   []string{"external_id 4.5e+15 is in scientific notation; the original ID was likely lost when the file was saved from a spreadsheet"},
+		},
+		{
+			name:   "non-string defaults are ignored",
+			mapped: map[string]interface{}{"total": 1.23e4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mangledValues(tt.mapped); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mangledValues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMangledNumbers(t *testing.T) {
+	mapping := &MappingProfile{
+		Name:       "test",
+		ColumnMaps: map[string]string{"Date": "date", "Total": "total", "Order": "external_id"},
+	}
+	csv := "Date,Total,Order\n2024-01-01,1.23E4,000123\n"
+
+	tests := []struct {
+		mode         string
+		wantValid    int
+		wantErrors   int
+		wantWarnings int
+	}{
+		{mode: MangledNumbersWarn, wantValid: 1, wantWarnings: 1},
+		{mode: MangledNumbersError, wantErrors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			p := NewParser("pos", mapping)
+			p.mangledNumbers = tt.mode
+
+			result, err := p.Parse(strings.NewReader(csv))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if result.ValidRows != tt.wantValid || result.ErrorRows != tt.wantErrors {
+				t.Errorf("ValidRows = %d, ErrorRows = %d, want %d and %d", result.ValidRows, result.ErrorRows, tt.wantValid, tt.wantErrors)
+			}
+			row := result.Rows[0]
+			if len(row.Warnings) != tt.wantWarnings {
+				t.Errorf("Warnings = %q, want %d", row.Warnings, tt.wantWarnings)
+			}
+			// Identifiers keep their leading zeros
+			if row.Mapped["external_id"] != "000123" {
+				t.Errorf("external_id = %v, want 000123", row.Mapped["external_id"])
+			}
+		})
+	}
+}
+
+func TestNormalizeNumberFormat(t *testing.T) {
+	for in, want := range map[string]string{"": "", "en": "en", " DE ": "de"} {
+		if got, err := NormalizeNumberFormat(in); err != nil || got != want {
+			t.Errorf("NormalizeNumberFormat(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := NormalizeNumberFormat("fr"); err == nil {
+		t.Error("NormalizeNumberFormat(fr) succeeded, want an error")
+	}
+}
+
+func TestParseWithNumberFormat(t *testing.T) {
+	csv := "Date,Total,Tax\n2024-03-04,\"1.234,56\",\"112,23\"\n2024-03-04,\"1,234.56\",\"112,23\"\n"
+	mapping := &MappingProfile{
+		ColumnMaps:   map[string]string{"Date": "date", "Total": "total", "Tax": "tax"},
+		NumberFormat: NumberFormatDE,
+	}
+
+	result, err := NewParser("pos", mapping).Parse(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(result.Rows) != 2 {
+		t.Fatalf("parsed %d rows, want 2", len(result.Rows))
+	}
+
+	first := result.Rows[0]
+	if len(first.Errors) != 0 {
+		t.Fatalf("line %d errors = %q, want none", first.LineNumber, first.Errors)
+	}
+	if first.Mapped["total"] != "1234.56" || first.Mapped["tax"] != "112.23" {
+		t.Errorf("mapped total, tax = %v, %v, want 1234.56, 112.23", first.Mapped["total"], first.Mapped["tax"])
+	}
+
+	second := result.Rows[1]
+	if len(second.Errors) == 0 || !strings.Contains(strings.Join(second.Errors, "; "), "ambiguous amount") {
+		t.Errorf("line %d errors = %q, want an ambiguous amount error", second.LineNumber, second.Errors)
+	}
+}
@@ -0,0 +1,49 @@
+package imports
+
+import "strings"
+
+// Canonical order types stored on sales.order_type
+const (
+	OrderTypeDineIn   = "dine_in"
+	OrderTypeTakeaway = "takeaway"
+	OrderTypeDelivery = "delivery"
+)
+
+// orderTypeAliases maps common POS spellings to canonical order types
+var orderTypeAliases = map[string]string{
+	"dine in":    OrderTypeDineIn,
+	"dine-in":    OrderTypeDineIn,
+	"dinein":     OrderTypeDineIn,
+	"eat in":     OrderTypeDineIn,
+	"eat-in":     OrderTypeDineIn,
+	"for here":   OrderTypeDineIn,
+	"table":      OrderTypeDineIn,
+	"takeaway":   OrderTypeTakeaway,
+	"take away":  OrderTypeTakeaway,
+	"take-away":  OrderTypeTakeaway,
+	"takeout":    OrderTypeTakeaway,
+	"take out":   OrderTypeTakeaway,
+	"take-out":   OrderTypeTakeaway,
+	"to go":      OrderTypeTakeaway,
+	"pickup":     OrderTypeTakeaway,
+	"pick up":    OrderTypeTakeaway,
+	"collection": OrderTypeTakeaway,
+	"delivery":   OrderTypeDelivery,
+	"delivered":  OrderTypeDelivery,
+}
+
+// NormalizeOrderType maps a raw POS order type to dine_in, takeaway or
+// delivery. Unrecognized values are kept as lower-case snake case so custom
+// types still group together; empty values return "".
+func NormalizeOrderType(raw string) string {
+	v := strings.ToLower(strings.TrimSpace(raw))
+	if v == "" {
+		return ""
+	}
+	if canonical, ok := orderTypeAliases[strings.ReplaceAll(v, "_", " ")]; ok {
+		return canonical
+	}
+	return strings.Join(strings.FieldsFunc(v, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}), "_")
+}
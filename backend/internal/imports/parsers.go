@@ -2,9 +2,10 @@ package imports
 
 import (
 	"encoding/csv"
-	"errors"
 	"fmt"
 	"io"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,35 +19,77 @@ type ParsedRow struct {
 	Raw        map[string]string
 	Mapped     map[string]interface{}
 	Errors     []string
+	Warnings   []string // issues that do not stop the row being applied
 }
 
 // ParseResult contains the results of parsing a CSV file
 type ParseResult struct {
-	Headers    []string
-	Rows       []ParsedRow
-	ValidRows  int
-	ErrorRows  int
-	TotalRows  int
-	SourceType string
+	Headers         []string
+	Warnings        []string // header-level issues, e.g. renamed duplicate columns
+	MappingWarnings []string // job-level: file headers and mapping columns that do not line up
+	Rows            []ParsedRow
+	ValidRows       int
+	ErrorRows       int
+	TotalRows       int
+	SourceType      string
 }
 
+// How the parser treats repeated header names
+const (
+	DuplicateHeadersError  = "error"  // reject the file (default)
+	DuplicateHeadersRename = "rename" // keep every column, suffixing repeats: Total, Total_2
+)
+
 // Parser handles CSV parsing and validation
 type Parser struct {
-	sourceType string
-	mapping    *MappingProfile
+	sourceType       string
+	mapping          *MappingProfile
+	duplicateHeaders string
+	mangledNumbers   string
 }
 
 // NewParser creates a new CSV parser
 func NewParser(sourceType string, mapping *MappingProfile) *Parser {
 	return &Parser{
-		sourceType: sourceType,
-		mapping:    mapping,
+		sourceType:       sourceType,
+		mapping:          mapping,
+		duplicateHeaders: DuplicateHeadersError,
+		mangledNumbers:   MangledNumbersWarn,
 	}
 }
 
-// Parse parses a CSV file using the configured mapping
+// Parse parses a CSV file using the configured mapping, keeping every row in
+// result.Rows
 func (p *Parser) Parse(reader io.Reader) (*ParseResult, error) {
+	var rows []ParsedRow
+	result, err := p.ParseEach(reader, func(row ParsedRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Rows = rows
+	return result, nil
+}
+
+// ParseEach parses a CSV file row by row, handing each row to fn instead of
+// collecting them, so memory use does not grow with file size. The returned
+// result carries headers and counts but no Rows. Parsing stops at the first
+// error returned by fn.
+func (p *Parser) ParseEach(reader io.Reader, fn func(ParsedRow) error) (*ParseResult, error) {
+	if p.mapping != nil && p.mapping.Encoding != "" {
+		decoded, err := decodeReader(reader, p.mapping.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		reader = decoded
+	}
+
 	csvReader := csv.NewReader(reader)
+	if p.mapping != nil {
+		csvReader.Comma = delimiterRune(p.mapping.Delimiter)
+	}
 	csvReader.LazyQuotes = true
 	csvReader.TrimLeadingSpace = true
 
@@ -56,17 +99,26 @@ func (p *Parser) Parse(reader io.Reader) (*ParseResult, error) {
 		return nil, fmt.Errorf("failed to read CSV headers: %w", err)
 	}
 
-	// Clean headers
+	// Clean headers; a UTF-8 BOM left on the first cell would stop it matching its mapping
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], "\uFEFF")
+	}
 	for i := range headers {
 		headers[i] = strings.TrimSpace(headers[i])
 	}
+	headers, warnings, err := p.dedupeHeaders(headers)
+	if err != nil {
+		return nil, err
+	}
 
 	result := &ParseResult{
-		Headers:    headers,
-		SourceType: p.sourceType,
+		Headers:         headers,
+		Warnings:        warnings,
+		MappingWarnings: p.mappingCoverage(headers),
+		SourceType:      p.sourceType,
 	}
 
-	// Read all rows
+	// Read rows one at a time
 	lineNum := 1
 	for {
 		record, err := csvReader.Read()
@@ -80,7 +132,6 @@ func (p *Parser) Parse(reader io.Reader) (*ParseResult, error) {
 		lineNum++
 
 		row := p.parseRow(headers, record, lineNum)
-		result.Rows = append(result.Rows, row)
 		result.TotalRows++
 
 		if len(row.Errors) == 0 {
@@ -88,11 +139,110 @@ func (p *Parser) Parse(reader io.Reader) (*ParseResult, error) {
 		} else {
 			result.ErrorRows++
 		}
+
+		if err := fn(row); err != nil {
+			return result, err
+		}
 	}
 
 	return result, nil
 }
 
+// mappingCoverage compares the file's headers with the mapping's source
+// columns, describing headers the mapping ignores and mapped columns the file
+// lacks. A mapping built for another export layout shows up here before
+// every row fails on missing required fields.
+func (p *Parser) mappingCoverage(headers []string) []string {
+	if p.mapping == nil {
+		return nil
+	}
+
+	present := make(map[string]bool, len(headers))
+	covered := make(map[string]bool)
+	var unmapped []string
+	for _, h := range headers {
+		present[h] = true
+		if target, ok := p.mapping.ColumnMaps[h]; ok {
+			covered[target] = true
+		} else {
+			unmapped = append(unmapped, h)
+		}
+	}
+	// Alternate column names for a field another present column fills are not missing
+	var missing []string
+	for col, target := range p.mapping.ColumnMaps {
+		if !present[col] && !covered[target] {
+			missing = append(missing, col)
+		}
+	}
+	sort.Strings(missing)
+
+	var warnings []string
+	if len(unmapped) > 0 {
+		warnings = append(warnings, fmt.Sprintf("file columns not in mapping %q: %s", p.mapping.Name, strings.Join(unmapped, ", ")))
+	}
+	if len(missing) > 0 {
+		warnings = append(warnings, fmt.Sprintf("mapping %q columns missing from file: %s", p.mapping.Name, strings.Join(missing, ", ")))
+	}
+	return warnings
+}
+
+// dedupeHeaders makes header names unique so no column is lost when rows are
+// keyed by header. Depending on the configured mode it either rejects the file,
+// naming each duplicated column and its positions, or renames the repeats and
+// returns a warning describing each rename.
+func (p *Parser) dedupeHeaders(headers []string) ([]string, []string, error) {
+	positions := make(map[string][]int, len(headers))
+	var duplicated []string
+	for i, h := range headers {
+		positions[h] = append(positions[h], i+1)
+		if len(positions[h]) == 2 {
+			duplicated = append(duplicated, h)
+		}
+	}
+	if len(duplicated) == 0 {
+		return headers, nil, nil
+	}
+
+	if p.duplicateHeaders != DuplicateHeadersRename {
+		descs := make([]string, len(duplicated))
+		for i, h := range duplicated {
+			descs[i] = fmt.Sprintf("%q at columns %s", h, joinInts(positions[h]))
+		}
+		return nil, nil, fmt.Errorf("duplicate header columns: %s", strings.Join(descs, "; "))
+	}
+
+	out := make([]string, len(headers))
+	copy(out, headers)
+	var warnings []string
+	for _, h := range duplicated {
+		n := 1
+		for _, pos := range positions[h][1:] {
+			// Skip suffixes that collide with a real column name
+			name := ""
+			for {
+				n++
+				name = fmt.Sprintf("%s_%d", h, n)
+				if _, taken := positions[name]; !taken {
+					break
+				}
+			}
+			positions[name] = []int{pos}
+			out[pos-1] = name
+			warnings = append(warnings, fmt.Sprintf("duplicate header %q at column %d renamed to %q", h, pos, name))
+		}
+	}
+	return out, warnings, nil
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
 func (p *Parser) parseRow(headers []string, record []string, lineNum int) ParsedRow {
 	row := ParsedRow{
 		LineNumber: lineNum,
@@ -125,14 +275,56 @@ func (p *Parser) parseRow(headers []string, record []string, lineNum int) Parsed
 		}
 	}
 
+	// Amounts written in the mapping's number format become plain decimals
+	var amountErrs []string
+	if p.mapping != nil && p.mapping.NumberFormat != "" {
+		amountErrs = normalizeAmounts(row.Mapped, p.mapping.NumberFormat)
+	}
+
+	// Dates are read in the mapping's declared order
+	var dateOrder string
+	if p.mapping != nil {
+		dateOrder = p.mapping.DateFormat
+	}
+	dateWarnings := normalizeDates(row.Mapped, dateOrder)
+
 	// Validate based on source type
 	switch p.sourceType {
 	case "pos":
 		row.Errors = p.validatePOSRow(row)
+		row.Warnings = posAmountWarnings(row)
 	case "payroll":
 		row.Errors = p.validatePayrollRow(row)
 	case "inventory":
 		row.Errors = p.validateInventoryRow(row)
+	case "purchases":
+		row.Errors = p.validatePurchaseRow(row)
+	case "expenses":
+		row.Errors = p.validateExpenseRow(row)
+	case "refunds":
+		row.Errors = p.validateRefundRow(row)
+	case "budget":
+		row.Errors = p.validateBudgetRow(row)
+	case "deposits":
+		row.Errors = p.validateDepositRow(row)
+	case "sale_items":
+		row.Errors = p.validateSaleItemRow(row)
+	}
+
+	row.Errors = append(amountErrs, row.Errors...)
+	row.Warnings = append(row.Warnings, dateWarnings...)
+
+	// Rules the mapping profile opted in to
+	if p.mapping != nil && len(p.mapping.Rules) > 0 {
+		row.Errors = append(row.Errors, applyRules(p.mapping.Rules, row)...)
+	}
+
+	if issues := mangledValues(row.Mapped); len(issues) > 0 {
+		if p.mangledNumbers == MangledNumbersError {
+			row.Errors = append(row.Errors, issues...)
+		} else {
+			row.Warnings = append(row.Warnings, issues...)
+		}
 	}
 
 	return row
@@ -157,7 +349,7 @@ func (p *Parser) validatePOSRow(row ParsedRow) []string {
 	}
 
 	// Validate numeric fields
-	numericFields := []string{"total", "discounts", "comps", "tax"}
+	numericFields := []string{"total", "discounts", "comps", "tax", "platform_fee", "commission"}
 	for _, field := range numericFields {
 		if val, ok := row.Mapped[field].(string); ok && val != "" {
 			if _, err := parseAmount(val); err != nil {
@@ -169,6 +361,31 @@ func (p *Parser) validatePOSRow(row ParsedRow) []string {
 	return errs
 }
 
+// posAmountWarnings flags POS amounts that parse but make no sense for a
+// sale. Refund rows may carry a negative total since processors report
+// refunds either way.
+func posAmountWarnings(row ParsedRow) []string {
+	var warnings []string
+	amount := func(field string) float64 {
+		v, _, _ := mappedAmount(row, field)
+		return v
+	}
+
+	total, tax := amount("total"), amount("tax")
+	if total < 0 && !IsPOSRefund(row) {
+		warnings = append(warnings, fmt.Sprintf("total is negative (%.2f); mark refunds with the refund column instead", total))
+	}
+	for _, field := range []string{"tax", "discounts", "comps", "platform_fee", "commission"} {
+		if v := amount(field); v < 0 {
+			warnings = append(warnings, fmt.Sprintf("%s is negative: %.2f", field, v))
+		}
+	}
+	if total >= 0 && tax > total {
+		warnings = append(warnings, fmt.Sprintf("subtotal is negative: total %.2f is less than tax %.2f", total, tax))
+	}
+	return warnings
+}
+
 func (p *Parser) validatePayrollRow(row ParsedRow) []string {
 	var errs []string
 
@@ -217,31 +434,201 @@ func (p *Parser) validateInventoryRow(row ParsedRow) []string {
 	return errs
 }
 
-// Helper functions for parsing
+func (p *Parser) validatePurchaseRow(row ParsedRow) []string {
+	var errs []string
 
-func parseDate(s string) (time.Time, error) {
-	formats := []string{
-		"2006-01-02",
-		"02/01/2006",
-		"01/02/2006",
-		"2006-01-02T15:04:05",
-		"2006-01-02 15:04:05",
+	// Required fields for purchase data
+	requiredFields := []string{"date", "supplier", "item_name", "quantity", "unit_cost"}
+	for _, field := range requiredFields {
+		if val, ok := row.Mapped[field]; !ok || val == "" {
+			errs = append(errs, fmt.Sprintf("missing required field: %s", field))
+		}
 	}
 
-	s = strings.TrimSpace(s)
-	for _, format := range formats {
-		if t, err := time.Parse(format, s); err == nil {
-			return t, nil
+	// Validate date format
+	if dateStr, ok := row.Mapped["date"].(string); ok && dateStr != "" {
+		if _, err := parseDate(dateStr); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid date format: %s", dateStr))
+		}
+	}
+
+	// Validate numeric fields
+	numericFields := []string{"quantity", "unit_cost", "total"}
+	for _, field := range numericFields {
+		if val, ok := row.Mapped[field].(string); ok && val != "" {
+			if _, err := parseAmount(val); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid numeric value for %s: %s", field, val))
+			}
 		}
 	}
-	return time.Time{}, errors.New("unable to parse date")
+
+	return errs
 }
 
+func (p *Parser) validateExpenseRow(row ParsedRow) []string {
+	var errs []string
+
+	// Required fields for operating expense data
+	requiredFields := []string{"date", "category", "amount"}
+	for _, field := range requiredFields {
+		if val, ok := row.Mapped[field]; !ok || val == "" {
+			errs = append(errs, fmt.Sprintf("missing required field: %s", field))
+		}
+	}
+
+	// Validate date format
+	if dateStr, ok := row.Mapped["date"].(string); ok && dateStr != "" {
+		if _, err := parseDate(dateStr); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid date format: %s", dateStr))
+		}
+	}
+
+	if val, ok := row.Mapped["amount"].(string); ok && val != "" {
+		if _, err := parseAmount(val); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid numeric value for amount: %s", val))
+		}
+	}
+
+	return errs
+}
+
+func (p *Parser) validateBudgetRow(row ParsedRow) []string {
+	var errs []string
+
+	// A budget row needs its month and at least one target
+	monthStr, _ := row.Mapped["month"].(string)
+	if monthStr == "" {
+		errs = append(errs, "missing required field: month")
+	} else if _, err := parseMonth(monthStr); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid month format: %s (use YYYY-MM)", monthStr))
+	}
+
+	hasTarget := false
+	for _, field := range budgetTargets {
+		if val, ok := row.Mapped[field].(string); ok && val != "" {
+			hasTarget = true
+			if _, err := parseAmount(val); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid numeric value for %s: %s", field, val))
+			}
+		}
+	}
+	if !hasTarget {
+		errs = append(errs, "missing budget targets: need at least one of "+strings.Join(budgetTargets, ", "))
+	}
+
+	return errs
+}
+
+func (p *Parser) validateRefundRow(row ParsedRow) []string {
+	var errs []string
+
+	// Required fields for refund data; external_id is optional but unmatched refunds are flagged
+	requiredFields := []string{"date", "amount"}
+	for _, field := range requiredFields {
+		if val, ok := row.Mapped[field]; !ok || val == "" {
+			errs = append(errs, fmt.Sprintf("missing required field: %s", field))
+		}
+	}
+
+	// Validate date format
+	if dateStr, ok := row.Mapped["date"].(string); ok && dateStr != "" {
+		if _, err := parseDate(dateStr); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid date format: %s", dateStr))
+		}
+	}
+
+	if val, ok := row.Mapped["amount"].(string); ok && val != "" {
+		if _, err := parseAmount(val); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid numeric value for amount: %s", val))
+		}
+	}
+
+	return errs
+}
+
+func (p *Parser) validateDepositRow(row ParsedRow) []string {
+	var errs []string
+
+	// Required fields for bank deposit data; reference is optional
+	requiredFields := []string{"date", "amount"}
+	for _, field := range requiredFields {
+		if val, ok := row.Mapped[field]; !ok || val == "" {
+			errs = append(errs, fmt.Sprintf("missing required field: %s", field))
+		}
+	}
+
+	if dateStr, ok := row.Mapped["date"].(string); ok && dateStr != "" {
+		if _, err := parseDate(dateStr); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid date format: %s", dateStr))
+		}
+	}
+
+	if val, ok := row.Mapped["amount"].(string); ok && val != "" {
+		if _, err := parseAmount(val); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid numeric value for amount: %s", val))
+		}
+	}
+
+	return errs
+}
+
+func (p *Parser) validateSaleItemRow(row ParsedRow) []string {
+	var errs []string
+
+	// Required fields for sale line data; the date narrows the parent sale lookup
+	requiredFields := []string{"date", "external_id", "quantity"}
+	for _, field := range requiredFields {
+		if val, ok := row.Mapped[field]; !ok || val == "" {
+			errs = append(errs, fmt.Sprintf("missing required field: %s", field))
+		}
+	}
+	sku, _ := row.Mapped["sku"].(string)
+	itemName, _ := row.Mapped["item_name"].(string)
+	if sku == "" && itemName == "" {
+		errs = append(errs, "missing required field: sku or item_name")
+	}
+
+	if dateStr, ok := row.Mapped["date"].(string); ok && dateStr != "" {
+		if _, err := parseDate(dateStr); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid date format: %s", dateStr))
+		}
+	}
+
+	numericFields := []string{"quantity", "unit_price", "line_total", "discounts", "comps"}
+	for _, field := range numericFields {
+		if val, ok := row.Mapped[field].(string); ok && val != "" {
+			if _, err := parseAmount(val); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid numeric value for %s: %s", field, val))
+			}
+		}
+	}
+
+	return errs
+}
+
+// Helper functions for parsing
+
+// parseDate parses a date, reading slash-separated dates as day/month.
+// Mappings with a date_format have already rewritten theirs to ISO form.
+func parseDate(s string) (time.Time, error) {
+	return parseDateOrder(s, DateFormatDMY)
+}
+
+// parseAmount parses a currency amount, ignoring $ and thousands separators.
+// Scientific notation such as 1.23E4 is accepted.
 func parseAmount(s string) (float64, error) {
 	s = strings.TrimSpace(s)
 	s = strings.ReplaceAll(s, "$", "")
 	s = strings.ReplaceAll(s, ",", "")
-	return strconv.ParseFloat(s, 64)
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	// ParseFloat accepts NaN and Inf, which are never real amounts
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("not a finite amount: %s", s)
+	}
+	return v, nil
 }
 
 func parseInt(s string) (int, error) {
@@ -254,39 +641,139 @@ func parseUUID(s string) (uuid.UUID, error) {
 	return uuid.Parse(s)
 }
 
-// DefaultMappings returns default column mappings for each source type
+// ValidSourceTypes lists every source type an import or mapping profile may use
+var ValidSourceTypes = []string{"pos", "sale_items", "payroll", "inventory", "purchases", "expenses", "refunds", "budget", "deposits"}
+
+// ErrUnknownSourceType is returned when an import names a source type not in ValidSourceTypes
+var ErrUnknownSourceType = fmt.Errorf("source_type must be one of: %s", strings.Join(ValidSourceTypes, ", "))
+
+// IsValidSourceType reports whether sourceType is in ValidSourceTypes
+func IsValidSourceType(sourceType string) bool {
+	for _, t := range ValidSourceTypes {
+		if t == sourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultMappings returns default column mappings for each of ValidSourceTypes
 func DefaultMappings() map[string]map[string]string {
-	return map[string]map[string]string{
-		"pos": {
-			"Date":          "date",
-			"Time":          "time",
-			"Total":         "total",
-			"Subtotal":      "subtotal",
-			"Tax":           "tax",
-			"Discounts":     "discounts",
-			"Comps":         "comps",
-			"Payment Method": "payment_method",
-			"Channel":       "channel",
-			"Server":        "server",
-		},
-		"payroll": {
-			"Period Start":   "period_start",
-			"Period End":     "period_end",
-			"Employee":       "employee_name",
-			"Hours Worked":   "hours_worked",
-			"Hourly Rate":    "hourly_rate",
-			"Total Wages":    "total_wages",
-			"Super":          "superannuation",
-			"Tax Withheld":   "tax_withheld",
-		},
-		"inventory": {
-			"Snapshot Date": "snapshot_date",
-			"Item Name":     "item_name",
-			"Category":      "category",
-			"Quantity":      "quantity",
-			"Unit":          "unit",
-			"Unit Cost":     "unit_cost",
-			"Total Value":   "total_value",
-		},
+	mappings := make(map[string]map[string]string, len(ValidSourceTypes))
+	for _, sourceType := range ValidSourceTypes {
+		mappings[sourceType] = defaultColumnMaps[sourceType]
 	}
+	return mappings
+}
+
+// defaultColumnMaps maps common export headers to fields, by source type
+var defaultColumnMaps = map[string]map[string]string{
+	"pos": {
+		"Date":             "date",
+		"Time":             "time",
+		"Total":            "total",
+		"Subtotal":         "subtotal",
+		"Tax":              "tax",
+		"Discounts":        "discounts",
+		"Comps":            "comps",
+		"Discount Reason":  "discount_reason",
+		"Comp Reason":      "comp_reason",
+		"Order Type":       "order_type",
+		"Dining Option":    "order_type",
+		"Service Type":     "order_type",
+		"Fulfillment":      "order_type",
+		"Transaction Type": "transaction_type",
+		"Gift Card":        "gift_card_number",
+		"Payment Method":   "payment_method",
+		"Channel":          "channel",
+		"Platform Fee":     "platform_fee",
+		"Refund":           "refund",
+		"Is Refund":        "refund",
+		"Commission":       "commission",
+		"Server":           "server",
+		"Order ID":         "external_id",
+		"Transaction ID":   "external_id",
+		"Check Number":     "external_id",
+	},
+	"payroll": {
+		"Period Start": "period_start",
+		"Period End":   "period_end",
+		"Employee":     "employee_name",
+		"Hours Worked": "hours_worked",
+		"Hourly Rate":  "hourly_rate",
+		"Total Wages":  "total_wages",
+		"Super":        "superannuation",
+		"Tax Withheld": "tax_withheld",
+	},
+	"inventory": {
+		"Snapshot Date": "snapshot_date",
+		"Item Name":     "item_name",
+		"Category":      "category",
+		"Quantity":      "quantity",
+		"Unit":          "unit",
+		"Unit Cost":     "unit_cost",
+		"Total Value":   "total_value",
+	},
+	"purchases": {
+		"Date":           "date",
+		"Invoice Date":   "date",
+		"Invoice Number": "invoice_number",
+		"Invoice #":      "invoice_number",
+		"Supplier":       "supplier",
+		"Item Name":      "item_name",
+		"Item":           "item_name",
+		"Category":       "category",
+		"Quantity":       "quantity",
+		"Unit Cost":      "unit_cost",
+		"Total":          "total",
+	},
+	"expenses": {
+		"Date":        "date",
+		"Category":    "category",
+		"Description": "description",
+		"Amount":      "amount",
+	},
+	"sale_items": {
+		"Date":           "date",
+		"Order ID":       "external_id",
+		"Check Number":   "external_id",
+		"Receipt Number": "external_id",
+		"SKU":            "sku",
+		"Item":           "item_name",
+		"Item Name":      "item_name",
+		"Quantity":       "quantity",
+		"Qty":            "quantity",
+		"Unit Price":     "unit_price",
+		"Price":          "unit_price",
+		"Line Total":     "line_total",
+		"Discounts":      "discounts",
+		"Comps":          "comps",
+	},
+	"deposits": {
+		"Date":         "date",
+		"Deposit Date": "date",
+		"Amount":       "amount",
+		"Reference":    "reference",
+		"Ref":          "reference",
+	},
+	"budget": {
+		"Month":          "month",
+		"Revenue Target": "revenue_target",
+		"Revenue":        "revenue_target",
+		"COGS Target":    "cogs_target",
+		"COGS":           "cogs_target",
+		"Labor Target":   "labor_target",
+		"Labor":          "labor_target",
+		"OpEx Target":    "opex_target",
+		"OpEx":           "opex_target",
+	},
+	"refunds": {
+		"Date":           "date",
+		"Refund Date":    "date",
+		"Order ID":       "external_id",
+		"Transaction ID": "external_id",
+		"Amount":         "amount",
+		"Refund Amount":  "amount",
+		"Reason":         "reason",
+	},
 }
@@ -0,0 +1,258 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseDuplicateHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         string
+		csv          string
+		wantHeaders  []string
+		wantWarnings []string
+		wantErr      string
+	}{
+		{
+			name:        "unique headers",
+			mode:        DuplicateHeadersError,
+			csv:         "Date,Total,Tax\n2024-01-01,100,10\n",
+			wantHeaders: []string{"Date", "Total", "Tax"},
+		},
+		{
+			name:    "rejected by default",
+			mode:    DuplicateHeadersError,
+			csv:     "Date,Total,Total\n2024-01-01,100,110\n",
+			wantErr: `duplicate header columns: "Total" at columns 2, 3`,
+		},
+		{
+			name:    "every duplicate is named",
+			mode:    DuplicateHeadersError,
+			csv:     "Total,Date,Total,Date,Total\n",
+			wantErr: `duplicate header columns: "Total" at columns 1, 3, 5; "Date" at columns 2, 4`,
+		},
+		{
+			name:         "renamed",
+			mode:         DuplicateHeadersRename,
+			csv:          "Date,Total,Total\n2024-01-01,100,110\n",
+			wantHeaders:  []string{"Date", "Total", "Total_2"},
+			wantWarnings: []string{`duplicate header "Total" at column 3 renamed to "Total_2"`},
+		},
+		{
+			name:        "rename skips taken names",
+			mode:        DuplicateHeadersRename,
+			csv:         "Total,Total_2,Total\n1,2,3\n",
+			wantHeaders: []string{"Total", "Total_2", "Total_3"},
+			wantWarnings: []string{
+				`duplicate header "Total" at column 3 renamed to "Total_3"`,
+			},
+		},
+		{
+			name:        "whitespace and BOM are trimmed before comparing",
+			mode:        DuplicateHeadersRename,
+			csv:         "\uFEFFDate, Date \n2024-01-01,2024-01-02\n",
+			wantHeaders: []string{"Date", "Date_2"},
+			wantWarnings: []string{
+				`duplicate header "Date" at column 2 renamed to "Date_2"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser("pos", nil)
+			p.duplicateHeaders = tt.mode
+
+			result, err := p.Parse(strings.NewReader(tt.csv))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Headers, tt.wantHeaders) {
+				t.Errorf("Headers = %q, want %q", result.Headers, tt.wantHeaders)
+			}
+			if !reflect.DeepEqual(result.Warnings, tt.wantWarnings) {
+				t.Errorf("Warnings = %q, want %q", result.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestParseRenamedDuplicateKeepsBothColumns(t *testing.T) {
+	p := NewParser("pos", &MappingProfile{
+		Name:       "test",
+		ColumnMaps: map[string]string{"Date": "date", "Total": "total", "Total_2": "tax"},
+	})
+	p.duplicateHeaders = DuplicateHeadersRename
+
+	result, err := p.Parse(strings.NewReader("Date,Total,Total\n2024-01-01,100,10\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(result.Rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(result.Rows))
+	}
+	row := result.Rows[0]
+	if row.Mapped["total"] != "100" || row.Mapped["tax"] != "10" {
+		t.Errorf("Mapped = %v, want total 100 and tax 10", row.Mapped)
+	}
+}
+
+func TestParseUTF16(t *testing.T) {
+	const content = "Date\tItem\tTotal\n2024-01-01\tCrème brûlée\t12.50\n2024-01-02\tPho\t€8.00\n"
+	withBOM := utf16Bytes(content, false)
+	mapping := &MappingProfile{
+		Encoding:   "utf-16le",
+		Delimiter:  "\t",
+		ColumnMaps: map[string]string{"Date": "date", "Item": "item", "Total": "total"},
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "with BOM", data: withBOM},
+		{name: "without BOM", data: withBOM[2:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewParser("pos", mapping).Parse(strings.NewReader(string(tt.data)))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if want := []string{"Date", "Item", "Total"}; !reflect.DeepEqual(result.Headers, want) {
+				t.Errorf("Headers = %q, want %q", result.Headers, want)
+			}
+			if len(result.Rows) != 2 {
+				t.Fatalf("got %d rows, want 2", len(result.Rows))
+			}
+			if got := result.Rows[0].Raw["Item"]; got != "Crème brûlée" {
+				t.Errorf("Item = %q, want Crème brûlée", got)
+			}
+			if got := result.Rows[1].Raw["Total"]; got != "€8.00" {
+				t.Errorf("Total = %q, want €8.00", got)
+			}
+		})
+	}
+}
+
+// BenchmarkParseEach streams a generated 50,000-row POS export
+func BenchmarkParseEach(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString("Order,Date,Time,Channel,Total,Covers\n")
+	channels := []string{"Dine In", "Takeaway", "Delivery"}
+	for i := 0; i < 50000; i++ {
+		fmt.Fprintf(&sb, "A-%d,2024-01-%02d,%02d:%02d,%s,\"%d.%02d\",%d\n",
+			i, i%28+1, 8+i%14, i%60, channels[i%len(channels)], 10+i%200, i%100, 1+i%6)
+	}
+	data := sb.String()
+
+	mapping := &MappingProfile{ColumnMaps: map[string]string{
+		"Order": "external_id", "Date": "date", "Time": "time",
+		"Channel": "channel", "Total": "total", "Covers": "covers",
+	}}
+	parser := NewParser("pos", mapping)
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows := 0
+		if _, err := parser.ParseEach(strings.NewReader(data), func(ParsedRow) error {
+			rows++
+			return nil
+		}); err != nil {
+			b.Fatal(err)
+		}
+		if rows != 50000 {
+			b.Fatalf("parsed %d rows, want 50000", rows)
+		}
+	}
+}
+
+func TestUnknownSourceTypeRejected(t *testing.T) {
+	p := &Pipeline{}
+	for _, sourceType := range []string{"", "pso", "POS", "payroll "} {
+		if _, err := p.StartImport(context.Background(), ImportParams{SourceType: sourceType, File: strings.NewReader("Date,Total\n")}); !errors.Is(err, ErrUnknownSourceType) {
+			t.Errorf("StartImport(%q) error = %v, want ErrUnknownSourceType", sourceType, err)
+		}
+		if _, err := p.Preview(context.Background(), sourceType, uuid.New(), nil, strings.NewReader("Date,Total\n"), 10); !errors.Is(err, ErrUnknownSourceType) {
+			t.Errorf("Preview(%q) error = %v, want ErrUnknownSourceType", sourceType, err)
+		}
+	}
+	if !strings.Contains(ErrUnknownSourceType.Error(), "pos, sale_items, payroll") {
+		t.Errorf("ErrUnknownSourceType = %q, want the valid types listed", ErrUnknownSourceType)
+	}
+}
+
+func TestDefaultMappingsCoverValidSourceTypes(t *testing.T) {
+	mappings := DefaultMappings()
+	if len(mappings) != len(ValidSourceTypes) {
+		t.Errorf("DefaultMappings() has %d source types, want %d", len(mappings), len(ValidSourceTypes))
+	}
+	for _, sourceType := range ValidSourceTypes {
+		if !IsValidSourceType(sourceType) {
+			t.Errorf("IsValidSourceType(%q) = false", sourceType)
+		}
+		if len(mappings[sourceType]) == 0 {
+			t.Errorf("DefaultMappings()[%q] is empty", sourceType)
+		}
+	}
+}
+
+func TestPOSAmountWarnings(t *testing.T) {
+	tests := []struct {
+		name   string
+		mapped map[string]interface{}
+		want   []string
+	}{
+		{name: "sane", mapped: map[string]interface{}{"total": "110.00", "tax": "10.00", "discounts": "5.00", "comps": "0"}},
+		{name: "negative total", mapped: map[string]interface{}{"total": "-20.00"}, want: []string{"total is negative (-20.00); mark refunds with the refund column instead"}},
+		{name: "refund column", mapped: map[string]interface{}{"total": "-20.00", "refund": "Yes"}},
+		{name: "refund transaction type", mapped: map[string]interface{}{"total": "-20.00", "transaction_type": "chargeback"}},
+		{
+			name:   "negative adjustments",
+			mapped: map[string]interface{}{"total": "50.00", "tax": "-1.00", "discounts": "-2.00", "comps": "-3.00", "platform_fee": "-4.00"},
+			want:   []string{"tax is negative: -1.00", "discounts is negative: -2.00", "comps is negative: -3.00", "platform_fee is negative: -4.00"},
+		},
+		{name: "tax exceeds total", mapped: map[string]interface{}{"total": "5.00", "tax": "9.00"}, want: []string{"subtotal is negative: total 5.00 is less than tax 9.00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := posAmountWarnings(ParsedRow{Mapped: tt.mapped}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("posAmountWarnings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePOSWarnsOnNegativeTotal(t *testing.T) {
+	csv := "Date,Total,Tax,Refund\n2024-03-04,-18.00,0,\n2024-03-04,-18.00,0,true\n"
+	columns := map[string]string{"Date": "date", "Total": "total", "Tax": "tax", "Refund": "refund"}
+	result, err := NewParser("pos", &MappingProfile{ColumnMaps: columns}).Parse(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(result.Rows) != 2 {
+		t.Fatalf("parsed %d rows, want 2", len(result.Rows))
+	}
+	if warnings := result.Rows[0].Warnings; len(warnings) != 1 || !strings.HasPrefix(warnings[0], "total is negative") {
+		t.Errorf("sale row warnings = %q, want a negative total warning", warnings)
+	}
+	if warnings := result.Rows[1].Warnings; len(warnings) != 0 {
+		t.Errorf("refund row warnings = %q, want none", warnings)
+	}
+}
@@ -4,31 +4,127 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/fieldcrypt"
+	"github.com/lakehouse/restaurant-finance/internal/storage"
 )
 
+// ErrImportRolledBack is returned when an atomic import is discarded because some rows failed
+var ErrImportRolledBack = errors.New("import rolled back due to row errors")
+
+// ErrImportCancelled is returned when an import is stopped through Cancel
+var ErrImportCancelled = errors.New("import cancelled")
+
+// ErrNotRetryable is returned when retrying an import that has not failed or been interrupted
+var ErrNotRetryable = errors.New("only failed or interrupted imports can be retried")
+
+// ErrUploadNotStored is returned when an import's original file was not kept
+var ErrUploadNotStored = errors.New("original upload is not available; re-upload the file")
+
+// ErrMappingNotFound is returned when an import names a mapping profile that does not exist
+var ErrMappingNotFound = errors.New("mapping not found")
+
+// ErrMappingInUse is returned when deleting a mapping profile that imports still reference
+var ErrMappingInUse = errors.New("mapping is used")
+
+// ErrMappingSourceMismatch is returned when an import's mapping profile was built for another source type
+var ErrMappingSourceMismatch = errors.New("mapping source_type does not match import source_type")
+
+// ErrIdempotentReplay is returned with the original job when an import
+// request repeats an idempotency key seen within the TTL
+var ErrIdempotentReplay = errors.New("import already created for this idempotency key")
+
+// ErrIdempotencyKeyInUse is returned when a request with the same idempotency
+// key is still being handled
+var ErrIdempotencyKeyInUse = errors.New("a request with this idempotency key is in progress")
+
+// ErrInvalidConflictStrategy is returned when an import names an unknown conflict strategy
+var ErrInvalidConflictStrategy = errors.New("conflict_strategy must be update, skip or reject")
+
+// Conflict strategies decide what happens when a POS row is imported again
+const (
+	ConflictUpdate = "update" // overwrite the stored sale
+	ConflictSkip   = "skip"   // keep the stored sale and warn
+	ConflictReject = "reject" // keep the stored sale and record the row as an error
+)
+
+// ValidConflictStrategy reports whether s is a known conflict strategy
+func ValidConflictStrategy(s string) bool {
+	return s == ConflictUpdate || s == ConflictSkip || s == ConflictReject
+}
+
+// localTime reads a wall-clock time from a file as local time at the job's
+// location, so occurred_at is the real instant the sale happened
+func (j *ImportJob) localTime(t time.Time) time.Time {
+	if j.loc == nil {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), j.loc)
+}
+
+// rowWarning is a note about a row that was applied successfully but should
+// be reviewed; it is recorded as a warning anomaly rather than a row error
+type rowWarning string
+
+func (w rowWarning) Error() string { return string(w) }
+
+// errTxAborted marks failures that leave the import transaction unusable
+var errTxAborted = errors.New("import transaction aborted")
+
+// rowExecutor is satisfied by both the pool and a transaction so row
+// processors can run inside the import transaction
+type rowExecutor interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// mappingGetter loads mapping profiles; MappingStore satisfies it
+type mappingGetter interface {
+	GetByID(ctx context.Context, id, locationID uuid.UUID) (*MappingProfile, error)
+}
+
+// idempotentJobs finds the job an idempotency key started; ImportStore satisfies it
+type idempotentJobs interface {
+	GetByIdempotencyKey(ctx context.Context, locationID uuid.UUID, key string, since time.Time) (*ImportJob, error)
+}
+
 // ImportJob represents an import job with its status and results
 type ImportJob struct {
-	ID             uuid.UUID  `json:"id"`
-	SourceType     string     `json:"source_type"`
-	Status         string     `json:"status"` // pending, processing, completed, failed
-	FileName       string     `json:"file_name"`
-	FileHash       string     `json:"file_hash"`
-	TotalRows      int        `json:"total_rows"`
-	ProcessedRows  int        `json:"processed_rows"`
-	ErrorRows      int        `json:"error_rows"`
-	LocationID     uuid.UUID  `json:"location_id"`
-	MappingID      *uuid.UUID `json:"mapping_id,omitempty"`
-	CreatedByID    uuid.UUID  `json:"created_by_id"`
-	CreatedAt      time.Time  `json:"created_at"`
-	CompletedAt    *time.Time `json:"completed_at,omitempty"`
-	ErrorMessage   string     `json:"error_message,omitempty"`
+	ID               uuid.UUID  `json:"id"`
+	SourceType       string     `json:"source_type"`
+	Status           string     `json:"status"` // pending, processing, completed, failed, cancelled
+	FileName         string     `json:"file_name"`
+	FileHash         string     `json:"file_hash"`
+	FilePath         string     `json:"file_path,omitempty"`
+	TotalRows        int        `json:"total_rows"`
+	ProcessedRows    int        `json:"processed_rows"`
+	ErrorRows        int        `json:"error_rows"`
+	LocationID       uuid.UUID  `json:"location_id"`
+	MappingID        *uuid.UUID `json:"mapping_id,omitempty"`
+	Atomic           bool       `json:"atomic"`            // all-or-nothing: any row error rolls back the import
+	ConflictStrategy string     `json:"conflict_strategy"` // update, skip or reject for POS rows already imported
+	IdempotencyKey   string     `json:"-"`                 // client key that started the job, empty if none
+	CreatedByID      uuid.UUID  `json:"created_by_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	ErrorMessage     string     `json:"error_message,omitempty"`
+	DataStart        *time.Time `json:"data_start,omitempty"` // first business day touched by the import's rows
+	DataEnd          *time.Time `json:"data_end,omitempty"`
+
+	loc *time.Location // the location's timezone, set while the job is processed
 }
 
 // ImportAnomaly represents an anomaly or issue detected during import
@@ -42,30 +138,140 @@ type ImportAnomaly struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// PipelineConfig holds tunable import pipeline behavior
+type PipelineConfig struct {
+	AnomalyCap   int           // Max stored anomalies per kind of problem; 0 stores all
+	MaxRetries   int           // Retries for transient DB errors per row
+	RetryBackoff time.Duration // Initial backoff between retries, doubled each attempt
+	// StreamThreshold is the file size in bytes above which rows are parsed and
+	// applied one at a time instead of being loaded into memory first
+	StreamThreshold int64
+	// DuplicateHeaders is how repeated header names are handled: error (fail the
+	// import) or rename (keep every column and record a warning anomaly)
+	DuplicateHeaders string
+	// MangledNumbers is how values a spreadsheet wrote in scientific notation
+	// are handled: warn (record a warning anomaly) or error (reject the row)
+	MangledNumbers string
+	// EnforceMappingSourceType rejects imports whose mapping profile was
+	// created for a different source type
+	EnforceMappingSourceType bool
+	// InventoryMode is how a recount of an item on the same day is stored:
+	// replace (overwrite the day's snapshot) or append (also keep every count)
+	InventoryMode string
+	// JobTimeout bounds how long an import run with Go may process before it
+	// is stopped and marked failed; zero means no limit
+	JobTimeout time.Duration
+	// FieldCipher encrypts the payroll fields named in EncryptedFields before
+	// they are stored; nil stores them in plain
+	FieldCipher     *fieldcrypt.Cipher
+	EncryptedFields []string // tax_withheld, superannuation; empty encrypts both
+	// CacheLookups keeps channel and daypart IDs in memory for the length of
+	// an import instead of querying them for every row
+	CacheLookups bool
+	// IdempotencyTTL is how long an import's idempotency key returns the
+	// original job to a retried request; 0 ignores keys
+	IdempotencyTTL time.Duration
+}
+
+// How inventory imports store same-day recounts
+const (
+	InventoryModeReplace = "replace" // keep only the latest count per item and day (default)
+	InventoryModeAppend  = "append"  // also record every count in inventory_counts
+)
+
 // Pipeline handles the import process
 type Pipeline struct {
 	db           *pgxpool.Pool
 	store        *ImportStore
-	mappingStore *MappingStore
+	mappingStore mappingGetter
+	keyedJobs    idempotentJobs
+	files        *storage.FileStorage
+	cfg          PipelineConfig
+
+	mu              sync.Mutex
+	running         map[uuid.UUID]*runningImport // jobs this process is currently applying
+	idempotencyKeys map[string]bool              // location:key pairs of imports being started
+	jobs            *jobManager                  // imports started with Go
+}
+
+// runningImport tracks an import this process is applying
+type runningImport struct {
+	cancel context.CancelCauseFunc
+	rows   atomic.Int64 // rows applied so far
 }
 
-// NewPipeline creates a new import pipeline
-func NewPipeline(db *pgxpool.Pool) *Pipeline {
+// NewPipeline creates a new import pipeline. When files is nil, uploads are
+// not kept and failed imports cannot be retried.
+func NewPipeline(db *pgxpool.Pool, files *storage.FileStorage, cfg PipelineConfig) *Pipeline {
+	store := NewImportStore(db)
 	return &Pipeline{
-		db:           db,
-		store:        NewImportStore(db),
-		mappingStore: NewMappingStore(db),
+		db:              db,
+		store:           store,
+		mappingStore:    NewMappingStore(db),
+		keyedJobs:       store,
+		files:           files,
+		cfg:             cfg,
+		running:         make(map[uuid.UUID]*runningImport),
+		idempotencyKeys: make(map[string]bool),
+		jobs:            newJobManager(),
 	}
 }
 
+// Go runs fn in a goroutine with a context independent of the caller's, so an
+// import keeps going after the request that started it returns. The context
+// ends when Drain gives up waiting or after the configured job timeout.
+func (p *Pipeline) Go(fn func(ctx context.Context)) {
+	p.jobs.start(p.cfg.JobTimeout, fn)
+}
+
 // StartImport creates a new import job and begins processing
 func (p *Pipeline) StartImport(ctx context.Context, params ImportParams) (*ImportJob, error) {
-	// Calculate file hash
-	hash := sha256.New()
-	if _, err := io.Copy(hash, params.File); err != nil {
-		return nil, fmt.Errorf("failed to hash file: %w", err)
+	if !IsValidSourceType(params.SourceType) {
+		return nil, ErrUnknownSourceType
+	}
+	if params.IdempotencyKey != "" && p.cfg.IdempotencyTTL > 0 {
+		release, err := p.claimIdempotencyKey(params.LocationID, params.IdempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		since := time.Now().Add(-p.cfg.IdempotencyTTL)
+		existing, err := p.keyedJobs.GetByIdempotencyKey(ctx, params.LocationID, params.IdempotencyKey, since)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		if existing != nil {
+			return existing, ErrIdempotentReplay
+		}
+	} else {
+		params.IdempotencyKey = ""
+	}
+	if params.ConflictStrategy == "" {
+		params.ConflictStrategy = ConflictUpdate
+	}
+	if !ValidConflictStrategy(params.ConflictStrategy) {
+		return nil, ErrInvalidConflictStrategy
+	}
+	if _, err := p.loadMapping(ctx, params.SourceType, params.LocationID, params.MappingID); err != nil {
+		return nil, err
+	}
+
+	// Calculate file hash, keeping the upload when storage is configured
+	var fileHash, filePath string
+	if p.files != nil {
+		var err error
+		fileHash, filePath, err = p.files.SaveUpload(params.FileName, params.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store file: %w", err)
+		}
+	} else {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, params.File); err != nil {
+			return nil, fmt.Errorf("failed to hash file: %w", err)
+		}
+		fileHash = hex.EncodeToString(hash.Sum(nil))
 	}
-	fileHash := hex.EncodeToString(hash.Sum(nil))
 
 	// Check for duplicate import (idempotency)
 	existingJob, err := p.store.GetByFileHash(ctx, fileHash, params.LocationID)
@@ -77,15 +283,19 @@ func (p *Pipeline) StartImport(ctx context.Context, params ImportParams) (*Impor
 
 	// Create import job
 	job := &ImportJob{
-		ID:          uuid.New(),
-		SourceType:  params.SourceType,
-		Status:      "pending",
-		FileName:    params.FileName,
-		FileHash:    fileHash,
-		LocationID:  params.LocationID,
-		MappingID:   params.MappingID,
-		CreatedByID: params.UserID,
-		CreatedAt:   time.Now(),
+		ID:               uuid.New(),
+		SourceType:       params.SourceType,
+		Status:           "pending",
+		FileName:         params.FileName,
+		FileHash:         fileHash,
+		FilePath:         filePath,
+		LocationID:       params.LocationID,
+		MappingID:        params.MappingID,
+		Atomic:           params.Atomic,
+		ConflictStrategy: params.ConflictStrategy,
+		IdempotencyKey:   params.IdempotencyKey,
+		CreatedByID:      params.UserID,
+		CreatedAt:        time.Now(),
 	}
 
 	if err := p.store.CreateJob(ctx, job); err != nil {
@@ -95,8 +305,161 @@ func (p *Pipeline) StartImport(ctx context.Context, params ImportParams) (*Impor
 	return job, nil
 }
 
-// ProcessImport processes an import job
-func (p *Pipeline) ProcessImport(ctx context.Context, jobID uuid.UUID, fileReader io.Reader) error {
+// claimIdempotencyKey marks a key as being handled so a concurrent retry gets
+// ErrIdempotencyKeyInUse instead of also creating a job. The returned func
+// releases the key.
+func (p *Pipeline) claimIdempotencyKey(locationID uuid.UUID, key string) (func(), error) {
+	k := locationID.String() + ":" + key
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.idempotencyKeys[k] {
+		return nil, ErrIdempotencyKeyInUse
+	}
+	p.idempotencyKeys[k] = true
+	return func() {
+		p.mu.Lock()
+		delete(p.idempotencyKeys, k)
+		p.mu.Unlock()
+	}, nil
+}
+
+// loadMapping fetches an import's mapping profile, verifying it exists and,
+// when enforced, was built for the same source type, so POS validators never
+// run against a payroll layout. A nil mappingID returns a nil profile.
+func (p *Pipeline) loadMapping(ctx context.Context, sourceType string, locationID uuid.UUID, mappingID *uuid.UUID) (*MappingProfile, error) {
+	if mappingID == nil {
+		return nil, nil
+	}
+	mapping, err := p.mappingStore.GetByID(ctx, *mappingID, locationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMappingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mapping: %w", err)
+	}
+	if p.cfg.EnforceMappingSourceType && mapping.SourceType != sourceType {
+		return nil, fmt.Errorf("%w: mapping %q is for %s files, not %s", ErrMappingSourceMismatch, mapping.Name, mapping.SourceType, sourceType)
+	}
+	return mapping, nil
+}
+
+// newParser creates a parser with the pipeline's header and number handling
+func (p *Pipeline) newParser(sourceType string, mapping *MappingProfile) *Parser {
+	parser := NewParser(sourceType, mapping)
+	if p.cfg.DuplicateHeaders != "" {
+		parser.duplicateHeaders = p.cfg.DuplicateHeaders
+	}
+	if p.cfg.MangledNumbers != "" {
+		parser.mangledNumbers = p.cfg.MangledNumbers
+	}
+	return parser
+}
+
+// PrepareRetry readies a failed or interrupted import to run again from its stored upload:
+// it clears the job's anomalies and row counts and opens the original file.
+// The caller passes the file and its size to ProcessImport and closes it.
+func (p *Pipeline) PrepareRetry(ctx context.Context, jobID uuid.UUID) (*ImportJob, *os.File, int64, error) {
+	job, err := p.store.GetJobByID(ctx, jobID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if job.Status != "failed" && job.Status != "interrupted" {
+		return nil, nil, 0, ErrNotRetryable
+	}
+	file, size, err := p.reopenUpload(ctx, job)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return job, file, size, nil
+}
+
+// reopenUpload opens a job's stored upload and resets the job to pending with
+// its anomalies and row counts cleared, ready to be processed again
+func (p *Pipeline) reopenUpload(ctx context.Context, job *ImportJob) (*os.File, int64, error) {
+	if p.files == nil || job.FilePath == "" {
+		return nil, 0, ErrUploadNotStored
+	}
+
+	file, err := p.files.OpenUpload(job.FileHash, job.FileName)
+	if err != nil {
+		return nil, 0, ErrUploadNotStored
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	if err := p.store.ResetJob(ctx, job.ID); err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	job.Status = "pending"
+	job.TotalRows, job.ProcessedRows, job.ErrorRows = 0, 0, 0
+	job.CompletedAt = nil
+	job.ErrorMessage = ""
+	job.DataStart, job.DataEnd = nil, nil
+
+	return file, info.Size(), nil
+}
+
+// isActive reports whether this process is applying the job right now
+func (p *Pipeline) isActive(jobID uuid.UUID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.running[jobID]
+	return ok
+}
+
+// Progress returns how many rows of an import this process has applied so
+// far. Returns false if the job is not running here.
+func (p *Pipeline) Progress(jobID uuid.UUID) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	run, ok := p.running[jobID]
+	if !ok {
+		return 0, false
+	}
+	return int(run.rows.Load()), true
+}
+
+// Cancel stops an import this process is applying. The job is marked
+// cancelled once its row loop notices, and none of its rows are kept.
+// Returns false if the job is not running here.
+func (p *Pipeline) Cancel(jobID uuid.UUID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	run, ok := p.running[jobID]
+	if ok {
+		run.cancel(ErrImportCancelled)
+	}
+	return ok
+}
+
+// ShouldStream reports whether a file of the given size (in bytes, or -1 when
+// unknown) is processed in streaming mode
+func (p *Pipeline) ShouldStream(size int64) bool {
+	return size < 0 || (p.cfg.StreamThreshold > 0 && size > p.cfg.StreamThreshold)
+}
+
+// ProcessImport processes an import job. size is the file length in bytes, or
+// -1 when unknown; files above the stream threshold are parsed and applied row
+// by row rather than loaded into memory first. Callers running it in the
+// background should do so with Go rather than pass the request context; Cancel
+// stops it between rows and discards the rows applied so far.
+func (p *Pipeline) ProcessImport(ctx context.Context, jobID uuid.UUID, fileReader io.Reader, size int64) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	run := &runningImport{cancel: cancel}
+	p.mu.Lock()
+	p.running[jobID] = run
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.running, jobID)
+		p.mu.Unlock()
+		cancel(nil)
+	}()
+
 	// Update job status to processing
 	if err := p.store.UpdateJobStatus(ctx, jobID, "processing", ""); err != nil {
 		return err
@@ -107,79 +470,163 @@ func (p *Pipeline) ProcessImport(ctx context.Context, jobID uuid.UUID, fileReade
 	if err != nil {
 		return err
 	}
+	if job.loc, err = p.locationTimezone(ctx, job.LocationID); err != nil {
+		p.store.UpdateJobStatus(ctx, jobID, "failed", fmt.Sprintf("failed to load location timezone: %v", err))
+		return err
+	}
 
 	// Get mapping if specified
 	var mapping *MappingProfile
 	if job.MappingID != nil {
-		mapping, err = p.mappingStore.GetByID(ctx, *job.MappingID)
+		mapping, err = p.mappingStore.GetByID(ctx, *job.MappingID, job.LocationID)
 		if err != nil {
 			p.store.UpdateJobStatus(ctx, jobID, "failed", fmt.Sprintf("failed to load mapping: %v", err))
 			return err
 		}
 	}
 
-	// Parse the file
-	parser := NewParser(job.SourceType, mapping)
-	result, err := parser.Parse(fileReader)
+	// Apply rows in one transaction so a crash or fatal error never leaves the
+	// import half-applied. Each row runs in its own savepoint so a bad row can
+	// be recorded as an anomaly without aborting the rest of the batch.
+	tx, err := p.db.Begin(ctx)
 	if err != nil {
-		p.store.UpdateJobStatus(ctx, jobID, "failed", fmt.Sprintf("failed to parse file: %v", err))
+		p.store.UpdateJobStatus(ctx, jobID, "failed", fmt.Sprintf("failed to begin transaction: %v", err))
 		return err
 	}
+	defer tx.Rollback(ctx)
 
-	// Update job with row counts
-	job.TotalRows = result.TotalRows
-	job.ErrorRows = result.ErrorRows
-
-	// Process rows
-	var processedRows int
-	for _, row := range result.Rows {
+	anomalies := newAnomalyRecorder(p.store, jobID, p.cfg.AnomalyCap)
+	var lookups *lookupCache
+	if p.cfg.CacheLookups {
+		lookups = newLookupCache()
+	}
+	var processedRows, failedRows int
+	applyRow := func(row ParsedRow) error {
+		if ctx.Err() != nil {
+			return ErrImportCancelled
+		}
+		raw := rowSnapshot(row.Raw)
+		for _, msg := range row.Warnings {
+			anomalies.Record(ctx, row.LineNumber, "warning", msg, raw)
+		}
 		if len(row.Errors) > 0 {
 			// Record anomalies for error rows
 			for _, errMsg := range row.Errors {
-				anomaly := &ImportAnomaly{
-					ID:          uuid.New(),
-					ImportJobID: jobID,
-					LineNumber:  row.LineNumber,
-					Severity:    "error",
-					Message:     errMsg,
-					CreatedAt:   time.Now(),
-				}
-				p.store.CreateAnomaly(ctx, anomaly)
+				anomalies.Record(ctx, row.LineNumber, "error", errMsg, raw)
 			}
-			continue
-		}
-
-		// Process valid row based on source type
-		var processErr error
-		switch job.SourceType {
-		case "pos":
-			processErr = p.processPOSRow(ctx, job, row)
-		case "payroll":
-			processErr = p.processPayrollRow(ctx, job, row)
-		case "inventory":
-			processErr = p.processInventoryRow(ctx, job, row)
-		}
-
-		if processErr != nil {
-			anomaly := &ImportAnomaly{
-				ID:          uuid.New(),
-				ImportJobID: jobID,
-				LineNumber:  row.LineNumber,
-				Severity:    "error",
-				Message:     processErr.Error(),
-				CreatedAt:   time.Now(),
+			return nil
+		}
+
+		// Process valid row based on source type, retrying transient DB errors
+		processErr := p.withRetry(ctx, func() error {
+			return p.processRow(ctx, tx, job, row, lookups)
+		})
+
+		if errors.Is(processErr, errTxAborted) {
+			return fmt.Errorf("import rolled back at line %d: %w", row.LineNumber, processErr)
+		}
+		var warning rowWarning
+		if errors.As(processErr, &warning) {
+			anomalies.Record(ctx, row.LineNumber, "warning", warning.Error(), raw)
+		} else if processErr != nil {
+			anomalies.Record(ctx, row.LineNumber, "error", processErr.Error(), raw)
+			failedRows++
+			return nil
+		}
+		processedRows++
+		run.rows.Add(1)
+
+		// Track the days this import touches so aggregates can be refreshed for just those
+		if start, end, ok := rowDateSpan(job.SourceType, row.Mapped); ok {
+			if job.DataStart == nil || start.Before(*job.DataStart) {
+				job.DataStart = &start
+			}
+			if job.DataEnd == nil || end.After(*job.DataEnd) {
+				job.DataEnd = &end
 			}
-			p.store.CreateAnomaly(ctx, anomaly)
-			job.ErrorRows++
-		} else {
-			processedRows++
 		}
+		return nil
 	}
 
-	// Update job as completed
-	job.ProcessedRows = processedRows
+	// Parse the file, either fully up front or streaming one row at a time
+	parser := p.newParser(job.SourceType, mapping)
+	var result *ParseResult
+	if p.ShouldStream(size) {
+		result, err = parser.ParseEach(fileReader, applyRow)
+	} else {
+		result, err = parser.Parse(fileReader)
+		if err == nil {
+			for _, row := range result.Rows {
+				if err = applyRow(row); err != nil {
+					break
+				}
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		// The job context is done, so record the outcome with one that is not
+		statusCtx := context.WithoutCancel(ctx)
+		anomalies.Flush(statusCtx)
+		switch cause := context.Cause(ctx); {
+		case errors.Is(cause, ErrImportCancelled):
+			p.store.UpdateJobStatus(statusCtx, jobID, "cancelled", "import cancelled; no rows were applied")
+			return ErrImportCancelled
+		case errors.Is(cause, context.DeadlineExceeded):
+			p.store.UpdateJobStatus(statusCtx, jobID, "failed", fmt.Sprintf("import timed out after %s; no rows were applied", p.cfg.JobTimeout))
+			return cause
+		default:
+			// Shutdown, either by Drain or by the reaper's context ending
+			p.store.UpdateJobStatus(statusCtx, jobID, "interrupted", "import was interrupted by a server shutdown; no rows were applied, retry it")
+			return ErrImportInterrupted
+		}
+	}
+	if errors.Is(err, errTxAborted) {
+		anomalies.Flush(ctx)
+		p.store.UpdateJobStatus(ctx, jobID, "failed", err.Error())
+		return err
+	}
+	if err != nil {
+		anomalies.Flush(ctx)
+		p.store.UpdateJobStatus(ctx, jobID, "failed", fmt.Sprintf("failed to parse file: %v", err))
+		return err
+	}
+
+	// Header warnings refer to the header line; mapping coverage applies to the whole job
+	for _, warning := range result.Warnings {
+		anomalies.Record(ctx, 1, "warning", warning, "")
+	}
+	for _, warning := range result.MappingWarnings {
+		anomalies.Record(ctx, 0, "warning", warning, "")
+	}
+	anomalies.Flush(ctx)
+
+	// Update job with row counts
+	job.TotalRows = result.TotalRows
+	job.ErrorRows = result.ErrorRows + failedRows
+
 	now := time.Now()
 	job.CompletedAt = &now
+
+	// All-or-nothing imports discard every row when any row failed
+	if job.Atomic && job.ErrorRows > 0 {
+		tx.Rollback(ctx)
+		job.ProcessedRows = 0
+		job.DataStart, job.DataEnd = nil, nil
+		job.Status = "failed"
+		job.ErrorMessage = fmt.Sprintf("import rolled back: %d rows had errors", job.ErrorRows)
+		if err := p.store.UpdateJob(ctx, job); err != nil {
+			return err
+		}
+		return ErrImportRolledBack
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		p.store.UpdateJobStatus(ctx, jobID, "failed", fmt.Sprintf("failed to commit import: %v", err))
+		return err
+	}
+
+	// Update job as completed
+	job.ProcessedRows = processedRows
 	job.Status = "completed"
 
 	if err := p.store.UpdateJob(ctx, job); err != nil {
@@ -189,12 +636,84 @@ func (p *Pipeline) ProcessImport(ctx context.Context, jobID uuid.UUID, fileReade
 	return nil
 }
 
-func (p *Pipeline) processPOSRow(ctx context.Context, job *ImportJob, row ParsedRow) error {
+// locationTimezone loads the timezone sale times in a location's files are written in
+func (p *Pipeline) locationTimezone(ctx context.Context, locationID uuid.UUID) (*time.Location, error) {
+	var name string
+	if err := p.db.QueryRow(ctx, `SELECT timezone FROM locations WHERE id = $1`, locationID).Scan(&name); err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(name)
+}
+
+// processRow applies a single row inside a savepoint of the import
+// transaction. Row errors roll back only the savepoint; if the savepoint itself
+// cannot be created or rolled back the transaction is unusable and
+// errTxAborted is returned. lookups may be nil to query every lookup.
+func (p *Pipeline) processRow(ctx context.Context, tx pgx.Tx, job *ImportJob, row ParsedRow, lookups *lookupCache) error {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errTxAborted, err)
+	}
+
+	// Channels created by this row only stay cached if the row commits
+	if lookups != nil {
+		defer lookups.discard()
+	}
+
+	switch job.SourceType {
+	case "pos":
+		err = p.processPOSRow(ctx, sp, job, row, lookups)
+	case "payroll":
+		err = p.processPayrollRow(ctx, sp, job, row)
+	case "inventory":
+		err = p.processInventoryRow(ctx, sp, job, row)
+	case "purchases":
+		err = p.processPurchaseRow(ctx, sp, job, row)
+	case "expenses":
+		err = p.processExpenseRow(ctx, sp, job, row)
+	case "refunds":
+		err = p.processRefundRow(ctx, sp, job, row)
+	case "budget":
+		err = p.processBudgetRow(ctx, sp, job, row)
+	case "deposits":
+		err = p.processDepositRow(ctx, sp, job, row)
+	case "sale_items":
+		err = p.processSaleItemRow(ctx, sp, job, row)
+	}
+
+	// Warnings keep the row; commit it and pass the warning on
+	var warning rowWarning
+	if errors.As(err, &warning) {
+		if err := sp.Commit(ctx); err != nil {
+			return fmt.Errorf("%w: %v", errTxAborted, err)
+		}
+		if lookups != nil {
+			lookups.commit()
+		}
+		return warning
+	}
+	if err != nil {
+		if rbErr := sp.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("%w: %v", errTxAborted, rbErr)
+		}
+		return err
+	}
+	if err := sp.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: %v", errTxAborted, err)
+	}
+	if lookups != nil {
+		lookups.commit()
+	}
+	return nil
+}
+
+func (p *Pipeline) processPOSRow(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow, lookups *lookupCache) error {
 	dateStr, _ := row.Mapped["date"].(string)
 	date, err := parseDate(dateStr)
 	if err != nil {
 		return fmt.Errorf("invalid date: %w", err)
 	}
+	occurredAt := job.localTime(date)
 
 	totalStr, _ := row.Mapped["total"].(string)
 	total, err := parseAmount(totalStr)
@@ -202,6 +721,25 @@ func (p *Pipeline) processPOSRow(ctx context.Context, job *ImportJob, row Parsed
 		return fmt.Errorf("invalid total: %w", err)
 	}
 
+	// Refunds are recorded against the refunds table rather than as a
+	// negative sale, so revenue nets them out the same way as a refunds import
+	if IsPOSRefund(row) {
+		return p.recordRefund(ctx, db, job, row, date, total)
+	}
+
+	// Gift card issuance is a liability, not revenue, so it only goes to the
+	// ledger. A redemption is ordinary revenue paid from a gift card: it is
+	// recorded as a sale and also reduces the outstanding liability.
+	txType, _ := row.Mapped["transaction_type"].(string)
+	if giftCardType := NormalizeTransactionType(txType); giftCardType != "" {
+		if err := p.recordGiftCard(ctx, db, job, row, giftCardType, occurredAt, total); err != nil {
+			return err
+		}
+		if giftCardType == TransactionGiftCardSale {
+			return nil
+		}
+	}
+
 	// Parse optional fields
 	var discounts, comps, tax float64
 	if v, ok := row.Mapped["discounts"].(string); ok && v != "" {
@@ -214,10 +752,19 @@ func (p *Pipeline) processPOSRow(ctx context.Context, job *ImportJob, row Parsed
 		tax, _ = parseAmount(v)
 	}
 
+	// Delivery platform commission; either field name may be mapped
+	var platformFee float64
+	for _, field := range []string{"platform_fee", "commission"} {
+		if v, ok := row.Mapped[field].(string); ok && v != "" {
+			platformFee, _ = parseAmount(v)
+			break
+		}
+	}
+
 	// Get or create channel
 	var channelID *uuid.UUID
 	if channel, ok := row.Mapped["channel"].(string); ok && channel != "" {
-		id, err := p.getOrCreateChannel(ctx, channel, job.LocationID)
+		id, err := p.getOrCreateChannel(ctx, db, lookups, channel, job.LocationID)
 		if err == nil {
 			channelID = &id
 		}
@@ -226,35 +773,55 @@ func (p *Pipeline) processPOSRow(ctx context.Context, job *ImportJob, row Parsed
 	// Get daypart based on time
 	var daypartID *uuid.UUID
 	if timeStr, ok := row.Mapped["time"].(string); ok && timeStr != "" {
-		id, err := p.getDaypartForTime(ctx, timeStr)
+		id, err := p.getDaypartForTime(ctx, db, lookups, timeStr)
 		if err == nil {
 			daypartID = &id
 		}
 	}
 
-	// Upsert sale using date + location + row number as key for idempotency
-	query := `
-		INSERT INTO sales (id, location_id, channel_id, daypart_id, occurred_at, total, subtotal, tax, discounts, comps, payment_method, import_source, source_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
-		ON CONFLICT (location_id, import_source, source_id) DO UPDATE SET
+	// Upsert sale keyed on its POS order ID, so a re-exported file updates the
+	// sales it shares with an earlier import (see saleSourceID). Skip and
+	// reject leave a sale that was already imported untouched.
+	onConflict := `ON CONFLICT (location_id, import_source, source_id) DO UPDATE SET
 			total = EXCLUDED.total,
 			subtotal = EXCLUDED.subtotal,
 			tax = EXCLUDED.tax,
 			discounts = EXCLUDED.discounts,
 			comps = EXCLUDED.comps,
-			updated_at = NOW()
-	`
+			platform_fee = EXCLUDED.platform_fee,
+			discount_reason = EXCLUDED.discount_reason,
+			comp_reason = EXCLUDED.comp_reason,
+			order_type = EXCLUDED.order_type,
+			server_name = EXCLUDED.server_name,
+			external_id = EXCLUDED.external_id,
+			updated_at = NOW()`
+	if job.ConflictStrategy == ConflictSkip || job.ConflictStrategy == ConflictReject {
+		onConflict = `ON CONFLICT (location_id, import_source, source_id) DO NOTHING`
+	}
+	query := `
+		INSERT INTO sales (id, location_id, channel_id, daypart_id, occurred_at, total, subtotal, tax, discounts, comps, payment_method, import_source, source_id, discount_reason, comp_reason, order_type, server_name, external_id, platform_fee, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, NOW(), NOW())
+		` + onConflict
 
 	subtotal := total - tax
 	paymentMethod, _ := row.Mapped["payment_method"].(string)
-	sourceID := fmt.Sprintf("%s-%d", job.FileHash[:8], row.LineNumber)
+	discountReason := optionalString(row.Mapped, "discount_reason")
+	compReason := optionalString(row.Mapped, "comp_reason")
+	serverName := optionalString(row.Mapped, "server")
+	var orderType *string
+	if v, ok := row.Mapped["order_type"].(string); ok {
+		if normalized := NormalizeOrderType(v); normalized != "" {
+			orderType = &normalized
+		}
+	}
+	sourceID := saleSourceID(job, row)
 
-	_, err = p.db.Exec(ctx, query,
+	tag, err := db.Exec(ctx, query,
 		uuid.New(),
 		job.LocationID,
 		channelID,
 		daypartID,
-		date,
+		occurredAt,
 		total,
 		subtotal,
 		tax,
@@ -263,12 +830,40 @@ func (p *Pipeline) processPOSRow(ctx context.Context, job *ImportJob, row Parsed
 		paymentMethod,
 		"csv-import",
 		sourceID,
+		discountReason,
+		compReason,
+		orderType,
+		serverName,
+		optionalString(row.Mapped, "external_id"),
+		platformFee,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if tag.RowsAffected() == 0 {
+		switch job.ConflictStrategy {
+		case ConflictSkip:
+			return rowWarning(fmt.Sprintf("sale %s was already imported; kept the existing sale", sourceID))
+		case ConflictReject:
+			return fmt.Errorf("sale %s was already imported; rejected by conflict_strategy reject", sourceID)
+		}
+	}
+	return nil
+}
+
+// saleSourceID identifies a sale across imports: the POS order ID when the
+// file maps one, so re-importing an edited export matches the sales it
+// already loaded, otherwise the file hash and line number, which only match
+// if the same file is processed again
+func saleSourceID(job *ImportJob, row ParsedRow) string {
+	if externalID := optionalString(row.Mapped, "external_id"); externalID != nil {
+		return *externalID
+	}
+	return fmt.Sprintf("%s-%d", job.FileHash[:8], row.LineNumber)
 }
 
-func (p *Pipeline) processPayrollRow(ctx context.Context, job *ImportJob, row ParsedRow) error {
+func (p *Pipeline) processPayrollRow(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow) error {
 	startStr, _ := row.Mapped["period_start"].(string)
 	startDate, err := parseDate(startStr)
 	if err != nil {
@@ -288,40 +883,89 @@ func (p *Pipeline) processPayrollRow(ctx context.Context, job *ImportJob, row Pa
 	}
 
 	// Parse optional fields
-	var super, taxWithheld float64
+	var super, taxWithheld, hours float64
 	if v, ok := row.Mapped["superannuation"].(string); ok && v != "" {
 		super, _ = parseAmount(v)
 	}
+	if v, ok := row.Mapped["hours_worked"].(string); ok && v != "" {
+		hours, _ = parseAmount(v)
+	}
 	if v, ok := row.Mapped["tax_withheld"].(string); ok && v != "" {
 		taxWithheld, _ = parseAmount(v)
 	}
 
+	superPlain, superSealed, err := p.sealAmount("superannuation", super)
+	if err != nil {
+		return err
+	}
+	taxPlain, taxSealed, err := p.sealAmount("tax_withheld", taxWithheld)
+	if err != nil {
+		return err
+	}
+
 	// Upsert payroll period
 	query := `
-		INSERT INTO payroll_periods (id, location_id, start_date, end_date, labor_cost, superannuation, tax_withheld, import_source, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		INSERT INTO payroll_periods (id, location_id, start_date, end_date, labor_cost, hours, superannuation, tax_withheld,
+			superannuation_encrypted, tax_withheld_encrypted, import_source, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
 		ON CONFLICT (location_id, start_date, end_date) DO UPDATE SET
 			labor_cost = EXCLUDED.labor_cost,
+			hours = EXCLUDED.hours,
 			superannuation = EXCLUDED.superannuation,
 			tax_withheld = EXCLUDED.tax_withheld,
+			superannuation_encrypted = EXCLUDED.superannuation_encrypted,
+			tax_withheld_encrypted = EXCLUDED.tax_withheld_encrypted,
 			updated_at = NOW()
 	`
 
-	_, err = p.db.Exec(ctx, query,
+	_, err = db.Exec(ctx, query,
 		uuid.New(),
 		job.LocationID,
 		startDate,
 		endDate,
 		wages,
-		super,
-		taxWithheld,
+		hours,
+		superPlain,
+		taxPlain,
+		superSealed,
+		taxSealed,
 		"csv-import",
 	)
 
 	return err
 }
 
-func (p *Pipeline) processInventoryRow(ctx context.Context, job *ImportJob, row ParsedRow) error {
+// sealAmount returns the plain and encrypted column values for a payroll
+// field: the ciphertext with a NULL plain value when the field is configured
+// for encryption, otherwise the plain value alone
+func (p *Pipeline) sealAmount(field string, v float64) (*float64, *string, error) {
+	if !p.encrypts(field) {
+		return &v, nil, nil
+	}
+	sealed, err := p.cfg.FieldCipher.EncryptAmount(v)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encrypt %s: %w", field, err)
+	}
+	return nil, &sealed, nil
+}
+
+// encrypts reports whether a payroll field is stored encrypted
+func (p *Pipeline) encrypts(field string) bool {
+	if p.cfg.FieldCipher == nil {
+		return false
+	}
+	if len(p.cfg.EncryptedFields) == 0 {
+		return true
+	}
+	for _, f := range p.cfg.EncryptedFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Pipeline) processInventoryRow(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow) error {
 	dateStr, _ := row.Mapped["snapshot_date"].(string)
 	date, err := parseDate(dateStr)
 	if err != nil {
@@ -352,7 +996,7 @@ func (p *Pipeline) processInventoryRow(ctx context.Context, job *ImportJob, row
 
 	totalValue := qty * cost
 
-	// Upsert inventory snapshot
+	// Upsert the day's snapshot; a recount replaces the earlier count
 	query := `
 		INSERT INTO inventory_snapshots (id, location_id, snapshot_date, item_name, category, quantity, unit, unit_cost, total_value, import_source, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
@@ -365,7 +1009,7 @@ func (p *Pipeline) processInventoryRow(ctx context.Context, job *ImportJob, row
 
 	category, _ := row.Mapped["category"].(string)
 
-	_, err = p.db.Exec(ctx, query,
+	_, err = db.Exec(ctx, query,
 		uuid.New(),
 		job.LocationID,
 		date,
@@ -377,28 +1021,241 @@ func (p *Pipeline) processInventoryRow(ctx context.Context, job *ImportJob, row
 		totalValue,
 		"csv-import",
 	)
+	if err != nil || p.cfg.InventoryMode != InventoryModeAppend {
+		return err
+	}
 
+	// Append mode also keeps every count, so same-day recounts stay auditable
+	// while the snapshot above holds the latest for valuation
+	sourceID := fmt.Sprintf("%s-%d", job.FileHash[:8], row.LineNumber)
+	_, err = db.Exec(ctx, `
+		INSERT INTO inventory_counts (id, location_id, snapshot_date, item_name, category, quantity, unit, unit_cost, total_value, import_job_id, import_source, source_id, counted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
+		ON CONFLICT (location_id, import_source, source_id) DO UPDATE SET
+			quantity = EXCLUDED.quantity,
+			unit_cost = EXCLUDED.unit_cost,
+			total_value = EXCLUDED.total_value,
+			import_job_id = EXCLUDED.import_job_id
+	`,
+		uuid.New(),
+		job.LocationID,
+		date,
+		itemName,
+		category,
+		qty,
+		unit,
+		cost,
+		totalValue,
+		job.ID,
+		"csv-import",
+		sourceID,
+	)
 	return err
 }
 
-func (p *Pipeline) getOrCreateChannel(ctx context.Context, name string, locationID uuid.UUID) (uuid.UUID, error) {
+func (p *Pipeline) processPurchaseRow(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow) error {
+	dateStr, _ := row.Mapped["date"].(string)
+	date, err := parseDate(dateStr)
+	if err != nil {
+		return fmt.Errorf("invalid date: %w", err)
+	}
+
+	supplier, _ := row.Mapped["supplier"].(string)
+	itemName, _ := row.Mapped["item_name"].(string)
+	if supplier == "" || itemName == "" {
+		return fmt.Errorf("supplier and item_name are required")
+	}
+
+	qtyStr, _ := row.Mapped["quantity"].(string)
+	qty, err := parseAmount(qtyStr)
+	if err != nil {
+		return fmt.Errorf("invalid quantity: %w", err)
+	}
+
+	costStr, _ := row.Mapped["unit_cost"].(string)
+	cost, err := parseAmount(costStr)
+	if err != nil {
+		return fmt.Errorf("invalid unit_cost: %w", err)
+	}
+
+	// Prefer the invoice line total when provided, otherwise derive it
+	total := qty * cost
+	if v, ok := row.Mapped["total"].(string); ok && v != "" {
+		total, err = parseAmount(v)
+		if err != nil {
+			return fmt.Errorf("invalid total: %w", err)
+		}
+	}
+
+	supplierID, err := p.getOrCreateSupplier(ctx, db, supplier, job.LocationID)
+	if err != nil {
+		return fmt.Errorf("failed to get supplier: %w", err)
+	}
+
+	// Invoice lines are keyed on supplier + invoice number + item so the same
+	// invoice imported from another file updates in place; lines without an
+	// invoice number fall back to file hash + row number
+	conflict := `(location_id, import_source, source_id)`
+	invoice, _ := row.Mapped["invoice_number"].(string)
+	invoice = strings.TrimSpace(invoice)
+	if invoice != "" {
+		conflict = `(location_id, supplier_id, invoice_number, item_name) WHERE invoice_number IS NOT NULL`
+	}
+	query := `
+		INSERT INTO purchases (id, location_id, purchase_date, supplier, supplier_id, invoice_number, item_name, category, quantity, unit_cost, total, import_source, source_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+		ON CONFLICT ` + conflict + ` DO UPDATE SET
+			purchase_date = EXCLUDED.purchase_date,
+			supplier = EXCLUDED.supplier,
+			supplier_id = EXCLUDED.supplier_id,
+			invoice_number = EXCLUDED.invoice_number,
+			item_name = EXCLUDED.item_name,
+			category = EXCLUDED.category,
+			quantity = EXCLUDED.quantity,
+			unit_cost = EXCLUDED.unit_cost,
+			total = EXCLUDED.total,
+			updated_at = NOW()
+	`
+
+	category, _ := row.Mapped["category"].(string)
+	sourceID := fmt.Sprintf("%s-%d", job.FileHash[:8], row.LineNumber)
+
+	_, err = db.Exec(ctx, query,
+		uuid.New(),
+		job.LocationID,
+		date,
+		supplier,
+		supplierID,
+		invoice,
+		itemName,
+		category,
+		qty,
+		cost,
+		total,
+		"csv-import",
+		sourceID,
+	)
+
+	return err
+}
+
+func (p *Pipeline) processExpenseRow(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow) error {
+	dateStr, _ := row.Mapped["date"].(string)
+	date, err := parseDate(dateStr)
+	if err != nil {
+		return fmt.Errorf("invalid date: %w", err)
+	}
+
+	category, _ := row.Mapped["category"].(string)
+	if category == "" {
+		return fmt.Errorf("category is required")
+	}
+
+	amountStr, _ := row.Mapped["amount"].(string)
+	amount, err := parseAmount(amountStr)
+	if err != nil {
+		return fmt.Errorf("invalid amount: %w", err)
+	}
+
+	// Upsert expense using file hash + row number as key for idempotency
+	query := `
+		INSERT INTO operating_expenses (id, location_id, expense_date, category, description, amount, import_source, source_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		ON CONFLICT (location_id, import_source, source_id) DO UPDATE SET
+			expense_date = EXCLUDED.expense_date,
+			category = EXCLUDED.category,
+			description = EXCLUDED.description,
+			amount = EXCLUDED.amount,
+			updated_at = NOW()
+	`
+
+	sourceID := fmt.Sprintf("%s-%d", job.FileHash[:8], row.LineNumber)
+
+	_, err = db.Exec(ctx, query,
+		uuid.New(),
+		job.LocationID,
+		date,
+		category,
+		optionalString(row.Mapped, "description"),
+		amount,
+		"csv-import",
+		sourceID,
+	)
+
+	return err
+}
+
+// getOrCreateChannel resolves a channel by display name, creating it for the
+// location if it does not exist. An import's rows all share one location, so
+// lookups are cached by name alone.
+func (p *Pipeline) getOrCreateChannel(ctx context.Context, db rowExecutor, lookups *lookupCache, name string, locationID uuid.UUID) (uuid.UUID, error) {
+	if lookups != nil {
+		if id, ok := lookups.channel(name); ok {
+			return id, nil
+		}
+	}
+
 	// Try to find existing channel
 	var id uuid.UUID
 	query := `SELECT id FROM service_channels WHERE LOWER(display_name) = LOWER($1) AND location_id = $2`
-	err := p.db.QueryRow(ctx, query, name, locationID).Scan(&id)
+	err := db.QueryRow(ctx, query, name, locationID).Scan(&id)
 	if err == nil {
+		if lookups != nil {
+			lookups.addChannel(name, id, false)
+		}
 		return id, nil
 	}
 
-	// Create new channel
-	id = uuid.New()
+	// Create new channel. A concurrent import may create the same one first;
+	// the insert then waits for it and the re-select picks up its row.
 	code := slugify(name)
-	insertQuery := `INSERT INTO service_channels (id, code, display_name, location_id, created_at, updated_at) VALUES ($1, $2, $3, $4, NOW(), NOW())`
-	_, err = p.db.Exec(ctx, insertQuery, id, code, name, locationID)
-	return id, err
+	insertQuery := `
+		INSERT INTO service_channels (id, code, display_name, location_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (location_id, LOWER(display_name)) DO NOTHING
+		RETURNING id
+	`
+	err = db.QueryRow(ctx, insertQuery, uuid.New(), code, name, locationID).Scan(&id)
+	created := err == nil
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = db.QueryRow(ctx, query, name, locationID).Scan(&id)
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if lookups != nil {
+		lookups.addChannel(name, id, created)
+	}
+	return id, nil
+}
+
+// getOrCreateSupplier returns the location's supplier with the given name,
+// matched case-insensitively, creating it on first use
+func (p *Pipeline) getOrCreateSupplier(ctx context.Context, db rowExecutor, name string, locationID uuid.UUID) (uuid.UUID, error) {
+	var id uuid.UUID
+	query := `SELECT id FROM suppliers WHERE LOWER(name) = LOWER($1) AND location_id = $2`
+	if err := db.QueryRow(ctx, query, name, locationID).Scan(&id); err == nil {
+		return id, nil
+	}
+
+	// As with channels, a concurrent import may insert the supplier first
+	insertQuery := `
+		INSERT INTO suppliers (id, location_id, name, code, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (location_id, LOWER(name)) DO NOTHING
+		RETURNING id
+	`
+	err := db.QueryRow(ctx, insertQuery, uuid.New(), locationID, name, slugify(name)).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = db.QueryRow(ctx, query, name, locationID).Scan(&id)
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
 }
 
-func (p *Pipeline) getDaypartForTime(ctx context.Context, timeStr string) (uuid.UUID, error) {
+func (p *Pipeline) getDaypartForTime(ctx context.Context, db rowExecutor, lookups *lookupCache, timeStr string) (uuid.UUID, error) {
 	// Parse time
 	t, err := time.Parse("15:04", timeStr)
 	if err != nil {
@@ -408,13 +1265,60 @@ func (p *Pipeline) getDaypartForTime(ctx context.Context, timeStr string) (uuid.
 		}
 	}
 
+	if lookups != nil {
+		id, ok, err := lookups.daypart(ctx, p.db, t)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if !ok {
+			return uuid.Nil, pgx.ErrNoRows
+		}
+		return id, nil
+	}
+
 	// Find daypart that contains this time
 	query := `SELECT id FROM dayparts WHERE start_time <= $1 AND end_time > $1 LIMIT 1`
 	var id uuid.UUID
-	err = p.db.QueryRow(ctx, query, t.Format("15:04:05")).Scan(&id)
+	err = db.QueryRow(ctx, query, t.Format("15:04:05")).Scan(&id)
 	return id, err
 }
 
+// optionalString returns a pointer to a mapped string field, or nil when it is absent or blank
+// rowDateSpan returns the business days a row contributes to KPI aggregates.
+// Sources that do not feed the aggregates report ok=false.
+func rowDateSpan(sourceType string, mapped map[string]interface{}) (start, end time.Time, ok bool) {
+	var startField, endField string
+	switch sourceType {
+	case "pos", "sale_items", "expenses", "refunds":
+		startField, endField = "date", "date"
+	case "payroll":
+		startField, endField = "period_start", "period_end"
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+
+	startStr, _ := mapped[startField].(string)
+	endStr, _ := mapped[endField].(string)
+	start, err := parseDate(startStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	end, err = parseDate(endStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+func optionalString(mapped map[string]interface{}, field string) *string {
+	v, ok := mapped[field].(string)
+	if !ok || strings.TrimSpace(v) == "" {
+		return nil
+	}
+	v = strings.TrimSpace(v)
+	return &v
+}
+
 func slugify(s string) string {
 	s = strings.ToLower(s)
 	s = strings.ReplaceAll(s, " ", "-")
@@ -430,4 +1334,10 @@ type ImportParams struct {
 	LocationID uuid.UUID
 	MappingID  *uuid.UUID
 	UserID     uuid.UUID
+	Atomic     bool // roll back the whole import if any row fails
+	// ConflictStrategy handles POS rows already imported; empty means update
+	ConflictStrategy string
+	// IdempotencyKey identifies a client request so a retry returns the
+	// original job instead of starting another
+	IdempotencyKey string
 }
@@ -0,0 +1,836 @@
+package imports
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/lakehouse/restaurant-finance/internal/fieldcrypt"
+)
+
+// fakeMappings serves mapping profiles from memory, scoped to their location
+// like MappingStore
+type fakeMappings map[uuid.UUID]*MappingProfile
+
+func (f fakeMappings) GetByID(ctx context.Context, id, locationID uuid.UUID) (*MappingProfile, error) {
+	m, ok := f[id]
+	if !ok || m.LocationID != locationID {
+		return nil, pgx.ErrNoRows
+	}
+	return m, nil
+}
+
+func TestLoadMapping(t *testing.T) {
+	payrollID := uuid.New()
+	posID := uuid.New()
+	otherID := uuid.New()
+	missingID := uuid.New()
+	locationID := uuid.New()
+	mappings := fakeMappings{
+		payrollID: {ID: payrollID, Name: "Xero payroll", SourceType: "payroll", LocationID: locationID},
+		posID:     {ID: posID, Name: "Square sales", SourceType: "pos", LocationID: locationID},
+		otherID:   {ID: otherID, Name: "Other venue sales", SourceType: "pos", LocationID: uuid.New()},
+	}
+
+	tests := []struct {
+		name        string
+		enforce     bool
+		sourceType  string
+		mappingID   *uuid.UUID
+		wantMapping *MappingProfile
+		wantErr     error
+	}{
+		{name: "no mapping", enforce: true, sourceType: "pos"},
+		{name: "matching source type", enforce: true, sourceType: "pos", mappingID: &posID, wantMapping: mappings[posID]},
+		{name: "mismatched source type", enforce: true, sourceType: "pos", mappingID: &payrollID, wantErr: ErrMappingSourceMismatch},
+		{name: "mismatch allowed when not enforced", sourceType: "pos", mappingID: &payrollID, wantMapping: mappings[payrollID]},
+		{name: "unknown mapping", enforce: true, sourceType: "pos", mappingID: &missingID, wantErr: ErrMappingNotFound},
+		{name: "another location's mapping", enforce: true, sourceType: "pos", mappingID: &otherID, wantErr: ErrMappingNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pipeline{mappingStore: mappings, cfg: PipelineConfig{EnforceMappingSourceType: tt.enforce}}
+			mapping, err := p.loadMapping(context.Background(), tt.sourceType, locationID, tt.mappingID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("loadMapping() error = %v, want %v", err, tt.wantErr)
+			}
+			if mapping != tt.wantMapping {
+				t.Errorf("loadMapping() = %v, want %v", mapping, tt.wantMapping)
+			}
+		})
+	}
+}
+
+// fakeExecutor records statements and their arguments and reports
+// rowsAffected for each insert
+type fakeExecutor struct {
+	rowsAffected int
+	statements   []string
+	args         [][]interface{}
+}
+
+func (f *fakeExecutor) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	f.statements = append(f.statements, sql)
+	f.args = append(f.args, args)
+	if f.rowsAffected == 0 {
+		return pgconn.NewCommandTag("INSERT 0 0"), nil
+	}
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (f *fakeExecutor) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	panic("unexpected QueryRow: " + sql)
+}
+
+func TestProcessPOSRowConflictStrategy(t *testing.T) {
+	tests := []struct {
+		strategy     string
+		rowsAffected int
+		wantUpdate   bool
+		wantWarning  bool
+		wantErr      bool
+	}{
+		{strategy: ConflictUpdate, rowsAffected: 1, wantUpdate: true},
+		{strategy: ConflictSkip, rowsAffected: 1},
+		{strategy: ConflictReject, rowsAffected: 1},
+		{strategy: ConflictSkip, rowsAffected: 0, wantWarning: true},
+		{strategy: ConflictReject, rowsAffected: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		name := tt.strategy
+		if tt.rowsAffected == 0 {
+			name += " existing sale"
+		}
+		t.Run(name, func(t *testing.T) {
+			p := &Pipeline{}
+			db := &fakeExecutor{rowsAffected: tt.rowsAffected}
+			job := &ImportJob{LocationID: uuid.New(), FileHash: "abcdef0123456789", ConflictStrategy: tt.strategy}
+			row := ParsedRow{LineNumber: 2, Mapped: map[string]interface{}{"date": "2024-01-01", "total": "100"}}
+
+			err := p.processPOSRow(context.Background(), db, job, row, nil)
+
+			var warning rowWarning
+			isWarning := errors.As(err, &warning)
+			if isWarning != tt.wantWarning {
+				t.Errorf("processPOSRow() error = %v, want warning %v", err, tt.wantWarning)
+			}
+			if gotErr := err != nil && !isWarning; gotErr != tt.wantErr {
+				t.Errorf("processPOSRow() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(db.statements) != 1 {
+				t.Fatalf("ran %d statements, want 1", len(db.statements))
+			}
+			if gotUpdate := strings.Contains(db.statements[0], "DO UPDATE"); gotUpdate != tt.wantUpdate {
+				t.Errorf("statement updates existing sale = %v, want %v", gotUpdate, tt.wantUpdate)
+			}
+		})
+	}
+}
+
+func TestValidConflictStrategy(t *testing.T) {
+	for _, s := range []string{ConflictUpdate, ConflictSkip, ConflictReject} {
+		if !ValidConflictStrategy(s) {
+			t.Errorf("ValidConflictStrategy(%q) = false, want true", s)
+		}
+	}
+	for _, s := range []string{"", "overwrite", "SKIP"} {
+		if ValidConflictStrategy(s) {
+			t.Errorf("ValidConflictStrategy(%q) = true, want false", s)
+		}
+	}
+}
+
+// fakeSales stands in for the sales table's (location_id, import_source,
+// source_id) unique key, applying the upsert or leaving the existing sale as
+// the statement's ON CONFLICT clause says
+type fakeSales struct {
+	totals map[string]float64 // by source_id
+}
+
+func (f *fakeSales) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	sourceID, total := args[12].(string), args[5].(float64)
+	if _, exists := f.totals[sourceID]; exists && !strings.Contains(sql, "DO UPDATE") {
+		return pgconn.NewCommandTag("INSERT 0 0"), nil
+	}
+	f.totals[sourceID] = total
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (f *fakeSales) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	panic("unexpected QueryRow: " + sql)
+}
+
+// Re-importing an edited export must meet the sales already loaded from the
+// original, even though the file hash and line numbers differ
+func TestReimportEditedExport(t *testing.T) {
+	mapping := &MappingProfile{ColumnMaps: map[string]string{"Order": "external_id", "Date": "date", "Total": "total"}}
+	original := "Order,Date,Total\nA-1001,2024-01-01,100.00\nA-1002,2024-01-01,45.50\n"
+	// A-1002 was corrected, a void line inserted above it and A-1003 added
+	edited := "Order,Date,Total\nA-1001,2024-01-01,100.00\nA-1005,2024-01-01,0\nA-1002,2024-01-01,54.50\nA-1003,2024-01-02,20.00\n"
+
+	tests := []struct {
+		strategy     string
+		wantA1002    float64
+		wantWarnings int
+		wantErrors   int
+	}{
+		{strategy: ConflictUpdate, wantA1002: 54.50},
+		{strategy: ConflictSkip, wantA1002: 45.50, wantWarnings: 2},
+		{strategy: ConflictReject, wantA1002: 45.50, wantErrors: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			p := &Pipeline{}
+			db := &fakeSales{totals: map[string]float64{}}
+			locationID := uuid.New()
+
+			var warnings, errs int
+			for i, file := range []string{original, edited} {
+				job := &ImportJob{LocationID: locationID, FileHash: fmt.Sprintf("%x", sha256.Sum256([]byte(file))), ConflictStrategy: tt.strategy}
+				result, err := NewParser("pos", mapping).Parse(strings.NewReader(file))
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				for _, row := range result.Rows {
+					if len(row.Errors) > 0 {
+						t.Fatalf("line %d: %v", row.LineNumber, row.Errors)
+					}
+					err := p.processPOSRow(context.Background(), db, job, row, nil)
+					var warning rowWarning
+					switch {
+					case i == 0 && err != nil:
+						t.Fatalf("first import line %d: %v", row.LineNumber, err)
+					case errors.As(err, &warning):
+						warnings++
+					case err != nil:
+						errs++
+					}
+				}
+			}
+
+			if len(db.totals) != 4 {
+				t.Errorf("stored %d sales, want 4: %v", len(db.totals), db.totals)
+			}
+			if got := db.totals["A-1002"]; got != tt.wantA1002 {
+				t.Errorf("A-1002 total = %.2f, want %.2f", got, tt.wantA1002)
+			}
+			if warnings != tt.wantWarnings || errs != tt.wantErrors {
+				t.Errorf("got %d warnings and %d errors, want %d and %d", warnings, errs, tt.wantWarnings, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestSaleSourceID(t *testing.T) {
+	job := &ImportJob{FileHash: "abcdef0123456789"}
+	tests := []struct {
+		name   string
+		mapped map[string]interface{}
+		want   string
+	}{
+		{name: "order id", mapped: map[string]interface{}{"external_id": " A-1001 "}, want: "A-1001"},
+		{name: "no order id", mapped: map[string]interface{}{}, want: "abcdef01-7"},
+		{name: "blank order id", mapped: map[string]interface{}{"external_id": "  "}, want: "abcdef01-7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := saleSourceID(job, ParsedRow{LineNumber: 7, Mapped: tt.mapped}); got != tt.want {
+				t.Errorf("saleSourceID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessPayrollRowEncrypts(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	cipher, err := fieldcrypt.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := ParsedRow{LineNumber: 2, Mapped: map[string]interface{}{
+		"period_start":   "2024-01-01",
+		"period_end":     "2024-01-07",
+		"total_wages":    "5000.00",
+		"superannuation": "550.00",
+		"tax_withheld":   "1234.56",
+	}}
+
+	tests := []struct {
+		name          string
+		cipher        *fieldcrypt.Cipher
+		fields        []string
+		wantEncrypted []string
+	}{
+		{name: "off"},
+		{name: "all fields", cipher: cipher, wantEncrypted: []string{"superannuation", "tax_withheld"}},
+		{name: "tax only", cipher: cipher, fields: []string{"tax_withheld"}, wantEncrypted: []string{"tax_withheld"}},
+	}
+
+	// Argument positions of each field's plain and encrypted columns
+	columns := map[string]struct {
+		plain, sealed int
+		value         float64
+	}{
+		"superannuation": {plain: 6, sealed: 8, value: 550},
+		"tax_withheld":   {plain: 7, sealed: 9, value: 1234.56},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeExecutor{rowsAffected: 1}
+			p := &Pipeline{cfg: PipelineConfig{FieldCipher: tt.cipher, EncryptedFields: tt.fields}}
+			if err := p.processPayrollRow(context.Background(), db, &ImportJob{LocationID: uuid.New()}, row); err != nil {
+				t.Fatalf("processPayrollRow() error = %v", err)
+			}
+			args := db.args[0]
+
+			for field, col := range columns {
+				encrypted := false
+				for _, f := range tt.wantEncrypted {
+					encrypted = encrypted || f == field
+				}
+				plain := args[col.plain].(*float64)
+				sealed := args[col.sealed].(*string)
+
+				if !encrypted {
+					if plain == nil || *plain != col.value || sealed != nil {
+						t.Errorf("%s stored as %v / %v, want plain %v", field, plain, sealed, col.value)
+					}
+					continue
+				}
+				// The raw row must not reveal the amount
+				if plain != nil || sealed == nil {
+					t.Fatalf("%s stored as %v / %v, want only ciphertext", field, plain, sealed)
+				}
+				if strings.Contains(*sealed, fmt.Sprint(col.value)) {
+					t.Errorf("%s ciphertext %q contains the amount", field, *sealed)
+				}
+				got, err := cipher.DecryptAmount(*sealed)
+				if err != nil || got != col.value {
+					t.Errorf("%s decrypts to %v, %v, want %v", field, got, err, col.value)
+				}
+			}
+		})
+	}
+}
+
+// fakePurchases resolves every supplier to supplierID and records the
+// purchase upserts
+type fakePurchases struct {
+	supplierID uuid.UUID
+	args       [][]interface{}
+}
+
+func (f *fakePurchases) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	f.args = append(f.args, args)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (f *fakePurchases) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return fakeRow{id: f.supplierID}
+}
+
+func TestProcessPurchaseRow(t *testing.T) {
+	job := &ImportJob{LocationID: uuid.New(), FileHash: fmt.Sprintf("%x", sha256.Sum256([]byte("invoices")))}
+
+	tests := []struct {
+		name      string
+		mapped    map[string]interface{}
+		wantTotal float64
+		wantErr   string
+	}{
+		{
+			name:      "total from invoice",
+			mapped:    map[string]interface{}{"date": "2024-03-04", "supplier": "Metro Meats", "item_name": "Beef mince", "category": "Meat", "quantity": "12", "unit_cost": "9.50", "total": "110.00"},
+			wantTotal: 110,
+		},
+		{
+			name:      "derived total",
+			mapped:    map[string]interface{}{"date": "2024-03-04", "supplier": "Metro Meats", "item_name": "Beef mince", "quantity": "12", "unit_cost": "9.50"},
+			wantTotal: 114,
+		},
+		{
+			name:    "missing supplier",
+			mapped:  map[string]interface{}{"date": "2024-03-04", "item_name": "Beef mince", "quantity": "12", "unit_cost": "9.50"},
+			wantErr: "supplier and item_name are required",
+		},
+		{
+			name:    "bad quantity",
+			mapped:  map[string]interface{}{"date": "2024-03-04", "supplier": "Metro Meats", "item_name": "Beef mince", "quantity": "a dozen", "unit_cost": "9.50"},
+			wantErr: "invalid quantity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakePurchases{supplierID: uuid.New()}
+			p := &Pipeline{}
+			err := p.processPurchaseRow(context.Background(), db, job, ParsedRow{LineNumber: 2, Mapped: tt.mapped})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("processPurchaseRow() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(db.args) != 0 {
+					t.Error("a rejected row was written")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processPurchaseRow() error = %v", err)
+			}
+
+			args := db.args[0]
+			if args[1] != job.LocationID || args[4] != db.supplierID {
+				t.Errorf("location, supplier = %v, %v, want %v, %v", args[1], args[4], job.LocationID, db.supplierID)
+			}
+			if got := args[10].(float64); got != tt.wantTotal {
+				t.Errorf("total = %v, want %v", got, tt.wantTotal)
+			}
+			if got, want := args[7], tt.mapped["category"]; want != nil && got != want {
+				t.Errorf("category = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// fakeSuppliers holds a location's suppliers by lower-cased name and records
+// the statements that write. Like fakeChannels, rivals are inserted by a
+// concurrent import just before this one's insert.
+type fakeSuppliers struct {
+	byName     map[string]uuid.UUID
+	rivals     map[string]uuid.UUID
+	statements []string
+	args       [][]interface{}
+}
+
+func (f *fakeSuppliers) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	f.statements = append(f.statements, sql)
+	f.args = append(f.args, args)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (f *fakeSuppliers) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if strings.Contains(sql, "INSERT INTO suppliers") {
+		f.statements = append(f.statements, sql)
+		f.args = append(f.args, args)
+		key := strings.ToLower(args[2].(string))
+		if id, ok := f.rivals[key]; ok {
+			f.byName[key] = id
+			delete(f.rivals, key)
+		}
+		if _, exists := f.byName[key]; exists {
+			return fakeRow{err: pgx.ErrNoRows}
+		}
+		id := args[0].(uuid.UUID)
+		f.byName[key] = id
+		return fakeRow{id: id}
+	}
+	id, ok := f.byName[strings.ToLower(args[0].(string))]
+	if !ok {
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+	return fakeRow{id: id}
+}
+
+func TestProcessPurchaseRowInvoiceKey(t *testing.T) {
+	job := &ImportJob{LocationID: uuid.New(), FileHash: fmt.Sprintf("%x", sha256.Sum256([]byte("invoices")))}
+	line := func(supplier, invoice string) ParsedRow {
+		mapped := map[string]interface{}{"date": "2024-03-04", "supplier": supplier, "item_name": "Beef mince", "quantity": "12", "unit_cost": "9.50"}
+		if invoice != "" {
+			mapped["invoice_number"] = invoice
+		}
+		return ParsedRow{LineNumber: 2, Mapped: mapped}
+	}
+
+	metro := uuid.New()
+	db := &fakeSuppliers{byName: map[string]uuid.UUID{"metro meats": metro}}
+	p := &Pipeline{}
+
+	// A known supplier is matched whatever its case, and the invoice number
+	// keys the line
+	if err := p.processPurchaseRow(context.Background(), db, job, line("METRO MEATS", " INV-1042 ")); err != nil {
+		t.Fatalf("processPurchaseRow() error = %v", err)
+	}
+	if len(db.statements) != 1 {
+		t.Fatalf("executed %d statements, want only the purchase upsert", len(db.statements))
+	}
+	if !strings.Contains(db.statements[0], "ON CONFLICT (location_id, supplier_id, invoice_number, item_name) WHERE invoice_number IS NOT NULL") {
+		t.Errorf("invoice line upsert = %s, want it keyed on supplier and invoice number", db.statements[0])
+	}
+	if args := db.args[0]; args[4] != metro || args[5] != "INV-1042" {
+		t.Errorf("supplier_id, invoice_number = %v, %q, want %v, INV-1042", args[4], args[5], metro)
+	}
+
+	// A new supplier is created once, and a line without an invoice number
+	// falls back to the file and row key
+	db.statements, db.args = nil, nil
+	for i := 0; i < 2; i++ {
+		if err := p.processPurchaseRow(context.Background(), db, job, line("Green Grocer", "")); err != nil {
+			t.Fatalf("processPurchaseRow() error = %v", err)
+		}
+	}
+	var inserts int
+	for i, sql := range db.statements {
+		if strings.Contains(sql, "INSERT INTO suppliers") {
+			inserts++
+			if args := db.args[i]; args[1] != job.LocationID || args[3] != "green-grocer" {
+				t.Errorf("supplier location, code = %v, %v, want %v, green-grocer", args[1], args[3], job.LocationID)
+			}
+			continue
+		}
+		if !strings.Contains(sql, "ON CONFLICT (location_id, import_source, source_id)") {
+			t.Errorf("line without an invoice upsert = %s, want it keyed on the source row", sql)
+		}
+		if supplierID := db.args[i][4]; supplierID != db.byName["green grocer"] {
+			t.Errorf("supplier_id = %v, want the created %v", supplierID, db.byName["green grocer"])
+		}
+	}
+	if inserts != 1 {
+		t.Errorf("created the supplier %d times, want once", inserts)
+	}
+}
+
+func TestNormalizeTransactionType(t *testing.T) {
+	tests := map[string]string{
+		"gift_card_sale":       TransactionGiftCardSale,
+		"Gift Card Purchase":   TransactionGiftCardSale,
+		"GIFTCARD-RELOAD":      TransactionGiftCardSale,
+		"gift card redemption": TransactionGiftCardRedemption,
+		" Gift-Card Payment ":  TransactionGiftCardRedemption,
+		"sale":                 "",
+		"":                     "",
+		"gift":                 "",
+	}
+	for in, want := range tests {
+		if got := NormalizeTransactionType(in); got != want {
+			t.Errorf("NormalizeTransactionType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// Selling a gift card takes on a liability, so only the ledger sees it;
+// spending one is revenue and also draws down the liability
+func TestProcessPOSRowGiftCards(t *testing.T) {
+	tests := []struct {
+		txType     string
+		wantLedger string // entry_type written to gift_card_ledger, empty for none
+		wantSale   bool
+	}{
+		{txType: "Gift Card Sale", wantLedger: "issue"},
+		{txType: "gift_card_redemption", wantLedger: "redeem", wantSale: true},
+		{txType: "", wantSale: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.txType, func(t *testing.T) {
+			p := &Pipeline{}
+			db := &fakeExecutor{rowsAffected: 1}
+			job := &ImportJob{LocationID: uuid.New(), FileHash: "abcdef0123456789"}
+			row := ParsedRow{LineNumber: 2, Mapped: map[string]interface{}{
+				"date": "2024-01-01", "total": "50.00", "transaction_type": tt.txType, "gift_card_number": "GC-1001",
+			}}
+
+			if err := p.processPOSRow(context.Background(), db, job, row, nil); err != nil {
+				t.Fatalf("processPOSRow() error = %v", err)
+			}
+
+			var ledger string
+			var sales int
+			for i, sql := range db.statements {
+				switch {
+				case strings.Contains(sql, "INSERT INTO gift_card_ledger"):
+					ledger = db.args[i][3].(string)
+					if amount := db.args[i][4].(float64); amount != 50 {
+						t.Errorf("ledger amount = %v, want 50", amount)
+					}
+				case strings.Contains(sql, "INSERT INTO sales"):
+					sales++
+				}
+			}
+			if ledger != tt.wantLedger {
+				t.Errorf("ledger entry = %q, want %q", ledger, tt.wantLedger)
+			}
+			if got := sales == 1; got != tt.wantSale {
+				t.Errorf("recorded %d sales, want sale %v", sales, tt.wantSale)
+			}
+		})
+	}
+}
+
+func TestProcessPOSRowPlatformFee(t *testing.T) {
+	tests := []struct {
+		name   string
+		mapped map[string]interface{}
+		want   float64
+	}{
+		{name: "platform fee", mapped: map[string]interface{}{"platform_fee": "12.60"}, want: 12.6},
+		{name: "commission", mapped: map[string]interface{}{"commission": "$8.25"}, want: 8.25},
+		{name: "platform fee wins", mapped: map[string]interface{}{"platform_fee": "5", "commission": "7"}, want: 5},
+		{name: "none", mapped: map[string]interface{}{}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pipeline{}
+			db := &fakeExecutor{rowsAffected: 1}
+			job := &ImportJob{LocationID: uuid.New(), FileHash: "abcdef0123456789"}
+			row := ParsedRow{LineNumber: 2, Mapped: map[string]interface{}{"date": "2024-01-01", "total": "42.00"}}
+			for k, v := range tt.mapped {
+				row.Mapped[k] = v
+			}
+
+			if err := p.processPOSRow(context.Background(), db, job, row, nil); err != nil {
+				t.Fatalf("processPOSRow() error = %v", err)
+			}
+			// platform_fee is the 19th column; the sale total stays gross
+			args := db.args[0]
+			if fee := args[18].(float64); fee != tt.want {
+				t.Errorf("platform_fee = %v, want %v", fee, tt.want)
+			}
+			if total := args[5].(float64); total != 42 {
+				t.Errorf("total = %v, want 42", total)
+			}
+		})
+	}
+}
+
+// fakeRefundSales matches refunds to the sales it holds by external ID and records
+// the refund upserts
+type fakeRefundSales struct {
+	byExternalID map[string]uuid.UUID
+	args         [][]interface{}
+}
+
+func (f *fakeRefundSales) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	f.args = append(f.args, args)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (f *fakeRefundSales) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	id, ok := f.byExternalID[args[1].(string)]
+	if !ok {
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+	return fakeRow{id: id}
+}
+
+func TestProcessRefundRow(t *testing.T) {
+	saleID := uuid.New()
+	job := &ImportJob{LocationID: uuid.New(), FileHash: "abcdef0123456789"}
+
+	tests := []struct {
+		name        string
+		mapped      map[string]interface{}
+		wantSale    *uuid.UUID
+		wantAmount  float64
+		wantWarning string
+		wantErr     string
+	}{
+		{
+			name:       "matched",
+			mapped:     map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1001", "amount": "25.50", "reason": "cold food"},
+			wantSale:   &saleID,
+			wantAmount: 25.5,
+		},
+		{
+			name:       "negative chargeback",
+			mapped:     map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1001", "amount": "-40.00", "reason": "chargeback"},
+			wantSale:   &saleID,
+			wantAmount: 40,
+		},
+		{
+			name:        "unknown sale",
+			mapped:      map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-9999", "amount": "12.00"},
+			wantAmount:  12,
+			wantWarning: "refund external_id ORD-9999 matches no sale",
+		},
+		{
+			name:        "no external id",
+			mapped:      map[string]interface{}{"date": "2024-03-04", "amount": "12.00"},
+			wantAmount:  12,
+			wantWarning: "refund has no external_id to match a sale",
+		},
+		{
+			name:    "bad amount",
+			mapped:  map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1001", "amount": "lots"},
+			wantErr: "invalid amount",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeRefundSales{byExternalID: map[string]uuid.UUID{"ORD-1001": saleID}}
+			p := &Pipeline{}
+			err := p.processRefundRow(context.Background(), db, job, ParsedRow{LineNumber: 2, Mapped: tt.mapped})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("processRefundRow() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(db.args) != 0 {
+					t.Error("a rejected row was written")
+				}
+				return
+			}
+
+			var warning rowWarning
+			switch {
+			case tt.wantWarning != "":
+				if !errors.As(err, &warning) || string(warning) != tt.wantWarning {
+					t.Errorf("processRefundRow() error = %v, want warning %q", err, tt.wantWarning)
+				}
+			case err != nil:
+				t.Fatalf("processRefundRow() error = %v", err)
+			}
+
+			// Unmatched refunds are still recorded so they can be spread over the day
+			if len(db.args) != 1 {
+				t.Fatalf("wrote %d refunds, want 1", len(db.args))
+			}
+			args := db.args[0]
+			if got := args[4].(*uuid.UUID); (got == nil) != (tt.wantSale == nil) || (got != nil && *got != *tt.wantSale) {
+				t.Errorf("sale_id = %v, want %v", got, tt.wantSale)
+			}
+			if got := args[5].(float64); got != tt.wantAmount {
+				t.Errorf("amount = %v, want %v", got, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestProcessPOSRowRefund(t *testing.T) {
+	saleID := uuid.New()
+	job := &ImportJob{LocationID: uuid.New(), FileHash: "abcdef0123456789"}
+	row := ParsedRow{LineNumber: 3, Mapped: map[string]interface{}{
+		"date": "2024-03-04", "total": "-25.50", "external_id": "ORD-1001", "refund": "true",
+	}}
+
+	// The refunds fake only accepts refund writes, so a sale insert would
+	// show up as a refund with the wrong arguments
+	db := &fakeRefundSales{byExternalID: map[string]uuid.UUID{"ORD-1001": saleID}}
+	if err := (&Pipeline{}).processPOSRow(context.Background(), db, job, row, nil); err != nil {
+		t.Fatalf("processPOSRow() error = %v", err)
+	}
+	if len(db.args) != 1 {
+		t.Fatalf("wrote %d rows, want 1 refund", len(db.args))
+	}
+	args := db.args[0]
+	if got := args[4].(*uuid.UUID); got == nil || *got != saleID {
+		t.Errorf("sale_id = %v, want %v", got, saleID)
+	}
+	if got := args[5].(float64); got != 25.5 {
+		t.Errorf("amount = %v, want 25.5", got)
+	}
+}
+
+// TestProcessPOSRowLocalTime checks a wall-clock time from the file is read in
+// the location's timezone, so a late sale keeps its local day
+func TestProcessPOSRowLocalTime(t *testing.T) {
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &ImportJob{LocationID: uuid.New(), FileHash: "abcdef0123456789", loc: losAngeles}
+	row := ParsedRow{LineNumber: 2, Mapped: map[string]interface{}{"date": "2024-03-04 23:30:00", "total": "42.00"}}
+
+	db := &fakeExecutor{rowsAffected: 1}
+	if err := (&Pipeline{}).processPOSRow(context.Background(), db, job, row, nil); err != nil {
+		t.Fatalf("processPOSRow() error = %v", err)
+	}
+
+	// occurred_at is the 5th column
+	got := db.args[0][4].(time.Time)
+	if want := time.Date(2024, 3, 5, 7, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("occurred_at = %v, want %v", got.UTC(), want)
+	}
+	if local := got.In(losAngeles); local.Day() != 4 || local.Hour() != 23 {
+		t.Errorf("occurred_at is %v locally, want 23:30 on the 4th", local)
+	}
+}
+
+// fakeKeyedJobs holds the jobs earlier requests started, by location and key
+type fakeKeyedJobs struct {
+	jobs  map[string]*ImportJob
+	since time.Time // the window start of the last lookup
+}
+
+func (f *fakeKeyedJobs) GetByIdempotencyKey(ctx context.Context, locationID uuid.UUID, key string, since time.Time) (*ImportJob, error) {
+	f.since = since
+	job, ok := f.jobs[locationID.String()+":"+key]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return job, nil
+}
+
+func TestStartImportIdempotencyKey(t *testing.T) {
+	locationID := uuid.New()
+	original := &ImportJob{ID: uuid.New(), LocationID: locationID, Status: "processing"}
+	keyed := &fakeKeyedJobs{jobs: map[string]*ImportJob{locationID.String() + ":retry-1": original}}
+	p := &Pipeline{
+		keyedJobs:       keyed,
+		cfg:             PipelineConfig{IdempotencyTTL: 24 * time.Hour},
+		idempotencyKeys: make(map[string]bool),
+	}
+	params := ImportParams{SourceType: "pos", LocationID: locationID, IdempotencyKey: "retry-1", File: strings.NewReader("Date,Total\n")}
+
+	// A retry gets the original job back, however many times it is sent
+	for i := 0; i < 2; i++ {
+		job, err := p.StartImport(context.Background(), params)
+		if !errors.Is(err, ErrIdempotentReplay) || job != original {
+			t.Fatalf("retry %d: StartImport() = %v, %v, want the original job and ErrIdempotentReplay", i+1, job, err)
+		}
+	}
+	if age := time.Since(keyed.since); age < 24*time.Hour || age > 24*time.Hour+time.Minute {
+		t.Errorf("looked back %v, want the 24h TTL", age)
+	}
+
+	// While the first request is still being handled a concurrent retry is refused
+	release, err := p.claimIdempotencyKey(locationID, "retry-1")
+	if err != nil {
+		t.Fatalf("claimIdempotencyKey() error = %v", err)
+	}
+	if _, err := p.StartImport(context.Background(), params); !errors.Is(err, ErrIdempotencyKeyInUse) {
+		t.Errorf("concurrent StartImport() error = %v, want ErrIdempotencyKeyInUse", err)
+	}
+	// Keys are per location
+	if other, err := p.claimIdempotencyKey(uuid.New(), "retry-1"); err != nil {
+		t.Errorf("another location's claim error = %v", err)
+	} else {
+		other()
+	}
+	release()
+	if _, err := p.StartImport(context.Background(), params); !errors.Is(err, ErrIdempotentReplay) {
+		t.Errorf("StartImport() after release error = %v, want ErrIdempotentReplay", err)
+	}
+}
+
+func TestParsePurchasesDefaultMapping(t *testing.T) {
+	csv := "Invoice Date,Invoice #,Supplier,Item,Quantity,Unit Cost,Total\n2024-03-04,INV-1042,Metro Meats,Beef mince,12,9.50,114.00\n"
+	result, err := NewParser("purchases", &MappingProfile{ColumnMaps: DefaultMappings()["purchases"]}).Parse(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	row := result.Rows[0]
+	if len(row.Errors) != 0 {
+		t.Fatalf("errors = %q, want none", row.Errors)
+	}
+	want := map[string]interface{}{"date": "2024-03-04", "invoice_number": "INV-1042", "supplier": "Metro Meats", "item_name": "Beef mince", "quantity": "12", "unit_cost": "9.50", "total": "114.00"}
+	for field, v := range want {
+		if row.Mapped[field] != v {
+			t.Errorf("%s = %v, want %v", field, row.Mapped[field], v)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package imports
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// PreviewRow is one parsed row as it would be applied by an import
+type PreviewRow struct {
+	LineNumber int                    `json:"line_number"`
+	Mapped     map[string]interface{} `json:"mapped"`
+	Errors     []string               `json:"errors,omitempty"`
+	Warnings   []string               `json:"warnings,omitempty"`
+}
+
+// PreviewResult is a dry run of an import: the first rows as mapped, the
+// validation outcome for the whole file and how its headers line up with
+// the mapping
+type PreviewResult struct {
+	SourceType      string       `json:"source_type"`
+	Headers         []string     `json:"headers"`
+	Warnings        []string     `json:"warnings,omitempty"`
+	Rows            []PreviewRow `json:"rows"`
+	TotalRows       int          `json:"total_rows"`
+	ValidRows       int          `json:"valid_rows"`
+	ErrorRows       int          `json:"error_rows"`
+	MatchedHeaders  []string     `json:"matched_headers"`
+	UnmappedHeaders []string     `json:"unmapped_headers"`
+	UnmappedFields  []string     `json:"unmapped_fields"`
+}
+
+// Preview parses reader exactly as an import with the same source type and
+// mapping would, without creating a job or writing any rows. Only the first
+// limit rows are returned; every row is validated and counted.
+func (p *Pipeline) Preview(ctx context.Context, sourceType string, locationID uuid.UUID, mappingID *uuid.UUID, reader io.Reader, limit int) (*PreviewResult, error) {
+	if !IsValidSourceType(sourceType) {
+		return nil, ErrUnknownSourceType
+	}
+	mapping, err := p.loadMapping(ctx, sourceType, locationID, mappingID)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := p.newParser(sourceType, mapping)
+
+	rows := make([]PreviewRow, 0, limit)
+	result, err := parser.ParseEach(reader, func(row ParsedRow) error {
+		if len(rows) < limit {
+			rows = append(rows, PreviewRow{LineNumber: row.LineNumber, Mapped: row.Mapped, Errors: row.Errors, Warnings: row.Warnings})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &PreviewResult{
+		SourceType: sourceType,
+		Headers:    result.Headers,
+		Warnings:   append(result.Warnings, result.MappingWarnings...),
+		Rows:       rows,
+		TotalRows:  result.TotalRows,
+		ValidRows:  result.ValidRows,
+		ErrorRows:  result.ErrorRows,
+	}
+	preview.MatchedHeaders, preview.UnmappedHeaders, preview.UnmappedFields = matchHeaders(sourceType, mapping, result.Headers)
+	return preview, nil
+}
+
+// matchHeaders splits headers into those the mapping reads and those it
+// ignores, and lists the source type's known target fields that neither a
+// present column nor a mapping default fills
+func matchHeaders(sourceType string, mapping *MappingProfile, headers []string) (matched, unmapped, missingFields []string) {
+	matched, unmapped = []string{}, []string{}
+	filled := make(map[string]bool)
+	for _, h := range headers {
+		var target string
+		if mapping != nil {
+			target = mapping.ColumnMaps[h]
+		}
+		if target == "" {
+			unmapped = append(unmapped, h)
+			continue
+		}
+		matched = append(matched, h)
+		filled[target] = true
+	}
+	if mapping != nil {
+		for field := range mapping.Defaults {
+			filled[field] = true
+		}
+	}
+
+	missingFields = []string{}
+	seen := make(map[string]bool)
+	for _, field := range DefaultMappings()[sourceType] {
+		if filled[field] || seen[field] {
+			continue
+		}
+		seen[field] = true
+		missingFields = append(missingFields, field)
+	}
+	sort.Strings(missingFields)
+	return matched, unmapped, missingFields
+}
@@ -0,0 +1,106 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// reapInterval is how often the reaper looks for stuck imports after its first pass
+const reapInterval = 5 * time.Minute
+
+// staleJobStore finds and transitions stuck jobs; ImportStore satisfies it
+type staleJobStore interface {
+	ListStaleJobs(ctx context.Context, startedBefore time.Time) ([]ImportJob, error)
+	ClaimStaleJob(ctx context.Context, id uuid.UUID, startedBefore time.Time) (bool, error)
+	UpdateJobStatus(ctx context.Context, id uuid.UUID, status string, errorMsg string) error
+}
+
+// Reaper recovers imports left in processing by a crash or restart. Stuck
+// jobs with a stored upload are processed again from the start; the rest are
+// marked failed so the user knows to re-upload.
+type Reaper struct {
+	pipeline   *Pipeline
+	jobs       staleJobStore
+	staleAfter time.Duration
+	onComplete func(ctx context.Context, jobID uuid.UUID)
+}
+
+// NewReaper creates a reaper for jobs processing longer than staleAfter.
+// onComplete, if set, is called after a requeued import completes.
+func NewReaper(pipeline *Pipeline, staleAfter time.Duration, onComplete func(ctx context.Context, jobID uuid.UUID)) *Reaper {
+	return &Reaper{pipeline: pipeline, jobs: pipeline.store, staleAfter: staleAfter, onComplete: onComplete}
+}
+
+// Run reaps stuck imports at startup and then periodically until ctx is cancelled
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+	for {
+		requeued, failed, err := r.Reap(ctx)
+		if err != nil {
+			log.Printf("Failed to reap stuck imports: %v", err)
+		} else if requeued+failed > 0 {
+			log.Printf("Reaped stuck imports: %d requeued, %d failed", requeued, failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reap finds imports processing since before the stale threshold that this
+// process is not running, requeueing those whose upload was kept and failing
+// the others. Requeued imports are processed before Reap returns.
+func (r *Reaper) Reap(ctx context.Context) (requeued, failed int, err error) {
+	cutoff := time.Now().Add(-r.staleAfter)
+	jobs, err := r.jobs.ListStaleJobs(ctx, cutoff)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range jobs {
+		job := &jobs[i]
+		if r.pipeline.isActive(job.ID) {
+			continue
+		}
+		claimed, err := r.jobs.ClaimStaleJob(ctx, job.ID, cutoff)
+		if err != nil {
+			return requeued, failed, err
+		}
+		if !claimed {
+			continue
+		}
+
+		file, size, err := r.pipeline.reopenUpload(ctx, job)
+		if errors.Is(err, ErrUploadNotStored) {
+			msg := fmt.Sprintf("import was interrupted after processing for over %s and its upload was not kept; re-upload the file", r.staleAfter)
+			if err := r.jobs.UpdateJobStatus(ctx, job.ID, "failed", msg); err != nil {
+				return requeued, failed, err
+			}
+			failed++
+			continue
+		}
+		if err != nil {
+			r.jobs.UpdateJobStatus(ctx, job.ID, "failed", fmt.Sprintf("import was interrupted and could not be requeued: %v", err))
+			failed++
+			continue
+		}
+
+		log.Printf("Requeueing import %s stuck in processing since before %s", job.ID, cutoff.Format(time.RFC3339))
+		requeued++
+		err = r.pipeline.ProcessImport(ctx, job.ID, file, size)
+		file.Close()
+		if err == nil && r.onComplete != nil {
+			r.onComplete(ctx, job.ID)
+		}
+	}
+	return requeued, failed, nil
+}
@@ -0,0 +1,90 @@
+package imports
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeJobs holds import jobs in memory with when each started processing
+type fakeJobs struct {
+	jobs      map[uuid.UUID]*ImportJob
+	startedAt map[uuid.UUID]time.Time
+	stolen    map[uuid.UUID]bool // claimed by another instance between list and claim
+}
+
+func (f *fakeJobs) add(status string, startedAt time.Time) uuid.UUID {
+	id := uuid.New()
+	f.jobs[id] = &ImportJob{ID: id, Status: status}
+	f.startedAt[id] = startedAt
+	return id
+}
+
+func (f *fakeJobs) ListStaleJobs(ctx context.Context, startedBefore time.Time) ([]ImportJob, error) {
+	var stale []ImportJob
+	for id, job := range f.jobs {
+		if job.Status == "processing" && f.startedAt[id].Before(startedBefore) {
+			stale = append(stale, *job)
+		}
+	}
+	return stale, nil
+}
+
+func (f *fakeJobs) ClaimStaleJob(ctx context.Context, id uuid.UUID, startedBefore time.Time) (bool, error) {
+	job := f.jobs[id]
+	if f.stolen[id] || job.Status != "processing" || !f.startedAt[id].Before(startedBefore) {
+		return false, nil
+	}
+	job.Status = "pending"
+	return true, nil
+}
+
+func (f *fakeJobs) UpdateJobStatus(ctx context.Context, id uuid.UUID, status string, errorMsg string) error {
+	f.jobs[id].Status = status
+	f.jobs[id].ErrorMessage = errorMsg
+	return nil
+}
+
+func TestReapFailsStuckJobWithoutUpload(t *testing.T) {
+	now := time.Now()
+	db := &fakeJobs{jobs: map[uuid.UUID]*ImportJob{}, startedAt: map[uuid.UUID]time.Time{}, stolen: map[uuid.UUID]bool{}}
+
+	stuck := db.add("processing", now.Add(-2*time.Hour))
+	running := db.add("processing", now.Add(-2*time.Hour))
+	stolen := db.add("processing", now.Add(-2*time.Hour))
+	db.stolen[stolen] = true
+	recent := db.add("processing", now.Add(-5*time.Minute))
+	done := db.add("completed", now.Add(-3*time.Hour))
+
+	// The pipeline keeps no uploads, and is still applying one of the jobs
+	p := &Pipeline{running: map[uuid.UUID]*runningImport{running: {}}}
+	r := &Reaper{pipeline: p, jobs: db, staleAfter: 30 * time.Minute}
+
+	requeued, failed, err := r.Reap(context.Background())
+	if err != nil {
+		t.Fatalf("Reap() error = %v", err)
+	}
+	if requeued != 0 || failed != 1 {
+		t.Errorf("Reap() = %d requeued, %d failed, want 0 and 1", requeued, failed)
+	}
+
+	if job := db.jobs[stuck]; job.Status != "failed" || !strings.Contains(job.ErrorMessage, "re-upload the file") {
+		t.Errorf("stuck job = %q (%q), want failed asking for a re-upload", job.Status, job.ErrorMessage)
+	}
+	for name, id := range map[string]uuid.UUID{"running here": running, "claimed elsewhere": stolen, "recent": recent} {
+		if status := db.jobs[id].Status; status != "processing" {
+			t.Errorf("%s job status = %q, want it left processing", name, status)
+		}
+	}
+	if status := db.jobs[done].Status; status != "completed" {
+		t.Errorf("completed job status = %q, want it untouched", status)
+	}
+
+	// A second pass finds nothing left to reap
+	if requeued, failed, err := r.Reap(context.Background()); err != nil || requeued+failed != 0 {
+		t.Errorf("second Reap() = %d, %d, %v, want nothing reaped", requeued, failed, err)
+	}
+}
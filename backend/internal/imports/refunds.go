@@ -0,0 +1,111 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// refundFlags are the refund column values and transaction types that mark a
+// POS row as a refund
+var refundFlags = map[string]bool{
+	"true": true, "yes": true, "y": true, "1": true,
+	"refund": true, "return": true, "chargeback": true,
+}
+
+// IsPOSRefund reports whether a POS row is a refund, either through the
+// refund column or a refund transaction type
+func IsPOSRefund(row ParsedRow) bool {
+	for _, field := range []string{"refund", "transaction_type"} {
+		if v, ok := row.Mapped[field].(string); ok && refundFlags[strings.ToLower(strings.TrimSpace(v))] {
+			return true
+		}
+	}
+	return false
+}
+
+// processRefundRow records a refund or chargeback, linking it to the original
+// sale by external_id. Refunds with no matching sale are still recorded (and
+// netted against the day's revenue) but flagged with a warning anomaly.
+func (p *Pipeline) processRefundRow(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow) error {
+	dateStr, _ := row.Mapped["date"].(string)
+	date, err := parseDate(dateStr)
+	if err != nil {
+		return fmt.Errorf("invalid date: %w", err)
+	}
+
+	amountStr, _ := row.Mapped["amount"].(string)
+	amount, err := parseAmount(amountStr)
+	if err != nil {
+		return fmt.Errorf("invalid amount: %w", err)
+	}
+	return p.recordRefund(ctx, db, job, row, date, amount)
+}
+
+// recordRefund writes a refund from a refunds import or a POS row flagged as
+// a refund
+func (p *Pipeline) recordRefund(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow, date time.Time, amount float64) error {
+	// Processors report refunds as either positive or negative amounts
+	amount = math.Abs(amount)
+
+	externalID := optionalString(row.Mapped, "external_id")
+	var saleID *uuid.UUID
+	if externalID != nil {
+		var id uuid.UUID
+		err := db.QueryRow(ctx, `
+			SELECT id FROM sales
+			WHERE location_id = $1 AND external_id = $2
+			ORDER BY occurred_at DESC
+			LIMIT 1
+		`, job.LocationID, *externalID).Scan(&id)
+		switch {
+		case err == nil:
+			saleID = &id
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+	}
+
+	// Upsert refund using file hash + row number as key for idempotency
+	query := `
+		INSERT INTO refunds (id, location_id, refund_date, external_id, sale_id, amount, reason, import_source, source_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		ON CONFLICT (location_id, import_source, source_id) DO UPDATE SET
+			refund_date = EXCLUDED.refund_date,
+			external_id = EXCLUDED.external_id,
+			sale_id = EXCLUDED.sale_id,
+			amount = EXCLUDED.amount,
+			reason = EXCLUDED.reason,
+			updated_at = NOW()
+	`
+
+	sourceID := fmt.Sprintf("%s-%d", job.FileHash[:8], row.LineNumber)
+	_, err := db.Exec(ctx, query,
+		uuid.New(),
+		job.LocationID,
+		date,
+		externalID,
+		saleID,
+		amount,
+		optionalString(row.Mapped, "reason"),
+		"csv-import",
+		sourceID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if saleID == nil {
+		if externalID == nil {
+			return rowWarning("refund has no external_id to match a sale")
+		}
+		return rowWarning(fmt.Sprintf("refund external_id %s matches no sale", *externalID))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// retryableSQLStates are Postgres error codes worth retrying for a row. Rows
+// run in a savepoint of the import transaction, so only errors that rolling
+// back the savepoint recovers from can be retried.
+var retryableSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"55P03": true, // lock_not_available
+}
+
+// connectionLostSQLStates are Postgres error codes that end the session
+var connectionLostSQLStates = map[string]bool{
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// isRetryable reports whether err is a transient database error a row can
+// be retried after
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && retryableSQLStates[pgErr.Code]
+}
+
+// isConnectionLost reports whether err means the connection, and with it the
+// import transaction, is gone
+func isConnectionLost(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return connectionLostSQLStates[pgErr.Code] || strings.HasPrefix(pgErr.Code, "08")
+	}
+	var netErr net.Error
+	return pgconn.SafeToRetry(err) || errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withRetry runs fn, retrying transient database errors with exponential
+// backoff. A lost connection takes the import transaction with it, so it is
+// returned as errTxAborted to fail the job rather than retried.
+func (p *Pipeline) withRetry(ctx context.Context, fn func() error) error {
+	backoff := p.cfg.RetryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err != nil && !errors.Is(err, errTxAborted) && isConnectionLost(err) {
+			return fmt.Errorf("%w: %v", errTxAborted, err)
+		}
+		if err == nil || attempt >= p.cfg.MaxRetries || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
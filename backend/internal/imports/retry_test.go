@@ -0,0 +1,125 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, want: true},
+		{name: "deadlock", err: &pgconn.PgError{Code: "40P01"}, want: true},
+		{name: "lock not available", err: &pgconn.PgError{Code: "55P03"}, want: true},
+		{name: "admin shutdown", err: &pgconn.PgError{Code: "57P01"}, want: false},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, want: false},
+		{name: "wrapped deadlock", err: fmt.Errorf("insert sale: %w", &pgconn.PgError{Code: "40P01"}), want: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "invalid input", err: &pgconn.PgError{Code: "22P02"}, want: false},
+		{name: "plain error", err: errors.New("invalid total"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	badRow := errors.New("invalid total")
+
+	tests := []struct {
+		name      string
+		errs      []error // returned by successive attempts; nil once exhausted
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "succeeds first time", wantCalls: 1},
+		{name: "transient then success", errs: []error{deadlock, deadlock}, wantCalls: 3},
+		{name: "gives up after max retries", errs: []error{deadlock, deadlock, deadlock, deadlock, deadlock}, wantCalls: 4, wantErr: deadlock},
+		{name: "row error is not retried", errs: []error{badRow}, wantCalls: 1, wantErr: badRow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pipeline{cfg: PipelineConfig{MaxRetries: 3, RetryBackoff: time.Millisecond}}
+			calls := 0
+			err := p.withRetry(context.Background(), func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("withRetry() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("withRetry() made %d attempts, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWithRetryStopsWhenCancelled(t *testing.T) {
+	p := &Pipeline{cfg: PipelineConfig{MaxRetries: 3, RetryBackoff: time.Hour}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := p.withRetry(ctx, func() error {
+		calls++
+		return &pgconn.PgError{Code: "40001"}
+	})
+	if err == nil {
+		t.Fatal("withRetry() error = nil, want the transient error")
+	}
+	if calls != 1 {
+		t.Errorf("withRetry() made %d attempts after cancel, want 1", calls)
+	}
+}
+
+// A dropped connection takes the import transaction with it, so the row is
+// not retried against it and the job fails instead
+func TestWithRetryConnectionLost(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "admin shutdown", err: &pgconn.PgError{Code: "57P01"}},
+		{name: "connection failure", err: fmt.Errorf("insert sale: %w", &pgconn.PgError{Code: "08006"})},
+		{name: "network error", err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}},
+		{name: "unexpected eof", err: fmt.Errorf("insert sale: %w", io.ErrUnexpectedEOF)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pipeline{cfg: PipelineConfig{MaxRetries: 3, RetryBackoff: time.Millisecond}}
+			calls := 0
+			err := p.withRetry(context.Background(), func() error {
+				calls++
+				return tt.err
+			})
+			if !errors.Is(err, errTxAborted) {
+				t.Errorf("withRetry() error = %v, want errTxAborted", err)
+			}
+			if calls != 1 {
+				t.Errorf("withRetry() made %d attempts, want 1", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package imports
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Rule is an optional row check a mapping profile can enable by name. It runs
+// after the row is mapped and the built-in validation for its source type,
+// and every problem it returns rejects the row and is recorded as an anomaly.
+type Rule struct {
+	Name        string                       `json:"name"`
+	Description string                       `json:"description"`
+	SourceTypes []string                     `json:"source_types,omitempty"` // empty applies to every source type
+	Check       func(row ParsedRow) []string `json:"-"`
+}
+
+// appliesTo reports whether the rule can be enabled for a source type
+func (r Rule) appliesTo(sourceType string) bool {
+	if len(r.SourceTypes) == 0 {
+		return true
+	}
+	for _, t := range r.SourceTypes {
+		if t == sourceType {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   = make(map[string]Rule)
+)
+
+// RegisterRule makes a rule available to mapping profiles. It panics if the
+// rule has no name or check, or if the name is already registered.
+func RegisterRule(rule Rule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	if rule.Name == "" || rule.Check == nil {
+		panic("imports: rule needs a name and a check")
+	}
+	if _, dup := rules[rule.Name]; dup {
+		panic("imports: rule registered twice: " + rule.Name)
+	}
+	rules[rule.Name] = rule
+}
+
+// Rules returns every registered rule sorted by name
+func Rules() []Rule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	list := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func lookupRule(name string) (Rule, bool) {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	r, ok := rules[name]
+	return r, ok
+}
+
+// ValidateRules checks that every named rule exists, applies to the source
+// type and is listed once
+func ValidateRules(sourceType string, names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		rule, ok := lookupRule(name)
+		if !ok {
+			return fmt.Errorf("unknown validation rule %q", name)
+		}
+		if !rule.appliesTo(sourceType) {
+			return fmt.Errorf("validation rule %q does not apply to %s imports", name, sourceType)
+		}
+		if seen[name] {
+			return fmt.Errorf("validation rule %q is listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// applyRules runs the mapping's enabled rules against a row
+func applyRules(names []string, row ParsedRow) []string {
+	var errs []string
+	for _, name := range names {
+		if rule, ok := lookupRule(name); ok {
+			errs = append(errs, rule.Check(row)...)
+		}
+	}
+	return errs
+}
+
+func init() {
+	RegisterRule(Rule{
+		Name:        "total_reconciles",
+		Description: "Total equals subtotal plus tax to within a cent",
+		SourceTypes: []string{"pos"},
+		Check:       checkTotalReconciles,
+	})
+	RegisterRule(Rule{
+		Name:        "non_negative_total",
+		Description: "Total is not negative unless the row is marked as a refund",
+		SourceTypes: []string{"pos"},
+		Check:       checkNonNegativeTotal,
+	})
+	RegisterRule(Rule{
+		Name:        "cover_count_present",
+		Description: "Covers is present and a whole number of at least one",
+		SourceTypes: []string{"pos"},
+		Check:       checkCoverCountPresent,
+	})
+}
+
+// mappedAmount returns a mapped amount field; ok is false when it is absent
+// and err is set when it cannot be parsed
+func mappedAmount(row ParsedRow, field string) (v float64, ok bool, err error) {
+	s, _ := row.Mapped[field].(string)
+	if s == "" {
+		return 0, false, nil
+	}
+	v, err = parseAmount(s)
+	return v, true, err
+}
+
+func checkTotalReconciles(row ParsedRow) []string {
+	total, hasTotal, err := mappedAmount(row, "total")
+	if err != nil || !hasTotal {
+		return nil // reported by the POS validation
+	}
+	subtotal, hasSubtotal, err := mappedAmount(row, "subtotal")
+	if err != nil {
+		return []string{fmt.Sprintf("total_reconciles: invalid subtotal: %v", row.Mapped["subtotal"])}
+	}
+	if !hasSubtotal {
+		return []string{"total_reconciles: subtotal is missing"}
+	}
+	tax, _, err := mappedAmount(row, "tax")
+	if err != nil {
+		return nil // reported by the POS validation
+	}
+
+	// Compare in whole cents so float noise can't push an exact match over
+	if math.Abs(math.Round(total*100)-math.Round((subtotal+tax)*100)) > 1 {
+		return []string{fmt.Sprintf("total_reconciles: total %.2f does not equal subtotal %.2f + tax %.2f", total, subtotal, tax)}
+	}
+	return nil
+}
+
+func checkNonNegativeTotal(row ParsedRow) []string {
+	total, hasTotal, err := mappedAmount(row, "total")
+	if err != nil || !hasTotal || IsPOSRefund(row) {
+		return nil
+	}
+	if total < 0 {
+		return []string{fmt.Sprintf("non_negative_total: total is negative: %.2f", total)}
+	}
+	return nil
+}
+
+func checkCoverCountPresent(row ParsedRow) []string {
+	s, _ := row.Mapped["covers"].(string)
+	if s == "" {
+		return []string{"cover_count_present: covers is missing"}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return []string{fmt.Sprintf("cover_count_present: covers must be a whole number of at least 1: %s", s)}
+	}
+	return nil
+}
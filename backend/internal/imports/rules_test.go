@@ -0,0 +1,103 @@
+package imports
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTotalReconcilesRule(t *testing.T) {
+	const csv = "Date,Subtotal,Tax,Total\n" +
+		"2024-03-01,100.00,10.00,110.00\n" + // exact
+		"2024-03-01,45.45,4.55,50.01\n" + // a cent of rounding is allowed
+		"2024-03-01,90.00,9.00,100.00\n" + // a dollar out
+		"2024-03-01,,2.00,22.00\n" + // nothing to reconcile against
+		"2024-03-01,abc,1.00,11.00\n"
+	columns := map[string]string{"Date": "date", "Subtotal": "subtotal", "Tax": "tax", "Total": "total"}
+
+	result, err := NewParser("pos", &MappingProfile{ColumnMaps: columns, Rules: []string{"total_reconciles"}}).Parse(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := [][]string{
+		nil,
+		nil,
+		{"total_reconciles: total 100.00 does not equal subtotal 90.00 + tax 9.00"},
+		{"total_reconciles: subtotal is missing"},
+		{"total_reconciles: invalid subtotal: abc"},
+	}
+	if len(result.Rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(result.Rows), len(want))
+	}
+	for i, row := range result.Rows {
+		if !reflect.DeepEqual(row.Errors, want[i]) {
+			t.Errorf("line %d errors = %q, want %q", row.LineNumber, row.Errors, want[i])
+		}
+	}
+	if result.ValidRows != 2 || result.ErrorRows != 3 {
+		t.Errorf("valid, error rows = %d, %d, want 2, 3", result.ValidRows, result.ErrorRows)
+	}
+
+	// Without the rule enabled the mismatched row is accepted
+	result, err = NewParser("pos", &MappingProfile{ColumnMaps: columns}).Parse(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if errs := result.Rows[2].Errors; len(errs) != 0 {
+		t.Errorf("line %d errors = %q without the rule, want none", result.Rows[2].LineNumber, errs)
+	}
+}
+
+func TestBuiltInRules(t *testing.T) {
+	row := func(fields map[string]interface{}) ParsedRow { return ParsedRow{Mapped: fields} }
+
+	tests := []struct {
+		name string
+		rule func(ParsedRow) []string
+		row  ParsedRow
+		want []string
+	}{
+		{name: "negative total", rule: checkNonNegativeTotal, row: row(map[string]interface{}{"total": "-12.50"}), want: []string{"non_negative_total: total is negative: -12.50"}},
+		{name: "refund", rule: checkNonNegativeTotal, row: row(map[string]interface{}{"total": "-12.50", "refund": "true"})},
+		{name: "zero total", rule: checkNonNegativeTotal, row: row(map[string]interface{}{"total": "0"})},
+		{name: "covers present", rule: checkCoverCountPresent, row: row(map[string]interface{}{"covers": "2"})},
+		{name: "covers missing", rule: checkCoverCountPresent, row: row(map[string]interface{}{}), want: []string{"cover_count_present: covers is missing"}},
+		{name: "covers zero", rule: checkCoverCountPresent, row: row(map[string]interface{}{"covers": "0"}), want: []string{"cover_count_present: covers must be a whole number of at least 1: 0"}},
+		{name: "covers fractional", rule: checkCoverCountPresent, row: row(map[string]interface{}{"covers": "1.5"}), want: []string{"cover_count_present: covers must be a whole number of at least 1: 1.5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule(tt.row); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("check = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		sourceType string
+		names      []string
+		wantErr    string
+	}{
+		{sourceType: "pos", names: []string{"total_reconciles", "cover_count_present"}},
+		{sourceType: "pos", names: []string{"totals_match"}, wantErr: `unknown validation rule "totals_match"`},
+		{sourceType: "payroll", names: []string{"total_reconciles"}, wantErr: `validation rule "total_reconciles" does not apply to payroll imports`},
+		{sourceType: "pos", names: []string{"total_reconciles", "total_reconciles"}, wantErr: `validation rule "total_reconciles" is listed twice`},
+	}
+
+	for _, tt := range tests {
+		err := ValidateRules(tt.sourceType, tt.names)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("ValidateRules(%s, %q) error = %v", tt.sourceType, tt.names, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("ValidateRules(%s, %q) error = %v, want %q", tt.sourceType, tt.names, err, tt.wantErr)
+		}
+	}
+}
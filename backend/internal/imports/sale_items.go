@@ -0,0 +1,151 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// processSaleItemRow records one line item of a sale imported from a POS file.
+// The line is attached to the sale with the same external_id on the row's
+// local day, and to the menu item with its SKU, or failing that its name, so
+// the line's recipe cost counts towards COGS. Lines whose menu item does not
+// exist yet are kept with their SKU and name and recorded as warnings.
+func (p *Pipeline) processSaleItemRow(ctx context.Context, db rowExecutor, job *ImportJob, row ParsedRow) error {
+	dateStr, _ := row.Mapped["date"].(string)
+	date, err := parseDate(dateStr)
+	if err != nil {
+		return fmt.Errorf("invalid date: %w", err)
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	externalID, _ := row.Mapped["external_id"].(string)
+	if externalID == "" {
+		return fmt.Errorf("external_id is required")
+	}
+
+	qtyStr, _ := row.Mapped["quantity"].(string)
+	qty, err := parseAmount(qtyStr)
+	if err != nil {
+		return fmt.Errorf("invalid quantity: %w", err)
+	}
+
+	var unitPrice, lineTotal, discounts, comps float64
+	if v, ok := row.Mapped["unit_price"].(string); ok && v != "" {
+		if unitPrice, err = parseAmount(v); err != nil {
+			return fmt.Errorf("invalid unit_price: %w", err)
+		}
+	}
+	// Prefer the line total when provided, otherwise derive it
+	lineTotal = qty * unitPrice
+	if v, ok := row.Mapped["line_total"].(string); ok && v != "" {
+		if lineTotal, err = parseAmount(v); err != nil {
+			return fmt.Errorf("invalid line_total: %w", err)
+		}
+		if unitPrice == 0 && qty != 0 {
+			unitPrice = lineTotal / qty
+		}
+	}
+	if v, ok := row.Mapped["discounts"].(string); ok && v != "" {
+		discounts, _ = parseAmount(v)
+	}
+	if v, ok := row.Mapped["comps"].(string); ok && v != "" {
+		comps, _ = parseAmount(v)
+	}
+
+	var saleID uuid.UUID
+	saleQuery := `
+		SELECT id FROM sales
+		WHERE location_id = $1 AND external_id = $2 AND occurred_at >= $3 AND occurred_at < $4
+		ORDER BY occurred_at
+		LIMIT 1
+	`
+	err = db.QueryRow(ctx, saleQuery, job.LocationID, externalID, job.localTime(day), job.localTime(day.AddDate(0, 0, 1))).Scan(&saleID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("no sale with external_id %s on %s; import the POS sales first", externalID, day.Format("2006-01-02"))
+	}
+	if err != nil {
+		return err
+	}
+
+	sku := optionalString(row.Mapped, "sku")
+	itemName := optionalString(row.Mapped, "item_name")
+	menuItemID, err := findMenuItem(ctx, db, sku, itemName)
+	if err != nil {
+		return fmt.Errorf("failed to look up menu item: %w", err)
+	}
+
+	// Upsert the line using file hash + row number as key for idempotency
+	query := `
+		INSERT INTO sale_lines (id, sale_id, location_id, menu_item_id, sku, item_name, quantity, unit_price, line_subtotal, line_discounts, line_comps, import_source, source_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+		ON CONFLICT (location_id, import_source, source_id) DO UPDATE SET
+			sale_id = EXCLUDED.sale_id,
+			menu_item_id = EXCLUDED.menu_item_id,
+			sku = EXCLUDED.sku,
+			item_name = EXCLUDED.item_name,
+			quantity = EXCLUDED.quantity,
+			unit_price = EXCLUDED.unit_price,
+			line_subtotal = EXCLUDED.line_subtotal,
+			line_discounts = EXCLUDED.line_discounts,
+			line_comps = EXCLUDED.line_comps,
+			updated_at = NOW()
+	`
+
+	sourceID := fmt.Sprintf("%s-%d", job.FileHash[:8], row.LineNumber)
+
+	_, err = db.Exec(ctx, query,
+		uuid.New(),
+		saleID,
+		job.LocationID,
+		menuItemID,
+		sku,
+		itemName,
+		qty,
+		unitPrice,
+		lineTotal,
+		discounts,
+		comps,
+		"csv-import",
+		sourceID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// The message leaves out the item so the anomaly cap groups them
+	if menuItemID == nil {
+		return rowWarning("no menu item matches this line's sku or name; its recipe cost is not counted in COGS until one is added")
+	}
+	return nil
+}
+
+// findMenuItem returns the menu item with the given SKU, or with the given
+// name when there is no SKU match, matched case-insensitively. It returns nil
+// when neither matches.
+func findMenuItem(ctx context.Context, db rowExecutor, sku, name *string) (*uuid.UUID, error) {
+	var id uuid.UUID
+	if sku != nil {
+		err := db.QueryRow(ctx, `SELECT id FROM menu_items WHERE LOWER(sku) = LOWER($1)`, *sku).Scan(&id)
+		if err == nil {
+			return &id, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+	if name != nil {
+		err := db.QueryRow(ctx, `SELECT id FROM menu_items WHERE LOWER(name) = LOWER($1) ORDER BY is_active DESC, created_at LIMIT 1`, *name).Scan(&id)
+		if err == nil {
+			return &id, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
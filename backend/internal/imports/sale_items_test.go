@@ -0,0 +1,207 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeSaleLines holds sales by external_id and menu items by lower-cased SKU
+// and name, and records the sale lookups and line writes
+type fakeSaleLines struct {
+	sales      map[string]uuid.UUID
+	menuBySKU  map[string]uuid.UUID
+	menuByName map[string]uuid.UUID
+	saleArgs   [][]interface{}
+	args       [][]interface{}
+}
+
+func (f *fakeSaleLines) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	f.args = append(f.args, args)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (f *fakeSaleLines) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	var id uuid.UUID
+	var ok bool
+	switch {
+	case strings.Contains(sql, "FROM sales"):
+		f.saleArgs = append(f.saleArgs, args)
+		id, ok = f.sales[args[1].(string)]
+	case strings.Contains(sql, "LOWER(sku)"):
+		id, ok = f.menuBySKU[strings.ToLower(args[0].(string))]
+	case strings.Contains(sql, "LOWER(name)"):
+		id, ok = f.menuByName[strings.ToLower(args[0].(string))]
+	}
+	if !ok {
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+	return fakeRow{id: id}
+}
+
+func TestProcessSaleItemRow(t *testing.T) {
+	saleID, burger, fries := uuid.New(), uuid.New(), uuid.New()
+	job := &ImportJob{LocationID: uuid.New(), FileHash: "abcdef0123456789"}
+
+	tests := []struct {
+		name        string
+		mapped      map[string]interface{}
+		wantItem    *uuid.UUID
+		wantQty     float64
+		wantPrice   float64
+		wantTotal   float64
+		wantWarning bool
+		wantErr     string
+	}{
+		{
+			name:      "matched by sku",
+			mapped:    map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1001", "sku": "burg-01", "quantity": "2", "unit_price": "18.50"},
+			wantItem:  &burger,
+			wantQty:   2,
+			wantPrice: 18.5,
+			wantTotal: 37,
+		},
+		{
+			name:      "falls back to name",
+			mapped:    map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1001", "sku": "UNKNOWN", "item_name": "Fries", "quantity": "3", "line_total": "15.00"},
+			wantItem:  &fries,
+			wantQty:   3,
+			wantPrice: 5,
+			wantTotal: 15,
+		},
+		{
+			name:        "no menu item",
+			mapped:      map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1001", "item_name": "Daily special", "quantity": "1", "unit_price": "24"},
+			wantQty:     1,
+			wantPrice:   24,
+			wantTotal:   24,
+			wantWarning: true,
+		},
+		{
+			name:    "no sale",
+			mapped:  map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-9999", "sku": "BURG-01", "quantity": "1"},
+			wantErr: "no sale with external_id ORD-9999 on 2024-03-04",
+		},
+		{
+			name:    "bad quantity",
+			mapped:  map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1001", "sku": "BURG-01", "quantity": "two"},
+			wantErr: "invalid quantity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeSaleLines{
+				sales:      map[string]uuid.UUID{"ORD-1001": saleID},
+				menuBySKU:  map[string]uuid.UUID{"burg-01": burger},
+				menuByName: map[string]uuid.UUID{"fries": fries},
+			}
+			err := (&Pipeline{}).processSaleItemRow(context.Background(), db, job, ParsedRow{LineNumber: 4, Mapped: tt.mapped})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("processSaleItemRow() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(db.args) != 0 {
+					t.Error("a rejected line was written")
+				}
+				return
+			}
+
+			var warning rowWarning
+			switch {
+			case tt.wantWarning:
+				if !errors.As(err, &warning) {
+					t.Errorf("processSaleItemRow() error = %v, want a warning", err)
+				}
+			case err != nil:
+				t.Fatalf("processSaleItemRow() error = %v", err)
+			}
+
+			// Lines without a menu item are still kept so they can be linked later
+			if len(db.args) != 1 {
+				t.Fatalf("wrote %d lines, want 1", len(db.args))
+			}
+			args := db.args[0]
+			if got := args[1].(uuid.UUID); got != saleID {
+				t.Errorf("sale_id = %v, want %v", got, saleID)
+			}
+			if got := args[3].(*uuid.UUID); (got == nil) != (tt.wantItem == nil) || (got != nil && *got != *tt.wantItem) {
+				t.Errorf("menu_item_id = %v, want %v", got, tt.wantItem)
+			}
+			if args[6] != tt.wantQty || args[7] != tt.wantPrice || args[8] != tt.wantTotal {
+				t.Errorf("quantity, unit_price, line_subtotal = %v, %v, %v, want %v, %v, %v", args[6], args[7], args[8], tt.wantQty, tt.wantPrice, tt.wantTotal)
+			}
+			if got := args[12].(string); got != "abcdef01-4" {
+				t.Errorf("source_id = %q, want abcdef01-4", got)
+			}
+		})
+	}
+}
+
+func TestProcessSaleItemRowLocalDay(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip(err)
+	}
+	job := &ImportJob{LocationID: uuid.New(), FileHash: "abcdef0123456789", loc: la}
+	db := &fakeSaleLines{sales: map[string]uuid.UUID{"ORD-1001": uuid.New()}}
+
+	row := ParsedRow{LineNumber: 2, Mapped: map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1001", "item_name": "Fries", "quantity": "1"}}
+	if err := (&Pipeline{}).processSaleItemRow(context.Background(), db, job, row); err != nil && !errors.As(err, new(rowWarning)) {
+		t.Fatalf("processSaleItemRow() error = %v", err)
+	}
+
+	// The parent sale is looked up within the location's day, not UTC's
+	if len(db.saleArgs) != 1 {
+		t.Fatalf("looked up %d sales, want 1", len(db.saleArgs))
+	}
+	from, to := db.saleArgs[0][2].(time.Time), db.saleArgs[0][3].(time.Time)
+	wantFrom := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+	if !from.Equal(wantFrom) || !to.Equal(wantFrom.Add(24*time.Hour)) {
+		t.Errorf("sale window = %v to %v, want %v to %v", from.UTC(), to.UTC(), wantFrom, wantFrom.Add(24*time.Hour))
+	}
+}
+
+func TestValidateSaleItemRow(t *testing.T) {
+	p := &Parser{sourceType: "sale_items"}
+	tests := []struct {
+		name   string
+		mapped map[string]interface{}
+		want   []string
+	}{
+		{
+			name:   "valid",
+			mapped: map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1", "sku": "BURG-01", "quantity": "2"},
+		},
+		{
+			name:   "no sku or name",
+			mapped: map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1", "quantity": "2"},
+			want:   []string{"missing required field: sku or item_name"},
+		},
+		{
+			name:   "no sale",
+			mapped: map[string]interface{}{"date": "2024-03-04", "item_name": "Fries", "quantity": "2"},
+			want:   []string{"missing required field: external_id"},
+		},
+		{
+			name:   "bad numbers",
+			mapped: map[string]interface{}{"date": "2024-03-04", "external_id": "ORD-1", "sku": "BURG-01", "quantity": "2", "unit_price": "cheap"},
+			want:   []string{"invalid numeric value for unit_price: cheap"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.validateSaleItemRow(ParsedRow{Mapped: tt.mapped})
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("validateSaleItemRow() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
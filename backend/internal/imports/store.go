@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -21,20 +22,28 @@ func NewImportStore(db *pgxpool.Pool) *ImportStore {
 // CreateJob creates a new import job
 func (s *ImportStore) CreateJob(ctx context.Context, job *ImportJob) error {
 	query := `
-		INSERT INTO import_jobs (id, source_type, status, file_name, file_hash, total_rows, processed_rows, error_rows, location_id, mapping_id, created_by_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO import_jobs (id, source_type, status, file_name, file_hash, file_path, total_rows, processed_rows, error_rows, location_id, mapping_id, atomic, conflict_strategy, idempotency_key, created_by_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 	`
+	var idempotencyKey *string
+	if job.IdempotencyKey != "" {
+		idempotencyKey = &job.IdempotencyKey
+	}
 	_, err := s.db.Exec(ctx, query,
 		job.ID,
 		job.SourceType,
 		job.Status,
 		job.FileName,
 		job.FileHash,
+		job.FilePath,
 		job.TotalRows,
 		job.ProcessedRows,
 		job.ErrorRows,
 		job.LocationID,
 		job.MappingID,
+		job.Atomic,
+		job.ConflictStrategy,
+		idempotencyKey,
 		job.CreatedByID,
 		job.CreatedAt,
 	)
@@ -44,7 +53,7 @@ func (s *ImportStore) CreateJob(ctx context.Context, job *ImportJob) error {
 // GetJobByID retrieves an import job by ID
 func (s *ImportStore) GetJobByID(ctx context.Context, id uuid.UUID) (*ImportJob, error) {
 	query := `
-		SELECT id, source_type, status, file_name, file_hash, total_rows, processed_rows, error_rows, location_id, mapping_id, created_by_id, created_at, completed_at, error_message
+		SELECT id, source_type, status, file_name, file_hash, file_path, total_rows, processed_rows, error_rows, location_id, mapping_id, atomic, conflict_strategy, created_by_id, created_at, completed_at, error_message, data_start, data_end
 		FROM import_jobs
 		WHERE id = $1
 	`
@@ -56,15 +65,20 @@ func (s *ImportStore) GetJobByID(ctx context.Context, id uuid.UUID) (*ImportJob,
 		&job.Status,
 		&job.FileName,
 		&job.FileHash,
+		&job.FilePath,
 		&job.TotalRows,
 		&job.ProcessedRows,
 		&job.ErrorRows,
 		&job.LocationID,
 		&job.MappingID,
+		&job.Atomic,
+		&job.ConflictStrategy,
 		&job.CreatedByID,
 		&job.CreatedAt,
 		&job.CompletedAt,
 		&job.ErrorMessage,
+		&job.DataStart,
+		&job.DataEnd,
 	)
 	if err != nil {
 		return nil, err
@@ -72,10 +86,26 @@ func (s *ImportStore) GetJobByID(ctx context.Context, id uuid.UUID) (*ImportJob,
 	return &job, nil
 }
 
+// GetByIdempotencyKey retrieves the import job a location started with the
+// given idempotency key since the given time
+func (s *ImportStore) GetByIdempotencyKey(ctx context.Context, locationID uuid.UUID, key string, since time.Time) (*ImportJob, error) {
+	var id uuid.UUID
+	err := s.db.QueryRow(ctx, `
+		SELECT id FROM import_jobs
+		WHERE location_id = $1 AND idempotency_key = $2 AND created_at >= $3
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, locationID, key, since).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetJobByID(ctx, id)
+}
+
 // GetByFileHash retrieves an import job by file hash
 func (s *ImportStore) GetByFileHash(ctx context.Context, fileHash string, locationID uuid.UUID) (*ImportJob, error) {
 	query := `
-		SELECT id, source_type, status, file_name, file_hash, total_rows, processed_rows, error_rows, location_id, mapping_id, created_by_id, created_at, completed_at, error_message
+		SELECT id, source_type, status, file_name, file_hash, file_path, total_rows, processed_rows, error_rows, location_id, mapping_id, atomic, conflict_strategy, created_by_id, created_at, completed_at, error_message, data_start, data_end
 		FROM import_jobs
 		WHERE file_hash = $1 AND location_id = $2
 		ORDER BY created_at DESC
@@ -89,15 +119,20 @@ func (s *ImportStore) GetByFileHash(ctx context.Context, fileHash string, locati
 		&job.Status,
 		&job.FileName,
 		&job.FileHash,
+		&job.FilePath,
 		&job.TotalRows,
 		&job.ProcessedRows,
 		&job.ErrorRows,
 		&job.LocationID,
 		&job.MappingID,
+		&job.Atomic,
+		&job.ConflictStrategy,
 		&job.CreatedByID,
 		&job.CreatedAt,
 		&job.CompletedAt,
 		&job.ErrorMessage,
+		&job.DataStart,
+		&job.DataEnd,
 	)
 	if err != nil {
 		return nil, err
@@ -110,10 +145,13 @@ func (s *ImportStore) UpdateJobStatus(ctx context.Context, id uuid.UUID, status
 	var query string
 	var args []interface{}
 
-	if status == "completed" || status == "failed" {
+	if status == "completed" || status == "failed" || status == "cancelled" || status == "interrupted" {
 		now := time.Now()
 		query = `UPDATE import_jobs SET status = $1, error_message = $2, completed_at = $3 WHERE id = $4`
 		args = []interface{}{status, errorMsg, now, id}
+	} else if status == "processing" {
+		query = `UPDATE import_jobs SET status = $1, error_message = $2, started_at = NOW() WHERE id = $3`
+		args = []interface{}{status, errorMsg, id}
 	} else {
 		query = `UPDATE import_jobs SET status = $1, error_message = $2 WHERE id = $3`
 		args = []interface{}{status, errorMsg, id}
@@ -127,8 +165,8 @@ func (s *ImportStore) UpdateJobStatus(ctx context.Context, id uuid.UUID, status
 func (s *ImportStore) UpdateJob(ctx context.Context, job *ImportJob) error {
 	query := `
 		UPDATE import_jobs
-		SET status = $1, total_rows = $2, processed_rows = $3, error_rows = $4, completed_at = $5, error_message = $6
-		WHERE id = $7
+		SET status = $1, total_rows = $2, processed_rows = $3, error_rows = $4, completed_at = $5, error_message = $6, data_start = $7, data_end = $8
+		WHERE id = $9
 	`
 	_, err := s.db.Exec(ctx, query,
 		job.Status,
@@ -137,6 +175,8 @@ func (s *ImportStore) UpdateJob(ctx context.Context, job *ImportJob) error {
 		job.ErrorRows,
 		job.CompletedAt,
 		job.ErrorMessage,
+		job.DataStart,
+		job.DataEnd,
 		job.ID,
 	)
 	return err
@@ -145,7 +185,7 @@ func (s *ImportStore) UpdateJob(ctx context.Context, job *ImportJob) error {
 // ListJobs retrieves import jobs for a location
 func (s *ImportStore) ListJobs(ctx context.Context, locationID uuid.UUID, limit int) ([]ImportJob, error) {
 	query := `
-		SELECT id, source_type, status, file_name, file_hash, total_rows, processed_rows, error_rows, location_id, mapping_id, created_by_id, created_at, completed_at, error_message
+		SELECT id, source_type, status, file_name, file_hash, file_path, total_rows, processed_rows, error_rows, location_id, mapping_id, atomic, conflict_strategy, created_by_id, created_at, completed_at, error_message, data_start, data_end
 		FROM import_jobs
 		WHERE location_id = $1
 		ORDER BY created_at DESC
@@ -167,15 +207,67 @@ func (s *ImportStore) ListJobs(ctx context.Context, locationID uuid.UUID, limit
 			&job.Status,
 			&job.FileName,
 			&job.FileHash,
+			&job.FilePath,
+			&job.TotalRows,
+			&job.ProcessedRows,
+			&job.ErrorRows,
+			&job.LocationID,
+			&job.MappingID,
+			&job.Atomic,
+			&job.ConflictStrategy,
+			&job.CreatedByID,
+			&job.CreatedAt,
+			&job.CompletedAt,
+			&job.ErrorMessage,
+			&job.DataStart,
+			&job.DataEnd,
+		)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// ListStaleJobs returns jobs that have been processing since before the cutoff
+func (s *ImportStore) ListStaleJobs(ctx context.Context, startedBefore time.Time) ([]ImportJob, error) {
+	query := `
+		SELECT id, source_type, status, file_name, file_hash, file_path, total_rows, processed_rows, error_rows, location_id, mapping_id, atomic, conflict_strategy, created_by_id, created_at, completed_at, error_message, data_start, data_end
+		FROM import_jobs
+		WHERE status = 'processing' AND COALESCE(started_at, created_at) < $1
+		ORDER BY created_at
+	`
+
+	rows, err := s.db.Query(ctx, query, startedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []ImportJob
+	for rows.Next() {
+		var job ImportJob
+		err := rows.Scan(
+			&job.ID,
+			&job.SourceType,
+			&job.Status,
+			&job.FileName,
+			&job.FileHash,
+			&job.FilePath,
 			&job.TotalRows,
 			&job.ProcessedRows,
 			&job.ErrorRows,
 			&job.LocationID,
 			&job.MappingID,
+			&job.Atomic,
+			&job.ConflictStrategy,
 			&job.CreatedByID,
 			&job.CreatedAt,
 			&job.CompletedAt,
 			&job.ErrorMessage,
+			&job.DataStart,
+			&job.DataEnd,
 		)
 		if err != nil {
 			return nil, err
@@ -185,6 +277,35 @@ func (s *ImportStore) ListJobs(ctx context.Context, locationID uuid.UUID, limit
 	return jobs, rows.Err()
 }
 
+// ClaimStaleJob moves a job that is still processing since before the cutoff
+// back to pending, returning false if it has since moved on or another
+// instance claimed it first
+func (s *ImportStore) ClaimStaleJob(ctx context.Context, id uuid.UUID, startedBefore time.Time) (bool, error) {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE import_jobs SET status = 'pending'
+		WHERE id = $1 AND status = 'processing' AND COALESCE(started_at, created_at) < $2
+	`, id, startedBefore)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ResetJob clears a job's anomalies and row counts and returns it to pending so it can be reprocessed
+func (s *ImportStore) ResetJob(ctx context.Context, id uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM import_anomalies WHERE import_job_id = $1`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			UPDATE import_jobs
+			SET status = 'pending', total_rows = 0, processed_rows = 0, error_rows = 0, completed_at = NULL, error_message = '', data_start = NULL, data_end = NULL
+			WHERE id = $1
+		`, id)
+		return err
+	})
+}
+
 // CreateAnomaly creates an import anomaly record
 func (s *ImportStore) CreateAnomaly(ctx context.Context, anomaly *ImportAnomaly) error {
 	query := `
@@ -203,6 +324,79 @@ func (s *ImportStore) CreateAnomaly(ctx context.Context, anomaly *ImportAnomaly)
 	return err
 }
 
+// AnomalyFilter selects a page of an import job's anomalies
+type AnomalyFilter struct {
+	JobID    uuid.UUID
+	Severity string // optional: error or warning
+	Page     int    // 1-based
+	PageSize int
+}
+
+// ListAnomalies retrieves a page of an import job's anomalies in line order,
+// along with the total number of matching anomalies
+func (s *ImportStore) ListAnomalies(ctx context.Context, filter AnomalyFilter) ([]ImportAnomaly, int, error) {
+	where := `
+		FROM import_anomalies
+		WHERE import_job_id = $1
+		AND ($2 = '' OR severity = $2)
+	`
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) `+where, filter.JobID, filter.Severity).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, import_job_id, line_number, severity, message, raw_data, created_at
+	` + where + `
+		ORDER BY line_number, created_at
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := s.db.Query(ctx, query, filter.JobID, filter.Severity, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	anomalies := []ImportAnomaly{}
+	for rows.Next() {
+		var a ImportAnomaly
+		if err := rows.Scan(&a.ID, &a.ImportJobID, &a.LineNumber, &a.Severity, &a.Message, &a.RawData, &a.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, total, rows.Err()
+}
+
+// EachAnomaly calls fn for every anomaly of an import job in line order,
+// optionally only those of one severity, without loading them all at once
+func (s *ImportStore) EachAnomaly(ctx context.Context, jobID uuid.UUID, severity string, fn func(ImportAnomaly) error) error {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, import_job_id, line_number, severity, message, raw_data, created_at
+		FROM import_anomalies
+		WHERE import_job_id = $1
+		AND ($2 = '' OR severity = $2)
+		ORDER BY line_number, created_at
+	`, jobID, severity)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a ImportAnomaly
+		if err := rows.Scan(&a.ID, &a.ImportJobID, &a.LineNumber, &a.Severity, &a.Message, &a.RawData, &a.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // GetAnomaliesForJob retrieves anomalies for an import job
 func (s *ImportStore) GetAnomaliesForJob(ctx context.Context, jobID uuid.UUID) ([]ImportAnomaly, error) {
 	query := `
@@ -0,0 +1,160 @@
+package kpi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ClosedDay marks a date on which a location did not trade
+type ClosedDay struct {
+	ID         uuid.UUID  `json:"id"`
+	LocationID uuid.UUID  `json:"location_id"`
+	Date       time.Time  `json:"date"`
+	Reason     string     `json:"reason,omitempty"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ListClosedDays retrieves closed days for a location within a date range
+func (s *Store) ListClosedDays(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]ClosedDay, error) {
+	query := `
+		SELECT id, location_id, date, COALESCE(reason, ''), created_by, created_at
+		FROM closed_days
+		WHERE location_id = $1 AND date >= $2 AND date <= $3
+		ORDER BY date
+	`
+
+	rows, err := s.db.Query(ctx, query, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []ClosedDay
+	for rows.Next() {
+		var d ClosedDay
+		if err := rows.Scan(&d.ID, &d.LocationID, &d.Date, &d.Reason, &d.CreatedBy, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// CreateClosedDay marks a date as closed, updating the reason if it is already marked
+func (s *Store) CreateClosedDay(ctx context.Context, day *ClosedDay) error {
+	query := `
+		INSERT INTO closed_days (id, location_id, date, reason, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (location_id, date) DO UPDATE SET reason = EXCLUDED.reason
+		RETURNING id, created_at
+	`
+	day.ID = uuid.New()
+	day.CreatedAt = time.Now()
+
+	err := s.db.QueryRow(ctx, query, day.ID, day.LocationID, day.Date, day.Reason, day.CreatedBy, day.CreatedAt).
+		Scan(&day.ID, &day.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(ctx, `UPDATE kpi_aggregates SET is_closed = TRUE WHERE location_id = $1 AND date = $2`, day.LocationID, day.Date)
+	if err != nil {
+		return err
+	}
+	return s.refreshRollupIfUsed(ctx)
+}
+
+// DeleteClosedDay removes a closed-day marker, returning false if none matched
+func (s *Store) DeleteClosedDay(ctx context.Context, id, locationID uuid.UUID) (bool, error) {
+	var date time.Time
+	err := s.db.QueryRow(ctx, `DELETE FROM closed_days WHERE id = $1 AND location_id = $2 RETURNING date`, id, locationID).Scan(&date)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	_, err = s.db.Exec(ctx, `UPDATE kpi_aggregates SET is_closed = FALSE WHERE location_id = $1 AND date = $2`, locationID, date)
+	if err != nil {
+		return true, err
+	}
+	return true, s.refreshRollupIfUsed(ctx)
+}
+
+// GetClosedDates returns the set of closed dates (YYYY-MM-DD) for a location within a range
+func (s *Store) GetClosedDates(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) (map[string]bool, error) {
+	rows, err := s.db.Query(ctx, `SELECT DISTINCT date FROM closed_days WHERE date >= $1 AND date <= $2 AND location_id = $3`, startDate, endDate, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dates := make(map[string]bool)
+	for rows.Next() {
+		var d time.Time
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		dates[d.Format("2006-01-02")] = true
+	}
+	return dates, rows.Err()
+}
+
+// DailyPoint represents one day of the KPI series
+type DailyPoint struct {
+	Date        string  `json:"date"`
+	Revenue     float64 `json:"revenue"`
+	COGS        float64 `json:"cogs"`
+	GrossMargin float64 `json:"gross_margin"`
+	LaborCost   float64 `json:"labor_cost"`
+	NetProfit   float64 `json:"net_profit"`
+	Covers      int     `json:"covers"`
+	AvgCheck    float64 `json:"avg_check"`
+	Closed      bool    `json:"closed"`
+}
+
+// GetDailySeries retrieves per-day KPI totals for a location and date range (days without data are omitted)
+func (s *Store) GetDailySeries(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]DailyPoint, error) {
+	query := `
+		SELECT
+			date,
+			COALESCE(SUM(revenue), 0) as revenue,
+			COALESCE(SUM(cogs), 0) as cogs,
+			COALESCE(SUM(gross_margin), 0) as gross_margin,
+			COALESCE(SUM(labor_cost), 0) as labor_cost,
+			COALESCE(SUM(net_profit), 0) as net_profit,
+			COALESCE(SUM(covers), 0) as covers,
+			CASE WHEN SUM(covers) > 0 THEN SUM(revenue) / SUM(covers) ELSE 0 END as avg_check,
+			BOOL_OR(is_closed) as closed
+		FROM ` + s.aggregatesFrom("day") + `
+		WHERE date >= $1 AND date <= $2 AND location_id = $3
+		GROUP BY date
+		ORDER BY date
+	`
+
+	rows, err := s.db.Query(ctx, query, startDate, endDate, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var series []DailyPoint
+	for rows.Next() {
+		var p DailyPoint
+		var date time.Time
+		err := rows.Scan(&date, &p.Revenue, &p.COGS, &p.GrossMargin, &p.LaborCost,
+			&p.NetProfit, &p.Covers, &p.AvgCheck, &p.Closed)
+		if err != nil {
+			return nil, err
+		}
+		p.Date = date.Format("2006-01-02")
+		series = append(series, p)
+	}
+	return series, rows.Err()
+}
@@ -0,0 +1,72 @@
+package kpi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// A closed day's costs and covers must not drag down the period's averages,
+// and it stays distinct from an open day that took nothing
+func TestAveragesIgnoreClosedDays(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locationID := testLocation(t, pool, "Closed Days Test")
+	store := NewStore(pool)
+
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	for _, a := range []struct {
+		date    time.Time
+		revenue float64
+		labor   float64
+		covers  int
+	}{
+		{date: day(1), revenue: 1000, labor: 250, covers: 40},
+		{date: day(2), revenue: 800, labor: 200, covers: 40},
+		// Closed for a private function: staff paid, no sales
+		{date: day(3), labor: 100, covers: 10},
+		// 4 May is open but has no aggregates
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO kpi_aggregates (date, location_id, revenue, labor_cost, covers)
+			VALUES ($1, $2, $3, $4, $5)
+		`, a.date, locationID, a.revenue, a.labor, a.covers); err != nil {
+			t.Fatalf("seed aggregates: %v", err)
+		}
+	}
+	if err := store.CreateClosedDay(ctx, &ClosedDay{LocationID: locationID, Date: day(3), Reason: "Renovation"}); err != nil {
+		t.Fatalf("CreateClosedDay() error = %v", err)
+	}
+
+	got, err := NewService(store).GetDailyKPIs(ctx, locationID, day(1), day(4), "custom")
+	if err != nil {
+		t.Fatalf("GetDailyKPIs() error = %v", err)
+	}
+
+	totals := got.Totals
+	if totals.OpenDays != 3 || totals.ClosedDays != 1 {
+		t.Errorf("open, closed days = %d, %d, want 3, 1", totals.OpenDays, totals.ClosedDays)
+	}
+	if totals.AvgDailyRevenue != 600 {
+		t.Errorf("AvgDailyRevenue = %v, want 600 (1800 over 3 open days)", totals.AvgDailyRevenue)
+	}
+	if totals.AvgCheck != 22.5 {
+		t.Errorf("AvgCheck = %v, want 22.5 (closed day's covers excluded)", totals.AvgCheck)
+	}
+	if totals.LaborPct != 25 {
+		t.Errorf("LaborPct = %v, want 25 (closed day's labor excluded)", totals.LaborPct)
+	}
+	// Totals still carry every dollar spent
+	if totals.LaborCost != 550 {
+		t.Errorf("LaborCost = %v, want 550", totals.LaborCost)
+	}
+
+	if len(got.Daily) != 4 {
+		t.Fatalf("got %d daily points, want 4", len(got.Daily))
+	}
+	for i, want := range []bool{false, false, true, false} {
+		if got.Daily[i].Closed != want {
+			t.Errorf("%s closed = %v, want %v", got.Daily[i].Date, got.Daily[i].Closed, want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package kpi
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultCOGSVarianceThreshold is the percent by which actual COGS may exceed
+// theoretical COGS before a category is flagged
+const DefaultCOGSVarianceThreshold = 10.0
+
+// COGSVarianceRow compares theoretical and actual COGS for one category
+type COGSVarianceRow struct {
+	Category    string   `json:"category"`
+	Theoretical float64  `json:"theoretical"`
+	Purchases   float64  `json:"purchases"`
+	Actual      float64  `json:"actual"`
+	Variance    float64  `json:"variance"`
+	VariancePct *float64 `json:"variance_pct"`
+	Flagged     bool     `json:"flagged"`
+}
+
+// COGSVarianceResponse represents the theoretical vs actual COGS comparison
+type COGSVarianceResponse struct {
+	Range            string            `json:"range"`
+	StartDate        string            `json:"start_date"`
+	EndDate          string            `json:"end_date"`
+	ThresholdPct     float64           `json:"threshold_pct"`
+	Theoretical      float64           `json:"theoretical"`
+	Actual           float64           `json:"actual"`
+	Variance         float64           `json:"variance"`
+	VariancePct      *float64          `json:"variance_pct"`
+	HasRecipeData    bool              `json:"has_recipe_data"`
+	HasInventoryData bool              `json:"has_inventory_data"`
+	Categories       []COGSVarianceRow `json:"categories"`
+	Notes            []string          `json:"notes,omitempty"`
+}
+
+// GetTheoreticalCOGSByCategory sums recipe cost of items sold at a location,
+// grouped by menu category
+func (s *Store) GetTheoreticalCOGSByCategory(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) (map[string]float64, error) {
+	query := `
+		SELECT
+			COALESCE(NULLIF(mi.category, ''), 'Uncategorized') as category,
+			COALESCE(SUM(sl.quantity * mi.recipe_cost), 0) as cogs
+		FROM sale_lines sl
+		JOIN sales s ON sl.sale_id = s.id
+		JOIN menu_items mi ON sl.menu_item_id = mi.id
+		WHERE s.occurred_at >= $1 AND s.occurred_at <= $2 AND s.location_id = $3
+		GROUP BY 1
+	`
+	return s.queryCategoryAmounts(ctx, query, startDate, endDate, locationID)
+}
+
+// GetActualCOGSByCategory computes the inventory change (opening value -
+// closing value) per category from the snapshots nearest each period boundary.
+// Purchases are added separately to give movement-based COGS.
+func (s *Store) GetActualCOGSByCategory(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) (map[string]float64, error) {
+	query := `
+		WITH opening AS (
+			SELECT DISTINCT ON (item_name) item_name, COALESCE(NULLIF(category, ''), 'Uncategorized') as category, total_value
+			FROM inventory_snapshots
+			WHERE snapshot_date <= $1 AND location_id = $3
+			ORDER BY item_name, snapshot_date DESC
+		), closing AS (
+			SELECT DISTINCT ON (item_name) item_name, COALESCE(NULLIF(category, ''), 'Uncategorized') as category, total_value
+			FROM inventory_snapshots
+			WHERE snapshot_date <= $2 AND location_id = $3
+			ORDER BY item_name, snapshot_date DESC
+		)
+		SELECT
+			COALESCE(c.category, o.category) as category,
+			COALESCE(SUM(o.total_value), 0) - COALESCE(SUM(c.total_value), 0) as cogs
+		FROM opening o
+		FULL OUTER JOIN closing c ON o.item_name = c.item_name
+		GROUP BY 1
+	`
+	return s.queryCategoryAmounts(ctx, query, startDate, endDate, locationID)
+}
+
+// HasInventorySnapshots reports whether a location has snapshots on or before
+// both period boundaries
+func (s *Store) HasInventorySnapshots(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) (bool, error) {
+	query := `
+		SELECT
+			EXISTS(SELECT 1 FROM inventory_snapshots WHERE snapshot_date <= $1 AND location_id = $3),
+			EXISTS(SELECT 1 FROM inventory_snapshots WHERE snapshot_date > $1 AND snapshot_date <= $2 AND location_id = $3)
+	`
+	var hasOpening, hasClosing bool
+	if err := s.db.QueryRow(ctx, query, startDate, endDate, locationID).Scan(&hasOpening, &hasClosing); err != nil {
+		return false, err
+	}
+	return hasOpening && hasClosing, nil
+}
+
+func (s *Store) queryCategoryAmounts(ctx context.Context, query string, args ...interface{}) (map[string]float64, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	amounts := make(map[string]float64)
+	for rows.Next() {
+		var category string
+		var amount float64
+		if err := rows.Scan(&category, &amount); err != nil {
+			return nil, err
+		}
+		amounts[category] += amount
+	}
+	return amounts, rows.Err()
+}
+
+// GetCOGSVariance compares recipe-based and inventory-movement COGS for a
+// location and period
+func (s *Service) GetCOGSVariance(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel string, thresholdPct float64) (*COGSVarianceResponse, error) {
+	theoretical, err := s.store.GetTheoreticalCOGSByCategory(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	hasInventory, err := s.store.HasInventorySnapshots(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	actual := map[string]float64{}
+	purchases := map[string]float64{}
+	if hasInventory {
+		stockChange, err := s.store.GetActualCOGSByCategory(ctx, locationID, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		purchases, err = s.store.GetPurchasesByCategory(ctx, locationID, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		actual = movementCOGS(stockChange, purchases)
+	}
+
+	return compareCOGS(theoretical, actual, purchases, hasInventory, thresholdPct, startDate, endDate, rangeLabel), nil
+}
+
+// movementCOGS combines the per-category stock change (opening - closing
+// inventory) with purchases: actual COGS = opening + purchases - closing
+func movementCOGS(stockChange, purchases map[string]float64) map[string]float64 {
+	actual := make(map[string]float64, len(stockChange))
+	for category, amount := range stockChange {
+		actual[category] = amount
+	}
+	for category, amount := range purchases {
+		actual[category] += amount
+	}
+	return actual
+}
+
+// compareCOGS builds the variance response from per-category theoretical,
+// actual and purchase amounts, flagging categories whose actual COGS exceeds
+// theoretical by more than thresholdPct
+func compareCOGS(theoretical, actual, purchases map[string]float64, hasInventory bool, thresholdPct float64, startDate, endDate time.Time, rangeLabel string) *COGSVarianceResponse {
+	response := &COGSVarianceResponse{
+		Range:            rangeLabel,
+		StartDate:        startDate.Format("2006-01-02"),
+		EndDate:          endDate.Format("2006-01-02"),
+		ThresholdPct:     thresholdPct,
+		HasRecipeData:    len(theoretical) > 0,
+		HasInventoryData: hasInventory,
+		Categories:       []COGSVarianceRow{},
+	}
+	if !response.HasRecipeData {
+		response.Notes = append(response.Notes, "no sale lines with recipe costs in period; theoretical COGS is zero")
+	}
+	if !response.HasInventoryData {
+		response.Notes = append(response.Notes, "inventory snapshots are required at or before both the start and end of the period to compute actual COGS")
+	}
+
+	categories := make(map[string]bool)
+	for c := range theoretical {
+		categories[c] = true
+	}
+	for c := range actual {
+		categories[c] = true
+	}
+
+	for category := range categories {
+		row := COGSVarianceRow{
+			Category:    category,
+			Theoretical: roundTo2(theoretical[category]),
+			Purchases:   roundTo2(purchases[category]),
+			Actual:      roundTo2(actual[category]),
+		}
+		row.Variance = roundTo2(row.Actual - row.Theoretical)
+		row.VariancePct = percentOf(row.Variance, row.Theoretical)
+		// Only flag when both methods are available; otherwise the gap is missing data, not waste
+		if hasInventory && response.HasRecipeData {
+			row.Flagged = row.Actual > row.Theoretical*(1+thresholdPct/100)
+		}
+
+		response.Theoretical += row.Theoretical
+		response.Actual += row.Actual
+		response.Categories = append(response.Categories, row)
+	}
+
+	sort.Slice(response.Categories, func(i, j int) bool {
+		return response.Categories[i].Category < response.Categories[j].Category
+	})
+
+	response.Theoretical = roundTo2(response.Theoretical)
+	response.Actual = roundTo2(response.Actual)
+	response.Variance = roundTo2(response.Actual - response.Theoretical)
+	response.VariancePct = percentOf(response.Variance, response.Theoretical)
+
+	return response
+}
+
+// percentOf returns part as a percentage of whole, or nil when whole is zero
+func percentOf(part, whole float64) *float64 {
+	if whole == 0 {
+		return nil
+	}
+	pct := roundTo2(part / whole * 100)
+	return &pct
+}
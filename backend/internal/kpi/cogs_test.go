@@ -0,0 +1,213 @@
+package kpi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCompareCOGS(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	// Meat loses a fifth more stock than recipes account for, produce is
+	// within the threshold and beverages only show up in inventory
+	theoretical := map[string]float64{"Meat": 1000, "Produce": 400}
+	purchases := map[string]float64{"Meat": 900, "Produce": 300, "Beverages": 50}
+	actual := map[string]float64{"Meat": 1200, "Produce": 420, "Beverages": 80}
+
+	got := compareCOGS(theoretical, actual, purchases, true, DefaultCOGSVarianceThreshold, start, end, "custom")
+
+	want := map[string]struct {
+		variance float64
+		flagged  bool
+	}{
+		"Beverages": {variance: 80, flagged: true},
+		"Meat":      {variance: 200, flagged: true},
+		"Produce":   {variance: 20},
+	}
+	if len(got.Categories) != len(want) {
+		t.Fatalf("got %d categories, want %d", len(got.Categories), len(want))
+	}
+	for i, row := range got.Categories {
+		if i > 0 && got.Categories[i-1].Category >= row.Category {
+			t.Errorf("categories not sorted: %q before %q", got.Categories[i-1].Category, row.Category)
+		}
+		w, ok := want[row.Category]
+		if !ok {
+			t.Errorf("unexpected category %q", row.Category)
+			continue
+		}
+		if row.Variance != w.variance || row.Flagged != w.flagged {
+			t.Errorf("%s: variance %.2f flagged %v, want %.2f flagged %v", row.Category, row.Variance, row.Flagged, w.variance, w.flagged)
+		}
+	}
+	if got.Categories[0].VariancePct != nil {
+		t.Errorf("Beverages VariancePct = %v, want nil without theoretical COGS", *got.Categories[0].VariancePct)
+	}
+	if got.Theoretical != 1400 || got.Actual != 1700 || got.Variance != 300 {
+		t.Errorf("totals theoretical %.2f actual %.2f variance %.2f, want 1400, 1700, 300", got.Theoretical, got.Actual, got.Variance)
+	}
+	if got.VariancePct == nil || *got.VariancePct != 21.43 {
+		t.Errorf("VariancePct = %v, want 21.43", got.VariancePct)
+	}
+
+	// A higher threshold tolerates the meat gap
+	got = compareCOGS(theoretical, actual, purchases, true, 25, start, end, "custom")
+	for _, row := range got.Categories {
+		if row.Category == "Meat" && row.Flagged {
+			t.Error("Meat flagged at a 25% threshold")
+		}
+	}
+}
+
+// Without both recipe and inventory data the gap is missing data, not waste
+func TestCompareCOGSMissingData(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		theoretical  map[string]float64
+		actual       map[string]float64
+		hasInventory bool
+	}{
+		{name: "no inventory", theoretical: map[string]float64{"Meat": 1000}, actual: map[string]float64{}},
+		{name: "no recipes", theoretical: map[string]float64{}, actual: map[string]float64{"Meat": 1200}, hasInventory: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareCOGS(tt.theoretical, tt.actual, map[string]float64{}, tt.hasInventory, DefaultCOGSVarianceThreshold, start, end, "custom")
+			if len(got.Notes) == 0 {
+				t.Error("no note explaining the missing data")
+			}
+			for _, row := range got.Categories {
+				if row.Flagged {
+					t.Errorf("%s flagged with missing data", row.Category)
+				}
+			}
+		})
+	}
+}
+
+// Purchases are consumed stock, so they raise actual COGS on top of the
+// inventory drawdown
+func TestRoundTo2(t *testing.T) {
+	for in, want := range map[float64]float64{
+		1.005: 1, 1.006: 1.01, 12.344: 12.34, 12.345: 12.35,
+		-80: -80, -12.344: -12.34, -12.346: -12.35, -0.001: 0,
+	} {
+		if got := roundTo2(in); got != want {
+			t.Errorf("roundTo2(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestMovementCOGS(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	theoretical := map[string]float64{"Meat": 1000, "Produce": 400}
+	// Meat stock fell by 300 and produce rose by 80 over the month
+	stockChange := map[string]float64{"Meat": 300, "Produce": -80}
+
+	before := compareCOGS(theoretical, movementCOGS(stockChange, nil), nil, true, DefaultCOGSVarianceThreshold, start, end, "custom")
+	purchases := map[string]float64{"Meat": 900, "Produce": 500, "Beverages": 50}
+	after := compareCOGS(theoretical, movementCOGS(stockChange, purchases), purchases, true, DefaultCOGSVarianceThreshold, start, end, "custom")
+
+	if before.Actual != 220 {
+		t.Errorf("actual before purchases = %v, want 220", before.Actual)
+	}
+	if after.Actual != 1670 {
+		t.Errorf("actual after purchases = %v, want 1670", after.Actual)
+	}
+
+	want := map[string]struct {
+		actual    float64
+		purchases float64
+		flagged   bool
+	}{
+		"Beverages": {actual: 50, purchases: 50, flagged: true},
+		"Meat":      {actual: 1200, purchases: 900, flagged: true},
+		"Produce":   {actual: 420, purchases: 500},
+	}
+	for _, row := range after.Categories {
+		w := want[row.Category]
+		if row.Actual != w.actual || row.Purchases != w.purchases || row.Flagged != w.flagged {
+			t.Errorf("%s: actual %v purchases %v flagged %v, want %v %v %v", row.Category, row.Actual, row.Purchases, row.Flagged, w.actual, w.purchases, w.flagged)
+		}
+	}
+
+	// The inputs are left as they were
+	if stockChange["Meat"] != 300 || len(stockChange) != 2 {
+		t.Errorf("movementCOGS modified the stock change: %v", stockChange)
+	}
+}
+
+// TestTheoreticalCOGSScopedToLocation checks recipe-cost COGS only counts
+// items sold at the requested location
+func TestTheoreticalCOGSScopedToLocation(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	day := time.Date(2001, 10, 3, 12, 0, 0, 0, time.UTC)
+
+	menuItems := map[string]uuid.UUID{}
+	for _, item := range []struct {
+		name, category string
+		cost           float64
+	}{{"Burger", "Mains", 4.5}, {"Chips", "Sides", 1.25}} {
+		var id uuid.UUID
+		if err := pool.QueryRow(ctx, `
+			INSERT INTO menu_items (name, category, recipe_cost) VALUES ($1, $2, $3) RETURNING id
+		`, item.name+" "+uuid.NewString()[:8], item.category, item.cost).Scan(&id); err != nil {
+			t.Fatalf("create menu item: %v", err)
+		}
+		menuItems[item.name] = id
+	}
+	// Registered first so it runs after the locations' sales are gone
+	t.Cleanup(func() {
+		for _, id := range menuItems {
+			if _, err := pool.Exec(ctx, `DELETE FROM menu_items WHERE id = $1`, id); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+	})
+
+	locationID := testLocation(t, pool, "COGS scope test")
+	otherID := testLocation(t, pool, "COGS scope other")
+	daypartID := seededDaypart(t, pool)
+
+	for _, s := range []struct {
+		locationID uuid.UUID
+		lines      map[string]float64 // menu item -> quantity
+	}{
+		{locationID, map[string]float64{"Burger": 2, "Chips": 4}},
+		{otherID, map[string]float64{"Burger": 100, "Chips": 100}},
+	} {
+		var saleID uuid.UUID
+		if err := pool.QueryRow(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total)
+			VALUES ($1, $2, $3, $4, 50, 50) RETURNING id
+		`, day, s.locationID, testChannel(t, pool, s.locationID, "Dine In"), daypartID).Scan(&saleID); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+		for name, quantity := range s.lines {
+			if _, err := pool.Exec(ctx, `
+				INSERT INTO sale_lines (sale_id, menu_item_id, quantity, location_id) VALUES ($1, $2, $3, $4)
+			`, saleID, menuItems[name], quantity, s.locationID); err != nil {
+				t.Fatalf("insert sale line: %v", err)
+			}
+		}
+	}
+
+	got, err := NewStore(pool).GetTheoreticalCOGSByCategory(ctx, locationID, day.Add(-12*time.Hour), day.Add(12*time.Hour))
+	if err != nil {
+		t.Fatalf("GetTheoreticalCOGSByCategory: %v", err)
+	}
+	if want := map[string]float64{"Mains": 9, "Sides": 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("theoretical COGS = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package kpi
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MetricChange is the change in one metric versus the prior period
+type MetricChange struct {
+	Current  float64  `json:"current"`
+	Previous float64  `json:"previous"`
+	Delta    float64  `json:"delta"`
+	DeltaPct *float64 `json:"delta_pct"` // null when the prior value was zero
+}
+
+// PeriodComparison compares a range with the immediately preceding range of
+// the same length, e.g. the last 7 days against the 7 days before them. A ytd
+// range is compared with the same stretch of the previous fiscal year.
+type PeriodComparison struct {
+	PreviousStart string       `json:"previous_start"`
+	PreviousEnd   string       `json:"previous_end"`
+	Revenue       MetricChange `json:"revenue"`
+	NetProfit     MetricChange `json:"net_profit"`
+	Covers        MetricChange `json:"covers"`
+	AvgCheck      MetricChange `json:"avg_check"`
+}
+
+// previousPeriod returns the range a period is compared with: the same
+// number of calendar days ending the day before startDate, or for ytd the
+// same dates one year earlier
+func previousPeriod(startDate, endDate time.Time, rangeLabel string) (time.Time, time.Time) {
+	if rangeLabel == "ytd" {
+		return startDate.AddDate(-1, 0, 0), endDate.AddDate(-1, 0, 0)
+	}
+	days := int(calendarDate(endDate).Sub(calendarDate(startDate)).Hours()/24) + 1
+	prevEnd := startDate.AddDate(0, 0, -1)
+	return prevEnd.AddDate(0, 0, -(days - 1)), prevEnd
+}
+
+// calendarDate strips the time of day so day counts ignore it
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// comparePeriods loads totals for the period before startDate..endDate and
+// reports the change from it to current
+func (s *Service) comparePeriods(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel string, current *KPITotals) (*PeriodComparison, error) {
+	prevStart, prevEnd := previousPeriod(startDate, endDate, rangeLabel)
+	previous, err := s.store.GetTotals(ctx, locationID, prevStart, prevEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PeriodComparison{
+		PreviousStart: prevStart.Format("2006-01-02"),
+		PreviousEnd:   prevEnd.Format("2006-01-02"),
+		Revenue:       metricChange(current.Revenue, previous.Revenue),
+		NetProfit:     metricChange(current.NetProfit, previous.NetProfit),
+		Covers:        metricChange(float64(current.Covers), float64(previous.Covers)),
+		AvgCheck:      metricChange(current.AvgCheck, previous.AvgCheck),
+	}, nil
+}
+
+// metricChange computes absolute and percent change. Percent change is
+// relative to the magnitude of the prior value so a loss shrinking reads as
+// an improvement, and is omitted when the prior value was zero.
+func metricChange(current, previous float64) MetricChange {
+	c := MetricChange{
+		Current:  roundSigned2(current),
+		Previous: roundSigned2(previous),
+		Delta:    roundSigned2(current - previous),
+	}
+	if previous != 0 {
+		pct := roundSigned2((current - previous) / math.Abs(previous) * 100)
+		c.DeltaPct = &pct
+	}
+	return c
+}
@@ -0,0 +1,67 @@
+package kpi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetricChange(t *testing.T) {
+	pct := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name              string
+		current, previous float64
+		wantDelta         float64
+		wantPct           *float64
+	}{
+		{name: "growth", current: 1500, previous: 1200, wantDelta: 300, wantPct: pct(25)},
+		{name: "decline", current: 900, previous: 1200, wantDelta: -300, wantPct: pct(-25)},
+		{name: "no prior revenue", current: 850.5, previous: 0, wantDelta: 850.5},
+		{name: "nothing either period", current: 0, previous: 0, wantDelta: 0},
+		// A loss of 200 narrowing to 50 is an improvement
+		{name: "loss shrinking", current: -50, previous: -200, wantDelta: 150, wantPct: pct(75)},
+		{name: "rounded", current: 100, previous: 30, wantDelta: 70, wantPct: pct(233.33)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := metricChange(tt.current, tt.previous)
+			if got.Current != tt.current || got.Previous != tt.previous || got.Delta != tt.wantDelta {
+				t.Errorf("metricChange() = %+v, want delta %v", got, tt.wantDelta)
+			}
+			switch {
+			case tt.wantPct == nil && got.DeltaPct != nil:
+				t.Errorf("delta pct = %v, want none", *got.DeltaPct)
+			case tt.wantPct != nil && (got.DeltaPct == nil || *got.DeltaPct != *tt.wantPct):
+				t.Errorf("delta pct = %v, want %v", got.DeltaPct, *tt.wantPct)
+			}
+		})
+	}
+}
+
+func TestPreviousPeriod(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		rangeLabel string
+		wantStart  time.Time
+		wantEnd    time.Time
+	}{
+		{name: "week", start: day(2024, 3, 4), end: day(2024, 3, 10), rangeLabel: "custom", wantStart: day(2024, 2, 26), wantEnd: day(2024, 3, 3)},
+		// The end is the last second of its day, which must not add a day
+		{name: "end of day", start: day(2024, 3, 1), end: time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC), rangeLabel: "mtd", wantStart: day(2024, 1, 30), wantEnd: day(2024, 2, 29)},
+		{name: "single day", start: day(2024, 1, 1), end: day(2024, 1, 1), rangeLabel: "custom", wantStart: day(2023, 12, 31), wantEnd: day(2023, 12, 31)},
+		{name: "ytd", start: day(2024, 7, 1), end: day(2024, 9, 15), rangeLabel: "ytd", wantStart: day(2023, 7, 1), wantEnd: day(2023, 9, 15)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := previousPeriod(tt.start, tt.end, tt.rangeLabel)
+			if !calendarDate(start).Equal(tt.wantStart) || !calendarDate(end).Equal(tt.wantEnd) {
+				t.Errorf("previousPeriod() = %s..%s, want %s..%s", start.Format("2006-01-02"), end.Format("2006-01-02"), tt.wantStart.Format("2006-01-02"), tt.wantEnd.Format("2006-01-02"))
+			}
+		})
+	}
+}
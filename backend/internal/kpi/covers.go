@@ -0,0 +1,25 @@
+package kpi
+
+import "math"
+
+// Covers are always whole guests. They are stored as INT on kpi_aggregates and
+// summed as BIGINT, so only computed paths (such as splitting a location's
+// covers across channels) can produce fractions. Those paths must round with
+// RoundCovers before the value is stored or used for avg_check.
+
+// RoundCovers converts a fractional guest count to whole covers. Halves round
+// away from zero (2.5 -> 3) and negative values clamp to zero.
+func RoundCovers(v float64) int {
+	if v <= 0 || math.IsNaN(v) {
+		return 0
+	}
+	return int(math.Round(v))
+}
+
+// AvgCheck returns revenue per cover rounded to cents, or zero when there are no covers
+func AvgCheck(revenue float64, covers int) float64 {
+	if covers <= 0 {
+		return 0
+	}
+	return roundTo2(revenue / float64(covers))
+}
@@ -0,0 +1,74 @@
+package kpi
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRoundCovers(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want int
+	}{
+		{in: 0, want: 0},
+		{in: 2.4, want: 2},
+		{in: 2.5, want: 3},
+		{in: 3.5, want: 4},
+		{in: 0.49, want: 0},
+		{in: -1.5, want: 0},
+		{in: math.NaN(), want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := RoundCovers(tt.in); got != tt.want {
+			t.Errorf("RoundCovers(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAvgCheck(t *testing.T) {
+	tests := []struct {
+		revenue float64
+		covers  int
+		want    float64
+	}{
+		{revenue: 100, covers: 4, want: 25},
+		{revenue: 100, covers: 3, want: 33.33},
+		{revenue: 200, covers: 3, want: 66.67},
+		{revenue: 100, covers: 0, want: 0},
+		{revenue: -30, covers: 2, want: -15},
+	}
+
+	for _, tt := range tests {
+		if got := AvgCheck(tt.revenue, tt.covers); got != tt.want {
+			t.Errorf("AvgCheck(%v, %d) = %v, want %v", tt.revenue, tt.covers, got, tt.want)
+		}
+	}
+}
+
+// Splitting a day's covers by revenue share gives fractional guests; rounded
+// per channel they stay whole and each channel's average check stays close to
+// the day's
+func TestAllocatedCoversAvgCheck(t *testing.T) {
+	const dayCovers = 7
+	revenue := []float64{250, 100, 50} // dine in, takeaway, delivery
+	dayAvg := AvgCheck(400, dayCovers)
+
+	for i, r := range revenue {
+		share := float64(dayCovers) * r / 400
+		covers := RoundCovers(share)
+		if covers != int(math.Round(share)) {
+			t.Errorf("channel %d: %v covers rounded to %d", i, share, covers)
+		}
+		if covers == 0 {
+			continue
+		}
+		avg := AvgCheck(r, covers)
+		if avg != math.Round(avg*100)/100 {
+			t.Errorf("channel %d: avg check %v is not in cents", i, avg)
+		}
+		if avg < dayAvg/2 || avg > dayAvg*2 {
+			t.Errorf("channel %d: avg check %v strays from the day's %v", i, avg, dayAvg)
+		}
+	}
+}
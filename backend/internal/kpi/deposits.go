@@ -0,0 +1,116 @@
+package kpi
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultDepositTolerance is the shortfall, in currency units, a day may have
+// before it is flagged; it absorbs float and rounding differences in the till
+const DefaultDepositTolerance = 5.0
+
+// DepositDay compares one day's POS cash sales with the cash banked for it
+type DepositDay struct {
+	Date       string  `json:"date"`
+	CashSales  float64 `json:"cash_sales"`
+	Deposited  float64 `json:"deposited"`
+	Difference float64 `json:"difference"` // deposited minus cash sales; negative is a shortfall
+	Short      bool    `json:"short"`      // shortfall larger than the tolerance
+}
+
+// DepositReconciliation compares daily cash sales against bank deposits
+type DepositReconciliation struct {
+	Range          string       `json:"range"`
+	Tolerance      float64      `json:"tolerance"`
+	CashSales      float64      `json:"cash_sales"`
+	Deposited      float64      `json:"deposited"`
+	Difference     float64      `json:"difference"`
+	ShortDays      int          `json:"short_days"`
+	ShortfallTotal float64      `json:"shortfall_total"` // sum of the flagged days' shortfalls
+	Days           []DepositDay `json:"days"`
+}
+
+// GetDepositDays returns cash sales and deposits per day for a location and
+// date range, including days that have only one of the two. Sales count as
+// cash when their payment method is cash, ignoring case.
+func (s *Store) GetDepositDays(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]DepositDay, error) {
+	query := `
+		WITH cash AS (
+			SELECT (s.occurred_at AT TIME ZONE l.timezone)::date as date, SUM(s.total) as amount
+			FROM sales s
+			JOIN locations l ON l.id = s.location_id
+			WHERE s.location_id = $1 AND s.occurred_at >= $2 AND s.occurred_at <= $3
+				AND LOWER(TRIM(s.payment_method)) = 'cash'
+			GROUP BY 1
+		), banked AS (
+			SELECT deposit_date as date, SUM(amount) as amount
+			FROM deposits
+			WHERE location_id = $1 AND deposit_date >= $2::date AND deposit_date <= $3::date
+			GROUP BY 1
+		)
+		SELECT COALESCE(c.date, b.date) as date, COALESCE(c.amount, 0), COALESCE(b.amount, 0)
+		FROM cash c
+		FULL OUTER JOIN banked b ON b.date = c.date
+		ORDER BY 1
+	`
+
+	rows, err := s.db.Query(ctx, query, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []DepositDay{}
+	for rows.Next() {
+		var d DepositDay
+		var date time.Time
+		if err := rows.Scan(&date, &d.CashSales, &d.Deposited); err != nil {
+			return nil, err
+		}
+		d.Date = date.Format("2006-01-02")
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// ReconcileDeposits compares daily cash sales with bank deposits, flagging
+// days where less was banked than taken in cash by more than tolerance
+func (s *Service) ReconcileDeposits(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel string, tolerance float64) (*DepositReconciliation, error) {
+	days, err := s.store.GetDepositDays(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return reconcileDeposits(days, rangeLabel, tolerance), nil
+}
+
+// reconcileDeposits fills in each day's difference and shortfall flag and the range totals
+func reconcileDeposits(days []DepositDay, rangeLabel string, tolerance float64) *DepositReconciliation {
+	result := &DepositReconciliation{Range: rangeLabel, Tolerance: tolerance, Days: days}
+	for i := range days {
+		d := &days[i]
+		d.CashSales = roundTo2(d.CashSales)
+		d.Deposited = roundTo2(d.Deposited)
+		d.Difference = roundSigned2(d.Deposited - d.CashSales)
+		d.Short = -d.Difference > tolerance
+		if d.Short {
+			result.ShortDays++
+			result.ShortfallTotal += math.Abs(d.Difference)
+		}
+		result.CashSales += d.CashSales
+		result.Deposited += d.Deposited
+	}
+	result.CashSales = roundTo2(result.CashSales)
+	result.Deposited = roundTo2(result.Deposited)
+	result.Difference = roundSigned2(result.Deposited - result.CashSales)
+	result.ShortfallTotal = roundTo2(result.ShortfallTotal)
+	return result
+}
+
+// roundSigned2 rounds to cents half away from zero; roundTo2 is only correct
+// for non-negative values
+func roundSigned2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
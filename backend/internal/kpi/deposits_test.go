@@ -0,0 +1,110 @@
+package kpi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReconcileDeposits(t *testing.T) {
+	days := []DepositDay{
+		{Date: "2024-03-01", CashSales: 820.40, Deposited: 820.40},  // banked in full
+		{Date: "2024-03-02", CashSales: 615.00, Deposited: 560.00},  // 55.00 short
+		{Date: "2024-03-03", CashSales: 300.00, Deposited: 296.50},  // short, but within tolerance
+		{Date: "2024-03-04", CashSales: 410.25, Deposited: 0},       // nothing banked
+		{Date: "2024-03-05", CashSales: 0, Deposited: 150.00},       // a late deposit
+		{Date: "2024-03-06", CashSales: 200.00, Deposited: 194.995}, // exactly at tolerance after rounding
+	}
+
+	got := reconcileDeposits(days, "7d", DefaultDepositTolerance)
+
+	wantShort := []bool{false, true, false, true, false, false}
+	wantDiff := []float64{0, -55, -3.5, -410.25, 150, -5}
+	for i, d := range got.Days {
+		if d.Short != wantShort[i] || d.Difference != wantDiff[i] {
+			t.Errorf("%s short, difference = %v, %v, want %v, %v", d.Date, d.Short, d.Difference, wantShort[i], wantDiff[i])
+		}
+	}
+
+	want := DepositReconciliation{
+		Range:          "7d",
+		Tolerance:      DefaultDepositTolerance,
+		CashSales:      2345.65,
+		Deposited:      2021.90,
+		Difference:     -323.75,
+		ShortDays:      2,
+		ShortfallTotal: 465.25,
+	}
+	got.Days = nil
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("reconcileDeposits() totals = %+v, want %+v", *got, want)
+	}
+}
+
+func TestGetDepositDays(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locationID := testLocation(t, pool, "Deposit reconciliation test")
+	channelID := testChannel(t, pool, locationID, "Dine In")
+	daypartID := seededDaypart(t, pool)
+
+	if _, err := pool.Exec(ctx, `UPDATE locations SET timezone = 'Australia/Brisbane' WHERE id = $1`, locationID); err != nil {
+		t.Fatalf("set timezone: %v", err)
+	}
+
+	for _, s := range []struct {
+		at     string
+		method string
+		total  float64
+	}{
+		{at: "2024-03-01T02:00:00Z", method: "Cash", total: 400},
+		{at: "2024-03-01T05:00:00Z", method: " cash ", total: 215},
+		{at: "2024-03-01T06:00:00Z", method: "card", total: 999}, // not cash
+		{at: "2024-03-01T15:00:00Z", method: "cash", total: 80},  // 01:00 on the 2nd in Brisbane
+	} {
+		at, err := time.Parse(time.RFC3339, s.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total, payment_method)
+			VALUES ($1, $2, $3, $4, $5, $5, $6)
+		`, at, locationID, channelID, daypartID, s.total, s.method); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+	}
+	for _, d := range []struct {
+		date   string
+		amount float64
+	}{
+		{date: "2024-03-01", amount: 560},
+		{date: "2024-03-03", amount: 50},
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO deposits (location_id, deposit_date, amount) VALUES ($1, $2, $3)
+		`, locationID, d.date, d.amount); err != nil {
+			t.Fatalf("insert deposit: %v", err)
+		}
+	}
+
+	svc := NewService(NewStore(pool))
+	start := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	got, err := svc.ReconcileDeposits(ctx, locationID, start, end, "custom", DefaultDepositTolerance)
+	if err != nil {
+		t.Fatalf("ReconcileDeposits() error = %v", err)
+	}
+
+	want := []DepositDay{
+		{Date: "2024-03-01", CashSales: 615, Deposited: 560, Difference: -55, Short: true},
+		{Date: "2024-03-02", CashSales: 80, Difference: -80, Short: true},
+		{Date: "2024-03-03", Deposited: 50, Difference: 50},
+	}
+	if !reflect.DeepEqual(got.Days, want) {
+		t.Errorf("days = %+v, want %+v", got.Days, want)
+	}
+	if got.ShortDays != 2 || got.ShortfallTotal != 135 {
+		t.Errorf("short days = %d totalling %v, want 2 totalling 135", got.ShortDays, got.ShortfallTotal)
+	}
+}
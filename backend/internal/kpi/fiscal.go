@@ -0,0 +1,83 @@
+package kpi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// FiscalYearStart is the month and day a location's fiscal year begins
+type FiscalYearStart struct {
+	Month time.Month `json:"month"`
+	Day   int        `json:"day"`
+}
+
+// DefaultFiscalYearStart is a calendar year
+var DefaultFiscalYearStart = FiscalYearStart{Month: time.January, Day: 1}
+
+// on returns the fiscal year start in the given year, moving a day past the
+// end of a short month (e.g. 29 February) back to its last day
+func (f FiscalYearStart) on(year int, loc *time.Location) time.Time {
+	last := time.Date(year, f.Month+1, 0, 0, 0, 0, 0, loc).Day()
+	day := f.Day
+	if day > last {
+		day = last
+	}
+	return time.Date(year, f.Month, day, 0, 0, 0, 0, loc)
+}
+
+// YearStart returns the start of the fiscal year containing t
+func (f FiscalYearStart) YearStart(t time.Time) time.Time {
+	start := f.on(t.Year(), t.Location())
+	if start.After(t) {
+		start = f.on(t.Year()-1, t.Location())
+	}
+	return start
+}
+
+// QuarterStart returns the start of the fiscal quarter containing t
+func (f FiscalYearStart) QuarterStart(t time.Time) time.Time {
+	year := f.YearStart(t)
+	for q := 3; q > 0; q-- {
+		if start := year.AddDate(0, 3*q, 0); !start.After(t) {
+			return start
+		}
+	}
+	return year
+}
+
+// GetFiscalYearStart returns a location's fiscal year start. uuid.Nil, used by
+// reports that span all locations, resolves to the only location of a
+// single-venue deployment and otherwise to a calendar year.
+func (s *Store) GetFiscalYearStart(ctx context.Context, locationID uuid.UUID) (FiscalYearStart, error) {
+	if locationID == uuid.Nil {
+		id, err := s.DefaultLocationID(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DefaultFiscalYearStart, nil
+		}
+		if err != nil {
+			return FiscalYearStart{}, err
+		}
+		locationID = id
+	}
+
+	var month, day int16
+	err := s.db.QueryRow(ctx, `
+		SELECT fiscal_year_start_month, fiscal_year_start_day FROM locations WHERE id = $1
+	`, locationID).Scan(&month, &day)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DefaultFiscalYearStart, nil
+	}
+	if err != nil {
+		return FiscalYearStart{}, err
+	}
+	return FiscalYearStart{Month: time.Month(month), Day: int(day)}, nil
+}
+
+// FiscalYearStart returns the fiscal year start ytd ranges use for a location
+func (s *Service) FiscalYearStart(ctx context.Context, locationID uuid.UUID) (FiscalYearStart, error) {
+	return s.store.GetFiscalYearStart(ctx, locationID)
+}
@@ -0,0 +1,75 @@
+package kpi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestYTDWithJulyFiscalStart(t *testing.T) {
+	brisbane, err := time.LoadLocation("Australia/Brisbane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	july := FiscalYearStart{Month: time.July, Day: 1}
+
+	tests := []struct {
+		name      string
+		fy        FiscalYearStart
+		reference time.Time
+		wantStart time.Time
+	}{
+		{name: "september", fy: july, reference: time.Date(2024, 9, 15, 12, 0, 0, 0, brisbane), wantStart: time.Date(2024, 7, 1, 0, 0, 0, 0, brisbane)},
+		// Before July the fiscal year began in the previous calendar year
+		{name: "march", fy: july, reference: time.Date(2024, 3, 10, 12, 0, 0, 0, brisbane), wantStart: time.Date(2023, 7, 1, 0, 0, 0, 0, brisbane)},
+		{name: "first day", fy: july, reference: time.Date(2024, 7, 1, 0, 0, 0, 0, brisbane), wantStart: time.Date(2024, 7, 1, 0, 0, 0, 0, brisbane)},
+		{name: "calendar year", fy: DefaultFiscalYearStart, reference: time.Date(2024, 9, 15, 12, 0, 0, 0, brisbane), wantStart: time.Date(2024, 1, 1, 0, 0, 0, 0, brisbane)},
+		// A 29 February start falls on the 28th in other years
+		{name: "leap day start", fy: FiscalYearStart{Month: time.February, Day: 29}, reference: time.Date(2023, 9, 15, 12, 0, 0, 0, brisbane), wantStart: time.Date(2023, 2, 28, 0, 0, 0, 0, brisbane)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := ParseDateRange("ytd", tt.reference, tt.fy)
+			if !start.Equal(tt.wantStart) {
+				t.Errorf("ytd start = %v, want %v", start, tt.wantStart)
+			}
+			wantEnd := time.Date(tt.reference.Year(), tt.reference.Month(), tt.reference.Day(), 23, 59, 59, 0, brisbane)
+			if !end.Equal(wantEnd) {
+				t.Errorf("ytd end = %v, want %v", end, wantEnd)
+			}
+		})
+	}
+}
+
+func TestFiscalQuarterStart(t *testing.T) {
+	july := FiscalYearStart{Month: time.July, Day: 1}
+
+	tests := []struct {
+		date time.Time
+		want time.Time
+	}{
+		{date: time.Date(2024, 9, 15, 0, 0, 0, 0, time.UTC), want: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{date: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), want: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{date: time.Date(2025, 2, 14, 0, 0, 0, 0, time.UTC), want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{date: time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC), want: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := july.QuarterStart(tt.date); !got.Equal(tt.want) {
+			t.Errorf("QuarterStart(%s) = %s, want %s", tt.date.Format("2006-01-02"), got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestYTDComparisonUsesPriorFiscalYear(t *testing.T) {
+	brisbane, err := time.LoadLocation("Australia/Brisbane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end := ParseDateRange("ytd", time.Date(2024, 9, 15, 12, 0, 0, 0, brisbane), FiscalYearStart{Month: time.July, Day: 1})
+
+	prevStart, prevEnd := previousPeriod(start, end, "ytd")
+	if got := prevStart.Format("2006-01-02") + ".." + prevEnd.Format("2006-01-02"); got != "2023-07-01..2023-09-15" {
+		t.Errorf("previous period = %s, want 2023-07-01..2023-09-15", got)
+	}
+}
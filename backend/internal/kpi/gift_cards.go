@@ -0,0 +1,66 @@
+package kpi
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GiftCardSummary represents gift card activity and the outstanding liability
+type GiftCardSummary struct {
+	Range                string  `json:"range"`
+	OpeningLiability     float64 `json:"opening_liability"`
+	Issued               float64 `json:"issued"`
+	IssuedCount          int     `json:"issued_count"`
+	Redeemed             float64 `json:"redeemed"`
+	RedeemedCount        int     `json:"redeemed_count"`
+	OutstandingLiability float64 `json:"outstanding_liability"` // as of the end of the range
+}
+
+// GetGiftCardSummary retrieves a location's gift card issuance and redemption
+// for a date range, plus the liability outstanding before and at the end of
+// the range
+func (s *Store) GetGiftCardSummary(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) (*GiftCardSummary, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(CASE WHEN entry_type = 'issue' THEN amount ELSE -amount END) FILTER (WHERE occurred_at < $1), 0) as opening,
+			COALESCE(SUM(amount) FILTER (WHERE entry_type = 'issue' AND occurred_at >= $1), 0) as issued,
+			COUNT(*) FILTER (WHERE entry_type = 'issue' AND occurred_at >= $1) as issued_count,
+			COALESCE(SUM(amount) FILTER (WHERE entry_type = 'redeem' AND occurred_at >= $1), 0) as redeemed,
+			COUNT(*) FILTER (WHERE entry_type = 'redeem' AND occurred_at >= $1) as redeemed_count
+		FROM gift_card_ledger
+		WHERE occurred_at <= $2 AND location_id = $3
+	`
+
+	var summary GiftCardSummary
+	err := s.db.QueryRow(ctx, query, startDate, endDate, locationID).Scan(
+		&summary.OpeningLiability,
+		&summary.Issued,
+		&summary.IssuedCount,
+		&summary.Redeemed,
+		&summary.RedeemedCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	summary.OutstandingLiability = summary.OpeningLiability + summary.Issued - summary.Redeemed
+	return &summary, nil
+}
+
+// GetGiftCardSummary retrieves a location's gift card activity and liability
+// for a date range
+func (s *Service) GetGiftCardSummary(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel string) (*GiftCardSummary, error) {
+	summary, err := s.store.GetGiftCardSummary(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Range = rangeLabel
+	summary.OpeningLiability = roundTo2(summary.OpeningLiability)
+	summary.Issued = roundTo2(summary.Issued)
+	summary.Redeemed = roundTo2(summary.Redeemed)
+	summary.OutstandingLiability = roundTo2(summary.OutstandingLiability)
+	return summary, nil
+}
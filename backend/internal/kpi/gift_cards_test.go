@@ -0,0 +1,52 @@
+package kpi
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGiftCardLiability(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locationID := testLocation(t, pool, "Gift Card Test")
+
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 12, 0, 0, 0, time.UTC) }
+	for i, e := range []struct {
+		at     time.Time
+		entry  string
+		amount float64
+	}{
+		{at: day(1, 20), entry: "issue", amount: 100},
+		{at: day(1, 25), entry: "redeem", amount: 30},
+		{at: day(2, 3), entry: "issue", amount: 50},
+		{at: day(2, 14), entry: "issue", amount: 25.5},
+		{at: day(2, 20), entry: "redeem", amount: 40},
+		{at: day(3, 2), entry: "redeem", amount: 10},
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO gift_card_ledger (location_id, occurred_at, entry_type, amount, import_source, source_id)
+			VALUES ($1, $2, $3, $4, 'test', $5)
+		`, locationID, e.at, e.entry, e.amount, strconv.Itoa(i)); err != nil {
+			t.Fatalf("seed ledger: %v", err)
+		}
+	}
+
+	got, err := NewService(NewStore(pool)).GetGiftCardSummary(ctx, locationID, day(2, 1), day(2, 29), "custom")
+	if err != nil {
+		t.Fatalf("GetGiftCardSummary() error = %v", err)
+	}
+	want := GiftCardSummary{
+		Range:                "custom",
+		OpeningLiability:     70,
+		Issued:               75.5,
+		IssuedCount:          2,
+		Redeemed:             40,
+		RedeemedCount:        1,
+		OutstandingLiability: 105.5,
+	}
+	if *got != want {
+		t.Errorf("GetGiftCardSummary() = %+v, want %+v", *got, want)
+	}
+}
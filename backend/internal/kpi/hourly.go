@@ -0,0 +1,111 @@
+package kpi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrHourlyDisabled is returned when a location has not opted in to hourly aggregates
+var ErrHourlyDisabled = errors.New("hourly aggregates are not enabled for this location")
+
+// HourlyPoint holds sales totals for one local hour of a day
+type HourlyPoint struct {
+	Hour      int     `json:"hour"`
+	Revenue   float64 `json:"revenue"`
+	Covers    int     `json:"covers"`
+	AvgCheck  float64 `json:"avg_check"`
+	Discounts float64 `json:"discounts"`
+	Comps     float64 `json:"comps"`
+}
+
+// HourlyResponse represents the intraday breakdown for a single date
+type HourlyResponse struct {
+	LocationID uuid.UUID     `json:"location_id"`
+	Date       string        `json:"date"`
+	Revenue    float64       `json:"revenue"`
+	Covers     int           `json:"covers"`
+	Hours      []HourlyPoint `json:"hours"`
+	UpdatedAt  *time.Time    `json:"updated_at,omitempty"`
+}
+
+// HourlyEnabled reports whether a location has opted in to hourly aggregates
+func (s *Store) HourlyEnabled(ctx context.Context, locationID uuid.UUID) (bool, error) {
+	var enabled bool
+	err := s.db.QueryRow(ctx, `SELECT hourly_aggregates FROM locations WHERE id = $1`, locationID).Scan(&enabled)
+	return enabled, err
+}
+
+// GetHourly retrieves the hourly buckets recorded for a location and date
+func (s *Store) GetHourly(ctx context.Context, locationID uuid.UUID, date time.Time) ([]HourlyPoint, *time.Time, error) {
+	query := `
+		SELECT hour, revenue, covers, discounts, comps, freshness_timestamp
+		FROM kpi_hourly_aggregates
+		WHERE location_id = $1 AND date = $2
+		ORDER BY hour
+	`
+
+	rows, err := s.db.Query(ctx, query, locationID, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var points []HourlyPoint
+	var updatedAt *time.Time
+	for rows.Next() {
+		var p HourlyPoint
+		var freshness time.Time
+		if err := rows.Scan(&p.Hour, &p.Revenue, &p.Covers, &p.Discounts, &p.Comps, &freshness); err != nil {
+			return nil, nil, err
+		}
+		if updatedAt == nil || freshness.After(*updatedAt) {
+			updatedAt = &freshness
+		}
+		points = append(points, p)
+	}
+	return points, updatedAt, rows.Err()
+}
+
+// GetHourly returns all 24 local hours for a date, filling hours without sales
+// with zeros. It returns ErrHourlyDisabled unless the location opted in.
+func (s *Service) GetHourly(ctx context.Context, locationID uuid.UUID, date time.Time) (*HourlyResponse, error) {
+	enabled, err := s.store.HourlyEnabled(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrHourlyDisabled
+	}
+
+	points, updatedAt, err := s.store.GetHourly(ctx, locationID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &HourlyResponse{
+		LocationID: locationID,
+		Date:       date.Format("2006-01-02"),
+		Hours:      make([]HourlyPoint, 24),
+		UpdatedAt:  updatedAt,
+	}
+	for h := range response.Hours {
+		response.Hours[h].Hour = h
+	}
+	for _, p := range points {
+		if p.Hour < 0 || p.Hour > 23 {
+			continue
+		}
+		p.Revenue = roundTo2(p.Revenue)
+		p.Discounts = roundTo2(p.Discounts)
+		p.Comps = roundTo2(p.Comps)
+		p.AvgCheck = AvgCheck(p.Revenue, p.Covers)
+		response.Hours[p.Hour] = p
+		response.Revenue += p.Revenue
+		response.Covers += p.Covers
+	}
+	response.Revenue = roundTo2(response.Revenue)
+	return response, nil
+}
@@ -0,0 +1,68 @@
+package kpi
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LaborProductivity relates revenue and covers to hours worked for a period
+type LaborProductivity struct {
+	Range               string   `json:"range"`
+	Revenue             float64  `json:"revenue"`
+	Covers              int      `json:"covers"`
+	LaborCost           float64  `json:"labor_cost"`
+	LaborHours          float64  `json:"labor_hours"`
+	HoursAvailable      bool     `json:"hours_available"`
+	RevenuePerLaborHour *float64 `json:"revenue_per_labor_hour"`
+	CoversPerLaborHour  *float64 `json:"covers_per_labor_hour"`
+}
+
+// GetLaborHours returns a location's payroll hours falling within a date range.
+// Pay periods that straddle the range are prorated by the number of
+// overlapping days.
+func (s *Store) GetLaborHours(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(
+			hours * ((LEAST(end_date, $2::date) - GREATEST(start_date, $1::date) + 1)::numeric
+				/ (end_date - start_date + 1))
+		), 0)
+		FROM payroll_periods
+		WHERE start_date <= $2::date AND end_date >= $1::date AND location_id = $3
+	`
+	var hours float64
+	err := s.db.QueryRow(ctx, query, startDate, endDate, locationID).Scan(&hours)
+	return hours, err
+}
+
+// GetLaborProductivity computes revenue and covers per labor hour. When no
+// payroll hours were imported for the period the per-hour figures are null and
+// HoursAvailable is false, rather than reporting a misleading zero.
+func (s *Service) GetLaborProductivity(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel string) (*LaborProductivity, error) {
+	totals, err := s.store.GetTotals(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	hours, err := s.store.GetLaborHours(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LaborProductivity{
+		Range:      rangeLabel,
+		Revenue:    roundTo2(totals.Revenue),
+		Covers:     totals.Covers,
+		LaborCost:  roundTo2(totals.LaborCost),
+		LaborHours: roundTo2(hours),
+	}
+	if hours > 0 {
+		revenuePerHour := roundTo2(totals.Revenue / hours)
+		coversPerHour := roundTo2(float64(totals.Covers) / hours)
+		result.HoursAvailable = true
+		result.RevenuePerLaborHour = &revenuePerHour
+		result.CoversPerLaborHour = &coversPerHour
+	}
+	return result, nil
+}
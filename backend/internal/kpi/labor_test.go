@@ -0,0 +1,72 @@
+package kpi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGetLaborProductivity(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	start := time.Date(2001, 6, 4, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 6)
+
+	locationID := testLocation(t, pool, "Labor productivity test")
+	otherID := testLocation(t, pool, "Labor productivity other")
+
+	for i, revenue := range []float64{1200, 800} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO kpi_aggregates (date, location_id, revenue, covers, labor_cost)
+			VALUES ($1, $2, $3, $4, 300)
+		`, start.AddDate(0, 0, i), locationID, revenue, 40+i*20); err != nil {
+			t.Fatalf("insert aggregate: %v", err)
+		}
+	}
+
+	// The week's 80 hours plus half of a fortnight straddling the range end,
+	// and the other location's payroll, which must not count
+	for _, p := range []struct {
+		locationID uuid.UUID
+		start, end time.Time
+		hours      float64
+	}{
+		{locationID, start, end, 80},
+		{locationID, end.AddDate(0, 0, -6), end.AddDate(0, 0, 7), 28},
+		{otherID, start, end, 500},
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO payroll_periods (location_id, start_date, end_date, labor_cost, hours)
+			VALUES ($1, $2, $3, 0, $4)
+		`, p.locationID, p.start, p.end, p.hours); err != nil {
+			t.Fatalf("insert payroll: %v", err)
+		}
+	}
+
+	svc := NewService(NewStore(pool))
+	got, err := svc.GetLaborProductivity(ctx, locationID, start, end, "custom")
+	if err != nil {
+		t.Fatalf("GetLaborProductivity: %v", err)
+	}
+	if got.Revenue != 2000 || got.Covers != 100 || got.LaborHours != 94 || !got.HoursAvailable {
+		t.Fatalf("got revenue %.2f, covers %d, hours %.2f, available %v; want 2000, 100, 94, true",
+			got.Revenue, got.Covers, got.LaborHours, got.HoursAvailable)
+	}
+	if got.RevenuePerLaborHour == nil || *got.RevenuePerLaborHour != 21.28 {
+		t.Errorf("RevenuePerLaborHour = %v, want 21.28", got.RevenuePerLaborHour)
+	}
+	if got.CoversPerLaborHour == nil || *got.CoversPerLaborHour != 1.06 {
+		t.Errorf("CoversPerLaborHour = %v, want 1.06", got.CoversPerLaborHour)
+	}
+
+	// Without payroll for the period the per-hour figures are absent, not zero
+	got, err = svc.GetLaborProductivity(ctx, locationID, start.AddDate(0, 0, -30), start.AddDate(0, 0, -1), "custom")
+	if err != nil {
+		t.Fatalf("GetLaborProductivity: %v", err)
+	}
+	if got.HoursAvailable || got.RevenuePerLaborHour != nil || got.CoversPerLaborHour != nil {
+		t.Errorf("with no hours got %+v, want per-hour figures unset", got)
+	}
+}
@@ -0,0 +1,128 @@
+package kpi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// venue is one location's seeded activity; each appears once, so a figure
+// that includes the other location's rows gives itself away
+type venue struct {
+	id             uuid.UUID
+	discountReason string
+	compReason     string
+	orderType      string
+	total          float64
+	tax            float64
+	supplier       string
+	purchases      float64
+	giftCards      float64
+}
+
+// TestBreakdownsScopedToLocation seeds two locations with the same kinds of
+// activity on the same day and checks each location-scoped endpoint returns
+// only the caller's location's figures
+func TestBreakdownsScopedToLocation(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	daypartID := seededDaypart(t, pool)
+	day := time.Date(2024, 5, 14, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+
+	venues := []venue{
+		{discountReason: "staff", compReason: "vip", orderType: "dine_in", total: 100, tax: 10, supplier: "Metro Meats", purchases: 200, giftCards: 50},
+		{discountReason: "promo", compReason: "manager", orderType: "delivery", total: 900, tax: 90, supplier: "Harbour Fish", purchases: 700, giftCards: 500},
+	}
+	for i := range venues {
+		v := &venues[i]
+		v.id = testLocation(t, pool, "Scope test "+v.orderType)
+		channelID := testChannel(t, pool, v.id, "Counter")
+
+		// Discounts and comps are a tenth and a twentieth of the total
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, tax, total, discounts, discount_reason, comps, comp_reason, order_type)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, day, v.id, channelID, daypartID, v.total-v.tax, v.tax, v.total, v.total/10, v.discountReason, v.total/20, v.compReason, v.orderType); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO purchases (location_id, purchase_date, supplier, item_name, quantity, unit_cost, total, import_source, source_id)
+			VALUES ($1, $2, $3, 'Stock', 1, $4, $4, 'test', $5)
+		`, v.id, day, v.supplier, v.purchases, uuid.NewString()); err != nil {
+			t.Fatalf("insert purchase: %v", err)
+		}
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO gift_card_ledger (location_id, occurred_at, entry_type, amount, import_source, source_id)
+			VALUES ($1, $2, 'issue', $3, 'test', $4)
+		`, v.id, day, v.giftCards, uuid.NewString()); err != nil {
+			t.Fatalf("insert gift card: %v", err)
+		}
+	}
+
+	svc := NewService(NewStore(pool))
+	for _, v := range venues {
+		t.Run(v.orderType, func(t *testing.T) {
+			t.Run("by-discount-reason", func(t *testing.T) {
+				got, err := svc.GetByDiscountReason(ctx, v.id, start, end, "custom")
+				if err != nil {
+					t.Fatalf("GetByDiscountReason() error = %v", err)
+				}
+				want := []ReasonSummary{
+					{Type: "discount", Reason: v.discountReason, Amount: v.total / 10, Count: 1},
+					{Type: "comp", Reason: v.compReason, Amount: v.total / 20, Count: 1},
+				}
+				if !reflect.DeepEqual(got.Reasons, want) {
+					t.Errorf("reasons = %+v, want %+v", got.Reasons, want)
+				}
+			})
+
+			t.Run("by-order-type", func(t *testing.T) {
+				got, err := svc.GetByOrderType(ctx, v.id, start, end, "custom")
+				if err != nil {
+					t.Fatalf("GetByOrderType() error = %v", err)
+				}
+				if len(got.OrderTypes) != 1 {
+					t.Fatalf("order types = %+v, want only %s", got.OrderTypes, v.orderType)
+				}
+				if ot := got.OrderTypes[0]; ot.OrderType != v.orderType || ot.Revenue != v.total || ot.Transactions != 1 || ot.SharePct != 100 {
+					t.Errorf("order type = %+v, want %s with revenue %v from 1 sale", ot, v.orderType, v.total)
+				}
+			})
+
+			t.Run("supplier-spend", func(t *testing.T) {
+				got, err := svc.GetSupplierSpend(ctx, v.id, start, end, "custom")
+				if err != nil {
+					t.Fatalf("GetSupplierSpend() error = %v", err)
+				}
+				if got.Total != v.purchases || len(got.Suppliers) != 1 || got.Suppliers[0].Supplier != v.supplier {
+					t.Errorf("supplier spend = %v from %+v, want %v from %s only", got.Total, got.Suppliers, v.purchases, v.supplier)
+				}
+			})
+
+			t.Run("gift-cards", func(t *testing.T) {
+				got, err := svc.GetGiftCardSummary(ctx, v.id, start, end, "custom")
+				if err != nil {
+					t.Fatalf("GetGiftCardSummary() error = %v", err)
+				}
+				if got.Issued != v.giftCards || got.IssuedCount != 1 || got.OutstandingLiability != v.giftCards {
+					t.Errorf("gift cards = %+v, want %v issued once", *got, v.giftCards)
+				}
+			})
+
+			t.Run("tax-summary", func(t *testing.T) {
+				got, err := svc.GetTaxSummary(ctx, v.id, start, end, "custom", "month")
+				if err != nil {
+					t.Fatalf("GetTaxSummary() error = %v", err)
+				}
+				if got.TaxCollected != v.tax || got.TaxableSales != v.total-v.tax {
+					t.Errorf("tax collected, taxable = %v, %v, want %v, %v", got.TaxCollected, got.TaxableSales, v.tax, v.total-v.tax)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package kpi
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SupplierSpend represents purchase totals for a single supplier
+type SupplierSpend struct {
+	Supplier  string  `json:"supplier"`
+	Total     float64 `json:"total"`
+	Lines     int     `json:"lines"`
+	Items     int     `json:"items"`
+	SharePct  float64 `json:"share_pct"`
+	LastOrder string  `json:"last_order"`
+}
+
+// SupplierSpendResponse represents supplier spend for a date range
+type SupplierSpendResponse struct {
+	Range     string          `json:"range"`
+	Total     float64         `json:"total"`
+	Suppliers []SupplierSpend `json:"suppliers"`
+}
+
+// GetSupplierSpend retrieves purchase totals grouped by supplier for a location and date range
+func (s *Store) GetSupplierSpend(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]SupplierSpend, error) {
+	query := `
+		SELECT
+			supplier,
+			COALESCE(SUM(total), 0) as total,
+			COUNT(*) as lines,
+			COUNT(DISTINCT item_name) as items,
+			MAX(purchase_date) as last_order
+		FROM purchases
+		WHERE purchase_date >= $1 AND purchase_date <= $2 AND location_id = $3
+		GROUP BY supplier
+		ORDER BY SUM(total) DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, startDate, endDate, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suppliers []SupplierSpend
+	for rows.Next() {
+		var sp SupplierSpend
+		var lastOrder time.Time
+		if err := rows.Scan(&sp.Supplier, &sp.Total, &sp.Lines, &sp.Items, &lastOrder); err != nil {
+			return nil, err
+		}
+		sp.LastOrder = lastOrder.Format("2006-01-02")
+		suppliers = append(suppliers, sp)
+	}
+	return suppliers, rows.Err()
+}
+
+// GetPurchasesByCategory sums a location's purchases per category for a date
+// range. Lines without a category inherit the category of the matching
+// inventory item.
+func (s *Store) GetPurchasesByCategory(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) (map[string]float64, error) {
+	query := `
+		SELECT
+			COALESCE(
+				NULLIF(p.category, ''),
+				(SELECT NULLIF(i.category, '') FROM inventory_snapshots i
+				 WHERE i.item_name = p.item_name AND i.location_id = p.location_id
+				 ORDER BY i.snapshot_date DESC LIMIT 1),
+				'Uncategorized'
+			) as category,
+			COALESCE(SUM(p.total), 0) as total
+		FROM purchases p
+		WHERE p.purchase_date >= $1 AND p.purchase_date <= $2 AND p.location_id = $3
+		GROUP BY 1
+	`
+	return s.queryCategoryAmounts(ctx, query, startDate, endDate, locationID)
+}
+
+// GetSupplierSpend retrieves a location's supplier spend with each supplier's
+// share of the total
+func (s *Service) GetSupplierSpend(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel string) (*SupplierSpendResponse, error) {
+	suppliers, err := s.store.GetSupplierSpend(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SupplierSpendResponse{
+		Range:     rangeLabel,
+		Suppliers: []SupplierSpend{},
+	}
+	for _, sp := range suppliers {
+		response.Total += sp.Total
+	}
+	for _, sp := range suppliers {
+		if response.Total > 0 {
+			sp.SharePct = roundTo2(sp.Total / response.Total * 100)
+		}
+		sp.Total = roundTo2(sp.Total)
+		response.Suppliers = append(response.Suppliers, sp)
+	}
+	response.Total = roundTo2(response.Total)
+
+	return response, nil
+}
@@ -0,0 +1,54 @@
+package kpi
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SeriesMetrics are the per-day metrics the daily series can be exported with,
+// in their default column order
+var SeriesMetrics = []string{"revenue", "cogs", "gross_margin", "labor_cost", "net_profit", "covers", "avg_check"}
+
+// IsSeriesMetric reports whether name is one of SeriesMetrics
+func IsSeriesMetric(name string) bool {
+	for _, m := range SeriesMetrics {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MetricValue formats one metric of the point for CSV output
+func (p DailyPoint) MetricValue(name string) string {
+	switch name {
+	case "revenue":
+		return formatAmount(p.Revenue)
+	case "cogs":
+		return formatAmount(p.COGS)
+	case "gross_margin":
+		return formatAmount(p.GrossMargin)
+	case "labor_cost":
+		return formatAmount(p.LaborCost)
+	case "net_profit":
+		return formatAmount(p.NetProfit)
+	case "covers":
+		return strconv.Itoa(p.Covers)
+	case "avg_check":
+		return formatAmount(p.AvgCheck)
+	}
+	return ""
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// DailySeries returns the gap-filled daily series for a location, one point
+// per calendar day from startDate to endDate
+func (s *Service) DailySeries(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]DailyPoint, error) {
+	return s.dailySeries(ctx, locationID, startDate, endDate)
+}
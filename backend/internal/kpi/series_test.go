@@ -0,0 +1,55 @@
+package kpi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDailySeriesOneRowPerDay(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locationID := testLocation(t, pool, "Daily Series Test")
+
+	for _, a := range []struct {
+		date    string
+		revenue float64
+		covers  int
+	}{
+		{date: "2024-03-02", revenue: 1250.5, covers: 48},
+		{date: "2024-03-05", revenue: 980, covers: 40},
+		{date: "2024-03-20", revenue: 500, covers: 20}, // outside the range
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO kpi_aggregates (date, location_id, revenue, covers) VALUES ($1, $2, $3, $4)
+		`, a.date, locationID, a.revenue, a.covers); err != nil {
+			t.Fatalf("seed aggregates: %v", err)
+		}
+	}
+
+	start, end := ParseDateRange("7d", time.Date(2024, 3, 7, 12, 0, 0, 0, time.UTC), DefaultFiscalYearStart)
+	series, err := NewService(NewStore(pool)).DailySeries(ctx, locationID, start, end)
+	if err != nil {
+		t.Fatalf("DailySeries() error = %v", err)
+	}
+
+	var dates []string
+	for _, p := range series {
+		dates = append(dates, p.Date)
+	}
+	want := []string{"2024-02-29", "2024-03-01", "2024-03-02", "2024-03-03", "2024-03-04", "2024-03-05", "2024-03-06", "2024-03-07"}
+	if len(dates) != len(want) {
+		t.Fatalf("dates = %v, want %v", dates, want)
+	}
+	for i := range want {
+		if dates[i] != want[i] {
+			t.Fatalf("dates = %v, want %v", dates, want)
+		}
+	}
+	if p := series[2]; p.Revenue != 1250.5 || p.Covers != 48 || p.AvgCheck != 26.05 {
+		t.Errorf("2 March = %+v, want its aggregates", p)
+	}
+	if p := series[3]; p.Revenue != 0 || p.Covers != 0 {
+		t.Errorf("3 March = %+v, want a gap-filled zero day", p)
+	}
+}
@@ -0,0 +1,90 @@
+package kpi
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServerSummary represents sales totals attributed to one server
+type ServerSummary struct {
+	Server      string  `json:"server"`
+	Revenue     float64 `json:"revenue"`
+	Covers      int     `json:"covers"`
+	AvgCheck    float64 `json:"avg_check"`
+	Discounts   float64 `json:"discounts"`
+	DiscountPct float64 `json:"discount_pct"` // discounts as a share of revenue
+	Comps       float64 `json:"comps"`
+	SharePct    float64 `json:"share_pct"`
+}
+
+// ServerResponse represents sales broken down by server
+type ServerResponse struct {
+	Range   string          `json:"range"`
+	Servers []ServerSummary `json:"servers"`
+}
+
+// GetByServer retrieves sales totals grouped by server for a location and date
+// range. Sales without a server are grouped as "unassigned".
+func (s *Store) GetByServer(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]ServerSummary, error) {
+	query := `
+		SELECT
+			COALESCE(NULLIF(server_name, ''), 'unassigned') as server,
+			COALESCE(SUM(total), 0) as revenue,
+			COUNT(*) as covers,
+			COALESCE(SUM(discounts), 0) as discounts,
+			COALESCE(SUM(comps), 0) as comps
+		FROM sales
+		WHERE occurred_at >= $1 AND occurred_at <= $2 AND location_id = $3
+		GROUP BY 1
+		ORDER BY revenue DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, startDate, endDate, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []ServerSummary
+	for rows.Next() {
+		var ss ServerSummary
+		if err := rows.Scan(&ss.Server, &ss.Revenue, &ss.Covers, &ss.Discounts, &ss.Comps); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, ss)
+	}
+	return summaries, rows.Err()
+}
+
+// GetByServer retrieves per-server sales performance with each server's share of revenue
+func (s *Service) GetByServer(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel string) (*ServerResponse, error) {
+	servers, err := s.store.GetByServer(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var revenue float64
+	for _, ss := range servers {
+		revenue += ss.Revenue
+	}
+
+	for i := range servers {
+		if revenue > 0 {
+			servers[i].SharePct = roundTo2(servers[i].Revenue / revenue * 100)
+		}
+		if servers[i].Revenue > 0 {
+			servers[i].DiscountPct = roundTo2(servers[i].Discounts / servers[i].Revenue * 100)
+		}
+		servers[i].AvgCheck = AvgCheck(servers[i].Revenue, servers[i].Covers)
+		servers[i].Revenue = roundTo2(servers[i].Revenue)
+		servers[i].Discounts = roundTo2(servers[i].Discounts)
+		servers[i].Comps = roundTo2(servers[i].Comps)
+	}
+
+	return &ServerResponse{
+		Range:   rangeLabel,
+		Servers: servers,
+	}, nil
+}
@@ -2,16 +2,23 @@ package kpi
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // DailyKPIResponse represents the response for daily KPI endpoint
 type DailyKPIResponse struct {
-	FreshnessTimestamp time.Time    `json:"freshnessTimestamp"`
-	Range              string       `json:"range"`
-	Totals             *KPITotals   `json:"totals"`
-	ByChannel          []KPISummary `json:"byChannel"`
-	ByDaypart          []KPISummary `json:"byDaypart"`
+	FreshnessTimestamp time.Time         `json:"freshnessTimestamp"`
+	Range              string            `json:"range"`
+	Totals             *KPITotals        `json:"totals"`
+	ByChannel          []KPISummary      `json:"byChannel"`
+	ByDaypart          []KPISummary      `json:"byDaypart"`
+	Daily              []DailyPoint      `json:"daily"`
+	Comparison         *PeriodComparison `json:"comparison"`
 }
 
 // Service handles KPI business logic
@@ -24,26 +31,44 @@ func NewService(store *Store) *Service {
 	return &Service{store: store}
 }
 
-// GetDailyKPIs retrieves KPIs for a date range with channel/daypart breakdowns
-func (s *Service) GetDailyKPIs(ctx context.Context, startDate, endDate time.Time, rangeLabel string) (*DailyKPIResponse, error) {
+// GetDailyKPIs retrieves KPIs for a location and date range with channel/daypart breakdowns
+func (s *Service) GetDailyKPIs(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel string) (*DailyKPIResponse, error) {
 	// Get totals
-	totals, err := s.store.GetTotals(ctx, startDate, endDate)
+	totals, err := s.store.GetTotals(ctx, locationID, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get by channel
-	byChannel, err := s.store.GetByChannel(ctx, startDate, endDate)
+	byChannel, err := s.store.GetByChannel(ctx, locationID, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get by daypart
-	byDaypart, err := s.store.GetByDaypart(ctx, startDate, endDate)
+	byDaypart, err := s.store.GetByDaypart(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get daily series with closed-day markers
+	daily, err := s.dailySeries(ctx, locationID, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
 
+	// Closed days are excluded from per-day averages
+	for _, p := range daily {
+		if p.Closed {
+			totals.ClosedDays++
+		} else {
+			totals.OpenDays++
+		}
+	}
+	if totals.OpenDays > 0 {
+		totals.AvgDailyRevenue = roundTo2(totals.Revenue / float64(totals.OpenDays))
+	}
+
 	// Calculate percentages for breakdowns
 	if totals.Revenue > 0 {
 		for i := range byChannel {
@@ -53,7 +78,7 @@ func (s *Service) GetDailyKPIs(ctx context.Context, startDate, endDate time.Time
 			byChannel[i].LaborCost = roundTo2(byChannel[i].LaborCost)
 			byChannel[i].Opex = roundTo2(byChannel[i].Opex)
 			byChannel[i].NetProfit = roundTo2(byChannel[i].NetProfit)
-			byChannel[i].AvgCheck = roundTo2(byChannel[i].AvgCheck)
+			byChannel[i].AvgCheck = AvgCheck(byChannel[i].Revenue, byChannel[i].Covers)
 		}
 		for i := range byDaypart {
 			byDaypart[i].Revenue = roundTo2(byDaypart[i].Revenue)
@@ -62,10 +87,16 @@ func (s *Service) GetDailyKPIs(ctx context.Context, startDate, endDate time.Time
 			byDaypart[i].LaborCost = roundTo2(byDaypart[i].LaborCost)
 			byDaypart[i].Opex = roundTo2(byDaypart[i].Opex)
 			byDaypart[i].NetProfit = roundTo2(byDaypart[i].NetProfit)
-			byDaypart[i].AvgCheck = roundTo2(byDaypart[i].AvgCheck)
+			byDaypart[i].AvgCheck = AvgCheck(byDaypart[i].Revenue, byDaypart[i].Covers)
 		}
 	}
 
+	// Compare against the preceding period of the same length
+	comparison, err := s.comparePeriods(ctx, locationID, startDate, endDate, rangeLabel, totals)
+	if err != nil {
+		return nil, err
+	}
+
 	// Round totals
 	totals.Revenue = roundTo2(totals.Revenue)
 	totals.COGS = roundTo2(totals.COGS)
@@ -82,23 +113,166 @@ func (s *Service) GetDailyKPIs(ctx context.Context, startDate, endDate time.Time
 		Totals:             totals,
 		ByChannel:          byChannel,
 		ByDaypart:          byDaypart,
+		Daily:              daily,
+		Comparison:         comparison,
+	}, nil
+}
+
+// dailySeries returns one point per calendar day in the range, gap-filling
+// days without aggregates and marking closed days
+func (s *Service) dailySeries(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]DailyPoint, error) {
+	points, err := s.store.GetDailySeries(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	closed, err := s.store.GetClosedDates(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]DailyPoint, len(points))
+	for _, p := range points {
+		byDate[p.Date] = p
+	}
+
+	var series []DailyPoint
+	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+		key := d.Format("2006-01-02")
+		p, ok := byDate[key]
+		if !ok {
+			p = DailyPoint{Date: key}
+		}
+		p.Closed = p.Closed || closed[key]
+		p.Revenue = roundTo2(p.Revenue)
+		p.COGS = roundTo2(p.COGS)
+		p.GrossMargin = roundTo2(p.GrossMargin)
+		p.LaborCost = roundTo2(p.LaborCost)
+		p.NetProfit = roundTo2(p.NetProfit)
+		p.AvgCheck = AvgCheck(p.Revenue, p.Covers)
+		series = append(series, p)
+	}
+	return series, nil
+}
+
+// DiscountReasonResponse represents discount/comp totals broken down by reason code
+type DiscountReasonResponse struct {
+	Range   string          `json:"range"`
+	Reasons []ReasonSummary `json:"reasons"`
+}
+
+// GetByDiscountReason retrieves discount and comp totals grouped by reason code for a location
+func (s *Service) GetByDiscountReason(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel string) (*DiscountReasonResponse, error) {
+	reasons, err := s.store.GetByDiscountReason(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range reasons {
+		reasons[i].Amount = roundTo2(reasons[i].Amount)
+	}
+
+	return &DiscountReasonResponse{
+		Range:   rangeLabel,
+		Reasons: reasons,
+	}, nil
+}
+
+// OrderTypeResponse represents sales broken down by fulfillment type
+type OrderTypeResponse struct {
+	Range      string             `json:"range"`
+	OrderTypes []OrderTypeSummary `json:"order_types"`
+}
+
+// GetByOrderType retrieves a location's sales grouped by order type with each
+// type's share of revenue
+func (s *Service) GetByOrderType(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel string) (*OrderTypeResponse, error) {
+	orderTypes, err := s.store.GetByOrderType(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var revenue float64
+	for _, ot := range orderTypes {
+		revenue += ot.Revenue
+	}
+
+	for i := range orderTypes {
+		if revenue > 0 {
+			orderTypes[i].SharePct = roundTo2(orderTypes[i].Revenue / revenue * 100)
+		}
+		orderTypes[i].AvgCheck = AvgCheck(orderTypes[i].Revenue, orderTypes[i].Transactions)
+		orderTypes[i].Revenue = roundTo2(orderTypes[i].Revenue)
+		orderTypes[i].Discounts = roundTo2(orderTypes[i].Discounts)
+		orderTypes[i].Comps = roundTo2(orderTypes[i].Comps)
+	}
+
+	return &OrderTypeResponse{
+		Range:      rangeLabel,
+		OrderTypes: orderTypes,
 	}, nil
 }
 
-// ParseDateRange converts a range string to start/end dates
-func ParseDateRange(rangeStr string, referenceDate time.Time) (start, end time.Time) {
+// DefaultLocationID returns the only location for single-venue deployments
+func (s *Service) DefaultLocationID(ctx context.Context) (uuid.UUID, error) {
+	return s.store.DefaultLocationID(ctx)
+}
+
+// RangeLimits bounds the date ranges KPI endpoints accept, so a crafted
+// range or reference date can't turn a request into a scan of every aggregate
+type RangeLimits struct {
+	MaxDays       int // Longest range in calendar days; 0 disables the check
+	MaxFutureDays int // How many days after today a range may end
+}
+
+// Check rejects a range longer than MaxDays or ending more than MaxFutureDays
+// after the day containing now
+func (l RangeLimits) Check(start, end, now time.Time) error {
+	if l.MaxDays > 0 {
+		days := int(calendarDate(end).Sub(calendarDate(start)).Hours()/24) + 1
+		if days > l.MaxDays {
+			return fmt.Errorf("range must not span more than %d days", l.MaxDays)
+		}
+	}
+	latest := calendarDate(now.In(end.Location())).AddDate(0, 0, l.MaxFutureDays)
+	if calendarDate(end).After(latest) {
+		return fmt.Errorf("range must not end after %s", latest.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// ValidRange reports whether rangeStr is a relative range ParseDateRange understands
+func ValidRange(rangeStr string) bool {
+	switch rangeStr {
+	case "7d", "30d", "mtd", "qtd", "ytd", "trailing12m":
+		return true
+	}
+	return false
+}
+
+// ParseDateRange converts a range string to start/end dates. qtd and ytd
+// start at the beginning of the fiscal quarter or year containing the
+// reference date.
+func ParseDateRange(rangeStr string, referenceDate time.Time, fy FiscalYearStart) (start, end time.Time) {
 	// Use Brisbane timezone
 	loc, _ := time.LoadLocation("Australia/Brisbane")
 	ref := referenceDate.In(loc)
 
 	// End is always end of reference date
 	end = time.Date(ref.Year(), ref.Month(), ref.Day(), 23, 59, 59, 0, loc)
+	day := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, loc)
 
 	switch rangeStr {
+	case "7d":
+		start = end.AddDate(0, 0, -7)
 	case "30d":
 		start = end.AddDate(0, 0, -30)
+	case "mtd":
+		start = time.Date(ref.Year(), ref.Month(), 1, 0, 0, 0, 0, loc)
+	case "qtd":
+		start = fy.QuarterStart(day)
 	case "ytd":
-		start = time.Date(ref.Year(), 1, 1, 0, 0, 0, 0, loc)
+		start = fy.YearStart(day)
 	case "trailing12m":
 		start = end.AddDate(-1, 0, 0)
 	default:
@@ -112,6 +286,20 @@ func ParseDateRange(rangeStr string, referenceDate time.Time) (start, end time.T
 	return start, end
 }
 
+// CustomDateRange converts explicit start and end dates to a range covering
+// both days in full. The end may not be before the start.
+func CustomDateRange(startDate, endDate time.Time) (start, end time.Time, err error) {
+	loc, _ := time.LoadLocation("Australia/Brisbane")
+	start = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, loc)
+	end = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, loc)
+
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, errors.New("end must not be before start")
+	}
+	return start, end, nil
+}
+
+// roundTo2 rounds half away from zero, so losses round like profits
 func roundTo2(f float64) float64 {
-	return float64(int(f*100+0.5)) / 100
+	return math.Round(f*100) / 100
 }
@@ -0,0 +1,104 @@
+package kpi
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDateRange(t *testing.T) {
+	brisbane, err := time.LoadLocation("Australia/Brisbane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 15 May 2024, the second month of a fiscal quarter starting in April
+	reference := time.Date(2024, 5, 15, 9, 30, 0, 0, brisbane)
+
+	tests := []struct {
+		rangeStr  string
+		fy        FiscalYearStart
+		wantStart string
+	}{
+		{rangeStr: "7d", fy: DefaultFiscalYearStart, wantStart: "2024-05-08"},
+		{rangeStr: "30d", fy: DefaultFiscalYearStart, wantStart: "2024-04-15"},
+		{rangeStr: "mtd", fy: DefaultFiscalYearStart, wantStart: "2024-05-01"},
+		{rangeStr: "qtd", fy: DefaultFiscalYearStart, wantStart: "2024-04-01"},
+		// Quarters of an August fiscal year start in February, May, August and November
+		{rangeStr: "qtd", fy: FiscalYearStart{Month: time.August, Day: 1}, wantStart: "2024-05-01"},
+		{rangeStr: "qtd", fy: FiscalYearStart{Month: time.July, Day: 1}, wantStart: "2024-04-01"},
+		{rangeStr: "ytd", fy: DefaultFiscalYearStart, wantStart: "2024-01-01"},
+		{rangeStr: "trailing12m", fy: DefaultFiscalYearStart, wantStart: "2023-05-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rangeStr, func(t *testing.T) {
+			start, end := ParseDateRange(tt.rangeStr, reference, tt.fy)
+			if got := start.Format("2006-01-02 15:04:05"); got != tt.wantStart+" 00:00:00" {
+				t.Errorf("start = %s, want %s 00:00:00", got, tt.wantStart)
+			}
+			if got := end.Format("2006-01-02 15:04:05"); got != "2024-05-15 23:59:59" {
+				t.Errorf("end = %s, want the end of the reference day", got)
+			}
+		})
+	}
+}
+
+func TestCustomDateRange(t *testing.T) {
+	start, end, err := CustomDateRange(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("CustomDateRange() error = %v", err)
+	}
+	if got := start.Format("2006-01-02 15:04:05") + " to " + end.Format("2006-01-02 15:04:05"); got != "2024-02-01 00:00:00 to 2024-02-29 23:59:59" {
+		t.Errorf("CustomDateRange() = %s, want both days in full", got)
+	}
+
+	day := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	if _, _, err := CustomDateRange(day, day); err != nil {
+		t.Errorf("CustomDateRange() of a single day error = %v", err)
+	}
+	if _, _, err := CustomDateRange(day, day.AddDate(0, 0, -1)); err == nil {
+		t.Error("CustomDateRange() with end before start returned no error")
+	}
+}
+
+func TestRangeLimitsCheck(t *testing.T) {
+	brisbane, err := time.LoadLocation("Australia/Brisbane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 15, 9, 30, 0, 0, brisbane)
+	limits := RangeLimits{MaxDays: 90, MaxFutureDays: 1}
+
+	tests := []struct {
+		name      string
+		limits    RangeLimits
+		rangeStr  string
+		reference time.Time
+		wantErr   string
+	}{
+		{name: "within span", limits: limits, rangeStr: "30d", reference: now},
+		// 30d covers 31 calendar days, ending on the reference day
+		{name: "at span", limits: RangeLimits{MaxDays: 31}, rangeStr: "30d", reference: now},
+		{name: "computed range too long", limits: limits, rangeStr: "trailing12m", reference: now, wantErr: "more than 90 days"},
+		{name: "ytd too long", limits: limits, rangeStr: "ytd", reference: now, wantErr: "more than 90 days"},
+		{name: "span unbounded", limits: RangeLimits{MaxFutureDays: 1}, rangeStr: "trailing12m", reference: now},
+		{name: "tomorrow", limits: limits, rangeStr: "7d", reference: now.AddDate(0, 0, 1)},
+		{name: "far future", limits: limits, rangeStr: "7d", reference: now.AddDate(10, 0, 0), wantErr: "must not end after 2024-05-16"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := ParseDateRange(tt.rangeStr, tt.reference, DefaultFiscalYearStart)
+			err := tt.limits.Check(start, end, now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package kpi
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Snapshot is a location's KPI totals for a period, frozen at a point in time
+type Snapshot struct {
+	ID          uuid.UUID `json:"id"`
+	LocationID  uuid.UUID `json:"location_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Label       string    `json:"label"`
+	Totals      KPITotals `json:"totals"`
+	CreatedBy   uuid.UUID `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MetricDrift is the change in one metric between a snapshot and now
+type MetricDrift struct {
+	Metric   string   `json:"metric"`
+	Snapshot float64  `json:"snapshot"`
+	Current  float64  `json:"current"`
+	Delta    float64  `json:"delta"`
+	DeltaPct *float64 `json:"delta_pct"` // null when the snapshot value was zero
+}
+
+// SnapshotComparison shows how current values differ from a snapshot
+type SnapshotComparison struct {
+	Snapshot Snapshot      `json:"snapshot"`
+	Current  KPITotals     `json:"current"`
+	HasDrift bool          `json:"has_drift"`
+	Drift    []MetricDrift `json:"drift"`
+}
+
+// CreateSnapshot stores a snapshot
+func (s *Store) CreateSnapshot(ctx context.Context, snap *Snapshot) error {
+	totals, err := json.Marshal(snap.Totals)
+	if err != nil {
+		return err
+	}
+	snap.ID = uuid.New()
+	snap.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO kpi_snapshots (id, location_id, period_start, period_end, label, totals, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err = s.db.Exec(ctx, query, snap.ID, snap.LocationID, snap.PeriodStart, snap.PeriodEnd, snap.Label, totals, snap.CreatedBy, snap.CreatedAt)
+	return err
+}
+
+// GetSnapshot retrieves a snapshot by ID
+func (s *Store) GetSnapshot(ctx context.Context, id uuid.UUID) (*Snapshot, error) {
+	query := `
+		SELECT id, location_id, period_start, period_end, label, totals, created_by, created_at
+		FROM kpi_snapshots
+		WHERE id = $1
+	`
+	var snap Snapshot
+	var totals []byte
+	err := s.db.QueryRow(ctx, query, id).Scan(&snap.ID, &snap.LocationID, &snap.PeriodStart, &snap.PeriodEnd, &snap.Label, &totals, &snap.CreatedBy, &snap.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(totals, &snap.Totals); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// ListSnapshots retrieves a location's snapshots, newest period first
+func (s *Store) ListSnapshots(ctx context.Context, locationID uuid.UUID) ([]Snapshot, error) {
+	query := `
+		SELECT id, location_id, period_start, period_end, label, totals, created_by, created_at
+		FROM kpi_snapshots
+		WHERE location_id = $1
+		ORDER BY period_start DESC, created_at DESC
+	`
+	rows, err := s.db.Query(ctx, query, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []Snapshot{}
+	for rows.Next() {
+		var snap Snapshot
+		var totals []byte
+		if err := rows.Scan(&snap.ID, &snap.LocationID, &snap.PeriodStart, &snap.PeriodEnd, &snap.Label, &totals, &snap.CreatedBy, &snap.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(totals, &snap.Totals); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, rows.Err()
+}
+
+// CreateSnapshot freezes the current totals for a location and period
+func (s *Service) CreateSnapshot(ctx context.Context, locationID, userID uuid.UUID, startDate, endDate time.Time, label string) (*Snapshot, error) {
+	totals, err := s.store.GetTotals(ctx, locationID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{
+		LocationID:  locationID,
+		PeriodStart: startDate,
+		PeriodEnd:   endDate,
+		Label:       label,
+		Totals:      *totals,
+		CreatedBy:   userID,
+	}
+	if err := s.store.CreateSnapshot(ctx, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// GetSnapshot retrieves a snapshot by ID
+func (s *Service) GetSnapshot(ctx context.Context, id uuid.UUID) (*Snapshot, error) {
+	return s.store.GetSnapshot(ctx, id)
+}
+
+// ListSnapshots retrieves a location's snapshots
+func (s *Service) ListSnapshots(ctx context.Context, locationID uuid.UUID) ([]Snapshot, error) {
+	return s.store.ListSnapshots(ctx, locationID)
+}
+
+// CompareSnapshot recomputes the snapshot's period and reports per-metric
+// drift. Differences under half a cent are treated as unchanged.
+func (s *Service) CompareSnapshot(ctx context.Context, snap *Snapshot) (*SnapshotComparison, error) {
+	current, err := s.store.GetTotals(ctx, snap.LocationID, snap.PeriodStart, snap.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	then, now := snap.Totals, *current
+	metrics := []struct {
+		name          string
+		snapshot, cur float64
+	}{
+		{"revenue", then.Revenue, now.Revenue},
+		{"cogs", then.COGS, now.COGS},
+		{"gross_margin", then.GrossMargin, now.GrossMargin},
+		{"labor_cost", then.LaborCost, now.LaborCost},
+		{"opex", then.Opex, now.Opex},
+		{"net_profit", then.NetProfit, now.NetProfit},
+		{"covers", float64(then.Covers), float64(now.Covers)},
+		{"discounts", then.Discounts, now.Discounts},
+		{"comps", then.Comps, now.Comps},
+	}
+
+	comparison := &SnapshotComparison{
+		Snapshot: *snap,
+		Current:  now,
+		Drift:    make([]MetricDrift, 0, len(metrics)),
+	}
+	for _, m := range metrics {
+		d := MetricDrift{
+			Metric:   m.name,
+			Snapshot: roundTo2(m.snapshot),
+			Current:  roundTo2(m.cur),
+			Delta:    roundTo2(m.cur - m.snapshot),
+		}
+		if m.snapshot != 0 {
+			pct := roundTo2((m.cur - m.snapshot) / math.Abs(m.snapshot) * 100)
+			d.DeltaPct = &pct
+		}
+		if math.Abs(m.cur-m.snapshot) >= 0.005 {
+			comparison.HasDrift = true
+		}
+		comparison.Drift = append(comparison.Drift, d)
+	}
+	return comparison, nil
+}
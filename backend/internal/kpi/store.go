@@ -47,12 +47,16 @@ type KPITotals struct {
 	AvgCheck           float64   `json:"avg_check"`
 	Discounts          float64   `json:"discounts"`
 	Comps              float64   `json:"comps"`
+	OpenDays           int       `json:"open_days"`
+	ClosedDays         int       `json:"closed_days"`
+	AvgDailyRevenue    float64   `json:"avg_daily_revenue"` // revenue per open day
 	FreshnessTimestamp time.Time `json:"freshness_timestamp"`
 }
 
 // Store handles KPI data persistence
 type Store struct {
-	db *pgxpool.Pool
+	db     *pgxpool.Pool
+	rollup bool // read totals and breakdowns from kpi_daily_rollup
 }
 
 // NewStore creates a new KPI store
@@ -60,8 +64,58 @@ func NewStore(db *pgxpool.Pool) *Store {
 	return &Store{db: db}
 }
 
-// GetAggregates retrieves KPI aggregates for a date range
-func (s *Store) GetAggregates(ctx context.Context, startDate, endDate time.Time) ([]KPIAggregate, error) {
+// NewRollupStore creates a KPI store that reads totals and channel, daypart
+// and daily breakdowns from the kpi_daily_rollup materialized view instead of
+// summing kpi_aggregates on every request
+func NewRollupStore(db *pgxpool.Pool) *Store {
+	return &Store{db: db, rollup: true}
+}
+
+// RefreshRollup recomputes kpi_daily_rollup from kpi_aggregates without
+// blocking readers
+func (s *Store) RefreshRollup(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY kpi_daily_rollup`)
+	return err
+}
+
+// refreshRollupIfUsed refreshes kpi_daily_rollup after a direct change to
+// kpi_aggregates, when this store reads from it
+func (s *Store) refreshRollupIfUsed(ctx context.Context) error {
+	if !s.rollup {
+		return nil
+	}
+	return s.RefreshRollup(ctx)
+}
+
+// aggregatesFrom returns the FROM item, aliased k, for queries that sum
+// aggregates: the rollup rows at grain (day, channel or daypart) when the
+// store reads the rollup, otherwise kpi_aggregates
+func (s *Store) aggregatesFrom(grain string) string {
+	if !s.rollup {
+		return "kpi_aggregates k"
+	}
+	return "(SELECT * FROM kpi_daily_rollup WHERE grain = '" + grain + "') k"
+}
+
+// DefaultLocationID returns the location for single-venue deployments. It
+// returns pgx.ErrNoRows unless exactly one location exists.
+func (s *Store) DefaultLocationID(ctx context.Context) (uuid.UUID, error) {
+	rows, err := s.db.Query(ctx, `SELECT id FROM locations LIMIT 2`)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(ids) != 1 {
+		return uuid.Nil, pgx.ErrNoRows
+	}
+	return ids[0], nil
+}
+
+// GetAggregates retrieves KPI aggregates for a location and date range
+func (s *Store) GetAggregates(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]KPIAggregate, error) {
 	query := `
 		SELECT k.id, k.date, k.location_id, k.channel_id, k.daypart_id,
 			k.revenue, k.cogs, k.gross_margin, k.labor_cost, k.labor_pct,
@@ -72,11 +126,11 @@ func (s *Store) GetAggregates(ctx context.Context, startDate, endDate time.Time)
 		FROM kpi_aggregates k
 		LEFT JOIN service_channels sc ON k.channel_id = sc.id
 		LEFT JOIN dayparts d ON k.daypart_id = d.id
-		WHERE k.date >= $1 AND k.date <= $2
+		WHERE k.date >= $1 AND k.date <= $2 AND k.location_id = $3
 		ORDER BY k.date DESC, sc.display_name, d.start_time
 	`
 
-	rows, err := s.db.Query(ctx, query, startDate, endDate)
+	rows, err := s.db.Query(ctx, query, startDate, endDate, locationID)
 	if err != nil {
 		return nil, err
 	}
@@ -101,28 +155,32 @@ func (s *Store) GetAggregates(ctx context.Context, startDate, endDate time.Time)
 	return aggregates, rows.Err()
 }
 
-// GetTotals retrieves totaled KPIs for a date range
-func (s *Store) GetTotals(ctx context.Context, startDate, endDate time.Time) (*KPITotals, error) {
+// GetTotals retrieves totaled KPIs for a location and date range
+func (s *Store) GetTotals(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) (*KPITotals, error) {
 	query := `
 		SELECT
 			COALESCE(SUM(revenue), 0) as revenue,
 			COALESCE(SUM(cogs), 0) as cogs,
 			COALESCE(SUM(gross_margin), 0) as gross_margin,
 			COALESCE(SUM(labor_cost), 0) as labor_cost,
-			CASE WHEN SUM(revenue) > 0 THEN SUM(labor_cost) / SUM(revenue) * 100 ELSE 0 END as labor_pct,
+			CASE WHEN SUM(revenue) FILTER (WHERE NOT is_closed) > 0
+				THEN SUM(labor_cost) FILTER (WHERE NOT is_closed) / SUM(revenue) FILTER (WHERE NOT is_closed) * 100
+				ELSE 0 END as labor_pct,
 			COALESCE(SUM(opex), 0) as opex,
 			COALESCE(SUM(net_profit), 0) as net_profit,
 			COALESCE(SUM(covers), 0) as covers,
-			CASE WHEN SUM(covers) > 0 THEN SUM(revenue) / SUM(covers) ELSE 0 END as avg_check,
+			CASE WHEN SUM(covers) FILTER (WHERE NOT is_closed) > 0
+				THEN SUM(revenue) FILTER (WHERE NOT is_closed) / SUM(covers) FILTER (WHERE NOT is_closed)
+				ELSE 0 END as avg_check,
 			COALESCE(SUM(discounts), 0) as discounts,
 			COALESCE(SUM(comps), 0) as comps,
 			COALESCE(MAX(freshness_timestamp), NOW()) as freshness_timestamp
-		FROM kpi_aggregates
-		WHERE date >= $1 AND date <= $2
+		FROM ` + s.aggregatesFrom("day") + `
+		WHERE date >= $1 AND date <= $2 AND location_id = $3
 	`
 
 	var totals KPITotals
-	err := s.db.QueryRow(ctx, query, startDate, endDate).Scan(
+	err := s.db.QueryRow(ctx, query, startDate, endDate, locationID).Scan(
 		&totals.Revenue, &totals.COGS, &totals.GrossMargin,
 		&totals.LaborCost, &totals.LaborPct, &totals.Opex,
 		&totals.NetProfit, &totals.Covers, &totals.AvgCheck,
@@ -135,8 +193,8 @@ func (s *Store) GetTotals(ctx context.Context, startDate, endDate time.Time) (*K
 	return &totals, nil
 }
 
-// GetByChannel retrieves KPIs grouped by channel for a date range
-func (s *Store) GetByChannel(ctx context.Context, startDate, endDate time.Time) ([]KPISummary, error) {
+// GetByChannel retrieves KPIs grouped by channel for a location and date range
+func (s *Store) GetByChannel(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]KPISummary, error) {
 	query := `
 		SELECT
 			sc.code as label,
@@ -151,15 +209,17 @@ func (s *Store) GetByChannel(ctx context.Context, startDate, endDate time.Time)
 			COALESCE(SUM(k.covers), 0) as covers,
 			CASE WHEN SUM(k.covers) > 0 THEN SUM(k.revenue) / SUM(k.covers) ELSE 0 END as avg_check,
 			COALESCE(SUM(k.discounts), 0) as discounts,
-			COALESCE(SUM(k.comps), 0) as comps
-		FROM kpi_aggregates k
+			COALESCE(SUM(k.comps), 0) as comps,
+			COALESCE(SUM(k.commissions), 0) as commissions,
+			COALESCE(SUM(k.revenue - k.commissions), 0) as net_revenue
+		FROM ` + s.aggregatesFrom("channel") + `
 		JOIN service_channels sc ON k.channel_id = sc.id
-		WHERE k.date >= $1 AND k.date <= $2 AND k.channel_id IS NOT NULL
+		WHERE k.date >= $1 AND k.date <= $2 AND k.location_id = $3 AND k.channel_id IS NOT NULL
 		GROUP BY sc.code, sc.display_name
 		ORDER BY sc.display_name
 	`
 
-	rows, err := s.db.Query(ctx, query, startDate, endDate)
+	rows, err := s.db.Query(ctx, query, startDate, endDate, locationID)
 	if err != nil {
 		return nil, err
 	}
@@ -168,8 +228,8 @@ func (s *Store) GetByChannel(ctx context.Context, startDate, endDate time.Time)
 	return scanSummaries(rows)
 }
 
-// GetByDaypart retrieves KPIs grouped by daypart for a date range
-func (s *Store) GetByDaypart(ctx context.Context, startDate, endDate time.Time) ([]KPISummary, error) {
+// GetByDaypart retrieves KPIs grouped by daypart for a location and date range
+func (s *Store) GetByDaypart(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]KPISummary, error) {
 	query := `
 		SELECT
 			d.code as label,
@@ -184,15 +244,17 @@ func (s *Store) GetByDaypart(ctx context.Context, startDate, endDate time.Time)
 			COALESCE(SUM(k.covers), 0) as covers,
 			CASE WHEN SUM(k.covers) > 0 THEN SUM(k.revenue) / SUM(k.covers) ELSE 0 END as avg_check,
 			COALESCE(SUM(k.discounts), 0) as discounts,
-			COALESCE(SUM(k.comps), 0) as comps
-		FROM kpi_aggregates k
+			COALESCE(SUM(k.comps), 0) as comps,
+			COALESCE(SUM(k.commissions), 0) as commissions,
+			COALESCE(SUM(k.revenue - k.commissions), 0) as net_revenue
+		FROM ` + s.aggregatesFrom("daypart") + `
 		JOIN dayparts d ON k.daypart_id = d.id
-		WHERE k.date >= $1 AND k.date <= $2 AND k.daypart_id IS NOT NULL
+		WHERE k.date >= $1 AND k.date <= $2 AND k.location_id = $3 AND k.daypart_id IS NOT NULL
 		GROUP BY d.code, d.display_name, d.start_time
 		ORDER BY d.start_time
 	`
 
-	rows, err := s.db.Query(ctx, query, startDate, endDate)
+	rows, err := s.db.Query(ctx, query, startDate, endDate, locationID)
 	if err != nil {
 		return nil, err
 	}
@@ -216,6 +278,8 @@ type KPISummary struct {
 	AvgCheck    float64 `json:"avg_check"`
 	Discounts   float64 `json:"discounts"`
 	Comps       float64 `json:"comps"`
+	Commissions float64 `json:"commissions"` // delivery platform fees
+	NetRevenue  float64 `json:"net_revenue"` // revenue less commissions
 }
 
 func scanSummaries(rows pgx.Rows) ([]KPISummary, error) {
@@ -226,6 +290,7 @@ func scanSummaries(rows pgx.Rows) ([]KPISummary, error) {
 			&s.Label, &s.DisplayName, &s.Revenue, &s.COGS, &s.GrossMargin,
 			&s.LaborCost, &s.LaborPct, &s.Opex, &s.NetProfit,
 			&s.Covers, &s.AvgCheck, &s.Discounts, &s.Comps,
+			&s.Commissions, &s.NetRevenue,
 		)
 		if err != nil {
 			return nil, err
@@ -234,3 +299,89 @@ func scanSummaries(rows pgx.Rows) ([]KPISummary, error) {
 	}
 	return summaries, rows.Err()
 }
+
+// ReasonSummary represents discount or comp totals for a single reason code
+type ReasonSummary struct {
+	Type   string  `json:"type"` // discount, comp
+	Reason string  `json:"reason"`
+	Amount float64 `json:"amount"`
+	Count  int     `json:"count"`
+}
+
+// GetByDiscountReason retrieves discount and comp totals grouped by reason code for a location and date range
+func (s *Store) GetByDiscountReason(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]ReasonSummary, error) {
+	query := `
+		SELECT type, reason, COALESCE(SUM(amount), 0) as amount, COUNT(*) as count
+		FROM (
+			SELECT 'discount' as type, COALESCE(NULLIF(discount_reason, ''), 'unspecified') as reason, discounts as amount
+			FROM sales
+			WHERE occurred_at >= $1 AND occurred_at <= $2 AND location_id = $3 AND discounts > 0
+			UNION ALL
+			SELECT 'comp' as type, COALESCE(NULLIF(comp_reason, ''), 'unspecified') as reason, comps as amount
+			FROM sales
+			WHERE occurred_at >= $1 AND occurred_at <= $2 AND location_id = $3 AND comps > 0
+		) r
+		GROUP BY type, reason
+		ORDER BY type DESC, amount DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, startDate, endDate, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []ReasonSummary
+	for rows.Next() {
+		var rs ReasonSummary
+		if err := rows.Scan(&rs.Type, &rs.Reason, &rs.Amount, &rs.Count); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, rs)
+	}
+	return summaries, rows.Err()
+}
+
+// OrderTypeSummary represents sales totals for a single order type
+type OrderTypeSummary struct {
+	OrderType    string  `json:"order_type"`
+	Revenue      float64 `json:"revenue"`
+	Transactions int     `json:"transactions"`
+	AvgCheck     float64 `json:"avg_check"`
+	Discounts    float64 `json:"discounts"`
+	Comps        float64 `json:"comps"`
+	SharePct     float64 `json:"share_pct"`
+}
+
+// GetByOrderType retrieves sales totals grouped by order type for a location
+// and date range, across all channels
+func (s *Store) GetByOrderType(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time) ([]OrderTypeSummary, error) {
+	query := `
+		SELECT
+			COALESCE(NULLIF(order_type, ''), 'unspecified') as order_type,
+			COALESCE(SUM(total), 0) as revenue,
+			COUNT(*) as transactions,
+			COALESCE(SUM(discounts), 0) as discounts,
+			COALESCE(SUM(comps), 0) as comps
+		FROM sales
+		WHERE occurred_at >= $1 AND occurred_at <= $2 AND location_id = $3
+		GROUP BY 1
+		ORDER BY revenue DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, startDate, endDate, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []OrderTypeSummary
+	for rows.Next() {
+		var ot OrderTypeSummary
+		if err := rows.Scan(&ot.OrderType, &ot.Revenue, &ot.Transactions, &ot.Discounts, &ot.Comps); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, ot)
+	}
+	return summaries, rows.Err()
+}
@@ -0,0 +1,82 @@
+package kpi
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool connects to the migrated database named by TEST_DATABASE_URL,
+// skipping the test when it is unset
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// testLocation creates a location and removes it, with everything the tests
+// seed for it, when the test ends
+func testLocation(t *testing.T, pool *pgxpool.Pool, name string) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	var locationID uuid.UUID
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO locations (name, timezone) VALUES ($1, 'UTC') RETURNING id
+	`, name).Scan(&locationID); err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM kpi_aggregates WHERE location_id = $1`,
+			`DELETE FROM payroll_periods WHERE location_id = $1`,
+			`DELETE FROM closed_days WHERE location_id = $1`,
+			`DELETE FROM gift_card_ledger WHERE location_id = $1`,
+			`DELETE FROM purchases WHERE location_id = $1`,
+			`DELETE FROM deposits WHERE location_id = $1`,
+			`DELETE FROM sales WHERE location_id = $1`,
+			`DELETE FROM service_channels WHERE location_id = $1`,
+			`DELETE FROM locations WHERE id = $1`,
+		} {
+			if _, err := pool.Exec(ctx, q, locationID); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+	})
+	return locationID
+}
+
+// testChannel creates a service channel for a location; testLocation's
+// cleanup removes it. Channel codes are unique across locations, so the code
+// carries a random suffix.
+func testChannel(t *testing.T, pool *pgxpool.Pool, locationID uuid.UUID, name string) uuid.UUID {
+	t.Helper()
+	var id uuid.UUID
+	code := name + "-" + uuid.NewString()[:8]
+	if err := pool.QueryRow(context.Background(), `
+		INSERT INTO service_channels (code, display_name, location_id) VALUES ($1, $2, $3) RETURNING id
+	`, code, name, locationID).Scan(&id); err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	return id
+}
+
+// seededDaypart returns one of the dayparts the migrations seed
+func seededDaypart(t *testing.T, pool *pgxpool.Pool) uuid.UUID {
+	t.Helper()
+	var id uuid.UUID
+	if err := pool.QueryRow(context.Background(), `SELECT id FROM dayparts ORDER BY start_time LIMIT 1`).Scan(&id); err != nil {
+		t.Fatalf("dayparts must be seeded: %v", err)
+	}
+	return id
+}
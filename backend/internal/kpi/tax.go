@@ -0,0 +1,126 @@
+package kpi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaxPeriodRow represents tax collected for one filing period and channel
+type TaxPeriodRow struct {
+	Period             string  `json:"period"`
+	Channel            string  `json:"channel"`
+	TaxableSales       float64 `json:"taxable_sales"`
+	TaxExemptSales     float64 `json:"tax_exempt_sales"`
+	TaxCollected       float64 `json:"tax_collected"`
+	Transactions       int     `json:"transactions"`
+	ExemptTransactions int     `json:"exempt_transactions"`
+}
+
+// TaxSummaryResponse represents tax totals for a range grouped by filing period
+type TaxSummaryResponse struct {
+	Range          string         `json:"range"`
+	GroupBy        string         `json:"group_by"`
+	TaxCollected   float64        `json:"tax_collected"`
+	TaxableSales   float64        `json:"taxable_sales"`
+	TaxExemptSales float64        `json:"tax_exempt_sales"`
+	Periods        []TaxPeriodRow `json:"periods"`
+}
+
+// TaxPeriodTrunc maps a filing period grouping to its date_trunc field
+func TaxPeriodTrunc(groupBy string) (string, error) {
+	switch groupBy {
+	case "", "month":
+		return "month", nil
+	case "quarter":
+		return "quarter", nil
+	default:
+		return "", fmt.Errorf("invalid group_by %q, use month or quarter", groupBy)
+	}
+}
+
+// FormatTaxPeriod renders the start of a filing period as 2024-03 or 2024-Q1
+func FormatTaxPeriod(periodStart time.Time, trunc string) string {
+	if trunc == "quarter" {
+		return fmt.Sprintf("%d-Q%d", periodStart.Year(), (int(periodStart.Month())-1)/3+1)
+	}
+	return periodStart.Format("2006-01")
+}
+
+// GetTaxSummary aggregates a location's sale-level tax by filing period and
+// channel, with periods starting in the location's timezone. Sales with zero
+// tax are reported as tax-exempt.
+func (s *Store) GetTaxSummary(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, trunc string) ([]TaxPeriodRow, error) {
+	query := `
+		SELECT
+			date_trunc('` + trunc + `', s.occurred_at AT TIME ZONE l.timezone) as period_start,
+			COALESCE(sc.display_name, 'Unknown') as channel,
+			COALESCE(SUM(s.subtotal) FILTER (WHERE s.tax <> 0), 0) as taxable_sales,
+			COALESCE(SUM(s.subtotal) FILTER (WHERE s.tax = 0), 0) as tax_exempt_sales,
+			COALESCE(SUM(s.tax), 0) as tax_collected,
+			COUNT(*) as transactions,
+			COUNT(*) FILTER (WHERE s.tax = 0) as exempt_transactions
+		FROM sales s
+		JOIN locations l ON l.id = s.location_id
+		LEFT JOIN service_channels sc ON s.channel_id = sc.id
+		WHERE s.occurred_at >= $1 AND s.occurred_at <= $2 AND s.location_id = $3
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`
+
+	rows, err := s.db.Query(ctx, query, startDate, endDate, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var periods []TaxPeriodRow
+	for rows.Next() {
+		var row TaxPeriodRow
+		var periodStart time.Time
+		err := rows.Scan(&periodStart, &row.Channel, &row.TaxableSales, &row.TaxExemptSales,
+			&row.TaxCollected, &row.Transactions, &row.ExemptTransactions)
+		if err != nil {
+			return nil, err
+		}
+		row.Period = FormatTaxPeriod(periodStart, trunc)
+		periods = append(periods, row)
+	}
+	return periods, rows.Err()
+}
+
+// GetTaxSummary retrieves a location's tax totals grouped by month or quarter
+func (s *Service) GetTaxSummary(ctx context.Context, locationID uuid.UUID, startDate, endDate time.Time, rangeLabel, groupBy string) (*TaxSummaryResponse, error) {
+	trunc, err := TaxPeriodTrunc(groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	periods, err := s.store.GetTaxSummary(ctx, locationID, startDate, endDate, trunc)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &TaxSummaryResponse{
+		Range:   rangeLabel,
+		GroupBy: trunc,
+		Periods: []TaxPeriodRow{},
+	}
+	for _, p := range periods {
+		response.TaxCollected += p.TaxCollected
+		response.TaxableSales += p.TaxableSales
+		response.TaxExemptSales += p.TaxExemptSales
+
+		p.TaxCollected = roundTo2(p.TaxCollected)
+		p.TaxableSales = roundTo2(p.TaxableSales)
+		p.TaxExemptSales = roundTo2(p.TaxExemptSales)
+		response.Periods = append(response.Periods, p)
+	}
+	response.TaxCollected = roundTo2(response.TaxCollected)
+	response.TaxableSales = roundTo2(response.TaxableSales)
+	response.TaxExemptSales = roundTo2(response.TaxExemptSales)
+
+	return response, nil
+}
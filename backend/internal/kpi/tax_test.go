@@ -0,0 +1,134 @@
+package kpi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTaxPeriodTrunc(t *testing.T) {
+	tests := []struct {
+		groupBy string
+		want    string
+		wantErr bool
+	}{
+		{groupBy: "", want: "month"},
+		{groupBy: "month", want: "month"},
+		{groupBy: "quarter", want: "quarter"},
+		{groupBy: "week", wantErr: true},
+		{groupBy: "month; DROP TABLE sales", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			got, err := TaxPeriodTrunc(tt.groupBy)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("TaxPeriodTrunc(%q) = %q, %v, want %q, error %v", tt.groupBy, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatTaxPeriod(t *testing.T) {
+	tests := []struct {
+		start time.Time
+		trunc string
+		want  string
+	}{
+		{start: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), trunc: "month", want: "2024-03"},
+		{start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), trunc: "quarter", want: "2024-Q1"},
+		{start: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), trunc: "quarter", want: "2024-Q4"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatTaxPeriod(tt.start, tt.trunc); got != tt.want {
+			t.Errorf("FormatTaxPeriod(%v, %s) = %q, want %q", tt.start, tt.trunc, got, tt.want)
+		}
+	}
+}
+
+// The filing totals must reconcile with the tax recorded on each sale,
+// whichever way they are grouped
+func TestTaxSummaryReconciles(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locationID := testLocation(t, pool, "Tax Test")
+	dineIn := testChannel(t, pool, locationID, "Dine In")
+	delivery := testChannel(t, pool, locationID, "Delivery")
+	daypartID := seededDaypart(t, pool)
+
+	sales := []struct {
+		day      time.Time
+		channel  uuid.UUID
+		subtotal float64
+		tax      float64
+	}{
+		{day: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), channel: dineIn, subtotal: 100, tax: 10},
+		{day: time.Date(2024, 1, 20, 19, 0, 0, 0, time.UTC), channel: delivery, subtotal: 45.45, tax: 4.55},
+		{day: time.Date(2024, 2, 3, 12, 0, 0, 0, time.UTC), channel: dineIn, subtotal: 33.33, tax: 3.33},
+		// Exempt sales
+		{day: time.Date(2024, 2, 10, 12, 0, 0, 0, time.UTC), channel: dineIn, subtotal: 20},
+		{day: time.Date(2024, 3, 31, 22, 0, 0, 0, time.UTC), channel: delivery, subtotal: 12.5},
+		// Outside the range
+		{day: time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC), channel: dineIn, subtotal: 500, tax: 50},
+	}
+	for _, s := range sales {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, tax, total)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, s.day, locationID, s.channel, daypartID, s.subtotal, s.tax, s.subtotal+s.tax); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	var saleTax float64
+	if err := pool.QueryRow(ctx, `
+		SELECT SUM(tax) FROM sales WHERE location_id = $1 AND occurred_at >= $2 AND occurred_at <= $3
+	`, locationID, start, end).Scan(&saleTax); err != nil {
+		t.Fatalf("sum sale tax: %v", err)
+	}
+
+	svc := NewService(NewStore(pool))
+	for _, groupBy := range []string{"month", "quarter"} {
+		t.Run(groupBy, func(t *testing.T) {
+			got, err := svc.GetTaxSummary(ctx, locationID, start, end, "custom", groupBy)
+			if err != nil {
+				t.Fatalf("GetTaxSummary() error = %v", err)
+			}
+			if got.TaxCollected != roundTo2(saleTax) || got.TaxCollected != 17.88 {
+				t.Errorf("TaxCollected = %v, want %v (sum of sale tax %v)", got.TaxCollected, 17.88, saleTax)
+			}
+			if got.TaxableSales != 178.78 || got.TaxExemptSales != 32.5 {
+				t.Errorf("taxable, exempt = %v, %v, want 178.78, 32.5", got.TaxableSales, got.TaxExemptSales)
+			}
+
+			var periodTax float64
+			var transactions, exempt int
+			periods := map[string]bool{}
+			for _, p := range got.Periods {
+				periodTax += p.TaxCollected
+				transactions += p.Transactions
+				exempt += p.ExemptTransactions
+				periods[p.Period] = true
+			}
+			if roundTo2(periodTax) != got.TaxCollected {
+				t.Errorf("periods sum to %v, total is %v", periodTax, got.TaxCollected)
+			}
+			if transactions != 5 || exempt != 2 {
+				t.Errorf("transactions, exempt = %d, %d, want 5, 2", transactions, exempt)
+			}
+			wantPeriods := 3
+			if groupBy == "quarter" {
+				wantPeriods = 1
+			}
+			if len(periods) != wantPeriods {
+				t.Errorf("got periods %v, want %d", periods, wantPeriods)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+// Package menu manages menu items and the recipe costs COGS is computed from
+package menu
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNotFound is returned when a menu item does not exist
+var ErrNotFound = errors.New("menu item not found")
+
+// ErrSKUTaken is returned when another menu item already has the SKU
+var ErrSKUTaken = errors.New("a menu item with this sku already exists")
+
+// Item is a menu item with its selling price and recipe cost
+type Item struct {
+	ID         uuid.UUID `json:"id"`
+	SKU        *string   `json:"sku,omitempty"`
+	Name       string    `json:"name"`
+	Category   string    `json:"category"`
+	RecipeCost float64   `json:"recipe_cost"`
+	Price      float64   `json:"price"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Validate checks an item's name and amounts and trims its SKU, clearing an
+// empty one
+func (i *Item) Validate() error {
+	i.Name = strings.TrimSpace(i.Name)
+	if i.Name == "" {
+		return errors.New("name is required")
+	}
+	if i.SKU != nil {
+		sku := strings.TrimSpace(*i.SKU)
+		i.SKU = &sku
+		if sku == "" {
+			i.SKU = nil
+		}
+	}
+	if i.RecipeCost < 0 || i.Price < 0 {
+		return errors.New("recipe_cost and price must not be negative")
+	}
+	return nil
+}
+
+// DateRange is the inclusive span of local days a location sold an item on
+type DateRange struct {
+	LocationID uuid.UUID
+	Start      time.Time
+	End        time.Time
+}
+
+// Store handles menu item persistence
+type Store struct {
+	db *pgxpool.Pool
+}
+
+// NewStore creates a new menu item store
+func NewStore(db *pgxpool.Pool) *Store {
+	return &Store{db: db}
+}
+
+const itemColumns = `id, sku, name, COALESCE(category, ''), recipe_cost, price, is_active, created_at, updated_at`
+
+func scanItem(row pgx.Row) (*Item, error) {
+	var i Item
+	err := row.Scan(&i.ID, &i.SKU, &i.Name, &i.Category, &i.RecipeCost, &i.Price, &i.Active, &i.CreatedAt, &i.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// List returns menu items ordered by category and name, optionally including
+// inactive ones
+func (s *Store) List(ctx context.Context, includeInactive bool) ([]Item, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+itemColumns+`
+		FROM menu_items
+		WHERE $1 OR is_active
+		ORDER BY category, name
+	`, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Item{}
+	for rows.Next() {
+		i, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *i)
+	}
+	return list, rows.Err()
+}
+
+// Get returns a menu item or ErrNotFound
+func (s *Store) Get(ctx context.Context, id uuid.UUID) (*Item, error) {
+	i, err := scanItem(s.db.QueryRow(ctx, `SELECT `+itemColumns+` FROM menu_items WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return i, err
+}
+
+// Create inserts a menu item and links imported sale lines carrying its SKU
+func (s *Store) Create(ctx context.Context, i *Item) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		i.ID = uuid.New()
+		err := tx.QueryRow(ctx, `
+			INSERT INTO menu_items (id, sku, name, category, recipe_cost, price, is_active, created_at, updated_at)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, NOW(), NOW())
+			RETURNING created_at, updated_at
+		`, i.ID, i.SKU, i.Name, i.Category, i.RecipeCost, i.Price, i.Active).Scan(&i.CreatedAt, &i.UpdatedAt)
+		if err != nil {
+			return constraintError(err)
+		}
+		return linkSaleLines(ctx, tx, i)
+	})
+}
+
+// Update saves a menu item and links imported sale lines carrying its SKU
+func (s *Store) Update(ctx context.Context, i *Item) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			UPDATE menu_items SET
+				sku = $2,
+				name = $3,
+				category = NULLIF($4, ''),
+				recipe_cost = $5,
+				price = $6,
+				is_active = $7,
+				updated_at = NOW()
+			WHERE id = $1
+			RETURNING updated_at
+		`, i.ID, i.SKU, i.Name, i.Category, i.RecipeCost, i.Price, i.Active).Scan(&i.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return constraintError(err)
+		}
+		return linkSaleLines(ctx, tx, i)
+	})
+}
+
+// UpsertBySKU creates or updates each item by SKU in one transaction, so a
+// menu export from the POS can seed and later re-sync recipe costs. Every
+// item must have a SKU. The stored items are returned with whether each was
+// created.
+func (s *Store) UpsertBySKU(ctx context.Context, items []Item) ([]Item, []bool, error) {
+	created := make([]bool, len(items))
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		for n := range items {
+			i := &items[n]
+			err := tx.QueryRow(ctx, `
+				INSERT INTO menu_items (id, sku, name, category, recipe_cost, price, is_active, created_at, updated_at)
+				VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, NOW(), NOW())
+				ON CONFLICT (LOWER(sku)) WHERE sku IS NOT NULL DO UPDATE SET
+					name = EXCLUDED.name,
+					category = EXCLUDED.category,
+					recipe_cost = EXCLUDED.recipe_cost,
+					price = EXCLUDED.price,
+					is_active = EXCLUDED.is_active,
+					updated_at = NOW()
+				RETURNING id, created_at, updated_at, xmax = 0
+			`, uuid.New(), i.SKU, i.Name, i.Category, i.RecipeCost, i.Price, i.Active).Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt, &created[n])
+			if err != nil {
+				return err
+			}
+			if err := linkSaleLines(ctx, tx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, created, nil
+}
+
+// SoldRanges returns, per location, the local days on which the items were
+// sold, which are the aggregate days a recipe cost change affects
+func (s *Store) SoldRanges(ctx context.Context, ids []uuid.UUID) ([]DateRange, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT s.location_id,
+			MIN((s.occurred_at AT TIME ZONE l.timezone)::date),
+			MAX((s.occurred_at AT TIME ZONE l.timezone)::date)
+		FROM sale_lines sl
+		JOIN sales s ON s.id = sl.sale_id
+		JOIN locations l ON l.id = s.location_id
+		WHERE sl.menu_item_id = ANY($1)
+		GROUP BY s.location_id
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ranges []DateRange
+	for rows.Next() {
+		var r DateRange
+		if err := rows.Scan(&r.LocationID, &r.Start, &r.End); err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, rows.Err()
+}
+
+// linkSaleLines attaches imported sale lines that carry the item's SKU but
+// had no matching menu item when they were imported
+func linkSaleLines(ctx context.Context, tx pgx.Tx, i *Item) error {
+	if i.SKU == nil {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE sale_lines SET menu_item_id = $1, updated_at = NOW()
+		WHERE menu_item_id IS NULL AND LOWER(sku) = LOWER($2)
+	`, i.ID, *i.SKU)
+	return err
+}
+
+// constraintError maps unique violations to ErrSKUTaken
+func constraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrSKUTaken
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+package menu
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool connects to the migrated database named by TEST_DATABASE_URL,
+// skipping the test when it is unset
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestItemValidate(t *testing.T) {
+	sku := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		item    Item
+		wantSKU *string
+		wantErr string
+	}{
+		{name: "trims sku", item: Item{Name: " Burger ", SKU: sku(" BURG-01 ")}, wantSKU: sku("BURG-01")},
+		{name: "clears empty sku", item: Item{Name: "Burger", SKU: sku("  ")}},
+		{name: "no sku", item: Item{Name: "Burger"}},
+		{name: "no name", item: Item{Name: "  "}, wantErr: "name is required"},
+		{name: "negative cost", item: Item{Name: "Burger", RecipeCost: -0.5}, wantErr: "recipe_cost and price must not be negative"},
+		{name: "negative price", item: Item{Name: "Burger", Price: -1}, wantErr: "recipe_cost and price must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.item.Validate()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if tt.item.Name != "Burger" {
+				t.Errorf("name = %q, want it trimmed", tt.item.Name)
+			}
+			got := tt.item.SKU
+			if (got == nil) != (tt.wantSKU == nil) || (got != nil && *got != *tt.wantSKU) {
+				t.Errorf("sku = %v, want %v", got, tt.wantSKU)
+			}
+		})
+	}
+}
+
+// TestUpsertBySKU seeds items from a menu export, re-syncs them with a new
+// recipe cost and checks imported sale lines with the SKU are linked
+func TestUpsertBySKU(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+	sku := "TEST-" + uuid.NewString()[:8]
+
+	var locationID, saleID, lineID uuid.UUID
+	if err := pool.QueryRow(ctx, `INSERT INTO locations (name, timezone) VALUES ('Menu test', 'UTC') RETURNING id`).Scan(&locationID); err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	var itemIDs []uuid.UUID
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM sales WHERE location_id = $1`,
+			`DELETE FROM locations WHERE id = $1`,
+		} {
+			if _, err := pool.Exec(ctx, q, locationID); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+		if _, err := pool.Exec(ctx, `DELETE FROM menu_items WHERE id = ANY($1)`, itemIDs); err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+	})
+
+	// A line imported before its menu item existed
+	soldAt := time.Date(2001, 9, 3, 12, 0, 0, 0, time.UTC)
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total)
+		VALUES ($1, $2, (SELECT id FROM service_channels LIMIT 1), (SELECT id FROM dayparts LIMIT 1), 20, 20)
+		RETURNING id
+	`, soldAt, locationID).Scan(&saleID); err != nil {
+		t.Fatalf("insert sale: %v", err)
+	}
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO sale_lines (sale_id, location_id, sku, quantity) VALUES ($1, $2, LOWER($3), 2) RETURNING id
+	`, saleID, locationID, sku).Scan(&lineID); err != nil {
+		t.Fatalf("insert sale line: %v", err)
+	}
+
+	stored, created, err := store.UpsertBySKU(ctx, []Item{{SKU: &sku, Name: "Burger", RecipeCost: 6, Price: 18, Active: true}})
+	if err != nil {
+		t.Fatalf("UpsertBySKU() error = %v", err)
+	}
+	itemIDs = append(itemIDs, stored[0].ID)
+	if !created[0] {
+		t.Error("first upsert reported an update, want a create")
+	}
+
+	var linked *uuid.UUID
+	if err := pool.QueryRow(ctx, `SELECT menu_item_id FROM sale_lines WHERE id = $1`, lineID).Scan(&linked); err != nil {
+		t.Fatalf("read sale line: %v", err)
+	}
+	if linked == nil || *linked != stored[0].ID {
+		t.Errorf("sale line menu_item_id = %v, want %v", linked, stored[0].ID)
+	}
+
+	// Re-syncing the export updates the same item in place
+	again, created, err := store.UpsertBySKU(ctx, []Item{{SKU: &sku, Name: "Burger", RecipeCost: 7.5, Price: 19, Active: true}})
+	if err != nil {
+		t.Fatalf("UpsertBySKU() error = %v", err)
+	}
+	if created[0] || again[0].ID != stored[0].ID {
+		t.Errorf("re-sync created %v with id %v, want an update of %v", created[0], again[0].ID, stored[0].ID)
+	}
+	got, err := store.Get(ctx, stored[0].ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.RecipeCost != 7.5 || got.Price != 19 {
+		t.Errorf("recipe_cost, price = %v, %v, want 7.5, 19", got.RecipeCost, got.Price)
+	}
+
+	ranges, err := store.SoldRanges(ctx, []uuid.UUID{got.ID})
+	if err != nil {
+		t.Fatalf("SoldRanges() error = %v", err)
+	}
+	day := time.Date(2001, 9, 3, 0, 0, 0, 0, time.UTC)
+	if len(ranges) != 1 || ranges[0].LocationID != locationID || !ranges[0].Start.Equal(day) || !ranges[0].End.Equal(day) {
+		t.Errorf("SoldRanges() = %+v, want %v on %v", ranges, locationID, day)
+	}
+
+	// A second item may not take the SKU
+	other := Item{SKU: &sku, Name: "Other burger", Active: true}
+	if err := store.Create(ctx, &other); !errors.Is(err, ErrSKUTaken) {
+		if err == nil {
+			itemIDs = append(itemIDs, other.ID)
+		}
+		t.Errorf("Create() with a taken SKU error = %v, want ErrSKUTaken", err)
+	}
+}
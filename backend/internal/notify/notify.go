@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// ErrNotConfigured is returned when a delivery channel has no settings
+var ErrNotConfigured = errors.New("notification channel is not configured")
+
+// Config holds email and webhook delivery settings
+type Config struct {
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	WebhookURL   string
+	Timeout      time.Duration
+}
+
+// DefaultConfig returns delivery settings with no channels configured
+func DefaultConfig() Config {
+	return Config{
+		SMTPPort: 587,
+		Timeout:  10 * time.Second,
+	}
+}
+
+// EmailConfigured reports whether SMTP delivery is set up
+func (c Config) EmailConfigured() bool {
+	return c.SMTPHost != "" && c.SMTPFrom != ""
+}
+
+// WebhookConfigured reports whether webhook delivery is set up
+func (c Config) WebhookConfigured() bool {
+	return c.WebhookURL != ""
+}
+
+// Notifier delivers notifications by email and webhook
+type Notifier struct {
+	cfg    Config
+	client *http.Client
+}
+
+// NewNotifier creates a new notifier
+func NewNotifier(cfg Config) *Notifier {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultConfig().Timeout
+	}
+	return &Notifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Config returns the notifier's delivery settings
+func (n *Notifier) Config() Config {
+	return n.cfg
+}
+
+// SendEmail delivers a plain-text email through the configured SMTP server,
+// upgrading to TLS when the server supports STARTTLS
+func (n *Notifier) SendEmail(ctx context.Context, to []string, subject, body string) error {
+	if !n.cfg.EmailConfigured() {
+		return ErrNotConfigured
+	}
+	if len(to) == 0 {
+		return errors.New("no email recipients")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(n.cfg.SMTPHost, fmt.Sprint(n.cfg.SMTPPort))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, n.cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.cfg.SMTPHost}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if n.cfg.SMTPUsername != "" {
+		auth := smtp.PlainAuth("", n.cfg.SMTPUsername, n.cfg.SMTPPassword, n.cfg.SMTPHost)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(n.cfg.SMTPFrom); err != nil {
+		return fmt.Errorf("smtp sender rejected: %w", err)
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp recipient %s rejected: %w", rcpt, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(buildMessage(n.cfg.SMTPFrom, to, subject, body)); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp message rejected: %w", err)
+	}
+	return c.Quit()
+}
+
+// PostWebhook sends payload as JSON to the configured webhook URL. Any non-2xx
+// response is reported as an error.
+func (n *Notifier) PostWebhook(ctx context.Context, payload interface{}) error {
+	if !n.cfg.WebhookConfigured() {
+		return ErrNotConfigured
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.WebhookURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+func buildMessage(from string, to []string, subject, body string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: " + subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
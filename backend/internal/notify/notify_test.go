@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is a minimal SMTP server that accepts one message per connection
+// and rejects recipients at the reject domain
+type fakeSMTP struct {
+	host     string
+	port     int
+	messages chan string
+}
+
+func newFakeSMTP(t *testing.T, reject string) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	addr := ln.Addr().(*net.TCPAddr)
+	f := &fakeSMTP{host: "127.0.0.1", port: addr.Port, messages: make(chan string, 1)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn, reject)
+		}
+	}()
+	return f
+}
+
+func (f *fakeSMTP) serve(conn net.Conn, reject string) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case cmd == "EHLO" || cmd == "HELO":
+			tp.PrintfLine("250 fake")
+		case cmd == "MAIL":
+			tp.PrintfLine("250 OK")
+		case cmd == "RCPT":
+			if reject != "" && strings.Contains(line, "@"+reject) {
+				tp.PrintfLine("550 no such user")
+				continue
+			}
+			tp.PrintfLine("250 OK")
+		case cmd == "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			f.messages <- string(data)
+			tp.PrintfLine("250 queued")
+		case cmd == "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (f *fakeSMTP) config() Config {
+	return Config{SMTPHost: f.host, SMTPPort: f.port, SMTPFrom: "finance@example.com", Timeout: 5 * time.Second}
+}
+
+func TestSendEmail(t *testing.T) {
+	server := newFakeSMTP(t, "")
+	n := NewNotifier(server.config())
+
+	if err := n.SendEmail(context.Background(), []string{"owner@example.com"}, "Test notification", "Line one\nLine two"); err != nil {
+		t.Fatalf("SendEmail() error = %v", err)
+	}
+
+	select {
+	case msg := <-server.messages:
+		for _, want := range []string{"From: finance@example.com", "To: owner@example.com", "Subject: Test notification", "Line one\nLine two"} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("message does not contain %q:\n%s", want, msg)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message delivered")
+	}
+}
+
+func TestSendEmailErrors(t *testing.T) {
+	server := newFakeSMTP(t, "gone.example")
+
+	// A port nothing listens on
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closedPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	unreachable := server.config()
+	unreachable.SMTPPort = closedPort
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		to      []string
+		wantErr string
+	}{
+		{name: "not configured", cfg: DefaultConfig(), to: []string{"owner@example.com"}, wantErr: ErrNotConfigured.Error()},
+		{name: "no recipients", cfg: server.config(), wantErr: "no email recipients"},
+		{name: "rejected recipient", cfg: server.config(), to: []string{"owner@gone.example"}, wantErr: "recipient owner@gone.example rejected"},
+		{name: "unreachable", cfg: unreachable, to: []string{"owner@example.com"}, wantErr: "connect to 127.0.0.1:" + strconv.Itoa(closedPort)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewNotifier(tt.cfg).SendEmail(context.Background(), tt.to, "Test", "body")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("SendEmail() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	var got map[string]interface{}
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ok.Close()
+
+	n := NewNotifier(Config{WebhookURL: ok.URL})
+	if err := n.PostWebhook(context.Background(), map[string]string{"event": "test"}); err != nil {
+		t.Fatalf("PostWebhook() error = %v", err)
+	}
+	if got["event"] != "test" {
+		t.Errorf("webhook received %v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+
+	err := NewNotifier(Config{WebhookURL: failing.URL}).PostWebhook(context.Background(), map[string]string{"event": "test"})
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "bad signature") {
+		t.Errorf("PostWebhook() error = %v, want the status and response body", err)
+	}
+
+	err = NewNotifier(DefaultConfig()).PostWebhook(context.Background(), nil)
+	if !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("PostWebhook() error = %v, want ErrNotConfigured", err)
+	}
+}
@@ -0,0 +1,121 @@
+// Package privacy handles data-protection requests such as erasing a staff
+// member's attribution from financial records
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNoStaffRecords is returned when no records are attributed to the staff member
+var ErrNoStaffRecords = errors.New("no records are attributed to this staff member")
+
+// tombstonePrefix starts the name that replaces an anonymized staff member's
+const tombstonePrefix = "redacted-"
+
+// AttributedSale is a sale recorded against a staff member
+type AttributedSale struct {
+	ID            uuid.UUID `json:"id"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	Total         float64   `json:"total"`
+	Subtotal      float64   `json:"subtotal"`
+	Tax           float64   `json:"tax"`
+	Discounts     float64   `json:"discounts"`
+	Comps         float64   `json:"comps"`
+	PaymentMethod *string   `json:"payment_method,omitempty"`
+	OrderType     *string   `json:"order_type,omitempty"`
+	SourceID      *string   `json:"source_id,omitempty"`
+}
+
+// StaffData is everything a location holds attributed to one staff member
+type StaffData struct {
+	Name  string           `json:"name"`
+	Sales []AttributedSale `json:"sales"`
+}
+
+// StaffStore exports and anonymizes staff attribution
+type StaffStore struct {
+	db *pgxpool.Pool
+}
+
+// NewStaffStore creates a new staff store
+func NewStaffStore(db *pgxpool.Pool) *StaffStore {
+	return &StaffStore{db: db}
+}
+
+// Export returns the records attributed to a staff member at the location.
+// Names match case-insensitively, ignoring surrounding spaces, so variants
+// typed into different POS exports are all found.
+func (s *StaffStore) Export(ctx context.Context, locationID uuid.UUID, name string) (*StaffData, error) {
+	return exportStaff(ctx, s.db, locationID, name)
+}
+
+// Anonymize exports a staff member's records and then replaces their name on
+// every one with a tombstone, in one transaction. Amounts are untouched, so
+// revenue and other totals are unchanged and the staff member's sales still
+// group together under the tombstone.
+func (s *StaffStore) Anonymize(ctx context.Context, locationID uuid.UUID, name string) (data *StaffData, tombstone string, err error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	data, err = exportStaff(ctx, tx, locationID, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data.Sales) == 0 {
+		return nil, "", ErrNoStaffRecords
+	}
+
+	tombstone = tombstonePrefix + strings.ReplaceAll(uuid.NewString(), "-", "")[:8]
+	_, err = tx.Exec(ctx, `
+		UPDATE sales SET server_name = $3, updated_at = NOW()
+		WHERE location_id = $1 AND LOWER(TRIM(server_name)) = LOWER(TRIM($2))
+	`, locationID, name, tombstone)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to anonymize sales: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, "", err
+	}
+	return data, tombstone, nil
+}
+
+// querier is satisfied by both the pool and a transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+func exportStaff(ctx context.Context, db querier, locationID uuid.UUID, name string) (*StaffData, error) {
+	rows, err := db.Query(ctx, `
+		SELECT id, occurred_at, total, subtotal, tax, discounts, comps, payment_method, order_type, source_id
+		FROM sales
+		WHERE location_id = $1 AND LOWER(TRIM(server_name)) = LOWER(TRIM($2))
+		ORDER BY occurred_at
+	`, locationID, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := &StaffData{Name: strings.TrimSpace(name), Sales: []AttributedSale{}}
+	for rows.Next() {
+		var sale AttributedSale
+		if err := rows.Scan(&sale.ID, &sale.OccurredAt, &sale.Total, &sale.Subtotal, &sale.Tax, &sale.Discounts,
+			&sale.Comps, &sale.PaymentMethod, &sale.OrderType, &sale.SourceID); err != nil {
+			return nil, err
+		}
+		data.Sales = append(data.Sales, sale)
+	}
+	return data, rows.Err()
+}
@@ -0,0 +1,135 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool connects to the migrated database named by TEST_DATABASE_URL,
+// skipping the test when it is unset
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// testLocation creates a location and removes it and its sales when the test
+// ends
+func testLocation(t *testing.T, pool *pgxpool.Pool, name string) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	var locationID uuid.UUID
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO locations (name, timezone) VALUES ($1, 'UTC') RETURNING id
+	`, name).Scan(&locationID); err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM sales WHERE location_id = $1`,
+			`DELETE FROM locations WHERE id = $1`,
+		} {
+			if _, err := pool.Exec(ctx, q, locationID); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+	})
+	return locationID
+}
+
+// insertSale records a sale served by server at the location
+func insertSale(t *testing.T, pool *pgxpool.Pool, locationID uuid.UUID, server string, total float64) {
+	t.Helper()
+	_, err := pool.Exec(context.Background(), `
+		INSERT INTO sales (occurred_at, location_id, channel_id, daypart_id, subtotal, total, server_name)
+		VALUES ($1, $2, (SELECT id FROM service_channels ORDER BY id LIMIT 1), (SELECT id FROM dayparts ORDER BY id LIMIT 1), $3, $3, $4)
+	`, time.Date(2001, 8, 1, 12, 0, 0, 0, time.UTC), locationID, total, server)
+	if err != nil {
+		t.Fatalf("insert sale: %v", err)
+	}
+}
+
+// revenueByServer sums sales totals per server name at the location
+func revenueByServer(t *testing.T, pool *pgxpool.Pool, locationID uuid.UUID) map[string]float64 {
+	t.Helper()
+	rows, err := pool.Query(context.Background(), `
+		SELECT server_name, SUM(total) FROM sales WHERE location_id = $1 GROUP BY server_name
+	`, locationID)
+	if err != nil {
+		t.Fatalf("query revenue: %v", err)
+	}
+	defer rows.Close()
+
+	revenue := map[string]float64{}
+	for rows.Next() {
+		var server string
+		var total float64
+		if err := rows.Scan(&server, &total); err != nil {
+			t.Fatalf("scan revenue: %v", err)
+		}
+		revenue[server] = total
+	}
+	return revenue
+}
+
+func TestAnonymizeKeepsRevenue(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locationID := testLocation(t, pool, "Anonymize test")
+	otherID := testLocation(t, pool, "Anonymize other")
+
+	// The same server typed three ways, a colleague, and a namesake elsewhere
+	insertSale(t, pool, locationID, "Jane Citizen", 120)
+	insertSale(t, pool, locationID, " jane citizen ", 80.5)
+	insertSale(t, pool, locationID, "JANE CITIZEN", 45.25)
+	insertSale(t, pool, locationID, "Sam Server", 60)
+	insertSale(t, pool, otherID, "Jane Citizen", 99)
+
+	store := NewStaffStore(pool)
+	data, tombstone, err := store.Anonymize(ctx, locationID, "Jane Citizen")
+	if err != nil {
+		t.Fatalf("Anonymize() error = %v", err)
+	}
+	if len(data.Sales) != 3 || data.Name != "Jane Citizen" {
+		t.Errorf("exported %d sales for %q, want 3 for Jane Citizen", len(data.Sales), data.Name)
+	}
+	if !strings.HasPrefix(tombstone, tombstonePrefix) || strings.Contains(strings.ToLower(tombstone), "jane") {
+		t.Errorf("tombstone = %q, want a redacted name", tombstone)
+	}
+
+	want := map[string]float64{tombstone: 245.75, "Sam Server": 60}
+	got := revenueByServer(t, pool, locationID)
+	if len(got) != len(want) || got[tombstone] != want[tombstone] || got["Sam Server"] != want["Sam Server"] {
+		t.Errorf("revenue by server = %v, want %v", got, want)
+	}
+	if other := revenueByServer(t, pool, otherID); other["Jane Citizen"] != 99 {
+		t.Errorf("other location's revenue by server = %v, want its Jane Citizen untouched", other)
+	}
+
+	// Nothing is left attributed to the name
+	remaining, err := store.Export(ctx, locationID, "jane citizen")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(remaining.Sales) != 0 {
+		t.Errorf("Export() after anonymizing = %d sales, want none", len(remaining.Sales))
+	}
+	if _, _, err := store.Anonymize(ctx, locationID, "Jane Citizen"); !errors.Is(err, ErrNoStaffRecords) {
+		t.Errorf("second Anonymize() error = %v, want ErrNoStaffRecords", err)
+	}
+}
@@ -0,0 +1,280 @@
+// Package sheets pushes export rows into a Google Sheet tab using a
+// location's own OAuth credentials
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/fieldcrypt"
+)
+
+// ErrNotConfigured is returned when a location has no Google Sheets credentials
+var ErrNotConfigured = errors.New("google sheets is not configured for this location")
+
+const (
+	defaultTokenURL = "https://oauth2.googleapis.com/token"
+	defaultAPIURL   = "https://sheets.googleapis.com/v4/spreadsheets"
+)
+
+// Credentials are a location's OAuth client and the refresh token granted
+// for the spreadsheets scope
+type Credentials struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Client writes rows to a spreadsheet tab
+type Client interface {
+	// ReplaceValues clears the tab and writes rows starting at A1
+	ReplaceValues(ctx context.Context, spreadsheetID, tab string, rows [][]string) error
+}
+
+// CredentialStore persists per-location credentials. The client secret and
+// refresh token are encrypted when a cipher is configured.
+type CredentialStore struct {
+	db     *pgxpool.Pool
+	cipher *fieldcrypt.Cipher
+}
+
+// NewCredentialStore creates a new credential store; cipher may be nil
+func NewCredentialStore(db *pgxpool.Pool, cipher *fieldcrypt.Cipher) *CredentialStore {
+	return &CredentialStore{db: db, cipher: cipher}
+}
+
+// Get returns a location's credentials or ErrNotConfigured
+func (s *CredentialStore) Get(ctx context.Context, locationID uuid.UUID) (*Credentials, error) {
+	var c Credentials
+	err := s.db.QueryRow(ctx, `
+		SELECT client_id, client_secret, refresh_token
+		FROM google_sheets_credentials
+		WHERE location_id = $1
+	`, locationID).Scan(&c.ClientID, &c.ClientSecret, &c.RefreshToken)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotConfigured
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ClientSecret, err = s.open(c.ClientSecret); err != nil {
+		return nil, err
+	}
+	if c.RefreshToken, err = s.open(c.RefreshToken); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Save stores a location's credentials, replacing any already saved
+func (s *CredentialStore) Save(ctx context.Context, locationID uuid.UUID, c Credentials) error {
+	secret, err := s.seal(c.ClientSecret)
+	if err != nil {
+		return err
+	}
+	token, err := s.seal(c.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(ctx, `
+		INSERT INTO google_sheets_credentials (location_id, client_id, client_secret, refresh_token)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (location_id) DO UPDATE SET
+			client_id = EXCLUDED.client_id,
+			client_secret = EXCLUDED.client_secret,
+			refresh_token = EXCLUDED.refresh_token,
+			updated_at = NOW()
+	`, locationID, c.ClientID, secret, token)
+	return err
+}
+
+// Delete removes a location's credentials, reporting whether any existed
+func (s *CredentialStore) Delete(ctx context.Context, locationID uuid.UUID) (bool, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM google_sheets_credentials WHERE location_id = $1`, locationID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *CredentialStore) seal(v string) (string, error) {
+	if s.cipher == nil {
+		return v, nil
+	}
+	return s.cipher.Encrypt(v)
+}
+
+// open decrypts a stored value; values saved before a key was configured
+// are returned as they are
+func (s *CredentialStore) open(v string) (string, error) {
+	if !fieldcrypt.IsEncrypted(v) {
+		return v, nil
+	}
+	if s.cipher == nil {
+		return "", errors.New("google sheets credentials are encrypted but FIELD_ENCRYPTION_KEY is not set")
+	}
+	return s.cipher.Decrypt(v)
+}
+
+// Exporter writes CSV exports into spreadsheets
+type Exporter struct {
+	creds     *CredentialStore
+	getCreds  func(context.Context, uuid.UUID) (*Credentials, error)
+	newClient func(Credentials) Client
+}
+
+// NewExporter creates an exporter that talks to the Google Sheets API
+func NewExporter(creds *CredentialStore, timeout time.Duration) *Exporter {
+	httpClient := &http.Client{Timeout: timeout}
+	return &Exporter{
+		creds:    creds,
+		getCreds: creds.Get,
+		newClient: func(c Credentials) Client {
+			return &apiClient{
+				creds:    c,
+				http:     httpClient,
+				tokenURL: defaultTokenURL,
+				apiURL:   defaultAPIURL,
+			}
+		},
+	}
+}
+
+// Credentials returns the exporter's credential store
+func (e *Exporter) Credentials() *CredentialStore {
+	return e.creds
+}
+
+// WriteCSV replaces the contents of a tab with the rows of a CSV export and
+// returns the number of rows written, including the header
+func (e *Exporter) WriteCSV(ctx context.Context, locationID uuid.UUID, spreadsheetID, tab string, data []byte) (int, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("read export: %w", err)
+	}
+
+	creds, err := e.getCreds(ctx, locationID)
+	if err != nil {
+		return 0, err
+	}
+	if err := e.newClient(*creds).ReplaceValues(ctx, spreadsheetID, tab, rows); err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// apiClient is a minimal Sheets REST client authorised by a refresh token
+type apiClient struct {
+	creds    Credentials
+	http     *http.Client
+	tokenURL string
+	apiURL   string
+}
+
+// ReplaceValues clears the tab, then writes rows as if typed by a user so
+// numbers and dates are parsed rather than stored as text
+func (c *apiClient) ReplaceValues(ctx context.Context, spreadsheetID, tab string, rows [][]string) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Quote the tab name so names with spaces or punctuation address the whole sheet
+	a1 := "'" + strings.ReplaceAll(tab, "'", "''") + "'"
+	base := c.apiURL + "/" + url.PathEscape(spreadsheetID) + "/values/" + url.PathEscape(a1)
+
+	if err := c.call(ctx, http.MethodPost, base+":clear", token, struct{}{}); err != nil {
+		return fmt.Errorf("clear sheet: %w", err)
+	}
+
+	body := struct {
+		Range          string     `json:"range"`
+		MajorDimension string     `json:"majorDimension"`
+		Values         [][]string `json:"values"`
+	}{a1, "ROWS", rows}
+	if err := c.call(ctx, http.MethodPut, base+"?valueInputOption=USER_ENTERED", token, body); err != nil {
+		return fmt.Errorf("write sheet: %w", err)
+	}
+	return nil
+}
+
+// accessToken exchanges the refresh token for a short-lived access token
+func (c *apiClient) accessToken(ctx context.Context) (string, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {c.creds.ClientID},
+		"client_secret": {c.creds.ClientSecret},
+		"refresh_token": {c.creds.RefreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token response had no access_token")
+	}
+	return token.AccessToken, nil
+}
+
+func (c *apiClient) call(ctx context.Context, method, endpoint, token string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
+
+// checkResponse reports a non-2xx response with the start of its body
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("google returned %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+}
@@ -0,0 +1,175 @@
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fakeClient records what an export writes instead of calling Google
+type fakeClient struct {
+	creds         Credentials
+	spreadsheetID string
+	tab           string
+	rows          [][]string
+	err           error
+}
+
+func (f *fakeClient) ReplaceValues(ctx context.Context, spreadsheetID, tab string, rows [][]string) error {
+	f.spreadsheetID, f.tab, f.rows = spreadsheetID, tab, rows
+	return f.err
+}
+
+// testExporter returns an exporter writing to client, with credentials only
+// for the location given
+func testExporter(locationID uuid.UUID, client *fakeClient) *Exporter {
+	return &Exporter{
+		getCreds: func(ctx context.Context, id uuid.UUID) (*Credentials, error) {
+			if id != locationID {
+				return nil, ErrNotConfigured
+			}
+			return &Credentials{ClientID: "client", ClientSecret: "secret", RefreshToken: "refresh"}, nil
+		},
+		newClient: func(c Credentials) Client {
+			client.creds = c
+			return client
+		},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	locationID := uuid.New()
+	client := &fakeClient{}
+	e := testExporter(locationID, client)
+
+	data := []byte("category,line_item,amount\n" +
+		"revenue,Dine In,1250.50\n" +
+		"revenue,\"Delivery, net of fees\",430.25\n" +
+		"total\n")
+
+	n, err := e.WriteCSV(context.Background(), locationID, "sheet-123", "pnl", data)
+	if err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	if n != 4 {
+		t.Errorf("WriteCSV() = %d rows, want 4", n)
+	}
+
+	want := [][]string{
+		{"category", "line_item", "amount"},
+		{"revenue", "Dine In", "1250.50"},
+		{"revenue", "Delivery, net of fees", "430.25"},
+		{"total"},
+	}
+	if !reflect.DeepEqual(client.rows, want) {
+		t.Errorf("rows written = %q, want %q", client.rows, want)
+	}
+	if client.spreadsheetID != "sheet-123" || client.tab != "pnl" {
+		t.Errorf("wrote to %s/%s, want sheet-123/pnl", client.spreadsheetID, client.tab)
+	}
+	if client.creds.RefreshToken != "refresh" {
+		t.Errorf("client credentials = %+v, want the location's", client.creds)
+	}
+}
+
+func TestWriteCSVErrors(t *testing.T) {
+	locationID := uuid.New()
+
+	client := &fakeClient{}
+	if _, err := testExporter(locationID, client).WriteCSV(context.Background(), uuid.New(), "sheet-123", "pnl", []byte("a,b\n")); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("WriteCSV() for another location error = %v, want ErrNotConfigured", err)
+	}
+	if client.rows != nil {
+		t.Errorf("rows written = %q, want none", client.rows)
+	}
+
+	client = &fakeClient{err: errors.New("quota exceeded")}
+	if n, err := testExporter(locationID, client).WriteCSV(context.Background(), locationID, "sheet-123", "pnl", []byte("a,b\n")); err == nil || n != 0 {
+		t.Errorf("WriteCSV() = %d, %v, want the client's error", n, err)
+	}
+
+	if _, err := testExporter(locationID, &fakeClient{}).WriteCSV(context.Background(), locationID, "sheet-123", "pnl", []byte("a,\"b\n")); err == nil || !strings.Contains(err.Error(), "read export") {
+		t.Errorf("WriteCSV() of malformed CSV error = %v, want a read error", err)
+	}
+}
+
+func TestAPIClientReplaceValues(t *testing.T) {
+	var calls []string
+	var written struct {
+		Range          string     `json:"range"`
+		MajorDimension string     `json:"majorDimension"`
+		Values         [][]string `json:"values"`
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			r.ParseForm()
+			if r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != "refresh" || r.PostForm.Get("client_secret") != "secret" {
+				t.Errorf("token form = %v, want a refresh token grant", r.PostForm)
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "access"})
+			return
+		}
+
+		if got := r.Header.Get("Authorization"); got != "Bearer access" {
+			t.Errorf("Authorization = %q, want the access token", got)
+		}
+		calls = append(calls, r.Method+" "+r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &written); err != nil {
+				t.Errorf("decode values: %v", err)
+			}
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	c := &apiClient{
+		creds:    Credentials{ClientID: "client", ClientSecret: "secret", RefreshToken: "refresh"},
+		http:     srv.Client(),
+		tokenURL: srv.URL + "/token",
+		apiURL:   srv.URL + "/v4/spreadsheets",
+	}
+	rows := [][]string{{"category", "amount"}, {"revenue", "1250.50"}}
+	if err := c.ReplaceValues(context.Background(), "sheet-123", "FY'24 P&L", rows); err != nil {
+		t.Fatalf("ReplaceValues() error = %v", err)
+	}
+
+	// The tab is quoted, with its own quote doubled, then path escaped
+	wantCalls := []string{
+		"POST /v4/spreadsheets/sheet-123/values/%27FY%27%2724%20P&L%27:clear?",
+		"PUT /v4/spreadsheets/sheet-123/values/%27FY%27%2724%20P&L%27?valueInputOption=USER_ENTERED",
+	}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("calls = %q, want %q", calls, wantCalls)
+	}
+	if written.Range != "'FY''24 P&L'" || written.MajorDimension != "ROWS" || !reflect.DeepEqual(written.Values, rows) {
+		t.Errorf("values written = %+v, want %q to 'FY''24 P&L'", written, rows)
+	}
+}
+
+func TestAPIClientReportsGoogleErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "access"})
+			return
+		}
+		http.Error(w, `{"error":{"message":"The caller does not have permission"}}`, http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c := &apiClient{http: srv.Client(), tokenURL: srv.URL + "/token", apiURL: srv.URL}
+	err := c.ReplaceValues(context.Background(), "sheet-123", "pnl", [][]string{{"a"}})
+	if err == nil || !strings.Contains(err.Error(), "clear sheet") || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "does not have permission") {
+		t.Errorf("ReplaceValues() error = %v, want the clear call's 403 and message", err)
+	}
+}
@@ -5,8 +5,8 @@ SELECT * FROM users WHERE email = $1 LIMIT 1;
 SELECT * FROM users WHERE id = $1 LIMIT 1;
 
 -- name: CreateUser :one
-INSERT INTO users (email, password_hash, role)
-VALUES ($1, $2, $3)
+INSERT INTO users (email, password_hash, role, location_id)
+VALUES ($1, $2, $3, $4)
 RETURNING *;
 
 -- name: GetLocation :one
@@ -144,8 +144,8 @@ FROM kpi_aggregates
 WHERE date >= $1 AND date <= $2;
 
 -- name: CreateExportJob :one
-INSERT INTO export_jobs (export_type, period_start, period_end, requested_by, status)
-VALUES ($1, $2, $3, $4, 'pending')
+INSERT INTO export_jobs (export_type, location_id, period_start, period_end, requested_by, status)
+VALUES ($1, $2, $3, $4, $5, 'pending')
 RETURNING *;
 
 -- name: GetExportJob :one
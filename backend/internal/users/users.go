@@ -0,0 +1,155 @@
+// Package users manages user accounts for owner admins
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+)
+
+// ErrNotFound is returned when a user does not exist
+var ErrNotFound = errors.New("user not found")
+
+// ErrEmailTaken is returned when another user already has the email
+var ErrEmailTaken = errors.New("a user with this email already exists")
+
+// ErrUnknownLocation is returned when a user is assigned a location that does not exist
+var ErrUnknownLocation = errors.New("location does not exist")
+
+// User is a user account; the password hash is never returned
+type User struct {
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	Role          auth.Role  `json:"role"`
+	LocationID    *uuid.UUID `json:"location_id"`
+	Active        bool       `json:"active"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	LastLogin     *time.Time `json:"last_login,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Store handles user persistence
+type Store struct {
+	db *pgxpool.Pool
+}
+
+// NewStore creates a new user store
+func NewStore(db *pgxpool.Pool) *Store {
+	return &Store{db: db}
+}
+
+const userColumns = `id, email, role, location_id, deactivated_at, last_login, created_at, updated_at`
+
+func scanUser(row pgx.Row) (*User, error) {
+	var u User
+	var role string
+	err := row.Scan(&u.ID, &u.Email, &role, &u.LocationID, &u.DeactivatedAt, &u.LastLogin, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	u.Role = auth.Role(role)
+	u.Active = u.DeactivatedAt == nil
+	return &u, nil
+}
+
+// List returns users ordered by email, optionally only those at a location
+// and only active ones
+func (s *Store) List(ctx context.Context, locationID *uuid.UUID, includeInactive bool) ([]User, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE ($1::uuid IS NULL OR location_id = $1)
+		AND ($2 OR deactivated_at IS NULL)
+		ORDER BY email
+	`, locationID, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *u)
+	}
+	return list, rows.Err()
+}
+
+// Get returns a user or ErrNotFound
+func (s *Store) Get(ctx context.Context, id uuid.UUID) (*User, error) {
+	u, err := scanUser(s.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return u, err
+}
+
+// Create inserts a user with an already hashed password
+func (s *Store) Create(ctx context.Context, u *User, passwordHash string) error {
+	u.ID = uuid.New()
+	u.Active = true
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO users (id, email, password_hash, role, location_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`, u.ID, u.Email, passwordHash, string(u.Role), u.LocationID).Scan(&u.CreatedAt, &u.UpdatedAt)
+	return constraintError(err)
+}
+
+// Update saves a user's role and location, and their password when
+// passwordHash is not empty
+func (s *Store) Update(ctx context.Context, u *User, passwordHash string) error {
+	err := s.db.QueryRow(ctx, `
+		UPDATE users SET
+			role = $2,
+			location_id = $3,
+			password_hash = COALESCE(NULLIF($4, ''), password_hash),
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, u.ID, string(u.Role), u.LocationID, passwordHash).Scan(&u.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return constraintError(err)
+}
+
+// SetActive deactivates or reactivates a user. Deactivating an already
+// inactive user keeps the original deactivation time.
+func (s *Store) SetActive(ctx context.Context, id uuid.UUID, active bool) (*User, error) {
+	u, err := scanUser(s.db.QueryRow(ctx, `
+		UPDATE users SET
+			deactivated_at = CASE WHEN $2 THEN NULL ELSE COALESCE(deactivated_at, NOW()) END,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+userColumns, id, active))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return u, err
+}
+
+// constraintError maps unique and foreign key violations to store errors
+func constraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return ErrEmailTaken
+		case "23503":
+			return ErrUnknownLocation
+		}
+	}
+	return err
+}
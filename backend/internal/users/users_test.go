@@ -0,0 +1,129 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/lakehouse/restaurant-finance/internal/auth"
+)
+
+// testPool connects to TEST_DATABASE_URL, skipping the test when it is unset
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// testLocation creates a location and removes it, and any users assigned to
+// it, when the test ends
+func testLocation(t *testing.T, pool *pgxpool.Pool) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	var locationID uuid.UUID
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO locations (name) VALUES ('User management test') RETURNING id
+	`).Scan(&locationID); err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM users WHERE location_id = $1`,
+			`DELETE FROM locations WHERE id = $1`,
+		} {
+			if _, err := pool.Exec(ctx, q, locationID); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+	})
+	return locationID
+}
+
+func TestStoreLifecycle(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	s := NewStore(pool)
+	locationID, otherLocation := testLocation(t, pool), testLocation(t, pool)
+	email := "manager-" + uuid.NewString()[:8] + "@example.com"
+
+	u := &User{Email: email, Role: auth.RoleManager, LocationID: &locationID}
+	if err := s.Create(ctx, u, "hash-1"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !u.Active || u.ID == uuid.Nil {
+		t.Errorf("created user = %+v, want an active user with an ID", u)
+	}
+
+	dup := &User{Email: email, Role: auth.RoleViewer, LocationID: &locationID}
+	if err := s.Create(ctx, dup, "hash-2"); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("Create() with a taken email error = %v, want ErrEmailTaken", err)
+	}
+	missing := uuid.New()
+	stray := &User{Email: "stray-" + uuid.NewString()[:8] + "@example.com", Role: auth.RoleViewer, LocationID: &missing}
+	if err := s.Create(ctx, stray, "hash-3"); !errors.Is(err, ErrUnknownLocation) {
+		t.Errorf("Create() at an unknown location error = %v, want ErrUnknownLocation", err)
+	}
+
+	// An empty hash keeps the password
+	u.Role, u.LocationID = auth.RoleAccountant, &otherLocation
+	if err := s.Update(ctx, u, ""); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	var hash string
+	if err := pool.QueryRow(ctx, `SELECT password_hash FROM users WHERE id = $1`, u.ID).Scan(&hash); err != nil {
+		t.Fatal(err)
+	}
+	if hash != "hash-1" {
+		t.Errorf("password hash = %q after an update without one, want it kept", hash)
+	}
+	got, err := s.Get(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Role != auth.RoleAccountant || *got.LocationID != otherLocation {
+		t.Errorf("updated user = %+v, want an accountant at the other location", got)
+	}
+
+	// Deactivating keeps the row, hides it from the default list, and a
+	// second deactivation keeps the original time
+	first, err := s.SetActive(ctx, u.ID, false)
+	if err != nil {
+		t.Fatalf("SetActive(false) error = %v", err)
+	}
+	again, err := s.SetActive(ctx, u.ID, false)
+	if err != nil {
+		t.Fatalf("SetActive(false) error = %v", err)
+	}
+	if first.Active || first.DeactivatedAt == nil || !again.DeactivatedAt.Equal(*first.DeactivatedAt) {
+		t.Errorf("deactivated at %v then %v, want one unchanged time", first.DeactivatedAt, again.DeactivatedAt)
+	}
+	if list, err := s.List(ctx, &otherLocation, false); err != nil || len(list) != 0 {
+		t.Errorf("List() of active users = %+v, %v, want none", list, err)
+	}
+	if list, err := s.List(ctx, &otherLocation, true); err != nil || len(list) != 1 || list[0].ID != u.ID {
+		t.Errorf("List() including inactive = %+v, %v, want the deactivated user", list, err)
+	}
+
+	if back, err := s.SetActive(ctx, u.ID, true); err != nil || !back.Active || back.DeactivatedAt != nil {
+		t.Errorf("SetActive(true) = %+v, %v, want the user active again", back, err)
+	}
+
+	if _, err := s.Get(ctx, uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of an unknown user error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.SetActive(ctx, uuid.New(), false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetActive() of an unknown user error = %v, want ErrNotFound", err)
+	}
+}
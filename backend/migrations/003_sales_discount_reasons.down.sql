@@ -0,0 +1,3 @@
+-- 003_sales_discount_reasons.down.sql
+ALTER TABLE sales DROP COLUMN IF EXISTS comp_reason;
+ALTER TABLE sales DROP COLUMN IF EXISTS discount_reason;
@@ -0,0 +1,5 @@
+-- 003_sales_discount_reasons.up.sql
+-- Reason codes explaining why discounts and comps were given
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS discount_reason VARCHAR(100);
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS comp_reason VARCHAR(100);
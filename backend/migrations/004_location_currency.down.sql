@@ -0,0 +1,2 @@
+-- 004_location_currency.down.sql
+ALTER TABLE locations DROP COLUMN IF EXISTS currency;
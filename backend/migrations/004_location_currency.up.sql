@@ -0,0 +1,4 @@
+-- 004_location_currency.up.sql
+-- ISO 4217 currency used when formatting amounts for a location
+
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'AUD';
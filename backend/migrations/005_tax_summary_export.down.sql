@@ -0,0 +1,3 @@
+-- 005_tax_summary_export.down.sql
+-- Postgres cannot drop a single enum value; remove any rows using it instead
+DELETE FROM export_jobs WHERE export_type::text = 'tax_summary';
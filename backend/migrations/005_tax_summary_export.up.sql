@@ -0,0 +1,4 @@
+-- 005_tax_summary_export.up.sql
+-- Tax summary export type for GST/VAT filing
+
+ALTER TYPE export_type ADD VALUE IF NOT EXISTS 'tax_summary';
@@ -0,0 +1,2 @@
+-- 006_mapping_encoding.down.sql
+ALTER TABLE mapping_profiles DROP COLUMN IF EXISTS encoding;
@@ -0,0 +1,4 @@
+-- 006_mapping_encoding.up.sql
+-- Explicit character encoding for files imported with a mapping profile
+
+ALTER TABLE mapping_profiles ADD COLUMN IF NOT EXISTS encoding VARCHAR(20) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- 007_closed_days.down.sql
+ALTER TABLE kpi_aggregates DROP COLUMN IF EXISTS is_closed;
+DROP TABLE IF EXISTS closed_days;
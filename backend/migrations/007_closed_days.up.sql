@@ -0,0 +1,15 @@
+-- 007_closed_days.up.sql
+-- Days a location was closed (holidays, renovations), excluded from averages
+
+CREATE TABLE closed_days (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    date DATE NOT NULL,
+    reason VARCHAR(255),
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (location_id, date)
+);
+CREATE INDEX idx_closed_days_date ON closed_days(date);
+
+ALTER TABLE kpi_aggregates ADD COLUMN IF NOT EXISTS is_closed BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- 008_purchases.down.sql
+-- The 'purchases' source_type enum value cannot be dropped and is left in place
+DROP TABLE IF EXISTS purchases;
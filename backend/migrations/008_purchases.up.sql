@@ -0,0 +1,23 @@
+-- 008_purchases.up.sql
+-- Supplier purchases/invoices used for movement-based COGS and supplier spend
+
+ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'purchases';
+
+CREATE TABLE purchases (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    purchase_date DATE NOT NULL,
+    supplier VARCHAR(255) NOT NULL,
+    item_name VARCHAR(255) NOT NULL,
+    category VARCHAR(100),
+    quantity DECIMAL(12, 3) NOT NULL DEFAULT 0,
+    unit_cost DECIMAL(12, 4) NOT NULL DEFAULT 0,
+    total DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    import_source VARCHAR(50),
+    source_id VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (location_id, import_source, source_id)
+);
+CREATE INDEX idx_purchases_date ON purchases(purchase_date);
+CREATE INDEX idx_purchases_supplier ON purchases(supplier);
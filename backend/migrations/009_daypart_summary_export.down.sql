@@ -0,0 +1,3 @@
+-- 009_daypart_summary_export.down.sql
+-- Postgres cannot drop a single enum value; remove any rows using it instead
+DELETE FROM export_jobs WHERE export_type::text = 'daypart_summary';
@@ -0,0 +1,4 @@
+-- 009_daypart_summary_export.up.sql
+-- Daypart summary export type
+
+ALTER TYPE export_type ADD VALUE IF NOT EXISTS 'daypart_summary';
@@ -0,0 +1,3 @@
+-- 010_sales_order_type.down.sql
+DROP INDEX IF EXISTS idx_sales_order_type;
+ALTER TABLE sales DROP COLUMN IF EXISTS order_type;
@@ -0,0 +1,6 @@
+-- 010_sales_order_type.up.sql
+-- Fulfillment type (dine_in, takeaway, delivery) independent of sales channel
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS order_type VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_type ON sales(location_id, order_type);
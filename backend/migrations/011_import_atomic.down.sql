@@ -0,0 +1,2 @@
+-- 011_import_atomic.down.sql
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS atomic;
@@ -0,0 +1,4 @@
+-- 011_import_atomic.up.sql
+-- All-or-nothing imports roll back every row when any row fails
+
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS atomic BOOLEAN NOT NULL DEFAULT false;
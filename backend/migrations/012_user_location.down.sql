@@ -0,0 +1,3 @@
+-- 012_user_location.down.sql
+DROP INDEX IF EXISTS idx_users_location;
+ALTER TABLE users DROP COLUMN IF EXISTS location_id;
@@ -0,0 +1,12 @@
+-- 012_user_location.up.sql
+-- Each user belongs to one location; login issues tokens scoped to it
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS location_id UUID REFERENCES locations(id);
+
+-- Single-venue deployments: assign existing users to the only location
+UPDATE users
+SET location_id = (SELECT id FROM locations LIMIT 1)
+WHERE location_id IS NULL
+AND (SELECT COUNT(*) FROM locations) = 1;
+
+CREATE INDEX IF NOT EXISTS idx_users_location ON users(location_id);
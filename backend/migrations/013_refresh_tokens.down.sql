@@ -0,0 +1,2 @@
+-- 013_refresh_tokens.down.sql
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,2 @@
+-- 014_gift_card_ledger.down.sql
+DROP TABLE IF EXISTS gift_card_ledger;
@@ -0,0 +1,18 @@
+-- 014_gift_card_ledger.up.sql
+-- Gift card issuance and redemption. Issuance is a liability, not revenue;
+-- redemptions reduce the liability while the redeeming sale counts as revenue.
+
+CREATE TABLE gift_card_ledger (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    occurred_at TIMESTAMPTZ NOT NULL,
+    entry_type VARCHAR(20) NOT NULL CHECK (entry_type IN ('issue', 'redeem')),
+    amount DECIMAL(12,2) NOT NULL CHECK (amount >= 0),
+    card_number VARCHAR(100),
+    import_source VARCHAR(50) NOT NULL,
+    source_id VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (location_id, import_source, source_id)
+);
+CREATE INDEX idx_gift_card_ledger_date ON gift_card_ledger(location_id, occurred_at);
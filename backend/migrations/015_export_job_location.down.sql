@@ -0,0 +1,3 @@
+-- 015_export_job_location.down.sql
+DROP INDEX IF EXISTS idx_export_jobs_location;
+ALTER TABLE export_jobs DROP COLUMN IF EXISTS location_id;
@@ -0,0 +1,12 @@
+-- 015_export_job_location.up.sql
+-- Scope export jobs to the location they were generated for
+
+ALTER TABLE export_jobs ADD COLUMN IF NOT EXISTS location_id UUID REFERENCES locations(id);
+
+-- Single-venue deployments: attribute existing jobs to the only location
+UPDATE export_jobs
+SET location_id = (SELECT id FROM locations LIMIT 1)
+WHERE location_id IS NULL
+AND (SELECT COUNT(*) FROM locations) = 1;
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_location ON export_jobs(location_id, requested_at DESC);
@@ -0,0 +1,3 @@
+-- 017_hourly_aggregates.down.sql
+DROP TABLE IF EXISTS kpi_hourly_aggregates;
+ALTER TABLE locations DROP COLUMN IF EXISTS hourly_aggregates;
@@ -0,0 +1,17 @@
+-- 017_hourly_aggregates.up.sql
+-- Optional hourly sales rollups for intraday staffing, enabled per location
+
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS hourly_aggregates BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS kpi_hourly_aggregates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    date DATE NOT NULL,
+    hour SMALLINT NOT NULL CHECK (hour BETWEEN 0 AND 23),
+    revenue DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    covers INT NOT NULL DEFAULT 0,
+    discounts DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    comps DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    freshness_timestamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (location_id, date, hour)
+);
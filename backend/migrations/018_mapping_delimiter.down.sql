@@ -0,0 +1,2 @@
+-- 018_mapping_delimiter.down.sql
+ALTER TABLE mapping_profiles DROP COLUMN IF EXISTS delimiter;
@@ -0,0 +1,4 @@
+-- 018_mapping_delimiter.up.sql
+-- Explicit field delimiter for files imported with a mapping profile
+
+ALTER TABLE mapping_profiles ADD COLUMN IF NOT EXISTS delimiter VARCHAR(4) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- 019_kpi_snapshots.down.sql
+DROP TABLE IF EXISTS kpi_snapshots;
@@ -0,0 +1,15 @@
+-- 019_kpi_snapshots.up.sql
+-- Frozen period totals (e.g. month-end close) to detect later drift
+
+CREATE TABLE IF NOT EXISTS kpi_snapshots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    label VARCHAR(255) NOT NULL DEFAULT '',
+    totals JSONB NOT NULL,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_kpi_snapshots_location ON kpi_snapshots(location_id, period_start);
@@ -0,0 +1,2 @@
+-- 020_import_file_path.down.sql
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS file_path;
@@ -0,0 +1,4 @@
+-- 020_import_file_path.up.sql
+-- Keep the stored upload for each import so failed jobs can be retried
+
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS file_path VARCHAR(500) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- 021_sales_server.down.sql
+DROP INDEX IF EXISTS idx_sales_server;
+ALTER TABLE sales DROP COLUMN IF EXISTS server_name;
@@ -0,0 +1,6 @@
+-- 021_sales_server.up.sql
+-- Server/staff member who rang up the sale, as named in the POS export
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS server_name VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_sales_server ON sales(location_id, server_name);
@@ -0,0 +1,3 @@
+-- 022_operating_expenses.down.sql
+-- The 'expenses' source_type enum value cannot be dropped and is left in place
+DROP TABLE IF EXISTS operating_expenses;
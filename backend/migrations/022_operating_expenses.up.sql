@@ -0,0 +1,19 @@
+-- 022_operating_expenses.up.sql
+-- Operating expenses (rent, utilities, etc.) rolled into kpi_aggregates.opex
+
+ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'expenses';
+
+CREATE TABLE operating_expenses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    expense_date DATE NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    description TEXT,
+    amount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    import_source VARCHAR(50),
+    source_id VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (location_id, import_source, source_id)
+);
+CREATE INDEX idx_operating_expenses_date ON operating_expenses(location_id, expense_date);
@@ -0,0 +1,3 @@
+-- 023_import_date_range.down.sql
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS data_end;
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS data_start;
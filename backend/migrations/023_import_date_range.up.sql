@@ -0,0 +1,5 @@
+-- 023_import_date_range.up.sql
+-- First and last business day touched by an import, used to scope aggregate refreshes
+
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS data_start DATE;
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS data_end DATE;
@@ -0,0 +1,6 @@
+-- 024_refunds.down.sql
+-- The 'refunds' source_type enum value cannot be dropped and is left in place
+ALTER TABLE kpi_aggregates DROP COLUMN IF EXISTS refunds;
+DROP TABLE IF EXISTS refunds;
+DROP INDEX IF EXISTS idx_sales_external_id;
+ALTER TABLE sales DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,26 @@
+-- 024_refunds.up.sql
+-- Refund/chargeback reports from payment processors, netted against revenue
+
+ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'refunds';
+
+-- POS order/transaction reference that refunds are matched on
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS external_id VARCHAR(100);
+CREATE INDEX IF NOT EXISTS idx_sales_external_id ON sales(location_id, external_id);
+
+CREATE TABLE refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    refund_date DATE NOT NULL,
+    external_id VARCHAR(100),
+    sale_id UUID REFERENCES sales(id) ON DELETE SET NULL, -- NULL when no sale matched
+    amount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    reason VARCHAR(255),
+    import_source VARCHAR(50),
+    source_id VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (location_id, import_source, source_id)
+);
+CREATE INDEX idx_refunds_date ON refunds(location_id, refund_date);
+
+ALTER TABLE kpi_aggregates ADD COLUMN IF NOT EXISTS refunds DECIMAL(12, 2) NOT NULL DEFAULT 0;
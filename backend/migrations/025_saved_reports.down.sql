@@ -0,0 +1,2 @@
+-- 025_saved_reports.down.sql
+DROP TABLE IF EXISTS saved_reports;
@@ -0,0 +1,19 @@
+-- 025_saved_reports.up.sql
+-- Named export configurations that can be re-run on demand
+
+CREATE TABLE IF NOT EXISTS saved_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    name VARCHAR(255) NOT NULL,
+    export_type VARCHAR(50) NOT NULL,
+    range_spec VARCHAR(50) NOT NULL DEFAULT '30d',
+    start_date DATE,
+    end_date DATE,
+    group_by VARCHAR(20) NOT NULL DEFAULT '',
+    summary_only BOOLEAN NOT NULL DEFAULT FALSE,
+    format VARCHAR(10) NOT NULL DEFAULT 'csv',
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (location_id, name)
+);
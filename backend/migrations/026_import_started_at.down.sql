@@ -0,0 +1,3 @@
+-- 026_import_started_at.down.sql
+-- started_at is part of the initial schema, so only the index is removed
+DROP INDEX IF EXISTS idx_import_jobs_processing;
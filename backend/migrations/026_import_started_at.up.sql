@@ -0,0 +1,5 @@
+-- 026_import_started_at.up.sql
+-- Record when an import began processing so jobs stuck after a crash can be found
+
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS started_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_import_jobs_processing ON import_jobs(started_at) WHERE status = 'processing';
@@ -0,0 +1,3 @@
+-- 027_import_cancelled.down.sql
+-- The 'cancelled' import_status enum value cannot be dropped and is left in place
+UPDATE import_jobs SET status = 'failed' WHERE status = 'cancelled';
@@ -0,0 +1,4 @@
+-- 027_import_cancelled.up.sql
+-- Imports stopped by the user before they completed
+
+ALTER TYPE import_status ADD VALUE IF NOT EXISTS 'cancelled';
@@ -0,0 +1,3 @@
+-- 028_budgets.down.sql
+-- The 'budget' source_type enum value cannot be dropped and is left in place
+DROP TABLE IF EXISTS budgets;
@@ -0,0 +1,19 @@
+-- 028_budgets.up.sql
+-- Monthly budget targets per location, loaded by the budget import
+
+ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'budget';
+
+CREATE TABLE IF NOT EXISTS budgets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    month DATE NOT NULL, -- first day of the budgeted month
+    revenue_target DECIMAL(12, 2),
+    cogs_target DECIMAL(12, 2),
+    labor_target DECIMAL(12, 2),
+    opex_target DECIMAL(12, 2),
+    import_source VARCHAR(50),
+    source_id VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (location_id, month)
+);
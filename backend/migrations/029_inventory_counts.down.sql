@@ -0,0 +1,2 @@
+-- 029_inventory_counts.down.sql
+DROP TABLE IF EXISTS inventory_counts;
@@ -0,0 +1,21 @@
+-- 029_inventory_counts.up.sql
+-- Every inventory count kept for audit when imports run in append mode;
+-- inventory_snapshots keeps the latest count per item and day for valuation
+
+CREATE TABLE IF NOT EXISTS inventory_counts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    snapshot_date DATE NOT NULL,
+    item_name VARCHAR(255) NOT NULL,
+    category VARCHAR(100),
+    quantity DECIMAL(12, 3) NOT NULL DEFAULT 0,
+    unit VARCHAR(20),
+    unit_cost DECIMAL(12, 4) NOT NULL DEFAULT 0,
+    total_value DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    import_job_id UUID REFERENCES import_jobs(id) ON DELETE SET NULL,
+    import_source VARCHAR(50),
+    source_id VARCHAR(100),
+    counted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (location_id, import_source, source_id)
+);
+CREATE INDEX IF NOT EXISTS idx_inventory_counts_item ON inventory_counts(location_id, snapshot_date, item_name);
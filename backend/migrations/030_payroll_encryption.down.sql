@@ -0,0 +1,4 @@
+-- 030_payroll_encryption.down.sql
+-- Encrypted values cannot be restored without the key, so they are dropped
+ALTER TABLE payroll_periods DROP COLUMN IF EXISTS superannuation_encrypted;
+ALTER TABLE payroll_periods DROP COLUMN IF EXISTS tax_withheld_encrypted;
@@ -0,0 +1,11 @@
+-- 030_payroll_encryption.up.sql
+-- Ciphertext for payroll amounts when FIELD_ENCRYPTION_KEY is set; the plain
+-- columns are left NULL for encrypted rows
+
+ALTER TABLE payroll_periods
+    ADD COLUMN IF NOT EXISTS superannuation DECIMAL(10, 2),
+    ADD COLUMN IF NOT EXISTS tax_withheld DECIMAL(10, 2),
+    ADD COLUMN IF NOT EXISTS superannuation_encrypted TEXT,
+    ADD COLUMN IF NOT EXISTS tax_withheld_encrypted TEXT;
+ALTER TABLE payroll_periods ALTER COLUMN superannuation DROP NOT NULL;
+ALTER TABLE payroll_periods ALTER COLUMN tax_withheld DROP NOT NULL;
@@ -0,0 +1,3 @@
+-- 031_import_interrupted.down.sql
+-- The 'interrupted' import_status enum value cannot be dropped and is left in place
+UPDATE import_jobs SET status = 'failed' WHERE status = 'interrupted';
@@ -0,0 +1,4 @@
+-- 031_import_interrupted.up.sql
+-- Imports stopped by a server shutdown before they completed; they can be retried
+
+ALTER TYPE import_status ADD VALUE IF NOT EXISTS 'interrupted';
@@ -0,0 +1,2 @@
+-- 032_kpi_daily_rollup.down.sql
+DROP MATERIALIZED VIEW IF EXISTS kpi_daily_rollup;
@@ -0,0 +1,3 @@
+-- 033_deposits.down.sql
+-- The 'deposits' source_type enum value cannot be dropped and is left in place
+DROP TABLE IF EXISTS deposits;
@@ -0,0 +1,18 @@
+-- 033_deposits.up.sql
+-- Bank deposits, reconciled against POS cash sales to catch shortfalls
+
+ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'deposits';
+
+CREATE TABLE IF NOT EXISTS deposits (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    deposit_date DATE NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    reference VARCHAR(100),
+    import_source VARCHAR(50),
+    source_id VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (location_id, import_source, source_id)
+);
+CREATE INDEX IF NOT EXISTS idx_deposits_date ON deposits(location_id, deposit_date);
@@ -0,0 +1,3 @@
+-- 034_location_fiscal_year.down.sql
+ALTER TABLE locations DROP COLUMN IF EXISTS fiscal_year_start_day;
+ALTER TABLE locations DROP COLUMN IF EXISTS fiscal_year_start_month;
@@ -0,0 +1,10 @@
+-- 034_location_fiscal_year.up.sql
+-- Month and day a location's fiscal year starts on; ytd ranges count from it.
+-- Defaults to a calendar year (1 January). Days past the end of a short month
+-- fall on its last day.
+
+ALTER TABLE locations
+    ADD COLUMN IF NOT EXISTS fiscal_year_start_month SMALLINT NOT NULL DEFAULT 1
+        CHECK (fiscal_year_start_month BETWEEN 1 AND 12),
+    ADD COLUMN IF NOT EXISTS fiscal_year_start_day SMALLINT NOT NULL DEFAULT 1
+        CHECK (fiscal_year_start_day BETWEEN 1 AND 31);
@@ -0,0 +1,2 @@
+-- 035_google_sheets_credentials.down.sql
+DROP TABLE IF EXISTS google_sheets_credentials;
@@ -0,0 +1,12 @@
+-- 035_google_sheets_credentials.up.sql
+-- Per-location OAuth credentials used to push exports into Google Sheets.
+-- client_secret and refresh_token are encrypted when FIELD_ENCRYPTION_KEY is set.
+
+CREATE TABLE IF NOT EXISTS google_sheets_credentials (
+    location_id UUID PRIMARY KEY REFERENCES locations(id) ON DELETE CASCADE,
+    client_id TEXT NOT NULL,
+    client_secret TEXT NOT NULL,
+    refresh_token TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
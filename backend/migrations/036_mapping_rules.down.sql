@@ -0,0 +1,2 @@
+-- 036_mapping_rules.down.sql
+ALTER TABLE mapping_profiles DROP COLUMN IF EXISTS rules;
@@ -0,0 +1,4 @@
+-- 036_mapping_rules.up.sql
+-- Named row validation rules enabled for files imported with a mapping profile
+
+ALTER TABLE mapping_profiles ADD COLUMN IF NOT EXISTS rules TEXT[] NOT NULL DEFAULT '{}';
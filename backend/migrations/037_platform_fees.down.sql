@@ -0,0 +1,37 @@
+-- 037_platform_fees.down.sql
+DROP MATERIALIZED VIEW IF EXISTS kpi_daily_rollup;
+
+CREATE MATERIALIZED VIEW kpi_daily_rollup AS
+SELECT
+    date,
+    location_id,
+    channel_id,
+    daypart_id,
+    CASE
+        WHEN GROUPING(channel_id) = 0 THEN 'channel'
+        WHEN GROUPING(daypart_id) = 0 THEN 'daypart'
+        ELSE 'day'
+    END AS grain,
+    COALESCE(channel_id, daypart_id, '00000000-0000-0000-0000-000000000000'::uuid) AS dimension_id,
+    SUM(revenue) AS revenue,
+    SUM(cogs) AS cogs,
+    SUM(gross_margin) AS gross_margin,
+    SUM(labor_cost) AS labor_cost,
+    SUM(opex) AS opex,
+    SUM(net_profit) AS net_profit,
+    SUM(covers) AS covers,
+    SUM(discounts) AS discounts,
+    SUM(comps) AS comps,
+    BOOL_OR(is_closed) AS is_closed,
+    MAX(freshness_timestamp) AS freshness_timestamp
+FROM kpi_aggregates
+GROUP BY GROUPING SETS (
+    (date, location_id),
+    (date, location_id, channel_id),
+    (date, location_id, daypart_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_kpi_daily_rollup_key ON kpi_daily_rollup(location_id, date, grain, dimension_id);
+
+ALTER TABLE kpi_aggregates DROP COLUMN IF EXISTS commissions;
+ALTER TABLE sales DROP COLUMN IF EXISTS platform_fee;
@@ -0,0 +1,42 @@
+-- 037_platform_fees.up.sql
+-- Delivery platform commissions charged on a sale. They are netted out of
+-- gross margin by the aggregates refresh and reported per channel.
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS platform_fee DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE kpi_aggregates ADD COLUMN IF NOT EXISTS commissions DECIMAL(12, 2) NOT NULL DEFAULT 0;
+
+-- Rebuild the rollup so it carries commissions
+DROP MATERIALIZED VIEW IF EXISTS kpi_daily_rollup;
+
+CREATE MATERIALIZED VIEW kpi_daily_rollup AS
+SELECT
+    date,
+    location_id,
+    channel_id,
+    daypart_id,
+    CASE
+        WHEN GROUPING(channel_id) = 0 THEN 'channel'
+        WHEN GROUPING(daypart_id) = 0 THEN 'daypart'
+        ELSE 'day'
+    END AS grain,
+    COALESCE(channel_id, daypart_id, '00000000-0000-0000-0000-000000000000'::uuid) AS dimension_id,
+    SUM(revenue) AS revenue,
+    SUM(cogs) AS cogs,
+    SUM(gross_margin) AS gross_margin,
+    SUM(labor_cost) AS labor_cost,
+    SUM(opex) AS opex,
+    SUM(net_profit) AS net_profit,
+    SUM(covers) AS covers,
+    SUM(discounts) AS discounts,
+    SUM(comps) AS comps,
+    SUM(commissions) AS commissions,
+    BOOL_OR(is_closed) AS is_closed,
+    MAX(freshness_timestamp) AS freshness_timestamp
+FROM kpi_aggregates
+GROUP BY GROUPING SETS (
+    (date, location_id),
+    (date, location_id, channel_id),
+    (date, location_id, daypart_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_kpi_daily_rollup_key ON kpi_daily_rollup(location_id, date, grain, dimension_id);
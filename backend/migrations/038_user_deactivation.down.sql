@@ -0,0 +1,3 @@
+-- 038_user_deactivation.down.sql
+ALTER TABLE users DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
@@ -0,0 +1,6 @@
+-- 038_user_deactivation.up.sql
+-- Users are deactivated rather than deleted so audit and created_by references
+-- keep pointing at them; deactivated users cannot log in or refresh tokens
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
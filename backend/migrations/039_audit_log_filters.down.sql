@@ -0,0 +1,3 @@
+-- 039_audit_log_filters.down.sql
+DROP INDEX IF EXISTS idx_audit_log_created;
+DROP INDEX IF EXISTS idx_audit_log_action;
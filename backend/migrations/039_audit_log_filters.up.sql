@@ -0,0 +1,5 @@
+-- 039_audit_log_filters.up.sql
+-- Indexes for the owner audit log listing, which filters by action and date
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at DESC);
@@ -0,0 +1,2 @@
+-- 040_import_conflict_strategy.down.sql
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS conflict_strategy;
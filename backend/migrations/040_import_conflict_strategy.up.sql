@@ -0,0 +1,5 @@
+-- 040_import_conflict_strategy.up.sql
+-- How an import handles POS rows that were already imported: update, skip or reject
+
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS conflict_strategy VARCHAR(10) NOT NULL DEFAULT 'update'
+    CHECK (conflict_strategy IN ('update', 'skip', 'reject'));
@@ -0,0 +1,2 @@
+-- 041_mapping_number_format.down.sql
+ALTER TABLE mapping_profiles DROP COLUMN IF EXISTS number_format;
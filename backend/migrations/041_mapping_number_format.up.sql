@@ -0,0 +1,4 @@
+-- 041_mapping_number_format.up.sql
+-- Thousands and decimal separators a mapping profile's amounts are written with
+
+ALTER TABLE mapping_profiles ADD COLUMN IF NOT EXISTS number_format VARCHAR(10) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- 042_mapping_date_format.down.sql
+ALTER TABLE mapping_profiles DROP COLUMN IF EXISTS date_format;
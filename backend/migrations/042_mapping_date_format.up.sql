@@ -0,0 +1,4 @@
+-- 042_mapping_date_format.up.sql
+-- Day/month order for slash-separated dates in files imported with a mapping profile
+
+ALTER TABLE mapping_profiles ADD COLUMN IF NOT EXISTS date_format VARCHAR(10) NOT NULL DEFAULT '';
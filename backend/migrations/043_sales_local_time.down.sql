@@ -0,0 +1,10 @@
+-- 043_sales_local_time.down.sql
+UPDATE gift_card_ledger g
+SET occurred_at = (g.occurred_at AT TIME ZONE l.timezone) AT TIME ZONE 'UTC'
+FROM locations l
+WHERE l.id = g.location_id AND g.import_source = 'csv-import';
+
+UPDATE sales s
+SET occurred_at = (s.occurred_at AT TIME ZONE l.timezone) AT TIME ZONE 'UTC'
+FROM locations l
+WHERE l.id = s.location_id AND s.import_source = 'csv-import';
@@ -0,0 +1,14 @@
+-- 043_sales_local_time.up.sql
+-- Imports used to store the wall-clock time from the file as if it were UTC.
+-- Reinterpret those times in the location's timezone so occurred_at is the
+-- real instant; aggregates need a full refresh afterwards.
+
+UPDATE sales s
+SET occurred_at = (s.occurred_at AT TIME ZONE 'UTC') AT TIME ZONE l.timezone
+FROM locations l
+WHERE l.id = s.location_id AND s.import_source = 'csv-import';
+
+UPDATE gift_card_ledger g
+SET occurred_at = (g.occurred_at AT TIME ZONE 'UTC') AT TIME ZONE l.timezone
+FROM locations l
+WHERE l.id = g.location_id AND g.import_source = 'csv-import';
@@ -0,0 +1,3 @@
+-- 044_import_idempotency_key.down.sql
+DROP INDEX IF EXISTS idx_import_jobs_idempotency_key;
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,7 @@
+-- 044_import_idempotency_key.up.sql
+-- Client Idempotency-Key that created an import, so a retried request returns the same job
+
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_idempotency_key ON import_jobs(location_id, idempotency_key, created_at DESC)
+    WHERE idempotency_key IS NOT NULL;
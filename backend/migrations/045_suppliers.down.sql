@@ -0,0 +1,5 @@
+-- 045_suppliers.down.sql
+DROP INDEX IF EXISTS idx_purchases_invoice_line;
+ALTER TABLE purchases DROP COLUMN IF EXISTS invoice_number;
+ALTER TABLE purchases DROP COLUMN IF EXISTS supplier_id;
+DROP TABLE IF EXISTS suppliers;
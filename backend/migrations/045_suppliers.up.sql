@@ -0,0 +1,29 @@
+-- 045_suppliers.up.sql
+-- Suppliers per location, and invoice numbers so re-imported invoice lines update in place
+
+CREATE TABLE IF NOT EXISTS suppliers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id),
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_suppliers_name ON suppliers(location_id, LOWER(name));
+
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS supplier_id UUID REFERENCES suppliers(id);
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS invoice_number VARCHAR(100);
+
+INSERT INTO suppliers (location_id, name, code)
+SELECT DISTINCT ON (location_id, LOWER(supplier)) location_id, supplier, REPLACE(REPLACE(LOWER(supplier), ' ', '-'), '_', '-')
+FROM purchases
+ORDER BY location_id, LOWER(supplier), created_at
+ON CONFLICT DO NOTHING;
+
+UPDATE purchases p SET supplier_id = s.id
+FROM suppliers s
+WHERE s.location_id = p.location_id AND LOWER(s.name) = LOWER(p.supplier) AND p.supplier_id IS NULL;
+
+-- One row per item on an invoice; lines without an invoice number keep the file/line key
+CREATE UNIQUE INDEX IF NOT EXISTS idx_purchases_invoice_line ON purchases(location_id, supplier_id, invoice_number, item_name)
+    WHERE invoice_number IS NOT NULL;
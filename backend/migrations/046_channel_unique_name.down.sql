@@ -0,0 +1,2 @@
+-- 046_channel_unique_name.down.sql
+DROP INDEX IF EXISTS idx_service_channels_name;
@@ -0,0 +1,27 @@
+-- 046_channel_unique_name.up.sql
+-- One channel per name and location, so concurrent imports can't create the
+-- same channel twice. Existing duplicates are merged into the oldest row;
+-- aggregates need a full refresh afterwards.
+
+ALTER TABLE service_channels ADD COLUMN IF NOT EXISTS location_id UUID REFERENCES locations(id);
+ALTER TABLE service_channels ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE service_channels ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE TEMP TABLE channel_merge AS
+SELECT id, keep_id FROM (
+    SELECT id, FIRST_VALUE(id) OVER (
+        PARTITION BY location_id, LOWER(display_name) ORDER BY created_at, id
+    ) AS keep_id
+    FROM service_channels
+) c
+WHERE id <> keep_id;
+
+UPDATE sales s SET channel_id = m.keep_id
+FROM channel_merge m
+WHERE s.channel_id = m.id;
+
+DELETE FROM kpi_aggregates WHERE channel_id IN (SELECT id FROM channel_merge);
+DELETE FROM service_channels WHERE id IN (SELECT id FROM channel_merge);
+DROP TABLE channel_merge;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_channels_name ON service_channels(location_id, LOWER(display_name));
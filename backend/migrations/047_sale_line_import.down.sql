@@ -0,0 +1,12 @@
+-- 047_sale_line_import.down.sql
+DROP INDEX IF EXISTS idx_sale_lines_unlinked;
+DROP INDEX IF EXISTS idx_sale_lines_source;
+ALTER TABLE sale_lines DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE sale_lines DROP COLUMN IF EXISTS created_at;
+ALTER TABLE sale_lines DROP COLUMN IF EXISTS source_id;
+ALTER TABLE sale_lines DROP COLUMN IF EXISTS import_source;
+ALTER TABLE sale_lines DROP COLUMN IF EXISTS item_name;
+ALTER TABLE sale_lines DROP COLUMN IF EXISTS sku;
+ALTER TABLE sale_lines DROP COLUMN IF EXISTS location_id;
+DROP INDEX IF EXISTS idx_menu_items_sku;
+ALTER TABLE menu_items DROP COLUMN IF EXISTS sku;
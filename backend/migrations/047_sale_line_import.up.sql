@@ -0,0 +1,20 @@
+-- 047_sale_line_import.up.sql
+-- Sale line items imported from POS item reports, linked to their sale and to
+-- menu items by SKU so recipe-cost COGS can be computed
+
+ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'sale_items';
+
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS sku VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_menu_items_sku ON menu_items(LOWER(sku)) WHERE sku IS NOT NULL;
+
+-- The SKU and name are kept so lines imported before their menu item exists can be linked later
+ALTER TABLE sale_lines ADD COLUMN IF NOT EXISTS location_id UUID REFERENCES locations(id);
+ALTER TABLE sale_lines ADD COLUMN IF NOT EXISTS sku VARCHAR(100);
+ALTER TABLE sale_lines ADD COLUMN IF NOT EXISTS item_name VARCHAR(255);
+ALTER TABLE sale_lines ADD COLUMN IF NOT EXISTS import_source VARCHAR(50);
+ALTER TABLE sale_lines ADD COLUMN IF NOT EXISTS source_id VARCHAR(100);
+ALTER TABLE sale_lines ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE sale_lines ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sale_lines_source ON sale_lines(location_id, import_source, source_id);
+CREATE INDEX IF NOT EXISTS idx_sale_lines_unlinked ON sale_lines(LOWER(sku)) WHERE menu_item_id IS NULL;
@@ -0,0 +1,2 @@
+-- 048_payroll_location.down.sql
+DROP INDEX IF EXISTS idx_payroll_periods_location_dates;
@@ -0,0 +1,20 @@
+-- 048_payroll_location.up.sql
+-- Payroll imports record the location they were loaded for, so labor hours
+-- can be reported per venue. Rows from before the column existed are
+-- assigned to the only location of single-venue deployments.
+
+ALTER TABLE payroll_periods ADD COLUMN IF NOT EXISTS location_id UUID REFERENCES locations(id);
+
+UPDATE payroll_periods SET location_id = (SELECT id FROM locations)
+WHERE location_id IS NULL AND (SELECT COUNT(*) FROM locations) = 1;
+
+-- A re-imported pay period replaces the earlier import, so keep only the
+-- latest row for each location and period before enforcing that
+DELETE FROM payroll_periods p
+USING payroll_periods newer
+WHERE p.location_id = newer.location_id
+AND p.start_date = newer.start_date
+AND p.end_date = newer.end_date
+AND (p.created_at, p.id) < (newer.created_at, newer.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payroll_periods_location_dates ON payroll_periods(location_id, start_date, end_date);
@@ -0,0 +1,3 @@
+-- 049_payroll_import_source.down.sql
+ALTER TABLE payroll_periods DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE payroll_periods DROP COLUMN IF EXISTS import_source;
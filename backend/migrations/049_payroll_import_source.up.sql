@@ -0,0 +1,6 @@
+-- 049_payroll_import_source.up.sql
+-- Payroll rows record how they were loaded and when an import last replaced
+-- them, like the other imported tables
+
+ALTER TABLE payroll_periods ADD COLUMN IF NOT EXISTS import_source VARCHAR(50);
+ALTER TABLE payroll_periods ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
@@ -5,7 +5,10 @@ import { useKPI, DateRange } from '@/hooks/use-kpi';
 import { KPICards, ChannelDaypartCharts } from '@/components/kpi';
 
 const DATE_RANGE_OPTIONS: { value: DateRange; label: string }[] = [
+  { value: '7d', label: 'Last 7 Days' },
   { value: '30d', label: 'Last 30 Days' },
+  { value: 'mtd', label: 'Month to Date' },
+  { value: 'qtd', label: 'Quarter to Date' },
   { value: 'ytd', label: 'Year to Date' },
   { value: 'trailing12m', label: 'Trailing 12 Months' },
 ];
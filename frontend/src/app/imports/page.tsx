@@ -12,8 +12,13 @@ import { MappingProfileForm, ImportLogTable, AnomalyTable } from '@/components/i
 
 const SOURCE_TYPES = [
   { value: 'pos', label: 'POS / Sales', description: 'Sales transactions from your point of sale system' },
+  { value: 'sale_items', label: 'Sale Items', description: 'Line items per sale, matched to menu items by SKU for recipe-cost COGS' },
   { value: 'payroll', label: 'Payroll', description: 'Employee wages and labor costs' },
   { value: 'inventory', label: 'Inventory', description: 'Stock snapshots and valuations' },
+  { value: 'expenses', label: 'Expenses', description: 'Rent, utilities and other operating expenses' },
+  { value: 'refunds', label: 'Refunds', description: 'Refund and chargeback reports from your payment processor' },
+  { value: 'budget', label: 'Budget', description: 'Monthly revenue, COGS, labor and OpEx targets' },
+  { value: 'deposits', label: 'Deposits', description: 'Bank deposits to reconcile against cash sales' },
 ];
 
 export default function ImportsPage() {
@@ -12,6 +12,7 @@ interface ExportPanelProps {
 const EXPORT_TYPES = [
   { value: 'pnl', label: 'P&L Report', description: 'Full profit & loss breakdown by date, channel, and daypart' },
   { value: 'channel_summary', label: 'Channel Summary', description: 'Revenue and margin by sales channel' },
+  { value: 'inventory_valuation', label: 'Inventory Valuation', description: 'Stock on hand at cost as of the end date, with change since the previous count' },
 ];
 
 export function ExportPanel({ defaultStartDate, defaultEndDate }: ExportPanelProps) {
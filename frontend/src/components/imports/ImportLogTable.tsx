@@ -27,6 +27,8 @@ export function ImportLogTable({ jobs, onSelect, selectedJobId }: ImportLogTable
       processing: 'bg-blue-100 text-blue-800',
       completed: 'bg-green-100 text-green-800',
       failed: 'bg-red-100 text-red-800',
+      cancelled: 'bg-gray-100 text-gray-800',
+      interrupted: 'bg-orange-100 text-orange-800',
     };
 
     return (
@@ -11,9 +11,14 @@ interface MappingProfileFormProps {
 }
 
 const SOURCE_TYPE_FIELDS: Record<string, string[]> = {
-  pos: ['date', 'time', 'total', 'subtotal', 'tax', 'discounts', 'comps', 'payment_method', 'channel', 'server'],
+  pos: ['date', 'time', 'total', 'subtotal', 'tax', 'discounts', 'comps', 'payment_method', 'channel', 'server', 'external_id'],
+  sale_items: ['date', 'external_id', 'sku', 'item_name', 'quantity', 'unit_price', 'line_total', 'discounts', 'comps'],
   payroll: ['period_start', 'period_end', 'employee_name', 'hours_worked', 'hourly_rate', 'total_wages', 'superannuation', 'tax_withheld'],
   inventory: ['snapshot_date', 'item_name', 'category', 'quantity', 'unit', 'unit_cost', 'total_value'],
+  expenses: ['date', 'category', 'description', 'amount'],
+  refunds: ['date', 'external_id', 'amount', 'reason'],
+  budget: ['month', 'revenue_target', 'cogs_target', 'labor_target', 'opex_target'],
+  deposits: ['date', 'amount', 'reference'],
 };
 
 export function MappingProfileForm({
@@ -63,11 +63,20 @@ export interface ExportJob {
   completed_at?: string;
 }
 
+export interface ExportListResponse {
+  data: ExportJob[];
+  total: number;
+  page: number;
+  page_size: number;
+  total_pages: number;
+}
+
 export function useExports() {
   return useQuery({
     queryKey: queryKeys.exports.list(),
     queryFn: async (): Promise<ExportJob[]> => {
-      return apiClient.get<ExportJob[]>('/exports');
+      const response = await apiClient.get<ExportListResponse>('/exports');
+      return response.data;
     },
   });
 }
@@ -80,6 +89,7 @@ export function useCreateExport() {
       exportType: string;
       startDate: string;
       endDate: string;
+      format?: 'csv' | 'pdf';
     }): Promise<Blob> => {
       const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL ?? 'http://localhost:8080/api/v1'}/exports/pnl`, {
         method: 'POST',
@@ -91,6 +101,7 @@ export function useCreateExport() {
           export_type: params.exportType,
           start_date: params.startDate,
           end_date: params.endDate,
+          format: params.format,
         }),
       });
 
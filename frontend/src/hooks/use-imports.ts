@@ -5,7 +5,7 @@ import { queryKeys } from '@/lib/query-client';
 export interface ImportJob {
   id: string;
   source_type: string;
-  status: 'pending' | 'processing' | 'completed' | 'failed';
+  status: 'pending' | 'processing' | 'completed' | 'failed' | 'cancelled' | 'interrupted';
   file_name: string;
   file_hash: string;
   total_rows: number;
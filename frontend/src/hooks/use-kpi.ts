@@ -33,6 +33,24 @@ export interface KPISummary {
   avgCheck: number;
   discounts: number;
   comps: number;
+  commissions: number;
+  netRevenue: number;
+}
+
+export interface MetricChange {
+  current: number;
+  previous: number;
+  delta: number;
+  deltaPct: number | null; // null when the prior period was zero
+}
+
+export interface PeriodComparison {
+  previousStart: string;
+  previousEnd: string;
+  revenue: MetricChange;
+  netProfit: MetricChange;
+  covers: MetricChange;
+  avgCheck: MetricChange;
 }
 
 export interface DailyKPIResponse {
@@ -41,9 +59,10 @@ export interface DailyKPIResponse {
   totals: KPITotals;
   byChannel: KPISummary[];
   byDaypart: KPISummary[];
+  comparison: PeriodComparison | null;
 }
 
-export type DateRange = '30d' | 'ytd' | 'trailing12m';
+export type DateRange = '7d' | '30d' | 'mtd' | 'qtd' | 'ytd' | 'trailing12m';
 
 interface UseKPIParams {
   range?: DateRange;
@@ -83,6 +102,31 @@ function transformKPISummary(data: Record<string, unknown>): KPISummary {
     avgCheck: (data.avg_check as number) || 0,
     discounts: (data.discounts as number) || 0,
     comps: (data.comps as number) || 0,
+    commissions: (data.commissions as number) || 0,
+    netRevenue: (data.net_revenue as number) || 0,
+  };
+}
+
+function transformMetricChange(data: Record<string, unknown> | undefined): MetricChange {
+  return {
+    current: (data?.current as number) || 0,
+    previous: (data?.previous as number) || 0,
+    delta: (data?.delta as number) || 0,
+    deltaPct: (data?.delta_pct as number | null) ?? null,
+  };
+}
+
+function transformComparison(data: Record<string, unknown> | undefined): PeriodComparison | null {
+  if (!data) {
+    return null;
+  }
+  return {
+    previousStart: (data.previous_start as string) || '',
+    previousEnd: (data.previous_end as string) || '',
+    revenue: transformMetricChange(data.revenue as Record<string, unknown>),
+    netProfit: transformMetricChange(data.net_profit as Record<string, unknown>),
+    covers: transformMetricChange(data.covers as Record<string, unknown>),
+    avgCheck: transformMetricChange(data.avg_check as Record<string, unknown>),
   };
 }
 
@@ -106,6 +150,7 @@ export function useKPI(params: UseKPIParams = {}) {
         totals: transformKPIData(rawData.totals as Record<string, unknown>),
         byChannel: ((rawData.byChannel as Record<string, unknown>[]) || []).map(transformKPISummary),
         byDaypart: ((rawData.byDaypart as Record<string, unknown>[]) || []).map(transformKPISummary),
+        comparison: transformComparison(rawData.comparison as Record<string, unknown> | undefined),
       };
     },
     staleTime: 30 * 1000, // 30 seconds
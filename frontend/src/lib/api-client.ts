@@ -3,6 +3,8 @@ const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
 export interface ApiError {
   message: string;
   status: number;
+  code?: string;
+  requestId?: string;
 }
 
 class ApiClient {
@@ -59,7 +61,9 @@ class ApiClient {
       };
       try {
         const data = await response.json();
-        error.message = data.error || data.message || error.message;
+        error.message = data.error?.message || data.error || data.message || error.message;
+        error.code = data.error?.code;
+        error.requestId = data.error?.request_id;
       } catch {
         // ignore JSON parse errors
       }
@@ -108,7 +112,9 @@ class ApiClient {
       };
       try {
         const data = await response.json();
-        error.message = data.error || data.message || error.message;
+        error.message = data.error?.message || data.error || data.message || error.message;
+        error.code = data.error?.code;
+        error.requestId = data.error?.request_id;
       } catch {
         // ignore JSON parse errors
       }